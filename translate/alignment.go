@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+// AlignedSentence is a source sentence aligned with its translation, for side by side display
+type AlignedSentence struct {
+	// Source is the source sentence
+	Source string `json:"source"`
+	// Target is the translation of the sentence
+	Target string `json:"target"`
+	// Offsets locate the sentences in the source and translated text
+	Offsets AlignmentOffsets `json:"offsets"`
+}
+
+// AlignmentOffsets are the offsets in characters (Unicode code points) of an aligned sentence
+// into the source and translated text, the end offsets being exclusive
+type AlignmentOffsets struct {
+	SourceStart int `json:"source_start"`
+	SourceEnd   int `json:"source_end"`
+	TargetStart int `json:"target_start"`
+	TargetEnd   int `json:"target_end"`
+}
+
+// translateAlignedText translates the text like translateText, also returning the alignment
+// of its sentences
+func (h *handler) translateAlignedText(ctx context.Context, sourceLanguage, targetLanguage, text string) (string, []AlignedSentence, error) {
+	tokens := splitLanguageSentences(sourceLanguage, text)
+
+	translatedSentences, err := h.translateSentences(ctx, sourceLanguage, targetLanguage, tokens)
+	if err != nil {
+		return "", nil, err
+	}
+
+	translatedText := joinSentences(translatedSentences)
+	return translatedText, alignSentences(text, tokens, translatedText, translatedSentences), nil
+}
+
+// alignSentences pairs the source sentences with their translations, locating each in order
+// in its text. A sentence the segmenter altered so it can't be found is given an empty span
+// at the end of the previous one.
+func alignSentences(sourceText string, sources []string, targetText string, targets []string) []AlignedSentence {
+	// The sentences were split from the text with its invalid UTF-8 replaced
+	sourceText = strings.ToValidUTF8(sourceText, string(utf8.RuneError))
+
+	source, target := newSentenceLocator(sourceText), newSentenceLocator(targetText)
+	alignment := make([]AlignedSentence, len(sources))
+	for i := range sources {
+		alignment[i] = AlignedSentence{Source: sources[i], Target: targets[i]}
+		alignment[i].Offsets.SourceStart, alignment[i].Offsets.SourceEnd = source.locate(sources[i])
+		alignment[i].Offsets.TargetStart, alignment[i].Offsets.TargetEnd = target.locate(targets[i])
+	}
+	return alignment
+}
+
+// sentenceLocator finds consecutive sentences in a text, converting their byte offsets into
+// character offsets as it goes
+type sentenceLocator struct {
+	text string
+	// offset is the byte offset after the last sentence found and characters its character
+	// offset
+	offset     int
+	characters int
+}
+
+func newSentenceLocator(text string) *sentenceLocator {
+	return &sentenceLocator{text: text}
+}
+
+// locate returns the character span of the next occurrence of the sentence
+func (l *sentenceLocator) locate(sentence string) (int, int) {
+	sentence = strings.TrimSpace(sentence)
+	index := strings.Index(l.text[l.offset:], sentence)
+	if sentence == "" || index < 0 {
+		return l.characters, l.characters
+	}
+
+	start := l.characters + utf8.RuneCountInString(l.text[l.offset:l.offset+index])
+	end := start + utf8.RuneCountInString(sentence)
+	l.offset += index + len(sentence)
+	l.characters = end
+	return start, end
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestAlignSentences(t *testing.T) {
+	tests := []struct {
+		name       string
+		sourceText string
+		sources    []string
+		targetText string
+		targets    []string
+		expected   []AlignmentOffsets
+	}{
+		{
+			name:       "Joined translation",
+			sourceText: "Hello.  How are you?",
+			sources:    []string{"Hello.  ", "How are you?"},
+			targetText: "Hola. ¿Cómo estás? ",
+			targets:    []string{"Hola.", "¿Cómo estás?"},
+			expected: []AlignmentOffsets{
+				{SourceStart: 0, SourceEnd: 6, TargetStart: 0, TargetEnd: 5},
+				{SourceStart: 8, SourceEnd: 20, TargetStart: 6, TargetEnd: 18},
+			},
+		},
+		{
+			name:       "Repeated sentence",
+			sourceText: "Ja. Ja.",
+			sources:    []string{"Ja.", "Ja."},
+			targetText: "Sí. Sí. ",
+			targets:    []string{"Sí.", "Sí."},
+			expected: []AlignmentOffsets{
+				{SourceStart: 0, SourceEnd: 3, TargetStart: 0, TargetEnd: 3},
+				{SourceStart: 4, SourceEnd: 7, TargetStart: 4, TargetEnd: 7},
+			},
+		},
+		{
+			name:       "Altered sentence",
+			sourceText: "Fine.\n\nThanks  a lot!",
+			sources:    []string{"Fine.", "Thanks a lot!"},
+			targetText: "Bien. ¡Muchas gracias! ",
+			targets:    []string{"Bien.", "¡Muchas gracias!"},
+			expected: []AlignmentOffsets{
+				{SourceStart: 0, SourceEnd: 5, TargetStart: 0, TargetEnd: 5},
+				{SourceStart: 5, SourceEnd: 5, TargetStart: 6, TargetEnd: 22},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alignment := alignSentences(tt.sourceText, tt.sources, tt.targetText, tt.targets)
+
+			got := make([]AlignmentOffsets, len(alignment))
+			for i, sentence := range alignment {
+				if sentence.Source != tt.sources[i] || sentence.Target != tt.targets[i] {
+					t.Errorf("alignSentences()[%d] = %q, %q, expected %q, %q", i, sentence.Source, sentence.Target, tt.sources[i], tt.targets[i])
+				}
+				got[i] = sentence.Offsets
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("alignSentences() offsets = %+v, expected %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandleAlignment(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name: "Translated",
+			body: `{"source_language":"en","target_language":"es","text":"Hello. How are you?","alignment":true}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body: `{"translated_text":"Hola. ¿Cómo estás? ","alignment":[` +
+					`{"source":"Hello.","target":"Hola.","offsets":{"source_start":0,"source_end":6,"target_start":0,"target_end":5}},` +
+					`{"source":"How are you?","target":"¿Cómo estás?","offsets":{"source_start":7,"source_end":19,"target_start":6,"target_end":18}}]}`,
+			},
+		},
+		{
+			name: "Unsupported format",
+			body: `{"source_language":"en","target_language":"es","text":"<p>Hello.</p>","format":"html","alignment":true}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"alignment","message":"alignment is only supported for text"}]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(map[string]string{"Hello.": "Hola.", "How are you?": "¿Cómo estás?"})
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxAlternatives is the largest number of alternative translations of a sentence
+const maxAlternatives = 5
+
+// SegmentAlternatives are the candidate translations of a source sentence besides the one of
+// the response, for reviewers to pick from
+type SegmentAlternatives struct {
+	// SourceText is the source sentence
+	SourceText string `json:"source_text"`
+	// TranslatedText is the translation of the sentence in the response
+	TranslatedText string `json:"translated_text"`
+	// Alternatives are the other candidate translations, empty when every candidate matched
+	// the translation of the response
+	Alternatives []string `json:"alternatives"`
+}
+
+// alternativeCandidate is a provider and the settings of a candidate translation
+type alternativeCandidate struct {
+	provider string
+	client   TranslateClient
+	settings *types.TranslationSettings
+}
+
+// translateWithAlternatives translates the text like translateText, also returning up to n
+// alternative translations of each sentence. Only the translations of the response are
+// cached, the alternatives are requested from the providers every time.
+func (h *handler) translateWithAlternatives(ctx context.Context, sourceLanguage, targetLanguage, text string, n int) (string, []SegmentAlternatives, error) {
+	tokens := splitLanguageSentences(sourceLanguage, text)
+
+	translatedSentences, err := h.translateSentences(ctx, sourceLanguage, targetLanguage, tokens)
+	if err != nil {
+		return "", nil, err
+	}
+
+	alternatives := make([]SegmentAlternatives, len(tokens))
+	errGroup, groupCtx := errgroup.WithContext(ctx)
+	errGroup.SetLimit(maxConcurrentTranslations)
+	for i, token := range tokens {
+		errGroup.Go(func() error {
+			alternatives[i] = SegmentAlternatives{
+				SourceText:     token,
+				TranslatedText: translatedSentences[i],
+				Alternatives:   h.alternativeTranslations(groupCtx, sourceLanguage, targetLanguage, token, translatedSentences[i], n),
+			}
+			return nil
+		})
+	}
+	if err := errGroup.Wait(); err != nil {
+		return "", nil, err
+	}
+
+	return joinSentences(translatedSentences), alternatives, nil
+}
+
+// alternativeTranslations returns up to n translations of the sentence differing from its
+// translation, requesting the candidates in turn until there are enough. A candidate the
+// provider fails to translate is skipped.
+func (h *handler) alternativeTranslations(ctx context.Context, sourceLanguage, targetLanguage, token, translated string, n int) []string {
+	alternatives := []string{}
+	for _, candidate := range h.alternativeCandidates(ctx, sourceLanguage, targetLanguage) {
+		if len(alternatives) == n {
+			break
+		}
+
+		output, err := candidate.client.TranslateText(ctx, &translate.TranslateTextInput{
+			SourceLanguageCode: aws.String(sourceLanguage),
+			TargetLanguageCode: aws.String(targetLanguage),
+			Text:               aws.String(token),
+			Settings:           candidate.settings,
+			TerminologyNames:   terminologyNames(ctx, targetLanguage),
+		})
+		if err != nil {
+			log.Printf("Error translating an alternative with %s: %v", candidate.provider, err)
+			continue
+		}
+
+		// Alternatives go through the same post-processing as the translation
+		alternative := []string{aws.ToString(output.TranslatedText)}
+		if casingPreservationFromContext(ctx) {
+			preserveCasing(targetLanguage, []string{token}, alternative)
+		}
+		if t := typographyFromContext(ctx); t != (typography{}) {
+			normalizeTypography(targetLanguage, t, alternative)
+		}
+		if alternative[0] != translated && !slices.Contains(alternatives, alternative[0]) {
+			alternatives = append(alternatives, alternative[0])
+		}
+	}
+	return alternatives
+}
+
+// alternativeCandidates returns the candidates of the alternative translations of the pair:
+// the translations of the configured providers other than the routed one, then the formal and
+// informal translations of the providers supporting a formality, then the brief translation
+// of AWS Translate
+func (h *handler) alternativeCandidates(ctx context.Context, sourceLanguage, targetLanguage string) []alternativeCandidate {
+	_, route := h.translationRoute(ctx, sourceLanguage, targetLanguage)
+
+	providers := []alternativeCandidate{{provider: providerAWSTranslate, client: h.translateClient}}
+	if h.deepLClient != nil {
+		providers = append(providers, alternativeCandidate{provider: providerDeepL, client: h.deepLClient})
+	}
+	if client, provider := h.translationProvider(sourceLanguage, targetLanguage); provider == providerSageMaker {
+		providers = append(providers, alternativeCandidate{provider: providerSageMaker, client: client})
+	}
+
+	var candidates []alternativeCandidate
+	for _, provider := range providers {
+		if provider.provider != route.Provider {
+			candidates = append(candidates, provider)
+		}
+	}
+	for _, provider := range providers {
+		// Our own model takes no settings
+		if provider.provider == providerSageMaker {
+			continue
+		}
+		for _, formality := range []types.Formality{types.FormalityFormal, types.FormalityInformal} {
+			provider.settings = &types.TranslationSettings{Formality: formality}
+			candidates = append(candidates, provider)
+		}
+	}
+	return append(candidates, alternativeCandidate{
+		provider: providerAWSTranslate,
+		client:   h.translateClient,
+		settings: &types.TranslationSettings{Brevity: types.BrevityOn},
+	})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+)
+
+func TestHandleAlternatives(t *testing.T) {
+	tests := []struct {
+		name               string
+		deepL              bool
+		body               string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "Other provider and formal translation",
+			deepL:              true,
+			body:               `{"source_language":"en","target_language":"es","text":"Hello","alternatives":2}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"translated_text":"Hola ","alternatives":[{"source_text":"Hello","translated_text":"Hola","alternatives":["Hola from DeepL","Buenos días"]}]}`,
+		},
+		{
+			name:               "Candidates matching the translation",
+			body:               `{"source_language":"en","target_language":"es","text":"Hello","alternatives":5}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"translated_text":"Hola ","alternatives":[{"source_text":"Hello","translated_text":"Hola","alternatives":["Buenos días"]}]}`,
+		},
+		{
+			name:               "Too many alternatives",
+			body:               `{"source_language":"en","target_language":"es","text":"Hello","alternatives":6}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Alternatives of HTML",
+			body:               `{"source_language":"en","target_language":"es","text":"<p>Hello</p>","format":"html","alternatives":2}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu   sync.Mutex
+				puts int
+			)
+			h := newMockHandler(nil)
+			h.translateClient.(*MockTranslateClient).TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				translated := "Hola"
+				if params.Settings != nil && params.Settings.Formality == types.FormalityFormal {
+					translated = "Buenos días"
+				}
+				return &translate.TranslateTextOutput{TranslatedText: aws.String(translated)}, nil
+			}
+			h.dynamoClient.(*MockDynamoDBClient).PutItemFunc = func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if _, ok := params.Item["source_text"]; ok {
+					puts++
+				}
+				return &dynamodb.PutItemOutput{}, nil
+			}
+			if tt.deepL {
+				deepL := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte(`{"translations":[{"text":"Hola from DeepL"}]}`))
+				}))
+				defer deepL.Close()
+				h.deepLClient = newDeepLTranslateClient(deepL.Client(), "key", deepL.URL)
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedStatusCode {
+				t.Fatalf("handle() status = %d, expected %d: %s", got.StatusCode, tt.expectedStatusCode, got.Body)
+			}
+			if tt.expectedBody == "" {
+				return
+			}
+			if got.Body != tt.expectedBody {
+				t.Errorf("handle() = %s, expected %s", got.Body, tt.expectedBody)
+			}
+			// Only the translation of the response is cached
+			if puts != 1 {
+				t.Errorf("PutItem() called %d times, expected once", puts)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/comprehend"
+	comprehendTypes "github.com/aws/aws-sdk-go-v2/service/comprehend/types"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// maxAnalysisBytes is the size of the text Comprehend detects the sentiment of, longer
+	// texts are analyzed up to it
+	maxAnalysisBytes = 5000
+	// entityThreshold is the Comprehend score above which an entity is reported
+	entityThreshold = 0.5
+)
+
+// comprehendLanguages are the languages Comprehend detects the sentiment and the entities of,
+// by language code or base language
+var comprehendLanguages = map[string]comprehendTypes.LanguageCode{
+	"ar":    comprehendTypes.LanguageCodeAr,
+	"de":    comprehendTypes.LanguageCodeDe,
+	"en":    comprehendTypes.LanguageCodeEn,
+	"es":    comprehendTypes.LanguageCodeEs,
+	"fr":    comprehendTypes.LanguageCodeFr,
+	"hi":    comprehendTypes.LanguageCodeHi,
+	"it":    comprehendTypes.LanguageCodeIt,
+	"ja":    comprehendTypes.LanguageCodeJa,
+	"ko":    comprehendTypes.LanguageCodeKo,
+	"pt":    comprehendTypes.LanguageCodePt,
+	"zh":    comprehendTypes.LanguageCodeZh,
+	"zh-TW": comprehendTypes.LanguageCodeZhTw,
+}
+
+// SourceAnalysis is the tone and the named entities of the source text, detected by
+// Comprehend alongside the translation
+type SourceAnalysis struct {
+	// Sentiment is the dominant sentiment of the text, POSITIVE, NEGATIVE, NEUTRAL or MIXED
+	Sentiment string `json:"sentiment"`
+	// SentimentScores are the confidence of each sentiment, by sentiment
+	SentimentScores map[string]float32 `json:"sentiment_scores"`
+	// Entities are the named entities of the text, such as people and organizations
+	Entities []SourceEntity `json:"entities"`
+	// Truncated is set when only the start of the text was analyzed
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// SourceEntity is a named entity of the source text
+type SourceEntity struct {
+	Text string `json:"text"`
+	// Type is the Comprehend entity type, such as PERSON, ORGANIZATION or COMMERCIAL_ITEM
+	Type  string  `json:"type"`
+	Score float32 `json:"score"`
+	// BeginOffset and EndOffset are the character offsets of the entity in the source text
+	BeginOffset int `json:"begin_offset"`
+	EndOffset   int `json:"end_offset"`
+}
+
+// comprehendLanguage returns the Comprehend language code of the language, false when
+// Comprehend doesn't analyze it
+func comprehendLanguage(language string) (comprehendTypes.LanguageCode, bool) {
+	if code, ok := comprehendLanguages[language]; ok {
+		return code, true
+	}
+	base, _, _ := strings.Cut(language, "-")
+	code, ok := comprehendLanguages[base]
+	return code, ok
+}
+
+// startSourceAnalysis detects the sentiment and the entities of the source text while it is
+// translated. The returned function waits for the analysis, which is nil when the language
+// isn't supported by Comprehend or the analysis failed: the translation is served without it.
+func (h *handler) startSourceAnalysis(ctx context.Context, request TranslateRequest) func() *SourceAnalysis {
+	languageCode, ok := comprehendLanguage(request.SourceLanguage)
+	if !request.Analyze || h.comprehendClient == nil || !ok {
+		return func() *SourceAnalysis { return nil }
+	}
+
+	result := make(chan *SourceAnalysis, 1)
+	go func() {
+		text, err := requestPlainText(request)
+		if err != nil {
+			log.Printf("Error extracting the text to analyze: %v", err)
+			result <- nil
+			return
+		}
+		analysis, err := analyzeText(ctx, h.comprehendClient, languageCode, text)
+		if err != nil {
+			log.Printf("Error analyzing source text: %v", err)
+		}
+		result <- analysis
+	}()
+
+	return func() *SourceAnalysis {
+		return <-result
+	}
+}
+
+// analyzeText detects the sentiment and the entities of the text
+func analyzeText(ctx context.Context, comprehendClient ComprehendClient, languageCode comprehendTypes.LanguageCode, text string) (*SourceAnalysis, error) {
+	analyzed := truncateBytes(text, maxAnalysisBytes)
+	analysis := &SourceAnalysis{Truncated: len(analyzed) < len(text)}
+
+	errGroup, groupCtx := errgroup.WithContext(ctx)
+	errGroup.Go(func() error {
+		out, err := comprehendClient.DetectSentiment(groupCtx, &comprehend.DetectSentimentInput{
+			LanguageCode: languageCode,
+			Text:         aws.String(analyzed),
+		})
+		if err != nil {
+			return err
+		}
+		analysis.Sentiment = string(out.Sentiment)
+		if score := out.SentimentScore; score != nil {
+			analysis.SentimentScores = map[string]float32{
+				string(comprehendTypes.SentimentTypePositive): aws.ToFloat32(score.Positive),
+				string(comprehendTypes.SentimentTypeNegative): aws.ToFloat32(score.Negative),
+				string(comprehendTypes.SentimentTypeNeutral):  aws.ToFloat32(score.Neutral),
+				string(comprehendTypes.SentimentTypeMixed):    aws.ToFloat32(score.Mixed),
+			}
+		}
+		return nil
+	})
+	errGroup.Go(func() error {
+		entities, err := detectEntities(groupCtx, comprehendClient, languageCode, analyzed)
+		analysis.Entities = entities
+		return err
+	})
+	if err := errGroup.Wait(); err != nil {
+		return nil, err
+	}
+
+	return analysis, nil
+}
+
+// detectEntities returns the named entities Comprehend detects in the text with a score
+// above the threshold
+func detectEntities(ctx context.Context, comprehendClient ComprehendClient, languageCode comprehendTypes.LanguageCode, text string) ([]SourceEntity, error) {
+	out, err := comprehendClient.DetectEntities(ctx, &comprehend.DetectEntitiesInput{
+		LanguageCode: languageCode,
+		Text:         aws.String(text),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entities := []SourceEntity{}
+	for _, entity := range out.Entities {
+		if aws.ToFloat32(entity.Score) < entityThreshold {
+			continue
+		}
+		entities = append(entities, SourceEntity{
+			Text:        aws.ToString(entity.Text),
+			Type:        string(entity.Type),
+			Score:       aws.ToFloat32(entity.Score),
+			BeginOffset: int(aws.ToInt32(entity.BeginOffset)),
+			EndOffset:   int(aws.ToInt32(entity.EndOffset)),
+		})
+	}
+	return entities, nil
+}
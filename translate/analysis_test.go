@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/comprehend"
+	comprehendTypes "github.com/aws/aws-sdk-go-v2/service/comprehend/types"
+)
+
+func TestSourceAnalysis(t *testing.T) {
+	translations := map[string]string{
+		"I am very unhappy with Acme.": "Estoy muy descontento con Acme.",
+	}
+
+	tests := []struct {
+		name             string
+		body             string
+		sentimentErr     error
+		expectedText     string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name:         "Text is analyzed",
+			body:         `{"source_language":"en","target_language":"es","text":"I am very unhappy with Acme.","analyze":true}`,
+			expectedText: "I am very unhappy with Acme.",
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body: `{"translated_text":"Estoy muy descontento con Acme. ","analysis":{"sentiment":"NEGATIVE",` +
+					`"sentiment_scores":{"MIXED":0,"NEGATIVE":0.875,"NEUTRAL":0.125,"POSITIVE":0},` +
+					`"entities":[{"text":"Acme","type":"ORGANIZATION","score":0.75,"begin_offset":23,"end_offset":27}]}}`,
+			},
+		},
+		{
+			name:         "The text content of HTML is analyzed",
+			body:         `{"source_language":"en-US","target_language":"es","text":"<p>I am very unhappy with Acme.</p>","format":"html","analyze":true}`,
+			expectedText: "I am very unhappy with Acme.",
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body: `{"translated_text":"\u003cp lang=\"es\"\u003eEstoy muy descontento con Acme.\u003c/p\u003e","analysis":{"sentiment":"NEGATIVE",` +
+					`"sentiment_scores":{"MIXED":0,"NEGATIVE":0.875,"NEUTRAL":0.125,"POSITIVE":0},` +
+					`"entities":[{"text":"Acme","type":"ORGANIZATION","score":0.75,"begin_offset":23,"end_offset":27}]}}`,
+			},
+		},
+		{
+			name: "Unsupported language is translated without analysis",
+			body: `{"source_language":"sv","target_language":"es","text":"I am very unhappy with Acme.","analyze":true}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Estoy muy descontento con Acme. "}`,
+			},
+		},
+		{
+			name:         "Failed analysis is left out",
+			body:         `{"source_language":"en","target_language":"es","text":"I am very unhappy with Acme.","analyze":true}`,
+			sentimentErr: errors.New("throttled"),
+			expectedText: "I am very unhappy with Acme.",
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Estoy muy descontento con Acme. "}`,
+			},
+		},
+		{
+			name: "Analysis of a dry run",
+			body: `{"source_language":"en","target_language":"es","text":"I am very unhappy with Acme.","analyze":true,"dry_run":true}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"analyze","message":"analyze is only supported for text and html, without dry_run"}]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var analyzed []string
+			h := newMockHandler(translations)
+			h.comprehendClient = &MockComprehendClient{
+				DetectSentimentFunc: func(ctx context.Context, params *comprehend.DetectSentimentInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectSentimentOutput, error) {
+					if tt.sentimentErr != nil {
+						return nil, tt.sentimentErr
+					}
+					analyzed = append(analyzed, aws.ToString(params.Text))
+					return &comprehend.DetectSentimentOutput{
+						Sentiment: comprehendTypes.SentimentTypeNegative,
+						SentimentScore: &comprehendTypes.SentimentScore{
+							Negative: aws.Float32(0.875),
+							Neutral:  aws.Float32(0.125),
+							Positive: aws.Float32(0),
+							Mixed:    aws.Float32(0),
+						},
+					}, nil
+				},
+				DetectEntitiesFunc: func(ctx context.Context, params *comprehend.DetectEntitiesInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectEntitiesOutput, error) {
+					return &comprehend.DetectEntitiesOutput{Entities: []comprehendTypes.Entity{
+						{Text: aws.String("Acme"), Type: comprehendTypes.EntityTypeOrganization, Score: aws.Float32(0.75), BeginOffset: aws.Int32(23), EndOffset: aws.Int32(27)},
+						{Text: aws.String("unhappy"), Type: comprehendTypes.EntityTypeOther, Score: aws.Float32(0.25), BeginOffset: aws.Int32(10), EndOffset: aws.Int32(17)},
+					}}, nil
+				},
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+			if tt.expectedText != "" && tt.sentimentErr == nil && (len(analyzed) != 1 || analyzed[0] != tt.expectedText) {
+				t.Errorf("DetectSentiment() texts = %q, expected %q", analyzed, tt.expectedText)
+			}
+		})
+	}
+}
+
+func TestComprehendLanguage(t *testing.T) {
+	tests := []struct {
+		language string
+		expected comprehendTypes.LanguageCode
+		ok       bool
+	}{
+		{language: "en", expected: comprehendTypes.LanguageCodeEn, ok: true},
+		{language: "pt-PT", expected: comprehendTypes.LanguageCodePt, ok: true},
+		{language: "zh-TW", expected: comprehendTypes.LanguageCodeZhTw, ok: true},
+		{language: "sv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			got, ok := comprehendLanguage(tt.language)
+			if got != tt.expected || ok != tt.ok {
+				t.Errorf("comprehendLanguage() = %v %v, expected %v %v", got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
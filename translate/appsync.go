@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	handlerModeAppSync = "appsync"
+
+	// appSyncTranslateField is the GraphQL field resolved by the function
+	appSyncTranslateField = "translate"
+)
+
+// AppSyncResolverEvent is the event of an AppSync direct Lambda resolver for the field
+//
+//	translate(text: String!, target: String!, source: String, format: String, moderation: String, transliterate: Boolean, style: String, noStore: Boolean): Translation
+type AppSyncResolverEvent struct {
+	// Arguments are the arguments of the field
+	Arguments AppSyncTranslateArguments `json:"arguments"`
+	// Info describes the field being resolved
+	Info AppSyncInfo `json:"info"`
+}
+
+// AppSyncTranslateArguments are the arguments of the translate field
+type AppSyncTranslateArguments struct {
+	Text          string `json:"text"`
+	Target        string `json:"target"`
+	Source        string `json:"source"`
+	Format        string `json:"format"`
+	Moderation    string `json:"moderation"`
+	Transliterate bool   `json:"transliterate"`
+	Style         string `json:"style"`
+	NoStore       bool   `json:"noStore"`
+}
+
+// AppSyncInfo is the GraphQL field of an AppSync resolver event
+type AppSyncInfo struct {
+	FieldName      string `json:"fieldName"`
+	ParentTypeName string `json:"parentTypeName"`
+}
+
+// Translation is the GraphQL type returned by the translate field
+type Translation struct {
+	TranslatedText   string `json:"translatedText"`
+	DetectedLanguage string `json:"detectedLanguage,omitempty"`
+	Skipped          bool   `json:"skipped"`
+	Degraded         bool   `json:"degraded"`
+	LowQuality       bool   `json:"lowQuality"`
+}
+
+// handleAppSyncEvent resolves the translate field of a GraphQL API. Errors are returned to
+// AppSync, which reports them in the errors of the GraphQL response.
+func (h *handler) handleAppSyncEvent(ctx context.Context, event AppSyncResolverEvent) (Translation, error) {
+	defer flushTelemetry(ctx)
+	defer recordStage(ctx, stageRequest, time.Now())
+
+	if event.Info.FieldName != "" && event.Info.FieldName != appSyncTranslateField {
+		return Translation{}, fmt.Errorf("field %q is not supported", event.Info.FieldName)
+	}
+
+	request := TranslateRequest{
+		SourceLanguage: event.Arguments.Source,
+		TargetLanguage: event.Arguments.Target,
+		Text:           event.Arguments.Text,
+		Format:         event.Arguments.Format,
+		Moderation:     event.Arguments.Moderation,
+		Transliterate:  event.Arguments.Transliterate,
+		Style:          event.Arguments.Style,
+		NoStore:        event.Arguments.NoStore,
+	}
+	if request.SourceLanguage == "" {
+		request.SourceLanguage = autoDetectLanguage
+	}
+
+	// Documents don't fit in GraphQL arguments, only text formats are resolved
+	if request.Format != "" && request.Format != formatText && request.Format != formatHTML {
+		return Translation{}, fmt.Errorf("format %q is not supported by the GraphQL API", request.Format)
+	}
+	if err := validateRequest(request); err != nil {
+		return Translation{}, err
+	}
+
+	ctx, writer := h.withCacheWriter(ctx)
+	defer writer.Close()
+
+	response, err := h.handleTranslateRequest(ctx, request)
+	if err != nil {
+		return Translation{}, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return Translation{}, errors.New(response.Body)
+	}
+
+	var translated TranslateResponse
+	if err := json.Unmarshal([]byte(response.Body), &translated); err != nil {
+		return Translation{}, fmt.Errorf("failed to unmarshal translation: %w", err)
+	}
+
+	return Translation{
+		TranslatedText:   translated.TranslatedText,
+		DetectedLanguage: translated.DetectedLanguage,
+		Skipped:          translated.Skipped,
+		Degraded:         translated.Degraded,
+		LowQuality:       translated.LowQuality,
+	}, nil
+}
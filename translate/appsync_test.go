@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHandleAppSyncEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    AppSyncResolverEvent
+		detected string
+		expected Translation
+		wantErr  string
+	}{
+		{
+			name: "Translate field",
+			event: AppSyncResolverEvent{
+				Arguments: AppSyncTranslateArguments{Text: "Hello", Source: "en", Target: "es"},
+				Info:      AppSyncInfo{FieldName: "translate", ParentTypeName: "Query"},
+			},
+			expected: Translation{TranslatedText: "Hola "},
+		},
+		{
+			name: "Source language is detected by default",
+			event: AppSyncResolverEvent{
+				Arguments: AppSyncTranslateArguments{Text: "Hola", Target: "es"},
+			},
+			detected: "es",
+			expected: Translation{TranslatedText: "Hola", DetectedLanguage: "es", Skipped: true},
+		},
+		{
+			name: "Invalid arguments",
+			event: AppSyncResolverEvent{
+				Arguments: AppSyncTranslateArguments{Text: "Hello", Source: "en", Target: "spanish!"},
+			},
+			wantErr: `target_language "spanish!" is not a valid language code`,
+		},
+		{
+			name: "Unsupported target language",
+			event: AppSyncResolverEvent{
+				Arguments: AppSyncTranslateArguments{Text: "Hello", Source: "en", Target: "fr"},
+			},
+			wantErr: "Target language not supported",
+		},
+		{
+			name: "Document formats are not supported",
+			event: AppSyncResolverEvent{
+				Arguments: AppSyncTranslateArguments{Text: "Hello", Source: "en", Target: "es", Format: formatPDF},
+			},
+			wantErr: `format "pdf" is not supported by the GraphQL API`,
+		},
+		{
+			name: "Unknown field",
+			event: AppSyncResolverEvent{
+				Arguments: AppSyncTranslateArguments{Text: "Hello", Source: "en", Target: "es"},
+				Info:      AppSyncInfo{FieldName: "detect", ParentTypeName: "Query"},
+			},
+			wantErr: `field "detect" is not supported`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(map[string]string{"Hello": "Hola"})
+			if tt.detected != "" {
+				h.comprehendClient = newMockLanguageDetector(tt.detected)
+			}
+
+			got, err := h.handleAppSyncEvent(context.Background(), tt.event)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("handleAppSyncEvent() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("handleAppSyncEvent() error = %v", err)
+				return
+			}
+
+			if got != tt.expected {
+				t.Errorf("handleAppSyncEvent() = %+v, expected %+v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	firehoseTypes "github.com/aws/aws-sdk-go-v2/service/firehose/types"
+)
+
+var (
+	// auditDeliveryStreamName is the Firehose delivery stream the audit record of each API
+	// request is written to, auditing is disabled when empty
+	auditDeliveryStreamName string
+	// auditIncludeText adds the text of the requests and their translations to the audit
+	// records, which only count characters otherwise
+	auditIncludeText bool
+)
+
+// AuditRecord is the append-only record of an API request. Its fields are flat and always
+// present so the delivered JSON lines map to a fixed Parquet schema.
+type AuditRecord struct {
+	Timestamp  string `json:"timestamp"`
+	RequestID  string `json:"request_id"`
+	Caller     string `json:"caller"`
+	Tenant     string `json:"tenant"`
+	SourceIP   string `json:"source_ip"`
+	Method     string `json:"method"`
+	Resource   string `json:"resource"`
+	StatusCode int    `json:"status_code"`
+
+	SourceLanguage string `json:"source_language"`
+	TargetLanguage string `json:"target_language"`
+	Format         string `json:"format"`
+	DryRun         bool   `json:"dry_run"`
+	// Characters and TranslatedCharacters are the length of the text and its translation
+	Characters           int64 `json:"characters"`
+	TranslatedCharacters int64 `json:"translated_characters"`
+	// Segments, CacheHits, FreshSegments and FreshCharacters count the cache lookups of the
+	// request and the misses translated by the provider
+	Segments        int64 `json:"segments"`
+	CacheHits       int64 `json:"cache_hits"`
+	FreshSegments   int64 `json:"fresh_segments"`
+	FreshCharacters int64 `json:"fresh_characters"`
+
+	// Text and TranslatedText are only recorded with auditIncludeText
+	Text           string `json:"text,omitempty"`
+	TranslatedText string `json:"translated_text,omitempty"`
+}
+
+// requestAudit collects the audit record of a request while it is handled
+type requestAudit struct {
+	record AuditRecord
+	usage  translationUsage
+}
+
+type auditKey struct{}
+
+// withAudit returns a context collecting the audit record of the request, the context is
+// unchanged and the audit nil unless auditing is enabled
+func withAudit(ctx context.Context, event events.APIGatewayProxyRequest) (context.Context, *requestAudit) {
+	if auditDeliveryStreamName == "" {
+		return ctx, nil
+	}
+
+	a := &requestAudit{record: AuditRecord{
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		RequestID: event.RequestContext.RequestID,
+		Caller:    callerFromEvent(event),
+		SourceIP:  event.RequestContext.Identity.SourceIP,
+		Method:    event.HTTPMethod,
+		Resource:  event.Resource,
+	}}
+	if e, ok := entitlementFromEvent(event); ok {
+		a.record.Tenant = e.Tenant
+	}
+	return context.WithValue(ctx, auditKey{}, a), a
+}
+
+// withItemAudit returns a context collecting the audit record of an item of a batch request
+// apart from the batch, the context is unchanged and the audit nil unless the batch is audited
+func withItemAudit(ctx context.Context) (context.Context, *requestAudit) {
+	batch := auditFromContext(ctx)
+	if batch == nil {
+		return ctx, nil
+	}
+	a := &requestAudit{record: batch.record}
+	return context.WithValue(ctx, auditKey{}, a), a
+}
+
+// auditFromContext returns the audit of the request, nil when auditing is disabled
+func auditFromContext(ctx context.Context) *requestAudit {
+	a, _ := ctx.Value(auditKey{}).(*requestAudit)
+	return a
+}
+
+// callerFromEvent identifies the caller of a request: the subject of its authorizer, the IAM
+// user or the API key it was called with
+func callerFromEvent(event events.APIGatewayProxyRequest) string {
+	claims := event.RequestContext.Authorizer
+	if nested, ok := claims["claims"].(map[string]any); ok {
+		if sub := claimString(nested["sub"]); sub != "" {
+			return sub
+		}
+	}
+	if principal := claimString(claims["principalId"]); principal != "" {
+		return principal
+	}
+	if identity := event.RequestContext.Identity; identity.UserArn != "" {
+		return identity.UserArn
+	}
+	return event.RequestContext.Identity.APIKeyID
+}
+
+// setRequest records the languages, format and length of a translation request
+func (a *requestAudit) setRequest(request TranslateRequest) {
+	if a == nil {
+		return
+	}
+	a.record.SourceLanguage = request.SourceLanguage
+	a.record.TargetLanguage = request.TargetLanguage
+	a.record.Format = request.Format
+	a.record.DryRun = request.DryRun
+	a.record.Characters = int64(utf8.RuneCountInString(request.Text))
+	if auditIncludeText {
+		a.record.Text = request.Text
+	}
+}
+
+// setSourceLanguage records the detected source language of a request
+func (a *requestAudit) setSourceLanguage(language string) {
+	if a != nil {
+		a.record.SourceLanguage = language
+	}
+}
+
+// setTranslation records the translated text of a response
+func (a *requestAudit) setTranslation(translatedText string) {
+	if a == nil {
+		return
+	}
+	a.record.TranslatedCharacters = int64(utf8.RuneCountInString(translatedText))
+	if auditIncludeText {
+		a.record.TranslatedText = translatedText
+	}
+}
+
+// writeAuditRecord writes the audit record of a request answered with the response. Failures
+// are logged, the caller already has its response.
+func (h *handler) writeAuditRecord(ctx context.Context, a *requestAudit, response events.APIGatewayProxyResponse) {
+	if a == nil || h.firehoseClient == nil {
+		return
+	}
+
+	record := a.record
+	record.StatusCode = response.StatusCode
+	record.Segments = a.usage.segments.Load()
+	record.CacheHits = a.usage.cacheHits.Load()
+	record.FreshSegments = a.usage.freshSegments.Load()
+	record.FreshCharacters = a.usage.freshCharacters.Load()
+
+	if err := h.putAuditRecord(ctx, record); err != nil {
+		log.Printf("Error writing audit record of request %s: %v", record.RequestID, err)
+	}
+}
+
+// putAuditRecord writes an audit record as a line of JSON to the audit delivery stream
+func (h *handler) putAuditRecord(ctx context.Context, record AuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	output, err := h.firehoseClient.PutRecordBatch(ctx, &firehose.PutRecordBatchInput{
+		DeliveryStreamName: aws.String(auditDeliveryStreamName),
+		Records:            []firehoseTypes.Record{{Data: append(data, '\n')}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put audit record to %s: %w", auditDeliveryStreamName, err)
+	}
+	if aws.ToInt32(output.FailedPutCount) > 0 {
+		return fmt.Errorf("failed to put audit record to %s", auditDeliveryStreamName)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+)
+
+func TestAuditRecord(t *testing.T) {
+	tests := []struct {
+		name        string
+		includeText bool
+		body        string
+		expected    AuditRecord
+	}{
+		{
+			name: "Translation",
+			body: `{"source_language":"en","target_language":"es","text":"Hello. Bye."}`,
+			expected: AuditRecord{
+				RequestID:            "request-1",
+				Caller:               "key-1",
+				SourceIP:             "192.0.2.1",
+				Method:               http.MethodPost,
+				Resource:             "/translate",
+				StatusCode:           http.StatusOK,
+				SourceLanguage:       "en",
+				TargetLanguage:       "es",
+				Characters:           11,
+				TranslatedCharacters: 13,
+				Segments:             2,
+				FreshSegments:        2,
+				FreshCharacters:      10,
+			},
+		},
+		{
+			name:        "Translation with text",
+			includeText: true,
+			body:        `{"source_language":"en","target_language":"es","text":"Hello"}`,
+			expected: AuditRecord{
+				RequestID:            "request-1",
+				Caller:               "key-1",
+				SourceIP:             "192.0.2.1",
+				Method:               http.MethodPost,
+				Resource:             "/translate",
+				StatusCode:           http.StatusOK,
+				SourceLanguage:       "en",
+				TargetLanguage:       "es",
+				Characters:           5,
+				TranslatedCharacters: 5,
+				Segments:             1,
+				FreshSegments:        1,
+				FreshCharacters:      5,
+				Text:                 "Hello",
+				TranslatedText:       "Hola ",
+			},
+		},
+		{
+			name: "Invalid request",
+			body: `{"source_language":"en","text":"Hello"}`,
+			expected: AuditRecord{
+				RequestID:      "request-1",
+				Caller:         "key-1",
+				SourceIP:       "192.0.2.1",
+				Method:         http.MethodPost,
+				Resource:       "/translate",
+				StatusCode:     http.StatusBadRequest,
+				SourceLanguage: "en",
+				Characters:     5,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousStream, previousIncludeText := auditDeliveryStreamName, auditIncludeText
+			auditDeliveryStreamName, auditIncludeText = "audit", tt.includeText
+			defer func() { auditDeliveryStreamName, auditIncludeText = previousStream, previousIncludeText }()
+
+			var records []AuditRecord
+			h := newMockHandler(map[string]string{"Hello.": "Hola.", "Hello": "Hola", "Bye.": "Adiós."})
+			h.firehoseClient = &MockFirehoseClient{
+				PutRecordBatchFunc: func(ctx context.Context, params *firehose.PutRecordBatchInput, optFns ...func(*firehose.Options)) (*firehose.PutRecordBatchOutput, error) {
+					if aws.ToString(params.DeliveryStreamName) != "audit" {
+						t.Errorf("PutRecordBatch() delivery stream = %s, expected audit", aws.ToString(params.DeliveryStreamName))
+					}
+					for _, record := range params.Records {
+						var r AuditRecord
+						if err := json.Unmarshal(record.Data, &r); err != nil {
+							t.Fatalf("audit record %q isn't JSON: %v", record.Data, err)
+						}
+						records = append(records, r)
+					}
+					return &firehose.PutRecordBatchOutput{FailedPutCount: aws.Int32(0)}, nil
+				},
+			}
+
+			_, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+				HTTPMethod: http.MethodPost,
+				Resource:   "/translate",
+				Body:       tt.body,
+				RequestContext: events.APIGatewayProxyRequestContext{
+					RequestID: "request-1",
+					Identity:  events.APIGatewayRequestIdentity{APIKeyID: "key-1", SourceIP: "192.0.2.1"},
+				},
+			})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if len(records) != 1 {
+				t.Fatalf("handle() wrote %d audit records, expected 1", len(records))
+			}
+
+			got := records[0]
+			if got.Timestamp == "" {
+				t.Error("audit record timestamp is missing")
+			}
+			got.Timestamp = ""
+			if got != tt.expected {
+				t.Errorf("audit record = %+v, expected %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCallerFromEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		context  events.APIGatewayProxyRequestContext
+		expected string
+	}{
+		{
+			name: "Cognito subject",
+			context: events.APIGatewayProxyRequestContext{
+				Authorizer: map[string]any{"claims": map[string]any{"sub": "user-1"}},
+				Identity:   events.APIGatewayRequestIdentity{APIKeyID: "key-1"},
+			},
+			expected: "user-1",
+		},
+		{
+			name: "Lambda authorizer principal",
+			context: events.APIGatewayProxyRequestContext{
+				Authorizer: map[string]any{"principalId": "service-1"},
+			},
+			expected: "service-1",
+		},
+		{
+			name: "IAM user",
+			context: events.APIGatewayProxyRequestContext{
+				Identity: events.APIGatewayRequestIdentity{UserArn: "arn:aws:iam::123456789012:user/ops", APIKeyID: "key-1"},
+			},
+			expected: "arn:aws:iam::123456789012:user/ops",
+		},
+		{
+			name:     "API key",
+			context:  events.APIGatewayProxyRequestContext{Identity: events.APIGatewayRequestIdentity{APIKeyID: "key-1"}},
+			expected: "key-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := callerFromEvent(events.APIGatewayProxyRequest{RequestContext: tt.context}); got != tt.expected {
+				t.Errorf("callerFromEvent() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
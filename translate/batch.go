@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	batchResource = "/translate/batch"
+
+	// maxBatchItems is the number of documents of a batch request
+	maxBatchItems = 100
+	// defaultBatchConcurrency is the number of documents of a batch translated at once
+	defaultBatchConcurrency = 4
+)
+
+// batchConcurrency is the number of documents of a batch translated at once, each translating
+// its sentences with up to maxConcurrentTranslations provider calls
+var batchConcurrency int
+
+// BatchRequest is the body of a batch request, translating independent documents in one call
+type BatchRequest struct {
+	// Items are the documents to translate, each with its own languages, format and options
+	Items []BatchItem `json:"items"`
+}
+
+// BatchItem is a document of a batch request
+type BatchItem struct {
+	// ID identifies the item in the results, its index when omitted
+	ID      string           `json:"id"`
+	Request TranslateRequest `json:"request"`
+}
+
+// BatchResponse is the response of a batch request, with the result of every item in the
+// order of the request
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+	// Succeeded and Failed count the items translated and those that failed
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// BatchResult is the result of an item of a batch request
+type BatchResult struct {
+	ID string `json:"id"`
+	// StatusCode is the status code the item would have been answered with on its own
+	StatusCode int `json:"status_code"`
+	// Response is the translation of a successful item
+	Response *TranslateResponse `json:"response,omitempty"`
+	// Error is the error of a failed item, the body of its response
+	Error string `json:"error,omitempty"`
+}
+
+// handleBatchRequest translates the documents of a batch request, a few at once and with the
+// bulk priority unless an item sets its own. An item that fails doesn't fail the batch, its
+// result carries the error it would have been answered with.
+func (h *handler) handleBatchRequest(ctx context.Context, event events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	var request BatchRequest
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusBadRequest, Body: "Invalid request format"}
+	}
+	errs := &validationError{}
+	switch {
+	case len(request.Items) == 0:
+		errs.add("items", "items is required")
+	case len(request.Items) > maxBatchItems:
+		errs.add("items", "items must not exceed %d documents", maxBatchItems)
+	}
+	if len(errs.Fields) > 0 {
+		return validationErrorResponse(errs)
+	}
+
+	caller, _ := entitlementFromEvent(event)
+	concurrency := batchConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	response := BatchResponse{Results: make([]BatchResult, len(request.Items))}
+	var group errgroup.Group
+	group.SetLimit(concurrency)
+	for i, item := range request.Items {
+		if item.ID == "" {
+			item.ID = strconv.Itoa(i)
+		}
+		if item.Request.TargetLanguage == "" {
+			item.Request.TargetLanguage = caller.DefaultTargetLanguage
+		}
+		if item.Request.Priority == "" {
+			item.Request.Priority = priorityBulk
+		}
+		group.Go(func() error {
+			response.Results[i] = h.translateBatchItem(ctx, item)
+			return nil
+		})
+	}
+	group.Wait()
+
+	for _, result := range response.Results {
+		if result.Response != nil {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+	return newRegistryResponse(http.StatusOK, response)
+}
+
+// translateBatchItem translates an item of a batch request, audited as a request of its own
+func (h *handler) translateBatchItem(ctx context.Context, item BatchItem) (result BatchResult) {
+	ctx, audit := withItemAudit(ctx)
+	result = BatchResult{ID: item.ID}
+	// An item runs on a goroutine of its own, its panic only fails the item
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			response := panicResponse(logPanic(item.ID, recovered))
+			result.StatusCode, result.Response, result.Error = response.StatusCode, nil, response.Body
+		}
+	}()
+
+	response, err := h.handleTranslateRequest(ctx, item.Request)
+	if err != nil {
+		log.Printf("Error translating batch item %s: %v", item.ID, err)
+		response = events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError, Body: "Error during translation"}
+	}
+	h.writeAuditRecord(ctx, audit, response)
+
+	result.StatusCode = response.StatusCode
+	if response.StatusCode != http.StatusOK {
+		result.Error = response.Body
+		return result
+	}
+	var translated TranslateResponse
+	if err := json.Unmarshal([]byte(response.Body), &translated); err != nil {
+		log.Printf("Error reading the translation of batch item %s: %v", item.ID, err)
+		result.StatusCode, result.Error = http.StatusInternalServerError, "Error during translation"
+		return result
+	}
+	result.Response = &translated
+	return result
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestBatchRequest(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name: "Items in the order of the request",
+			body: `{"items":[{"id":"greeting","request":{"source_language":"en","target_language":"es","text":"Hello."}},` +
+				`{"request":{"source_language":"en","target_language":"es","text":"Goodbye."}}]}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body: `{"results":[{"id":"greeting","status_code":200,"response":{"translated_text":"Hola. "}},` +
+					`{"id":"1","status_code":200,"response":{"translated_text":"Adiós. "}}],"succeeded":2,"failed":0}`,
+			},
+		},
+		{
+			name: "Failed item doesn't fail the batch",
+			body: `{"items":[{"id":"a","request":{"source_language":"en","target_language":"es","text":"Hello."}},` +
+				`{"id":"b","request":{"source_language":"en","target_language":"es"}}]}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body: `{"results":[{"id":"a","status_code":200,"response":{"translated_text":"Hola. "}},` +
+					`{"id":"b","status_code":400,"error":"{\"error\":\"Invalid request\",\"fields\":[{\"field\":\"text\",\"message\":\"text is required\"}]}"}],"succeeded":1,"failed":1}`,
+			},
+		},
+		{
+			name: "No items",
+			body: `{"items":[]}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"items","message":"items is required"}]}`,
+			},
+		},
+		{
+			name: "Invalid body",
+			body: `{"items":`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "Invalid request format",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(map[string]string{"Hello.": "Hola.", "Goodbye.": "Adiós."})
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+				Resource:   batchResource,
+				HTTPMethod: http.MethodPost,
+				Body:       tt.body,
+			})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"github.com/sony/gobreaker/v2"
+)
+
+const (
+	// breakerInterval is how often the failure counts of a closed breaker are reset
+	breakerInterval = time.Minute
+	// breakerHalfOpenRequests is the number of probe requests let through a half-open breaker
+	breakerHalfOpenRequests = 1
+)
+
+// dependencyUnavailable is returned when a dependency's circuit breaker is open
+type dependencyUnavailable struct {
+	// Dependency is the name of the unavailable dependency
+	Dependency string
+	// RetryAfter is how long until the breaker lets a probe request through
+	RetryAfter time.Duration
+}
+
+func (d *dependencyUnavailable) Error() string {
+	return fmt.Sprintf("%s is unavailable, retry after %s", d.Dependency, d.RetryAfter)
+}
+
+// newCircuitBreaker creates a breaker that opens once at least breakerMinRequests calls were
+// made in the interval and breakerFailurePercent of them failed
+func newCircuitBreaker(name string) *gobreaker.CircuitBreaker[any] {
+	return gobreaker.NewCircuitBreaker[any](gobreaker.Settings{
+		Name:        name,
+		MaxRequests: breakerHalfOpenRequests,
+		Interval:    breakerInterval,
+		Timeout:     time.Duration(breakerOpenSeconds) * time.Second,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.Requests >= uint32(breakerMinRequests) &&
+				counts.TotalFailures*100 >= counts.Requests*uint32(breakerFailurePercent)
+		},
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			log.Printf("Circuit breaker %s changed from %s to %s", name, from, to)
+		},
+		IsSuccessful: isDependencyHealthy,
+	})
+}
+
+// imposedTimeout is the error of a call cut by a deadline the request set itself
+type imposedTimeout struct {
+	err error
+}
+
+func (e *imposedTimeout) Error() string { return e.err.Error() }
+
+func (e *imposedTimeout) Unwrap() error { return e.err }
+
+// isDependencyHealthy reports whether the call outcome says nothing bad about the dependency.
+// Texts the provider can't translate, cancelled calls and calls cut by the deadline of the
+// request are the caller's problem.
+func isDependencyHealthy(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.As(err, new(*imposedTimeout)) {
+		return true
+	}
+	// A failed condition is an answer from a healthy table
+	if errors.As(err, new(*dynamoTypes.ConditionalCheckFailedException)) {
+		return true
+	}
+	_, permanent := permanentTranslateFailure(err)
+	return permanent
+}
+
+// executeWithBreaker runs the call through the breaker, converting a rejected call into a
+// dependencyUnavailable error
+func executeWithBreaker[T any](ctx context.Context, breaker *gobreaker.CircuitBreaker[any], call func() (T, error)) (T, error) {
+	result, err := breaker.Execute(func() (any, error) {
+		result, err := call()
+		if err != nil && imposedDeadlineExceeded(ctx) {
+			return result, &imposedTimeout{err: err}
+		}
+		return result, err
+	})
+	if timeout, ok := err.(*imposedTimeout); ok {
+		err = timeout.err
+	}
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		var zero T
+		return zero, &dependencyUnavailable{
+			Dependency: breaker.Name(),
+			RetryAfter: time.Duration(breakerOpenSeconds) * time.Second,
+		}
+	}
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result.(T), nil
+}
+
+// breakerTranslateClient is a TranslateClient guarded by a circuit breaker
+type breakerTranslateClient struct {
+	client  TranslateClient
+	breaker *gobreaker.CircuitBreaker[any]
+}
+
+func newBreakerTranslateClient(client TranslateClient) *breakerTranslateClient {
+	return &breakerTranslateClient{client: client, breaker: newCircuitBreaker("translate")}
+}
+
+func (c *breakerTranslateClient) TranslateText(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+	return executeWithBreaker(ctx, c.breaker, func() (*translate.TranslateTextOutput, error) {
+		return c.client.TranslateText(ctx, params, optFns...)
+	})
+}
+
+func (c *breakerTranslateClient) ListLanguages(ctx context.Context, params *translate.ListLanguagesInput, optFns ...func(*translate.Options)) (*translate.ListLanguagesOutput, error) {
+	return executeWithBreaker(ctx, c.breaker, func() (*translate.ListLanguagesOutput, error) {
+		return c.client.ListLanguages(ctx, params, optFns...)
+	})
+}
+
+// breakerDynamoDBClient is a DynamoDBClient guarded by a circuit breaker
+type breakerDynamoDBClient struct {
+	client  DynamoDBClient
+	breaker *gobreaker.CircuitBreaker[any]
+}
+
+func newBreakerDynamoDBClient(client DynamoDBClient) *breakerDynamoDBClient {
+	return &breakerDynamoDBClient{client: client, breaker: newCircuitBreaker("dynamodb")}
+}
+
+func (c *breakerDynamoDBClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return executeWithBreaker(ctx, c.breaker, func() (*dynamodb.GetItemOutput, error) {
+		return c.client.GetItem(ctx, params, optFns...)
+	})
+}
+
+func (c *breakerDynamoDBClient) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	return executeWithBreaker(ctx, c.breaker, func() (*dynamodb.PutItemOutput, error) {
+		return c.client.PutItem(ctx, params, optFns...)
+	})
+}
+
+func (c *breakerDynamoDBClient) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	return executeWithBreaker(ctx, c.breaker, func() (*dynamodb.ScanOutput, error) {
+		return c.client.Scan(ctx, params, optFns...)
+	})
+}
+
+func (c *breakerDynamoDBClient) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	return executeWithBreaker(ctx, c.breaker, func() (*dynamodb.UpdateItemOutput, error) {
+		return c.client.UpdateItem(ctx, params, optFns...)
+	})
+}
+
+func (c *breakerDynamoDBClient) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	return executeWithBreaker(ctx, c.breaker, func() (*dynamodb.QueryOutput, error) {
+		return c.client.Query(ctx, params, optFns...)
+	})
+}
+
+func (c *breakerDynamoDBClient) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	return executeWithBreaker(ctx, c.breaker, func() (*dynamodb.DeleteItemOutput, error) {
+		return c.client.DeleteItem(ctx, params, optFns...)
+	})
+}
+
+// unavailableResponse returns a 503 response when the error was caused by an open breaker
+func unavailableResponse(err error) (events.APIGatewayProxyResponse, bool) {
+	var unavailable *dependencyUnavailable
+	if !errors.As(err, &unavailable) {
+		return events.APIGatewayProxyResponse{}, false
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusServiceUnavailable,
+		Headers: map[string]string{
+			"Retry-After": strconv.Itoa(int(unavailable.RetryAfter.Seconds())),
+		},
+		Body: "Service temporarily unavailable",
+	}, true
+}
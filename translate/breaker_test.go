@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+)
+
+func TestIsDependencyHealthy(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "No error", err: nil, expected: true},
+		{name: "Cancelled call", err: context.Canceled, expected: true},
+		{name: "Call cut by the deadline of the request", err: &imposedTimeout{err: context.DeadlineExceeded}, expected: true},
+		{name: "Call timed out", err: context.DeadlineExceeded, expected: false},
+		{name: "Unsupported language pair", err: &types.UnsupportedLanguagePairException{}, expected: true},
+		{name: "Service error", err: &types.InternalServerException{}, expected: false},
+		{name: "Generic error", err: fmt.Errorf("mock error"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDependencyHealthy(tt.err); got != tt.expected {
+				t.Errorf("isDependencyHealthy() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBreakerTranslateClient(t *testing.T) {
+	tests := []struct {
+		name          string
+		failures      int
+		expectedOpen  bool
+		expectedCalls int
+	}{
+		{
+			name:          "Breaker stays closed below the minimum requests",
+			failures:      defaultBreakerMinRequests - 1,
+			expectedOpen:  false,
+			expectedCalls: defaultBreakerMinRequests,
+		},
+		{
+			name:          "Breaker opens once the failure rate is reached",
+			failures:      defaultBreakerMinRequests,
+			expectedOpen:  true,
+			expectedCalls: defaultBreakerMinRequests,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			client := newBreakerTranslateClient(&MockTranslateClient{
+				TranslateTextFunc: func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+					calls++
+					return nil, fmt.Errorf("mock error")
+				},
+			})
+
+			for i := 0; i < tt.failures; i++ {
+				client.TranslateText(context.Background(), &translate.TranslateTextInput{})
+			}
+
+			_, err := client.TranslateText(context.Background(), &translate.TranslateTextInput{})
+			var unavailable *dependencyUnavailable
+			if gotOpen := errors.As(err, &unavailable); gotOpen != tt.expectedOpen {
+				t.Errorf("TranslateText() error = %v, expected open %v", err, tt.expectedOpen)
+			}
+			if calls != tt.expectedCalls {
+				t.Errorf("TranslateText() reached the client %d times, expected %d", calls, tt.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestBreakerIgnoresImposedDeadlines(t *testing.T) {
+	tests := []struct {
+		name         string
+		withDeadline func(ctx context.Context, deadline time.Time) (context.Context, context.CancelFunc)
+		expectedOpen bool
+	}{
+		{
+			name: "Calls cut by the deadline of the request",
+			withDeadline: func(ctx context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
+				return (&requestDeadline{deadline: deadline}).providerContext(ctx)
+			},
+			expectedOpen: false,
+		},
+		{
+			name:         "Calls timing out on their own",
+			withDeadline: context.WithDeadline,
+			expectedOpen: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newBreakerTranslateClient(&MockTranslateClient{
+				TranslateTextFunc: func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+					if _, ok := ctx.Deadline(); !ok {
+						return &translate.TranslateTextOutput{}, nil
+					}
+					<-ctx.Done()
+					return nil, ctx.Err()
+				},
+			})
+
+			for i := 0; i < defaultBreakerMinRequests; i++ {
+				ctx, cancel := tt.withDeadline(context.Background(), time.Now().Add(5*time.Millisecond))
+				_, err := client.TranslateText(ctx, &translate.TranslateTextInput{})
+				cancel()
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("TranslateText() error = %v, expected %v", err, context.DeadlineExceeded)
+				}
+			}
+
+			_, err := client.TranslateText(context.Background(), &translate.TranslateTextInput{})
+			var unavailable *dependencyUnavailable
+			if gotOpen := errors.As(err, &unavailable); gotOpen != tt.expectedOpen {
+				t.Errorf("TranslateText() error = %v, expected open %v", err, tt.expectedOpen)
+			}
+		})
+	}
+}
+
+func TestHandleUnavailable(t *testing.T) {
+	tests := []struct {
+		name             string
+		listError        error
+		translateError   error
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name:      "Language check rejected by the breaker",
+			listError: &dependencyUnavailable{Dependency: "translate", RetryAfter: 30 * time.Second},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusServiceUnavailable,
+				Headers:    map[string]string{"Retry-After": "30"},
+				Body:       "Service temporarily unavailable",
+			},
+		},
+		{
+			name:           "Translation rejected by the breaker",
+			translateError: &dependencyUnavailable{Dependency: "translate", RetryAfter: 30 * time.Second},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusServiceUnavailable,
+				Headers:    map[string]string{"Retry-After": "30"},
+				Body:       "Service temporarily unavailable",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(nil)
+			mockClient := h.translateClient.(*MockTranslateClient)
+			listLanguages := mockClient.ListLanguagesFunc
+			mockClient.ListLanguagesFunc = func(ctx context.Context, params *translate.ListLanguagesInput, optFns ...func(*translate.Options)) (*translate.ListLanguagesOutput, error) {
+				if tt.listError != nil {
+					return nil, tt.listError
+				}
+				return listLanguages(ctx, params, optFns...)
+			}
+			mockClient.TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				return nil, tt.translateError
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+				Body: `{"source_language":"en","target_language":"es","text":"Hello"}`,
+			})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body || got.Headers["Retry-After"] != tt.expectedResponse.Headers["Retry-After"] {
+				t.Errorf("handle() = %v, expected %v", got, tt.expectedResponse)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// responseReserve is the time kept from the translation stages before the invocation
+	// deadline, to assemble the response and drain the cache writes
+	responseReserve = 500 * time.Millisecond
+	// returnMargin is the time kept from the cache writes before the invocation deadline, for
+	// the runtime to send the response once the handler returns
+	returnMargin = 100 * time.Millisecond
+	// lookupShare is the share of the time left to translate that the cache lookups of a batch
+	// of sentences may take, the rest being left to translate the misses
+	lookupShare = 0.5
+)
+
+// stageBudgets are the deadlines of the stages of a request, derived from the time Lambda
+// leaves the invocation so that a stage overrunning is cancelled rather than the function
+// being killed mid-write without a response. The calls a budget cuts aren't counted by the
+// breakers of the dependencies.
+type stageBudgets struct {
+	// translate is the deadline of the lookup and translation stages, the segments not
+	// translated by then being served untranslated
+	translate time.Time
+	// cacheWrite is the deadline of the write-behind stage, the writes not done by then are
+	// dropped
+	cacheWrite time.Time
+}
+
+type stageBudgetsKey struct{}
+
+// withStageBudgets returns a context budgeting the stages of the request from its deadline,
+// unchanged when it has no deadline or its stages are already budgeted
+func withStageBudgets(ctx context.Context) context.Context {
+	deadline, ok := ctx.Deadline()
+	if !ok || stageBudgetsFromContext(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, stageBudgetsKey{}, &stageBudgets{
+		translate:  deadline.Add(-responseReserve),
+		cacheWrite: deadline.Add(-returnMargin),
+	})
+}
+
+// stageBudgetsFromContext returns the stage budgets of the request, nil when it has none
+func stageBudgetsFromContext(ctx context.Context) *stageBudgets {
+	b, _ := ctx.Value(stageBudgetsKey{}).(*stageBudgets)
+	return b
+}
+
+// lookupContext returns the context of the cache lookups of a batch of sentences, cancelled
+// once they took their share of the time left to translate
+func (b *stageBudgets) lookupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b == nil {
+		return ctx, func() {}
+	}
+	left := time.Until(b.translate)
+	return withImposedDeadline(ctx, time.Now().Add(time.Duration(float64(left)*lookupShare)))
+}
+
+// cacheWriteContext returns the context of the write-behind stage, cancelled at its deadline
+func (b *stageBudgets) cacheWriteContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b == nil {
+		return ctx, func() {}
+	}
+	return withImposedDeadline(ctx, b.cacheWrite)
+}
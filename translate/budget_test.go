@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/sony/gobreaker/v2"
+)
+
+func TestWithStageBudgets(t *testing.T) {
+	if got := stageBudgetsFromContext(withStageBudgets(context.Background())); got != nil {
+		t.Errorf("withStageBudgets() without deadline = %+v, expected none", got)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	ctx = withStageBudgets(ctx)
+	expected := stageBudgets{translate: deadline.Add(-responseReserve), cacheWrite: deadline.Add(-returnMargin)}
+	if got := stageBudgetsFromContext(ctx); got == nil || *got != expected {
+		t.Errorf("withStageBudgets() = %+v, expected %+v", got, expected)
+	}
+
+	// The stages are budgeted once, from the deadline of the invocation
+	shorter, cancelShorter := context.WithTimeout(ctx, time.Second)
+	defer cancelShorter()
+	if got := stageBudgetsFromContext(withStageBudgets(shorter)); got == nil || *got != expected {
+		t.Errorf("withStageBudgets() of a derived context = %+v, expected %+v", got, expected)
+	}
+}
+
+func TestStageBudgets(t *testing.T) {
+	tests := []struct {
+		name             string
+		timeout          time.Duration
+		slowLookups      bool
+		slowWrites       bool
+		expectedResponse string
+		expectedWriteErr error
+	}{
+		{
+			name:             "Enough time left",
+			timeout:          5 * time.Second,
+			expectedResponse: `{"translated_text":"Hola. "}`,
+		},
+		{
+			name:             "Invocation about to time out",
+			timeout:          responseReserve + deadlineMargin/2,
+			expectedResponse: `{"translated_text":"Hello. ","timed_out":true,"segments":[{"source_text":"Hello.","translated_text":"Hello.","timed_out":true}]}`,
+		},
+		{
+			name:             "Lookups overrunning their budget",
+			timeout:          responseReserve + 600*time.Millisecond,
+			slowLookups:      true,
+			expectedResponse: `{"translated_text":"Hola. "}`,
+		},
+		{
+			name:             "Cache writes overrunning their budget",
+			timeout:          responseReserve + 500*time.Millisecond,
+			slowWrites:       true,
+			expectedResponse: `{"translated_text":"Hola. "}`,
+			expectedWriteErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(map[string]string{"Hello.": "Hola."})
+			mock := h.dynamoClient.(*MockDynamoDBClient)
+			if tt.slowLookups {
+				// The lookups of the response and of its sentences block, not that of the languages
+				mock.GetItemFunc = func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					hash := params.Key["hash"].(*types.AttributeValueMemberS).Value
+					if hash != getCacheKey("en", "es", "Hello.") && !strings.HasPrefix(hash, responseCachePrefix) {
+						return &dynamodb.GetItemOutput{}, nil
+					}
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+			}
+			var (
+				mu       sync.Mutex
+				writeErr error
+			)
+			if tt.slowWrites {
+				mock.PutItemFunc = func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					if _, ok := params.Item["translated_text"]; !ok {
+						return &dynamodb.PutItemOutput{}, nil
+					}
+					<-ctx.Done()
+					mu.Lock()
+					writeErr = ctx.Err()
+					mu.Unlock()
+					return nil, ctx.Err()
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			got, err := h.handle(ctx, events.APIGatewayProxyRequest{Body: `{"source_language":"en","target_language":"es","text":"Hello."}`})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if ctx.Err() != nil {
+				t.Errorf("handle() returned after the deadline of the invocation")
+			}
+			if got.StatusCode != http.StatusOK || got.Body != tt.expectedResponse {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, http.StatusOK, tt.expectedResponse)
+			}
+			if !errors.Is(writeErr, tt.expectedWriteErr) {
+				t.Errorf("handle() cache write error = %v, expected %v", writeErr, tt.expectedWriteErr)
+			}
+		})
+	}
+}
+
+func TestStageBudgetsKeepBreakerClosed(t *testing.T) {
+	tests := []struct {
+		name        string
+		slowLookups bool
+		slowWrites  bool
+	}{
+		{name: "Lookups overrunning their budget", slowLookups: true},
+		{name: "Cache writes overrunning their budget", slowWrites: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(map[string]string{"Hello.": "Hola."})
+			mock := h.dynamoClient.(*MockDynamoDBClient)
+			if tt.slowLookups {
+				mock.GetItemFunc = func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					hash := params.Key["hash"].(*types.AttributeValueMemberS).Value
+					if hash != getCacheKey("en", "es", "Hello.") && !strings.HasPrefix(hash, responseCachePrefix) {
+						return &dynamodb.GetItemOutput{}, nil
+					}
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+			}
+			if tt.slowWrites {
+				mock.PutItemFunc = func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					if _, ok := params.Item["translated_text"]; !ok {
+						return &dynamodb.PutItemOutput{}, nil
+					}
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+			}
+			client := newBreakerDynamoDBClient(mock)
+			h.dynamoClient = client
+
+			for i := 0; i < defaultBreakerMinRequests; i++ {
+				ctx, cancel := context.WithTimeout(context.Background(), responseReserve+200*time.Millisecond)
+				got, err := h.handle(ctx, events.APIGatewayProxyRequest{Body: `{"source_language":"en","target_language":"es","text":"Hello."}`})
+				cancel()
+				if err != nil || got.StatusCode != http.StatusOK {
+					t.Fatalf("handle() = %d %s, %v, expected %d", got.StatusCode, got.Body, err, http.StatusOK)
+				}
+			}
+
+			if counts := client.breaker.Counts(); counts.Requests < uint32(defaultBreakerMinRequests) {
+				t.Errorf("breaker counted %d calls, expected at least %d", counts.Requests, defaultBreakerMinRequests)
+			}
+			if state := client.breaker.State(); state != gobreaker.StateClosed {
+				t.Errorf("breaker state = %s, expected %s", state, gobreaker.StateClosed)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// cacheOverflowPrefix is the S3 prefix holding the text of overflowed cache items
+const cacheOverflowPrefix = "cache-overflow/"
+
+const (
+	// providerAWSTranslate, providerSageMaker, providerDeepL, providerTMX and providerPostEdit
+	// are the provenance of the cache items, translated by AWS Translate, our own model or
+	// DeepL, imported from a translation memory or approved in a post-editing session
+	providerAWSTranslate = "aws-translate"
+	providerSageMaker    = "sagemaker"
+	providerDeepL        = "deepl"
+	providerTMX          = "tmx"
+	providerPostEdit     = "post-edit"
+)
+
+// overflowText is the text of a cache item stored in S3
+type overflowText struct {
+	SourceText     string `json:"source_text"`
+	TranslatedText string `json:"translated_text"`
+}
+
+// peerCache is a replica of the cache table in another region of a global table
+type peerCache struct {
+	region    string
+	tableName string
+	client    DynamoDBClient
+}
+
+// lookupCache returns the cached translation of the text, consulting the peer replicas on a
+// local miss and loading the text of overflowed items from S3
+func (h *handler) lookupCache(ctx context.Context, sourceLanguage, targetLanguage, text string) (CacheItem, bool, error) {
+	defer recordStage(ctx, stageCacheLookup, time.Now())
+
+	// The items cached from auto don't record the language detected by the provider, which
+	// is then only checked by translating the text again
+	if sourceLanguage == autoDetectLanguage && checksDetectedLanguage(ctx, targetLanguage) {
+		recordCacheLookup(ctx, false)
+		return CacheItem{}, false, nil
+	}
+
+	// Styled translations are cached apart from the plain ones
+	targetLanguage = cacheTargetLanguage(ctx, targetLanguage)
+
+	cacheItem, useCache, err := shouldCacheBeUsed(ctx, h.dynamoClient, sourceLanguage, targetLanguage, text)
+	if err != nil {
+		return CacheItem{}, false, err
+	}
+
+	if !useCache {
+		// The item may have been written in another region and not replicated yet
+		cacheItem, useCache = h.readRepair(ctx, getCacheKey(sourceLanguage, targetLanguage, text))
+	}
+
+	// Failures are only remembered until they expire, the table TTL removes them lazily
+	if useCache && cacheItem.Failure != "" && cacheItem.ExpiresAt <= time.Now().Unix() {
+		recordCacheLookup(ctx, false)
+		return CacheItem{}, false, nil
+	}
+
+	if useCache && cacheItem.OverflowKey != "" {
+		cacheItem, err = h.resolveCacheItem(ctx, cacheItem)
+		if err != nil {
+			// Treat a missing overflow object as a miss, the translation will be stored again
+			log.Printf("Error loading overflowed cache item %s: %v", cacheItem.Hash, err)
+			recordCacheLookup(ctx, false)
+			return CacheItem{}, false, nil
+		}
+	}
+
+	recordCacheLookup(ctx, useCache)
+	// A dry run doesn't serve the translation
+	if useCache && cacheItem.Failure == "" && dryRunFromContext(ctx) == nil {
+		h.recordCacheHit(ctx, cacheItem.Hash)
+	}
+	return cacheItem, useCache, nil
+}
+
+// cacheReverseTranslation caches the translation of the item in the opposite direction as a
+// derived item. Translations from a detected language have no direction to reverse.
+func (h *handler) cacheReverseTranslation(ctx context.Context, item CacheItem) {
+	if item.SourceLanguage == autoDetectLanguage || item.Failure != "" {
+		return
+	}
+
+	reverse := CacheItem{
+		Hash:            getCacheKey(item.TargetLanguage, item.SourceLanguage, item.TranslatedText),
+		TranslatedText:  item.SourceText,
+		SourceText:      item.TranslatedText,
+		SourceLanguage:  item.TargetLanguage,
+		TargetLanguage:  item.SourceLanguage,
+		Region:          item.Region,
+		Provider:        item.Provider,
+		TranslateRegion: item.TranslateRegion,
+		Route:           item.Route,
+		Derived:         true,
+	}
+
+	// The translation is already cached, a missing reverse item only costs a later miss
+	if err := h.cacheTranslation(ctx, reverse); err != nil {
+		log.Printf("Error caching reverse translation %s: %v", reverse.Hash, err)
+	}
+}
+
+// storeCacheItem caches the item, moving its text to S3 when the item would be too large
+// for DynamoDB. Items are only overflowed when an overflow bucket is configured.
+func (h *handler) storeCacheItem(ctx context.Context, item CacheItem) error {
+	defer recordStage(ctx, stageCacheStore, time.Now())
+
+	// The content hashes and lengths are kept when the text is overflowed
+	item = withContentHashes(item)
+	if item.CreatedAt == 0 {
+		item.CreatedAt = time.Now().Unix()
+	}
+	item.SourceCharacters = utf8.RuneCountInString(item.SourceText)
+	item.TranslatedCharacters = utf8.RuneCountInString(item.TranslatedText)
+	item = withSourceRetention(item)
+
+	if cacheOverflowBucket != "" && len(item.SourceText)+len(item.TranslatedText) > cacheOverflowThreshold {
+		body, err := json.Marshal(overflowText{
+			SourceText:     item.SourceText,
+			TranslatedText: item.TranslatedText,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal overflow text: %w", err)
+		}
+
+		overflowKey := cacheOverflowPrefix + item.Hash
+		_, err = h.s3Client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(cacheOverflowBucket),
+			Key:         aws.String(overflowKey),
+			Body:        bytes.NewReader(body),
+			ContentType: aws.String("application/json"),
+		})
+		if err != nil {
+			return fmt.Errorf("error storing overflow text: %w", err)
+		}
+
+		item.SourceText = ""
+		item.TranslatedText = ""
+		item.OverflowKey = overflowKey
+	}
+
+	return cacheTranslatedText(ctx, h.dynamoClient, item)
+}
+
+// resolveCacheItem loads the text of an overflowed cache item from S3
+func (h *handler) resolveCacheItem(ctx context.Context, item CacheItem) (CacheItem, error) {
+	object, err := h.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(cacheOverflowBucket),
+		Key:    aws.String(item.OverflowKey),
+	})
+	if err != nil {
+		return item, err
+	}
+	defer object.Body.Close()
+
+	body, err := io.ReadAll(object.Body)
+	if err != nil {
+		return item, err
+	}
+
+	var text overflowText
+	if err := json.Unmarshal(body, &text); err != nil {
+		return item, fmt.Errorf("failed to unmarshal overflow text: %w", err)
+	}
+
+	item.SourceText = text.SourceText
+	item.TranslatedText = text.TranslatedText
+	return item, nil
+}
+
+// readRepair looks a missed item up in the peer replicas. Global tables replicate
+// asynchronously, so an item written in another region may not have reached this one yet.
+// A hit is written back to the local replica so later lookups don't need the peers, unless
+// the item has replicated in the meantime: its hits and ratings are then kept.
+func (h *handler) readRepair(ctx context.Context, hash string) (CacheItem, bool) {
+	for _, peer := range h.peerCaches {
+		cacheItem, found, err := getCacheItem(ctx, peer.client, peer.tableName, hash)
+		if err != nil {
+			// Peers are best effort, the translation can still be served without them
+			log.Printf("Error reading cache from %s: %v", peer.region, err)
+			continue
+		}
+		if !found {
+			continue
+		}
+
+		if err := putCacheItem(ctx, h.dynamoClient, cacheItem, true); err != nil {
+			log.Printf("Error repairing cache item from %s: %v", peer.region, err)
+		}
+		return cacheItem, true
+	}
+
+	return CacheItem{}, false
+}
+
+// parseRegionTableNames parses a list of region=table pairs separated by commas
+func parseRegionTableNames(value string) map[string]string {
+	tableNames := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		region, tableName, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || region == "" || tableName == "" {
+			continue
+		}
+		tableNames[strings.TrimSpace(region)] = strings.TrimSpace(tableName)
+	}
+	return tableNames
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestParseRegionTableNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]string
+	}{
+		{
+			name:  "Multiple regions",
+			input: "us-east-1=TranslateCache, eu-west-1=TranslateCacheEU",
+			expected: map[string]string{
+				"us-east-1": "TranslateCache",
+				"eu-west-1": "TranslateCacheEU",
+			},
+		},
+		{
+			name:     "Malformed pairs are ignored",
+			input:    "us-east-1,=TranslateCache,eu-west-1=",
+			expected: map[string]string{},
+		},
+		{
+			name:     "Empty input",
+			input:    "",
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseRegionTableNames(tt.input)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseRegionTableNames() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadRepair(t *testing.T) {
+	peerItem := map[string]dynamoTypes.AttributeValue{
+		"hash":            &dynamoTypes.AttributeValueMemberS{Value: "test-hash"},
+		"translated_text": &dynamoTypes.AttributeValueMemberS{Value: "Hola"},
+		"source_text":     &dynamoTypes.AttributeValueMemberS{Value: "Hello"},
+		"source_language": &dynamoTypes.AttributeValueMemberS{Value: "en"},
+		"target_language": &dynamoTypes.AttributeValueMemberS{Value: "es"},
+		"region":          &dynamoTypes.AttributeValueMemberS{Value: "eu-west-1"},
+	}
+
+	tests := []struct {
+		name             string
+		peers            []map[string]dynamoTypes.AttributeValue
+		peerErrors       []error
+		repairError      error
+		expectedCache    CacheItem
+		expectedUse      bool
+		expectedRepaired bool
+	}{
+		{
+			name:             "No peers",
+			expectedCache:    CacheItem{},
+			expectedUse:      false,
+			expectedRepaired: false,
+		},
+		{
+			name:       "Hit in second peer is repaired",
+			peers:      []map[string]dynamoTypes.AttributeValue{nil, peerItem},
+			peerErrors: []error{fmt.Errorf("mock error"), nil},
+			expectedCache: CacheItem{
+				Hash:           "test-hash",
+				TranslatedText: "Hola",
+				SourceText:     "Hello",
+				SourceLanguage: "en",
+				TargetLanguage: "es",
+				Region:         "eu-west-1",
+			},
+			expectedUse:      true,
+			expectedRepaired: true,
+		},
+		{
+			name:        "Hit already replicated is kept",
+			peers:       []map[string]dynamoTypes.AttributeValue{peerItem},
+			peerErrors:  []error{nil},
+			repairError: &dynamoTypes.ConditionalCheckFailedException{},
+			expectedCache: CacheItem{
+				Hash:           "test-hash",
+				TranslatedText: "Hola",
+				SourceText:     "Hello",
+				SourceLanguage: "en",
+				TargetLanguage: "es",
+				Region:         "eu-west-1",
+			},
+			expectedUse:      true,
+			expectedRepaired: true,
+		},
+		{
+			name:             "Miss in every peer",
+			peers:            []map[string]dynamoTypes.AttributeValue{nil},
+			peerErrors:       []error{nil},
+			expectedCache:    CacheItem{},
+			expectedUse:      false,
+			expectedRepaired: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repaired := false
+			h := &handler{
+				dynamoClient: &MockDynamoDBClient{
+					PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+						repaired = true
+						if *params.TableName != translateTableName {
+							t.Errorf("readRepair() repaired table = %s, expected %s", *params.TableName, translateTableName)
+						}
+						if aws.ToString(params.ConditionExpression) != "attribute_not_exists(#hash)" {
+							t.Errorf("readRepair() condition = %q, expected the item to be written only if absent", aws.ToString(params.ConditionExpression))
+						}
+						if tt.repairError != nil {
+							return nil, tt.repairError
+						}
+						return &dynamodb.PutItemOutput{}, nil
+					},
+				},
+			}
+
+			for i, item := range tt.peers {
+				err := tt.peerErrors[i]
+				tableName := fmt.Sprintf("peer-table-%d", i)
+				h.peerCaches = append(h.peerCaches, peerCache{
+					region:    fmt.Sprintf("peer-region-%d", i),
+					tableName: tableName,
+					client: &MockDynamoDBClient{
+						GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+							if *params.TableName != tableName {
+								t.Errorf("readRepair() read table = %s, expected %s", *params.TableName, tableName)
+							}
+							if err != nil {
+								return nil, err
+							}
+							return &dynamodb.GetItemOutput{Item: item}, nil
+						},
+					},
+				})
+			}
+
+			gotCache, gotUse := h.readRepair(context.Background(), "test-hash")
+			if gotUse != tt.expectedUse {
+				t.Errorf("readRepair() useCache = %v, expected %v", gotUse, tt.expectedUse)
+			}
+
+			if gotCache != tt.expectedCache {
+				t.Errorf("readRepair() cacheItem = %v, expected %v", gotCache, tt.expectedCache)
+			}
+
+			if repaired != tt.expectedRepaired {
+				t.Errorf("readRepair() repaired = %v, expected %v", repaired, tt.expectedRepaired)
+			}
+		})
+	}
+}
+
+func TestStoreCacheItem(t *testing.T) {
+	tests := []struct {
+		name             string
+		bucket           string
+		threshold        int
+		item             CacheItem
+		expectedOverflow bool
+	}{
+		{
+			name:             "Small item is stored in DynamoDB",
+			bucket:           "overflow-bucket",
+			threshold:        100,
+			item:             CacheItem{Hash: "test-hash", SourceText: "Hello", TranslatedText: "Hola"},
+			expectedOverflow: false,
+		},
+		{
+			name:             "Large item overflows to S3",
+			bucket:           "overflow-bucket",
+			threshold:        5,
+			item:             CacheItem{Hash: "test-hash", SourceText: "Hello", TranslatedText: "Hola"},
+			expectedOverflow: true,
+		},
+		{
+			name:             "No overflow without a bucket",
+			bucket:           "",
+			threshold:        5,
+			item:             CacheItem{Hash: "test-hash", SourceText: "Hello", TranslatedText: "Hola"},
+			expectedOverflow: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, threshold := cacheOverflowBucket, cacheOverflowThreshold
+			cacheOverflowBucket, cacheOverflowThreshold = tt.bucket, tt.threshold
+			t.Cleanup(func() {
+				cacheOverflowBucket, cacheOverflowThreshold = bucket, threshold
+			})
+
+			var (
+				stored     map[string]dynamoTypes.AttributeValue
+				overflowed []byte
+			)
+			h := &handler{
+				dynamoClient: &MockDynamoDBClient{
+					PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+						stored = params.Item
+						return &dynamodb.PutItemOutput{}, nil
+					},
+				},
+				s3Client: &MockS3Client{
+					PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+						if *params.Key != cacheOverflowPrefix+tt.item.Hash {
+							t.Errorf("storeCacheItem() overflow key = %s", *params.Key)
+						}
+						overflowed, _ = io.ReadAll(params.Body)
+						return &s3.PutObjectOutput{}, nil
+					},
+				},
+			}
+
+			if err := h.storeCacheItem(context.Background(), tt.item); err != nil {
+				t.Errorf("storeCacheItem() error = %v", err)
+				return
+			}
+
+			gotItem, ok := cacheItemFromAttributes(stored)
+			if !ok {
+				t.Errorf("storeCacheItem() stored malformed item %v", stored)
+				return
+			}
+
+			if (gotItem.OverflowKey != "") != tt.expectedOverflow || (overflowed != nil) != tt.expectedOverflow {
+				t.Errorf("storeCacheItem() overflowed = %v, expected %v", gotItem.OverflowKey != "", tt.expectedOverflow)
+			}
+
+			if tt.expectedOverflow && (gotItem.SourceText != "" || gotItem.TranslatedText != "") {
+				t.Errorf("storeCacheItem() kept text in overflowed item %v", gotItem)
+			}
+		})
+	}
+}
+
+func TestLookupCacheOverflow(t *testing.T) {
+	overflowItem := map[string]dynamoTypes.AttributeValue{
+		"hash":            &dynamoTypes.AttributeValueMemberS{Value: "test-hash"},
+		"translated_text": &dynamoTypes.AttributeValueMemberS{Value: ""},
+		"source_text":     &dynamoTypes.AttributeValueMemberS{Value: ""},
+		"source_language": &dynamoTypes.AttributeValueMemberS{Value: "en"},
+		"target_language": &dynamoTypes.AttributeValueMemberS{Value: "es"},
+		"overflow_key":    &dynamoTypes.AttributeValueMemberS{Value: cacheOverflowPrefix + "test-hash"},
+	}
+
+	tests := []struct {
+		name          string
+		mockBody      string
+		mockError     error
+		expectedCache CacheItem
+		expectedUse   bool
+	}{
+		{
+			name:     "Overflow text is resolved",
+			mockBody: `{"source_text":"Hello","translated_text":"Hola"}`,
+			expectedCache: CacheItem{
+				Hash:           "test-hash",
+				TranslatedText: "Hola",
+				SourceText:     "Hello",
+				SourceLanguage: "en",
+				TargetLanguage: "es",
+				OverflowKey:    cacheOverflowPrefix + "test-hash",
+			},
+			expectedUse: true,
+		},
+		{
+			name:          "Missing overflow object is a miss",
+			mockError:     fmt.Errorf("mock error"),
+			expectedCache: CacheItem{},
+			expectedUse:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &handler{
+				dynamoClient: &MockDynamoDBClient{
+					GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+						return &dynamodb.GetItemOutput{Item: overflowItem}, nil
+					},
+				},
+				s3Client: &MockS3Client{
+					GetObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+						if tt.mockError != nil {
+							return nil, tt.mockError
+						}
+						return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(tt.mockBody))}, nil
+					},
+				},
+			}
+
+			gotCache, gotUse, err := h.lookupCache(context.Background(), "en", "es", "Hello")
+			if err != nil {
+				t.Errorf("lookupCache() error = %v", err)
+				return
+			}
+
+			if gotUse != tt.expectedUse {
+				t.Errorf("lookupCache() useCache = %v, expected %v", gotUse, tt.expectedUse)
+			}
+
+			if gotCache != tt.expectedCache {
+				t.Errorf("lookupCache() cacheItem = %v, expected %v", gotCache, tt.expectedCache)
+			}
+		})
+	}
+}
+
+func TestCacheReverseTranslation(t *testing.T) {
+	tests := []struct {
+		name           string
+		sourceLanguage string
+		expected       []CacheItem
+	}{
+		{
+			name:           "Reverse item is derived",
+			sourceLanguage: "en",
+			expected: []CacheItem{
+				{Hash: getCacheKey("en", "es", "Hello"), TranslatedText: "Hola", SourceText: "Hello", SourceLanguage: "en", TargetLanguage: "es", SourceHash: getHashFromText("Hello"), TranslatedHash: getHashFromText("Hola"), Provider: providerAWSTranslate, TranslateRegion: region, SourceCharacters: 5, TranslatedCharacters: 4},
+				{Hash: getCacheKey("es", "en", "Hola"), TranslatedText: "Hello", SourceText: "Hola", SourceLanguage: "es", TargetLanguage: "en", Derived: true, SourceHash: getHashFromText("Hola"), TranslatedHash: getHashFromText("Hello"), Provider: providerAWSTranslate, TranslateRegion: region, SourceCharacters: 4, TranslatedCharacters: 5},
+			},
+		},
+		{
+			name:           "Detected language is not reversed",
+			sourceLanguage: autoDetectLanguage,
+			expected: []CacheItem{
+				{Hash: getCacheKey(autoDetectLanguage, "es", "Hello"), TranslatedText: "Hola", SourceText: "Hello", SourceLanguage: autoDetectLanguage, TargetLanguage: "es", SourceHash: getHashFromText("Hello"), TranslatedHash: getHashFromText("Hola"), Provider: providerAWSTranslate, TranslateRegion: region, SourceCharacters: 5, TranslatedCharacters: 4},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := cacheReverseEntries
+			cacheReverseEntries = true
+			defer func() { cacheReverseEntries = previous }()
+
+			var stored []CacheItem
+
+			h := newMockHandler(map[string]string{"Hello": "Hola"})
+			h.dynamoClient.(*MockDynamoDBClient).PutItemFunc = func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				item, _ := cacheItemFromAttributes(params.Item)
+				item.Region, item.CreatedAt = "", 0
+				stored = append(stored, item)
+				return &dynamodb.PutItemOutput{}, nil
+			}
+
+			if _, err := h.translateToken(context.Background(), tt.sourceLanguage, "es", "Hello"); err != nil {
+				t.Errorf("translateToken() error = %v", err)
+				return
+			}
+
+			if !reflect.DeepEqual(stored, tt.expected) {
+				t.Errorf("translateToken() cached %+v, expected %+v", stored, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+const (
+	handlerModeCacheWriter = "cache-writer"
+
+	// maxCacheQueueMessageSize is the SQS message size limit, larger items are stored directly
+	maxCacheQueueMessageSize = 256 * 1024
+)
+
+// writeCacheItem publishes the item to the cache write queue when one is configured, leaving
+// the PutItem to the cache writer function. Items that can't be published are stored directly.
+func (h *handler) writeCacheItem(ctx context.Context, item CacheItem) error {
+	if h.sqsClient == nil || cacheWriteQueueURL == "" {
+		return h.storeCacheItem(ctx, item)
+	}
+
+	body, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache item: %w", err)
+	}
+	if len(body) > maxCacheQueueMessageSize {
+		return h.storeCacheItem(ctx, item)
+	}
+
+	_, err = h.sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(cacheWriteQueueURL),
+		MessageBody: aws.String(string(body)),
+	})
+	if err != nil {
+		log.Printf("Error publishing cache item %s, storing it directly: %v", item.Hash, err)
+		return h.storeCacheItem(ctx, item)
+	}
+
+	return nil
+}
+
+// handleCacheWriteEvent stores the cache items published to the cache write queue. Items
+// that fail, such as throttled writes, are reported back to be retried by the queue.
+func (h *handler) handleCacheWriteEvent(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+	defer flushTelemetry(ctx)
+
+	var response events.SQSEventResponse
+	for _, record := range event.Records {
+		var item CacheItem
+		if err := json.Unmarshal([]byte(record.Body), &item); err != nil || item.Hash == "" {
+			// Retrying a malformed message won't fix it
+			log.Printf("Dropping malformed cache item message %s: %v", record.MessageId, err)
+			continue
+		}
+
+		if err := h.storeCacheItem(ctx, item); err != nil {
+			log.Printf("Error caching item %s from message %s: %v", item.Hash, record.MessageId, err)
+			response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{
+				ItemIdentifier: record.MessageId,
+			})
+		}
+	}
+
+	return response, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+func TestWriteCacheItem(t *testing.T) {
+	tests := []struct {
+		name              string
+		queueURL          string
+		sendError         error
+		item              CacheItem
+		expectedPublished bool
+		expectedStored    bool
+	}{
+		{
+			name:           "Stored directly without a queue",
+			item:           CacheItem{Hash: "test-hash", TranslatedText: "Hola"},
+			expectedStored: true,
+		},
+		{
+			name:              "Published to the queue",
+			queueURL:          "https://sqs.us-east-1.amazonaws.com/123456789012/cache-writes",
+			item:              CacheItem{Hash: "test-hash", TranslatedText: "Hola"},
+			expectedPublished: true,
+		},
+		{
+			name:              "Stored directly when publishing fails",
+			queueURL:          "https://sqs.us-east-1.amazonaws.com/123456789012/cache-writes",
+			sendError:         fmt.Errorf("mock error"),
+			item:              CacheItem{Hash: "test-hash", TranslatedText: "Hola"},
+			expectedPublished: true,
+			expectedStored:    true,
+		},
+		{
+			name:           "Stored directly when too large for a message",
+			queueURL:       "https://sqs.us-east-1.amazonaws.com/123456789012/cache-writes",
+			item:           CacheItem{Hash: "test-hash", TranslatedText: strings.Repeat("a", maxCacheQueueMessageSize)},
+			expectedStored: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(queueURL string) { cacheWriteQueueURL = queueURL }(cacheWriteQueueURL)
+			cacheWriteQueueURL = tt.queueURL
+
+			var published, stored bool
+
+			h := newMockHandler(nil)
+			h.dynamoClient.(*MockDynamoDBClient).PutItemFunc = func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				stored = true
+				return &dynamodb.PutItemOutput{}, nil
+			}
+			h.sqsClient = &MockSQSClient{
+				SendMessageFunc: func(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+					published = true
+					if *params.QueueUrl != tt.queueURL {
+						t.Errorf("SendMessage() queue = %s, expected %s", *params.QueueUrl, tt.queueURL)
+					}
+					return &sqs.SendMessageOutput{}, tt.sendError
+				},
+			}
+
+			if err := h.writeCacheItem(context.Background(), tt.item); err != nil {
+				t.Errorf("writeCacheItem() error = %v", err)
+				return
+			}
+
+			if published != tt.expectedPublished || stored != tt.expectedStored {
+				t.Errorf("writeCacheItem() published = %v, stored = %v, expected %v, %v", published, stored, tt.expectedPublished, tt.expectedStored)
+			}
+		})
+	}
+}
+
+func TestHandleCacheWriteEvent(t *testing.T) {
+	event := events.SQSEvent{
+		Records: []events.SQSMessage{
+			{MessageId: "stored", Body: `{"Hash":"hash-1","TranslatedText":"Hola","SourceText":"Hello","SourceLanguage":"en","TargetLanguage":"es"}`},
+			{MessageId: "throttled", Body: `{"Hash":"hash-2","TranslatedText":"Adiós","SourceText":"Goodbye","SourceLanguage":"en","TargetLanguage":"es"}`},
+			{MessageId: "malformed", Body: `not json`},
+		},
+	}
+
+	var storedHashes []string
+
+	h := newMockHandler(nil)
+	h.dynamoClient.(*MockDynamoDBClient).PutItemFunc = func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+		item, _ := cacheItemFromAttributes(params.Item)
+		if item.Hash == "hash-2" {
+			return nil, fmt.Errorf("mock throttling error")
+		}
+		storedHashes = append(storedHashes, item.Hash)
+		return &dynamodb.PutItemOutput{}, nil
+	}
+
+	got, err := h.handleCacheWriteEvent(context.Background(), event)
+	if err != nil {
+		t.Errorf("handleCacheWriteEvent() error = %v", err)
+		return
+	}
+
+	if !slices.Equal(storedHashes, []string{"hash-1"}) {
+		t.Errorf("handleCacheWriteEvent() stored %v, expected [hash-1]", storedHashes)
+	}
+	if len(got.BatchItemFailures) != 1 || got.BatchItemFailures[0].ItemIdentifier != "throttled" {
+		t.Errorf("handleCacheWriteEvent() failures = %v, expected [throttled]", got.BatchItemFailures)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+const (
+	casingUpper    = "upper"
+	casingTitle    = "title"
+	casingSentence = "sentence"
+)
+
+// minorWordLength is the length of the words a title may keep lowercase, such as "of" and "the"
+const minorWordLength = 3
+
+type casingKey struct{}
+
+// withCasingPreservation returns a context whose translations take the casing of their source
+// sentences, unchanged unless enabled
+func withCasingPreservation(ctx context.Context, enabled bool) context.Context {
+	if !enabled {
+		return ctx
+	}
+	return context.WithValue(ctx, casingKey{}, true)
+}
+
+// casingPreservationFromContext reports whether the casing of the request is preserved
+func casingPreservationFromContext(ctx context.Context) bool {
+	enabled, _ := ctx.Value(casingKey{}).(bool)
+	return enabled
+}
+
+// preserveCasing applies the casing of each source sentence written in all caps, in title case
+// or in sentence case to its translation, which providers tend to lowercase. Target languages
+// without letter case are left as they are.
+func preserveCasing(targetLanguage string, sources, translations []string) {
+	upper := cases.Upper(language.Make(targetLanguage))
+	for i, source := range sources {
+		switch detectCasing(source) {
+		case casingUpper:
+			translations[i] = upper.String(translations[i])
+		case casingTitle:
+			translations[i] = titleCase(upper, translations[i])
+		case casingSentence:
+			translations[i] = capitalize(upper, translations[i])
+		}
+	}
+}
+
+// detectCasing returns the casing of the text: "upper" for at least two letters all in
+// uppercase, "title" for at least two capitalized words with only short words in lowercase,
+// "sentence" when the first letter is uppercase, and empty otherwise
+func detectCasing(text string) string {
+	var letters, upperLetters, words, capitalized int
+	title, firstUpper := true, false
+	for _, word := range strings.Fields(text) {
+		first, ok := firstLetter(word)
+		if !ok {
+			continue
+		}
+		words++
+		wordLetters := letterCount(word)
+		letters += wordLetters
+		for _, r := range word {
+			if unicode.IsUpper(r) {
+				upperLetters++
+			}
+		}
+
+		switch {
+		case unicode.IsUpper(first):
+			capitalized++
+			firstUpper = firstUpper || words == 1
+		case words == 1 || wordLetters > minorWordLength:
+			title = false
+		}
+	}
+
+	switch {
+	case letters >= 2 && upperLetters == letters:
+		return casingUpper
+	case title && capitalized >= 2:
+		return casingTitle
+	case firstUpper:
+		return casingSentence
+	}
+	return ""
+}
+
+// titleCase capitalizes the words of the text, keeping the short words after the first as
+// they are
+func titleCase(upper cases.Caser, text string) string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	rest := text
+	for i, word := range words {
+		index := strings.Index(rest, word)
+		b.WriteString(rest[:index])
+		rest = rest[index+len(word):]
+		if i == 0 || letterCount(word) > minorWordLength {
+			word = capitalize(upper, word)
+		}
+		b.WriteString(word)
+	}
+	b.WriteString(rest)
+	return b.String()
+}
+
+// capitalize uppercases the first letter of the text, keeping the others as they are
+func capitalize(upper cases.Caser, text string) string {
+	for i, r := range text {
+		if unicode.IsLetter(r) {
+			size := utf8.RuneLen(r)
+			return text[:i] + upper.String(text[i:i+size]) + text[i+size:]
+		}
+	}
+	return text
+}
+
+// firstLetter returns the first letter of the word, reporting whether it has one
+func firstLetter(word string) (rune, bool) {
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			return r, true
+		}
+	}
+	return 0, false
+}
+
+// letterCount returns the number of letters of the word
+func letterCount(word string) int {
+	count := 0
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestPreserveCasing(t *testing.T) {
+	tests := []struct {
+		name           string
+		targetLanguage string
+		source         string
+		translation    string
+		expected       string
+	}{
+		{
+			name:           "All caps",
+			targetLanguage: "es",
+			source:         "SAVE CHANGES",
+			translation:    "guardar cambios",
+			expected:       "GUARDAR CAMBIOS",
+		},
+		{
+			name:           "All caps in Turkish",
+			targetLanguage: "tr",
+			source:         "EDIT",
+			translation:    "düzenle ve imzala",
+			expected:       "DÜZENLE VE İMZALA",
+		},
+		{
+			name:           "Title case",
+			targetLanguage: "es",
+			source:         "Terms of Service",
+			translation:    "términos de servicio",
+			expected:       "Términos de Servicio",
+		},
+		{
+			name:           "Sentence case",
+			targetLanguage: "de",
+			source:         "Save",
+			translation:    "speichern",
+			expected:       "Speichern",
+		},
+		{
+			name:           "Sentence with a proper noun",
+			targetLanguage: "fr",
+			source:         "Ask John about the invoice.",
+			translation:    "demandez à John la facture.",
+			expected:       "Demandez à John la facture.",
+		},
+		{
+			name:           "Lowercase",
+			targetLanguage: "es",
+			source:         "save",
+			translation:    "Guardar",
+			expected:       "Guardar",
+		},
+		{
+			name:           "Target language without case",
+			targetLanguage: "ja",
+			source:         "SAVE",
+			translation:    "保存",
+			expected:       "保存",
+		},
+		{
+			name:           "Single capital letter",
+			targetLanguage: "es",
+			source:         "I",
+			translation:    "yo",
+			expected:       "Yo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			translations := []string{tt.translation}
+			preserveCasing(tt.targetLanguage, []string{tt.source}, translations)
+			if translations[0] != tt.expected {
+				t.Errorf("preserveCasing() = %q, expected %q", translations[0], tt.expected)
+			}
+		})
+	}
+}
+
+func TestDetectCasing(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{text: "OK", expected: casingUpper},
+		{text: "DELETE 3 FILES?", expected: casingUpper},
+		{text: "Add to Cart", expected: casingTitle},
+		{text: "Log In", expected: casingTitle},
+		{text: "The API is fast.", expected: casingSentence},
+		{text: "Go to settings", expected: casingSentence},
+		{text: "iPhone settings", expected: ""},
+		{text: "123", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := detectCasing(tt.text); got != tt.expected {
+				t.Errorf("detectCasing() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandlePreserveCasing(t *testing.T) {
+	h := newMockHandler(map[string]string{"SAVE": "guardar"})
+
+	got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+		Body: `{"source_language":"en","target_language":"es","text":"SAVE","preserve_casing":true}`,
+	})
+	if err != nil {
+		t.Fatalf("handle() error = %v", err)
+	}
+
+	expected := `{"translated_text":"GUARDAR "}`
+	if got.StatusCode != http.StatusOK || got.Body != expected {
+		t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, http.StatusOK, expected)
+	}
+}
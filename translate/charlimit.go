@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// maxRequestCharacters caps the characters of a request translated by the provider, zero for
+// no cap. Requests may lower it with max_characters.
+var maxRequestCharacters int
+
+// characterLimit caps the characters of a request translated by the provider. The cache
+// misses of a text are held back until all of its segments are looked up, and only sent to
+// the provider if the misses of the request so far fit in the limit.
+type characterLimit struct {
+	maxCharacters int64
+	usage         translationUsage
+}
+
+// characterLimitExceeded is returned when the cache misses of a request exceed its limit
+type characterLimitExceeded struct {
+	MaxCharacters int64
+	Estimate      CostEstimate
+}
+
+func (e *characterLimitExceeded) Error() string {
+	return fmt.Sprintf("%d characters to translate exceed the limit of %d", e.Estimate.FreshCharacters, e.MaxCharacters)
+}
+
+type characterLimitKey struct{}
+
+// withCharacterLimit returns a context capping the characters translated by the provider to
+// the lower of the requested and configured limits, unchanged when neither is set
+func withCharacterLimit(ctx context.Context, requested int) context.Context {
+	limit := maxRequestCharacters
+	if requested > 0 && (limit <= 0 || requested < limit) {
+		limit = requested
+	}
+	if limit <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, characterLimitKey{}, &characterLimit{maxCharacters: int64(limit)})
+}
+
+// characterLimitFromContext returns the character limit of the request, nil when uncapped
+func characterLimitFromContext(ctx context.Context) *characterLimit {
+	l, _ := ctx.Value(characterLimitKey{}).(*characterLimit)
+	return l
+}
+
+// release sends the held misses to the provider, unless the request went over its limit
+func (l *characterLimit) release(held []bool, misses chan<- int) error {
+	if estimate := l.usage.Estimate(); estimate.FreshCharacters > l.maxCharacters {
+		return &characterLimitExceeded{MaxCharacters: l.maxCharacters, Estimate: *estimate}
+	}
+	for index, miss := range held {
+		if miss {
+			misses <- index
+		}
+	}
+	return nil
+}
+
+// CharacterLimitResponse is the body of a request rejected by its character limit
+type CharacterLimitResponse struct {
+	// Error summarizes why the request was rejected
+	Error string `json:"error"`
+	// MaxCharacters is the limit of the request
+	MaxCharacters int64 `json:"max_characters"`
+	// Estimate is the cost estimate of the request, whose fresh characters exceed the limit
+	Estimate CostEstimate `json:"estimate"`
+}
+
+// characterLimitResponse returns a 413 response with the estimate of the request when the
+// error was caused by its character limit
+func characterLimitResponse(err error) (events.APIGatewayProxyResponse, bool) {
+	var exceeded *characterLimitExceeded
+	if !errors.As(err, &exceeded) {
+		return events.APIGatewayProxyResponse{}, false
+	}
+
+	body, err := json.Marshal(CharacterLimitResponse{
+		Error:         "Request exceeds its character limit",
+		MaxCharacters: exceeded.MaxCharacters,
+		Estimate:      exceeded.Estimate,
+	})
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusRequestEntityTooLarge,
+			Body:       exceeded.Error(),
+		}, true
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Body:       string(body),
+	}, true
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestHandleCharacterLimit(t *testing.T) {
+	cachedHash := getCacheKey("en", "es", "Hello.")
+	exceeded := `{"error":"Request exceeds its character limit","max_characters":10,"estimate":{"segments":2,"cache_hits":1,"fresh_segments":1,"fresh_characters":18,"estimated_cost":0.0003}}`
+
+	tests := []struct {
+		name                 string
+		maxRequestCharacters int
+		body                 string
+		expectedResponse     events.APIGatewayProxyResponse
+		expectedTranslations int32
+	}{
+		{
+			name:                 "Within the requested limit",
+			body:                 `{"source_language":"en","target_language":"es","text":"Hello. How are you today?","max_characters":18}`,
+			expectedResponse:     events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: `{"translated_text":"Hola. mock "}`},
+			expectedTranslations: 1,
+		},
+		{
+			name:             "Requested limit exceeded",
+			body:             `{"source_language":"en","target_language":"es","text":"Hello. How are you today?","max_characters":10}`,
+			expectedResponse: events.APIGatewayProxyResponse{StatusCode: http.StatusRequestEntityTooLarge, Body: exceeded},
+		},
+		{
+			name:                 "Configured limit exceeded",
+			maxRequestCharacters: 10,
+			body:                 `{"source_language":"en","target_language":"es","text":"Hello. How are you today?"}`,
+			expectedResponse:     events.APIGatewayProxyResponse{StatusCode: http.StatusRequestEntityTooLarge, Body: exceeded},
+		},
+		{
+			name:                 "Requested limit above the configured limit",
+			maxRequestCharacters: 10,
+			body:                 `{"source_language":"en","target_language":"es","text":"Hello. How are you today?","max_characters":100}`,
+			expectedResponse:     events.APIGatewayProxyResponse{StatusCode: http.StatusRequestEntityTooLarge, Body: exceeded},
+		},
+		{
+			name: "Negative limit",
+			body: `{"source_language":"en","target_language":"es","text":"Hello.","max_characters":-1}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"max_characters","message":"max_characters must be a positive number"}]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := maxRequestCharacters
+			maxRequestCharacters = tt.maxRequestCharacters
+			defer func() { maxRequestCharacters = original }()
+
+			var translations atomic.Int32
+
+			h := newMockHandler(nil)
+			h.dynamoClient = &MockDynamoDBClient{
+				GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					if params.Key["hash"].(*dynamoTypes.AttributeValueMemberS).Value != cachedHash {
+						return &dynamodb.GetItemOutput{}, nil
+					}
+					return &dynamodb.GetItemOutput{Item: map[string]dynamoTypes.AttributeValue{
+						"hash":            &dynamoTypes.AttributeValueMemberS{Value: cachedHash},
+						"source_text":     &dynamoTypes.AttributeValueMemberS{Value: "Hello."},
+						"translated_text": &dynamoTypes.AttributeValueMemberS{Value: "Hola."},
+						"source_language": &dynamoTypes.AttributeValueMemberS{Value: "en"},
+						"target_language": &dynamoTypes.AttributeValueMemberS{Value: "es"},
+					}}, nil
+				},
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					return &dynamodb.PutItemOutput{}, nil
+				},
+				UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+					return &dynamodb.UpdateItemOutput{}, nil
+				},
+			}
+			h.translateClient.(*MockTranslateClient).TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				translations.Add(1)
+				return &translate.TranslateTextOutput{TranslatedText: aws.String("mock")}, nil
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+			if translations.Load() != tt.expectedTranslations {
+				t.Errorf("handle() made %d translations, expected %d", translations.Load(), tt.expectedTranslations)
+			}
+		})
+	}
+}
+
+func TestWithCharacterLimit(t *testing.T) {
+	tests := []struct {
+		name                 string
+		maxRequestCharacters int
+		requested            int
+		expected             int64
+	}{
+		{name: "Unlimited"},
+		{name: "Requested", requested: 500, expected: 500},
+		{name: "Configured", maxRequestCharacters: 1000, expected: 1000},
+		{name: "Requested below configured", maxRequestCharacters: 1000, requested: 500, expected: 500},
+		{name: "Requested above configured", maxRequestCharacters: 1000, requested: 5000, expected: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := maxRequestCharacters
+			maxRequestCharacters = tt.maxRequestCharacters
+			defer func() { maxRequestCharacters = original }()
+
+			var got int64
+			if limit := characterLimitFromContext(withCharacterLimit(context.Background(), tt.requested)); limit != nil {
+				got = limit.maxCharacters
+			}
+			if got != tt.expected {
+				t.Errorf("withCharacterLimit() limit = %d, expected %d", got, tt.expected)
+			}
+		})
+	}
+}
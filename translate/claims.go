@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultTenantClaim                = "custom:tenant"
+	defaultLanguagePairsClaim         = "custom:language_pairs"
+	defaultDefaultTargetLanguageClaim = "custom:default_target_language"
+
+	// anyLanguage matches every language in the language pairs of an entitlement
+	anyLanguage = "*"
+)
+
+// errNotEntitled is the reason a language pair outside the entitlement of the caller is
+// rejected
+var errNotEntitled = errors.New("not in the entitlement of the caller")
+
+var (
+	// claimsAuthorization requires the claims of a Cognito or JWT authorizer on translation
+	// requests, rejecting the requests without them
+	claimsAuthorization bool
+	// tenantClaim, languagePairsClaim and defaultTargetLanguageClaim are the names of the
+	// claims holding the entitlement of the caller
+	tenantClaim                string
+	languagePairsClaim         string
+	defaultTargetLanguageClaim string
+)
+
+type entitlementKey struct{}
+
+// entitlement is what the authorizer claims of the caller allow
+type entitlement struct {
+	Tenant string
+	// LanguagePairs are the allowed source:target pairs, either side may be *. Every pair is
+	// allowed when empty.
+	LanguagePairs map[string]bool
+	// DefaultTargetLanguage is the target language of the requests without one
+	DefaultTargetLanguage string
+}
+
+// entitlementFromEvent reads the entitlement of the caller from the authorizer of the
+// request: the claims of a Cognito user pool authorizer, or the context of a Lambda
+// authorizer. It reports whether the request has any.
+func entitlementFromEvent(event events.APIGatewayProxyRequest) (entitlement, bool) {
+	claims := event.RequestContext.Authorizer
+	if nested, ok := claims["claims"].(map[string]any); ok {
+		claims = nested
+	}
+	if len(claims) == 0 {
+		return entitlement{}, false
+	}
+
+	e := entitlement{
+		Tenant:                claimString(claims[tenantClaim]),
+		DefaultTargetLanguage: claimString(claims[defaultTargetLanguageClaim]),
+		LanguagePairs:         normalizeLanguagePairs(parseLanguagePairs(claimString(claims[languagePairsClaim]))),
+	}
+	return e, true
+}
+
+// normalizeLanguagePairs normalizes the language codes of the pairs, so a claim of en:pt-pt
+// matches the pt-PT requests do
+func normalizeLanguagePairs(pairs map[string]bool) map[string]bool {
+	normalized := make(map[string]bool, len(pairs))
+	for pair := range pairs {
+		source, target, _ := strings.Cut(pair, ":")
+		normalized[languagePair(normalizeLanguageCode(source), normalizeLanguageCode(target))] = true
+	}
+	return normalized
+}
+
+// claimString returns a claim as a string, joining lists with commas as JWT authorizers pass
+// array claims as lists
+func claimString(claim any) string {
+	switch claim := claim.(type) {
+	case string:
+		return claim
+	case []any:
+		values := make([]string, 0, len(claim))
+		for _, value := range claim {
+			values = append(values, fmt.Sprint(value))
+		}
+		return strings.Join(values, ",")
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(claim)
+	}
+}
+
+// withEntitlement returns a context carrying the entitlement of the caller and records its
+// tenant on the request span
+func withEntitlement(ctx context.Context, e entitlement) context.Context {
+	if e.Tenant != "" {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("tenant", e.Tenant))
+	}
+	return context.WithValue(ctx, entitlementKey{}, e)
+}
+
+// entitlementFromContext returns the entitlement of the caller, if the request has one
+func entitlementFromContext(ctx context.Context) (entitlement, bool) {
+	e, ok := ctx.Value(entitlementKey{}).(entitlement)
+	return e, ok
+}
+
+// allows reports whether the entitlement allows translating from the source language to the
+// target language. A source language still to be detected is allowed when some source may
+// be translated to the target language, the detected language is checked again.
+func (e entitlement) allows(sourceLanguage, targetLanguage string) bool {
+	if len(e.LanguagePairs) == 0 {
+		return true
+	}
+	if sourceLanguage == autoDetectLanguage {
+		for pair := range e.LanguagePairs {
+			if _, target, _ := strings.Cut(pair, ":"); target == targetLanguage || target == anyLanguage {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, pair := range []string{
+		languagePair(sourceLanguage, targetLanguage),
+		languagePair(anyLanguage, targetLanguage),
+		languagePair(sourceLanguage, anyLanguage),
+		languagePair(anyLanguage, anyLanguage),
+	} {
+		if e.LanguagePairs[pair] {
+			return true
+		}
+	}
+	return false
+}
+
+// checksDetectedLanguage reports whether the language detected in a text translated from auto
+// must be checked, as the policy blocks languages or the entitlement of the caller only allows
+// some source languages to the target language
+func checksDetectedLanguage(ctx context.Context, targetLanguage string) bool {
+	if configuredLanguagePolicy.blocksLanguages() {
+		return true
+	}
+	e, ok := entitlementFromContext(ctx)
+	return ok && len(e.LanguagePairs) > 0 &&
+		!e.LanguagePairs[languagePair(anyLanguage, targetLanguage)] &&
+		!e.LanguagePairs[languagePair(anyLanguage, anyLanguage)]
+}
+
+// checkDetectedLanguage returns a languagePairNotAllowed error when the language policy
+// doesn't allow translating the detected source language to the target language, or the
+// caller isn't entitled to. A source language to be detected was only checked as auto.
+func checkDetectedLanguage(ctx context.Context, sourceLanguage, targetLanguage string) error {
+	if err := configuredLanguagePolicy.checkPair(sourceLanguage, targetLanguage); err != nil {
+		return err
+	}
+	if e, ok := entitlementFromContext(ctx); ok && !e.allows(sourceLanguage, targetLanguage) {
+		return &languagePairNotAllowed{SourceLanguage: sourceLanguage, TargetLanguage: targetLanguage, err: errNotEntitled}
+	}
+	return nil
+}
+
+// checkEntitlement returns the response rejecting a language pair the language policy doesn't
+// allow or the caller isn't entitled to. Requests without an entitlement are only checked
+// against the policy.
+func checkEntitlement(ctx context.Context, sourceLanguage, targetLanguage string) (events.APIGatewayProxyResponse, bool) {
+	if err := configuredLanguagePolicy.check(sourceLanguage, targetLanguage); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusForbidden,
+			Body:       fmt.Sprintf("Language pair %s to %s not allowed: %v", sourceLanguage, targetLanguage, err),
+		}, false
+	}
+	e, ok := entitlementFromContext(ctx)
+	if !ok || e.allows(sourceLanguage, targetLanguage) {
+		return events.APIGatewayProxyResponse{}, true
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusForbidden,
+		Body:       fmt.Sprintf("Language pair %s to %s not allowed", sourceLanguage, targetLanguage),
+	}, false
+}
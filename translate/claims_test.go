@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestEntitlementFromEvent(t *testing.T) {
+	tests := []struct {
+		name       string
+		authorizer map[string]any
+		expected   entitlement
+		found      bool
+	}{
+		{
+			name: "Cognito claims",
+			authorizer: map[string]any{"claims": map[string]any{
+				"custom:tenant":                  "acme",
+				"custom:language_pairs":          "en:es, en:fr",
+				"custom:default_target_language": "es",
+			}},
+			expected: entitlement{
+				Tenant:                "acme",
+				LanguagePairs:         map[string]bool{"en:es": true, "en:fr": true},
+				DefaultTargetLanguage: "es",
+			},
+			found: true,
+		},
+		{
+			name: "Lambda authorizer context with a list claim",
+			authorizer: map[string]any{
+				"custom:tenant":         "acme",
+				"custom:language_pairs": []any{"*:en", "de:fr"},
+			},
+			expected: entitlement{
+				Tenant:        "acme",
+				LanguagePairs: map[string]bool{"*:en": true, "de:fr": true},
+			},
+			found: true,
+		},
+		{
+			name: "Language codes are normalized",
+			authorizer: map[string]any{
+				"custom:language_pairs": "EN:pt-pt,*:ZH-tw",
+			},
+			expected: entitlement{
+				LanguagePairs: map[string]bool{"en:pt-PT": true, "*:zh-TW": true},
+			},
+			found: true,
+		},
+		{
+			name:     "No authorizer",
+			expected: entitlement{},
+			found:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := entitlementFromEvent(events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: tt.authorizer},
+			})
+			if found != tt.found {
+				t.Fatalf("entitlementFromEvent() found = %v, expected %v", found, tt.found)
+			}
+			if found && !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("entitlementFromEvent() = %+v, expected %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEntitlementAllows(t *testing.T) {
+	e := entitlement{LanguagePairs: map[string]bool{"en:es": true, "*:en": true, "fr:ja": true}}
+
+	tests := []struct {
+		source   string
+		target   string
+		expected bool
+	}{
+		{source: "en", target: "es", expected: true},
+		{source: "de", target: "en", expected: true},
+		{source: "fr", target: "ja", expected: true},
+		{source: "en", target: "fr", expected: false},
+		{source: autoDetectLanguage, target: "es", expected: true},
+		{source: autoDetectLanguage, target: "de", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source+":"+tt.target, func(t *testing.T) {
+			if got := e.allows(tt.source, tt.target); got != tt.expected {
+				t.Errorf("allows() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+
+	if !(entitlement{}).allows("en", "ja") {
+		t.Error("allows() = false without language pairs, expected every pair allowed")
+	}
+}
+
+func TestChecksDetectedLanguage(t *testing.T) {
+	tests := []struct {
+		name          string
+		languagePairs map[string]bool
+		blocked       string
+		entitled      bool
+		expected      bool
+	}{
+		{name: "No entitlement", expected: false},
+		{name: "Every pair", entitled: true, expected: false},
+		{name: "Any source to the target", languagePairs: map[string]bool{"*:es": true}, entitled: true, expected: false},
+		{name: "Some sources to the target", languagePairs: map[string]bool{"fr:es": true}, entitled: true, expected: true},
+		{name: "Blocked languages", blocked: "ru", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := configuredLanguagePolicy
+			configuredLanguagePolicy = languagePolicy{blocked: parseLanguageList(tt.blocked)}
+			defer func() { configuredLanguagePolicy = previous }()
+
+			ctx := context.Background()
+			if tt.entitled {
+				ctx = withEntitlement(ctx, entitlement{LanguagePairs: tt.languagePairs})
+			}
+			if got := checksDetectedLanguage(ctx, "es"); got != tt.expected {
+				t.Errorf("checksDetectedLanguage() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandleEntitledRequest(t *testing.T) {
+	claims := map[string]any{"claims": map[string]any{
+		"custom:language_pairs":          "en:es",
+		"custom:default_target_language": "es",
+	}}
+
+	tests := []struct {
+		name             string
+		authorizer       map[string]any
+		body             string
+		detected         string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name:       "Default target language",
+			authorizer: claims,
+			body:       `{"source_language":"en","text":"Hello"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola "}`,
+			},
+		},
+		{
+			name:       "Pair outside the entitlement",
+			authorizer: claims,
+			body:       `{"source_language":"es","target_language":"en","text":"Hola"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusForbidden,
+				Body:       "Language pair es to en not allowed",
+			},
+		},
+		{
+			name:       "Detected language outside the entitlement in a text",
+			authorizer: claims,
+			body:       `{"source_language":"auto","target_language":"es","text":"Bonjour"}`,
+			detected:   "fr",
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusForbidden,
+				Body:       "Language pair fr to es not allowed",
+			},
+		},
+		{
+			name:       "Detected language outside the entitlement in a document",
+			authorizer: claims,
+			body:       `{"source_language":"auto","target_language":"es","format":"csv","columns":["title"],"document":"` + base64.StdEncoding.EncodeToString([]byte("sku,title\nA1,Bonjour\n")) + `"}`,
+			detected:   "fr",
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusForbidden,
+				Body:       "Language pair fr to es not allowed: not in the entitlement of the caller",
+			},
+		},
+		{
+			name: "Missing claims",
+			body: `{"source_language":"en","target_language":"es","text":"Hello"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusForbidden,
+				Body:       "Missing authorization claims",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := claimsAuthorization
+			claimsAuthorization = true
+			defer func() { claimsAuthorization = previous }()
+
+			h := newMockHandler(map[string]string{"Hello": "Hola"})
+			if tt.detected != "" {
+				h.comprehendClient = newMockLanguageDetector(tt.detected)
+				mock := h.translateClient.(*MockTranslateClient)
+				translateText := mock.TranslateTextFunc
+				mock.TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+					output, err := translateText(ctx, params, optFns...)
+					if err == nil && aws.ToString(params.SourceLanguageCode) == autoDetectLanguage {
+						output.SourceLanguageCode = aws.String(tt.detected)
+					}
+					return output, err
+				}
+			}
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+				HTTPMethod:     http.MethodPost,
+				Body:           tt.body,
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: tt.authorizer},
+			})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+// Package client is the Go client of the translation API. It sends the API key, retries the
+// requests failing while the translation provider is unavailable and parses the version 2
+// responses:
+//
+//	c, err := client.New("https://abc123.execute-api.us-east-1.amazonaws.com/prod", client.Options{APIKey: key})
+//	if err != nil {
+//		return err
+//	}
+//	response, err := c.Translate(ctx, client.TranslateRequest{TargetLanguage: "es", Text: "Hello."})
+//
+// Consumers mock the Translator interface in their tests, see the clienttest package.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is the number of times a failed request is retried by default
+	DefaultMaxRetries = 3
+	// DefaultRetryDelay is the delay before the first retry, doubled by each retry
+	DefaultRetryDelay = 200 * time.Millisecond
+
+	// AutoDetect lets the API detect the source language
+	AutoDetect = "auto"
+
+	// mediaType is the media type of the version 2 responses
+	mediaType = "application/vnd.gotranslate.v2+json"
+	// maxResponseSize is the size limit of a response body
+	maxResponseSize = 10 * 1024 * 1024
+)
+
+// Translator is the interface of Client, for consumers to mock in their tests
+type Translator interface {
+	Translate(ctx context.Context, request TranslateRequest) (*TranslateResponse, error)
+	TranslateHTML(ctx context.Context, sourceLanguage, targetLanguage, html string) (*TranslateResponse, error)
+	Languages(ctx context.Context) ([]string, error)
+}
+
+var _ Translator = (*Client)(nil)
+
+// Options configures a Client
+type Options struct {
+	// APIKey is sent in the x-api-key header of the requests
+	APIKey string
+	// HTTPClient sends the requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// MaxRetries is the number of times a failed request is retried, DefaultMaxRetries when
+	// zero and no retries when negative
+	MaxRetries int
+	// RetryDelay is the delay before the first retry, DefaultRetryDelay when zero. A longer
+	// Retry-After of the response takes precedence.
+	RetryDelay time.Duration
+}
+
+// Client calls the translation API
+type Client struct {
+	endpoint   string
+	apiKey     string
+	httpClient *http.Client
+	maxRetries int
+	retryDelay time.Duration
+}
+
+// Error is returned when the API rejects a request
+type Error struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Message is why the request was rejected
+	Message string
+	// Fields are the errors of each invalid field of a request rejected by validation
+	Fields []FieldError
+	// RetryAfter is how long until the request can be retried, when the API says
+	RetryAfter time.Duration
+	// Estimate is the cost estimate of a request rejected by its character limit
+	Estimate *CostEstimate
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("translation API returned %d: %s", e.StatusCode, e.Message)
+	for _, field := range e.Fields {
+		message += "; " + field.Message
+	}
+	return message
+}
+
+// New returns a client of the API deployed at the endpoint, the URL of the API Gateway stage
+func New(endpoint string, opts Options) (*Client, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint, %w", err)
+	}
+	if parsed.Scheme != "https" && parsed.Scheme != "http" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q, an absolute http or https URL is expected", endpoint)
+	}
+
+	c := &Client{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		apiKey:     opts.APIKey,
+		httpClient: opts.HTTPClient,
+		maxRetries: opts.MaxRetries,
+		retryDelay: opts.RetryDelay,
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	switch {
+	case c.maxRetries == 0:
+		c.maxRetries = DefaultMaxRetries
+	case c.maxRetries < 0:
+		c.maxRetries = 0
+	}
+	if c.retryDelay == 0 {
+		c.retryDelay = DefaultRetryDelay
+	}
+	return c, nil
+}
+
+// Translate translates the text, document or web page of the request, detecting the source
+// language when it is empty
+func (c *Client) Translate(ctx context.Context, request TranslateRequest) (*TranslateResponse, error) {
+	if request.SourceLanguage == "" {
+		request.SourceLanguage = AutoDetect
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request, %w", err)
+	}
+
+	path := "/translate"
+	if request.URL != "" {
+		path = "/translate/url"
+	}
+
+	var response TranslateResponse
+	if err := c.do(ctx, http.MethodPost, path, body, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// TranslateHTML translates the text nodes of an HTML document, leaving the markup untouched
+func (c *Client) TranslateHTML(ctx context.Context, sourceLanguage, targetLanguage, html string) (*TranslateResponse, error) {
+	return c.Translate(ctx, TranslateRequest{
+		SourceLanguage: sourceLanguage,
+		TargetLanguage: targetLanguage,
+		Text:           html,
+		Format:         "html",
+	})
+}
+
+// Languages returns the codes of the supported target languages
+func (c *Client) Languages(ctx context.Context) ([]string, error) {
+	var response languagesResponse
+	if err := c.do(ctx, http.MethodGet, "/languages", nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Languages, nil
+}
+
+// do sends the request, retrying it while it fails with a retryable error, and unmarshals the
+// response body into out
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out any) error {
+	for attempt := 0; ; attempt++ {
+		err := c.send(ctx, method, path, body, out)
+		if err == nil || attempt >= c.maxRetries || !retryable(ctx, err) {
+			return err
+		}
+
+		delay := c.retryDelay << attempt
+		var apiErr *Error
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+			delay = apiErr.RetryAfter
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// send sends the request once
+func (c *Client) send(ctx context.Context, method, path string, body []byte, out any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request, %w", err)
+	}
+	request.Header.Set("Accept", mediaType)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		request.Header.Set("x-api-key", c.apiKey)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read response, %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return newError(response, data)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response, %w", err)
+	}
+	return nil
+}
+
+// newError returns the error of a rejected request, whose body is either plain text or the
+// validation errors
+func newError(response *http.Response, data []byte) *Error {
+	apiErr := &Error{StatusCode: response.StatusCode, Message: strings.TrimSpace(string(data))}
+
+	var body errorResponse
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		apiErr.Message, apiErr.Fields, apiErr.Estimate = body.Error, body.Fields, body.Estimate
+	}
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
+
+// retryable reports whether the request may succeed if it is sent again: the API was
+// unavailable, throttled the request or couldn't be reached
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	// Only transport errors are left, the others are returned before sending the request
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
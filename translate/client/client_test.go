@@ -0,0 +1,230 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		wantErr  bool
+	}{
+		{name: "Stage URL", endpoint: "https://abc123.execute-api.us-east-1.amazonaws.com/prod/"},
+		{name: "Local API", endpoint: "http://localhost:3000"},
+		{name: "Relative URL", endpoint: "/prod", wantErr: true},
+		{name: "Other scheme", endpoint: "ftp://example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.endpoint, Options{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name             string
+		statusCodes      []int
+		retryAfter       string
+		body             string
+		maxRetries       int
+		expected         *TranslateResponse
+		expectedErr      *Error
+		expectedAttempts int32
+	}{
+		{
+			name:             "Translation",
+			statusCodes:      []int{http.StatusOK},
+			body:             `{"version":"2","translated_text":"Hola.","segments":[],"stats":{"translated_characters":5,"rows":0,"skipped":false,"degraded":false,"low_quality":false,"pivoted":false}}`,
+			expected:         &TranslateResponse{TranslatedText: "Hola.", Segments: []Segment{}, Stats: Stats{TranslatedCharacters: 5}},
+			expectedAttempts: 1,
+		},
+		{
+			name:             "Retried while unavailable",
+			statusCodes:      []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			retryAfter:       "0",
+			body:             `{"translated_text":"Hola.","segments":[],"stats":{}}`,
+			expected:         &TranslateResponse{TranslatedText: "Hola.", Segments: []Segment{}},
+			expectedAttempts: 3,
+		},
+		{
+			name:             "Retries exhausted",
+			statusCodes:      []int{http.StatusServiceUnavailable},
+			body:             "Service temporarily unavailable",
+			maxRetries:       2,
+			expectedErr:      &Error{StatusCode: http.StatusServiceUnavailable, Message: "Service temporarily unavailable"},
+			expectedAttempts: 3,
+		},
+		{
+			name:        "Validation error",
+			statusCodes: []int{http.StatusBadRequest},
+			body:        `{"error":"Invalid request","fields":[{"field":"text","message":"text is required"}]}`,
+			expectedErr: &Error{
+				StatusCode: http.StatusBadRequest,
+				Message:    "Invalid request",
+				Fields:     []FieldError{{Field: "text", Message: "text is required"}},
+			},
+			expectedAttempts: 1,
+		},
+		{
+			name:        "Character limit exceeded",
+			statusCodes: []int{http.StatusRequestEntityTooLarge},
+			body:        `{"error":"Request exceeds its character limit","max_characters":10,"estimate":{"segments":1,"cache_hits":0,"fresh_segments":1,"fresh_characters":18,"estimated_cost":0.0003}}`,
+			expectedErr: &Error{
+				StatusCode: http.StatusRequestEntityTooLarge,
+				Message:    "Request exceeds its character limit",
+				Estimate:   &CostEstimate{Segments: 1, FreshSegments: 1, FreshCharacters: 18, EstimatedCost: 0.0003},
+			},
+			expectedAttempts: 1,
+		},
+		{
+			name:             "Unsupported language is not retried",
+			statusCodes:      []int{http.StatusUnprocessableEntity},
+			body:             "Target language not supported",
+			expectedErr:      &Error{StatusCode: http.StatusUnprocessableEntity, Message: "Target language not supported"},
+			expectedAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempt := int(attempts.Add(1)) - 1
+
+				body, _ := io.ReadAll(r.Body)
+				if r.Method != http.MethodPost || r.URL.Path != "/prod/translate" || string(body) != `{"source_language":"auto","target_language":"es","text":"Hello."}` {
+					t.Errorf("Translate() sent %s %s %s", r.Method, r.URL.Path, body)
+				}
+				if r.Header.Get("x-api-key") != "key" || r.Header.Get("Accept") != mediaType {
+					t.Errorf("Translate() headers = %v, expected the API key and version 2", r.Header)
+				}
+
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.statusCodes[min(attempt, len(tt.statusCodes)-1)])
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			c, err := New(server.URL+"/prod/", Options{APIKey: "key", MaxRetries: tt.maxRetries, RetryDelay: time.Millisecond})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			got, err := c.Translate(context.Background(), TranslateRequest{TargetLanguage: "es", Text: "Hello."})
+			if tt.expectedErr != nil {
+				var apiErr *Error
+				if !errors.As(err, &apiErr) || !reflect.DeepEqual(apiErr, tt.expectedErr) {
+					t.Errorf("Translate() error = %#v, expected %#v", err, tt.expectedErr)
+				}
+			} else if err != nil || !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Translate() = %+v, %v, expected %+v", got, err, tt.expected)
+			}
+
+			if attempts.Load() != tt.expectedAttempts {
+				t.Errorf("Translate() attempts = %d, expected %d", attempts.Load(), tt.expectedAttempts)
+			}
+		})
+	}
+}
+
+func TestTranslateHTML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"source_language":"en","target_language":"es","text":"\u003cp\u003eHello.\u003c/p\u003e","format":"html"}` {
+			t.Errorf("TranslateHTML() sent %s", body)
+		}
+		w.Write([]byte(`{"translated_text":"<p>Hola.</p>","segments":[],"stats":{}}`))
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL, Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	got, err := c.TranslateHTML(context.Background(), "en", "es", "<p>Hello.</p>")
+	if err != nil || got.TranslatedText != "<p>Hola.</p>" {
+		t.Errorf("TranslateHTML() = %+v, %v, expected <p>Hola.</p>", got, err)
+	}
+}
+
+func TestTranslateURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path != "/translate/url" || string(body) != `{"source_language":"en","target_language":"es","url":"https://example.com/"}` {
+			t.Errorf("Translate() sent %s %s", r.URL.Path, body)
+		}
+		w.Write([]byte(`{"translated_text":"<p>Hola.</p>","segments":[],"stats":{}}`))
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL, Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	got, err := c.Translate(context.Background(), TranslateRequest{SourceLanguage: "en", TargetLanguage: "es", URL: "https://example.com/"})
+	if err != nil || got.TranslatedText != "<p>Hola.</p>" {
+		t.Errorf("Translate() = %+v, %v, expected <p>Hola.</p>", got, err)
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/languages" {
+			t.Errorf("Languages() requested %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"languages":["en","es","sw"]}`))
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL, Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	got, err := c.Languages(context.Background())
+	if err != nil || !reflect.DeepEqual(got, []string{"en", "es", "sw"}) {
+		t.Errorf("Languages() = %v, %v, expected en, es and sw", got, err)
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL, Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := c.Languages(ctx); err == nil {
+		t.Errorf("Languages() error = nil, expected the unavailable error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Languages() waited %s, expected the cancellation to stop the retries", elapsed)
+	}
+}
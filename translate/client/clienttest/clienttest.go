@@ -0,0 +1,53 @@
+// Package clienttest provides a mock of the translation API client for the tests of its
+// consumers
+package clienttest
+
+import (
+	"context"
+
+	"translate/client"
+)
+
+// MockTranslator implements client.Translator with the functions of its fields
+type MockTranslator struct {
+	TranslateFunc     func(ctx context.Context, request client.TranslateRequest) (*client.TranslateResponse, error)
+	TranslateHTMLFunc func(ctx context.Context, sourceLanguage, targetLanguage, html string) (*client.TranslateResponse, error)
+	LanguagesFunc     func(ctx context.Context) ([]string, error)
+}
+
+var _ client.Translator = (*MockTranslator)(nil)
+
+func (m *MockTranslator) Translate(ctx context.Context, request client.TranslateRequest) (*client.TranslateResponse, error) {
+	return m.TranslateFunc(ctx, request)
+}
+
+func (m *MockTranslator) TranslateHTML(ctx context.Context, sourceLanguage, targetLanguage, html string) (*client.TranslateResponse, error) {
+	return m.TranslateHTMLFunc(ctx, sourceLanguage, targetLanguage, html)
+}
+
+func (m *MockTranslator) Languages(ctx context.Context) ([]string, error) {
+	return m.LanguagesFunc(ctx)
+}
+
+// Echo returns a mock translating every text to itself, as the API does when the source
+// language is the target language
+func Echo() *MockTranslator {
+	translate := func(text string) *client.TranslateResponse {
+		return &client.TranslateResponse{
+			TranslatedText: text,
+			Segments:       []client.Segment{},
+			Stats:          client.Stats{TranslatedCharacters: len([]rune(text)), Skipped: true},
+		}
+	}
+	return &MockTranslator{
+		TranslateFunc: func(ctx context.Context, request client.TranslateRequest) (*client.TranslateResponse, error) {
+			return translate(request.Text), nil
+		},
+		TranslateHTMLFunc: func(ctx context.Context, sourceLanguage, targetLanguage, html string) (*client.TranslateResponse, error) {
+			return translate(html), nil
+		},
+		LanguagesFunc: func(ctx context.Context) ([]string, error) {
+			return []string{"en"}, nil
+		},
+	}
+}
@@ -0,0 +1,313 @@
+package client
+
+// TranslateRequest is a request to translate a text or document, mirroring the request of
+// the API
+type TranslateRequest struct {
+	// SourceLanguage is the language code of the source text, "auto" or empty to detect it
+	SourceLanguage string `json:"source_language,omitempty"`
+	// TargetLanguage is the language code of the target text
+	TargetLanguage string `json:"target_language"`
+	// Text is the text to be translated
+	Text string `json:"text,omitempty"`
+	// Format is the format of the input, one of "text" (default), "html", "pdf", "email",
+	// "plural", "android_strings", "ios_strings", "ios_stringsdict" or "csv"
+	Format string `json:"format,omitempty"`
+	// Document is the base64 encoded document for non-text formats
+	Document string `json:"document,omitempty"`
+	// Output is the shape of the translated document, either "text" (default) or "blocks"
+	Output string `json:"output,omitempty"`
+	// Moderation checks the translated text, either "flag" or "mask", empty to disable
+	Moderation string `json:"moderation,omitempty"`
+	// Transliterate romanizes the text instead of translating it
+	Transliterate bool `json:"transliterate,omitempty"`
+	// Style is the tone of the translation, one of "technical", "marketing", "casual" or
+	// "legal", empty for the provider's default
+	Style string `json:"style,omitempty"`
+	// NoStore reads the translations from the cache without caching the new ones
+	NoStore bool `json:"no_store,omitempty"`
+	// Plurals are the English "one" and "other" forms of a resource string for the "plural" format
+	Plurals map[string]string `json:"plurals,omitempty"`
+	// PluralPlaceholder is the count placeholder of the plural forms, "{count}" by default
+	PluralPlaceholder string `json:"plural_placeholder,omitempty"`
+	// Columns are the header names of the columns translated by the "csv" format
+	Columns []string `json:"columns,omitempty"`
+	// Key is the key of a CSV file in the bulk bucket, used instead of the document
+	Key string `json:"key,omitempty"`
+	// DryRun looks the text up in the cache without translating the misses, returning the
+	// cost estimate of translating them instead
+	DryRun bool `json:"dry_run,omitempty"`
+	// MaxCharacters caps the characters translated after the cache lookups, the request is
+	// rejected with its cost estimate when exceeded
+	MaxCharacters int `json:"max_characters,omitempty"`
+	// Alignment returns the source sentences aligned with their translations, for the "text"
+	// format
+	Alignment bool `json:"alignment,omitempty"`
+	// LocalizeFormats formats the numbers, dates and currency amounts of English text with the
+	// conventions of the target language
+	LocalizeFormats bool `json:"localize_formats,omitempty"`
+	// Typography normalizes the quotation marks, dashes and ellipses of the translation, such
+	// as quotes=typographic,dashes=ascii
+	Typography string `json:"typography,omitempty"`
+	// PreserveCasing applies the casing of source sentences in all caps, title case or
+	// sentence case to their translations
+	PreserveCasing bool `json:"preserve_casing,omitempty"`
+	// MaxLength is the maximum number of characters of the translation of the "text" format
+	// and of each string of the resource formats
+	MaxLength int `json:"max_length,omitempty"`
+	// Truncation is how a translation still too long is cut, "ellipsis", "hard" or "none"
+	Truncation string `json:"truncation,omitempty"`
+	// Inclusive phrases the translation gender-neutrally, for the target languages that
+	// support it
+	Inclusive bool `json:"inclusive,omitempty"`
+	// Alternatives is the number of alternative translations of each sentence returned along
+	// the translation, up to 5, for the "text" format
+	Alternatives int `json:"alternatives,omitempty"`
+	// Analyze returns the sentiment and the named entities of the source text of "text" and
+	// "html" requests along the translation
+	Analyze bool `json:"analyze,omitempty"`
+	// TranslateEntities translates the names of people, organizations and products detected
+	// in the source text rather than keeping them verbatim
+	TranslateEntities bool `json:"translate_entities,omitempty"`
+	// GrammarCheck reports the likely problems a grammar check finds in each translated
+	// segment, without applying its fixes
+	GrammarCheck bool `json:"grammar_check,omitempty"`
+	// EchoParameters returns the parameters the "text" or "html" request was translated with,
+	// after its languages were normalized or detected and its options defaulted
+	EchoParameters bool `json:"echo_parameters,omitempty"`
+	// LanguageCodeCase is the casing of the language codes of the response: "canonical" for
+	// the BCP 47 casing such as pt-BR, the default, "lower" or "upper"
+	LanguageCodeCase string `json:"language_code_case,omitempty"`
+	// TranslateMetadata also translates the <title> and the description, Open Graph and
+	// Twitter card <meta> tags of "html" documents
+	TranslateMetadata bool `json:"translate_metadata,omitempty"`
+	// Priority is "interactive" (default) for user-facing requests, whose provider calls go
+	// before those of "bulk" requests such as batch items
+	Priority string `json:"priority,omitempty"`
+	// DeadlineMS is the time in milliseconds the request should be answered in, the segments
+	// not translated by then are served untranslated
+	DeadlineMS int `json:"deadline_ms,omitempty"`
+	// Granularity is the chunk of text translated at once: "sentence" (default), "paragraph"
+	// or "block"
+	Granularity string `json:"granularity,omitempty"`
+	// Disclaimer adds a localized machine translation disclaimer to the translation:
+	// "prepend" or "append"
+	Disclaimer string `json:"disclaimer,omitempty"`
+	// URL is a web page fetched and translated as HTML by the API instead of the text, the
+	// request is sent to /translate/url
+	URL string `json:"url,omitempty"`
+	// PreviousText and PreviousTranslation are an earlier version of the text and its
+	// translation, only the sentences changed since are translated
+	PreviousText        string `json:"previous_text,omitempty"`
+	PreviousTranslation string `json:"previous_translation,omitempty"`
+}
+
+// TranslateResponse is the translation of a request, in the version 2 shape of the API
+type TranslateResponse struct {
+	// TranslatedText is the translated text
+	TranslatedText string `json:"translated_text"`
+	// DetectedLanguage is the detected language of the source text
+	DetectedLanguage string `json:"detected_language,omitempty"`
+	// Blocks are the source and translated text blocks of a document
+	Blocks []Block `json:"blocks,omitempty"`
+	// Plurals are the translated CLDR plural forms of the target language
+	Plurals map[string]string `json:"plurals,omitempty"`
+	// OutputKey is the key of the translated CSV file in the bulk bucket
+	OutputKey string `json:"output_key,omitempty"`
+	// Segments reports the translated segments that needed attention
+	Segments []Segment `json:"segments"`
+	// Stats describes how the text was translated
+	Stats Stats `json:"stats"`
+	// Estimate is the cost estimate of a dry run
+	Estimate *CostEstimate `json:"estimate,omitempty"`
+	// Alignment pairs the source sentences with their translations when requested
+	Alignment []AlignedSentence `json:"alignment,omitempty"`
+	// Direction is "rtl" when the target language is written right to left
+	Direction string `json:"direction,omitempty"`
+	// Changes reports the sentences translated and reused for a previous version of the text
+	Changes *ChangeReport `json:"changes,omitempty"`
+	// Alternatives are the alternative translations of each sentence when requested
+	Alternatives []SegmentAlternatives `json:"alternatives,omitempty"`
+	// Analysis is the sentiment and the named entities of the source text when requested
+	Analysis *SourceAnalysis `json:"analysis,omitempty"`
+	// Disclaimer is the disclaimer added to the translated text
+	Disclaimer string `json:"disclaimer,omitempty"`
+	// Parameters are the parameters the request was translated with when requested
+	Parameters *Parameters `json:"parameters,omitempty"`
+}
+
+// Parameters are the parameters a request was translated with, once its languages were
+// normalized or detected and its options defaulted
+type Parameters struct {
+	SourceLanguage string `json:"source_language"`
+	TargetLanguage string `json:"target_language"`
+	Format         string `json:"format"`
+	Style          string `json:"style,omitempty"`
+	Moderation     string `json:"moderation,omitempty"`
+	Typography     string `json:"typography,omitempty"`
+	MaxLength      int    `json:"max_length,omitempty"`
+	Truncation     string `json:"truncation,omitempty"`
+	// Granularity is the chunk of text translated at once, left out for sentences
+	Granularity string `json:"granularity,omitempty"`
+	// Disclaimer is the position of the disclaimer added to the translation
+	Disclaimer string `json:"disclaimer,omitempty"`
+	// Options are the other options applied to the translation in alphabetical order, such
+	// as inclusive or preserve_casing
+	Options []string `json:"options,omitempty"`
+}
+
+// ChangeReport describes how a text was translated from the translation of its previous
+// version
+type ChangeReport struct {
+	// Reused is the number of sentences whose previous translation was kept
+	Reused int `json:"reused"`
+	// Translated are the sentences new or changed since the previous version
+	Translated []string `json:"translated"`
+	// Removed are the sentences of the previous version no longer in the text
+	Removed []string `json:"removed"`
+	// Mismatched is set when the previous translation couldn't be paired with the previous
+	// text, every sentence was then translated
+	Mismatched bool `json:"mismatched,omitempty"`
+}
+
+// SegmentAlternatives are the candidate translations of a source sentence besides the one of
+// the response
+type SegmentAlternatives struct {
+	// SourceText is the source sentence
+	SourceText string `json:"source_text"`
+	// TranslatedText is the translation of the sentence in the response
+	TranslatedText string `json:"translated_text"`
+	// Alternatives are the other candidate translations
+	Alternatives []string `json:"alternatives"`
+}
+
+// SourceAnalysis is the sentiment and the named entities of the source text, missing when the
+// source language isn't supported
+type SourceAnalysis struct {
+	// Sentiment is POSITIVE, NEGATIVE, NEUTRAL or MIXED
+	Sentiment string `json:"sentiment"`
+	// SentimentScores are the confidence of each sentiment, by sentiment
+	SentimentScores map[string]float32 `json:"sentiment_scores"`
+	Entities        []SourceEntity     `json:"entities"`
+	// Truncated is set when only the start of the text was analyzed
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// SourceEntity is a named entity of the source text
+type SourceEntity struct {
+	Text string `json:"text"`
+	// Type is the entity type, such as PERSON, ORGANIZATION or COMMERCIAL_ITEM
+	Type  string  `json:"type"`
+	Score float32 `json:"score"`
+	// BeginOffset and EndOffset are the character offsets of the entity in the source text
+	BeginOffset int `json:"begin_offset"`
+	EndOffset   int `json:"end_offset"`
+}
+
+// CostEstimate is the cost estimate of a dry run
+type CostEstimate struct {
+	// Segments is the number of segments of the request
+	Segments int64 `json:"segments"`
+	// CacheHits is the number of segments translated by the cache
+	CacheHits int64 `json:"cache_hits"`
+	// FreshSegments and FreshCharacters are the segments the provider would translate and
+	// their length in characters
+	FreshSegments   int64 `json:"fresh_segments"`
+	FreshCharacters int64 `json:"fresh_characters"`
+	// EstimatedCost is the cost in USD of translating the fresh characters
+	EstimatedCost float64 `json:"estimated_cost"`
+}
+
+// AlignedSentence is a source sentence aligned with its translation
+type AlignedSentence struct {
+	Source  string           `json:"source"`
+	Target  string           `json:"target"`
+	Offsets AlignmentOffsets `json:"offsets"`
+}
+
+// AlignmentOffsets are the offsets in characters (Unicode code points) of an aligned sentence
+// into the source and translated text, the end offsets being exclusive
+type AlignmentOffsets struct {
+	SourceStart int `json:"source_start"`
+	SourceEnd   int `json:"source_end"`
+	TargetStart int `json:"target_start"`
+	TargetEnd   int `json:"target_end"`
+}
+
+// Block is a source and translated text block of a document
+type Block struct {
+	SourceText     string `json:"source_text"`
+	TranslatedText string `json:"translated_text"`
+}
+
+// Segment is a translated segment that needed attention, such as a moderated one
+type Segment struct {
+	SourceText     string `json:"source_text"`
+	TranslatedText string `json:"translated_text"`
+	// Labels are the moderation labels found in the translated text
+	Labels []string `json:"labels,omitempty"`
+	// Masked is set when the flagged text was masked in the response
+	Masked bool `json:"masked,omitempty"`
+	// Constraint is how the translation was fitted to the maximum length, "brevity",
+	// "truncated" or "exceeded"
+	Constraint string `json:"constraint,omitempty"`
+	// Length is the number of characters of the translation before it was constrained
+	Length int `json:"length,omitempty"`
+	// Issues are the likely problems a grammar check found in the translated text
+	Issues []GrammarIssue `json:"issues,omitempty"`
+	// TimedOut is set when the segment was left untranslated to answer by the deadline
+	TimedOut bool `json:"timed_out,omitempty"`
+}
+
+// GrammarIssue is a likely problem of a translated segment, never applied to the translation
+type GrammarIssue struct {
+	Message string `json:"message"`
+	// Rule is the identifier of the rule of the grammar checker that matched
+	Rule string `json:"rule"`
+	// Category is the category of the rule, such as GRAMMAR, TYPOS or PUNCTUATION
+	Category string `json:"category,omitempty"`
+	Text     string `json:"text"`
+	// Offset and Length locate the flagged text in the translated text of the segment, in
+	// characters
+	Offset int `json:"offset"`
+	Length int `json:"length"`
+	// Replacements are the suggested replacements of the flagged text, best first
+	Replacements []string `json:"replacements,omitempty"`
+}
+
+// Stats describes how the text of a response was translated
+type Stats struct {
+	// TranslatedCharacters is the length of the translated text in characters
+	TranslatedCharacters int `json:"translated_characters"`
+	// Rows is the number of CSV rows translated
+	Rows int `json:"rows"`
+	// TranslationConfidence is the confidence score of the translation
+	TranslationConfidence float64 `json:"translation_confidence,omitempty"`
+	// Skipped is set when the source language is the target language
+	Skipped bool `json:"skipped"`
+	// Degraded is set when some text was left untranslated because the provider was unavailable
+	Degraded bool `json:"degraded"`
+	// TimedOut is set when some segments were left untranslated to answer by the deadline
+	TimedOut bool `json:"timed_out,omitempty"`
+	// LowQuality is set when some short strings were translated by the offline fallback
+	LowQuality bool `json:"low_quality"`
+	// Pivoted is set when some text was translated through the pivot language
+	Pivoted bool `json:"pivoted"`
+}
+
+// FieldError is the validation error of a request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// languagesResponse is the body of the languages response
+type languagesResponse struct {
+	Languages []string `json:"languages"`
+}
+
+// errorResponse is the body of a request rejected by validation or its character limit
+type errorResponse struct {
+	Error    string        `json:"error"`
+	Fields   []FieldError  `json:"fields"`
+	Estimate *CostEstimate `json:"estimate"`
+}
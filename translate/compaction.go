@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	cacheJobCompact = "compact"
+
+	// compactionCursorHash is the item holding the key the next compaction resumes from, when
+	// the previous one ran out of time before the end of the table
+	compactionCursorHash = "compaction-cursor"
+	// defaultCompactionRate is the number of items scanned and deleted per second
+	defaultCompactionRate = 25
+	// compactionDeadlineMargin is the time left to save the cursor before the function times out
+	compactionDeadlineMargin = 30 * time.Second
+)
+
+// compactionPageInterval is the minimum time between two scanned pages, each of up to the
+// rate of the compaction
+var compactionPageInterval = time.Second
+
+// compactCache deletes the cache items older than the maximum age or without a hit for the
+// idle days. The scan and the deletes are paced at the rate of the request so they don't
+// compete with production reads, and a scan that runs out of time is resumed by the next
+// run. Items cached before their creation time was recorded are skipped.
+func (h *handler) compactCache(ctx context.Context, request CacheJobRequest) (CacheJobResponse, error) {
+	if request.MaxAgeDays <= 0 && request.IdleDays <= 0 {
+		return CacheJobResponse{}, fmt.Errorf("max_age_days or idle_days is required")
+	}
+	rate := request.Rate
+	if rate <= 0 {
+		rate = defaultCompactionRate
+	}
+
+	now := time.Now()
+	evictable := func(item map[string]types.AttributeValue) (evict, known bool) {
+		// The translations approved by a reviewer are never evicted
+		if authoritative, ok := item["authoritative"].(*types.AttributeValueMemberBOOL); ok && authoritative.Value {
+			return false, true
+		}
+		createdAt, ok := numberAttribute(item, "created_at")
+		if !ok {
+			return false, false
+		}
+		if request.MaxAgeDays > 0 && now.Sub(time.Unix(createdAt, 0)) > days(request.MaxAgeDays) {
+			return true, true
+		}
+		lastUsed := createdAt
+		if lastHitAt, ok := numberAttribute(item, "last_hit_at"); ok {
+			lastUsed = lastHitAt
+		}
+		return request.IdleDays > 0 && now.Sub(time.Unix(lastUsed, 0)) > days(request.IdleDays), true
+	}
+
+	startKey, err := h.loadCompactionCursor(ctx)
+	if err != nil {
+		return CacheJobResponse{}, err
+	}
+
+	pace := time.NewTicker(time.Second / time.Duration(rate))
+	defer pace.Stop()
+
+	response := CacheJobResponse{Action: request.Action}
+	for {
+		pageStart := time.Now()
+		out, err := h.dynamoClient.Scan(ctx, &dynamodb.ScanInput{
+			TableName:         aws.String(translateTableName),
+			ExclusiveStartKey: startKey,
+			Limit:             aws.Int32(int32(rate)),
+		})
+		if err != nil {
+			return response, fmt.Errorf("error scanning cache: %w", err)
+		}
+
+		for _, item := range out.Items {
+			hash, ok := item["hash"].(*types.AttributeValueMemberS)
+			if !ok || hash.Value == supportedLanguagesHash || hash.Value == compactionCursorHash {
+				continue
+			}
+			response.Scanned++
+
+			evict, known := evictable(item)
+			if !known {
+				response.Skipped++
+			}
+			if !evict {
+				continue
+			}
+
+			select {
+			case <-pace.C:
+			case <-ctx.Done():
+				return response, ctx.Err()
+			}
+			if err := h.deleteCacheItem(ctx, item); err != nil {
+				return response, fmt.Errorf("error evicting cache item %s: %w", hash.Value, err)
+			}
+			response.Items++
+		}
+
+		startKey = out.LastEvaluatedKey
+		if len(startKey) == 0 {
+			break
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < compactionDeadlineMargin {
+			response.Partial = true
+			break
+		}
+
+		// Pages are paced whether or not their items were evicted
+		select {
+		case <-time.After(time.Until(pageStart.Add(compactionPageInterval))):
+		case <-ctx.Done():
+			return response, ctx.Err()
+		}
+	}
+
+	if err := h.saveCompactionCursor(ctx, startKey); err != nil {
+		return response, err
+	}
+
+	log.Printf("Evicted %d of %d cache items, skipped %d without a creation time, partial %v", response.Items, response.Scanned, response.Skipped, response.Partial)
+	return response, nil
+}
+
+// loadCompactionCursor returns the key the previous compaction stopped at, nil to start from
+// the beginning of the table
+func (h *handler) loadCompactionCursor(ctx context.Context) (map[string]types.AttributeValue, error) {
+	out, err := h.dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(translateTableName),
+		Key: map[string]types.AttributeValue{
+			"hash": &types.AttributeValueMemberS{Value: compactionCursorHash},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading compaction cursor: %w", err)
+	}
+
+	cursor, ok := out.Item["cursor"].(*types.AttributeValueMemberS)
+	if !ok {
+		return nil, nil
+	}
+	return map[string]types.AttributeValue{"hash": &types.AttributeValueMemberS{Value: cursor.Value}}, nil
+}
+
+// saveCompactionCursor records the key the next compaction resumes from, removing the cursor
+// once the whole table was scanned
+func (h *handler) saveCompactionCursor(ctx context.Context, startKey map[string]types.AttributeValue) error {
+	key := map[string]types.AttributeValue{
+		"hash": &types.AttributeValueMemberS{Value: compactionCursorHash},
+	}
+
+	var err error
+	if hash, ok := startKey["hash"].(*types.AttributeValueMemberS); ok {
+		_, err = h.dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(translateTableName),
+			Item: map[string]types.AttributeValue{
+				"hash":   key["hash"],
+				"cursor": &types.AttributeValueMemberS{Value: hash.Value},
+			},
+		})
+	} else {
+		_, err = h.dynamoClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(translateTableName),
+			Key:       key,
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("error saving compaction cursor: %w", err)
+	}
+	return nil
+}
+
+// numberAttribute returns the integer value of a number attribute of the item
+func numberAttribute(item map[string]types.AttributeValue, name string) (int64, bool) {
+	value, ok := item[name].(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, false
+	}
+	number, err := strconv.ParseInt(value.Value, 10, 64)
+	return number, err == nil
+}
+
+// days returns the duration of a number of days
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestCompactCache(t *testing.T) {
+	previous := compactionPageInterval
+	compactionPageInterval = 0
+	defer func() { compactionPageInterval = previous }()
+
+	daysAgo := func(n int) string {
+		return strconv.FormatInt(time.Now().Add(-days(n)).Unix(), 10)
+	}
+	item := func(hash, createdAt, lastHitAt string) map[string]dynamoTypes.AttributeValue {
+		item := map[string]dynamoTypes.AttributeValue{"hash": &dynamoTypes.AttributeValueMemberS{Value: hash}}
+		if createdAt != "" {
+			item["created_at"] = &dynamoTypes.AttributeValueMemberN{Value: createdAt}
+		}
+		if lastHitAt != "" {
+			item["last_hit_at"] = &dynamoTypes.AttributeValueMemberN{Value: lastHitAt}
+		}
+		return item
+	}
+	// A translation approved by a reviewer is kept however old
+	approved := item("approved", daysAgo(100), "")
+	approved["authoritative"] = &dynamoTypes.AttributeValueMemberBOOL{Value: true}
+	// pages are the scanned pages of the table, each page starting after the last item of the
+	// previous one
+	pages := [][]map[string]dynamoTypes.AttributeValue{
+		{
+			item("old", daysAgo(100), daysAgo(1)),
+			item("idle", daysAgo(40), daysAgo(31)),
+			item(supportedLanguagesHash, "", ""),
+		},
+		{
+			item("recently-hit", daysAgo(40), daysAgo(2)),
+			item("new", daysAgo(1), ""),
+			item("legacy", "", ""),
+			approved,
+		},
+	}
+
+	tests := []struct {
+		name             string
+		request          CacheJobRequest
+		cursor           string
+		timeout          time.Duration
+		expectedDeleted  []string
+		expectedCursor   string
+		expectedResponse CacheJobResponse
+		wantErr          bool
+	}{
+		{
+			name:             "Old and idle items are evicted",
+			request:          CacheJobRequest{Action: cacheJobCompact, MaxAgeDays: 90, IdleDays: 30, Rate: 1000},
+			expectedDeleted:  []string{"old", "idle", compactionCursorHash},
+			expectedResponse: CacheJobResponse{Action: cacheJobCompact, Items: 2, Scanned: 6, Skipped: 1},
+		},
+		{
+			name:             "Only old items are evicted",
+			request:          CacheJobRequest{Action: cacheJobCompact, MaxAgeDays: 90, Rate: 1000},
+			expectedDeleted:  []string{"old", compactionCursorHash},
+			expectedResponse: CacheJobResponse{Action: cacheJobCompact, Items: 1, Scanned: 6, Skipped: 1},
+		},
+		{
+			name:             "Compaction resumes from the cursor",
+			request:          CacheJobRequest{Action: cacheJobCompact, IdleDays: 30, Rate: 1000},
+			cursor:           supportedLanguagesHash,
+			expectedDeleted:  []string{compactionCursorHash},
+			expectedResponse: CacheJobResponse{Action: cacheJobCompact, Items: 0, Scanned: 4, Skipped: 1},
+		},
+		{
+			name:             "Cursor is saved when time runs out",
+			request:          CacheJobRequest{Action: cacheJobCompact, IdleDays: 30, Rate: 1000},
+			timeout:          compactionDeadlineMargin - time.Second,
+			expectedDeleted:  []string{"idle"},
+			expectedCursor:   supportedLanguagesHash,
+			expectedResponse: CacheJobResponse{Action: cacheJobCompact, Items: 1, Scanned: 2, Partial: true},
+		},
+		{
+			name:    "Thresholds are required",
+			request: CacheJobRequest{Action: cacheJobCompact},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				deleted []string
+				cursor  string
+			)
+
+			h := newMockHandler(nil)
+			h.dynamoClient = &MockDynamoDBClient{
+				GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					if tt.cursor == "" {
+						return &dynamodb.GetItemOutput{}, nil
+					}
+					return &dynamodb.GetItemOutput{Item: map[string]dynamoTypes.AttributeValue{
+						"hash":   &dynamoTypes.AttributeValueMemberS{Value: compactionCursorHash},
+						"cursor": &dynamoTypes.AttributeValueMemberS{Value: tt.cursor},
+					}}, nil
+				},
+				ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+					page := 0
+					if params.ExclusiveStartKey != nil {
+						page = 1
+					}
+					out := &dynamodb.ScanOutput{Items: pages[page]}
+					if page == 0 {
+						out.LastEvaluatedKey = map[string]dynamoTypes.AttributeValue{"hash": pages[0][2]["hash"]}
+					}
+					return out, nil
+				},
+				DeleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+					deleted = append(deleted, params.Key["hash"].(*dynamoTypes.AttributeValueMemberS).Value)
+					return &dynamodb.DeleteItemOutput{}, nil
+				},
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					cursor = params.Item["cursor"].(*dynamoTypes.AttributeValueMemberS).Value
+					return &dynamodb.PutItemOutput{}, nil
+				},
+			}
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			got, err := h.handleCacheJob(ctx, tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handleCacheJob() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got != tt.expectedResponse {
+				t.Errorf("handleCacheJob() = %+v, expected %+v", got, tt.expectedResponse)
+			}
+			if !slices.Equal(deleted, tt.expectedDeleted) {
+				t.Errorf("DeleteItem() = %v, expected %v", deleted, tt.expectedDeleted)
+			}
+			if cursor != tt.expectedCursor {
+				t.Errorf("PutItem() cursor = %q, expected %q", cursor, tt.expectedCursor)
+			}
+		})
+	}
+}
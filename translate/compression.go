@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	cacheEncodingZstd = "zstd"
+
+	// minCompressedTextLength is the combined text length below which compression isn't
+	// worth it, short sentences grow once the zstd frame header is added
+	minCompressedTextLength = 128
+)
+
+var (
+	// The encoder and decoder are safe for concurrent use through EncodeAll and DecodeAll
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// encodeTextAttributes adds the source and translated text attributes to the item,
+// compressing them when cache compression is enabled
+func encodeTextAttributes(attributes map[string]types.AttributeValue, sourceText, translatedText string) {
+	if cacheCompression != cacheEncodingZstd || len(sourceText)+len(translatedText) < minCompressedTextLength {
+		attributes["source_text"] = &types.AttributeValueMemberS{Value: sourceText}
+		attributes["translated_text"] = &types.AttributeValueMemberS{Value: translatedText}
+		return
+	}
+
+	attributes["source_text"] = &types.AttributeValueMemberB{Value: zstdEncoder.EncodeAll([]byte(sourceText), nil)}
+	attributes["translated_text"] = &types.AttributeValueMemberB{Value: zstdEncoder.EncodeAll([]byte(translatedText), nil)}
+	attributes["encoding"] = &types.AttributeValueMemberS{Value: cacheEncodingZstd}
+}
+
+// decodeTextAttribute reads a text attribute written by encodeTextAttributes. Compressed
+// items are always decoded, even when compression has since been turned off.
+func decodeTextAttribute(attribute types.AttributeValue, encoding string) (string, bool) {
+	switch value := attribute.(type) {
+	case *types.AttributeValueMemberS:
+		if encoding != "" {
+			return "", false
+		}
+		return value.Value, true
+	case *types.AttributeValueMemberB:
+		if encoding != cacheEncodingZstd {
+			return "", false
+		}
+		text, err := zstdDecoder.DecodeAll(value.Value, nil)
+		if err != nil {
+			return "", false
+		}
+		return string(text), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestEncodeTextAttributes(t *testing.T) {
+	longText := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 10)
+
+	tests := []struct {
+		name               string
+		compression        string
+		sourceText         string
+		translatedText     string
+		expectedCompressed bool
+	}{
+		{
+			name:               "Compression disabled",
+			compression:        "",
+			sourceText:         longText,
+			translatedText:     longText,
+			expectedCompressed: false,
+		},
+		{
+			name:               "Long text is compressed",
+			compression:        cacheEncodingZstd,
+			sourceText:         longText,
+			translatedText:     longText,
+			expectedCompressed: true,
+		},
+		{
+			name:               "Short text is not compressed",
+			compression:        cacheEncodingZstd,
+			sourceText:         "Hello",
+			translatedText:     "Hola",
+			expectedCompressed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compression := cacheCompression
+			cacheCompression = tt.compression
+			t.Cleanup(func() {
+				cacheCompression = compression
+			})
+
+			var stored map[string]dynamoTypes.AttributeValue
+			mockClient := &MockDynamoDBClient{
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					stored = params.Item
+					return &dynamodb.PutItemOutput{}, nil
+				},
+			}
+
+			item := CacheItem{
+				Hash:           "test-hash",
+				TranslatedText: tt.translatedText,
+				SourceText:     tt.sourceText,
+				SourceLanguage: "en",
+				TargetLanguage: "es",
+			}
+			if err := cacheTranslatedText(context.Background(), mockClient, item); err != nil {
+				t.Errorf("cacheTranslatedText() error = %v", err)
+				return
+			}
+
+			_, compressed := stored["translated_text"].(*dynamoTypes.AttributeValueMemberB)
+			if compressed != tt.expectedCompressed {
+				t.Errorf("cacheTranslatedText() compressed = %v, expected %v", compressed, tt.expectedCompressed)
+			}
+
+			// Whatever was written must read back as the original item
+			got, ok := cacheItemFromAttributes(stored)
+			if !ok || got != item {
+				t.Errorf("cacheItemFromAttributes() = %v, %v, expected %v", got, ok, item)
+			}
+		})
+	}
+}
+
+func TestDecodeTextAttribute(t *testing.T) {
+	tests := []struct {
+		name       string
+		attribute  dynamoTypes.AttributeValue
+		encoding   string
+		expected   string
+		expectedOK bool
+	}{
+		{
+			name:       "Plain string",
+			attribute:  &dynamoTypes.AttributeValueMemberS{Value: "Hola"},
+			encoding:   "",
+			expected:   "Hola",
+			expectedOK: true,
+		},
+		{
+			name:       "Compressed binary",
+			attribute:  &dynamoTypes.AttributeValueMemberB{Value: zstdEncoder.EncodeAll([]byte("Hola"), nil)},
+			encoding:   cacheEncodingZstd,
+			expected:   "Hola",
+			expectedOK: true,
+		},
+		{
+			name:       "Binary without encoding",
+			attribute:  &dynamoTypes.AttributeValueMemberB{Value: []byte("Hola")},
+			encoding:   "",
+			expected:   "",
+			expectedOK: false,
+		},
+		{
+			name:       "Corrupt compressed binary",
+			attribute:  &dynamoTypes.AttributeValueMemberB{Value: []byte("not zstd")},
+			encoding:   cacheEncodingZstd,
+			expected:   "",
+			expectedOK: false,
+		},
+		{
+			name:       "Unknown encoding",
+			attribute:  &dynamoTypes.AttributeValueMemberS{Value: "Hola"},
+			encoding:   "gzip",
+			expected:   "",
+			expectedOK: false,
+		},
+		{
+			name:       "Missing attribute",
+			attribute:  nil,
+			encoding:   "",
+			expected:   "",
+			expectedOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := decodeTextAttribute(tt.attribute, tt.encoding)
+			if ok != tt.expectedOK {
+				t.Errorf("decodeTextAttribute() ok = %v, expected %v", ok, tt.expectedOK)
+			}
+
+			if got != tt.expected {
+				t.Errorf("decodeTextAttribute() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
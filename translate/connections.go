@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// sdkMaxIdleConnsPerHost keeps a connection per concurrent lookup and provider call open
+	// between calls, the sdk default of 10 closing the others after each burst
+	sdkMaxIdleConnsPerHost = 64
+	sdkMaxIdleConns        = 256
+	// sdkIdleConnTimeout is how long an unused connection is kept, longer than the time
+	// between the requests of a warm instance
+	sdkIdleConnTimeout = 5 * time.Minute
+	// sdkDialTimeout and sdkTLSHandshakeTimeout bound opening a connection, far below the sdk
+	// defaults so a stuck connection attempt is retried rather than waited out
+	sdkDialTimeout         = 3 * time.Second
+	sdkTLSHandshakeTimeout = 3 * time.Second
+	sdkKeepAlive           = 30 * time.Second
+
+	// warmConnectionsPerHost is the number of connections opened to each service at init
+	warmConnectionsPerHost = 4
+	// connectionsWarmTimeout bounds the time the init phase waits for the connections
+	connectionsWarmTimeout = 2 * time.Second
+)
+
+var sdkConnections, _ = meter.Int64Counter("translate.sdk.connections",
+	metric.WithDescription("Connections used by sdk calls, by host and whether they were reused"),
+)
+
+// newSDKHTTPClient returns the http client shared by the sdk clients, keeping enough
+// connections open for the concurrent calls of a request so they don't pay for a TLS handshake.
+// The TLS configuration replaces the default one when set.
+func newSDKHTTPClient(tlsConfig *tls.Config) aws.HTTPClient {
+	client := awshttp.NewBuildableClient().
+		WithDialerOptions(func(dialer *net.Dialer) {
+			dialer.Timeout = sdkDialTimeout
+			dialer.KeepAlive = sdkKeepAlive
+		}).
+		WithTransportOptions(func(transport *http.Transport) {
+			transport.MaxIdleConns = sdkMaxIdleConns
+			transport.MaxIdleConnsPerHost = sdkMaxIdleConnsPerHost
+			transport.IdleConnTimeout = sdkIdleConnTimeout
+			transport.TLSHandshakeTimeout = sdkTLSHandshakeTimeout
+			if tlsConfig != nil {
+				transport.TLSClientConfig = tlsConfig.Clone()
+			}
+		})
+	return &connectionRecordingClient{client: client}
+}
+
+// newProviderTransport returns the transport shared by the clients calling other endpoints
+// than those of the sdk: the providers with their own API, GitHub and the web pages. The TLS
+// configuration replaces the default one when set, the default transport is left alone.
+func newProviderTransport(tlsConfig *tls.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: sdkDialTimeout, KeepAlive: sdkKeepAlive}).DialContext
+	transport.TLSHandshakeTimeout = sdkTLSHandshakeTimeout
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig.Clone()
+	}
+	return transport
+}
+
+// newProviderHTTPClient returns the http client of a provider called over its own API rather
+// than through the sdk. Every call is bounded by the timeout, so a stalled endpoint fails fast
+// and trips the breaker of the provider instead of holding the request until the deadline.
+func newProviderHTTPClient(transport *http.Transport, timeout time.Duration) *http.Client {
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+// connectionRecordingClient counts whether the connection of each call was reused
+type connectionRecordingClient struct {
+	client aws.HTTPClient
+}
+
+func (c *connectionRecordingClient) Do(request *http.Request) (*http.Response, error) {
+	ctx := request.Context()
+	host := request.URL.Hostname()
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			sdkConnections.Add(ctx, 1, metric.WithAttributes(
+				attribute.String("host", host),
+				attribute.Bool("reused", info.Reused),
+			))
+		},
+	}
+	return c.client.Do(request.WithContext(httptrace.WithClientTrace(ctx, trace)))
+}
+
+// sdkEndpoints returns the endpoints of the services called for every request, the endpoint
+// of a service or the base endpoint of the configuration when they override them
+func sdkEndpoints(cfg aws.Config) []string {
+	var endpoints []string
+	for _, service := range []string{serviceTranslate, serviceDynamoDB} {
+		switch endpoint := serviceEndpoint(service, cfg.Region); {
+		case endpoint != nil:
+			endpoints = append(endpoints, *endpoint)
+		case cfg.BaseEndpoint != nil:
+			endpoints = append(endpoints, *cfg.BaseEndpoint)
+		default:
+			endpoints = append(endpoints, fmt.Sprintf("https://%s.%s.amazonaws.com", service, cfg.Region))
+		}
+	}
+	return slices.Compact(endpoints)
+}
+
+// warmConnections opens connections to the endpoints during the init phase, so the first
+// calls of the first request don't wait for TLS handshakes. Failing to is only logged.
+func warmConnections(ctx context.Context, client aws.HTTPClient, endpoints []string) {
+	ctx, cancel := context.WithTimeout(ctx, connectionsWarmTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, endpoint := range endpoints {
+		for range warmConnectionsPerHost {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := warmConnection(ctx, client, endpoint); err != nil {
+					log.Printf("Error warming connection to %s: %v", endpoint, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// warmConnection opens a connection to the endpoint with an unsigned HEAD request, whatever
+// the status of its response, and returns it to the idle pool
+func warmConnection(ctx context.Context, client aws.HTTPClient, endpoint string) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	// The connection is only reused once the body is read to the end
+	io.Copy(io.Discard, response.Body)
+	return response.Body.Close()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestSDKEndpoints(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       aws.Config
+		endpoints string
+		expected  []string
+	}{
+		{
+			name:     "Regional endpoints",
+			cfg:      aws.Config{Region: "eu-west-1"},
+			expected: []string{"https://translate.eu-west-1.amazonaws.com", "https://dynamodb.eu-west-1.amazonaws.com"},
+		},
+		{
+			name:     "Base endpoint",
+			cfg:      aws.Config{Region: "eu-west-1", BaseEndpoint: aws.String("http://localhost:4566")},
+			expected: []string{"http://localhost:4566"},
+		},
+		{
+			name:      "Service endpoint",
+			cfg:       aws.Config{Region: "eu-west-1"},
+			endpoints: "translate@eu-west-1=https://vpce-0a1b.translate.eu-west-1.vpce.amazonaws.com",
+			expected:  []string{"https://vpce-0a1b.translate.eu-west-1.vpce.amazonaws.com", "https://dynamodb.eu-west-1.amazonaws.com"},
+		},
+		{
+			name:      "Service endpoint over the base endpoint",
+			cfg:       aws.Config{Region: "eu-west-1", BaseEndpoint: aws.String("http://localhost:4566")},
+			endpoints: "dynamodb@eu-west-1=http://localhost:8000",
+			expected:  []string{"http://localhost:4566", "http://localhost:8000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := serviceEndpoints
+			serviceEndpoints = parseServiceEndpoints(tt.endpoints)
+			defer func() { serviceEndpoints = original }()
+
+			if got := sdkEndpoints(tt.cfg); !slices.Equal(got, tt.expected) {
+				t.Errorf("sdkEndpoints() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWarmConnections(t *testing.T) {
+	var opened atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Hold the connection so the concurrent warming calls can't share it
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			opened.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client := newSDKHTTPClient(nil)
+	warmConnections(context.Background(), client, []string{server.URL})
+	if got := opened.Load(); got != warmConnectionsPerHost {
+		t.Fatalf("warmConnections() opened %d connections, expected %d", got, warmConnectionsPerHost)
+	}
+
+	// The calls that follow reuse the warm connections
+	for range warmConnectionsPerHost {
+		if err := warmConnection(context.Background(), client, server.URL); err != nil {
+			t.Fatalf("warmConnection() error = %v", err)
+		}
+	}
+	if got := opened.Load(); got != warmConnectionsPerHost {
+		t.Errorf("calls opened %d connections, expected %d", got, warmConnectionsPerHost)
+	}
+}
+
+func TestProviderHTTPClientTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Stall like an endpoint that stopped answering
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := newProviderHTTPClient(newProviderTransport(nil), 50*time.Millisecond)
+	if client == http.DefaultClient || client.Transport == http.DefaultTransport {
+		t.Fatalf("newProviderHTTPClient() shares the default client")
+	}
+
+	start := time.Now()
+	_, err := client.Get(server.URL)
+	if err == nil {
+		t.Fatalf("Get() error = nil, expected a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Get() returned after %v, expected the timeout", elapsed)
+	}
+}
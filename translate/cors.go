@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	defaultCORSAllowedHeaders = "Content-Type,Accept,X-Api-Key"
+	defaultCORSMaxAge         = 600
+
+	// corsAllowedMethods are the methods of the API resources
+	corsAllowedMethods = "GET,POST,OPTIONS"
+)
+
+var (
+	// corsAllowedOrigins are the origins of the browser clients allowed to call the API, * for
+	// any origin. CORS is disabled when empty.
+	corsAllowedOrigins []string
+	// corsAllowedHeaders are the request headers the browser clients may send
+	corsAllowedHeaders string
+	// corsMaxAge is the number of seconds the browsers cache a preflight response
+	corsMaxAge int
+)
+
+// corsExposedHeaders are the response headers the browser clients may read
+var corsExposedHeaders = strings.Join([]string{traceIDHeader, "Retry-After"}, ",")
+
+// handlePreflight answers the CORS preflight request of a browser. Preflights from origins
+// that aren't allowed get no CORS headers, so the browser blocks the request.
+func handlePreflight(event events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	response := events.APIGatewayProxyResponse{StatusCode: http.StatusNoContent}
+	setCORSHeaders(event, &response)
+	if _, ok := response.Headers["Access-Control-Allow-Origin"]; ok {
+		response.Headers["Access-Control-Allow-Methods"] = corsAllowedMethods
+		response.Headers["Access-Control-Allow-Headers"] = corsAllowedHeaders
+		response.Headers["Access-Control-Max-Age"] = strconv.Itoa(corsMaxAge)
+	}
+	return response
+}
+
+// setCORSHeaders allows the origin of the request to read the response when it is allowed
+func setCORSHeaders(event events.APIGatewayProxyRequest, response *events.APIGatewayProxyResponse) {
+	if len(corsAllowedOrigins) == 0 {
+		return
+	}
+
+	origin := headerValue(event.Headers, "Origin")
+	allowed := "*"
+	if !slices.Contains(corsAllowedOrigins, "*") {
+		if origin == "" || !slices.Contains(corsAllowedOrigins, origin) {
+			return
+		}
+		allowed = origin
+	}
+
+	if response.Headers == nil {
+		response.Headers = make(map[string]string)
+	}
+	response.Headers["Access-Control-Allow-Origin"] = allowed
+	response.Headers["Access-Control-Expose-Headers"] = corsExposedHeaders
+	// The response depends on the origin unless every origin is allowed
+	if allowed != "*" {
+		response.Headers["Vary"] = "Origin"
+	}
+}
+
+// parseCORSOrigins parses a list of origins separated by commas, without trailing slashes
+func parseCORSOrigins(value string) []string {
+	var origins []string
+	for _, origin := range strings.Split(value, ",") {
+		if origin = strings.TrimSuffix(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
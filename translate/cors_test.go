@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestHandlePreflight(t *testing.T) {
+	tests := []struct {
+		name            string
+		origins         []string
+		origin          string
+		expectedHeaders map[string]string
+	}{
+		{
+			name:    "Allowed origin",
+			origins: []string{"https://app.example.com", "chrome-extension://abcdef"},
+			origin:  "chrome-extension://abcdef",
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":   "chrome-extension://abcdef",
+				"Access-Control-Allow-Methods":  corsAllowedMethods,
+				"Access-Control-Allow-Headers":  defaultCORSAllowedHeaders,
+				"Access-Control-Max-Age":        "600",
+				"Access-Control-Expose-Headers": corsExposedHeaders,
+				"Vary":                          "Origin",
+			},
+		},
+		{
+			name:    "Any origin",
+			origins: []string{"*"},
+			origin:  "https://other.example.com",
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":   "*",
+				"Access-Control-Allow-Methods":  corsAllowedMethods,
+				"Access-Control-Allow-Headers":  defaultCORSAllowedHeaders,
+				"Access-Control-Max-Age":        "600",
+				"Access-Control-Expose-Headers": corsExposedHeaders,
+			},
+		},
+		{
+			name:    "Origin not allowed",
+			origins: []string{"https://app.example.com"},
+			origin:  "https://evil.example.com",
+		},
+		{
+			name:   "CORS disabled",
+			origin: "https://app.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousOrigins, previousHeaders, previousMaxAge := corsAllowedOrigins, corsAllowedHeaders, corsMaxAge
+			corsAllowedOrigins, corsAllowedHeaders, corsMaxAge = tt.origins, defaultCORSAllowedHeaders, defaultCORSMaxAge
+			defer func() {
+				corsAllowedOrigins, corsAllowedHeaders, corsMaxAge = previousOrigins, previousHeaders, previousMaxAge
+			}()
+
+			h := newMockHandler(nil)
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+				HTTPMethod: http.MethodOptions,
+				Resource:   "/translate",
+				Headers:    map[string]string{"origin": tt.origin},
+			})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != http.StatusNoContent {
+				t.Errorf("handle() status = %d, expected %d", got.StatusCode, http.StatusNoContent)
+			}
+			if len(got.Headers) != len(tt.expectedHeaders) || (len(got.Headers) > 0 && !reflect.DeepEqual(got.Headers, tt.expectedHeaders)) {
+				t.Errorf("handle() headers = %v, expected %v", got.Headers, tt.expectedHeaders)
+			}
+		})
+	}
+}
+
+func TestCORSHeadersOnResponses(t *testing.T) {
+	previousOrigins := corsAllowedOrigins
+	corsAllowedOrigins = []string{"https://app.example.com"}
+	defer func() { corsAllowedOrigins = previousOrigins }()
+
+	h := newMockHandler(map[string]string{"Hello": "Hola"})
+	got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodPost,
+		Headers:    map[string]string{"Origin": "https://app.example.com"},
+		Body:       `{"source_language":"en","target_language":"es","text":"Hello"}`,
+	})
+	if err != nil {
+		t.Fatalf("handle() error = %v", err)
+	}
+	if got.StatusCode != http.StatusOK {
+		t.Fatalf("handle() status = %d, expected %d", got.StatusCode, http.StatusOK)
+	}
+	if origin := got.Headers["Access-Control-Allow-Origin"]; origin != "https://app.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q, expected the request origin", origin)
+	}
+	if _, ok := got.Headers["Access-Control-Max-Age"]; ok {
+		t.Error("Access-Control-Max-Age set on a response other than a preflight")
+	}
+}
+
+func TestParseCORSOrigins(t *testing.T) {
+	got := parseCORSOrigins(" https://app.example.com/, ,chrome-extension://abcdef")
+	expected := []string{"https://app.example.com", "chrome-extension://abcdef"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("parseCORSOrigins() = %v, expected %v", got, expected)
+	}
+}
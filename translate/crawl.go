@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	handlerModeCrawlJob = "crawl-job"
+
+	// crawlJobPrefix is the prefix of the translated pages, keyed by language and URL
+	crawlJobPrefix = "crawl/"
+
+	// maxSitemapDepth is the number of nested sitemap indexes followed
+	maxSitemapDepth = 2
+
+	defaultCrawlConcurrency = 4
+	defaultCrawlMaxPages    = 500
+)
+
+var (
+	// crawlConcurrency is the number of pages a crawl job fetches and translates at once
+	crawlConcurrency int
+	// crawlMaxPages is the number of pages a crawl job translates, the others are skipped
+	crawlMaxPages int
+)
+
+// errRobotsDisallowed is the error of a page robots.txt doesn't allow crawling
+var errRobotsDisallowed = errors.New("disallowed by robots.txt")
+
+// CrawlJobRequest is the event of a job fetching a list of web pages and storing their
+// translations in the bulk bucket
+type CrawlJobRequest struct {
+	// URLs are the pages to translate
+	URLs []string `json:"urls"`
+	// SitemapURL is the sitemap.xml listing pages to translate along with the URLs, sitemap
+	// indexes are followed
+	SitemapURL string `json:"sitemap_url"`
+	// SourceLanguage is the language code of the pages
+	SourceLanguage string `json:"source_language"`
+	// TargetLanguages are the language codes the pages are translated to
+	TargetLanguages []string `json:"target_languages"`
+}
+
+// CrawlJobResult reports the outcome of a crawl job
+type CrawlJobResult struct {
+	// Pages are the translated pages
+	Pages []CrawlPage `json:"pages"`
+	// Failed are the pages that couldn't be fetched or translated
+	Failed []CrawlFailure `json:"failed,omitempty"`
+	// Disallowed are the URLs robots.txt doesn't allow crawling
+	Disallowed []string `json:"disallowed,omitempty"`
+	// Skipped is the number of URLs over the page limit of the job
+	Skipped int `json:"skipped,omitempty"`
+}
+
+// CrawlPage is a page translated by a crawl job
+type CrawlPage struct {
+	URL string `json:"url"`
+	// OutputKeys are the keys of the translated page in the bulk bucket by language
+	OutputKeys map[string]string `json:"output_keys"`
+}
+
+// CrawlFailure is a page a crawl job failed to translate
+type CrawlFailure struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+// handleCrawlJob fetches the pages of the request, at most crawlConcurrency at once, and
+// stores their translations in the bulk bucket. A page failing doesn't fail the job, it is
+// reported in the result instead.
+func (h *handler) handleCrawlJob(ctx context.Context, request CrawlJobRequest) (CrawlJobResult, error) {
+	defer flushTelemetry(ctx)
+
+	if len(request.URLs) == 0 && request.SitemapURL == "" {
+		return CrawlJobResult{}, fmt.Errorf("urls or sitemap_url is required")
+	}
+	if request.SourceLanguage == "" || request.SourceLanguage == autoDetectLanguage || len(request.TargetLanguages) == 0 {
+		return CrawlJobResult{}, fmt.Errorf("source_language and target_languages are required")
+	}
+	for _, targetLanguage := range request.TargetLanguages {
+		if err := configuredLanguagePolicy.check(request.SourceLanguage, targetLanguage); err != nil {
+			return CrawlJobResult{}, fmt.Errorf("language pair %s to %s not allowed: %w", request.SourceLanguage, targetLanguage, err)
+		}
+	}
+
+	ctx, writer := h.withCacheWriter(ctx)
+	defer writer.Close()
+
+	crawler := &crawler{client: h.pageClient(), robots: map[string]*robotsRules{}}
+
+	urls := request.URLs
+	if request.SitemapURL != "" {
+		listed, err := crawler.sitemapURLs(ctx, request.SitemapURL, 0)
+		if err != nil {
+			return CrawlJobResult{}, fmt.Errorf("error reading sitemap %s: %w", request.SitemapURL, err)
+		}
+		urls = append(urls, listed...)
+	}
+
+	var (
+		result CrawlJobResult
+		seen   = map[string]bool{}
+		pages  []string
+	)
+	for _, pageURL := range urls {
+		if seen[pageURL] {
+			continue
+		}
+		seen[pageURL] = true
+		if len(pages) == crawlMaxPages {
+			result.Skipped++
+			continue
+		}
+		pages = append(pages, pageURL)
+	}
+
+	// Every page has its own slot so the result keeps the order of the request
+	var (
+		translated = make([]*CrawlPage, len(pages))
+		failures   = make([]error, len(pages))
+		group      errgroup.Group
+	)
+	group.SetLimit(max(crawlConcurrency, 1))
+	for i, pageURL := range pages {
+		group.Go(func() error {
+			translated[i], failures[i] = h.translatePage(ctx, crawler, request, pageURL)
+			return nil
+		})
+	}
+	group.Wait()
+
+	for i, pageURL := range pages {
+		switch {
+		case errors.Is(failures[i], errRobotsDisallowed):
+			result.Disallowed = append(result.Disallowed, pageURL)
+		case failures[i] != nil:
+			result.Failed = append(result.Failed, CrawlFailure{URL: pageURL, Error: failures[i].Error()})
+		default:
+			result.Pages = append(result.Pages, *translated[i])
+		}
+	}
+
+	log.Printf("Crawled %d pages: %d translated, %d failed, %d disallowed, %d skipped",
+		len(pages), len(result.Pages), len(result.Failed), len(result.Disallowed), result.Skipped)
+	return result, nil
+}
+
+// translatePage fetches an HTML page and stores its translation to every target language
+func (h *handler) translatePage(ctx context.Context, crawler *crawler, request CrawlJobRequest, pageURL string) (*CrawlPage, error) {
+	page, err := url.Parse(pageURL)
+	if err != nil || (page.Scheme != "http" && page.Scheme != "https") || page.Host == "" {
+		return nil, fmt.Errorf("url is not an http or https url")
+	}
+
+	allowed, err := crawler.allowed(ctx, page)
+	if err != nil {
+		return nil, fmt.Errorf("error reading robots.txt: %w", err)
+	}
+	if !allowed {
+		return nil, errRobotsDisallowed
+	}
+
+	body, contentType, err := fetchPage(ctx, crawler.client, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "text/html" {
+		return nil, fmt.Errorf("content type %q is not html", contentType)
+	}
+	source, err := decodeHTMLPage(body, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CrawlPage{URL: pageURL, OutputKeys: map[string]string{}}
+	for _, targetLanguage := range request.TargetLanguages {
+		translated, err := h.translateHTML(ctx, request.SourceLanguage, targetLanguage, source)
+		if err != nil {
+			return nil, fmt.Errorf("error translating to %s: %w", targetLanguage, err)
+		}
+
+		key := crawlOutputKey(targetLanguage, page)
+		_, err = h.s3Client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(bulkBucket),
+			Key:         aws.String(key),
+			Body:        strings.NewReader(translated),
+			ContentType: aws.String("text/html; charset=utf-8"),
+			Metadata:    map[string]string{"source-url": pageURL},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error storing the %s translation: %w", targetLanguage, err)
+		}
+		result.OutputKeys[targetLanguage] = key
+	}
+	return result, nil
+}
+
+// crawlOutputKey returns the key of the translation of a page, the host and path of its URL
+// under the language. Directories are stored as their index.html and the query, if any, is
+// appended escaped so it doesn't add path segments.
+func crawlOutputKey(language string, page *url.URL) string {
+	pagePath := page.EscapedPath()
+	if pagePath == "" || strings.HasSuffix(pagePath, "/") {
+		pagePath += "index.html"
+	}
+	key := crawlJobPrefix + language + "/" + strings.ToLower(page.Host) + path.Clean("/"+pagePath)
+	if page.RawQuery != "" {
+		key += "%3F" + url.QueryEscape(page.RawQuery)
+	}
+	return key
+}
+
+// crawler fetches the pages of a crawl job, reading the robots.txt of each host once
+type crawler struct {
+	client *http.Client
+
+	mu     sync.Mutex
+	robots map[string]*robotsRules
+}
+
+// allowed reports whether the robots.txt of the host of the page allows crawling it. A
+// missing robots.txt allows everything, an unreachable one disallows everything until the
+// next job.
+func (c *crawler) allowed(ctx context.Context, page *url.URL) (bool, error) {
+	origin := page.Scheme + "://" + page.Host
+
+	c.mu.Lock()
+	rules, ok := c.robots[origin]
+	c.mu.Unlock()
+	if !ok {
+		body, _, err := fetchPage(ctx, c.client, origin+"/robots.txt")
+		var statusErr *fetchStatusError
+		switch {
+		case err == nil:
+			rules = parseRobots(string(body), pageUserAgent)
+		case errors.As(err, &statusErr) && statusErr.StatusCode >= 400 && statusErr.StatusCode < 500:
+			rules = &robotsRules{}
+		default:
+			return false, err
+		}
+
+		c.mu.Lock()
+		c.robots[origin] = rules
+		c.mu.Unlock()
+	}
+
+	target := page.EscapedPath()
+	if target == "" {
+		target = "/"
+	}
+	if page.RawQuery != "" {
+		target += "?" + page.RawQuery
+	}
+	return rules.allows(target), nil
+}
+
+// robotsRules are the allow and disallow rules of robots.txt obeyed by the crawler
+type robotsRules struct {
+	rules []robotsRule
+}
+
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// parseRobots returns the rules of the robots.txt groups of the user agent, falling back to
+// the groups of "*" when none names it. Consecutive user-agent lines share a group.
+func parseRobots(body, userAgent string) *robotsRules {
+	var (
+		named, wildcard []robotsRule
+		agents          []string
+		inRules         bool
+		hasNamed        bool
+	)
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		value = strings.TrimSpace(value)
+
+		switch field {
+		case "user-agent":
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			// An empty disallow allows everything, like no rule at all
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{pattern: value, allow: field == "allow"}
+			for _, agent := range agents {
+				switch {
+				case agent == strings.ToLower(userAgent):
+					named = append(named, rule)
+					hasNamed = true
+				case agent == "*":
+					wildcard = append(wildcard, rule)
+				}
+			}
+		}
+	}
+
+	if hasNamed {
+		return &robotsRules{rules: named}
+	}
+	return &robotsRules{rules: wildcard}
+}
+
+// allows reports whether the path, with its query, may be crawled. The longest matching rule
+// wins, allow winning a tie, and a path without a matching rule is allowed.
+func (r *robotsRules) allows(target string) bool {
+	allowed, longest := true, -1
+	for _, rule := range r.rules {
+		if !matchRobotsPattern(rule.pattern, target) {
+			continue
+		}
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			allowed, longest = rule.allow, len(rule.pattern)
+		}
+	}
+	return allowed
+}
+
+// matchRobotsPattern matches a path prefix pattern, where * matches any characters and a
+// trailing $ anchors the pattern to the end of the path
+func matchRobotsPattern(pattern, target string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(target, parts[0]) {
+		return false
+	}
+	rest := target[len(parts[0]):]
+	for i, part := range parts[1:] {
+		// The last part of an anchored pattern must end the path
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		index := strings.Index(rest, part)
+		if index < 0 {
+			return false
+		}
+		rest = rest[index+len(part):]
+	}
+	return !anchored || rest == ""
+}
+
+// sitemapDocument is either a sitemap listing pages or a sitemap index listing sitemaps
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []string `xml:"url>loc"`
+	Sitemaps []string `xml:"sitemap>loc"`
+}
+
+// sitemapURLs returns the page URLs listed by the sitemap and the sitemaps of an index
+func (c *crawler) sitemapURLs(ctx context.Context, sitemapURL string, depth int) ([]string, error) {
+	body, _, err := fetchPage(ctx, c.client, sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var sitemap sitemapDocument
+	if err := xml.NewDecoder(bytes.NewReader(body)).Decode(&sitemap); err != nil {
+		return nil, fmt.Errorf("invalid sitemap: %w", err)
+	}
+
+	var urls []string
+	for _, loc := range sitemap.URLs {
+		urls = append(urls, strings.TrimSpace(loc))
+	}
+	if sitemap.XMLName.Local == "sitemapindex" {
+		if depth >= maxSitemapDepth {
+			return nil, fmt.Errorf("sitemap indexes are nested more than %d deep", maxSitemapDepth)
+		}
+		for _, loc := range sitemap.Sitemaps {
+			nested, err := c.sitemapURLs(ctx, strings.TrimSpace(loc), depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("error reading sitemap %s: %w", loc, err)
+			}
+			urls = append(urls, nested...)
+		}
+	}
+	return urls, nil
+}
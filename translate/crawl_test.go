@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRobotsRules(t *testing.T) {
+	robots := `# Crawlers
+User-agent: *
+Disallow: /private/
+Allow: /private/press
+Disallow: /*.pdf$
+Disallow: /search?
+
+User-agent: other
+Disallow: /
+`
+	named := robots + `
+User-agent: GoTranslate
+User-agent: another
+Disallow: /drafts
+`
+
+	tests := []struct {
+		name     string
+		robots   string
+		path     string
+		expected bool
+	}{
+		{name: "No rule", robots: robots, path: "/about", expected: true},
+		{name: "Disallowed prefix", robots: robots, path: "/private/notes", expected: false},
+		{name: "Longer allow wins", robots: robots, path: "/private/press/2024", expected: true},
+		{name: "Anchored wildcard", robots: robots, path: "/files/report.pdf", expected: false},
+		{name: "Anchored wildcard not at the end", robots: robots, path: "/files/report.pdf.html", expected: true},
+		{name: "Query disallowed", robots: robots, path: "/search?q=shoes", expected: false},
+		{name: "Without the query", robots: robots, path: "/search", expected: true},
+		{name: "Other agent is ignored", robots: robots, path: "/", expected: true},
+		{name: "Named group replaces the wildcard", robots: named, path: "/private/notes", expected: true},
+		{name: "Named group", robots: named, path: "/drafts/one", expected: false},
+		{name: "Empty disallow", robots: "User-agent: *\nDisallow:\n", path: "/private", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseRobots(tt.robots, pageUserAgent).allows(tt.path)
+			if got != tt.expected {
+				t.Errorf("allows(%q) = %v, expected %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCrawlOutputKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{name: "Page", url: "https://Example.com/docs/intro.html", expected: "crawl/es/example.com/docs/intro.html"},
+		{name: "Root", url: "https://example.com", expected: "crawl/es/example.com/index.html"},
+		{name: "Directory", url: "https://example.com/docs/", expected: "crawl/es/example.com/docs/index.html"},
+		{name: "Dot segments", url: "https://example.com/docs/../../etc/passwd", expected: "crawl/es/example.com/etc/passwd"},
+		{name: "Query", url: "https://example.com/search?q=a/b", expected: "crawl/es/example.com/search%3Fq%3Da%2Fb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := crawlOutputKey("es", page); got != tt.expected {
+				t.Errorf("crawlOutputKey() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandleCrawlJob(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != pageUserAgent {
+			t.Errorf("fetch user agent = %q, expected %q", r.Header.Get("User-Agent"), pageUserAgent)
+		}
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+		case "/sitemap.xml":
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>` + server.URL + `/pages.xml</loc></sitemap>
+</sitemapindex>`))
+		case "/pages.xml":
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>` + server.URL + `/</loc></url>
+  <url><loc>` + server.URL + `/private/page</loc></url>
+  <url><loc>` + server.URL + `/logo.png</loc></url>
+  <url><loc>` + server.URL + `/missing</loc></url>
+</urlset>`))
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<p>Hello.</p>"))
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("PNG"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	originalBucket, originalMaxPages, originalConcurrency := bulkBucket, crawlMaxPages, crawlConcurrency
+	bulkBucket, crawlMaxPages, crawlConcurrency = "bulk", 4, 2
+	defer func() {
+		bulkBucket, crawlMaxPages, crawlConcurrency = originalBucket, originalMaxPages, originalConcurrency
+	}()
+
+	objects := map[string]string{}
+	h := newMockHandler(map[string]string{"Hello.": "Hola."})
+	h.s3Client = newMockBulkBucket(objects)
+	h.httpClient = server.Client()
+
+	got, err := h.handleCrawlJob(context.Background(), CrawlJobRequest{
+		URLs:            []string{server.URL + "/private/page", server.URL + "/extra"},
+		SitemapURL:      server.URL + "/sitemap.xml",
+		SourceLanguage:  "en",
+		TargetLanguages: []string{"es"},
+	})
+	if err != nil {
+		t.Fatalf("handleCrawlJob() error = %v", err)
+	}
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	expected := CrawlJobResult{
+		Pages: []CrawlPage{{URL: server.URL + "/", OutputKeys: map[string]string{"es": "crawl/es/" + host + "/index.html"}}},
+		Failed: []CrawlFailure{
+			{URL: server.URL + "/extra", Error: "unexpected status 404"},
+			{URL: server.URL + "/logo.png", Error: `content type "image/png" is not html`},
+		},
+		Disallowed: []string{server.URL + "/private/page"},
+		Skipped:    1,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("handleCrawlJob() = %+v, expected %+v", got, expected)
+	}
+
+	translated := objects["crawl/es/"+host+"/index.html"]
+	if !strings.Contains(translated, "Hola.") {
+		t.Errorf("translated page = %q, expected the translation", translated)
+	}
+}
+
+func TestHandleCrawlJobInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		request CrawlJobRequest
+	}{
+		{name: "No pages", request: CrawlJobRequest{SourceLanguage: "en", TargetLanguages: []string{"es"}}},
+		{name: "No target languages", request: CrawlJobRequest{URLs: []string{"https://example.com"}, SourceLanguage: "en"}},
+		{name: "Detected source language", request: CrawlJobRequest{URLs: []string{"https://example.com"}, SourceLanguage: "auto", TargetLanguages: []string{"es"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newMockHandler(nil).handleCrawlJob(context.Background(), tt.request); err == nil {
+				t.Errorf("handleCrawlJob() error = nil, expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	formatCSV = "csv"
+
+	// bulkOutputPrefix is the prefix of translated CSV files written to the bulk bucket
+	bulkOutputPrefix = "translated/"
+)
+
+// invalidCSVError reports a CSV file that can't be translated as requested
+type invalidCSVError struct {
+	err error
+}
+
+func (e invalidCSVError) Error() string {
+	return e.err.Error()
+}
+
+func (e invalidCSVError) Unwrap() error {
+	return e.err
+}
+
+func (h *handler) handleCSV(ctx context.Context, request TranslateRequest) (events.APIGatewayProxyResponse, error) {
+	var input io.Reader
+	if request.Key != "" {
+		object, err := h.s3Client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bulkBucket),
+			Key:    aws.String(request.Key),
+		})
+		if err != nil {
+			log.Printf("Error fetching csv s3://%s/%s: %v", bulkBucket, request.Key, err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "key could not be read from the bulk bucket",
+			}, nil
+		}
+		defer object.Body.Close()
+		input = object.Body
+	} else {
+		document, err := base64.StdEncoding.DecodeString(request.Document)
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "document must be base64 encoded",
+			}, nil
+		}
+		input = bytes.NewReader(document)
+	}
+
+	var output bytes.Buffer
+	rows, err := h.translateCSV(ctx, request.SourceLanguage, request.TargetLanguage, request.Columns, input, &output)
+	if response, ok := unavailableResponse(err); ok {
+		return response, nil
+	}
+	if response, ok := characterLimitResponse(err); ok {
+		return response, nil
+	}
+	if response, ok := languagePolicyResponse(err); ok {
+		return response, nil
+	}
+	var invalid invalidCSVError
+	if errors.As(err, &invalid) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       fmt.Sprintf("document is not a valid csv file: %v", invalid),
+		}, nil
+	}
+	if err != nil {
+		log.Printf("Error translating csv: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error during translation",
+		}, nil
+	}
+
+	if dryRun := dryRunFromContext(ctx); dryRun != nil {
+		return newJSONResponse(ctx, TranslateResponse{Rows: rows, Estimate: dryRun.Estimate()}), nil
+	}
+
+	response := TranslateResponse{
+		Rows:       rows,
+		Degraded:   degradationFromContext(ctx).Degraded(),
+		LowQuality: degradationFromContext(ctx).LowQuality(),
+		Pivoted:    pivotReportFromContext(ctx).Pivoted(),
+	}
+
+	// Files read from S3 are written back next to the source, they are too large to return
+	if request.Key != "" {
+		outputKey := bulkOutputPrefix + request.TargetLanguage + "/" + request.Key
+		_, err := h.s3Client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(bulkBucket),
+			Key:         aws.String(outputKey),
+			Body:        bytes.NewReader(output.Bytes()),
+			ContentType: aws.String("text/csv"),
+		})
+		if err != nil {
+			log.Printf("Error storing csv s3://%s/%s: %v", bulkBucket, outputKey, err)
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Error storing translation",
+			}, nil
+		}
+		log.Printf("Translated %d rows of s3://%s/%s to %s", rows, bulkBucket, request.Key, outputKey)
+		response.OutputKey = outputKey
+	} else {
+		response.TranslatedText = output.String()
+	}
+
+	return newJSONResponse(ctx, response), nil
+}
+
+// translateCSV translates the named columns of a CSV file row by row, copying the header
+// row and every other column as is. It returns the number of data rows translated.
+func (h *handler) translateCSV(ctx context.Context, sourceLanguage, targetLanguage string, columns []string, r io.Reader, w io.Writer) (int, error) {
+	reader := csv.NewReader(r)
+	writer := csv.NewWriter(w)
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return 0, invalidCSVError{fmt.Errorf("header row is missing")}
+	}
+	if err != nil {
+		return 0, invalidCSVError{err}
+	}
+
+	indexes := make([]int, 0, len(columns))
+	for _, column := range columns {
+		index := -1
+		for i, name := range header {
+			if strings.TrimSpace(name) == column {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return 0, invalidCSVError{fmt.Errorf("column %q is not in the header row", column)}
+		}
+		indexes = append(indexes, index)
+	}
+
+	if err := writer.Write(header); err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return rows, invalidCSVError{err}
+		}
+
+		if err := h.translateFields(ctx, sourceLanguage, targetLanguage, record, indexes); err != nil {
+			return rows, fmt.Errorf("error translating row %d: %w", rows+1, err)
+		}
+		if err := writer.Write(record); err != nil {
+			return rows, err
+		}
+		rows++
+	}
+
+	writer.Flush()
+	return rows, writer.Error()
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestTranslateCSV(t *testing.T) {
+	translations := map[string]string{
+		"Red shirt.":     "Camisa roja.",
+		"Soft cotton.":   "Algodón suave.",
+		"Blue, slim fit": "Azul, ajuste ceñido",
+	}
+
+	tests := []struct {
+		name         string
+		input        string
+		columns      []string
+		expected     string
+		expectedRows int
+		wantInvalid  bool
+	}{
+		{
+			name:         "Selected columns are translated",
+			input:        "sku,title,description\nA1,Red shirt.,Red shirt. Soft cotton.\nA2,\"Blue, slim fit\",\n",
+			columns:      []string{"description"},
+			expected:     "sku,title,description\nA1,Red shirt.,Camisa roja. Algodón suave.\nA2,\"Blue, slim fit\",\n",
+			expectedRows: 2,
+		},
+		{
+			name:         "Several columns",
+			input:        "sku,title\nA2,\"Blue, slim fit\"\n",
+			columns:      []string{"title", "sku"},
+			expected:     "sku,title\nA2,\"Azul, ajuste ceñido\"\n",
+			expectedRows: 1,
+		},
+		{
+			name:         "Header only",
+			input:        "sku,title\n",
+			columns:      []string{"title"},
+			expected:     "sku,title\n",
+			expectedRows: 0,
+		},
+		{
+			name:        "Unknown column",
+			input:       "sku,title\nA1,Red shirt.\n",
+			columns:     []string{"price"},
+			wantInvalid: true,
+		},
+		{
+			name:        "Inconsistent row",
+			input:       "sku,title\nA1,Red shirt.,extra\n",
+			columns:     []string{"title"},
+			wantInvalid: true,
+		},
+		{
+			name:        "Empty file",
+			input:       "",
+			columns:     []string{"title"},
+			wantInvalid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(translations)
+
+			var output bytes.Buffer
+			rows, err := h.translateCSV(context.Background(), "en", "es", tt.columns, strings.NewReader(tt.input), &output)
+			if _, invalid := err.(invalidCSVError); invalid != tt.wantInvalid {
+				t.Errorf("translateCSV() error = %v, wantInvalid %v", err, tt.wantInvalid)
+				return
+			}
+			if tt.wantInvalid {
+				return
+			}
+			if err != nil {
+				t.Errorf("translateCSV() error = %v", err)
+				return
+			}
+
+			if output.String() != tt.expected || rows != tt.expectedRows {
+				t.Errorf("translateCSV() = %q, %d rows, expected %q, %d rows", output.String(), rows, tt.expected, tt.expectedRows)
+			}
+		})
+	}
+}
+
+func TestHandleCSV(t *testing.T) {
+	translations := map[string]string{
+		"Red shirt.": "Camisa roja.",
+	}
+	document := base64.StdEncoding.EncodeToString([]byte("sku,title\nA1,Red shirt.\n"))
+
+	tests := []struct {
+		name             string
+		body             string
+		getError         error
+		expectedResponse events.APIGatewayProxyResponse
+		expectedPutKey   string
+	}{
+		{
+			name: "Inline document",
+			body: `{"source_language":"en","target_language":"es","format":"csv","columns":["title"],"document":"` + document + `"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"sku,title\nA1,Camisa roja.\n","rows":1}`,
+			},
+		},
+		{
+			name: "S3 pointer",
+			body: `{"source_language":"en","target_language":"es","format":"csv","columns":["title"],"key":"catalog/products.csv"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"","rows":1,"output_key":"translated/es/catalog/products.csv"}`,
+			},
+			expectedPutKey: "translated/es/catalog/products.csv",
+		},
+		{
+			name:     "S3 object is missing",
+			body:     `{"source_language":"en","target_language":"es","format":"csv","columns":["title"],"key":"missing.csv"}`,
+			getError: fmt.Errorf("mock error"),
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "key could not be read from the bulk bucket",
+			},
+		},
+		{
+			name: "Unknown column",
+			body: `{"source_language":"en","target_language":"es","format":"csv","columns":["price"],"document":"` + document + `"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `document is not a valid csv file: column "price" is not in the header row`,
+			},
+		},
+		{
+			name: "Columns are required",
+			body: `{"source_language":"en","target_language":"es","format":"csv","document":"` + document + `"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"columns","message":"columns is required"}]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var putKey, putBody string
+
+			h := newMockHandler(translations)
+			h.s3Client = &MockS3Client{
+				GetObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+					if tt.getError != nil {
+						return nil, tt.getError
+					}
+					return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("sku,title\nA1,Red shirt.\n"))}, nil
+				},
+				PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+					putKey = *params.Key
+					body, _ := io.ReadAll(params.Body)
+					putBody = string(body)
+					return &s3.PutObjectOutput{}, nil
+				},
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %v, expected %v", got, tt.expectedResponse)
+			}
+			if putKey != tt.expectedPutKey {
+				t.Errorf("PutObject() key = %q, expected %q", putKey, tt.expectedPutKey)
+			}
+			if tt.expectedPutKey != "" && putBody != "sku,title\nA1,Camisa roja.\n" {
+				t.Errorf("PutObject() body = %q", putBody)
+			}
+		})
+	}
+}
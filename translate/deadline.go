@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// deadlineMargin is the time left before the deadline of a request under which no new
+// segment is sent to the provider, leaving time to assemble the response
+const deadlineMargin = 200 * time.Millisecond
+
+// requestDeadline holds the deadline of a request and the segments left untranslated because
+// of it
+type requestDeadline struct {
+	deadline time.Time
+
+	mu       sync.Mutex
+	segments []SegmentReport
+}
+
+type requestDeadlineKey struct{}
+
+// withDeadline returns a context serving the segments not translated deadlineMS milliseconds
+// from now, or by the translation budget of the request if sooner, untranslated. It is
+// unchanged when deadlineMS is 0 and the request has no budget.
+func withDeadline(ctx context.Context, deadlineMS int) (context.Context, *requestDeadline) {
+	var deadline time.Time
+	if b := stageBudgetsFromContext(ctx); b != nil {
+		deadline = b.translate
+	}
+	if deadlineMS > 0 {
+		if requested := time.Now().Add(time.Duration(deadlineMS) * time.Millisecond); deadline.IsZero() || requested.Before(deadline) {
+			deadline = requested
+		}
+	}
+	if deadline.IsZero() {
+		return ctx, nil
+	}
+	d := &requestDeadline{deadline: deadline}
+	return context.WithValue(ctx, requestDeadlineKey{}, d), d
+}
+
+// deadlineFromContext returns the deadline of the request, nil when it has none
+func deadlineFromContext(ctx context.Context) *requestDeadline {
+	d, _ := ctx.Value(requestDeadlineKey{}).(*requestDeadline)
+	return d
+}
+
+// Segments returns the segments left untranslated so far
+func (d *requestDeadline) Segments() []SegmentReport {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.segments
+}
+
+// TimedOut reports whether any segment was left untranslated
+func (d *requestDeadline) TimedOut() bool {
+	return len(d.Segments()) > 0
+}
+
+// nearing reports whether the deadline is too close to send another segment to the provider
+func (d *requestDeadline) nearing() bool {
+	return d != nil && time.Until(d.deadline) < deadlineMargin
+}
+
+// providerContext returns the context of a provider call, cancelled at the deadline
+func (d *requestDeadline) providerContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d == nil {
+		return ctx, func() {}
+	}
+	return withImposedDeadline(ctx, d.deadline)
+}
+
+type imposedDeadlineKey struct{}
+
+// withImposedDeadline returns a context cancelled at a deadline the request set itself, such
+// as its deadline_ms or the budget of a stage. The calls it cuts say nothing about the health
+// of the dependency, so they aren't counted by its breaker.
+func withImposedDeadline(ctx context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
+	if current, ok := ctx.Deadline(); ok && !deadline.Before(current) {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(context.WithValue(ctx, imposedDeadlineKey{}, deadline), deadline)
+}
+
+// imposedDeadlineExceeded reports whether the context was cut by a deadline of
+// withImposedDeadline rather than by that of the invocation or of its caller
+func imposedDeadlineExceeded(ctx context.Context) bool {
+	imposed, ok := ctx.Value(imposedDeadlineKey{}).(time.Time)
+	if !ok || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return false
+	}
+	deadline, _ := ctx.Deadline()
+	return deadline.Equal(imposed)
+}
+
+// serveTimedOut reports whether the segment is left untranslated because of the deadline of
+// the request: it is too close to send it to the provider, or err is the provider call cut
+// by it. The segment is reported as timed out.
+func serveTimedOut(ctx context.Context, token string, err error) bool {
+	d := deadlineFromContext(ctx)
+	switch {
+	case d == nil:
+		return false
+	case err == nil && !d.nearing():
+		return false
+	// A call cancelled by the caller rather than the deadline still fails the request
+	case err != nil && (!errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil):
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.segments = append(d.segments, SegmentReport{SourceText: token, TranslatedText: token, TimedOut: true})
+	return true
+}
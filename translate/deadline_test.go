@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestDeadline(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name: "Translated before the deadline",
+			body: `{"source_language":"en","target_language":"es","text":"Hello.","deadline_ms":10000}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola. "}`,
+			},
+		},
+		{
+			name: "Deadline too close to translate",
+			body: `{"source_language":"en","target_language":"es","text":"Hello.","deadline_ms":1}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hello. ","timed_out":true,"segments":[{"source_text":"Hello.","translated_text":"Hello.","timed_out":true}]}`,
+			},
+		},
+		{
+			name: "Call cut by the deadline",
+			body: `{"source_language":"en","target_language":"es","text":"Hello. Stuck.","deadline_ms":300}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola. Stuck. ","timed_out":true,"segments":[{"source_text":"Stuck.","translated_text":"Stuck.","timed_out":true}]}`,
+			},
+		},
+		{
+			name: "Negative deadline",
+			body: `{"source_language":"en","target_language":"es","text":"Hello.","deadline_ms":-5}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"deadline_ms","message":"deadline_ms must be a positive number"}]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(map[string]string{"Hello.": "Hola."})
+			mock := h.translateClient.(*MockTranslateClient)
+			translateText := mock.TranslateTextFunc
+			mock.TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				if aws.ToString(params.Text) == "Stuck." {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+				return translateText(ctx, params, optFns...)
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+)
+
+const (
+	defaultDeepLAPIURL     = "https://api.deepl.com"
+	defaultDeepLFreeAPIURL = "https://api-free.deepl.com"
+
+	// deepLFreeKeySuffix ends the authentication keys of the DeepL API Free plan, which has
+	// its own host
+	deepLFreeKeySuffix = ":fx"
+	// maxDeepLResponseSize is the size limit of a DeepL response
+	maxDeepLResponseSize = 1024 * 1024
+	// deepLRequestTimeout bounds a call of the DeepL API, a batch of sentences rarely takes
+	// more than a few seconds
+	deepLRequestTimeout = 10 * time.Second
+)
+
+var (
+	// deepLAuthKey is the authentication key of the DeepL API, empty to disable the provider
+	deepLAuthKey string
+	// deepLAPIURL is the DeepL API, the host of the plan of the key when empty
+	deepLAPIURL string
+)
+
+// deepLTargetLanguages are the DeepL codes of the target languages whose variant DeepL
+// requires, the other languages are the upper case of our codes
+var deepLTargetLanguages = map[string]string{
+	"en":    "EN-US",
+	"pt":    "PT-BR",
+	"zh":    "ZH-HANS",
+	"zh-TW": "ZH-HANT",
+}
+
+// deepLFormalities are the DeepL formalities of the formality settings of a style
+var deepLFormalities = map[types.Formality]string{
+	types.FormalityFormal:   "prefer_more",
+	types.FormalityInformal: "prefer_less",
+}
+
+// deepLTranslateRequest is the body of a translate request of the DeepL API
+type deepLTranslateRequest struct {
+	Text       []string `json:"text"`
+	SourceLang string   `json:"source_lang,omitempty"`
+	TargetLang string   `json:"target_lang"`
+	Formality  string   `json:"formality,omitempty"`
+}
+
+// deepLTranslateResponse is the body of a translate response of the DeepL API
+type deepLTranslateResponse struct {
+	Translations []struct {
+		DetectedSourceLanguage string `json:"detected_source_language"`
+		Text                   string `json:"text"`
+	} `json:"translations"`
+}
+
+// deepLLanguage is a language of the languages response of the DeepL API
+type deepLLanguage struct {
+	Language string `json:"language"`
+}
+
+// deepLTranslateClient is a TranslateClient backed by the DeepL API. The formality of a style
+// is passed on, the other settings aren't supported and are ignored.
+type deepLTranslateClient struct {
+	httpClient *http.Client
+	authKey    string
+	apiURL     string
+}
+
+// newDeepLTranslateClient returns the client of the DeepL API with the key, on the host of
+// the plan of the key unless the API URL is set
+func newDeepLTranslateClient(httpClient *http.Client, authKey, apiURL string) *deepLTranslateClient {
+	if apiURL == "" {
+		apiURL = defaultDeepLAPIURL
+		if strings.HasSuffix(authKey, deepLFreeKeySuffix) {
+			apiURL = defaultDeepLFreeAPIURL
+		}
+	}
+	return &deepLTranslateClient{httpClient: httpClient, authKey: authKey, apiURL: strings.TrimSuffix(apiURL, "/")}
+}
+
+func (c *deepLTranslateClient) TranslateText(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+	request := deepLTranslateRequest{
+		Text:       []string{aws.ToString(params.Text)},
+		TargetLang: deepLTargetLanguage(aws.ToString(params.TargetLanguageCode)),
+	}
+	// DeepL detects the source language when none is given
+	if sourceLanguage := aws.ToString(params.SourceLanguageCode); sourceLanguage != autoDetectLanguage {
+		request.SourceLang = deepLSourceLanguage(sourceLanguage)
+	}
+	if params.Settings != nil {
+		request.Formality = deepLFormalities[params.Settings.Formality]
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal deepl request: %w", err)
+	}
+	responseBody, err := c.do(ctx, http.MethodPost, "/v2/translate", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response deepLTranslateResponse
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal deepl response: %w", err)
+	}
+	if len(response.Translations) == 0 {
+		return nil, fmt.Errorf("deepl returned no translation")
+	}
+
+	sourceLanguage := aws.ToString(params.SourceLanguageCode)
+	if sourceLanguage == autoDetectLanguage {
+		sourceLanguage = strings.ToLower(response.Translations[0].DetectedSourceLanguage)
+	}
+	return &translate.TranslateTextOutput{
+		TranslatedText:     aws.String(response.Translations[0].Text),
+		SourceLanguageCode: aws.String(sourceLanguage),
+		TargetLanguageCode: params.TargetLanguageCode,
+	}, nil
+}
+
+// ListLanguages lists the target languages of DeepL, with the variants DeepL requires
+// mapped back to our codes
+func (c *deepLTranslateClient) ListLanguages(ctx context.Context, params *translate.ListLanguagesInput, optFns ...func(*translate.Options)) (*translate.ListLanguagesOutput, error) {
+	responseBody, err := c.do(ctx, http.MethodGet, "/v2/languages?type=target", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var languages []deepLLanguage
+	if err := json.Unmarshal(responseBody, &languages); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal deepl languages: %w", err)
+	}
+
+	codes := make(map[string]string, len(deepLTargetLanguages))
+	for language, code := range deepLTargetLanguages {
+		codes[code] = language
+	}
+	output := &translate.ListLanguagesOutput{}
+	for _, language := range languages {
+		code, ok := codes[strings.ToUpper(language.Language)]
+		if !ok {
+			code = strings.ToLower(language.Language)
+		}
+		output.Languages = append(output.Languages, types.Language{LanguageCode: aws.String(code)})
+	}
+	return output, nil
+}
+
+// do sends a request to the DeepL API and returns the body of its successful response
+func (c *deepLTranslateClient) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, method, c.apiURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deepl request: %w", err)
+	}
+	request.Header.Set("Authorization", "DeepL-Auth-Key "+c.authKey)
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call deepl: %w", err)
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(io.LimitReader(response.Body, maxDeepLResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deepl response: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("deepl returned %d: %s", response.StatusCode, responseBody)
+	}
+	return responseBody, nil
+}
+
+// deepLTargetLanguage returns the DeepL code of a target language
+func deepLTargetLanguage(language string) string {
+	if code, ok := deepLTargetLanguages[language]; ok {
+		return code
+	}
+	return strings.ToUpper(language)
+}
+
+// deepLSourceLanguage returns the DeepL code of a source language, which has no variant
+func deepLSourceLanguage(language string) string {
+	base, _, _ := strings.Cut(language, "-")
+	return strings.ToUpper(base)
+}
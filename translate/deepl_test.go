@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+)
+
+func TestDeepLTranslateText(t *testing.T) {
+	tests := []struct {
+		name                   string
+		sourceLanguage         string
+		targetLanguage         string
+		settings               *types.TranslationSettings
+		expectedRequest        string
+		expectedSourceLanguage string
+	}{
+		{
+			name:                   "Language pair",
+			sourceLanguage:         "ja",
+			targetLanguage:         "en",
+			expectedRequest:        `{"text":["こんにちは"],"source_lang":"JA","target_lang":"EN-US"}`,
+			expectedSourceLanguage: "ja",
+		},
+		{
+			name:                   "Detected source language with a formality",
+			sourceLanguage:         autoDetectLanguage,
+			targetLanguage:         "de",
+			settings:               &types.TranslationSettings{Formality: types.FormalityFormal},
+			expectedRequest:        `{"text":["こんにちは"],"target_lang":"DE","formality":"prefer_more"}`,
+			expectedSourceLanguage: "ja",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var request, authorization string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				request, authorization = string(body), r.Header.Get("Authorization")
+				w.Write([]byte(`{"translations":[{"detected_source_language":"JA","text":"Hello"}]}`))
+			}))
+			defer server.Close()
+
+			client := newDeepLTranslateClient(server.Client(), "key", server.URL)
+			got, err := client.TranslateText(context.Background(), &translate.TranslateTextInput{
+				Text:               aws.String("こんにちは"),
+				SourceLanguageCode: aws.String(tt.sourceLanguage),
+				TargetLanguageCode: aws.String(tt.targetLanguage),
+				Settings:           tt.settings,
+			})
+			if err != nil {
+				t.Fatalf("TranslateText() error = %v", err)
+			}
+
+			if request != tt.expectedRequest {
+				t.Errorf("TranslateText() request = %s, expected %s", request, tt.expectedRequest)
+			}
+			if authorization != "DeepL-Auth-Key key" {
+				t.Errorf("TranslateText() authorization = %q, expected the key", authorization)
+			}
+			if aws.ToString(got.TranslatedText) != "Hello" || aws.ToString(got.SourceLanguageCode) != tt.expectedSourceLanguage {
+				t.Errorf("TranslateText() = %q from %q, expected %q from %q", aws.ToString(got.TranslatedText), aws.ToString(got.SourceLanguageCode), "Hello", tt.expectedSourceLanguage)
+			}
+		})
+	}
+}
+
+func TestDeepLListLanguages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("type") != "target" {
+			t.Errorf("ListLanguages() type = %q, expected target", r.URL.Query().Get("type"))
+		}
+		w.Write([]byte(`[{"language":"DE"},{"language":"EN-US"},{"language":"ZH-HANT"}]`))
+	}))
+	defer server.Close()
+
+	client := newDeepLTranslateClient(server.Client(), "key", server.URL)
+	got, err := client.ListLanguages(context.Background(), &translate.ListLanguagesInput{})
+	if err != nil {
+		t.Fatalf("ListLanguages() error = %v", err)
+	}
+
+	var languages []string
+	for _, language := range got.Languages {
+		languages = append(languages, aws.ToString(language.LanguageCode))
+	}
+	if len(languages) != 3 || languages[0] != "de" || languages[1] != "en" || languages[2] != "zh-TW" {
+		t.Errorf("ListLanguages() = %v, expected de, en and zh-TW", languages)
+	}
+}
+
+func TestNewDeepLTranslateClient(t *testing.T) {
+	if got := newDeepLTranslateClient(http.DefaultClient, "key:fx", "").apiURL; got != defaultDeepLFreeAPIURL {
+		t.Errorf("apiURL of a free key = %s, expected %s", got, defaultDeepLFreeAPIURL)
+	}
+	if got := newDeepLTranslateClient(http.DefaultClient, "key", "").apiURL; got != defaultDeepLAPIURL {
+		t.Errorf("apiURL = %s, expected %s", got, defaultDeepLAPIURL)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+const (
+	// degradedModeAuto serves untranslated text for cache misses while the provider's
+	// circuit breaker is open
+	degradedModeAuto = "auto"
+	// degradedModeForce only serves cached translations, the provider is never called
+	degradedModeForce = "force"
+)
+
+// degradation records whether any text of a request was served untranslated, or translated
+// by the offline fallback
+type degradation struct {
+	degraded   atomic.Bool
+	lowQuality atomic.Bool
+}
+
+type degradationKey struct{}
+
+// withDegradation returns a context that allows the request to be served degraded
+func withDegradation(ctx context.Context) (context.Context, *degradation) {
+	d := &degradation{}
+	return context.WithValue(ctx, degradationKey{}, d), d
+}
+
+// degradationFromContext returns the degradation of the request, nil when the request
+// can't be degraded
+func degradationFromContext(ctx context.Context) *degradation {
+	d, _ := ctx.Value(degradationKey{}).(*degradation)
+	return d
+}
+
+// Degraded reports whether any text was left untranslated
+func (d *degradation) Degraded() bool {
+	return d != nil && d.degraded.Load()
+}
+
+// LowQuality reports whether any text was translated by the offline fallback
+func (d *degradation) LowQuality() bool {
+	return d != nil && d.lowQuality.Load()
+}
+
+// serveUntranslated reports whether a cache miss should be answered with the untranslated
+// text, err being the provider error if it was called. Requests without a degradation in
+// their context, such as inbound emails which are retried, are never degraded.
+func serveUntranslated(ctx context.Context, err error) bool {
+	d := degradationFromContext(ctx)
+	if d == nil {
+		return false
+	}
+
+	var unavailable *dependencyUnavailable
+	switch {
+	case degradedMode == degradedModeForce:
+	case degradedMode == degradedModeAuto && errors.As(err, &unavailable):
+	default:
+		return false
+	}
+
+	d.degraded.Store(true)
+	return true
+}
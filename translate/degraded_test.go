@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestServeUntranslated(t *testing.T) {
+	unavailable := &dependencyUnavailable{Dependency: "translate", RetryAfter: 30 * time.Second}
+
+	tests := []struct {
+		name         string
+		mode         string
+		tracked      bool
+		err          error
+		expected     bool
+		expectedMark bool
+	}{
+		{name: "Disabled", mode: "", tracked: true, err: unavailable, expected: false},
+		{name: "Auto with open breaker", mode: degradedModeAuto, tracked: true, err: unavailable, expected: true, expectedMark: true},
+		{name: "Auto with other error", mode: degradedModeAuto, tracked: true, err: fmt.Errorf("mock error"), expected: false},
+		{name: "Forced", mode: degradedModeForce, tracked: true, err: nil, expected: true, expectedMark: true},
+		{name: "Untracked request", mode: degradedModeForce, tracked: false, err: nil, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := degradedMode
+			degradedMode = tt.mode
+			defer func() { degradedMode = original }()
+
+			ctx := context.Background()
+			var d *degradation
+			if tt.tracked {
+				ctx, d = withDegradation(ctx)
+			}
+
+			if got := serveUntranslated(ctx, tt.err); got != tt.expected {
+				t.Errorf("serveUntranslated() = %v, expected %v", got, tt.expected)
+			}
+			if got := d.Degraded(); got != tt.expectedMark {
+				t.Errorf("Degraded() = %v, expected %v", got, tt.expectedMark)
+			}
+		})
+	}
+}
+
+func TestHandleDegraded(t *testing.T) {
+	cachedItem := map[string]dynamoTypes.AttributeValue{
+		"hash":            &dynamoTypes.AttributeValueMemberS{Value: "test-hash"},
+		"translated_text": &dynamoTypes.AttributeValueMemberS{Value: "Hola."},
+		"source_text":     &dynamoTypes.AttributeValueMemberS{Value: "Hello."},
+		"source_language": &dynamoTypes.AttributeValueMemberS{Value: "en"},
+		"target_language": &dynamoTypes.AttributeValueMemberS{Value: "es"},
+	}
+	unavailable := &dependencyUnavailable{Dependency: "translate", RetryAfter: 30 * time.Second}
+
+	tests := []struct {
+		name             string
+		mode             string
+		translateError   error
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name:           "Open breaker fails without degraded mode",
+			mode:           "",
+			translateError: unavailable,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       "Service temporarily unavailable",
+			},
+		},
+		{
+			name:           "Open breaker serves the cache in auto mode",
+			mode:           degradedModeAuto,
+			translateError: unavailable,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola. How are you? ","degraded":true}`,
+			},
+		},
+		{
+			name: "Forced mode never calls the provider",
+			mode: degradedModeForce,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola. How are you? ","degraded":true}`,
+			},
+		},
+		{
+			name: "Healthy provider in auto mode",
+			mode: degradedModeAuto,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola. ¿Cómo estás? "}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := degradedMode
+			degradedMode = tt.mode
+			defer func() { degradedMode = original }()
+
+			h := newMockHandler(map[string]string{"How are you?": "¿Cómo estás?"})
+			h.dynamoClient = &MockDynamoDBClient{
+				GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					if params.Key["hash"].(*dynamoTypes.AttributeValueMemberS).Value == getHashFromText("en-es-Hello.") {
+						return &dynamodb.GetItemOutput{Item: cachedItem}, nil
+					}
+					return &dynamodb.GetItemOutput{}, nil
+				},
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					return &dynamodb.PutItemOutput{}, nil
+				},
+			}
+			mockClient := h.translateClient.(*MockTranslateClient)
+			translateText := mockClient.TranslateTextFunc
+			mockClient.TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				if tt.mode == degradedModeForce {
+					t.Errorf("TranslateText() called in forced degraded mode")
+				}
+				if tt.translateError != nil {
+					return nil, tt.translateError
+				}
+				return translateText(ctx, params, optFns...)
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+				Body: `{"source_language":"en","target_language":"es","text":"Hello. How are you?"}`,
+			})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %v, expected %v", got, tt.expectedResponse)
+			}
+		})
+	}
+}
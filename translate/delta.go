@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"strings"
+)
+
+// ChangeReport describes how a text was translated from the translation of its previous
+// version
+type ChangeReport struct {
+	// Reused is the number of sentences whose previous translation was kept
+	Reused int `json:"reused"`
+	// Translated are the sentences new or changed since the previous version
+	Translated []string `json:"translated"`
+	// Removed are the sentences of the previous version no longer in the text
+	Removed []string `json:"removed"`
+	// Mismatched is set when the previous translation doesn't have a sentence for each
+	// sentence of the previous text, every sentence is then translated
+	Mismatched bool `json:"mismatched,omitempty"`
+}
+
+// translateDelta translates the text of the request reusing the translation of its previous
+// version: the sentences found in the previous text keep their previous translation, only
+// the new and changed ones are translated. The previous text and translation are paired
+// sentence by sentence, as the translation of a text has a sentence for each of its own.
+func (h *handler) translateDelta(ctx context.Context, request TranslateRequest) (string, []AlignedSentence, *ChangeReport, error) {
+	sentences := splitLanguageSentences(request.SourceLanguage, request.Text)
+	previousSentences := splitLanguageSentences(request.SourceLanguage, request.PreviousText)
+	previousTranslations := splitLanguageSentences(request.TargetLanguage, request.PreviousTranslation)
+
+	changes := &ChangeReport{Translated: []string{}, Removed: []string{}}
+	reusable := map[string]string{}
+	if len(previousSentences) == len(previousTranslations) {
+		for i, sentence := range previousSentences {
+			if _, ok := reusable[sentence]; !ok {
+				reusable[sentence] = previousTranslations[i]
+			}
+		}
+	} else {
+		changes.Mismatched = true
+	}
+
+	var (
+		translated = make([]string, len(sentences))
+		current    = make(map[string]bool, len(sentences))
+		fresh      []string
+		indexes    []int
+	)
+	for i, sentence := range sentences {
+		current[sentence] = true
+		if translation, ok := reusable[sentence]; ok {
+			translated[i] = translation
+			changes.Reused++
+			continue
+		}
+		fresh = append(fresh, sentence)
+		indexes = append(indexes, i)
+	}
+	for _, sentence := range previousSentences {
+		if !current[sentence] {
+			changes.Removed = append(changes.Removed, sentence)
+		}
+	}
+
+	if len(fresh) > 0 {
+		freshTranslations, err := h.translateSentences(ctx, request.SourceLanguage, request.TargetLanguage, fresh)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		for i, index := range indexes {
+			translated[index] = freshTranslations[i]
+		}
+		changes.Translated = fresh
+	}
+
+	translatedText := joinSentences(translated)
+	var alignment []AlignedSentence
+	if request.Alignment {
+		alignment = alignSentences(request.Text, sentences, translatedText, translated)
+	}
+	return translatedText, alignment, changes, nil
+}
+
+// validateDelta checks the previous version of a delta translation
+func validateDelta(errs *validationError, request TranslateRequest) {
+	if request.PreviousText == "" && request.PreviousTranslation == "" {
+		return
+	}
+
+	if strings.TrimSpace(request.PreviousText) == "" || strings.TrimSpace(request.PreviousTranslation) == "" {
+		errs.add("previous_text", "previous_text and previous_translation are required together")
+	}
+	if request.Format != "" && request.Format != formatText {
+		errs.add("previous_text", "previous_text is only supported for text")
+	}
+}
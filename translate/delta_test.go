@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestTranslateDelta(t *testing.T) {
+	translations := map[string]string{
+		"Hello.":           "Hola.",
+		"How are you?":     "¿Cómo estás?",
+		"See you soon.":    "Hasta pronto.",
+		"How are you all?": "¿Cómo están todos?",
+	}
+
+	tests := []struct {
+		name                 string
+		request              TranslateRequest
+		expectedText         string
+		expectedChanges      ChangeReport
+		expectedTranslations int32
+	}{
+		{
+			name: "One changed sentence",
+			request: TranslateRequest{
+				Text:                "Hello. How are you all? See you soon.",
+				PreviousText:        "Hello. How are you? See you soon.",
+				PreviousTranslation: "Hola, amigo. ¿Qué tal? Hasta pronto.",
+			},
+			expectedText: "Hola, amigo. ¿Cómo están todos? Hasta pronto. ",
+			expectedChanges: ChangeReport{
+				Reused:     2,
+				Translated: []string{"How are you all?"},
+				Removed:    []string{"How are you?"},
+			},
+			expectedTranslations: 1,
+		},
+		{
+			name: "Unchanged",
+			request: TranslateRequest{
+				Text:                "Hello. See you soon.",
+				PreviousText:        "Hello. See you soon.",
+				PreviousTranslation: "Hola, amigo. Hasta luego.",
+			},
+			expectedText:    "Hola, amigo. Hasta luego. ",
+			expectedChanges: ChangeReport{Reused: 2, Translated: []string{}, Removed: []string{}},
+		},
+		{
+			name: "Mismatched previous translation",
+			request: TranslateRequest{
+				Text:                "Hello. See you soon.",
+				PreviousText:        "Hello. See you soon.",
+				PreviousTranslation: "Hola y hasta luego.",
+			},
+			expectedText: "Hola. Hasta pronto. ",
+			expectedChanges: ChangeReport{
+				Translated: []string{"Hello.", "See you soon."},
+				Removed:    []string{},
+				Mismatched: true,
+			},
+			expectedTranslations: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			h := newMockHandler(translations)
+			mock := h.translateClient.(*MockTranslateClient)
+			translateText := mock.TranslateTextFunc
+			mock.TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				calls.Add(1)
+				return translateText(ctx, params, optFns...)
+			}
+
+			tt.request.SourceLanguage, tt.request.TargetLanguage = "en", "es"
+			got, _, changes, err := h.translateDelta(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("translateDelta() error = %v", err)
+			}
+			if got != tt.expectedText {
+				t.Errorf("translateDelta() = %q, expected %q", got, tt.expectedText)
+			}
+			if !reflect.DeepEqual(*changes, tt.expectedChanges) {
+				t.Errorf("translateDelta() changes = %+v, expected %+v", *changes, tt.expectedChanges)
+			}
+			if calls.Load() != tt.expectedTranslations {
+				t.Errorf("translateDelta() made %d translations, expected %d", calls.Load(), tt.expectedTranslations)
+			}
+		})
+	}
+}
+
+func TestHandleDelta(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name: "Changes",
+			body: `{"source_language":"en","target_language":"es","text":"Hello. See you soon.","previous_text":"Hello.","previous_translation":"Hola, amigo."}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola, amigo. Hasta pronto. ","changes":{"reused":1,"translated":["See you soon."],"removed":[]}}`,
+			},
+		},
+		{
+			name: "Missing previous translation",
+			body: `{"source_language":"en","target_language":"es","text":"Hello.","previous_text":"Hello."}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"previous_text","message":"previous_text and previous_translation are required together"}]}`,
+			},
+		},
+		{
+			name: "Unsupported format",
+			body: `{"source_language":"en","target_language":"es","text":"<p>Hello.</p>","format":"html","previous_text":"<p>Hi.</p>","previous_translation":"<p>Hola.</p>"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"previous_text","message":"previous_text is only supported for text"}]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(map[string]string{"See you soon.": "Hasta pronto."})
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/language"
+)
+
+// directionRTL is the text direction of right-to-left languages
+const directionRTL = "rtl"
+
+// rtlScripts are the right-to-left scripts, by ISO 15924 code
+var rtlScripts = map[string]bool{
+	"Adlm": true,
+	"Arab": true,
+	"Hebr": true,
+	"Nkoo": true,
+	"Rohg": true,
+	"Syrc": true,
+	"Thaa": true,
+}
+
+// textDirection returns "rtl" when the language is written right to left, such as ar, he, fa
+// and ur, and is empty otherwise. The likely script of the language decides, so a language
+// written in several scripts follows its script subtag.
+func textDirection(languageCode string) string {
+	tag, err := language.Parse(languageCode)
+	if err != nil {
+		return ""
+	}
+	if script, _ := tag.Script(); rtlScripts[script.String()] {
+		return directionRTL
+	}
+	return ""
+}
+
+// setHTMLLanguage sets the lang attribute of the translated elements to the target language:
+// the top-level elements holding translated text, such as <html>, and the elements already
+// declaring a language. Their dir attribute is set for right-to-left languages, or reset when
+// declared. Elements opting out of translation declare the source language unless they
+// already declare one.
+func setHTMLLanguage(tokens []htmlToken, sourceLanguage, targetLanguage string) {
+	for i, token := range tokens {
+		if token.Tag == nil {
+			continue
+		}
+		_, declared := htmlAttribute(*token.Tag, "lang")
+		_, dirDeclared := htmlAttribute(*token.Tag, "dir")
+
+		var attrs []html.Attribute
+		switch {
+		case token.NoTranslate:
+			if declared || sourceLanguage == "" || sourceLanguage == autoDetectLanguage {
+				continue
+			}
+			attrs = []html.Attribute{{Key: "lang", Val: sourceLanguage}}
+		case token.Untranslated:
+			continue
+		case token.TopLevel && token.Translated, token.Tag.Data == "html", declared:
+			attrs = []html.Attribute{{Key: "lang", Val: targetLanguage}}
+			if direction := textDirection(targetLanguage); direction != "" || dirDeclared {
+				attrs = append([]html.Attribute{{Key: "dir", Val: cmp.Or(direction, "ltr")}}, attrs...)
+			}
+		default:
+			continue
+		}
+		tokens[i].Raw = setHTMLAttributes(*token.Tag, attrs).String()
+	}
+}
+
+// htmlAttribute returns the value of the attribute of the tag
+func htmlAttribute(tag html.Token, key string) (string, bool) {
+	for _, attr := range tag.Attr {
+		if attr.Namespace == "" && attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+// setHTMLAttributes returns the tag with the attributes, replacing any of the same key
+func setHTMLAttributes(tag html.Token, attrs []html.Attribute) html.Token {
+	kept := make([]html.Attribute, 0, len(tag.Attr)+len(attrs))
+	for _, attr := range tag.Attr {
+		if !slices.ContainsFunc(attrs, func(a html.Attribute) bool { return attr.Namespace == "" && a.Key == attr.Key }) {
+			kept = append(kept, attr)
+		}
+	}
+	tag.Attr = append(kept, attrs...)
+	return tag
+}
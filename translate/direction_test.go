@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+)
+
+func TestTextDirection(t *testing.T) {
+	tests := []struct {
+		languageCode string
+		expected     string
+	}{
+		{languageCode: "ar", expected: "rtl"},
+		{languageCode: "he", expected: "rtl"},
+		{languageCode: "fa-AF", expected: "rtl"},
+		{languageCode: "ur", expected: "rtl"},
+		{languageCode: "ps", expected: "rtl"},
+		{languageCode: "es"},
+		{languageCode: "zh-TW"},
+		{languageCode: "az-Arab", expected: "rtl"},
+		{languageCode: "auto"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.languageCode, func(t *testing.T) {
+			if got := textDirection(tt.languageCode); got != tt.expected {
+				t.Errorf("textDirection() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSetHTMLLanguage(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		targetLanguage string
+		expected       string
+	}{
+		{
+			name:           "Document",
+			input:          `<!DOCTYPE html><html lang="en"><body><p>Hello</p></body></html>`,
+			targetLanguage: "es",
+			expected:       `<!DOCTYPE html><html lang="es"><body><p>Hello</p></body></html>`,
+		},
+		{
+			name:           "Fragment",
+			input:          `<h1>Hello</h1><hr><div class="a"><p lang="en-US">Hello</p><img src="a.png"></div>`,
+			targetLanguage: "es",
+			expected:       `<h1 lang="es">Hello</h1><hr><div class="a" lang="es"><p lang="es">Hello</p><img src="a.png"></div>`,
+		},
+		{
+			name:           "Declared direction reset",
+			input:          `<p dir="rtl" lang="ar">Hello</p>`,
+			targetLanguage: "en",
+			expected:       `<p dir="ltr" lang="en">Hello</p>`,
+		},
+		{
+			name:           "Notranslate regions keep their language",
+			input:          `<p>Hello <span translate="no">Acme <q lang="de">Gut</q></span> <code class="notranslate" lang="en-GB">colour</code></p>`,
+			targetLanguage: "ar",
+			expected:       `<p dir="rtl" lang="ar">Hello <span translate="no" lang="en">Acme <q lang="de">Gut</q></span> <code class="notranslate" lang="en-GB">colour</code></p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, _, _, err := getTextFromHTML(tt.input, defaultSegmenter)
+			if err != nil {
+				t.Fatalf("getTextFromHTML() error = %v", err)
+			}
+
+			setHTMLLanguage(tokens, "en", tt.targetLanguage)
+
+			var got strings.Builder
+			for _, token := range tokens {
+				got.WriteString(token.Raw)
+			}
+			if got.String() != tt.expected {
+				t.Errorf("setHTMLLanguage() = %s, expected %s", got.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandleDirection(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name: "Text",
+			body: `{"source_language":"en","target_language":"ar","text":"Hello."}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"مرحبا. ","direction":"rtl"}`,
+			},
+		},
+		{
+			name: "HTML fragment",
+			body: `{"source_language":"en","target_language":"ar","format":"html","text":"<p lang=\"en\" class=\"intro\">Hello.</p>\n<img src=\"a.png\"><div><p>Hello.</p></div>"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"\u003cp class=\"intro\" dir=\"rtl\" lang=\"ar\"\u003eمرحبا.\u003c/p\u003e\n\u003cimg src=\"a.png\"\u003e\u003cdiv dir=\"rtl\" lang=\"ar\"\u003e\u003cp\u003eمرحبا.\u003c/p\u003e\u003c/div\u003e","direction":"rtl"}`,
+			},
+		},
+		{
+			name: "HTML document",
+			body: `{"source_language":"en","target_language":"ar","format":"html","text":"<html><body><p>Hello.</p></body></html>"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"\u003chtml dir=\"rtl\" lang=\"ar\"\u003e\u003cbody\u003e\u003cp\u003eمرحبا.\u003c/p\u003e\u003c/body\u003e\u003c/html\u003e","direction":"rtl"}`,
+			},
+		},
+		{
+			name: "Left to right",
+			body: `{"source_language":"en","target_language":"es","format":"html","text":"<p>Hello.</p>"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"\u003cp lang=\"es\"\u003eHola.\u003c/p\u003e"}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(nil)
+			mock := h.translateClient.(*MockTranslateClient)
+			mock.ListLanguagesFunc = func(ctx context.Context, params *translate.ListLanguagesInput, optFns ...func(*translate.Options)) (*translate.ListLanguagesOutput, error) {
+				return &translate.ListLanguagesOutput{Languages: []types.Language{{LanguageCode: aws.String("ar")}, {LanguageCode: aws.String("es")}}}, nil
+			}
+			mock.TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				translated := map[string]string{"ar": "مرحبا.", "es": "Hola."}[*params.TargetLanguageCode]
+				return &translate.TranslateTextOutput{TranslatedText: aws.String(translated)}, nil
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// disclaimerPrepend and disclaimerAppend add the disclaimer before or after the translation
+	disclaimerPrepend = "prepend"
+	disclaimerAppend  = "append"
+
+	// defaultDisclaimerText is the disclaimer of the translations without template
+	defaultDisclaimerText     = "This text was machine translated. The original text governs."
+	defaultDisclaimerLanguage = "en"
+
+	// defaultDisclaimerTableTTL is the number of seconds the templates of the disclaimer table
+	// are cached
+	defaultDisclaimerTableTTL = 300
+
+	// disclaimerSeparator separates the disclaimer from a text translation
+	disclaimerSeparator = "\n\n"
+	// disclaimerClass is the class of the banner of the disclaimer of HTML translations
+	disclaimerClass = "translation-disclaimer"
+	// anyTenant is the tenant of the templates of every tenant
+	anyTenant = "*"
+)
+
+var (
+	// disclaimerTableName is the DynamoDB table holding the disclaimer templates by tenant and
+	// language, empty to add the default disclaimer to the requests asking for one only
+	disclaimerTableName string
+	// disclaimerTableTTL is the number of seconds the templates of the table are cached
+	disclaimerTableTTL int
+
+	bodyStartPattern = regexp.MustCompile(`(?i)<body(\s[^>]*)?>`)
+	bodyEndPattern   = regexp.MustCompile(`(?i)</body\s*>`)
+)
+
+// disclaimerTemplate is the disclaimer of the translations of a tenant to a language. * stands
+// for every tenant or language.
+type disclaimerTemplate struct {
+	Tenant   string
+	Language string
+	Text     string
+	// Position is the position the disclaimer is added at to every translation of the tenant,
+	// even those of requests not asking for one. Empty to only add it to the requests asking.
+	Position string
+}
+
+type disclaimerKey struct {
+	tenant   string
+	language string
+}
+
+// disclaimerCache caches the templates of the disclaimer table
+type disclaimerCache struct {
+	mu        sync.Mutex
+	templates map[disclaimerKey]disclaimerTemplate
+	refreshAt time.Time
+	// refreshing is set while a request scans the table again, the others keep using the
+	// templates read before
+	refreshing bool
+}
+
+// disclaimer is the notice added to a translation, such as "machine translated, the original
+// governs". Its text is added as is after the translation, so it is never sent to the
+// provider nor changed by the moderation or the other options of the request.
+type disclaimer struct {
+	text string
+	// language is the language of the text, empty when unknown
+	language string
+	position string
+}
+
+// disclaimerFormats are the formats a disclaimer can be added to. The other formats have no
+// room for it, so their translations are refused when a template requires one.
+var disclaimerFormats = []string{"", formatText, formatHTML, formatPDF, formatEmail}
+
+// resolveDisclaimer returns the disclaimer of the translation of the request, nil when it has
+// none. The template of the tenant of the caller and target language is used, falling back
+// to its base language, to the templates of every language then of every tenant. The position
+// of the request is used, otherwise the first position of the templates. An error is returned
+// when the templates can't be read, as they may require one.
+func (h *handler) resolveDisclaimer(ctx context.Context, request TranslateRequest) (*disclaimer, error) {
+	templates, err := h.disclaimerTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 && request.Disclaimer == "" {
+		return nil, nil
+	}
+
+	e, _ := entitlementFromContext(ctx)
+	base, _, _ := strings.Cut(request.TargetLanguage, "-")
+	var (
+		text, language string
+		position       = request.Disclaimer
+	)
+	for _, tenant := range []string{e.Tenant, anyTenant} {
+		if tenant == "" {
+			continue
+		}
+		for _, key := range []string{request.TargetLanguage, base, anyLanguage} {
+			template, ok := templates[disclaimerKey{tenant: tenant, language: key}]
+			if !ok {
+				continue
+			}
+			if text == "" {
+				text, language = template.Text, template.Language
+			}
+			if position == "" {
+				position = template.Position
+			}
+		}
+	}
+	if position == "" {
+		return nil, nil
+	}
+	if text == "" {
+		text, language = defaultDisclaimerText, defaultDisclaimerLanguage
+	}
+	if language == anyLanguage {
+		language = ""
+	}
+	return &disclaimer{text: text, language: language, position: position}, nil
+}
+
+// disclaimerTemplates returns the templates of the disclaimer table, which is scanned again
+// once their TTL has passed. The table is scanned without holding the cache, the requests
+// arriving meanwhile using the templates read before. The last templates read are kept when
+// the table can't be read; until any were read, the error is returned.
+func (h *handler) disclaimerTemplates(ctx context.Context) (map[disclaimerKey]disclaimerTemplate, error) {
+	if disclaimerTableName == "" {
+		return nil, nil
+	}
+
+	h.disclaimers.mu.Lock()
+	if h.disclaimers.templates != nil && (h.disclaimers.refreshing || time.Now().Before(h.disclaimers.refreshAt)) {
+		defer h.disclaimers.mu.Unlock()
+		return h.disclaimers.templates, nil
+	}
+	h.disclaimers.refreshing = true
+	h.disclaimers.mu.Unlock()
+
+	templates, err := h.scanDisclaimerTable(ctx)
+
+	h.disclaimers.mu.Lock()
+	defer h.disclaimers.mu.Unlock()
+	h.disclaimers.refreshing = false
+	if err != nil {
+		if h.disclaimers.templates == nil {
+			return nil, fmt.Errorf("failed to read disclaimer table %s: %w", disclaimerTableName, err)
+		}
+		log.Printf("Error reading disclaimer table %s: %v", disclaimerTableName, err)
+		templates = h.disclaimers.templates
+	}
+	h.disclaimers.templates = templates
+	h.disclaimers.refreshAt = time.Now().Add(time.Duration(disclaimerTableTTL) * time.Second)
+	return templates, nil
+}
+
+// scanDisclaimerTable reads the templates of the disclaimer table, items holding a tenant, a
+// language, a text and optionally a position
+func (h *handler) scanDisclaimerTable(ctx context.Context) (map[disclaimerKey]disclaimerTemplate, error) {
+	templates := make(map[disclaimerKey]disclaimerTemplate)
+	paginator := dynamodb.NewScanPaginator(h.dynamoClient, &dynamodb.ScanInput{
+		TableName: aws.String(disclaimerTableName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			tenant, _ := item["tenant"].(*dynamoTypes.AttributeValueMemberS)
+			language, _ := item["language"].(*dynamoTypes.AttributeValueMemberS)
+			text, _ := item["text"].(*dynamoTypes.AttributeValueMemberS)
+			if tenant == nil || language == nil || text == nil || strings.TrimSpace(text.Value) == "" {
+				continue
+			}
+			template := disclaimerTemplate{Tenant: tenant.Value, Language: language.Value, Text: strings.TrimSpace(text.Value)}
+			if template.Language != anyLanguage {
+				template.Language = normalizeLanguageCode(template.Language)
+			}
+			if position, ok := item["position"].(*dynamoTypes.AttributeValueMemberS); ok {
+				if position.Value != disclaimerPrepend && position.Value != disclaimerAppend {
+					log.Printf("Ignoring invalid disclaimer position %q of %s:%s", position.Value, template.Tenant, template.Language)
+				} else {
+					template.Position = position.Value
+				}
+			}
+			templates[disclaimerKey{tenant: template.Tenant, language: template.Language}] = template
+		}
+	}
+	return templates, nil
+}
+
+type requestDisclaimerKey struct{}
+
+// withDisclaimer returns a context adding the disclaimer to the translations of the formats
+// handled apart, such as the text parts of an email
+func withDisclaimer(ctx context.Context, d *disclaimer) context.Context {
+	if d == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, requestDisclaimerKey{}, d)
+}
+
+// disclaimerFromContext returns the disclaimer of the request, nil when it has none
+func disclaimerFromContext(ctx context.Context) *disclaimer {
+	d, _ := ctx.Value(requestDisclaimerKey{}).(*disclaimer)
+	return d
+}
+
+// add adds the disclaimer to the translation of the response, as a paragraph of a text or a
+// banner of an HTML document, shifting the alignment of the translated sentences past it. It
+// is added to the text parts of an email as they are translated.
+func (d *disclaimer) add(response *TranslateResponse, format string) {
+	if d == nil {
+		return
+	}
+	switch format {
+	case formatHTML:
+		response.TranslatedText = d.addToHTML(response.TranslatedText)
+	case formatEmail:
+	default:
+		if d.position == disclaimerPrepend {
+			shift := utf8.RuneCountInString(d.text + disclaimerSeparator)
+			for i := range response.Alignment {
+				response.Alignment[i].Offsets.TargetStart += shift
+				response.Alignment[i].Offsets.TargetEnd += shift
+			}
+		}
+		response.TranslatedText = d.addToText(response.TranslatedText)
+	}
+	response.Disclaimer = d.text
+	if response.Parameters != nil {
+		response.Parameters.Disclaimer = d.position
+	}
+}
+
+func (d *disclaimer) addToText(text string) string {
+	if d.position == disclaimerPrepend {
+		return d.text + disclaimerSeparator + text
+	}
+	return strings.TrimRightFunc(text, unicode.IsSpace) + disclaimerSeparator + d.text
+}
+
+// addToHTML adds the banner at the start or the end of the body of the document, or of the
+// fragment when it has no body
+func (d *disclaimer) addToHTML(document string) string {
+	banner := `<div class="` + disclaimerClass + `" role="note"`
+	if d.language != "" {
+		banner += ` lang="` + html.EscapeString(d.language) + `"`
+	}
+	banner += ">" + html.EscapeString(d.text) + "</div>"
+
+	if d.position == disclaimerPrepend {
+		if loc := bodyStartPattern.FindStringIndex(document); loc != nil {
+			return document[:loc[1]] + banner + document[loc[1]:]
+		}
+		return banner + document
+	}
+	if locs := bodyEndPattern.FindAllStringIndex(document, -1); len(locs) > 0 {
+		end := locs[len(locs)-1][0]
+		return document[:end] + banner + document[end:]
+	}
+	return document + banner
+}
+
+// remove removes the disclaimer from an earlier translation of the text, such as the previous
+// translation of a delta request, so its sentences match those of the previous text
+func (d *disclaimer) remove(text string) string {
+	if d == nil {
+		return text
+	}
+	if d.position == disclaimerPrepend {
+		return strings.TrimPrefix(text, d.text+disclaimerSeparator)
+	}
+	return strings.TrimSuffix(text, disclaimerSeparator+d.text)
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/textract"
+	textractTypes "github.com/aws/aws-sdk-go-v2/service/textract/types"
+)
+
+func TestDisclaimerAdd(t *testing.T) {
+	tests := []struct {
+		name              string
+		disclaimer        disclaimer
+		format            string
+		translated        string
+		alignment         []AlignedSentence
+		expected          string
+		expectedAlignment []AlignedSentence
+	}{
+		{
+			name:       "Appended to a text",
+			disclaimer: disclaimer{text: "Traducción automática.", language: "es", position: disclaimerAppend},
+			translated: "Hola. ",
+			expected:   "Hola.\n\nTraducción automática.",
+		},
+		{
+			name:              "Prepended to a text",
+			disclaimer:        disclaimer{text: "Traducción automática.", language: "es", position: disclaimerPrepend},
+			translated:        "Hola. ",
+			alignment:         []AlignedSentence{{Source: "Hello.", Target: "Hola.", Offsets: AlignmentOffsets{SourceEnd: 6, TargetEnd: 5}}},
+			expected:          "Traducción automática.\n\nHola. ",
+			expectedAlignment: []AlignedSentence{{Source: "Hello.", Target: "Hola.", Offsets: AlignmentOffsets{SourceEnd: 6, TargetStart: 24, TargetEnd: 29}}},
+		},
+		{
+			name:       "Banner of an HTML fragment",
+			disclaimer: disclaimer{text: "Traducción <automática>.", language: "es", position: disclaimerAppend},
+			format:     formatHTML,
+			translated: `<p lang="es">Hola.</p>`,
+			expected:   `<p lang="es">Hola.</p><div class="translation-disclaimer" role="note" lang="es">Traducción &lt;automática&gt;.</div>`,
+		},
+		{
+			name:       "Banner at the start of the body",
+			disclaimer: disclaimer{text: "Machine translated.", position: disclaimerPrepend},
+			format:     formatHTML,
+			translated: `<html lang="es"><BODY class="page"><p>Hola.</p></BODY></html>`,
+			expected:   `<html lang="es"><BODY class="page"><div class="translation-disclaimer" role="note">Machine translated.</div><p>Hola.</p></BODY></html>`,
+		},
+		{
+			name:       "Banner at the end of the body",
+			disclaimer: disclaimer{text: "Traducción automática.", language: "es", position: disclaimerAppend},
+			format:     formatHTML,
+			translated: `<html><body><p>Hola.</p></body></html>`,
+			expected:   `<html><body><p>Hola.</p><div class="translation-disclaimer" role="note" lang="es">Traducción automática.</div></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := TranslateResponse{TranslatedText: tt.translated, Alignment: tt.alignment, Parameters: &ResolvedParameters{}}
+			tt.disclaimer.add(&response, tt.format)
+			if response.TranslatedText != tt.expected {
+				t.Errorf("add() = %q, expected %q", response.TranslatedText, tt.expected)
+			}
+			if response.Disclaimer != tt.disclaimer.text || response.Parameters.Disclaimer != tt.disclaimer.position {
+				t.Errorf("add() disclaimer = %q, position = %q, expected %q and %q", response.Disclaimer, response.Parameters.Disclaimer, tt.disclaimer.text, tt.disclaimer.position)
+			}
+			for i, sentence := range response.Alignment {
+				if sentence != tt.expectedAlignment[i] {
+					t.Errorf("add() alignment = %+v, expected %+v", sentence, tt.expectedAlignment[i])
+				}
+			}
+		})
+	}
+
+	// An earlier translation matches the sentences of its text again without the disclaimer
+	d := &disclaimer{text: "Traducción automática.", position: disclaimerAppend}
+	if got := d.remove(d.addToText("Hola. ")); got != "Hola." {
+		t.Errorf("remove() = %q, expected %q", got, "Hola.")
+	}
+}
+
+func TestHandleDisclaimer(t *testing.T) {
+	templates := []map[string]dynamoTypes.AttributeValue{
+		{
+			"tenant":   &dynamoTypes.AttributeValueMemberS{Value: "*"},
+			"language": &dynamoTypes.AttributeValueMemberS{Value: "es"},
+			"text":     &dynamoTypes.AttributeValueMemberS{Value: "Traducción automática, prevalece el original."},
+		},
+		{
+			"tenant":   &dynamoTypes.AttributeValueMemberS{Value: "acme"},
+			"language": &dynamoTypes.AttributeValueMemberS{Value: "*"},
+			"text":     &dynamoTypes.AttributeValueMemberS{Value: "Machine translated by Acme."},
+			"position": &dynamoTypes.AttributeValueMemberS{Value: disclaimerPrepend},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		templates        []map[string]dynamoTypes.AttributeValue
+		scanErr          error
+		tenant           string
+		body             string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name: "Without disclaimer",
+			body: `{"source_language":"en","target_language":"es","text":"Hello"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola "}`,
+			},
+		},
+		{
+			name: "Default disclaimer",
+			body: `{"source_language":"en","target_language":"es","text":"Hello","disclaimer":"append"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola\n\nThis text was machine translated. The original text governs.","disclaimer":"This text was machine translated. The original text governs."}`,
+			},
+		},
+		{
+			name:      "Localized disclaimer",
+			templates: templates,
+			body:      `{"source_language":"en","target_language":"es","text":"Hello","disclaimer":"append","echo_parameters":true}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola\n\nTraducción automática, prevalece el original.","disclaimer":"Traducción automática, prevalece el original.","parameters":{"source_language":"en","target_language":"es","format":"text","disclaimer":"append"}}`,
+			},
+		},
+		{
+			name:      "Disclaimer required for the tenant",
+			templates: templates,
+			tenant:    "acme",
+			body:      `{"source_language":"en","target_language":"es","text":"Hello"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Machine translated by Acme.\n\nHola ","disclaimer":"Machine translated by Acme."}`,
+			},
+		},
+		{
+			name:      "Disclaimer not required for another tenant",
+			templates: templates,
+			tenant:    "globex",
+			body:      `{"source_language":"en","target_language":"es","text":"Hello"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola "}`,
+			},
+		},
+		{
+			name:      "Disclaimer required for a PDF",
+			templates: templates,
+			tenant:    "acme",
+			body:      `{"source_language":"en","target_language":"es","format":"pdf","document":"JVBERi0="}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Machine translated by Acme.\n\nHola","disclaimer":"Machine translated by Acme."}`,
+			},
+		},
+		{
+			name:      "Disclaimer required for an email",
+			templates: templates,
+			tenant:    "acme",
+			body:      `{"source_language":"en","target_language":"es","format":"email","document":"U3ViamVjdDogSGkNCg0KSGVsbG8="}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Subject: Hi\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\nMachine translated by Acme.\r\n\r\nHola","disclaimer":"Machine translated by Acme."}`,
+			},
+		},
+		{
+			name:      "Disclaimer required for a format without room for it",
+			templates: templates,
+			tenant:    "acme",
+			body:      `{"source_language":"en","target_language":"es","format":"plural","plurals":{"other":"Hello"}}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusUnprocessableEntity,
+				Body:       "A disclaimer is required, it is not supported for plural",
+			},
+		},
+		{
+			name:    "Disclaimer table unreadable",
+			scanErr: fmt.Errorf("mock error"),
+			body:    `{"source_language":"en","target_language":"es","text":"Hello"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Error resolving disclaimer",
+			},
+		},
+		{
+			name: "Disclaimer asked for a format without room for it",
+			body: `{"source_language":"en","target_language":"es","format":"plural","plurals":{"other":"Hello"},"disclaimer":"append"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"disclaimer","message":"disclaimer is only supported for text, html, pdf and email"}]}`,
+			},
+		},
+		{
+			name: "Unsupported position",
+			body: `{"source_language":"en","target_language":"es","text":"Hello","disclaimer":"footer"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"disclaimer","message":"disclaimer \"footer\" is not supported"}]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousTable := disclaimerTableName
+			disclaimerTableName = ""
+			if tt.templates != nil || tt.scanErr != nil {
+				disclaimerTableName = "Disclaimers"
+			}
+			defer func() { disclaimerTableName = previousTable }()
+
+			h := newMockHandler(map[string]string{"Hello": "Hola"})
+			h.dynamoClient.(*MockDynamoDBClient).ScanFunc = func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+				if tt.scanErr != nil {
+					return nil, tt.scanErr
+				}
+				return &dynamodb.ScanOutput{Items: tt.templates}, nil
+			}
+			h.textractClient = &MockTextractClient{
+				DetectDocumentTextFunc: func(ctx context.Context, params *textract.DetectDocumentTextInput, optFns ...func(*textract.Options)) (*textract.DetectDocumentTextOutput, error) {
+					return &textract.DetectDocumentTextOutput{
+						Blocks: []textractTypes.Block{{BlockType: textractTypes.BlockTypeLine, Text: aws.String("Hello")}},
+					}, nil
+				},
+			}
+
+			var authorizer map[string]any
+			if tt.tenant != "" {
+				authorizer = map[string]any{"claims": map[string]any{"custom:tenant": tt.tenant}}
+			}
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+				HTTPMethod:     http.MethodPost,
+				Body:           tt.body,
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: authorizer},
+			})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+		})
+	}
+}
+
+func TestDisclaimerTemplates(t *testing.T) {
+	previousTable := disclaimerTableName
+	disclaimerTableName = "Disclaimers"
+	defer func() { disclaimerTableName = previousTable }()
+
+	var scanErr error
+	h := newMockHandler(nil)
+	h.dynamoClient.(*MockDynamoDBClient).ScanFunc = func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		return &dynamodb.ScanOutput{Items: []map[string]dynamoTypes.AttributeValue{{
+			"tenant":   &dynamoTypes.AttributeValueMemberS{Value: "*"},
+			"language": &dynamoTypes.AttributeValueMemberS{Value: "es"},
+			"text":     &dynamoTypes.AttributeValueMemberS{Value: "Traducción automática."},
+		}}}, nil
+	}
+
+	// Nothing read yet, a required disclaimer can't be ruled out
+	scanErr = fmt.Errorf("mock error")
+	if _, err := h.disclaimerTemplates(context.Background()); err == nil {
+		t.Errorf("disclaimerTemplates() without templates read expected an error")
+	}
+
+	scanErr = nil
+	if templates, err := h.disclaimerTemplates(context.Background()); err != nil || len(templates) != 1 {
+		t.Fatalf("disclaimerTemplates() = %v, %v, expected 1 template", templates, err)
+	}
+
+	// The templates read before are kept once their TTL has passed and the table can't be read
+	scanErr = fmt.Errorf("mock error")
+	h.disclaimers.refreshAt = time.Now()
+	if templates, err := h.disclaimerTemplates(context.Background()); err != nil || len(templates) != 1 {
+		t.Errorf("disclaimerTemplates() = %v, %v, expected the templates read before", templates, err)
+	}
+}
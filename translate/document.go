@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	handlerModeDocumentTask = "document-task"
+
+	documentTaskChunk     = "chunk"
+	documentTaskTranslate = "translate"
+	documentTaskReduce    = "reduce"
+
+	// documentJobPrefix is the prefix of the chunks and translated chunks of a document job
+	documentJobPrefix = "documents/"
+
+	// defaultDocumentChunkSize is the number of bytes of a chunk, small enough for a single
+	// invocation to translate it
+	defaultDocumentChunkSize = 64 * 1024
+
+	// maxConcurrentDocumentChunks is the number of chunks the map state translates at once
+	maxConcurrentDocumentChunks = 10
+)
+
+// DocumentTaskRequest is the event of a Step Functions task translating a large text
+// document in the bulk bucket. The document is split into chunks, the chunks are translated
+// by a map state and the translated chunks are joined into the output document.
+type DocumentTaskRequest struct {
+	// Action is the step of the state machine, one of "chunk", "translate" or "reduce"
+	Action string `json:"action"`
+	// JobID identifies the job, usually the name of the state machine execution
+	JobID string `json:"job_id"`
+	// SourceLanguage is the language code of the document
+	SourceLanguage string `json:"source_language"`
+	// TargetLanguage is the language code the document is translated to
+	TargetLanguage string `json:"target_language"`
+	// Key is the key of the document in the bulk bucket
+	Key string `json:"key"`
+	// Chunk is the chunk translated by the translate action
+	Chunk *DocumentChunk `json:"chunk,omitempty"`
+	// Chunks are the translated chunks joined by the reduce action
+	Chunks []DocumentChunk `json:"chunks,omitempty"`
+}
+
+// DocumentChunk is a part of a document stored in the bulk bucket
+type DocumentChunk struct {
+	// Index is the position of the chunk in the document
+	Index int `json:"index"`
+	// Key is the key of the source chunk
+	Key string `json:"key"`
+	// TranslatedKey is the key of the translated chunk, which checkpoints the translation
+	TranslatedKey string `json:"translated_key"`
+}
+
+// DocumentTaskResult is the output of a document task
+type DocumentTaskResult struct {
+	// Chunks are the chunks written by the chunk action
+	Chunks []DocumentChunk `json:"chunks,omitempty"`
+	// Chunk is the chunk translated by the translate action
+	Chunk *DocumentChunk `json:"chunk,omitempty"`
+	// OutputKey is the key of the translated document written by the reduce action
+	OutputKey string `json:"output_key,omitempty"`
+}
+
+func (h *handler) handleDocumentTask(ctx context.Context, request DocumentTaskRequest) (DocumentTaskResult, error) {
+	defer flushTelemetry(ctx)
+
+	if request.JobID == "" || request.Key == "" {
+		return DocumentTaskResult{}, fmt.Errorf("job_id and key are required")
+	}
+	if request.Action != documentTaskReduce {
+		if err := configuredLanguagePolicy.checkPair(request.SourceLanguage, request.TargetLanguage); err != nil {
+			return DocumentTaskResult{}, fmt.Errorf("job %s: %w", request.JobID, err)
+		}
+	}
+
+	switch request.Action {
+	case documentTaskChunk:
+		return h.chunkDocument(ctx, request)
+	case documentTaskTranslate:
+		return h.translateDocumentChunk(ctx, request)
+	case documentTaskReduce:
+		return h.reduceDocument(ctx, request)
+	default:
+		return DocumentTaskResult{}, fmt.Errorf("action %q is not supported", request.Action)
+	}
+}
+
+// chunkDocument splits the document into chunks on line breaks, storing each chunk
+func (h *handler) chunkDocument(ctx context.Context, request DocumentTaskRequest) (DocumentTaskResult, error) {
+	document, err := h.getBulkObject(ctx, request.Key)
+	if err != nil {
+		return DocumentTaskResult{}, fmt.Errorf("error fetching document: %w", err)
+	}
+
+	var result DocumentTaskResult
+	for i, chunk := range splitDocument(string(document), documentChunkSize) {
+		key := documentJobPrefix + request.JobID + "/chunks/" + strconv.Itoa(i)
+		if err := h.putBulkObject(ctx, key, []byte(chunk)); err != nil {
+			return DocumentTaskResult{}, fmt.Errorf("error storing chunk %d: %w", i, err)
+		}
+		result.Chunks = append(result.Chunks, DocumentChunk{
+			Index:         i,
+			Key:           key,
+			TranslatedKey: documentJobPrefix + request.JobID + "/translated/" + strconv.Itoa(i),
+		})
+	}
+
+	log.Printf("Split s3://%s/%s into %d chunks for job %s", bulkBucket, request.Key, len(result.Chunks), request.JobID)
+	return result, nil
+}
+
+// translateDocumentChunk translates a chunk line by line. A chunk translated by an earlier
+// attempt of the task is not translated again.
+func (h *handler) translateDocumentChunk(ctx context.Context, request DocumentTaskRequest) (DocumentTaskResult, error) {
+	if request.Chunk == nil {
+		return DocumentTaskResult{}, fmt.Errorf("chunk is required")
+	}
+	chunk := *request.Chunk
+
+	_, err := h.getBulkObject(ctx, chunk.TranslatedKey)
+	if err == nil {
+		return DocumentTaskResult{Chunk: &chunk}, nil
+	}
+	if !errors.As(err, new(*s3Types.NoSuchKey)) {
+		return DocumentTaskResult{}, fmt.Errorf("error checking chunk %d: %w", chunk.Index, err)
+	}
+
+	text, err := h.getBulkObject(ctx, chunk.Key)
+	if err != nil {
+		return DocumentTaskResult{}, fmt.Errorf("error fetching chunk %d: %w", chunk.Index, err)
+	}
+
+	// Lines are translated separately to keep the layout of the document
+	lines := strings.Split(string(text), "\n")
+	var indexes []int
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			indexes = append(indexes, i)
+		}
+	}
+	if err := h.translateFields(ctx, request.SourceLanguage, request.TargetLanguage, lines, indexes); err != nil {
+		return DocumentTaskResult{}, fmt.Errorf("error translating chunk %d: %w", chunk.Index, err)
+	}
+
+	if err := h.putBulkObject(ctx, chunk.TranslatedKey, []byte(strings.Join(lines, "\n"))); err != nil {
+		return DocumentTaskResult{}, fmt.Errorf("error storing chunk %d: %w", chunk.Index, err)
+	}
+
+	return DocumentTaskResult{Chunk: &chunk}, nil
+}
+
+// reduceDocument joins the translated chunks in order into the translated document
+func (h *handler) reduceDocument(ctx context.Context, request DocumentTaskRequest) (DocumentTaskResult, error) {
+	translated := make([][]byte, len(request.Chunks))
+	for _, chunk := range request.Chunks {
+		if chunk.Index < 0 || chunk.Index >= len(translated) || translated[chunk.Index] != nil {
+			return DocumentTaskResult{}, fmt.Errorf("chunk index %d is out of sequence", chunk.Index)
+		}
+
+		text, err := h.getBulkObject(ctx, chunk.TranslatedKey)
+		if err != nil {
+			return DocumentTaskResult{}, fmt.Errorf("error fetching translated chunk %d: %w", chunk.Index, err)
+		}
+		translated[chunk.Index] = text
+	}
+
+	outputKey := bulkOutputPrefix + request.TargetLanguage + "/" + request.Key
+	if err := h.putBulkObject(ctx, outputKey, bytes.Join(translated, nil)); err != nil {
+		return DocumentTaskResult{}, fmt.Errorf("error storing document: %w", err)
+	}
+
+	log.Printf("Translated %d chunks of job %s to s3://%s/%s", len(request.Chunks), request.JobID, bulkBucket, outputKey)
+	return DocumentTaskResult{OutputKey: outputKey}, nil
+}
+
+// splitDocument splits the text into chunks of about size bytes, only breaking it after a
+// line break. Lines longer than the size are kept whole.
+func splitDocument(text string, size int) []string {
+	var (
+		chunks  []string
+		current strings.Builder
+	)
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if current.Len() > 0 && current.Len()+len(line) > size {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+		current.WriteString(line)
+	}
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
+	}
+	return chunks
+}
+
+// getBulkObject reads an object of the bulk bucket
+func (h *handler) getBulkObject(ctx context.Context, key string) ([]byte, error) {
+	object, err := h.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bulkBucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Body.Close()
+
+	return io.ReadAll(object.Body)
+}
+
+// putBulkObject writes an object to the bulk bucket
+func (h *handler) putBulkObject(ctx context.Context, key string, body []byte) error {
+	_, err := h.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bulkBucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("text/plain; charset=utf-8"),
+	})
+	return err
+}
+
+// documentStateMachine returns the Amazon States Language definition of the document state
+// machine invoking the function. The execution input is a DocumentTaskRequest without action
+// and job_id, the execution name is the job id. Every task is retried with backoff, and the
+// translate tasks skip chunks translated by an earlier attempt, so a failed execution can be
+// redriven without translating the document again.
+//
+// statemachine/translate_document.asl.json is generated from it, run
+// go test -run TestDocumentStateMachine -update after changing it.
+func documentStateMachine(functionArn string) ([]byte, error) {
+	retry := []map[string]any{{
+		"ErrorEquals":     []string{"States.TaskFailed", "Lambda.TooManyRequestsException", "Lambda.ServiceException"},
+		"IntervalSeconds": 2,
+		"MaxAttempts":     6,
+		"BackoffRate":     2,
+	}}
+	parameters := func(action string, extra map[string]any) map[string]any {
+		extra["action"] = action
+		extra["job_id.$"] = "$$.Execution.Name"
+		extra["key.$"] = "$.key"
+		extra["source_language.$"] = "$.source_language"
+		extra["target_language.$"] = "$.target_language"
+		return extra
+	}
+	task := func(parameters map[string]any) map[string]any {
+		state := map[string]any{
+			"Type":     "Task",
+			"Resource": functionArn,
+			"Retry":    retry,
+		}
+		if parameters != nil {
+			state["Parameters"] = parameters
+		}
+		return state
+	}
+
+	chunk := task(parameters(documentTaskChunk, map[string]any{}))
+	chunk["ResultPath"] = "$.chunked"
+	chunk["Next"] = "Translate"
+
+	// The map state selects the parameters of each item, the task passes them through
+	translateChunk := task(nil)
+	translateChunk["OutputPath"] = "$.chunk"
+	translateChunk["End"] = true
+	translate := map[string]any{
+		"Type":           "Map",
+		"ItemsPath":      "$.chunked.chunks",
+		"MaxConcurrency": maxConcurrentDocumentChunks,
+		"ItemSelector":   parameters(documentTaskTranslate, map[string]any{"chunk.$": "$$.Map.Item.Value"}),
+		"ItemProcessor": map[string]any{
+			"ProcessorConfig": map[string]any{"Mode": "INLINE"},
+			"StartAt":         "TranslateChunk",
+			"States":          map[string]any{"TranslateChunk": translateChunk},
+		},
+		"ResultPath": "$.translated",
+		"Next":       "Reduce",
+	}
+
+	reduce := task(parameters(documentTaskReduce, map[string]any{"chunks.$": "$.translated"}))
+	reduce["End"] = true
+
+	definition, err := json.Marshal(map[string]any{
+		"Comment": "Translates a document of the bulk bucket in chunks",
+		"StartAt": "Chunk",
+		"States": map[string]any{
+			"Chunk":     chunk,
+			"Translate": translate,
+			"Reduce":    reduce,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// jsoniter doesn't indent nested maps consistently
+	var indented bytes.Buffer
+	if err := stdjson.Indent(&indented, definition, "", "  "); err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var update = flag.Bool("update", false, "update generated files")
+
+// newMockBulkBucket returns an S3 client storing objects in the map
+func newMockBulkBucket(objects map[string]string) *MockS3Client {
+	return &MockS3Client{
+		GetObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			object, ok := objects[*params.Key]
+			if !ok {
+				return nil, &s3Types.NoSuchKey{}
+			}
+			return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(object))}, nil
+		},
+		PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			body, _ := io.ReadAll(params.Body)
+			objects[*params.Key] = string(body)
+			return &s3.PutObjectOutput{}, nil
+		},
+	}
+}
+
+func TestSplitDocument(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		size     int
+		expected []string
+	}{
+		{
+			name:     "Fits in a chunk",
+			text:     "one\ntwo\n",
+			size:     16,
+			expected: []string{"one\ntwo\n"},
+		},
+		{
+			name:     "Split on line breaks",
+			text:     "one\ntwo\nthree",
+			size:     8,
+			expected: []string{"one\ntwo\n", "three"},
+		},
+		{
+			name:     "Long line is kept whole",
+			text:     "one\na very long line\ntwo\n",
+			size:     8,
+			expected: []string{"one\n", "a very long line\n", "two\n"},
+		},
+		{
+			name: "Empty document",
+			text: "",
+			size: 8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitDocument(tt.text, tt.size)
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("splitDocument() = %q, expected %q", got, tt.expected)
+			}
+			if strings.Join(got, "") != tt.text {
+				t.Errorf("splitDocument() lost text, got %q", got)
+			}
+		})
+	}
+}
+
+func TestHandleDocumentTask(t *testing.T) {
+	translations := map[string]string{
+		"Hello.":     "Hola.",
+		"Thank you.": "Gracias.",
+	}
+	objects := map[string]string{
+		"books/letter.txt": "Hello.\n\nThank you.\n",
+	}
+
+	h := newMockHandler(translations)
+	h.s3Client = newMockBulkBucket(objects)
+
+	previous := documentChunkSize
+	documentChunkSize = 8
+	defer func() { documentChunkSize = previous }()
+
+	request := DocumentTaskRequest{
+		JobID:          "job",
+		SourceLanguage: "en",
+		TargetLanguage: "es",
+		Key:            "books/letter.txt",
+	}
+
+	request.Action = documentTaskChunk
+	chunked, err := h.handleDocumentTask(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handleDocumentTask(chunk) error = %v", err)
+	}
+	if len(chunked.Chunks) != 2 {
+		t.Fatalf("handleDocumentTask(chunk) = %d chunks, expected 2", len(chunked.Chunks))
+	}
+
+	// A chunk translated by an earlier attempt is kept
+	objects[chunked.Chunks[1].TranslatedKey] = "Checkpoint.\n"
+
+	request.Action = documentTaskTranslate
+	var translated []DocumentChunk
+	for _, chunk := range chunked.Chunks {
+		request.Chunk = &chunk
+		result, err := h.handleDocumentTask(context.Background(), request)
+		if err != nil {
+			t.Fatalf("handleDocumentTask(translate) error = %v", err)
+		}
+		translated = append(translated, *result.Chunk)
+	}
+	request.Chunk = nil
+
+	// The map state may return the chunks in any order
+	slices.Reverse(translated)
+
+	request.Action = documentTaskReduce
+	request.Chunks = translated
+	reduced, err := h.handleDocumentTask(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handleDocumentTask(reduce) error = %v", err)
+	}
+
+	if reduced.OutputKey != "translated/es/books/letter.txt" {
+		t.Errorf("handleDocumentTask(reduce) output key = %q", reduced.OutputKey)
+	}
+	if expected := "Hola.\n\nCheckpoint.\n"; objects[reduced.OutputKey] != expected {
+		t.Errorf("translated document = %q, expected %q", objects[reduced.OutputKey], expected)
+	}
+
+	request.Chunks = append(translated, translated[0])
+	if _, err := h.handleDocumentTask(context.Background(), request); err == nil {
+		t.Errorf("handleDocumentTask(reduce) with a duplicate chunk, expected an error")
+	}
+
+	request.Action = "unknown"
+	if _, err := h.handleDocumentTask(context.Background(), request); err == nil {
+		t.Errorf("handleDocumentTask(unknown) expected an error")
+	}
+
+	previousPolicy := configuredLanguagePolicy
+	configuredLanguagePolicy = languagePolicy{blocked: parseLanguageList("es")}
+	defer func() { configuredLanguagePolicy = previousPolicy }()
+
+	request.Action = documentTaskChunk
+	if _, err := h.handleDocumentTask(context.Background(), request); !errors.As(err, new(*languagePairNotAllowed)) {
+		t.Errorf("handleDocumentTask(chunk) to a blocked language error = %v, expected the pair to be rejected", err)
+	}
+}
+
+func TestDocumentStateMachine(t *testing.T) {
+	const path = "../statemachine/translate_document.asl.json"
+
+	got, err := documentStateMachine("${DocumentTaskFunctionArn}")
+	if err != nil {
+		t.Fatalf("documentStateMachine() error = %v", err)
+	}
+	got = append(got, '\n')
+
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("error writing %s: %v", path, err)
+		}
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", path, err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Errorf("%s is out of date, run go test -run TestDocumentStateMachine -update", path)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// defaultTranslatePrice is the AWS Translate price in USD per million characters of standard
+// real-time translation
+const defaultTranslatePrice = 15.0
+
+// translatePrice is the price in USD per million characters the cost of the fresh text of a
+// dry run is estimated with
+var translatePrice float64
+
+// CostEstimate is the result of a dry run, which looks the segments of the request up in the
+// cache without translating the misses
+type CostEstimate struct {
+	// Segments is the number of segments of the request
+	Segments int64 `json:"segments"`
+	// CacheHits is the number of segments translated by the cache
+	CacheHits int64 `json:"cache_hits"`
+	// FreshSegments and FreshCharacters are the segments the provider would translate and
+	// their length in characters
+	FreshSegments   int64 `json:"fresh_segments"`
+	FreshCharacters int64 `json:"fresh_characters"`
+	// EstimatedCost is the cost in USD of translating the fresh characters
+	EstimatedCost float64 `json:"estimated_cost"`
+}
+
+// translationUsage counts the segments of a request looked up in the cache and the misses
+// translated by the provider
+type translationUsage struct {
+	segments        atomic.Int64
+	cacheHits       atomic.Int64
+	freshSegments   atomic.Int64
+	freshCharacters atomic.Int64
+}
+
+type dryRunKey struct{}
+
+// withDryRun returns a context whose cache misses are counted rather than translated, the
+// context is unchanged and the dry run nil unless enabled
+func withDryRun(ctx context.Context, enabled bool) (context.Context, *translationUsage) {
+	if !enabled {
+		return ctx, nil
+	}
+	u := &translationUsage{}
+	return context.WithValue(ctx, dryRunKey{}, u), u
+}
+
+// dryRunFromContext returns the dry run of the request, nil when the request translates
+func dryRunFromContext(ctx context.Context) *translationUsage {
+	u, _ := ctx.Value(dryRunKey{}).(*translationUsage)
+	return u
+}
+
+// recordLookup counts a segment looked up in the cache
+func (u *translationUsage) recordLookup(hit bool) {
+	u.segments.Add(1)
+	if hit {
+		u.cacheHits.Add(1)
+	}
+}
+
+// recordMiss counts a segment the provider would translate
+func (u *translationUsage) recordMiss(text string) {
+	u.freshSegments.Add(1)
+	u.freshCharacters.Add(int64(utf8.RuneCountInString(text)))
+}
+
+// Estimate returns the estimate of the segments counted so far, nil without usage. The cost
+// is rounded to a hundredth of a cent.
+func (u *translationUsage) Estimate() *CostEstimate {
+	if u == nil {
+		return nil
+	}
+	freshCharacters := u.freshCharacters.Load()
+	return &CostEstimate{
+		Segments:        u.segments.Load(),
+		CacheHits:       u.cacheHits.Load(),
+		FreshSegments:   u.freshSegments.Load(),
+		FreshCharacters: freshCharacters,
+		EstimatedCost:   math.Round(float64(freshCharacters)*translatePrice/1e6*1e4) / 1e4,
+	}
+}
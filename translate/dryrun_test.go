@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestHandleDryRun(t *testing.T) {
+	cachedHash := getCacheKey("en", "es", "Hello.")
+	csvDocument := base64.StdEncoding.EncodeToString([]byte("sku,title\nA1,Hello.\nA2,Red shirt.\n"))
+
+	tests := []struct {
+		name             string
+		body             string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name: "Text",
+			body: `{"source_language":"en","target_language":"es","text":"Hello. How are you today?","dry_run":true}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"","estimate":{"segments":2,"cache_hits":1,"fresh_segments":1,"fresh_characters":18,"estimated_cost":0.0003}}`,
+			},
+		},
+		{
+			name: "CSV",
+			body: `{"source_language":"en","target_language":"es","format":"csv","document":"` + csvDocument + `","columns":["title"],"dry_run":true}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"","rows":2,"estimate":{"segments":2,"cache_hits":1,"fresh_segments":1,"fresh_characters":10,"estimated_cost":0.0001}}`,
+			},
+		},
+		{
+			name: "Unsupported format",
+			body: `{"source_language":"en","target_language":"es","format":"pdf","document":"JVBERi0=","dry_run":true}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"dry_run","message":"dry_run is only supported for text, html and csv"}]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var translations, writes atomic.Int32
+
+			h := newMockHandler(nil)
+			h.dynamoClient = &MockDynamoDBClient{
+				GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					hash := params.Key["hash"].(*dynamoTypes.AttributeValueMemberS).Value
+					if hash != cachedHash {
+						return &dynamodb.GetItemOutput{}, nil
+					}
+					return &dynamodb.GetItemOutput{Item: map[string]dynamoTypes.AttributeValue{
+						"hash":            &dynamoTypes.AttributeValueMemberS{Value: cachedHash},
+						"source_text":     &dynamoTypes.AttributeValueMemberS{Value: "Hello."},
+						"translated_text": &dynamoTypes.AttributeValueMemberS{Value: "Hola."},
+						"source_language": &dynamoTypes.AttributeValueMemberS{Value: "en"},
+						"target_language": &dynamoTypes.AttributeValueMemberS{Value: "es"},
+					}}, nil
+				},
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					if params.Item["hash"].(*dynamoTypes.AttributeValueMemberS).Value != supportedLanguagesHash {
+						writes.Add(1)
+					}
+					return &dynamodb.PutItemOutput{}, nil
+				},
+				UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+					writes.Add(1)
+					return &dynamodb.UpdateItemOutput{}, nil
+				},
+			}
+			h.translateClient.(*MockTranslateClient).TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				translations.Add(1)
+				return &translate.TranslateTextOutput{TranslatedText: aws.String("mock")}, nil
+			}
+			h.s3Client = &MockS3Client{
+				PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+					writes.Add(1)
+					return &s3.PutObjectOutput{}, nil
+				},
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+			if translations.Load() != 0 || writes.Load() != 0 {
+				t.Errorf("handle() made %d translations and %d writes, expected none", translations.Load(), writes.Load())
+			}
+		})
+	}
+}
+
+func TestCostEstimate(t *testing.T) {
+	original := translatePrice
+	translatePrice = 15
+	defer func() { translatePrice = original }()
+
+	var d *translationUsage
+	if d.Estimate() != nil {
+		t.Errorf("Estimate() of a translating request = %v, expected nil", d.Estimate())
+	}
+
+	d = &translationUsage{}
+	d.recordLookup(false)
+	for range 1000 {
+		d.recordMiss("Ünïcödé characters count once.")
+	}
+
+	got := d.Estimate()
+	expected := CostEstimate{Segments: 1, FreshSegments: 1000, FreshCharacters: 30000, EstimatedCost: 0.45}
+	if *got != expected {
+		t.Errorf("Estimate() = %+v, expected %+v", *got, expected)
+	}
+}
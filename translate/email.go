@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	formatEmail = "email"
+
+	// emailSignatureDelimiter is the line starting the signature of a plain text message
+	emailSignatureDelimiter = "-- "
+)
+
+// headerField is a single message header, kept in the order it appeared in the message
+type headerField struct {
+	Name  string
+	Value string
+}
+
+func (h *handler) handleEmail(ctx context.Context, request TranslateRequest) (events.APIGatewayProxyResponse, error) {
+	message, err := base64.StdEncoding.DecodeString(request.Document)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "document must be base64 encoded",
+		}, nil
+	}
+
+	degradation := degradationFromContext(ctx)
+	moderation := moderationFromContext(ctx)
+
+	translatedMessage, err := h.translateEmail(ctx, request.SourceLanguage, request.TargetLanguage, message)
+	if response, ok := unavailableResponse(err); ok {
+		return response, nil
+	}
+	if response, ok := characterLimitResponse(err); ok {
+		return response, nil
+	}
+	if response, ok := languagePolicyResponse(err); ok {
+		return response, nil
+	}
+	if err != nil {
+		log.Printf("Error translating email: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error during translation",
+		}, nil
+	}
+
+	response := TranslateResponse{
+		TranslatedText: string(translatedMessage),
+		Skipped:        request.SourceLanguage == request.TargetLanguage,
+		Degraded:       degradation.Degraded(),
+		LowQuality:     degradation.LowQuality(),
+		Pivoted:        pivotReportFromContext(ctx).Pivoted(),
+		Segments:       moderation.Segments(),
+		Direction:      textDirection(request.TargetLanguage),
+	}
+	disclaimerFromContext(ctx).add(&response, formatEmail)
+
+	return newJSONResponse(ctx, response), nil
+}
+
+// translateEmail translates the text/plain and text/html parts of an RFC 2822 message,
+// preserving the headers and any other parts such as attachments
+func (h *handler) translateEmail(ctx context.Context, sourceLanguage, targetLanguage string, message []byte) ([]byte, error) {
+	fields, body, err := splitMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make(textproto.MIMEHeader)
+	for _, field := range fields {
+		header.Add(field.Name, strings.ReplaceAll(field.Value, "\r\n", ""))
+	}
+
+	translatedBody, err := h.translateMIMEPart(ctx, sourceLanguage, targetLanguage, header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the original headers back in order, picking up any encoding changes
+	var buf bytes.Buffer
+	written := make(map[string]bool)
+	for _, field := range fields {
+		key := textproto.CanonicalMIMEHeaderKey(field.Name)
+		if key == "Content-Type" || key == "Content-Transfer-Encoding" {
+			if written[key] {
+				continue
+			}
+			written[key] = true
+			field.Value = header.Get(key)
+		}
+		fmt.Fprintf(&buf, "%s: %s\r\n", field.Name, field.Value)
+	}
+	for _, key := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+		if !written[key] && header.Get(key) != "" {
+			fmt.Fprintf(&buf, "%s: %s\r\n", key, header.Get(key))
+		}
+	}
+	buf.WriteString("\r\n")
+	buf.Write(translatedBody)
+
+	return buf.Bytes(), nil
+}
+
+// translateMIMEPart translates the body of a single MIME entity, updating its header to
+// match the encoding of the returned body
+func (h *handler) translateMIMEPart(ctx context.Context, sourceLanguage, targetLanguage string, header textproto.MIMEHeader, body []byte) ([]byte, error) {
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// Leave entities we can't understand untouched
+		return body, nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		return h.translateMultipart(ctx, sourceLanguage, targetLanguage, body, params["boundary"])
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return body, nil
+	}
+	if disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition")); disposition == "attachment" {
+		return body, nil
+	}
+	if charset := strings.ToLower(params["charset"]); charset != "" && charset != "utf-8" && charset != "us-ascii" {
+		return body, nil
+	}
+
+	decoded, err := decodeTransferEncoding(body, header.Get("Content-Transfer-Encoding"))
+	if err != nil {
+		return nil, err
+	}
+
+	var translated string
+	if mediaType == "text/html" {
+		translated, err = h.translateHTML(ctx, sourceLanguage, targetLanguage, string(decoded))
+	} else {
+		translated, err = h.translatePlainText(ctx, sourceLanguage, targetLanguage, string(decoded))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if d := disclaimerFromContext(ctx); d != nil {
+		if mediaType == "text/html" {
+			translated = d.addToHTML(translated)
+		} else {
+			translated = d.addToText(translated)
+		}
+	}
+
+	var buf bytes.Buffer
+	writer := quotedprintable.NewWriter(&buf)
+	if _, err := writer.Write([]byte(translated)); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	params["charset"] = "utf-8"
+	header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	return buf.Bytes(), nil
+}
+
+// translatePlainText translates a text/plain body line by line to keep its line breaks and
+// paragraphs. The signature, from the "-- " delimiter on, is left untranslated.
+func (h *handler) translatePlainText(ctx context.Context, sourceLanguage, targetLanguage, text string) (string, error) {
+	lines := strings.Split(text, "\n")
+	crlf := make([]bool, len(lines))
+	var indexes []int
+	for i, line := range lines {
+		line, crlf[i] = strings.CutSuffix(line, "\r")
+		if line == emailSignatureDelimiter {
+			break
+		}
+		if strings.TrimSpace(line) != "" {
+			lines[i] = line
+			indexes = append(indexes, i)
+		}
+	}
+	if err := h.translateFields(ctx, sourceLanguage, targetLanguage, lines, indexes); err != nil {
+		return "", err
+	}
+	for _, i := range indexes {
+		if crlf[i] {
+			lines[i] += "\r"
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// translateMultipart translates each part of a multipart body, keeping the original boundary
+func (h *handler) translateMultipart(ctx context.Context, sourceLanguage, targetLanguage string, body []byte, boundary string) ([]byte, error) {
+	if boundary == "" {
+		return nil, fmt.Errorf("multipart body is missing a boundary")
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return nil, err
+	}
+
+	for {
+		part, err := reader.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read multipart body: %w", err)
+		}
+
+		partBody, err := io.ReadAll(part)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read multipart body: %w", err)
+		}
+
+		partHeader := textproto.MIMEHeader(part.Header)
+		translatedPart, err := h.translateMIMEPart(ctx, sourceLanguage, targetLanguage, partHeader, partBody)
+		if err != nil {
+			return nil, err
+		}
+
+		partWriter, err := writer.CreatePart(partHeader)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := partWriter.Write(translatedPart); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// extractEmailText returns the decoded text of the text/plain and text/html parts of a message
+func extractEmailText(message []byte) (string, error) {
+	fields, body, err := splitMessage(message)
+	if err != nil {
+		return "", err
+	}
+
+	header := make(textproto.MIMEHeader)
+	for _, field := range fields {
+		header.Add(field.Name, strings.ReplaceAll(field.Value, "\r\n", ""))
+	}
+
+	texts, err := collectMIMEText(header, body)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(texts, "\n"), nil
+}
+
+// collectMIMEText walks a MIME entity collecting the text of every inline text part
+func collectMIMEText(header textproto.MIMEHeader, body []byte) ([]string, error) {
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		var texts []string
+		reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read multipart body: %w", err)
+			}
+
+			partBody, err := io.ReadAll(part)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read multipart body: %w", err)
+			}
+
+			partTexts, err := collectMIMEText(textproto.MIMEHeader(part.Header), partBody)
+			if err != nil {
+				return nil, err
+			}
+			texts = append(texts, partTexts...)
+		}
+		return texts, nil
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return nil, nil
+	}
+	if disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition")); disposition == "attachment" {
+		return nil, nil
+	}
+
+	decoded, err := decodeTransferEncoding(body, header.Get("Content-Transfer-Encoding"))
+	if err != nil {
+		return nil, err
+	}
+
+	if mediaType == "text/html" {
+		_, sentences, _, err := getTextFromHTML(string(decoded), defaultSegmenter)
+		if err != nil {
+			return nil, err
+		}
+		return []string{strings.Join(sentences, " ")}, nil
+	}
+
+	return []string{string(decoded)}, nil
+}
+
+// splitMessage splits a raw message into its ordered header fields and body
+func splitMessage(message []byte) ([]headerField, []byte, error) {
+	reader := bufio.NewReader(bytes.NewReader(message))
+
+	var fields []headerField
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, nil, err
+		}
+
+		trimmed := strings.TrimRight(line, "\r\n")
+		if trimmed == "" {
+			break
+		}
+
+		// Folded header lines continue the previous field
+		if (trimmed[0] == ' ' || trimmed[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1].Value += "\r\n" + trimmed
+		} else {
+			name, value, found := strings.Cut(trimmed, ":")
+			if !found {
+				return nil, nil, fmt.Errorf("malformed header line %q", trimmed)
+			}
+			fields = append(fields, headerField{Name: name, Value: strings.TrimSpace(value)})
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil, nil, fmt.Errorf("message has no headers")
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fields, body, nil
+}
+
+// decodeTransferEncoding decodes a body according to its Content-Transfer-Encoding
+func decodeTransferEncoding(body []byte, encoding string) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return io.ReadAll(quotedprintable.NewReader(bytes.NewReader(body)))
+	case "base64":
+		return io.ReadAll(base64.NewDecoder(base64.StdEncoding, newLineStripper(body)))
+	default:
+		return body, nil
+	}
+}
+
+// newLineStripper returns a reader over the body with line breaks removed
+func newLineStripper(body []byte) io.Reader {
+	stripped := bytes.NewBuffer(make([]byte, 0, len(body)))
+	for _, b := range body {
+		if b != '\r' && b != '\n' {
+			stripped.WriteByte(b)
+		}
+	}
+	return stripped
+}
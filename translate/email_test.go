@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		expectedFields []headerField
+		expectedBody   string
+		wantErr        bool
+	}{
+		{
+			name:  "Headers and body",
+			input: "From: a@example.com\r\nSubject: Hello\r\n\r\nHello world.",
+			expectedFields: []headerField{
+				{Name: "From", Value: "a@example.com"},
+				{Name: "Subject", Value: "Hello"},
+			},
+			expectedBody: "Hello world.",
+			wantErr:      false,
+		},
+		{
+			name:  "Folded header",
+			input: "Subject: Hello\r\n world\nTo: b@example.com\n\nBody",
+			expectedFields: []headerField{
+				{Name: "Subject", Value: "Hello\r\n world"},
+				{Name: "To", Value: "b@example.com"},
+			},
+			expectedBody: "Body",
+			wantErr:      false,
+		},
+		{
+			name:    "Malformed header",
+			input:   "Not a header\r\n\r\nBody",
+			wantErr: true,
+		},
+		{
+			name:    "Empty message",
+			input:   "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, body, err := splitMessage([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("splitMessage() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(fields) != len(tt.expectedFields) {
+				t.Errorf("splitMessage() fields = %v, expected %v", fields, tt.expectedFields)
+				return
+			}
+			for i := range fields {
+				if fields[i] != tt.expectedFields[i] {
+					t.Errorf("splitMessage() fields[%d] = %v, expected %v", i, fields[i], tt.expectedFields[i])
+				}
+			}
+
+			if string(body) != tt.expectedBody {
+				t.Errorf("splitMessage() body = %q, expected %q", body, tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestDecodeTransferEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		encoding string
+		expected string
+	}{
+		{
+			name:     "Quoted printable",
+			input:    "Caf=C3=A9 au=\r\n lait",
+			encoding: "quoted-printable",
+			expected: "Café au lait",
+		},
+		{
+			name:     "Base64 with line breaks",
+			input:    "SGVsbG8g\r\nd29ybGQ=",
+			encoding: "Base64",
+			expected: "Hello world",
+		},
+		{
+			name:     "7bit",
+			input:    "Hello world",
+			encoding: "7bit",
+			expected: "Hello world",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeTransferEncoding([]byte(tt.input), tt.encoding)
+			if err != nil {
+				t.Errorf("decodeTransferEncoding() error = %v", err)
+				return
+			}
+
+			if string(got) != tt.expected {
+				t.Errorf("decodeTransferEncoding() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractEmailText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "Single part plain text",
+			input:    "Subject: Hello\r\n\r\nHola mundo.",
+			expected: "Hola mundo.",
+			wantErr:  false,
+		},
+		{
+			name: "Multipart with html and attachment",
+			input: "Content-Type: multipart/mixed; boundary=B\r\n\r\n" +
+				"--B\r\nContent-Type: text/html\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n<p>Hola =C2=BFqu=C3=A9 tal?</p>\r\n" +
+				"--B\r\nContent-Type: text/plain\r\nContent-Disposition: attachment\r\n\r\nignored\r\n" +
+				"--B--\r\n",
+			expected: "Hola ¿qué tal?",
+			wantErr:  false,
+		},
+		{
+			name:    "Malformed message",
+			input:   "not a message",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractEmailText([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("extractEmailText() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != tt.expected {
+				t.Errorf("extractEmailText() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTranslateEmail(t *testing.T) {
+	translations := map[string]string{
+		"Hello world.": "Hola mundo.",
+		"Hello":        "Hola",
+	}
+
+	tests := []struct {
+		name        string
+		input       string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:  "Single part plain text",
+			input: "From: a@example.com\r\nSubject: Hello\r\n\r\nHello world.",
+			contains: []string{
+				"From: a@example.com\r\nSubject: Hello\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n",
+				"Hola mundo.",
+			},
+			notContains: []string{"Hello world."},
+		},
+		{
+			name: "Paragraphs and signature",
+			input: "From: a@example.com\r\n\r\n" +
+				"Hello world. Hello\r\n" +
+				"Hello world.\r\n" +
+				"\r\n" +
+				"Hello\r\n" +
+				"-- \r\n" +
+				"Hello world.\r\n",
+			contains: []string{
+				"\r\n\r\nHola mundo. Hola\r\nHola mundo.\r\n\r\nHola\r\n--=20\r\nHello world.\r\n",
+			},
+			notContains: []string{"Hola -- ", "Hola mundo.=20"},
+		},
+		{
+			name: "Multipart alternative with attachment",
+			input: "From: a@example.com\r\n" +
+				"Content-Type: multipart/mixed; boundary=BOUNDARY\r\n" +
+				"\r\n" +
+				"--BOUNDARY\r\n" +
+				"Content-Type: text/plain; charset=us-ascii\r\n" +
+				"\r\n" +
+				"Hello world.\r\n" +
+				"--BOUNDARY\r\n" +
+				"Content-Type: text/html; charset=utf-8\r\n" +
+				"Content-Transfer-Encoding: base64\r\n" +
+				"\r\n" +
+				base64.StdEncoding.EncodeToString([]byte("<p>Hello</p>")) + "\r\n" +
+				"--BOUNDARY\r\n" +
+				"Content-Type: text/plain\r\n" +
+				"Content-Disposition: attachment; filename=notes.txt\r\n" +
+				"\r\n" +
+				"Hello world.\r\n" +
+				"--BOUNDARY--\r\n",
+			contains: []string{
+				"From: a@example.com\r\nContent-Type: multipart/mixed; boundary=BOUNDARY\r\n\r\n",
+				"Hola mundo.",
+				"<p lang=3D\"es\">Hola</p>",
+				"filename=notes.txt\r\nContent-Type: text/plain\r\n\r\nHello world.",
+				"--BOUNDARY--",
+			},
+			notContains: []string{"base64"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(translations)
+
+			got, err := h.translateEmail(context.Background(), "en", "es", []byte(tt.input))
+			if err != nil {
+				t.Errorf("translateEmail() error = %v", err)
+				return
+			}
+
+			for _, expected := range tt.contains {
+				if !strings.Contains(string(got), expected) {
+					t.Errorf("translateEmail() = %q, expected to contain %q", got, expected)
+				}
+			}
+			for _, unexpected := range tt.notContains {
+				if strings.Contains(string(got), unexpected) {
+					t.Errorf("translateEmail() = %q, expected not to contain %q", got, unexpected)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleEmail(t *testing.T) {
+	h := newMockHandler(map[string]string{"Hello world.": "Hola mundo."})
+
+	message := base64.StdEncoding.EncodeToString([]byte("Subject: Hello\r\n\r\nHello world."))
+	got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+		Body: `{"source_language":"en","target_language":"es","format":"email","document":"` + message + `"}`,
+	})
+	if err != nil {
+		t.Errorf("handle() error = %v", err)
+		return
+	}
+
+	expected := events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       `{"translated_text":"Subject: Hello\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\nHola mundo."}`,
+	}
+	if got.StatusCode != expected.StatusCode || got.Body != expected.Body {
+		t.Errorf("handle() = %v, expected %v", got, expected)
+	}
+}
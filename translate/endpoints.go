@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// The services whose endpoint can be overridden, named as in SERVICE_ENDPOINTS
+const (
+	serviceTranslate        = "translate"
+	serviceDynamoDB         = "dynamodb"
+	serviceS3               = "s3"
+	serviceComprehend       = "comprehend"
+	serviceTextract         = "textract"
+	serviceSQS              = "sqs"
+	serviceKinesis          = "kinesis"
+	serviceFirehose         = "firehose"
+	serviceSageMakerRuntime = "sagemaker-runtime"
+	serviceSSM              = "ssm"
+	serviceSecretsManager   = "secretsmanager"
+)
+
+var endpointServices = []string{
+	serviceTranslate, serviceDynamoDB, serviceS3, serviceComprehend, serviceTextract,
+	serviceSQS, serviceKinesis, serviceFirehose, serviceSageMakerRuntime, serviceSSM,
+	serviceSecretsManager,
+}
+
+// serviceEndpoints are the endpoints replacing the public ones of the services, such as the
+// interface endpoints of a VPC without NAT, keyed by service or service@region
+var serviceEndpoints map[string]string
+
+// parseServiceEndpoints parses a list of service=url or service@region=url pairs separated by
+// commas, such as translate=https://vpce-0a1b.translate.eu-west-1.vpce.amazonaws.com. Invalid
+// pairs are logged and ignored.
+func parseServiceEndpoints(value string) map[string]string {
+	endpoints := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, endpoint, _ := strings.Cut(pair, "=")
+		key, endpoint = strings.TrimSpace(key), strings.TrimSpace(endpoint)
+		service, endpointRegion, scoped := strings.Cut(key, "@")
+		parsed, err := url.Parse(endpoint)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" ||
+			!slices.Contains(endpointServices, service) || (scoped && endpointRegion == "") {
+			log.Printf("Ignoring invalid service endpoint %q", pair)
+			continue
+		}
+		endpoints[key] = strings.TrimSuffix(endpoint, "/")
+	}
+	return endpoints
+}
+
+// serviceEndpoint returns the endpoint overriding that of the service in the region, nil when
+// it has none. An endpoint without region only applies to the region of the function, as the
+// interface endpoints of a VPC are regional.
+func serviceEndpoint(service, clientRegion string) *string {
+	if endpoint, ok := serviceEndpoints[service+"@"+clientRegion]; ok {
+		return &endpoint
+	}
+	if endpoint, ok := serviceEndpoints[service]; ok && clientRegion == region {
+		return &endpoint
+	}
+	return nil
+}
+
+// overrideEndpoint sets the base endpoint of a client of the service in the region when it is
+// overridden, leaving that of the configuration otherwise
+func overrideEndpoint(base **string, service, clientRegion string) {
+	if endpoint := serviceEndpoint(service, clientRegion); endpoint != nil {
+		*base = endpoint
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestParseServiceEndpoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected map[string]string
+	}{
+		{
+			name:     "Empty",
+			expected: map[string]string{},
+		},
+		{
+			name:  "Service and regional endpoints",
+			value: "translate=https://vpce-0a1b.translate.eu-west-1.vpce.amazonaws.com/, dynamodb@us-east-1 = https://dynamodb.us-east-1.amazonaws.com",
+			expected: map[string]string{
+				"translate":          "https://vpce-0a1b.translate.eu-west-1.vpce.amazonaws.com",
+				"dynamodb@us-east-1": "https://dynamodb.us-east-1.amazonaws.com",
+			},
+		},
+		{
+			name:     "Invalid endpoints",
+			value:    "polly=https://polly.amazonaws.com,translate=vpce-0a1b,s3=ftp://s3.local,sqs@=https://sqs.local,comprehend",
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseServiceEndpoints(tt.value); !maps.Equal(got, tt.expected) {
+				t.Errorf("parseServiceEndpoints() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOverrideEndpoint(t *testing.T) {
+	originalEndpoints, originalRegion := serviceEndpoints, region
+	serviceEndpoints = parseServiceEndpoints("translate=https://vpce-0a1b.translate.eu-west-1.vpce.amazonaws.com,translate@eu-central-1=https://vpce-2c3d.translate.eu-central-1.vpce.amazonaws.com")
+	region = "eu-west-1"
+	defer func() { serviceEndpoints, region = originalEndpoints, originalRegion }()
+
+	tests := []struct {
+		name         string
+		service      string
+		clientRegion string
+		base         *string
+		expected     *string
+	}{
+		{
+			name:         "Endpoint of the function region",
+			service:      serviceTranslate,
+			clientRegion: "eu-west-1",
+			expected:     aws.String("https://vpce-0a1b.translate.eu-west-1.vpce.amazonaws.com"),
+		},
+		{
+			name:         "Endpoint of another region",
+			service:      serviceTranslate,
+			clientRegion: "eu-central-1",
+			expected:     aws.String("https://vpce-2c3d.translate.eu-central-1.vpce.amazonaws.com"),
+		},
+		{
+			name:         "Region without endpoint",
+			service:      serviceTranslate,
+			clientRegion: "us-east-1",
+			base:         aws.String("http://localhost:4566"),
+			expected:     aws.String("http://localhost:4566"),
+		},
+		{
+			name:         "Service without endpoint",
+			service:      serviceDynamoDB,
+			clientRegion: "eu-west-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.base
+			overrideEndpoint(&got, tt.service, tt.clientRegion)
+			if aws.ToString(got) != aws.ToString(tt.expected) {
+				t.Errorf("overrideEndpoint() = %q, expected %q", aws.ToString(got), aws.ToString(tt.expected))
+			}
+		})
+	}
+}
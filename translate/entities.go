@@ -0,0 +1,121 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	comprehendTypes "github.com/aws/aws-sdk-go-v2/service/comprehend/types"
+)
+
+// entityProtection keeps the names of people, organizations and products Comprehend detects
+// in the source text verbatim in the translation, unless the request opts out
+var entityProtection bool
+
+// protectedEntityTypes are the Comprehend entity types kept verbatim by entity protection
+var protectedEntityTypes = map[string]bool{
+	string(comprehendTypes.EntityTypePerson):         true,
+	string(comprehendTypes.EntityTypeOrganization):   true,
+	string(comprehendTypes.EntityTypeCommercialItem): true,
+}
+
+type protectedEntitiesKey struct{}
+
+// withProtectedEntities returns a context keeping the entities verbatim in the translations,
+// unchanged without entities
+func withProtectedEntities(ctx context.Context, entities []string) context.Context {
+	if len(entities) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, protectedEntitiesKey{}, entities)
+}
+
+// protectedEntitiesFromContext returns the entities of the request kept verbatim, longest
+// first
+func protectedEntitiesFromContext(ctx context.Context) []string {
+	entities, _ := ctx.Value(protectedEntitiesKey{}).([]string)
+	return entities
+}
+
+// protectEntities detects the names of the source text of a text or html request and returns
+// a context keeping them verbatim. Failing to detect them is only logged, the text is then
+// translated as a whole.
+func (h *handler) protectEntities(ctx context.Context, request TranslateRequest) context.Context {
+	if !entityProtection || request.TranslateEntities || request.DryRun || h.comprehendClient == nil {
+		return ctx
+	}
+	if request.Format != "" && request.Format != formatText && request.Format != formatHTML {
+		return ctx
+	}
+	languageCode, ok := comprehendLanguage(request.SourceLanguage)
+	if !ok {
+		return ctx
+	}
+
+	text, err := requestPlainText(request)
+	if err != nil {
+		log.Printf("Error extracting the text to detect entities of: %v", err)
+		return ctx
+	}
+	detected, err := detectEntities(ctx, h.comprehendClient, languageCode, truncateBytes(text, maxAnalysisBytes))
+	if err != nil {
+		log.Printf("Error detecting entities to protect: %v", err)
+		return ctx
+	}
+
+	var entities []string
+	for _, entity := range detected {
+		if protectedEntityTypes[entity.Type] && strings.ContainsFunc(entity.Text, unicode.IsLetter) && !slices.Contains(entities, entity.Text) {
+			entities = append(entities, entity.Text)
+		}
+	}
+	// A name containing another is replaced first
+	slices.SortStableFunc(entities, func(a, b string) int {
+		return cmp.Compare(len(b), len(a))
+	})
+	return withProtectedEntities(ctx, entities)
+}
+
+// shieldEntities replaces the whole-word occurrences of the entities in the text with the
+// placeholders following the tokens already shielded
+func shieldEntities(text string, entities []string, tokens []string) (string, []string) {
+	for _, entity := range entities {
+		var (
+			shielded strings.Builder
+			offset   int
+		)
+		for {
+			index := strings.Index(text[offset:], entity)
+			if index < 0 {
+				break
+			}
+			start := offset + index
+			end := start + len(entity)
+			before, _ := utf8.DecodeLastRuneInString(text[:start])
+			after, _ := utf8.DecodeRuneInString(text[end:])
+			if isWordRune(before) || isWordRune(after) {
+				shielded.WriteString(text[offset:end])
+				offset = end
+				continue
+			}
+
+			shielded.WriteString(text[offset:start])
+			shielded.WriteString(shieldedTokenPlaceholder(len(tokens)))
+			tokens = append(tokens, entity)
+			offset = end
+		}
+		shielded.WriteString(text[offset:])
+		text = shielded.String()
+	}
+	return text, tokens
+}
+
+// isWordRune reports whether the rune is part of a word, a name inside a longer word isn't
+// the name
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
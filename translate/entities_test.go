@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/comprehend"
+	comprehendTypes "github.com/aws/aws-sdk-go-v2/service/comprehend/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func TestShieldEntities(t *testing.T) {
+	tests := []struct {
+		name             string
+		text             string
+		entities         []string
+		tokens           []string
+		expectedShielded string
+		expectedTokens   []string
+	}{
+		{
+			name:             "Names",
+			text:             "Anna Rose works at Rose Bakery with Anna.",
+			entities:         []string{"Rose Bakery", "Anna Rose", "Anna"},
+			expectedShielded: "{@1} works at {@0} with {@2}.",
+			expectedTokens:   []string{"Rose Bakery", "Anna Rose", "Anna"},
+		},
+		{
+			name:             "After shielded tokens",
+			text:             "Email {@0} at Acme.",
+			entities:         []string{"Acme"},
+			tokens:           []string{"sales@acme.com"},
+			expectedShielded: "Email {@0} at {@1}.",
+			expectedTokens:   []string{"sales@acme.com", "Acme"},
+		},
+		{
+			name:             "Name inside a word",
+			text:             "Annabel met Anna.",
+			entities:         []string{"Anna"},
+			expectedShielded: "Annabel met {@0}.",
+			expectedTokens:   []string{"Anna"},
+		},
+		{
+			name:             "No entities",
+			text:             "Hello.",
+			expectedShielded: "Hello.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shielded, tokens := shieldEntities(tt.text, tt.entities, tt.tokens)
+			if shielded != tt.expectedShielded || !reflect.DeepEqual(tokens, tt.expectedTokens) {
+				t.Errorf("shieldEntities() = %q, %q, expected %q, %q", shielded, tokens, tt.expectedShielded, tt.expectedTokens)
+			}
+		})
+	}
+}
+
+func TestEntityProtection(t *testing.T) {
+	original := entityProtection
+	entityProtection = true
+	defer func() { entityProtection = original }()
+
+	translations := map[string]string{
+		"{@1} thanks {@0}.":        "{@1} agradece a {@0}.",
+		"{@0} thanks Lucky Star.":  "{@0} agradece a Estrella de la Suerte.",
+		"Apple thanks Lucky Star.": "Manzana agradece a Estrella de la Suerte.",
+		"Nothing to protect here.": "Nada que proteger aquí.",
+	}
+
+	tests := []struct {
+		name             string
+		body             string
+		entities         []comprehendTypes.Entity
+		expectedResponse events.APIGatewayProxyResponse
+		expectedKey      string
+	}{
+		{
+			name: "Names are kept verbatim",
+			body: `{"source_language":"en","target_language":"es","text":"Apple thanks Lucky Star."}`,
+			entities: []comprehendTypes.Entity{
+				{Text: aws.String("Apple"), Type: comprehendTypes.EntityTypeOrganization, Score: aws.Float32(0.9)},
+				{Text: aws.String("Lucky Star"), Type: comprehendTypes.EntityTypePerson, Score: aws.Float32(0.9)},
+			},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Apple agradece a Lucky Star. "}`,
+			},
+			expectedKey: getCacheKey("en", "es+entities", "Apple thanks Lucky Star."),
+		},
+		{
+			name: "Other entity types and low scores are translated",
+			body: `{"source_language":"en","target_language":"es","text":"Apple thanks Lucky Star."}`,
+			entities: []comprehendTypes.Entity{
+				{Text: aws.String("Apple"), Type: comprehendTypes.EntityTypeCommercialItem, Score: aws.Float32(0.9)},
+				{Text: aws.String("Lucky Star"), Type: comprehendTypes.EntityTypeLocation, Score: aws.Float32(0.9)},
+				{Text: aws.String("thanks"), Type: comprehendTypes.EntityTypePerson, Score: aws.Float32(0.1)},
+			},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Apple agradece a Estrella de la Suerte. "}`,
+			},
+			expectedKey: getCacheKey("en", "es+entities", "Apple thanks Lucky Star."),
+		},
+		{
+			name: "Opting out translates the names",
+			body: `{"source_language":"en","target_language":"es","text":"Apple thanks Lucky Star.","translate_entities":true}`,
+			entities: []comprehendTypes.Entity{
+				{Text: aws.String("Apple"), Type: comprehendTypes.EntityTypeOrganization, Score: aws.Float32(0.9)},
+			},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Manzana agradece a Estrella de la Suerte. "}`,
+			},
+			expectedKey: getCacheKey("en", "es", "Apple thanks Lucky Star."),
+		},
+		{
+			name: "Text without names",
+			body: `{"source_language":"en","target_language":"es","text":"Nothing to protect here."}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Nada que proteger aquí. "}`,
+			},
+			expectedKey: getCacheKey("en", "es", "Nothing to protect here."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cached []string
+			h := newMockHandler(translations)
+			h.dynamoClient.(*MockDynamoDBClient).PutItemFunc = func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				item, _ := cacheItemFromAttributes(params.Item)
+				cached = append(cached, item.Hash)
+				return &dynamodb.PutItemOutput{}, nil
+			}
+			h.comprehendClient = &MockComprehendClient{
+				DetectEntitiesFunc: func(ctx context.Context, params *comprehend.DetectEntitiesInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectEntitiesOutput, error) {
+					return &comprehend.DetectEntitiesOutput{Entities: tt.entities}, nil
+				},
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+			if !slices.Contains(cached, tt.expectedKey) {
+				t.Errorf("PutItem() hashes = %q, expected %q", cached, tt.expectedKey)
+			}
+		})
+	}
+}
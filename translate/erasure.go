@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	// erasureResource is the API Gateway resource of erasure requests
+	erasureResource = "/cache/erasure"
+
+	// sourceHashIndex and translatedHashIndex are the table indexes of the content hashes
+	sourceHashIndex     = "SourceHashIndex"
+	translatedHashIndex = "TranslatedHashIndex"
+
+	// maxErasureContents caps the texts and hashes of an erasure request
+	maxErasureContents = 100
+)
+
+// ErasureRequest lists the content erased from the cache, either as texts or as the hex
+// SHA-256 hashes of the texts so they don't need to be sent again
+type ErasureRequest struct {
+	Texts  []string `json:"texts"`
+	Hashes []string `json:"hashes"`
+}
+
+// ErasureReport lists the cache items deleted for each content hash of an erasure request
+type ErasureReport struct {
+	// Results are the deleted items of each content hash, in the order of the request
+	Results []ErasureResult `json:"results"`
+	// Deleted is the number of cache items deleted
+	Deleted int `json:"deleted"`
+	// Error is set when the erasure failed part way, the results then list the items deleted
+	// before the failure
+	Error string `json:"error,omitempty"`
+}
+
+// ErasureResult lists the cache items holding a content as their source or translated text
+type ErasureResult struct {
+	ContentHash string   `json:"content_hash"`
+	Deleted     []string `json:"deleted"`
+}
+
+// withContentHashes sets the content hashes of the texts of the item
+func withContentHashes(item CacheItem) CacheItem {
+	if item.SourceText != "" {
+		item.SourceHash = getHashFromText(item.SourceText)
+	}
+	if item.TranslatedText != "" {
+		item.TranslatedHash = getHashFromText(item.TranslatedText)
+	}
+	return item
+}
+
+// handleErasureRequest deletes the cache items whose source or translated text is one of the
+// contents of the request, along with their overflowed text, and the cached responses holding
+// one of them as a sentence. Items cached before the content hashes were introduced aren't
+// indexed and can't be found.
+func (h *handler) handleErasureRequest(ctx context.Context, event events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	var request ErasureRequest
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Invalid request format",
+		}
+	}
+
+	contentHashes := append([]string{}, request.Hashes...)
+	for _, text := range request.Texts {
+		contentHashes = append(contentHashes, getHashFromText(text))
+	}
+	if len(contentHashes) == 0 || len(contentHashes) > maxErasureContents {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       fmt.Sprintf("Between 1 and %d texts and hashes are required", maxErasureContents),
+		}
+	}
+
+	report := ErasureReport{Results: make([]ErasureResult, len(contentHashes))}
+	for i, contentHash := range contentHashes {
+		report.Results[i] = ErasureResult{ContentHash: contentHash, Deleted: []string{}}
+	}
+
+	statusCode := http.StatusOK
+	if err := h.eraseContents(ctx, contentHashes, &report); err != nil {
+		// The items deleted before the failure are reported so the caller knows what is left
+		log.Printf("Error erasing contents %v: %v", contentHashes, err)
+		statusCode = http.StatusInternalServerError
+		report.Error = "Error erasing content"
+	}
+
+	// The content hashes are logged as the record of the erasure, never the text
+	log.Printf("Audit: erased %d cache items for contents %v", report.Deleted, contentHashes)
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error marshalling response",
+		}
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Body:       string(body),
+	}
+}
+
+// eraseContents deletes the items of each content, recording the deleted items in the report
+// as it goes. A failure stops the erasure, the items left unchecked are then still cached.
+func (h *handler) eraseContents(ctx context.Context, contentHashes []string, report *ErasureReport) error {
+	for i, contentHash := range contentHashes {
+		deleted, err := h.eraseContent(ctx, contentHash)
+		report.Deleted += len(deleted)
+		report.Results[i].Deleted = deleted
+		if err != nil {
+			return fmt.Errorf("content %s: %w", contentHash, err)
+		}
+	}
+	return nil
+}
+
+// eraseContent deletes the items holding the content as their source or translated text,
+// and the cached responses referenced from it, returning the hashes of the deleted items
+func (h *handler) eraseContent(ctx context.Context, contentHash string) ([]string, error) {
+	deleted := []string{}
+	for _, index := range []struct{ name, attribute string }{
+		{sourceHashIndex, "source_hash"},
+		{translatedHashIndex, "translated_hash"},
+	} {
+		paginator := dynamodb.NewQueryPaginator(h.dynamoClient, &dynamodb.QueryInput{
+			TableName:                 aws.String(translateTableName),
+			IndexName:                 aws.String(index.name),
+			KeyConditionExpression:    aws.String("#content = :hash"),
+			ExpressionAttributeNames:  map[string]string{"#content": index.attribute},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":hash": &types.AttributeValueMemberS{Value: contentHash}},
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return deleted, fmt.Errorf("failed to query %s: %w", index.name, err)
+			}
+
+			for _, item := range page.Items {
+				hash, ok := item["hash"].(*types.AttributeValueMemberS)
+				if !ok || slices.Contains(deleted, hash.Value) {
+					continue
+				}
+				if responseHash, ok := referencedResponse(hash.Value); ok && !slices.Contains(deleted, responseHash) {
+					found, err := h.eraseResponse(ctx, responseHash)
+					if err != nil {
+						return deleted, err
+					}
+					if found {
+						deleted = append(deleted, responseHash)
+					}
+				}
+				if err := h.deleteCacheItem(ctx, item); err != nil {
+					return deleted, err
+				}
+				deleted = append(deleted, hash.Value)
+			}
+		}
+	}
+
+	return deleted, nil
+}
+
+// eraseResponse deletes the cached response of the hash, reporting whether it was found. The
+// index projection doesn't carry the overflow key of the response, which is read first. An
+// expired response may already be gone.
+func (h *handler) eraseResponse(ctx context.Context, hash string) (bool, error) {
+	out, err := h.dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(translateTableName),
+		Key:       map[string]types.AttributeValue{"hash": &types.AttributeValueMemberS{Value: hash}},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to read cached response %s: %w", hash, err)
+	}
+	if out.Item == nil {
+		return false, nil
+	}
+	return true, h.deleteCacheItem(ctx, out.Item)
+}
+
+// deleteCacheItem deletes the overflowed text of the item, then the item itself
+func (h *handler) deleteCacheItem(ctx context.Context, item map[string]types.AttributeValue) error {
+	if overflowKey, ok := item["overflow_key"].(*types.AttributeValueMemberS); ok && cacheOverflowBucket != "" {
+		_, err := h.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(cacheOverflowBucket),
+			Key:    aws.String(overflowKey.Value),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete overflow text %s: %w", overflowKey.Value, err)
+		}
+	}
+
+	_, err := h.dynamoClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(translateTableName),
+		Key:       map[string]types.AttributeValue{"hash": item["hash"]},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete cache item: %w", err)
+	}
+	return nil
+}
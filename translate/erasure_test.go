@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestWithContentHashes(t *testing.T) {
+	tests := []struct {
+		name     string
+		item     CacheItem
+		expected CacheItem
+	}{
+		{
+			name:     "Translation",
+			item:     CacheItem{SourceText: "Hello", TranslatedText: "Hola"},
+			expected: CacheItem{SourceText: "Hello", TranslatedText: "Hola", SourceHash: getHashFromText("Hello"), TranslatedHash: getHashFromText("Hola")},
+		},
+		{
+			name:     "Failure has no translated text",
+			item:     CacheItem{SourceText: "Hello", Failure: "DetectedLanguageLowConfidenceException"},
+			expected: CacheItem{SourceText: "Hello", Failure: "DetectedLanguageLowConfidenceException", SourceHash: getHashFromText("Hello")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withContentHashes(tt.item); got != tt.expected {
+				t.Errorf("withContentHashes() = %+v, expected %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandleErasureRequest(t *testing.T) {
+	original := cacheOverflowBucket
+	cacheOverflowBucket = "overflow-bucket"
+	defer func() { cacheOverflowBucket = original }()
+
+	item := func(hash, overflowKey string) map[string]dynamoTypes.AttributeValue {
+		item := map[string]dynamoTypes.AttributeValue{"hash": &dynamoTypes.AttributeValueMemberS{Value: hash}}
+		if overflowKey != "" {
+			item["overflow_key"] = &dynamoTypes.AttributeValueMemberS{Value: overflowKey}
+		}
+		return item
+	}
+	reference := func(side, text, responseHash string) string {
+		return responseReferencePrefix + side + "-" + getHashFromText(text) + "/" + responseHash
+	}
+	// indexes are the items of each index by content hash
+	indexes := map[string]map[string][]map[string]dynamoTypes.AttributeValue{
+		sourceHashIndex: {
+			getHashFromText("Jane Doe"): {item("en-es", ""), item("en-fr", cacheOverflowPrefix+"en-fr"), item(reference("source", "Jane Doe", "response-1"), "")},
+		},
+		translatedHashIndex: {
+			getHashFromText("Jane Doe"): {item("es-en", ""), item("en-es", "")},
+			getHashFromText("Llámame."): {item(reference("translated", "Llámame.", "response-2"), ""), item(reference("translated", "Llámame.", "response-expired"), "")},
+		},
+	}
+	// responses are the cached responses, read by their hash
+	responses := map[string]map[string]dynamoTypes.AttributeValue{
+		"response-1": item("response-1", cacheOverflowPrefix+"response-1"),
+		"response-2": item("response-2", ""),
+	}
+
+	tests := []struct {
+		name             string
+		body             string
+		queryError       error
+		getError         error
+		expectedDeleted  []string
+		expectedObjects  []string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name:            "Items of a text are deleted",
+			body:            `{"texts":["Jane Doe"]}`,
+			expectedDeleted: []string{"en-es", "en-fr", "response-1", reference("source", "Jane Doe", "response-1"), "es-en"},
+			expectedObjects: []string{cacheOverflowPrefix + "en-fr", cacheOverflowPrefix + "response-1"},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body: fmt.Sprintf(`{"results":[{"content_hash":"%s","deleted":["en-es","en-fr","response-1","%s","es-en"]}],"deleted":5}`,
+					getHashFromText("Jane Doe"), reference("source", "Jane Doe", "response-1")),
+			},
+		},
+		{
+			name:            "Responses holding the translated sentence of a hash are deleted",
+			body:            fmt.Sprintf(`{"hashes":["%s"]}`, getHashFromText("Llámame.")),
+			expectedDeleted: []string{"response-2", reference("translated", "Llámame.", "response-2"), reference("translated", "Llámame.", "response-expired")},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body: fmt.Sprintf(`{"results":[{"content_hash":"%s","deleted":["response-2","%s","%s"]}],"deleted":3}`,
+					getHashFromText("Llámame."), reference("translated", "Llámame.", "response-2"), reference("translated", "Llámame.", "response-expired")),
+			},
+		},
+		{
+			name: "Unknown hash",
+			body: `{"hashes":["unknown"]}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"results":[{"content_hash":"unknown","deleted":[]}],"deleted":0}`,
+			},
+		},
+		{
+			name: "Nothing to erase",
+			body: `{"texts":[]}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "Between 1 and 100 texts and hashes are required",
+			},
+		},
+		{
+			name: "Invalid request",
+			body: `{"texts":"Jane Doe"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "Invalid request format",
+			},
+		},
+		{
+			name:       "Query error",
+			body:       `{"texts":["Jane Doe"]}`,
+			queryError: fmt.Errorf("mock error"),
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       fmt.Sprintf(`{"results":[{"content_hash":"%s","deleted":[]}],"deleted":0,"error":"Error erasing content"}`, getHashFromText("Jane Doe")),
+			},
+		},
+		{
+			name:            "Unread response reports the items already deleted",
+			body:            `{"texts":["Jane Doe"]}`,
+			getError:        fmt.Errorf("mock error"),
+			expectedDeleted: []string{"en-es", "en-fr"},
+			expectedObjects: []string{cacheOverflowPrefix + "en-fr"},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       fmt.Sprintf(`{"results":[{"content_hash":"%s","deleted":["en-es","en-fr"]}],"deleted":2,"error":"Error erasing content"}`, getHashFromText("Jane Doe")),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted, objects []string
+
+			h := newMockHandler(nil)
+			h.dynamoClient = &MockDynamoDBClient{
+				QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+					if tt.queryError != nil {
+						return nil, tt.queryError
+					}
+					contentHash := params.ExpressionAttributeValues[":hash"].(*dynamoTypes.AttributeValueMemberS).Value
+					return &dynamodb.QueryOutput{Items: indexes[aws.ToString(params.IndexName)][contentHash]}, nil
+				},
+				GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					if tt.getError != nil {
+						return nil, tt.getError
+					}
+					hash := params.Key["hash"].(*dynamoTypes.AttributeValueMemberS).Value
+					return &dynamodb.GetItemOutput{Item: responses[hash]}, nil
+				},
+				DeleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+					deleted = append(deleted, params.Key["hash"].(*dynamoTypes.AttributeValueMemberS).Value)
+					return &dynamodb.DeleteItemOutput{}, nil
+				},
+			}
+			h.s3Client = &MockS3Client{
+				DeleteObjectFunc: func(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+					objects = append(objects, aws.ToString(params.Key))
+					return &s3.DeleteObjectOutput{}, nil
+				},
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+				Resource:   erasureResource,
+				HTTPMethod: http.MethodPost,
+				Body:       tt.body,
+			})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %v, expected %v", got, tt.expectedResponse)
+			}
+			if !slices.Equal(deleted, tt.expectedDeleted) {
+				t.Errorf("DeleteItem() = %v, expected %v", deleted, tt.expectedDeleted)
+			}
+			if !slices.Equal(objects, tt.expectedObjects) {
+				t.Errorf("DeleteObject() = %v, expected %v", objects, tt.expectedObjects)
+			}
+		})
+	}
+}
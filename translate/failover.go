@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// failoverTranslateRegion is the region AWS Translate fails over to while the primary region
+// is unavailable, empty to disable failover
+var failoverTranslateRegion string
+
+// failoverTranslateClient translates in the primary region, failing over to the secondary
+// region while the circuit breaker of the primary is open. The breaker tracks the health of
+// the primary: it opens on sustained errors and lets probes through once it half-opens, the
+// calls it rejects in the meantime go to the secondary.
+type failoverTranslateClient struct {
+	primary         TranslateClient
+	secondary       TranslateClient
+	secondaryRegion string
+}
+
+func (c *failoverTranslateClient) TranslateText(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+	output, err := c.primary.TranslateText(ctx, params, optFns...)
+	if !c.failover(ctx, err) {
+		return output, err
+	}
+	return c.secondary.TranslateText(ctx, params, optFns...)
+}
+
+func (c *failoverTranslateClient) ListLanguages(ctx context.Context, params *translate.ListLanguagesInput, optFns ...func(*translate.Options)) (*translate.ListLanguagesOutput, error) {
+	output, err := c.primary.ListLanguages(ctx, params, optFns...)
+	if !c.failover(ctx, err) {
+		return output, err
+	}
+	return c.secondary.ListLanguages(ctx, params, optFns...)
+}
+
+// failover reports whether the call failed because the primary is unavailable, recording
+// the secondary as the region of the translation when it is
+func (c *failoverTranslateClient) failover(ctx context.Context, err error) bool {
+	var unavailable *dependencyUnavailable
+	if !errors.As(err, &unavailable) {
+		return false
+	}
+
+	providerFailovers.Add(ctx, 1, metric.WithAttributes(attribute.String("region", c.secondaryRegion)))
+	if translateRegion, ok := ctx.Value(translateRegionKey{}).(*string); ok {
+		*translateRegion = c.secondaryRegion
+	}
+	return true
+}
+
+type translateRegionKey struct{}
+
+// withTranslateRegion returns a context recording the region of the AWS Translate endpoint
+// that translated a segment, the primary region unless the translation failed over
+func withTranslateRegion(ctx context.Context, primaryRegion string) (context.Context, *string) {
+	translateRegion := &primaryRegion
+	return context.WithValue(ctx, translateRegionKey{}, translateRegion), translateRegion
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestFailoverTranslateClient(t *testing.T) {
+	unavailable := &dependencyUnavailable{Dependency: "translate", RetryAfter: 30 * time.Second}
+
+	tests := []struct {
+		name           string
+		primaryError   error
+		secondaryError error
+		expectedText   string
+		expectedRegion string
+		wantErr        bool
+	}{
+		{
+			name:           "Healthy primary",
+			expectedText:   "Hola",
+			expectedRegion: region,
+		},
+		{
+			name:           "Open primary breaker fails over",
+			primaryError:   unavailable,
+			expectedText:   "Hola (secondary)",
+			expectedRegion: "us-west-2",
+		},
+		{
+			name:         "Primary errors don't fail over",
+			primaryError: fmt.Errorf("mock error"),
+			wantErr:      true,
+		},
+		{
+			name:           "Both regions unavailable",
+			primaryError:   unavailable,
+			secondaryError: unavailable,
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := func(text string, err error) *MockTranslateClient {
+				return &MockTranslateClient{
+					TranslateTextFunc: func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+						if err != nil {
+							return nil, err
+						}
+						return &translate.TranslateTextOutput{TranslatedText: aws.String(text)}, nil
+					},
+				}
+			}
+
+			var stored CacheItem
+			h := newMockHandler(nil)
+			h.translateClient = &failoverTranslateClient{
+				primary:         client("Hola", tt.primaryError),
+				secondary:       client("Hola (secondary)", tt.secondaryError),
+				secondaryRegion: "us-west-2",
+			}
+			h.dynamoClient.(*MockDynamoDBClient).PutItemFunc = func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				if params.Item["hash"].(*dynamoTypes.AttributeValueMemberS).Value != supportedLanguagesHash {
+					stored, _ = cacheItemFromAttributes(params.Item)
+				}
+				return &dynamodb.PutItemOutput{}, nil
+			}
+
+			got, err := h.translateToken(context.Background(), "en", "es", "Hello")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("translateToken() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got != tt.expectedText {
+				t.Errorf("translateToken() = %q, expected %q", got, tt.expectedText)
+			}
+			if stored.TranslateRegion != tt.expectedRegion {
+				t.Errorf("PutItem() translate_region = %q, expected %q", stored.TranslateRegion, tt.expectedRegion)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxFallbackLength is the number of characters of the longest string translated by the
+// phrase table, only short UI strings such as button labels are
+const maxFallbackLength = 40
+
+var (
+	// offlineFallback translates short strings with the phrase table while the providers are
+	// unreachable, rather than leaving them untranslated or failing the request
+	offlineFallback bool
+
+	//go:embed data/fallback.json
+	fallbackData []byte
+	// fallbackPhrases are the phrase tables of each language pair, keyed by the lowercase
+	// source phrase
+	fallbackPhrases = parseFallbackPhrases(fallbackData)
+)
+
+// parseFallbackPhrases parses the embedded phrase tables, which are checked by the tests
+func parseFallbackPhrases(data []byte) map[string]map[string]string {
+	var phrases map[string]map[string]string
+	if err := json.Unmarshal(data, &phrases); err != nil {
+		panic(fmt.Sprintf("invalid fallback phrases, %v", err))
+	}
+	return phrases
+}
+
+// serveFallback returns the phrase table translation of a short string when the provider is
+// unreachable or disabled by the degraded mode, err being the provider error if it was
+// called. Like untranslated text, the fallback is only served to requests that can be
+// degraded, and is marked as low quality.
+func serveFallback(ctx context.Context, sourceLanguage, targetLanguage, text string, err error) (string, bool) {
+	d := degradationFromContext(ctx)
+	if d == nil || !offlineFallback {
+		return "", false
+	}
+	if degradedMode != degradedModeForce && !providerUnreachable(err) {
+		return "", false
+	}
+
+	translated, ok := fallbackTranslation(sourceLanguage, targetLanguage, text)
+	if !ok {
+		return "", false
+	}
+
+	d.lowQuality.Store(true)
+	return translated, true
+}
+
+// providerUnreachable reports whether the provider error means it couldn't be reached, as
+// opposed to failing to translate the text
+func providerUnreachable(err error) bool {
+	var (
+		unavailable *dependencyUnavailable
+		netErr      net.Error
+	)
+	return errors.As(err, &unavailable) || errors.As(err, &netErr)
+}
+
+// fallbackTranslation looks the text up in the phrase table of the language pair, ignoring
+// its case and a trailing period, colon or ellipsis which are carried over to the translation
+func fallbackTranslation(sourceLanguage, targetLanguage, text string) (string, bool) {
+	phrases, ok := fallbackPhrases[languagePair(sourceLanguage, targetLanguage)]
+	if !ok {
+		return "", false
+	}
+
+	phrase := strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(phrase) > maxFallbackLength {
+		return "", false
+	}
+
+	var suffix string
+	for _, punctuation := range []string{"...", "…", ":", "."} {
+		if trimmed, found := strings.CutSuffix(phrase, punctuation); found {
+			phrase, suffix = trimmed, punctuation
+			break
+		}
+	}
+
+	translated, ok := phrases[strings.ToLower(phrase)]
+	if !ok {
+		return "", false
+	}
+	return matchCase(phrase, translated) + suffix, true
+}
+
+// matchCase applies the case of the source phrase to its translation, which the phrase
+// table holds capitalized
+func matchCase(phrase, translated string) string {
+	switch {
+	case strings.ToUpper(translated) == translated:
+		// Acronyms such as OK keep their case
+		return translated
+	case strings.ToUpper(phrase) == phrase && strings.ToLower(phrase) != phrase:
+		return strings.ToUpper(translated)
+	case strings.ToLower(phrase) == phrase:
+		first, size := utf8.DecodeRuneInString(translated)
+		return string(unicode.ToLower(first)) + translated[size:]
+	default:
+		return translated
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestFallbackPhrases(t *testing.T) {
+	if len(fallbackPhrases) == 0 {
+		t.Fatalf("fallbackPhrases is empty")
+	}
+	for pair, phrases := range fallbackPhrases {
+		if source, target, found := strings.Cut(pair, ":"); !found || source == "" || target == "" {
+			t.Errorf("fallbackPhrases pair %q is not formatted as source:target", pair)
+		}
+		for phrase, translated := range phrases {
+			if phrase != strings.ToLower(phrase) || len(phrase) > maxFallbackLength || translated == "" {
+				t.Errorf("fallbackPhrases[%q][%q] = %q, expected a short lowercase phrase and a translation", pair, phrase, translated)
+			}
+		}
+	}
+}
+
+func TestFallbackTranslation(t *testing.T) {
+	tests := []struct {
+		name           string
+		targetLanguage string
+		text           string
+		expected       string
+		expectedOK     bool
+	}{
+		{name: "Phrase", targetLanguage: "es", text: "Save", expected: "Guardar", expectedOK: true},
+		{name: "Lowercase phrase", targetLanguage: "fr", text: "sign in", expected: "se connecter", expectedOK: true},
+		{name: "Uppercase phrase", targetLanguage: "de", text: "CANCEL", expected: "ABBRECHEN", expectedOK: true},
+		{name: "Acronym keeps its case", targetLanguage: "fr", text: "ok", expected: "OK", expectedOK: true},
+		{name: "Trailing punctuation", targetLanguage: "es", text: " Loading... ", expected: "Cargando...", expectedOK: true},
+		{name: "Unknown phrase", targetLanguage: "es", text: "Save as draft", expectedOK: false},
+		{name: "Unknown language pair", targetLanguage: "ja", text: "Save", expectedOK: false},
+		{name: "Long text", targetLanguage: "es", text: strings.Repeat("save ", 10), expectedOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := fallbackTranslation("en", tt.targetLanguage, tt.text)
+			if got != tt.expected || ok != tt.expectedOK {
+				t.Errorf("fallbackTranslation() = %q, %v, expected %q, %v", got, ok, tt.expected, tt.expectedOK)
+			}
+		})
+	}
+}
+
+func TestHandleFallback(t *testing.T) {
+	unavailable := &dependencyUnavailable{Dependency: "translate", RetryAfter: 30 * time.Second}
+	unreachable := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name             string
+		mode             string
+		disabled         bool
+		text             string
+		translateError   error
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name:           "Open breaker translates short strings",
+			text:           "Save",
+			translateError: unavailable,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Guardar ","low_quality":true}`,
+			},
+		},
+		{
+			name:           "Unreachable provider translates short strings",
+			text:           "Save",
+			translateError: unreachable,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Guardar ","low_quality":true}`,
+			},
+		},
+		{
+			name:           "Other sentences are degraded in auto mode",
+			mode:           degradedModeAuto,
+			text:           "Cancel. How are you?",
+			translateError: unavailable,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Cancelar. How are you? ","degraded":true,"low_quality":true}`,
+			},
+		},
+		{
+			name:           "Other sentences fail without degraded mode",
+			text:           "Cancel. How are you?",
+			translateError: unavailable,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       "Service temporarily unavailable",
+			},
+		},
+		{
+			name: "Forced mode translates short strings",
+			mode: degradedModeForce,
+			text: "Save",
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Guardar ","low_quality":true}`,
+			},
+		},
+		{
+			name:           "Disabled fallback",
+			mode:           degradedModeAuto,
+			disabled:       true,
+			text:           "Save",
+			translateError: unavailable,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Save ","degraded":true}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalMode, originalFallback := degradedMode, offlineFallback
+			degradedMode, offlineFallback = tt.mode, !tt.disabled
+			defer func() { degradedMode, offlineFallback = originalMode, originalFallback }()
+
+			h := newMockHandler(nil)
+			h.translateClient.(*MockTranslateClient).TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				if tt.mode == degradedModeForce {
+					t.Errorf("TranslateText() called in forced degraded mode")
+				}
+				return nil, tt.translateError
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+				Body: `{"source_language":"en","target_language":"es","text":"` + tt.text + `"}`,
+			})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %v, expected %v", got, tt.expectedResponse)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// feedbackResource and feedbackStatsResource are the API Gateway resources rating cached
+	// translations and reporting the ratings by language pair
+	feedbackResource      = "/feedback"
+	feedbackStatsResource = "/feedback/stats"
+
+	// minRating and maxRating bound the ratings, lowRating is the highest rating counted as a
+	// bad translation
+	minRating = 1
+	maxRating = 5
+	lowRating = 2
+
+	// maxCorrectionLength caps the corrections of the feedback, in characters
+	maxCorrectionLength = 5000
+
+	// feedbackStatsID is the sort key of the item aggregating the ratings of a language pair
+	feedbackStatsID = "stats"
+)
+
+// feedbackTableName is the table of the ratings, keyed by language pair and rating id, with
+// an item aggregating the ratings of each pair. Feedback is disabled without it.
+var feedbackTableName string
+
+// FeedbackRequest rates the cached translation of a segment, identified by its hash or by its
+// languages and source text
+type FeedbackRequest struct {
+	// Hash is the cache key of the translation
+	Hash           string `json:"hash,omitempty"`
+	SourceLanguage string `json:"source_language,omitempty"`
+	TargetLanguage string `json:"target_language,omitempty"`
+	SourceText     string `json:"source_text,omitempty"`
+	// Rating is the quality of the translation, from 1 to 5
+	Rating int `json:"rating"`
+	// Correction is the translation the user suggests instead
+	Correction string `json:"correction,omitempty"`
+}
+
+// FeedbackResponse acknowledges a rating
+type FeedbackResponse struct {
+	Hash           string `json:"hash"`
+	SourceLanguage string `json:"source_language"`
+	TargetLanguage string `json:"target_language"`
+	Rating         int    `json:"rating"`
+}
+
+// FeedbackStats are the aggregate ratings of the translations of language pairs
+type FeedbackStats struct {
+	Pairs []PairQuality `json:"pairs"`
+}
+
+// PairQuality aggregates the ratings of the translations of a language pair
+type PairQuality struct {
+	SourceLanguage string `json:"source_language"`
+	TargetLanguage string `json:"target_language"`
+	Ratings        int64  `json:"ratings"`
+	// AverageRating is the mean of the ratings, the quality score of the pair
+	AverageRating float64 `json:"average_rating"`
+	// LowRatings counts the ratings of 2 or less
+	LowRatings  int64 `json:"low_ratings"`
+	Corrections int64 `json:"corrections"`
+}
+
+// handleFeedbackRequest serves the feedback resources
+func (h *handler) handleFeedbackRequest(ctx context.Context, event events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	if feedbackTableName == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotImplemented,
+			Body:       "Feedback not configured",
+		}
+	}
+
+	if event.Resource == feedbackStatsResource {
+		return h.feedbackStats(ctx, event.QueryStringParameters["source_language"], event.QueryStringParameters["target_language"])
+	}
+	return h.rateTranslation(ctx, event.Body)
+}
+
+// rateTranslation stores the rating of a cached translation, counting it in the aggregate of
+// its language pair and on the cache item itself
+func (h *handler) rateTranslation(ctx context.Context, body string) events.APIGatewayProxyResponse {
+	var request FeedbackRequest
+	if err := json.Unmarshal([]byte(body), &request); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Invalid request format",
+		}
+	}
+
+	errs := &validationError{}
+	if request.Hash == "" {
+		validateLanguageCode(errs, "source_language", request.SourceLanguage, false)
+		validateLanguageCode(errs, "target_language", request.TargetLanguage, false)
+		if request.SourceText == "" {
+			errs.add("source_text", "hash or source_text is required")
+		}
+	}
+	if request.Rating < minRating || request.Rating > maxRating {
+		errs.add("rating", "rating must be between %d and %d", minRating, maxRating)
+	}
+	if utf8.RuneCountInString(request.Correction) > maxCorrectionLength {
+		errs.add("correction", "correction must not exceed %d characters", maxCorrectionLength)
+	}
+	if len(errs.Fields) > 0 {
+		return validationErrorResponse(errs)
+	}
+
+	hash := request.Hash
+	if hash == "" {
+		hash = getCacheKey(request.SourceLanguage, request.TargetLanguage, request.SourceText)
+	}
+	item, found, err := getCacheItem(ctx, h.dynamoClient, translateTableName, hash)
+	if err != nil {
+		return registryErrorResponse("Error reading translation", err)
+	}
+	if !found || item.Failure != "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotFound,
+			Body:       "Translation not found",
+		}
+	}
+	if response, ok := checkEntitlement(ctx, item.SourceLanguage, item.TargetLanguage); !ok {
+		return response
+	}
+
+	if err := h.storeFeedback(ctx, item, request.Rating, request.Correction); err != nil {
+		return registryErrorResponse("Error storing feedback", err)
+	}
+
+	return newRegistryResponse(http.StatusCreated, FeedbackResponse{
+		Hash:           item.Hash,
+		SourceLanguage: item.SourceLanguage,
+		TargetLanguage: item.TargetLanguage,
+		Rating:         request.Rating,
+	})
+}
+
+// storeFeedback writes the rating and adds it to the aggregate of the pair and to the cache
+// item. A cache item deleted since it was read isn't recreated.
+func (h *handler) storeFeedback(ctx context.Context, item CacheItem, rating int, correction string) error {
+	pair := languagePair(item.SourceLanguage, item.TargetLanguage)
+	id, err := newRandomID()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	attributes := map[string]types.AttributeValue{
+		"pair":       &types.AttributeValueMemberS{Value: pair},
+		"id":         &types.AttributeValueMemberS{Value: fmt.Sprintf("%d#%s", now.UnixNano(), id)},
+		"hash":       &types.AttributeValueMemberS{Value: item.Hash},
+		"rating":     &types.AttributeValueMemberN{Value: strconv.Itoa(rating)},
+		"created_at": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+	}
+	if correction != "" {
+		attributes["correction"] = &types.AttributeValueMemberS{Value: correction}
+	}
+	if _, err := h.dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(feedbackTableName),
+		Item:      attributes,
+	}); err != nil {
+		return err
+	}
+
+	counts := map[string]types.AttributeValue{
+		":one":        &types.AttributeValueMemberN{Value: "1"},
+		":rating":     &types.AttributeValueMemberN{Value: strconv.Itoa(rating)},
+		":low":        &types.AttributeValueMemberN{Value: "0"},
+		":correction": &types.AttributeValueMemberN{Value: "0"},
+		":source":     &types.AttributeValueMemberS{Value: item.SourceLanguage},
+		":target":     &types.AttributeValueMemberS{Value: item.TargetLanguage},
+	}
+	if rating <= lowRating {
+		counts[":low"] = &types.AttributeValueMemberN{Value: "1"}
+	}
+	if correction != "" {
+		counts[":correction"] = &types.AttributeValueMemberN{Value: "1"}
+	}
+	if _, err := h.dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(feedbackTableName),
+		Key: map[string]types.AttributeValue{
+			"pair": &types.AttributeValueMemberS{Value: pair},
+			"id":   &types.AttributeValueMemberS{Value: feedbackStatsID},
+		},
+		UpdateExpression:          aws.String("ADD ratings :one, rating_sum :rating, low_ratings :low, corrections :correction SET source_language = :source, target_language = :target"),
+		ExpressionAttributeValues: counts,
+	}); err != nil {
+		return err
+	}
+
+	_, err = h.dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(translateTableName),
+		Key: map[string]types.AttributeValue{
+			"hash": &types.AttributeValueMemberS{Value: item.Hash},
+		},
+		UpdateExpression:         aws.String("ADD ratings :one, rating_sum :rating"),
+		ConditionExpression:      aws.String("attribute_exists(#hash)"),
+		ExpressionAttributeNames: map[string]string{"#hash": "hash"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one":    &types.AttributeValueMemberN{Value: "1"},
+			":rating": &types.AttributeValueMemberN{Value: strconv.Itoa(rating)},
+		},
+	})
+	if errors.As(err, new(*types.ConditionalCheckFailedException)) {
+		return nil
+	}
+	return err
+}
+
+// feedbackStats returns the aggregate ratings of the language pair, or of every rated pair
+// the caller is entitled to when the languages are missing
+func (h *handler) feedbackStats(ctx context.Context, sourceLanguage, targetLanguage string) events.APIGatewayProxyResponse {
+	stats := FeedbackStats{Pairs: []PairQuality{}}
+
+	if sourceLanguage != "" || targetLanguage != "" {
+		errs := &validationError{}
+		validateLanguageCode(errs, "source_language", sourceLanguage, false)
+		validateLanguageCode(errs, "target_language", targetLanguage, false)
+		if len(errs.Fields) > 0 {
+			return validationErrorResponse(errs)
+		}
+		if response, ok := checkEntitlement(ctx, sourceLanguage, targetLanguage); !ok {
+			return response
+		}
+
+		out, err := h.dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(feedbackTableName),
+			Key: map[string]types.AttributeValue{
+				"pair": &types.AttributeValueMemberS{Value: languagePair(sourceLanguage, targetLanguage)},
+				"id":   &types.AttributeValueMemberS{Value: feedbackStatsID},
+			},
+		})
+		if err != nil {
+			return registryErrorResponse("Error reading feedback", err)
+		}
+		if quality, ok := pairQualityFromAttributes(out.Item); ok {
+			stats.Pairs = append(stats.Pairs, quality)
+		}
+		return newRegistryResponse(http.StatusOK, stats)
+	}
+
+	e, entitled := entitlementFromContext(ctx)
+	paginator := dynamodb.NewScanPaginator(h.dynamoClient, &dynamodb.ScanInput{
+		TableName:                 aws.String(feedbackTableName),
+		FilterExpression:          aws.String("id = :stats"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":stats": &types.AttributeValueMemberS{Value: feedbackStatsID}},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return registryErrorResponse("Error reading feedback", err)
+		}
+		for _, item := range page.Items {
+			quality, ok := pairQualityFromAttributes(item)
+			if ok && (!entitled || e.allows(quality.SourceLanguage, quality.TargetLanguage)) {
+				stats.Pairs = append(stats.Pairs, quality)
+			}
+		}
+	}
+	slices.SortFunc(stats.Pairs, func(a, b PairQuality) int {
+		return strings.Compare(languagePair(a.SourceLanguage, a.TargetLanguage), languagePair(b.SourceLanguage, b.TargetLanguage))
+	})
+
+	return newRegistryResponse(http.StatusOK, stats)
+}
+
+// pairQualityFromAttributes reads the aggregate item of a pair, malformed items are skipped
+func pairQualityFromAttributes(item map[string]types.AttributeValue) (PairQuality, bool) {
+	sourceLanguage, ok := item["source_language"].(*types.AttributeValueMemberS)
+	if !ok {
+		return PairQuality{}, false
+	}
+	targetLanguage, ok := item["target_language"].(*types.AttributeValueMemberS)
+	if !ok {
+		return PairQuality{}, false
+	}
+	ratings, _ := numberAttribute(item, "ratings")
+	if ratings == 0 {
+		return PairQuality{}, false
+	}
+	sum, _ := numberAttribute(item, "rating_sum")
+	low, _ := numberAttribute(item, "low_ratings")
+	corrections, _ := numberAttribute(item, "corrections")
+
+	return PairQuality{
+		SourceLanguage: sourceLanguage.Value,
+		TargetLanguage: targetLanguage.Value,
+		Ratings:        ratings,
+		AverageRating:  float64(sum) / float64(ratings),
+		LowRatings:     low,
+		Corrections:    corrections,
+	}, true
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestHandleFeedback(t *testing.T) {
+	previous := feedbackTableName
+	feedbackTableName = "feedback"
+	defer func() { feedbackTableName = previous }()
+
+	hash := getCacheKey("en", "es", "Hello")
+	cacheItem := map[string]dynamoTypes.AttributeValue{
+		"hash":            &dynamoTypes.AttributeValueMemberS{Value: hash},
+		"source_language": &dynamoTypes.AttributeValueMemberS{Value: "en"},
+		"target_language": &dynamoTypes.AttributeValueMemberS{Value: "es"},
+		"source_text":     &dynamoTypes.AttributeValueMemberS{Value: "Hello"},
+		"translated_text": &dynamoTypes.AttributeValueMemberS{Value: "Hola"},
+	}
+
+	tests := []struct {
+		name               string
+		body               string
+		expectedStatusCode int
+		expectedBody       string
+		expectedRatings    string
+	}{
+		{
+			name:               "Rating by hash",
+			body:               `{"hash":"` + hash + `","rating":4}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedBody:       `{"hash":"` + hash + `","source_language":"en","target_language":"es","rating":4}`,
+			expectedRatings:    `{"pairs":[{"source_language":"en","target_language":"es","ratings":1,"average_rating":4,"low_ratings":0,"corrections":0}]}`,
+		},
+		{
+			name:               "Low rating by source text with a correction",
+			body:               `{"source_language":"en","target_language":"es","source_text":"Hello","rating":1,"correction":"Buenas"}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedBody:       `{"hash":"` + hash + `","source_language":"en","target_language":"es","rating":1}`,
+			expectedRatings:    `{"pairs":[{"source_language":"en","target_language":"es","ratings":1,"average_rating":1,"low_ratings":1,"corrections":1}]}`,
+		},
+		{
+			name:               "Unknown translation",
+			body:               `{"hash":"unknown","rating":3}`,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       "Translation not found",
+		},
+		{
+			name:               "Rating out of range",
+			body:               `{"hash":"` + hash + `","rating":6}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error":"Invalid request","fields":[{"field":"rating","message":"rating must be between 1 and 5"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				stats        = map[string]dynamoTypes.AttributeValue{}
+				cacheRatings int
+			)
+			h := newMockHandler(nil)
+			h.dynamoClient = &MockDynamoDBClient{
+				GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					if *params.TableName == feedbackTableName {
+						return &dynamodb.GetItemOutput{Item: stats}, nil
+					}
+					if params.Key["hash"].(*dynamoTypes.AttributeValueMemberS).Value != hash {
+						return &dynamodb.GetItemOutput{}, nil
+					}
+					return &dynamodb.GetItemOutput{Item: cacheItem}, nil
+				},
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					return &dynamodb.PutItemOutput{}, nil
+				},
+				UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+					if *params.TableName != feedbackTableName {
+						cacheRatings++
+						return &dynamodb.UpdateItemOutput{}, nil
+					}
+					// The counters of the first rating of the pair
+					stats["source_language"] = params.ExpressionAttributeValues[":source"]
+					stats["target_language"] = params.ExpressionAttributeValues[":target"]
+					for attribute, value := range map[string]string{"ratings": ":one", "rating_sum": ":rating", "low_ratings": ":low", "corrections": ":correction"} {
+						stats[attribute] = params.ExpressionAttributeValues[value]
+					}
+					return &dynamodb.UpdateItemOutput{}, nil
+				},
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Resource: feedbackResource, HTTPMethod: http.MethodPost, Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedStatusCode || got.Body != tt.expectedBody {
+				t.Fatalf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedStatusCode, tt.expectedBody)
+			}
+			if tt.expectedRatings == "" {
+				return
+			}
+			if cacheRatings != 1 {
+				t.Errorf("UpdateItem() cache item updated %d times, expected once", cacheRatings)
+			}
+
+			got, err = h.handle(context.Background(), events.APIGatewayProxyRequest{
+				Resource:              feedbackStatsResource,
+				HTTPMethod:            http.MethodGet,
+				QueryStringParameters: map[string]string{"source_language": "en", "target_language": "es"},
+			})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != http.StatusOK || got.Body != tt.expectedRatings {
+				t.Errorf("handle() stats = %d %s, expected %d %s", got.StatusCode, got.Body, http.StatusOK, tt.expectedRatings)
+			}
+		})
+	}
+}
+
+func TestFeedbackStatsOfEveryPair(t *testing.T) {
+	previous := feedbackTableName
+	feedbackTableName = "feedback"
+	defer func() { feedbackTableName = previous }()
+
+	stats := func(source, target string, ratings, sum int) map[string]dynamoTypes.AttributeValue {
+		return map[string]dynamoTypes.AttributeValue{
+			"pair":            &dynamoTypes.AttributeValueMemberS{Value: languagePair(source, target)},
+			"id":              &dynamoTypes.AttributeValueMemberS{Value: feedbackStatsID},
+			"source_language": &dynamoTypes.AttributeValueMemberS{Value: source},
+			"target_language": &dynamoTypes.AttributeValueMemberS{Value: target},
+			"ratings":         &dynamoTypes.AttributeValueMemberN{Value: strconv.Itoa(ratings)},
+			"rating_sum":      &dynamoTypes.AttributeValueMemberN{Value: strconv.Itoa(sum)},
+		}
+	}
+
+	h := newMockHandler(nil)
+	h.dynamoClient.(*MockDynamoDBClient).ScanFunc = func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+		return &dynamodb.ScanOutput{Items: []map[string]dynamoTypes.AttributeValue{
+			stats("en", "fr", 4, 10),
+			stats("en", "de", 2, 9),
+		}}, nil
+	}
+
+	got := h.feedbackStats(context.Background(), "", "")
+	expected := `{"pairs":[{"source_language":"en","target_language":"de","ratings":2,"average_rating":4.5,"low_ratings":0,"corrections":0},` +
+		`{"source_language":"en","target_language":"fr","ratings":4,"average_rating":2.5,"low_ratings":0,"corrections":0}]}`
+	if got.StatusCode != http.StatusOK || got.Body != expected {
+		t.Errorf("feedbackStats() = %d %s, expected %d %s", got.StatusCode, got.Body, http.StatusOK, expected)
+	}
+
+	// A caller only sees the pairs it may translate
+	ctx := withEntitlement(context.Background(), entitlement{LanguagePairs: map[string]bool{"en:fr": true}})
+	got = h.feedbackStats(ctx, "", "")
+	expected = `{"pairs":[{"source_language":"en","target_language":"fr","ratings":4,"average_rating":2.5,"low_ratings":0,"corrections":0}]}`
+	if got.Body != expected {
+		t.Errorf("feedbackStats() = %s, expected %s", got.Body, expected)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// pageUserAgent is the user agent of the requests fetching web pages
+	pageUserAgent = "gotranslate"
+
+	// pageFetchTimeout bounds the fetch of a web page, sitemap or robots.txt
+	pageFetchTimeout = 10 * time.Second
+
+	// maxPageSize is the size in bytes of the largest web page or sitemap fetched, leaving
+	// room for the translation in a Lambda response
+	maxPageSize = 2 * 1024 * 1024
+
+	// maxPageRedirects is the number of redirects followed by a fetch
+	maxPageRedirects = 5
+)
+
+// errBlockedAddress is the error of a fetch connecting to an address that isn't public
+var errBlockedAddress = errors.New("address is not public")
+
+// sharedAddressSpace is the carrier-grade NAT range, which isn't reported as private
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// newPageClient returns the HTTP client fetching web pages on behalf of callers. It only
+// connects to public addresses, checking the address actually dialed so neither a redirect
+// nor a DNS answer changing between lookups reaches the instance metadata or a VPC address.
+// The client is built on a copy of the transport, the default one when nil.
+func newPageClient(base *http.Transport) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: pageFetchTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicAddress(addrPort.Addr()) {
+				return fmt.Errorf("%s: %w", addrPort.Addr(), errBlockedAddress)
+			}
+			return nil
+		},
+	}
+
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   pageFetchTimeout,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) >= maxPageRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxPageRedirects)
+			}
+			if request.URL.Scheme != "http" && request.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to %s is not http or https", request.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// isPublicAddress reports whether the address is reachable on the internet, rather than a
+// loopback, private, link-local or otherwise reserved address
+func isPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!addr.IsLoopback() &&
+		!addr.IsLinkLocalUnicast() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// fetchStatusError is the error of a fetch answered with another status than 200 OK
+type fetchStatusError struct {
+	StatusCode int
+}
+
+func (e *fetchStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.StatusCode)
+}
+
+// fetchPage returns the body and content type of a URL, failing unless it responds with
+// 200 OK within maxPageSize bytes
+func fetchPage(ctx context.Context, client *http.Client, target string) ([]byte, string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("User-Agent", pageUserAgent)
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch %s: %w", target, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, "", &fetchStatusError{StatusCode: response.StatusCode}
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxPageSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", target, err)
+	}
+	if len(body) > maxPageSize {
+		return nil, "", fmt.Errorf("response is larger than %d bytes", maxPageSize)
+	}
+	return body, response.Header.Get("Content-Type"), nil
+}
+
+// decodeHTMLPage converts an HTML page to UTF-8 from the charset of its content type, byte
+// order mark or meta tag
+func decodeHTMLPage(body []byte, contentType string) (string, error) {
+	reader, err := charset.NewReader(bytes.NewReader(body), contentType)
+	if err != nil {
+		return "", fmt.Errorf("unsupported charset: %w", err)
+	}
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode page: %w", err)
+	}
+	return string(decoded), nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		address  string
+		expected bool
+	}{
+		{address: "93.184.216.34", expected: true},
+		{address: "2606:2800:220:1::", expected: true},
+		{address: "127.0.0.1", expected: false},
+		{address: "10.0.1.20", expected: false},
+		{address: "172.16.0.1", expected: false},
+		{address: "192.168.1.1", expected: false},
+		{address: "169.254.169.254", expected: false},
+		{address: "100.64.0.1", expected: false},
+		{address: "0.0.0.0", expected: false},
+		{address: "255.255.255.255", expected: false},
+		{address: "::1", expected: false},
+		{address: "fd00:ec2::254", expected: false},
+		{address: "fe80::1", expected: false},
+		{address: "::ffff:127.0.0.1", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			if got := isPublicAddress(netip.MustParseAddr(tt.address)); got != tt.expected {
+				t.Errorf("isPublicAddress(%s) = %v, expected %v", tt.address, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFetchPageBlocksPrivateAddresses(t *testing.T) {
+	var fetched bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched = true
+	}))
+	defer server.Close()
+
+	_, _, err := fetchPage(context.Background(), newPageClient(nil), server.URL)
+	if !errors.Is(err, errBlockedAddress) {
+		t.Errorf("fetchPage() error = %v, expected %v", err, errBlockedAddress)
+	}
+	if fetched {
+		t.Errorf("fetchPage() connected to the loopback server")
+	}
+}
+
+func TestFetchPage(t *testing.T) {
+	tests := []struct {
+		name        string
+		statusCode  int
+		body        string
+		expectedErr string
+	}{
+		{name: "Page", statusCode: http.StatusOK, body: "<p>Hello.</p>"},
+		{name: "Not found", statusCode: http.StatusNotFound, expectedErr: "unexpected status 404"},
+		{name: "Too large", statusCode: http.StatusOK, body: string(make([]byte, maxPageSize+1)), expectedErr: "response is larger than 2097152 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("User-Agent") != pageUserAgent {
+					t.Errorf("fetchPage() user agent = %q, expected %q", r.Header.Get("User-Agent"), pageUserAgent)
+				}
+				w.Header().Set("Content-Type", "text/html")
+				w.WriteHeader(tt.statusCode)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			body, contentType, err := fetchPage(context.Background(), server.Client(), server.URL)
+			if tt.expectedErr != "" {
+				if err == nil || err.Error() != tt.expectedErr {
+					t.Errorf("fetchPage() error = %v, expected %s", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil || string(body) != tt.body || contentType != "text/html" {
+				t.Errorf("fetchPage() = %q, %q, %v, expected %q", body, contentType, err, tt.body)
+			}
+		})
+	}
+}
+
+func TestDecodeHTMLPage(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		expected    string
+	}{
+		{name: "UTF-8", body: []byte("<p>Café</p>"), contentType: "text/html; charset=utf-8", expected: "<p>Café</p>"},
+		{name: "Content type charset", body: []byte("<p>Caf\xe9</p>"), contentType: "text/html; charset=iso-8859-1", expected: "<p>Café</p>"},
+		{name: "Meta charset", body: []byte(`<meta charset="windows-1252"><p>Caf` + "\xe9</p>"), contentType: "text/html", expected: `<meta charset="windows-1252"><p>Café</p>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeHTMLPage(tt.body, tt.contentType)
+			if err != nil || got != tt.expected {
+				t.Errorf("decodeHTMLPage() = %q, %v, expected %q", got, err, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	handlerModeGitHub = "github-webhook"
+
+	// githubBranchPrefix prefixes the branches of the localization pull requests
+	githubBranchPrefix = "gotranslate/"
+
+	// githubAPIVersion is the version of the GitHub REST API requested
+	githubAPIVersion = "2022-11-28"
+
+	defaultGitHubAPIURL         = "https://api.github.com"
+	defaultGitHubSourceLanguage = "en"
+	githubRequestTimeout        = 30 * time.Second
+	maxGitHubResponseSize       = 10 << 20
+)
+
+var (
+	// githubWebhookSecret is the secret of the webhook signing the GitHub deliveries, the
+	// integration is disabled without it
+	githubWebhookSecret string
+	// githubToken authenticates the GitHub API requests reading the changed files and opening
+	// the pull requests
+	githubToken string
+	// githubAPIURL is the base URL of the GitHub API, set for GitHub Enterprise Server
+	githubAPIURL string
+	// githubSourceLanguage is the language of the source files of the repositories
+	githubSourceLanguage string
+	// githubTargetLanguages are the languages the source files are translated to
+	githubTargetLanguages []string
+	// githubGlossary is the AWS Translate custom terminology of the localized files, none
+	// when empty
+	githubGlossary string
+)
+
+// errGitHubNotFound is the error of a GitHub API request for a missing resource
+var errGitHubNotFound = errors.New("not found")
+
+// GitHubPushEvent is the payload of a GitHub push webhook, reduced to the fields read
+type GitHubPushEvent struct {
+	// Ref is the full name of the pushed ref, such as refs/heads/main
+	Ref string `json:"ref"`
+	// After is the commit the ref points to after the push
+	After string `json:"after"`
+	// Deleted is set when the push deleted the ref
+	Deleted    bool             `json:"deleted"`
+	Repository GitHubRepository `json:"repository"`
+	Commits    []GitHubCommit   `json:"commits"`
+}
+
+// GitHubRepository is the repository of a push
+type GitHubRepository struct {
+	// FullName is the owner and name of the repository, such as octo/docs
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+// GitHubCommit is a commit of a push, listing the files it changed
+type GitHubCommit struct {
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+}
+
+// GitHubLocalization reports the outcome of a push
+type GitHubLocalization struct {
+	// PullRequestURL is the pull request with the localized files, empty when none changed
+	PullRequestURL string `json:"pull_request_url,omitempty"`
+	// Files are the localized files written to the pull request
+	Files []string `json:"files"`
+	// Failed are the source files that couldn't be localized
+	Failed []string `json:"failed,omitempty"`
+}
+
+// githubFile is a localized file of a push
+type githubFile struct {
+	path    string
+	content []byte
+	// sha is the blob of the file it replaces, empty for a new file
+	sha string
+}
+
+// handleGitHubWebhook localizes the Markdown and JSON message files changed by a push to the
+// default branch of a repository. Their translations are committed to a new branch and
+// proposed in a pull request, reviewed like any other change of the repository.
+func (h *handler) handleGitHubWebhook(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	defer flushTelemetry(ctx)
+
+	if githubWebhookSecret == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotImplemented,
+			Body:       "GitHub integration not configured",
+		}, nil
+	}
+
+	body := []byte(event.Body)
+	if event.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(event.Body)
+		if err != nil {
+			return events.APIGatewayProxyResponse{StatusCode: http.StatusBadRequest, Body: "Invalid body encoding"}, nil
+		}
+		body = decoded
+	}
+	if !validGitHubSignature(body, headerValue(event.Headers, "X-Hub-Signature-256"), githubWebhookSecret) {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusUnauthorized, Body: "Invalid signature"}, nil
+	}
+
+	eventType := headerValue(event.Headers, "X-GitHub-Event")
+	if eventType == "ping" {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: "pong"}, nil
+	}
+	if eventType != "push" {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusAccepted, Body: "Event ignored"}, nil
+	}
+
+	var push GitHubPushEvent
+	if err := json.Unmarshal(body, &push); err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusBadRequest, Body: "Invalid push event"}, nil
+	}
+	// Only the default branch is localized, which also leaves out the pushes of our branches
+	if push.Deleted || push.Ref != "refs/heads/"+push.Repository.DefaultBranch {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusAccepted, Body: "Push ignored"}, nil
+	}
+
+	ctx = withGlossary(ctx, githubGlossary)
+	ctx, writer := h.withCacheWriter(ctx)
+	defer writer.Close()
+
+	httpClient := &http.Client{Timeout: githubRequestTimeout}
+	if h.transport != nil {
+		httpClient.Transport = h.transport
+	}
+	github := &githubClient{client: httpClient, baseURL: githubAPIURL, token: githubToken}
+	localization, err := h.localizePush(ctx, github, push)
+	if response, ok := unavailableResponse(err); ok {
+		return response, nil
+	}
+	if err != nil {
+		log.Printf("Error localizing push %s of %s: %v", push.After, push.Repository.FullName, err)
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusBadGateway, Body: "Error localizing push"}, nil
+	}
+
+	data, err := json.Marshal(localization)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError, Body: "Error marshalling response"}, nil
+	}
+	return events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: string(data)}, nil
+}
+
+// localizePush translates the localizable files changed by the push and opens a pull request
+// with the translations that differ from the files in the repository
+func (h *handler) localizePush(ctx context.Context, github *githubClient, push GitHubPushEvent) (GitHubLocalization, error) {
+	repository := push.Repository.FullName
+	localization := GitHubLocalization{Files: []string{}}
+
+	var files []githubFile
+	for _, sourcePath := range changedLocalizableFiles(push, githubSourceLanguage) {
+		source, _, err := github.getFile(ctx, repository, sourcePath, push.After)
+		if errors.Is(err, errGitHubNotFound) {
+			// Removed by a later commit of the push
+			continue
+		}
+		if err != nil {
+			return localization, err
+		}
+
+		for _, targetLanguage := range githubTargetLanguages {
+			if targetLanguage == githubSourceLanguage {
+				continue
+			}
+			if err := configuredLanguagePolicy.check(githubSourceLanguage, targetLanguage); err != nil {
+				log.Printf("Skipping %s of %s to %s: %v", sourcePath, repository, targetLanguage, err)
+				continue
+			}
+			targetPath, _ := localizedPath(sourcePath, githubSourceLanguage, targetLanguage)
+			translated, err := h.translateRepositoryFile(ctx, targetLanguage, sourcePath, source)
+			if err != nil {
+				if _, ok := unavailableResponse(err); ok {
+					return localization, err
+				}
+				log.Printf("Error translating %s of %s to %s: %v", sourcePath, repository, targetLanguage, err)
+				localization.Failed = append(localization.Failed, sourcePath)
+				break
+			}
+
+			current, sha, err := github.getFile(ctx, repository, targetPath, push.After)
+			if err != nil && !errors.Is(err, errGitHubNotFound) {
+				return localization, err
+			}
+			if bytes.Equal(current, translated) {
+				continue
+			}
+			files = append(files, githubFile{path: targetPath, content: translated, sha: sha})
+		}
+	}
+	if len(files) == 0 {
+		return localization, nil
+	}
+
+	branch := githubBranchPrefix + push.After[:min(len(push.After), 12)]
+	if err := github.createBranch(ctx, repository, branch, push.After); err != nil {
+		return localization, err
+	}
+	message := "Update translations for " + push.After[:min(len(push.After), 12)]
+	for _, file := range files {
+		if err := github.putFile(ctx, repository, branch, message, file); err != nil {
+			return localization, err
+		}
+		localization.Files = append(localization.Files, file.path)
+	}
+
+	body := fmt.Sprintf("Translations of the files changed by %s, from %s to %s.\n", push.After, githubSourceLanguage, strings.Join(githubTargetLanguages, ", "))
+	pullRequestURL, err := github.createPullRequest(ctx, repository, branch, push.Repository.DefaultBranch, message, body)
+	if err != nil {
+		return localization, err
+	}
+	localization.PullRequestURL = pullRequestURL
+	return localization, nil
+}
+
+// translateRepositoryFile translates a Markdown or JSON message file of a repository
+func (h *handler) translateRepositoryFile(ctx context.Context, targetLanguage, filePath string, content []byte) ([]byte, error) {
+	if path.Ext(filePath) == ".json" {
+		return h.translateJSONMessages(ctx, githubSourceLanguage, targetLanguage, content)
+	}
+	translated, err := h.translateMarkdown(ctx, githubSourceLanguage, targetLanguage, string(content))
+	return []byte(translated), err
+}
+
+// changedLocalizableFiles returns the Markdown and JSON files added or modified by the push
+// whose path names the source language, in the order of the push
+func changedLocalizableFiles(push GitHubPushEvent, sourceLanguage string) []string {
+	var files []string
+	for _, commit := range push.Commits {
+		for _, file := range slices.Concat(commit.Added, commit.Modified) {
+			switch path.Ext(file) {
+			case ".md", ".markdown", ".json":
+			default:
+				continue
+			}
+			if _, ok := localizedPath(file, sourceLanguage, sourceLanguage); !ok || slices.Contains(files, file) {
+				continue
+			}
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// localizedPath returns the path of the translation of a source file, replacing the source
+// language where it names a directory, as in docs/en/intro.md, or the file, as in
+// locales/en.json and intro.en.md. Files whose path doesn't name the source language aren't
+// localized.
+func localizedPath(filePath, sourceLanguage, targetLanguage string) (string, bool) {
+	segments := strings.Split(filePath, "/")
+	for i, segment := range segments[:len(segments)-1] {
+		if segment == sourceLanguage {
+			segments[i] = targetLanguage
+			return strings.Join(segments, "/"), true
+		}
+	}
+
+	name := segments[len(segments)-1]
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	switch {
+	case stem == sourceLanguage:
+		stem = targetLanguage
+	case strings.HasSuffix(stem, "."+sourceLanguage):
+		stem = strings.TrimSuffix(stem, sourceLanguage) + targetLanguage
+	default:
+		return "", false
+	}
+	segments[len(segments)-1] = stem + ext
+	return strings.Join(segments, "/"), true
+}
+
+// validGitHubSignature reports whether the signature header of a delivery is the HMAC of its
+// body with the webhook secret
+func validGitHubSignature(body []byte, signature, secret string) bool {
+	digest, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// headerValue returns the value of a header of an API Gateway request, whose names keep the
+// case they were sent with
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// githubStatusError is the error of a GitHub API request answered with an unexpected status
+type githubStatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *githubStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Message)
+}
+
+// githubClient calls the GitHub REST API of a repository
+type githubClient struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+// do sends a request to the GitHub API, decoding the JSON response into out
+func (c *githubClient) do(ctx context.Context, method, endpoint string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.baseURL, "/")+endpoint, body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("X-GitHub-Api-Version", githubAPIVersion)
+	request.Header.Set("User-Agent", pageUserAgent)
+	if c.token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if in != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxGitHubResponseSize))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case response.StatusCode == http.StatusNotFound:
+		return errGitHubNotFound
+	case response.StatusCode < 200 || response.StatusCode >= 300:
+		var apiError struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(data, &apiError)
+		return &githubStatusError{StatusCode: response.StatusCode, Message: apiError.Message}
+	case out == nil:
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// getFile returns the content and blob of a file of the repository at the ref
+func (c *githubClient) getFile(ctx context.Context, repository, filePath, ref string) ([]byte, string, error) {
+	var file struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+		SHA      string `json:"sha"`
+	}
+	endpoint := fmt.Sprintf("/repos/%s/contents/%s?ref=%s", repository, escapeGitHubPath(filePath), url.QueryEscape(ref))
+	if err := c.do(ctx, http.MethodGet, endpoint, nil, &file); err != nil {
+		return nil, "", err
+	}
+	if file.Encoding != "base64" {
+		return nil, "", fmt.Errorf("unsupported encoding %q of %s", file.Encoding, filePath)
+	}
+	// The content is wrapped in lines
+	content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+	if err != nil {
+		return nil, "", err
+	}
+	return content, file.SHA, nil
+}
+
+// createBranch creates the branch at the commit. A branch left by an earlier delivery of the
+// same push is reused.
+func (c *githubClient) createBranch(ctx context.Context, repository, branch, sha string) error {
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/git/refs", repository), map[string]string{
+		"ref": "refs/heads/" + branch,
+		"sha": sha,
+	}, nil)
+	var statusErr *githubStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnprocessableEntity {
+		return nil
+	}
+	return err
+}
+
+// putFile commits the file to the branch
+func (c *githubClient) putFile(ctx context.Context, repository, branch, message string, file githubFile) error {
+	request := map[string]string{
+		"message": message,
+		"content": base64.StdEncoding.EncodeToString(file.content),
+		"branch":  branch,
+	}
+	if file.sha != "" {
+		request["sha"] = file.sha
+	}
+	return c.do(ctx, http.MethodPut, fmt.Sprintf("/repos/%s/contents/%s", repository, escapeGitHubPath(file.path)), request, nil)
+}
+
+// createPullRequest opens a pull request merging the branch into the base branch and returns
+// its URL. A pull request left open by an earlier delivery already has the new commits, its
+// URL isn't known and is returned empty.
+func (c *githubClient) createPullRequest(ctx context.Context, repository, branch, base, title, body string) (string, error) {
+	var pullRequest struct {
+		HTMLURL string `json:"html_url"`
+	}
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/pulls", repository), map[string]string{
+		"title": title,
+		"head":  branch,
+		"base":  base,
+		"body":  body,
+	}, &pullRequest)
+	var statusErr *githubStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnprocessableEntity {
+		return "", nil
+	}
+	return pullRequest.HTMLURL, err
+}
+
+// escapeGitHubPath escapes the segments of a repository path for the contents endpoints
+func escapeGitHubPath(filePath string) string {
+	segments := strings.Split(filePath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// parseLanguageCodes parses a list of language codes separated by commas
+func parseLanguageCodes(value string) []string {
+	var codes []string
+	for _, code := range strings.Split(value, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// fakeGitHub serves the contents, refs and pulls endpoints of the GitHub API for a repository
+type fakeGitHub struct {
+	mu sync.Mutex
+	// files are the contents of the repository by path
+	files map[string]string
+	// written are the files committed by path
+	written  map[string]string
+	branches []string
+	pulls    int
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	filePath, isContents := strings.CutPrefix(r.URL.Path, "/repos/octo/docs/contents/")
+	switch {
+	case isContents && r.Method == http.MethodGet:
+		content, ok := f.files[filePath]
+		if !ok {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"encoding":"base64","sha":"blob-` + filePath + `","content":"` + base64.StdEncoding.EncodeToString([]byte(content)) + `"}`))
+	case isContents && r.Method == http.MethodPut:
+		var request map[string]string
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &request)
+		content, _ := base64.StdEncoding.DecodeString(request["content"])
+		f.written[filePath] = string(content)
+		w.WriteHeader(http.StatusCreated)
+	case r.URL.Path == "/repos/octo/docs/git/refs":
+		var request map[string]string
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &request)
+		f.branches = append(f.branches, request["ref"])
+		w.WriteHeader(http.StatusCreated)
+	case r.URL.Path == "/repos/octo/docs/pulls":
+		f.pulls++
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"html_url":"https://github.com/octo/docs/pull/7"}`))
+	default:
+		http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+	}
+}
+
+// signGitHubDelivery returns the signature header of a webhook delivery
+func signGitHubDelivery(body, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestHandleGitHubWebhook(t *testing.T) {
+	push := `{"ref":"refs/heads/main","after":"0123456789abcdef","repository":{"full_name":"octo/docs","default_branch":"main"},` +
+		`"commits":[{"added":["docs/en/intro.md","src/main.go"],"modified":["locales/en.json","README.md"]}]}`
+
+	tests := []struct {
+		name             string
+		event            string
+		body             string
+		signature        string
+		expectedResponse events.APIGatewayProxyResponse
+		expectedWritten  map[string]string
+	}{
+		{
+			name:  "Push",
+			event: "push",
+			body:  push,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"pull_request_url":"https://github.com/octo/docs/pull/7","files":["docs/es/intro.md"]}`,
+			},
+			expectedWritten: map[string]string{"docs/es/intro.md": "# Hola\n"},
+		},
+		{
+			name:      "Invalid signature",
+			event:     "push",
+			body:      push,
+			signature: "sha256=00",
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusUnauthorized,
+				Body:       "Invalid signature",
+			},
+		},
+		{
+			name:  "Ping",
+			event: "ping",
+			body:  `{"zen":"Keep it logically awesome."}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       "pong",
+			},
+		},
+		{
+			name:  "Other branch",
+			event: "push",
+			body:  strings.Replace(push, "refs/heads/main", "refs/heads/feature", 1),
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusAccepted,
+				Body:       "Push ignored",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			github := &fakeGitHub{
+				files: map[string]string{
+					"docs/en/intro.md": "# Hello\n",
+					"locales/en.json":  `{"save": "Save"}`,
+					// The translation of the messages is already up to date
+					"locales/es.json": `{"save": "Guardar"}`,
+				},
+				written: map[string]string{},
+			}
+			server := httptest.NewServer(github)
+			defer server.Close()
+
+			previousSecret, previousURL, previousLanguages := githubWebhookSecret, githubAPIURL, githubTargetLanguages
+			githubWebhookSecret, githubAPIURL, githubTargetLanguages = "secret", server.URL, []string{"es"}
+			defer func() {
+				githubWebhookSecret, githubAPIURL, githubTargetLanguages = previousSecret, previousURL, previousLanguages
+			}()
+
+			signature := tt.signature
+			if signature == "" {
+				signature = signGitHubDelivery(tt.body, "secret")
+			}
+			h := newMockHandler(map[string]string{"Hello": "Hola", "Save": "Guardar"})
+
+			got, err := h.handleGitHubWebhook(context.Background(), events.APIGatewayProxyRequest{
+				HTTPMethod: http.MethodPost,
+				Headers:    map[string]string{"x-github-event": tt.event, "x-hub-signature-256": signature},
+				Body:       tt.body,
+			})
+			if err != nil {
+				t.Fatalf("handleGitHubWebhook() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handleGitHubWebhook() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+			for filePath, expected := range tt.expectedWritten {
+				if github.written[filePath] != expected {
+					t.Errorf("written %s = %q, expected %q", filePath, github.written[filePath], expected)
+				}
+			}
+			if len(github.written) != len(tt.expectedWritten) {
+				t.Errorf("written %d files, expected %d", len(github.written), len(tt.expectedWritten))
+			}
+		})
+	}
+}
+
+func TestLocalizedPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+		ok       bool
+	}{
+		{path: "docs/en/intro.md", expected: "docs/fr/intro.md", ok: true},
+		{path: "locales/en.json", expected: "locales/fr.json", ok: true},
+		{path: "guide/intro.en.md", expected: "guide/intro.fr.md", ok: true},
+		{path: "en/en.json", expected: "fr/en.json", ok: true},
+		{path: "README.md", ok: false},
+		{path: "docs/english/intro.md", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := localizedPath(tt.path, "en", "fr")
+			if got != tt.expected || ok != tt.ok {
+				t.Errorf("localizedPath() = %q, %v, expected %q, %v", got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidGitHubSignature(t *testing.T) {
+	body := `{"ref":"refs/heads/main"}`
+
+	tests := []struct {
+		name      string
+		signature string
+		expected  bool
+	}{
+		{name: "Valid", signature: signGitHubDelivery(body, "secret"), expected: true},
+		{name: "Other secret", signature: signGitHubDelivery(body, "other"), expected: false},
+		{name: "Missing prefix", signature: strings.TrimPrefix(signGitHubDelivery(body, "secret"), "sha256="), expected: false},
+		{name: "Not hex", signature: "sha256=zz", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validGitHubSignature([]byte(body), tt.signature, "secret"); got != tt.expected {
+				t.Errorf("validGitHubSignature() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "context"
+
+type glossaryKey struct{}
+
+// withGlossary returns a context translating the segments with the AWS Translate custom
+// terminology of the name, unchanged without a name
+func withGlossary(ctx context.Context, name string) context.Context {
+	if name == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, glossaryKey{}, name)
+}
+
+// glossaryFromContext returns the glossary of the segments, empty for none
+func glossaryFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(glossaryKey{}).(string)
+	return name
+}
+
+// terminologyNames returns the custom terminologies AWS Translate applies to the segments, the
+// glossary or else the inclusive terminology of the target language
+func terminologyNames(ctx context.Context, targetLanguage string) []string {
+	if name := glossaryFromContext(ctx); name != "" {
+		return []string{name}
+	}
+	if name := inclusiveTerminology(ctx, targetLanguage); name != "" {
+		return []string{name}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCacheTargetLanguage(t *testing.T) {
+	tests := []struct {
+		name      string
+		style     string
+		brief     bool
+		inclusive bool
+		glossary  string
+		expected  string
+	}{
+		{name: "Plain", expected: "es"},
+		{name: "Style", style: "legal", expected: "es+legal"},
+		{name: "Glossary", glossary: "contracts", expected: "es@contracts"},
+		{name: "Style and glossary", style: "legal", glossary: "contracts", expected: "es+legal@contracts"},
+		{name: "Brief and inclusive", style: "legal", brief: true, inclusive: true, expected: "es+legal+brief+inclusive"},
+		{name: "Inclusive with a glossary", inclusive: true, glossary: "contracts", expected: "es@contracts"},
+	}
+
+	previous := inclusiveTerminologies
+	inclusiveTerminologies = map[string]string{"es": "inclusive-es"}
+	defer func() { inclusiveTerminologies = previous }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := withGlossary(withInclusive(withStyle(context.Background(), tt.style), tt.inclusive), tt.glossary)
+			if tt.brief {
+				ctx = withBrevity(ctx)
+			}
+			if got := cacheTargetLanguage(ctx, "es"); got != tt.expected {
+				t.Errorf("cacheTargetLanguage() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	// maxGrammarResponseSize is the size limit of a grammar check response
+	maxGrammarResponseSize = 1024 * 1024
+	// maxGrammarReplacements is the number of replacements suggested for each issue
+	maxGrammarReplacements = 5
+	// grammarCheckTimeout bounds a call of the grammar check, which only flags issues and
+	// isn't worth holding the translation for
+	grammarCheckTimeout = 5 * time.Second
+)
+
+var (
+	// grammarCheckURL is the LanguageTool compatible API checking the translations of the
+	// requests asking for it, empty to disable the grammar check
+	grammarCheckURL string
+	// grammarCheckUsername and grammarCheckAPIKey authenticate with LanguageTool Premium,
+	// empty for a self-hosted server
+	grammarCheckUsername string
+	grammarCheckAPIKey   string
+)
+
+// GrammarIssue is a likely problem of a translated segment flagged by the grammar check, never
+// applied to the translation
+type GrammarIssue struct {
+	// Message explains the issue
+	Message string `json:"message"`
+	// Rule is the identifier of the rule of the grammar checker that matched
+	Rule string `json:"rule"`
+	// Category is the category of the rule, such as GRAMMAR, TYPOS or PUNCTUATION
+	Category string `json:"category,omitempty"`
+	// Text is the flagged text
+	Text string `json:"text"`
+	// Offset and Length locate the flagged text in the translated text of the segment, in
+	// characters
+	Offset int `json:"offset"`
+	Length int `json:"length"`
+	// Replacements are the suggested replacements of the flagged text, best first
+	Replacements []string `json:"replacements,omitempty"`
+}
+
+// languageToolResponse is the body of a check response of the LanguageTool API
+type languageToolResponse struct {
+	Matches []struct {
+		Message      string `json:"message"`
+		Offset       int    `json:"offset"`
+		Length       int    `json:"length"`
+		Replacements []struct {
+			Value string `json:"value"`
+		} `json:"replacements"`
+		Rule struct {
+			ID       string `json:"id"`
+			Category struct {
+				ID string `json:"id"`
+			} `json:"category"`
+		} `json:"rule"`
+	} `json:"matches"`
+}
+
+// grammarCheckClient checks texts with a LanguageTool compatible API
+type grammarCheckClient struct {
+	httpClient *http.Client
+	apiURL     string
+	username   string
+	apiKey     string
+}
+
+// newGrammarCheckClient returns the client of the LanguageTool compatible API
+func newGrammarCheckClient(httpClient *http.Client, apiURL, username, apiKey string) *grammarCheckClient {
+	return &grammarCheckClient{httpClient: httpClient, apiURL: strings.TrimSuffix(apiURL, "/"), username: username, apiKey: apiKey}
+}
+
+// check returns the matches of the text in the language, whose offsets and lengths are in
+// UTF-16 code units like the LanguageTool API
+func (c *grammarCheckClient) check(ctx context.Context, language, text string) (languageToolResponse, error) {
+	form := url.Values{"text": {text}, "language": {language}}
+	if c.apiKey != "" {
+		form.Set("username", c.username)
+		form.Set("apiKey", c.apiKey)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL+"/v2/check", strings.NewReader(form.Encode()))
+	if err != nil {
+		return languageToolResponse{}, fmt.Errorf("failed to create grammar check request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return languageToolResponse{}, fmt.Errorf("failed to call grammar check: %w", err)
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(io.LimitReader(response.Body, maxGrammarResponseSize))
+	if err != nil {
+		return languageToolResponse{}, fmt.Errorf("failed to read grammar check response: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return languageToolResponse{}, fmt.Errorf("grammar check returned %d: %s", response.StatusCode, responseBody)
+	}
+
+	var matches languageToolResponse
+	if err := json.Unmarshal(responseBody, &matches); err != nil {
+		return languageToolResponse{}, fmt.Errorf("failed to unmarshal grammar check response: %w", err)
+	}
+	return matches, nil
+}
+
+// grammarCheck holds the segments of a request flagged by the grammar check
+type grammarCheck struct {
+	mu       sync.Mutex
+	segments []SegmentReport
+}
+
+type grammarCheckKey struct{}
+
+// withGrammarCheck returns a context checking the grammar of every translated segment of the
+// request, unchanged unless enabled
+func withGrammarCheck(ctx context.Context, enabled bool) (context.Context, *grammarCheck) {
+	if !enabled {
+		return ctx, nil
+	}
+	g := &grammarCheck{}
+	return context.WithValue(ctx, grammarCheckKey{}, g), g
+}
+
+// grammarCheckFromContext returns the grammar check of the request, nil when it isn't checked
+func grammarCheckFromContext(ctx context.Context) *grammarCheck {
+	g, _ := ctx.Value(grammarCheckKey{}).(*grammarCheck)
+	return g
+}
+
+// Segments returns the segments flagged so far
+func (g *grammarCheck) Segments() []SegmentReport {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.segments
+}
+
+// checkGrammar reports the issues the grammar check finds in the translated sentences, one
+// line each in a single call. The translation is served unchecked when the check fails.
+func (h *handler) checkGrammar(ctx context.Context, targetLanguage string, sourceSentences, translatedSentences []string) {
+	g := grammarCheckFromContext(ctx)
+	if g == nil || h.grammarClient == nil {
+		return
+	}
+
+	response, err := h.grammarClient.check(ctx, targetLanguage, strings.Join(translatedSentences, "\n"))
+	if err != nil {
+		log.Printf("Error checking the grammar of the translation: %v", err)
+		return
+	}
+
+	issues := make([][]GrammarIssue, len(translatedSentences))
+	for _, match := range response.Matches {
+		// Find the sentence of the match, each is followed by a line break
+		start := 0
+		for i, sentence := range translatedSentences {
+			units := utf16Length(sentence)
+			if match.Offset >= start+units {
+				start += units + 1
+				continue
+			}
+
+			offset := runeOffset(sentence, match.Offset-start)
+			end := runeOffset(sentence, min(match.Offset-start+match.Length, units))
+			issue := GrammarIssue{
+				Message:  match.Message,
+				Rule:     match.Rule.ID,
+				Category: match.Rule.Category.ID,
+				Text:     string([]rune(sentence)[offset:end]),
+				Offset:   offset,
+				Length:   end - offset,
+			}
+			for _, replacement := range match.Replacements[:min(len(match.Replacements), maxGrammarReplacements)] {
+				issue.Replacements = append(issue.Replacements, replacement.Value)
+			}
+			issues[i] = append(issues[i], issue)
+			break
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, sentenceIssues := range issues {
+		if len(sentenceIssues) == 0 {
+			continue
+		}
+		g.segments = append(g.segments, SegmentReport{
+			SourceText:     sourceSentences[i],
+			TranslatedText: translatedSentences[i],
+			Issues:         sentenceIssues,
+		})
+	}
+}
+
+// utf16Length returns the length of the text in UTF-16 code units
+func utf16Length(text string) int {
+	length := 0
+	for _, r := range text {
+		length += utf16.RuneLen(r)
+	}
+	return length
+}
+
+// runeOffset converts an offset in UTF-16 code units of the text to an offset in characters
+func runeOffset(text string, units int) int {
+	offset := 0
+	for _, r := range text {
+		if units <= 0 {
+			break
+		}
+		units -= utf16.RuneLen(r)
+		offset++
+	}
+	return offset
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestGrammarCheck(t *testing.T) {
+	translations := map[string]string{
+		"Hello.":              "Hola.",
+		"I have two cats.":    "Yo tiene dos gatos.",
+		"The café is open.":   "El café esta abierto.",
+		"Nothing is flagged.": "Nada está marcado.",
+	}
+
+	tests := []struct {
+		name             string
+		body             string
+		status           int
+		matches          string
+		expectedText     string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name:         "Issues are reported by segment",
+			body:         `{"source_language":"en","target_language":"es","text":"Hello. I have two cats. The café is open.","grammar_check":true}`,
+			status:       http.StatusOK,
+			expectedText: "Hola.\nYo tiene dos gatos.\nEl café esta abierto.",
+			// "tiene" and "esta", the é of café counting as a single code unit
+			matches: `{"matches":[` +
+				`{"message":"Verb agreement","offset":9,"length":5,"replacements":[{"value":"tengo"}],"rule":{"id":"AGREEMENT","category":{"id":"GRAMMAR"}}},` +
+				`{"message":"Missing accent","offset":34,"length":4,"replacements":[{"value":"está"},{"value":"ésta"}],"rule":{"id":"ACCENT","category":{"id":"TYPOS"}}}]}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body: `{"translated_text":"Hola. Yo tiene dos gatos. El café esta abierto. ","segments":[` +
+					`{"source_text":"I have two cats.","translated_text":"Yo tiene dos gatos.","issues":[{"message":"Verb agreement","rule":"AGREEMENT","category":"GRAMMAR","text":"tiene","offset":3,"length":5,"replacements":["tengo"]}]},` +
+					`{"source_text":"The café is open.","translated_text":"El café esta abierto.","issues":[{"message":"Missing accent","rule":"ACCENT","category":"TYPOS","text":"esta","offset":8,"length":4,"replacements":["está","ésta"]}]}]}`,
+			},
+		},
+		{
+			name:         "Nothing flagged",
+			body:         `{"source_language":"en","target_language":"es","text":"Nothing is flagged.","grammar_check":true}`,
+			status:       http.StatusOK,
+			matches:      `{"matches":[]}`,
+			expectedText: "Nada está marcado.",
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Nada está marcado. "}`,
+			},
+		},
+		{
+			name:         "Failed check serves the translation",
+			body:         `{"source_language":"en","target_language":"es","text":"Nothing is flagged.","grammar_check":true}`,
+			status:       http.StatusBadRequest,
+			expectedText: "Nada está marcado.",
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Nada está marcado. "}`,
+			},
+		},
+		{
+			name: "Unchecked without the option",
+			body: `{"source_language":"en","target_language":"es","text":"Nothing is flagged."}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Nada está marcado. "}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var checked, language string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				checked, language = r.FormValue("text"), r.FormValue("language")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.matches))
+			}))
+			defer server.Close()
+
+			previous := grammarCheckURL
+			grammarCheckURL = server.URL
+			defer func() { grammarCheckURL = previous }()
+
+			h := newMockHandler(translations)
+			h.grammarClient = newGrammarCheckClient(server.Client(), server.URL, "", "")
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+			if checked != tt.expectedText || (checked != "" && language != "es") {
+				t.Errorf("check() text = %q in %q, expected %q in es", checked, language, tt.expectedText)
+			}
+		})
+	}
+}
+
+func TestGrammarCheckTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Stall like a grammar checker that stopped answering
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	previous := grammarCheckURL
+	grammarCheckURL = server.URL
+	defer func() { grammarCheckURL = previous }()
+
+	h := newMockHandler(map[string]string{"Hello.": "Hola."})
+	h.grammarClient = newGrammarCheckClient(newProviderHTTPClient(newProviderTransport(nil), 50*time.Millisecond), server.URL, "", "")
+
+	start := time.Now()
+	got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+		Body: `{"source_language":"en","target_language":"es","text":"Hello.","grammar_check":true}`,
+	})
+	if err != nil {
+		t.Fatalf("handle() error = %v", err)
+	}
+	expected := `{"translated_text":"Hola. "}`
+	if got.StatusCode != http.StatusOK || got.Body != expected {
+		t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, http.StatusOK, expected)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("handle() returned after %v, expected the grammar check to time out", elapsed)
+	}
+}
+
+func TestGrammarCheckDisabled(t *testing.T) {
+	h := newMockHandler(nil)
+	got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+		Body: `{"source_language":"en","target_language":"es","text":"Hello.","grammar_check":true}`,
+	})
+	if err != nil {
+		t.Fatalf("handle() error = %v", err)
+	}
+	expected := `{"error":"Invalid request","fields":[{"field":"grammar_check","message":"grammar_check is not enabled"}]}`
+	if got.StatusCode != http.StatusBadRequest || got.Body != expected {
+		t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, http.StatusBadRequest, expected)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+const (
+	// granularitySentence translates each sentence on its own, the default, which reuses the
+	// cached sentences of texts that are partly the same
+	granularitySentence = "sentence"
+	// granularityParagraph translates each paragraph whole, giving the provider the context of
+	// its other sentences in fewer calls
+	granularityParagraph = "paragraph"
+	// granularityBlock translates each text whole, or each text node of HTML documents
+	granularityBlock = "block"
+
+	// maxChunkBytes is the size of the longest paragraph or block sent to the provider whole,
+	// well under the 10,000 bytes the providers take in a call. Longer ones are split into
+	// sentences.
+	maxChunkBytes = 5000
+)
+
+// paragraphBreakPattern matches the blank lines between paragraphs
+var paragraphBreakPattern = regexp.MustCompile(`\n[ \t\r\f\v]*\n\s*`)
+
+// granularSegmenter splits texts into the chunks of a granularity coarser than the sentence,
+// splitting the chunks too long to be sent whole with the sentence segmenter
+type granularSegmenter struct {
+	granularity string
+	sentences   Segmenter
+}
+
+func (s granularSegmenter) Segment(text string) []string {
+	chunks := []string{text}
+	if s.granularity == granularityParagraph {
+		chunks = paragraphBreakPattern.Split(text, -1)
+	}
+
+	var segments []string
+	for _, chunk := range chunks {
+		chunk = strings.TrimSpace(chunk)
+		switch {
+		case chunk == "":
+		case len(chunk) > maxChunkBytes:
+			segments = append(segments, s.sentences.Segment(chunk)...)
+		default:
+			segments = append(segments, chunk)
+		}
+	}
+	return segments
+}
+
+type granularityKey struct{}
+
+// withGranularity returns a context whose texts are split into chunks of the granularity
+func withGranularity(ctx context.Context, granularity string) context.Context {
+	if granularity == "" || granularity == granularitySentence {
+		return ctx
+	}
+	return context.WithValue(ctx, granularityKey{}, granularity)
+}
+
+// chunkSegmenter returns the segmenter splitting the texts of the request in the language
+// into the chunks of its granularity
+func chunkSegmenter(ctx context.Context, language string) Segmenter {
+	segmenter := segmenterFor(language)
+	if granularity, ok := ctx.Value(granularityKey{}).(string); ok {
+		return granularSegmenter{granularity: granularity, sentences: segmenter}
+	}
+	return segmenter
+}
+
+// splitChunks splits the text in the language into the chunks of the granularity of the
+// request
+func splitChunks(ctx context.Context, language, input string) []string {
+	return splitSentencesWith(chunkSegmenter(ctx, language), input)
+}
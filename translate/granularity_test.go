@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestSplitChunks(t *testing.T) {
+	longParagraph := strings.Repeat("A long sentence of the paragraph. ", 200)
+	longSentences := slices.Repeat([]string{"A long sentence of the paragraph."}, 200)
+
+	tests := []struct {
+		name        string
+		granularity string
+		input       string
+		expected    []string
+	}{
+		{
+			name:     "Sentences by default",
+			input:    "Hello. How are you?\n\nFine.",
+			expected: []string{"Hello.", "How are you?", "Fine."},
+		},
+		{
+			name:        "Paragraphs",
+			granularity: granularityParagraph,
+			input:       "Hello. How are you?\n \n\nFine.\nThanks.",
+			expected:    []string{"Hello. How are you?", "Fine.\nThanks."},
+		},
+		{
+			name:        "Block",
+			granularity: granularityBlock,
+			input:       " Hello. How are you?\n\nFine. ",
+			expected:    []string{"Hello. How are you?\n\nFine."},
+		},
+		{
+			name:        "Paragraph too long to be sent whole",
+			granularity: granularityParagraph,
+			input:       "Hello.\n\n" + longParagraph,
+			expected:    append([]string{"Hello."}, longSentences...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := withGranularity(context.Background(), tt.granularity)
+			if got := splitChunks(ctx, "en", tt.input); !slices.Equal(got, tt.expected) {
+				t.Errorf("splitChunks() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGranularity(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name: "Paragraph",
+			body: `{"source_language":"en","target_language":"es","text":"Hello. How are you?\n\nHello.","granularity":"paragraph"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola, ¿cómo estás? Hola. "}`,
+			},
+		},
+		{
+			name: "HTML block",
+			body: `{"source_language":"en","target_language":"es","text":"<p>Hello. How are you?</p>","format":"html","granularity":"block"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"\u003cp lang=\"es\"\u003eHola, ¿cómo estás?\u003c/p\u003e"}`,
+			},
+		},
+		{
+			name: "Echoed granularity",
+			body: `{"source_language":"en","target_language":"es","text":"Hello.","granularity":"block","echo_parameters":true}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola. ","parameters":{"source_language":"en","target_language":"es","format":"text","granularity":"block"}}`,
+			},
+		},
+		{
+			name: "Unsupported granularity",
+			body: `{"source_language":"en","target_language":"es","text":"Hello.","granularity":"word"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"granularity","message":"granularity \"word\" is not supported"}]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(map[string]string{"Hello.": "Hola.", "Hello. How are you?": "Hola, ¿cómo estás?"})
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+		})
+	}
+}
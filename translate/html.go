@@ -0,0 +1,506 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+const formatHTML = "html"
+
+// skippedHTMLElements are elements whose text content is never translated, as is the content
+// of elements marked translate="no" or with the notranslate class
+var skippedHTMLElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"title":    true,
+	"textarea": true,
+}
+
+// voidHTMLElements are elements without an end tag or content
+var voidHTMLElements = map[string]bool{
+	"area":   true,
+	"base":   true,
+	"br":     true,
+	"col":    true,
+	"embed":  true,
+	"hr":     true,
+	"img":    true,
+	"input":  true,
+	"link":   true,
+	"meta":   true,
+	"source": true,
+	"track":  true,
+	"wbr":    true,
+}
+
+// htmlToken is a single token of an HTML document
+type htmlToken struct {
+	// Raw is the token exactly as it appeared in the source document
+	Raw string
+	// Text is the unescaped text of a translatable text token
+	Text string
+	// Separators are the text between the sentences of Text, usually a single space
+	Separators []string
+	// Tag is the parsed start tag of an element, whose attributes may be rewritten
+	Tag *html.Token
+	// TopLevel is set on the start tag of a top-level element and Translated when the element
+	// holds translatable text
+	TopLevel   bool
+	Translated bool
+	// Untranslated is set on the start tags inside skipped elements, and NoTranslate on the
+	// start tag of an element opting out of translation
+	Untranslated bool
+	NoTranslate  bool
+	// Directives are the translator instructions in scope of a text token
+	Directives htmlDirectives
+}
+
+// htmlDirectivePrefix starts the HTML comments holding translator instructions for the
+// element following them, such as <!-- gotranslate: skip --> or
+// <!-- gotranslate: style=legal glossary=contracts -->
+const htmlDirectivePrefix = "gotranslate:"
+
+// htmlDirectives are the translator instructions of an element and its content, inheriting
+// those of the enclosing elements
+type htmlDirectives struct {
+	// Skip leaves the element untranslated
+	Skip bool
+	// Style is the style of the text, overriding the style of the request
+	Style string
+	// Glossary is the AWS Translate custom terminology of the text
+	Glossary string
+}
+
+// htmlScope holds the directives of an element until its end tag
+type htmlScope struct {
+	depth      int
+	directives htmlDirectives
+}
+
+// separator returns the text following the sentence at the index
+func (t htmlToken) separator(index int) string {
+	if index < len(t.Separators) {
+		return t.Separators[index]
+	}
+	return " "
+}
+
+// translateHTML translates the text nodes of an HTML document, leaving the markup untouched
+func (h *handler) translateHTML(ctx context.Context, sourceLanguage, targetLanguage, input string) (string, error) {
+	translated := newSpillBuffer()
+	defer translated.Close()
+	if err := h.streamHTML(ctx, sourceLanguage, targetLanguage, translated, strings.NewReader(input)); err != nil {
+		return "", err
+	}
+	return translated.String()
+}
+
+// streamHTML translates the HTML document read from r in windows of documentWindowBytes,
+// writing the translation of each window to w before reading the next one, so memory holds
+// the tokens and sentences of a window rather than those of the whole document
+func (h *handler) streamHTML(ctx context.Context, sourceLanguage, targetLanguage string, w io.Writer, r io.Reader) error {
+	stream := newHTMLStream(r, chunkSegmenter(ctx, sourceLanguage))
+	for {
+		window, err := stream.next(documentWindowBytes)
+		if err != nil {
+			return err
+		}
+		if len(window.tokens) == 0 {
+			return nil
+		}
+
+		translatedSentences, err := h.translateScopedSentences(ctx, sourceLanguage, targetLanguage, window.tokens, window.sentences, window.sentenceCounts)
+		if err != nil {
+			return err
+		}
+		if htmlMetadataFromContext(ctx) {
+			if err := h.translateHTMLMetadata(ctx, sourceLanguage, targetLanguage, window.tokens); err != nil {
+				return err
+			}
+		}
+		setHTMLLanguage(window.tokens, sourceLanguage, targetLanguage)
+
+		// A mismatch keeps the source text of the affected nodes rather than failing the request
+		if err := writeHTML(w, window.tokens, window.sentenceCounts, translatedSentences); err != nil {
+			var mismatch *htmlMismatchError
+			if !errors.As(err, &mismatch) {
+				return err
+			}
+			log.Printf("Error reconstructing html, untranslated text is kept: %v", err)
+		}
+	}
+}
+
+// translateScopedSentences translates the sentences of the HTML tokens, those in the scope of
+// the same directives together with their style and glossary
+func (h *handler) translateScopedSentences(ctx context.Context, sourceLanguage, targetLanguage string, tokens []htmlToken, sentences []string, sentenceCounts []int) ([]string, error) {
+	var (
+		groups  []htmlDirectives
+		indexes = map[htmlDirectives][]int{}
+		offset  int
+	)
+	for i, token := range tokens {
+		if i >= len(sentenceCounts) || sentenceCounts[i] <= 0 {
+			continue
+		}
+		if _, ok := indexes[token.Directives]; !ok {
+			groups = append(groups, token.Directives)
+		}
+		for range sentenceCounts[i] {
+			indexes[token.Directives] = append(indexes[token.Directives], offset)
+			offset++
+		}
+	}
+	if len(groups) <= 1 && (len(groups) == 0 || groups[0] == htmlDirectives{}) {
+		return h.translateSentences(ctx, sourceLanguage, targetLanguage, sentences)
+	}
+
+	translatedSentences := make([]string, len(sentences))
+	for _, directives := range groups {
+		groupCtx := withGlossary(ctx, directives.Glossary)
+		if directives.Style != "" {
+			groupCtx = withStyle(groupCtx, directives.Style)
+		}
+
+		groupSentences := make([]string, len(indexes[directives]))
+		for i, index := range indexes[directives] {
+			groupSentences[i] = sentences[index]
+		}
+		translated, err := h.translateSentences(groupCtx, sourceLanguage, targetLanguage, groupSentences)
+		if err != nil {
+			return nil, err
+		}
+		for i, index := range indexes[directives] {
+			translatedSentences[index] = translated[i]
+		}
+	}
+	return translatedSentences, nil
+}
+
+// htmlWindow is a run of consecutive tokens of an HTML document with the sentences of their
+// translatable text, sentenceCounts holding the number of sentences taken from each token
+type htmlWindow struct {
+	tokens         []htmlToken
+	sentences      []string
+	sentenceCounts []int
+}
+
+// htmlStream tokenizes an HTML document incrementally into windows, keeping the nesting and
+// the directives in scope from one window to the next
+type htmlStream struct {
+	z         *html.Tokenizer
+	segmenter Segmenter
+	// depth is the nesting of the current token, topLevel the index in the window of the
+	// start tag of its top-level element and skippedDepth the depth of the skipped element it
+	// is in
+	depth        int
+	topLevel     int
+	skippedDepth int
+	// scopes are the elements with directives the token is in, and pending the directives of
+	// a comment waiting for the next element
+	scopes  []htmlScope
+	pending *htmlDirectives
+	done    bool
+}
+
+// newHTMLStream returns a stream of the HTML document read from r, splitting every
+// translatable text token into sentences with the segmenter
+func newHTMLStream(r io.Reader, segmenter Segmenter) *htmlStream {
+	return &htmlStream{z: html.NewTokenizer(r), segmenter: segmenter, topLevel: -1, skippedDepth: -1}
+}
+
+// directives returns the translator instructions in scope of the current token
+func (s *htmlStream) directives() htmlDirectives {
+	if len(s.scopes) == 0 {
+		return htmlDirectives{}
+	}
+	return s.scopes[len(s.scopes)-1].directives
+}
+
+// settled reports whether the window may end after its last token: outside skipped elements,
+// whose content is read together with their start tag, and once the top-level element around
+// the token is known to hold translatable text or not, which sets its language.
+func (s *htmlStream) settled(window htmlWindow) bool {
+	if s.skippedDepth >= 0 {
+		return false
+	}
+	if s.depth == 0 || s.topLevel < 0 {
+		return true
+	}
+	top := window.tokens[s.topLevel]
+	return top.Translated || top.Tag.Data == "html"
+}
+
+// next returns the next window of the document, ending at the first settled token after
+// windowBytes bytes of source, or at the end of the document when windowBytes is 0. The
+// window is empty once the document was read.
+func (s *htmlStream) next(windowBytes int) (htmlWindow, error) {
+	var (
+		window htmlWindow
+		size   int
+	)
+	for !s.done {
+		tokenType := s.z.Next()
+		if tokenType == html.ErrorToken {
+			s.done = true
+			if s.z.Err() != io.EOF {
+				return htmlWindow{}, fmt.Errorf("failed to tokenize html: %w", s.z.Err())
+			}
+			break
+		}
+
+		token := htmlToken{Raw: string(s.z.Raw())}
+		count := 0
+
+		switch tokenType {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tag := s.z.Token()
+			token.Tag = &tag
+			token.TopLevel = s.depth == 0
+			if token.TopLevel {
+				s.topLevel = len(window.tokens)
+			}
+
+			hasContent := tokenType == html.StartTagToken && !voidHTMLElements[tag.Data]
+			elementDirectives := s.directives()
+			if s.pending != nil {
+				elementDirectives, s.pending = *s.pending, nil
+				if hasContent && s.skippedDepth < 0 {
+					s.scopes = append(s.scopes, htmlScope{depth: s.depth, directives: elementDirectives})
+				}
+			}
+
+			switch {
+			case s.skippedDepth >= 0:
+				token.Untranslated = true
+			case skippedHTMLElements[tag.Data] || isNoTranslate(tag) || elementDirectives.Skip:
+				token.Untranslated, token.NoTranslate = true, !skippedHTMLElements[tag.Data]
+				if hasContent {
+					s.skippedDepth = s.depth
+				}
+			}
+			if hasContent {
+				s.depth++
+			}
+		case html.EndTagToken:
+			s.depth = max(s.depth-1, 0)
+			if s.depth <= s.skippedDepth {
+				s.skippedDepth = -1
+			}
+			if len(s.scopes) > 0 && s.depth <= s.scopes[len(s.scopes)-1].depth {
+				s.scopes = s.scopes[:len(s.scopes)-1]
+			}
+		case html.CommentToken:
+			if s.skippedDepth >= 0 {
+				break
+			}
+			if commentDirectives, ok := parseHTMLDirectives(string(s.z.Text()), s.directives()); ok {
+				s.pending = &commentDirectives
+			}
+		case html.TextToken:
+			if s.skippedDepth >= 0 {
+				break
+			}
+			// Invalid UTF-8 is replaced as the segmenter would, so the sentences are found in the text
+			token.Text = strings.ToValidUTF8(html.UnescapeString(token.Raw), string(utf8.RuneError))
+			if strings.TrimSpace(token.Text) == "" {
+				break
+			}
+			textSentences := splitSentencesWith(s.segmenter, token.Text)
+			token.Directives = s.directives()
+			if s.depth > 0 && s.topLevel >= 0 {
+				window.tokens[s.topLevel].Translated = true
+			}
+			window.sentences = append(window.sentences, textSentences...)
+			count = len(textSentences)
+			token.Separators = sentenceSeparators(token.Text, textSentences)
+		}
+
+		window.tokens = append(window.tokens, token)
+		window.sentenceCounts = append(window.sentenceCounts, count)
+		size += len(token.Raw)
+		if windowBytes > 0 && size >= windowBytes && s.settled(window) {
+			break
+		}
+	}
+
+	// The top-level element of the next window's tokens was settled in this one
+	s.topLevel = -1
+	return window, nil
+}
+
+// getTextFromHTML tokenizes the whole HTML document and splits every translatable text token
+// into sentences with the segmenter. sentenceCounts holds the number of sentences taken from
+// each token.
+func getTextFromHTML(input string, segmenter Segmenter) ([]htmlToken, []string, []int, error) {
+	window, err := newHTMLStream(strings.NewReader(input), segmenter).next(0)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return window.tokens, window.sentences, window.sentenceCounts, nil
+}
+
+// isNoTranslate reports whether the element opts out of translation, with translate="no" or
+// the notranslate class
+func isNoTranslate(tag html.Token) bool {
+	for _, attr := range tag.Attr {
+		switch attr.Key {
+		case "translate":
+			if strings.EqualFold(strings.TrimSpace(attr.Val), "no") {
+				return true
+			}
+		case "class":
+			if slices.Contains(strings.Fields(attr.Val), "notranslate") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseHTMLDirectives reads the translator instructions of a comment on top of the inherited
+// ones, reporting whether the comment holds instructions. Unknown instructions are ignored.
+func parseHTMLDirectives(comment string, inherited htmlDirectives) (htmlDirectives, bool) {
+	instructions, ok := strings.CutPrefix(strings.TrimSpace(comment), htmlDirectivePrefix)
+	if !ok {
+		return inherited, false
+	}
+
+	directives := inherited
+	for _, instruction := range strings.FieldsFunc(instructions, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		key, value, _ := strings.Cut(instruction, "=")
+		switch {
+		case key == "skip":
+			directives.Skip = true
+		case key == "style" && value != "":
+			if _, ok := styleSettings[value]; !ok {
+				log.Printf("Ignoring the unsupported style %q of an HTML comment", value)
+				continue
+			}
+			directives.Style = value
+		case key == "glossary" && value != "":
+			directives.Glossary = value
+		default:
+			log.Printf("Ignoring the unknown instruction %q of an HTML comment", instruction)
+		}
+	}
+	return directives, true
+}
+
+// sentenceSeparators finds the text between the sentences split from the text, so they are
+// joined back the same way. A sentence the segmenter changed is separated by a space.
+func sentenceSeparators(text string, sentences []string) []string {
+	separators := make([]string, 0, max(len(sentences)-1, 0))
+	offset := 0
+	for i, sentence := range sentences {
+		index := strings.Index(text[offset:], sentence)
+		if i > 0 {
+			if index >= 0 && strings.TrimSpace(text[offset:offset+index]) == "" {
+				separators = append(separators, text[offset:offset+index])
+			} else {
+				separators = append(separators, " ")
+			}
+		}
+		if index >= 0 {
+			offset += index + len(sentence)
+		}
+	}
+	return separators
+}
+
+// htmlMismatchError describes the tokens whose sentence counts or translated sentences don't
+// match, which kept their source text
+type htmlMismatchError struct {
+	err error
+}
+
+func (e *htmlMismatchError) Error() string {
+	return e.err.Error()
+}
+
+func (e *htmlMismatchError) Unwrap() error {
+	return e.err
+}
+
+// reconstructHTML rebuilds the HTML document, replacing the text of each translated token
+// with its translated sentences while keeping the surrounding whitespace. When the sentence
+// counts or the translated sentences don't match the tokens, the tokens without a count or
+// without enough translated sentences keep their source text and an error describing the
+// mismatch is returned with the document.
+func reconstructHTML(tokens []htmlToken, sentenceCounts []int, translatedSentences []string) (string, error) {
+	var builder strings.Builder
+	err := writeHTML(&builder, tokens, sentenceCounts, translatedSentences)
+	return builder.String(), err
+}
+
+// writeHTML writes the HTML of the tokens to w as reconstructHTML rebuilds it. Besides the
+// errors of w, an *htmlMismatchError is returned once the tokens are written when the
+// sentence counts or the translated sentences don't match them.
+func writeHTML(w io.Writer, tokens []htmlToken, sentenceCounts []int, translatedSentences []string) error {
+	var (
+		output        = bufio.NewWriter(w)
+		untouched     int
+		missing       int
+		sentenceIndex int
+	)
+
+	for i, token := range tokens {
+		count := 0
+		if i < len(sentenceCounts) {
+			count = sentenceCounts[i]
+		}
+		start := sentenceIndex
+		sentenceIndex += max(count, 0)
+		if count <= 0 {
+			output.WriteString(token.Raw)
+			continue
+		}
+		if start+count > len(translatedSentences) {
+			output.WriteString(token.Raw)
+			untouched++
+			missing += min(count, start+count-len(translatedSentences))
+			continue
+		}
+
+		leading := token.Text[:len(token.Text)-len(strings.TrimLeftFunc(token.Text, unicode.IsSpace))]
+		trailing := token.Text[len(strings.TrimRightFunc(token.Text, unicode.IsSpace)):]
+
+		output.WriteString(leading)
+		for j, sentence := range translatedSentences[start : start+count] {
+			if j > 0 {
+				output.WriteString(token.separator(j - 1))
+			}
+			output.WriteString(html.EscapeString(sentence))
+		}
+		output.WriteString(trailing)
+	}
+	// The writer keeps the first error of w, returned by Flush
+	if err := output.Flush(); err != nil {
+		return err
+	}
+
+	var errs []error
+	if len(sentenceCounts) != len(tokens) {
+		errs = append(errs, fmt.Errorf("%d sentence counts for %d tokens", len(sentenceCounts), len(tokens)))
+	}
+	if untouched > 0 {
+		errs = append(errs, fmt.Errorf("%d translated sentences are missing, %d text nodes kept their source text", missing, untouched))
+	}
+	if sentenceIndex < len(translatedSentences) {
+		errs = append(errs, fmt.Errorf("%d translated sentences are left over", len(translatedSentences)-sentenceIndex))
+	}
+	if len(errs) > 0 {
+		return &htmlMismatchError{err: errors.Join(errs...)}
+	}
+	return nil
+}
@@ -0,0 +1,570 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"golang.org/x/net/html"
+)
+
+func TestGetTextFromHTML(t *testing.T) {
+	tests := []struct {
+		name                   string
+		input                  string
+		expectedSentences      []string
+		expectedSentenceCounts []int
+	}{
+		{
+			name:                   "Paragraph with multiple sentences",
+			input:                  "<p>Hello world. How are you?</p>",
+			expectedSentences:      []string{"Hello world.", "How are you?"},
+			expectedSentenceCounts: []int{0, 2, 0},
+		},
+		{
+			name:                   "Nested elements",
+			input:                  "<div><b>Hello</b> world</div>",
+			expectedSentences:      []string{"Hello", "world"},
+			expectedSentenceCounts: []int{0, 0, 1, 0, 1, 0},
+		},
+		{
+			name:                   "Script and style are skipped",
+			input:                  "<script>var a = 1;</script><style>p { color: red; }</style><p>Hello</p>",
+			expectedSentences:      []string{"Hello"},
+			expectedSentenceCounts: []int{0, 0, 0, 0, 0, 0, 0, 1, 0},
+		},
+		{
+			name:                   "Notranslate regions are skipped",
+			input:                  `<p>Hello <span translate="no">Acme <b>Cloud</b></span> <i class="brand notranslate">Go</i> world</p>`,
+			expectedSentences:      []string{"Hello", "world"},
+			expectedSentenceCounts: []int{0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0},
+		},
+		{
+			name:                   "Whitespace only text",
+			input:                  "<ul>\n  <li>Hello</li>\n</ul>",
+			expectedSentences:      []string{"Hello"},
+			expectedSentenceCounts: []int{0, 0, 0, 1, 0, 0, 0},
+		},
+		{
+			name:                   "Empty input",
+			input:                  "",
+			expectedSentences:      nil,
+			expectedSentenceCounts: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, sentences, sentenceCounts, err := getTextFromHTML(tt.input, defaultSegmenter)
+			if err != nil {
+				t.Errorf("getTextFromHTML() error = %v", err)
+				return
+			}
+
+			if len(tokens) != len(sentenceCounts) {
+				t.Errorf("getTextFromHTML() returned %d tokens and %d sentence counts", len(tokens), len(sentenceCounts))
+			}
+
+			if !slices.Equal(sentences, tt.expectedSentences) {
+				t.Errorf("getTextFromHTML() sentences = %q, expected %q", sentences, tt.expectedSentences)
+			}
+
+			if !slices.Equal(sentenceCounts, tt.expectedSentenceCounts) {
+				t.Errorf("getTextFromHTML() sentenceCounts = %v, expected %v", sentenceCounts, tt.expectedSentenceCounts)
+			}
+		})
+	}
+}
+
+func TestReconstructHTML(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		translated []string
+		expected   string
+		wantErr    bool
+	}{
+		{
+			name:       "Paragraph with multiple sentences",
+			input:      "<p>Hello world. How are you?</p>",
+			translated: []string{"Hola mundo.", "¿Cómo estás?"},
+			expected:   "<p>Hola mundo. ¿Cómo estás?</p>",
+		},
+		{
+			name:       "Whitespace and attributes are preserved",
+			input:      "<a href='/home' class=nav>\n  Home\n</a>",
+			translated: []string{"Inicio"},
+			expected:   "<a href='/home' class=nav>\n  Inicio\n</a>",
+		},
+		{
+			name:       "Translated text is escaped",
+			input:      "<p>Fish &amp; chips</p>",
+			translated: []string{"Pescado & papas"},
+			expected:   "<p>Pescado &amp; papas</p>",
+		},
+		{
+			name:       "Script is untouched",
+			input:      "<script>if (a < b) {}</script><p>Hello</p>",
+			translated: []string{"Hola"},
+			expected:   "<script>if (a < b) {}</script><p>Hola</p>",
+		},
+		{
+			name:       "Missing sentences keep the source text",
+			input:      "<p>Hello world. How are you?</p><p>Goodbye.</p>",
+			translated: []string{"Hola mundo.", "¿Cómo estás?"},
+			expected:   "<p>Hola mundo. ¿Cómo estás?</p><p>Goodbye.</p>",
+			wantErr:    true,
+		},
+		{
+			name:       "Partially missing sentences keep the source text of the node",
+			input:      "<p>Hello world. How are you?</p>",
+			translated: []string{"Hola mundo."},
+			expected:   "<p>Hello world. How are you?</p>",
+			wantErr:    true,
+		},
+		{
+			name:       "Left over sentences",
+			input:      "<p>Hello</p>",
+			translated: []string{"Hola", "Adiós"},
+			expected:   "<p>Hola</p>",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, _, sentenceCounts, err := getTextFromHTML(tt.input, defaultSegmenter)
+			if err != nil {
+				t.Errorf("getTextFromHTML() error = %v", err)
+				return
+			}
+
+			got, err := reconstructHTML(tokens, sentenceCounts, tt.translated)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("reconstructHTML() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("reconstructHTML() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReconstructHTMLMarkupFidelity(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "Self-closing void elements",
+			input: "<p>Line one<br/>Line two<br />Line three</p><hr/>",
+		},
+		{
+			name:  "Void elements without slash",
+			input: "<meta charset=utf-8><link rel=stylesheet href=\"/a.css\"><p>Hello<br>world</p><input type=text disabled>",
+		},
+		{
+			name:  "Attribute order and quoting",
+			input: "<img width=10 src='/logo.png' alt=\"Logo\" data-x = 1 ><a title=Home href=\"/\" class='nav main'>Home</a>",
+		},
+		{
+			name:  "Unclosed elements are not closed",
+			input: "<ul><li>One<li>Two</ul><p>Open paragraph",
+		},
+		{
+			name:  "Uppercase tags and comments",
+			input: "<DIV CLASS=x><!-- keep --><P>Hello.</P></DIV>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, sentences, sentenceCounts, err := getTextFromHTML(tt.input, defaultSegmenter)
+			if err != nil {
+				t.Errorf("getTextFromHTML() error = %v", err)
+				return
+			}
+
+			got, err := reconstructHTML(tokens, sentenceCounts, sentences)
+			if err != nil {
+				t.Errorf("reconstructHTML() error = %v", err)
+			}
+			if got != tt.input {
+				t.Errorf("reconstructHTML() = %q, expected %q", got, tt.input)
+			}
+		})
+	}
+}
+
+func TestParseHTMLDirectives(t *testing.T) {
+	tests := []struct {
+		name       string
+		comment    string
+		inherited  htmlDirectives
+		expected   htmlDirectives
+		expectedOK bool
+	}{
+		{name: "Skip", comment: " gotranslate: skip ", expected: htmlDirectives{Skip: true}, expectedOK: true},
+		{
+			name:       "Style and glossary",
+			comment:    "gotranslate: style=legal, glossary=contracts",
+			expected:   htmlDirectives{Style: "legal", Glossary: "contracts"},
+			expectedOK: true,
+		},
+		{
+			name:       "Inherited",
+			comment:    "gotranslate: glossary=products",
+			inherited:  htmlDirectives{Style: "legal", Glossary: "contracts"},
+			expected:   htmlDirectives{Style: "legal", Glossary: "products"},
+			expectedOK: true,
+		},
+		{
+			name:       "Unknown instructions",
+			comment:    "gotranslate: style=poetic tone=dry",
+			inherited:  htmlDirectives{Style: "casual"},
+			expected:   htmlDirectives{Style: "casual"},
+			expectedOK: true,
+		},
+		{name: "Other comment", comment: " TODO: translate this ", inherited: htmlDirectives{Style: "casual"}, expected: htmlDirectives{Style: "casual"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseHTMLDirectives(tt.comment, tt.inherited)
+			if got != tt.expected || ok != tt.expectedOK {
+				t.Errorf("parseHTMLDirectives() = %+v, %v, expected %+v, %v", got, ok, tt.expected, tt.expectedOK)
+			}
+		})
+	}
+}
+
+func TestTranslateHTMLDirectives(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls = map[string]string{}
+	)
+	h := newMockHandler(nil)
+	h.translateClient.(*MockTranslateClient).TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		call := "plain"
+		if params.Settings != nil {
+			call = string(params.Settings.Formality)
+		}
+		if len(params.TerminologyNames) > 0 {
+			call += " " + strings.Join(params.TerminologyNames, ",")
+		}
+		calls[*params.Text] = call
+		return &translate.TranslateTextOutput{TranslatedText: aws.String(strings.ToUpper(*params.Text))}, nil
+	}
+
+	input := `<p>Hello.</p>
+<!-- gotranslate: skip -->
+<p>Acme Cloud.</p>
+<!-- gotranslate: style=legal glossary=contracts -->
+<section><p>Terms apply.</p><!-- gotranslate: glossary=products --><p>Buy now.</p><p>Read on.</p></section>
+<p>Goodbye.</p>`
+	got, err := h.translateHTML(context.Background(), "en", "es", input)
+	if err != nil {
+		t.Fatalf("translateHTML() error = %v", err)
+	}
+
+	expected := `<p lang="es">HELLO.</p>
+<!-- gotranslate: skip -->
+<p lang="en">Acme Cloud.</p>
+<!-- gotranslate: style=legal glossary=contracts -->
+<section lang="es"><p>TERMS APPLY.</p><!-- gotranslate: glossary=products --><p>BUY NOW.</p><p>READ ON.</p></section>
+<p lang="es">GOODBYE.</p>`
+	if got != expected {
+		t.Errorf("translateHTML() = %s, expected %s", got, expected)
+	}
+
+	expectedCalls := map[string]string{
+		"Hello.":       "plain",
+		"Terms apply.": "FORMAL contracts",
+		"Buy now.":     "FORMAL products",
+		"Read on.":     "FORMAL contracts",
+		"Goodbye.":     "plain",
+	}
+	if !maps.Equal(calls, expectedCalls) {
+		t.Errorf("translateHTML() translated %v, expected %v", calls, expectedCalls)
+	}
+}
+
+func TestTranslateHTML(t *testing.T) {
+	h := newMockHandler(map[string]string{
+		"Hello": "Hola",
+		"world": "mundo",
+	})
+
+	got, err := h.translateHTML(context.Background(), "en", "es", "<div><b>Hello</b> world</div>")
+	if err != nil {
+		t.Errorf("translateHTML() error = %v", err)
+		return
+	}
+
+	expected := "<div lang=\"es\"><b>Hola</b> mundo</div>"
+	if got != expected {
+		t.Errorf("translateHTML() = %q, expected %q", got, expected)
+	}
+}
+
+// TestHTMLGolden translates each input.html under testdata/html with an identity translation
+// and compares the reconstructed document with the expected.html next to it, so any change
+// to the markup of real-world documents shows up as a diff of the golden file
+func TestHTMLGolden(t *testing.T) {
+	const dir = "testdata/html"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", dir, err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		t.Run(entry.Name(), func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join(dir, entry.Name(), "input.html"))
+			if err != nil {
+				t.Fatalf("error reading input: %v", err)
+			}
+
+			got, err := newMockHandler(nil).translateHTML(context.Background(), "en", "es", string(input))
+			if err != nil {
+				t.Fatalf("translateHTML() error = %v", err)
+			}
+
+			path := filepath.Join(dir, entry.Name(), "expected.html")
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatalf("error writing %s: %v", path, err)
+				}
+			}
+
+			expected, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("error reading %s: %v", path, err)
+			}
+			if got != string(expected) {
+				t.Errorf("translateHTML() = %q, expected %q, run go test -run TestHTMLGolden -update if the change is intended", got, expected)
+			}
+		})
+	}
+}
+
+func TestHTMLStreamWindows(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "Window per top-level element",
+			input:    "<p>Hello.</p><p>World.</p>",
+			expected: []string{"<p>Hello.", "</p>", "<p>World.", "</p>"},
+		},
+		{
+			name:     "Top-level element read until its text",
+			input:    "<div><img src=\"a.png\"><p>Hello.</p></div>",
+			expected: []string{"<div><img src=\"a.png\"><p>Hello.", "</p>", "</div>"},
+		},
+		{
+			name:     "Top-level element without text read whole",
+			input:    "<div><img src=\"a.png\"></div><p>Hello.</p>",
+			expected: []string{"<div><img src=\"a.png\"></div>", "<p>Hello.", "</p>"},
+		},
+		{
+			name:     "Skipped element read whole",
+			input:    "<html><title>Page</title><script>var a;</script></html>",
+			expected: []string{"<html>", "<title>Page</title>", "<script>var a;</script>", "</html>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := newHTMLStream(strings.NewReader(tt.input), defaultSegmenter)
+			var windows []string
+			for {
+				window, err := stream.next(1)
+				if err != nil {
+					t.Fatalf("next() error = %v", err)
+				}
+				if len(window.tokens) == 0 {
+					break
+				}
+				var raw strings.Builder
+				for _, token := range window.tokens {
+					raw.WriteString(token.Raw)
+				}
+				windows = append(windows, raw.String())
+			}
+			if !slices.Equal(windows, tt.expected) {
+				t.Errorf("next() windows = %q, expected %q", windows, tt.expected)
+			}
+		})
+	}
+}
+
+// TestStreamHTMLGolden translates the golden documents in the smallest windows, which must
+// give the same documents as translating them whole
+func TestStreamHTMLGolden(t *testing.T) {
+	const dir = "testdata/html"
+
+	previous := documentWindowBytes
+	documentWindowBytes = 1
+	defer func() { documentWindowBytes = previous }()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", dir, err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		t.Run(entry.Name(), func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join(dir, entry.Name(), "input.html"))
+			if err != nil {
+				t.Fatalf("error reading input: %v", err)
+			}
+			expected, err := os.ReadFile(filepath.Join(dir, entry.Name(), "expected.html"))
+			if err != nil {
+				t.Fatalf("error reading expected: %v", err)
+			}
+
+			var got strings.Builder
+			if err := newMockHandler(nil).streamHTML(context.Background(), "en", "es", &got, bytes.NewReader(input)); err != nil {
+				t.Fatalf("streamHTML() error = %v", err)
+			}
+			if got.String() != string(expected) {
+				t.Errorf("streamHTML() = %q, expected %q", got.String(), expected)
+			}
+		})
+	}
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("mock error")
+}
+
+func TestStreamHTMLWriteError(t *testing.T) {
+	err := newMockHandler(nil).streamHTML(context.Background(), "en", "es", failingWriter{}, strings.NewReader("<p>Hello.</p>"))
+	if err == nil || err.Error() != "mock error" {
+		t.Errorf("streamHTML() error = %v, expected the error of the writer", err)
+	}
+}
+
+// htmlOutline returns the tokens of a document for comparison, with the unescaped text
+// between tags joined and its whitespace collapsed. Invalid UTF-8 in text is replaced.
+func htmlOutline(input string) []string {
+	var (
+		outline []string
+		text    strings.Builder
+	)
+	flush := func() {
+		if words := strings.Fields(strings.ToValidUTF8(text.String(), "\uFFFD")); len(words) > 0 {
+			outline = append(outline, strings.Join(words, " "))
+		}
+		text.Reset()
+	}
+
+	z := html.NewTokenizer(strings.NewReader(input))
+	for {
+		tokenType := z.Next()
+		if tokenType == html.ErrorToken {
+			if z.Err() != io.EOF {
+				outline = append(outline, "error: "+z.Err().Error())
+			}
+			break
+		}
+		if tokenType == html.TextToken {
+			text.WriteString(html.UnescapeString(string(z.Raw())))
+			continue
+		}
+		flush()
+		outline = append(outline, string(z.Raw()))
+	}
+	flush()
+
+	return outline
+}
+
+func FuzzReconstructHTML(f *testing.F) {
+	f.Add("<p>Hello world. How are you?</p>", 0)
+	f.Add("<div><b>Hello</b> world</div>", 1)
+	f.Add("<a href='/home' class=nav>\n  Home\n</a>", 0)
+	f.Add("<p>Fish &amp; chips &lt;3 &nbsp;</p>", 0)
+	f.Add("<script>if (a < b) {}</script><p>Hello</p><!-- note -->", 2)
+	f.Add("<title>Shop</title><textarea>Say <b>hi</b>.</textarea>text", -1)
+	f.Add("<p>Dr. Smith arrived at 5 p.m. Was he late?</p>", 1)
+
+	f.Fuzz(func(t *testing.T, input string, mismatch int) {
+		tokens, sentences, sentenceCounts, err := getTextFromHTML(input, defaultSegmenter)
+		if err != nil {
+			return
+		}
+
+		// Identity translations reproduce an equivalent document
+		got, err := reconstructHTML(tokens, sentenceCounts, sentences)
+		if err != nil {
+			t.Errorf("reconstructHTML() error = %v", err)
+		}
+		if !slices.Equal(htmlOutline(got), htmlOutline(input)) {
+			t.Errorf("reconstructHTML() = %q, expected the markup and text of %q", got, input)
+		}
+
+		// Mismatched translations must never panic and are reported
+		translated := sentences
+		if mismatch > 0 {
+			translated = sentences[:max(len(sentences)-mismatch, 0)]
+		} else if mismatch < 0 {
+			translated = append(slices.Clone(sentences), "extra")
+		}
+		if _, err := reconstructHTML(tokens, sentenceCounts, translated); (err != nil) != (len(translated) != len(sentences)) {
+			t.Errorf("reconstructHTML() with %d of %d sentences error = %v", len(translated), len(sentences), err)
+		}
+		if _, err := reconstructHTML(tokens, sentenceCounts[:len(sentenceCounts)/2], sentences); err == nil && len(tokens) > 0 {
+			t.Errorf("reconstructHTML() with missing sentence counts, expected an error")
+		}
+	})
+}
+
+// benchmarkHTML is a scraped page fragment with markup, entities and skipped elements
+var benchmarkHTML = strings.Repeat(`<div class="product"><h2>Red shirt</h2><p>Soft cotton &amp; linen. <b>Machine washable</b> at 30&deg;C.</p><script>track("view");</script><ul><li>Slim fit.</li><li>Made in Portugal.</li></ul></div>`, 20)
+
+func BenchmarkGetTextFromHTML(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		if _, _, _, err := getTextFromHTML(benchmarkHTML, defaultSegmenter); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReconstructHTML(b *testing.B) {
+	tokens, sentences, sentenceCounts, err := getTextFromHTML(benchmarkHTML, defaultSegmenter)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		reconstructHTML(tokens, sentenceCounts, sentences)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import "context"
+
+// inclusiveTerminologies are the AWS Translate custom terminologies mapping gendered terms to
+// gender-neutral phrasing by target language tag or base language, formatted as
+// language=terminology,language=terminology like the region tables. The inclusive option has
+// no effect on the other target languages.
+var inclusiveTerminologies map[string]string
+
+type inclusiveKey struct{}
+
+// withInclusive returns a context requesting gender-neutral phrasing of the translations,
+// unchanged unless enabled
+func withInclusive(ctx context.Context, enabled bool) context.Context {
+	if !enabled {
+		return ctx
+	}
+	return context.WithValue(ctx, inclusiveKey{}, true)
+}
+
+// inclusiveFromContext reports whether the request asked for gender-neutral phrasing
+func inclusiveFromContext(ctx context.Context) bool {
+	enabled, _ := ctx.Value(inclusiveKey{}).(bool)
+	return enabled
+}
+
+// inclusiveTerminology returns the custom terminology phrasing the translations of an
+// inclusive request in the target language gender-neutrally, empty when the language has
+// none. AWS Translate applies a single terminology, so a glossary takes precedence.
+func inclusiveTerminology(ctx context.Context, targetLanguage string) string {
+	if !inclusiveFromContext(ctx) || glossaryFromContext(ctx) != "" {
+		return ""
+	}
+	terminology, _ := languageConvention(inclusiveTerminologies, targetLanguage)
+	return terminology
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestHandleInclusiveRequest(t *testing.T) {
+	tests := []struct {
+		name                string
+		terminologies       map[string]string
+		body                string
+		expectedBody        string
+		expectedTerminology string
+		expectedHash        string
+	}{
+		{
+			name:                "Inclusive terminology of the target language",
+			terminologies:       map[string]string{"es": "inclusive-es"},
+			body:                `{"source_language":"en","target_language":"es","text":"Dear employees","inclusive":true}`,
+			expectedBody:        `{"translated_text":"Estimado personal "}`,
+			expectedTerminology: "inclusive-es",
+			expectedHash:        getCacheKey("en", "es+inclusive", "Dear employees"),
+		},
+		{
+			name:          "Target language without an inclusive terminology",
+			terminologies: map[string]string{"de": "inclusive-de"},
+			body:          `{"source_language":"en","target_language":"es","text":"Dear employees","inclusive":true}`,
+			expectedBody:  `{"translated_text":"Hola from DeepL "}`,
+			expectedHash:  getCacheKey("en", "es", "Dear employees"),
+		},
+		{
+			name:          "Not inclusive",
+			terminologies: map[string]string{"es": "inclusive-es"},
+			body:          `{"source_language":"en","target_language":"es","text":"Dear employees"}`,
+			expectedBody:  `{"translated_text":"Hola from DeepL "}`,
+			expectedHash:  getCacheKey("en", "es", "Dear employees"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousTerminologies, previousRules := inclusiveTerminologies, routingRules
+			inclusiveTerminologies, routingRules = tt.terminologies, parseRoutingRules("*:*=deepl")
+			defer func() { inclusiveTerminologies, routingRules = previousTerminologies, previousRules }()
+
+			// Other translations are routed to DeepL, which has no inclusive terminology
+			deepL := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"translations":[{"text":"Hola from DeepL"}]}`))
+			}))
+			defer deepL.Close()
+
+			var (
+				mu          sync.Mutex
+				terminology string
+				hash        string
+			)
+			h := newMockHandler(nil)
+			h.deepLClient = newDeepLTranslateClient(deepL.Client(), "key", deepL.URL)
+			h.translateClient.(*MockTranslateClient).TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				if len(params.TerminologyNames) == 1 {
+					terminology = params.TerminologyNames[0]
+				}
+				return &translate.TranslateTextOutput{TranslatedText: aws.String("Estimado personal")}, nil
+			}
+			h.dynamoClient.(*MockDynamoDBClient).PutItemFunc = func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				hash = params.Item["hash"].(*dynamoTypes.AttributeValueMemberS).Value
+				return &dynamodb.PutItemOutput{}, nil
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != http.StatusOK || got.Body != tt.expectedBody {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, http.StatusOK, tt.expectedBody)
+			}
+			if terminology != tt.expectedTerminology {
+				t.Errorf("TranslateText() terminology = %q, expected %q", terminology, tt.expectedTerminology)
+			}
+			if hash != tt.expectedHash {
+				t.Errorf("PutItem() hash = %q, expected %q", hash, tt.expectedHash)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+// The integration tests run the handler against a real DynamoDB API, either dynamodb-local or
+// LocalStack, while translations still come from the mock translate client. Start one with
+//
+//	docker run --rm -p 8000:8000 amazon/dynamodb-local
+//
+// and run make integration, setting DYNAMODB_ENDPOINT when it doesn't listen on port 8000.
+// Each test creates its own table with the schema of the template and deletes it afterwards.
+
+// newIntegrationHandler returns a handler whose cache is a new table of the local DynamoDB,
+// and a counter of the calls to the translate client
+func newIntegrationHandler(t *testing.T, translations map[string]string) (*handler, *atomic.Int32) {
+	t.Helper()
+
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "http://localhost:8000"
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background(),
+		config.WithRegion("us-east-1"),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("test", "test", "")),
+	)
+	if err != nil {
+		t.Fatalf("failed to load configuration: %v", err)
+	}
+	client := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+		o.BaseEndpoint = aws.String(endpoint)
+	})
+
+	tableName := fmt.Sprintf("translate-integration-%d", time.Now().UnixNano())
+	createIntegrationTable(t, client, tableName)
+
+	previous := translateTableName
+	translateTableName = tableName
+	t.Cleanup(func() { translateTableName = previous })
+
+	h := newMockHandler(translations)
+	h.dynamoClient = newBreakerDynamoDBClient(client)
+
+	// Count the translations to tell cache hits from misses
+	calls := &atomic.Int32{}
+	mock := h.translateClient.(*MockTranslateClient)
+	translateText := mock.TranslateTextFunc
+	mock.TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+		calls.Add(1)
+		return translateText(ctx, params, optFns...)
+	}
+
+	return h, calls
+}
+
+// createIntegrationTable creates a table with the keys and indexes of TranslateTable in
+// template.yaml, deleted when the test finishes
+func createIntegrationTable(t *testing.T, client *dynamodb.Client, tableName string) {
+	t.Helper()
+
+	ctx := context.Background()
+	hashIndex := func(name, attribute string) dynamoTypes.GlobalSecondaryIndex {
+		return dynamoTypes.GlobalSecondaryIndex{
+			IndexName: aws.String(name),
+			KeySchema: []dynamoTypes.KeySchemaElement{
+				{AttributeName: aws.String(attribute), KeyType: dynamoTypes.KeyTypeHash},
+			},
+			Projection: &dynamoTypes.Projection{
+				ProjectionType:   dynamoTypes.ProjectionTypeInclude,
+				NonKeyAttributes: []string{"overflow_key"},
+			},
+		}
+	}
+	_, err := client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		AttributeDefinitions: []dynamoTypes.AttributeDefinition{
+			{AttributeName: aws.String("hash"), AttributeType: dynamoTypes.ScalarAttributeTypeS},
+			{AttributeName: aws.String("source_hash"), AttributeType: dynamoTypes.ScalarAttributeTypeS},
+			{AttributeName: aws.String("translated_hash"), AttributeType: dynamoTypes.ScalarAttributeTypeS},
+		},
+		KeySchema: []dynamoTypes.KeySchemaElement{
+			{AttributeName: aws.String("hash"), KeyType: dynamoTypes.KeyTypeHash},
+		},
+		GlobalSecondaryIndexes: []dynamoTypes.GlobalSecondaryIndex{
+			hashIndex(sourceHashIndex, "source_hash"),
+			hashIndex(translatedHashIndex, "translated_hash"),
+		},
+		BillingMode: dynamoTypes.BillingModePayPerRequest,
+	})
+	if err != nil {
+		t.Fatalf("failed to create table %s, is DynamoDB running? %v", tableName, err)
+	}
+	t.Cleanup(func() {
+		if _, err := client.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: aws.String(tableName)}); err != nil {
+			t.Logf("failed to delete table %s: %v", tableName, err)
+		}
+	})
+
+	waiter := dynamodb.NewTableExistsWaiter(client)
+	if err := waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)}, time.Minute); err != nil {
+		t.Fatalf("table %s is not active: %v", tableName, err)
+	}
+}
+
+// readIntegrationCacheItem reads the cache item with the hash from the table of the test
+func readIntegrationCacheItem(t *testing.T, h *handler, hash string) (CacheItem, bool) {
+	t.Helper()
+
+	item, ok, err := getCacheItem(context.Background(), h.dynamoClient, translateTableName, hash)
+	if err != nil {
+		t.Fatalf("getCacheItem() error = %v", err)
+	}
+	return item, ok
+}
+
+func TestIntegrationTranslateCache(t *testing.T) {
+	h, calls := newIntegrationHandler(t, map[string]string{
+		"Hello.":        "Hola.",
+		"How are you?":  "¿Cómo estás?",
+		"Good morning.": "Buenos días.",
+	})
+
+	tests := []struct {
+		name             string
+		body             string
+		expectedCalls    int32
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name:             "Translations are cached",
+			body:             `{"source_language":"en","target_language":"es","text":"Hello. How are you?"}`,
+			expectedCalls:    2,
+			expectedResponse: events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: `{"translated_text":"Hola. ¿Cómo estás? "}`},
+		},
+		{
+			name:             "Cached translations are read",
+			body:             `{"source_language":"en","target_language":"es","text":"Hello. How are you?"}`,
+			expectedResponse: events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: `{"translated_text":"Hola. ¿Cómo estás? "}`},
+		},
+		{
+			name:             "Only new sentences are translated",
+			body:             `{"source_language":"en","target_language":"es","text":"Good morning. How are you?"}`,
+			expectedCalls:    1,
+			expectedResponse: events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: `{"translated_text":"Buenos días. ¿Cómo estás? "}`},
+		},
+		{
+			name:             "Styled translations are cached apart",
+			body:             `{"source_language":"en","target_language":"es","text":"Hello.","style":"legal"}`,
+			expectedCalls:    1,
+			expectedResponse: events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: `{"translated_text":"Hola. "}`},
+		},
+	}
+
+	// The cases run in order, each one reading the cache written by the previous ones
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.Store(0)
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %v, expected %v", got, tt.expectedResponse)
+			}
+			if got := calls.Load(); got != tt.expectedCalls {
+				t.Errorf("TranslateText() calls = %d, expected %d", got, tt.expectedCalls)
+			}
+		})
+	}
+
+	t.Run("Cache items are complete", func(t *testing.T) {
+		item, ok := readIntegrationCacheItem(t, h, getCacheKey("en", "es", "How are you?"))
+		if !ok {
+			t.Fatalf("getCacheItem() found no item, expected the translation")
+		}
+
+		if item.SourceText != "How are you?" || item.TranslatedText != "¿Cómo estás?" || item.Provider != providerAWSTranslate {
+			t.Errorf("getCacheItem() = %+v, expected the translation of %q", item, "How are you?")
+		}
+		if item.SourceHash != getHashFromText("How are you?") || item.CreatedAt == 0 {
+			t.Errorf("getCacheItem() = %+v, expected the content hash and creation time", item)
+		}
+		if cacheHitTracking && item.Hits != 2 {
+			t.Errorf("getCacheItem() hits = %d, expected 2", item.Hits)
+		}
+	})
+}
+
+func TestIntegrationCacheWriteEvent(t *testing.T) {
+	h, _ := newIntegrationHandler(t, nil)
+
+	items := []CacheItem{
+		{Hash: getCacheKey("en", "es", "Hello."), SourceText: "Hello.", TranslatedText: "Hola.", SourceLanguage: "en", TargetLanguage: "es", Provider: providerAWSTranslate},
+		{Hash: getCacheKey("en", "fr", "Hello."), SourceText: "Hello.", TranslatedText: "Bonjour.", SourceLanguage: "en", TargetLanguage: "fr", Provider: providerAWSTranslate},
+	}
+
+	event := events.SQSEvent{Records: []events.SQSMessage{{MessageId: "malformed", Body: "{"}}}
+	for i, item := range items {
+		body, err := json.Marshal(item)
+		if err != nil {
+			t.Fatalf("failed to marshal item: %v", err)
+		}
+		event.Records = append(event.Records, events.SQSMessage{MessageId: fmt.Sprint(i), Body: string(body)})
+	}
+
+	response, err := h.handleCacheWriteEvent(context.Background(), event)
+	if err != nil {
+		t.Fatalf("handleCacheWriteEvent() error = %v", err)
+	}
+	if len(response.BatchItemFailures) > 0 {
+		t.Errorf("handleCacheWriteEvent() failures = %v, expected none", response.BatchItemFailures)
+	}
+
+	for _, expected := range items {
+		got, ok := readIntegrationCacheItem(t, h, expected.Hash)
+		if !ok || got.TranslatedText != expected.TranslatedText {
+			t.Errorf("getCacheItem(%s) = %+v, expected %q", expected.Hash, got, expected.TranslatedText)
+		}
+	}
+}
+
+func TestIntegrationErasure(t *testing.T) {
+	h, _ := newIntegrationHandler(t, map[string]string{"Jane Doe.": "Jane Doe."})
+
+	for _, target := range []string{"es", "fr"} {
+		body := fmt.Sprintf(`{"source_language":"en","target_language":"%s","text":"Jane Doe."}`, target)
+		if _, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: body}); err != nil {
+			t.Fatalf("handle() error = %v", err)
+		}
+	}
+
+	got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+		Resource:   erasureResource,
+		HTTPMethod: http.MethodPost,
+		Body:       `{"texts":["Jane Doe."]}`,
+	})
+	if err != nil {
+		t.Fatalf("handle() error = %v", err)
+	}
+
+	var report ErasureReport
+	if err := json.Unmarshal([]byte(got.Body), &report); err != nil {
+		t.Fatalf("handle() = %v, expected an erasure report", got)
+	}
+	if report.Deleted != 2 {
+		t.Errorf("handle() deleted = %d, expected 2", report.Deleted)
+	}
+
+	for _, target := range []string{"es", "fr"} {
+		if item, ok := readIntegrationCacheItem(t, h, getCacheKey("en", target, "Jane Doe.")); ok {
+			t.Errorf("getCacheItem() = %+v, expected the item to be erased", item)
+		}
+	}
+}
+
+func TestIntegrationCompaction(t *testing.T) {
+	previous := compactionPageInterval
+	compactionPageInterval = 0
+	defer func() { compactionPageInterval = previous }()
+
+	h, _ := newIntegrationHandler(t, nil)
+
+	items := []CacheItem{
+		{Hash: "old", SourceText: "Old.", TranslatedText: "Viejo.", SourceLanguage: "en", TargetLanguage: "es", CreatedAt: time.Now().Add(-days(100)).Unix()},
+		{Hash: "new", SourceText: "New.", TranslatedText: "Nuevo.", SourceLanguage: "en", TargetLanguage: "es"},
+	}
+	for _, item := range items {
+		if err := h.storeCacheItem(context.Background(), item); err != nil {
+			t.Fatalf("storeCacheItem() error = %v", err)
+		}
+	}
+
+	// A small rate scans the table over several pages
+	got, err := h.handleCacheJob(context.Background(), CacheJobRequest{Action: cacheJobCompact, MaxAgeDays: 90, Rate: 1})
+	if err != nil {
+		t.Fatalf("handleCacheJob() error = %v", err)
+	}
+	expected := CacheJobResponse{Action: cacheJobCompact, Items: 1, Scanned: 2}
+	if got != expected {
+		t.Errorf("handleCacheJob() = %+v, expected %+v", got, expected)
+	}
+
+	for _, item := range items {
+		cached, ok := readIntegrationCacheItem(t, h, item.Hash)
+		if ok == (item.Hash == "old") {
+			t.Errorf("getCacheItem(%s) = %+v, expected only the old item to be evicted", item.Hash, cached)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// jsonPlaceholderPattern matches the interpolation placeholders of JSON message files, such
+// as the {{name}} of i18next, the {name} of ICU and vue-i18n and printf style specifiers
+var jsonPlaceholderPattern = regexp.MustCompile(`\{\{[^{}]*\}\}|\{[^{}]*\}|` + formatSpecifierPattern.String())
+
+// icuArgumentPattern matches the plural and select arguments of ICU messages, whose nested
+// branches the placeholders can't protect. Such messages are left untranslated.
+var icuArgumentPattern = regexp.MustCompile(`\{\s*\w+\s*,\s*(?:plural|select|selectordinal)\s*,`)
+
+// jsonFrame is an array or object the decoder of a JSON message file is in
+type jsonFrame struct {
+	object bool
+	// key is set in objects when the next string is a key
+	key bool
+}
+
+// translateJSONMessages translates the string values of a JSON message file, keeping its
+// keys, layout and key order. Placeholders are protected, messages whose placeholders don't
+// survive translation and ICU plural and select messages are kept untranslated.
+func (h *handler) translateJSONMessages(ctx context.Context, sourceLanguage, targetLanguage string, document []byte) ([]byte, error) {
+	if !stdjson.Valid(document) {
+		return nil, fmt.Errorf("invalid JSON")
+	}
+
+	var (
+		values []resourceValue
+		stack  []jsonFrame
+	)
+	// afterValue expects the next key of the object the value ends
+	afterValue := func() {
+		if len(stack) > 0 && stack[len(stack)-1].object {
+			stack[len(stack)-1].key = true
+		}
+	}
+
+	decoder := stdjson.NewDecoder(bytes.NewReader(document))
+	for {
+		start := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		end := int(decoder.InputOffset())
+
+		switch token := token.(type) {
+		case stdjson.Delim:
+			switch token {
+			case '{':
+				stack = append(stack, jsonFrame{object: true, key: true})
+			case '[':
+				stack = append(stack, jsonFrame{})
+			default:
+				stack = stack[:len(stack)-1]
+				afterValue()
+			}
+		case string:
+			if len(stack) > 0 && stack[len(stack)-1].key {
+				stack[len(stack)-1].key = false
+				continue
+			}
+			// The token starts after the separators before it
+			start += bytes.IndexByte(document[start:end], '"')
+			if !icuArgumentPattern.MatchString(token) {
+				values = append(values, resourceValue{start: start, end: end, text: token})
+			}
+			afterValue()
+		default:
+			afterValue()
+		}
+	}
+
+	texts := make([]string, len(values))
+	for i, value := range values {
+		texts[i] = value.text
+	}
+	translated, err := h.translateMasked(ctx, sourceLanguage, targetLanguage, texts, jsonPlaceholderPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	edits := make([]resourceEdit, 0, len(values))
+	for i, value := range values {
+		if translated[i] == value.text {
+			continue
+		}
+		edits = append(edits, resourceEdit{start: value.start, end: value.end, text: encodeJSONString(translated[i])})
+	}
+	return applyResourceEdits(document, edits), nil
+}
+
+// encodeJSONString returns the JSON string literal of the text, leaving HTML characters
+// unescaped
+func encodeJSONString(text string) string {
+	var buf bytes.Buffer
+	encoder := stdjson.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	// Encoding a string can't fail
+	_ = encoder.Encode(text)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTranslateJSONMessages(t *testing.T) {
+	translations := map[string]string{
+		"Hello":                   "Hola",
+		"Welcome back, {0}!":      "¡Bienvenido de nuevo, {0}!",
+		"You have {0} <b>new</b>": "Tienes {0} <b>nuevos</b>",
+		"Save":                    "Guardar",
+		"Say \"hi\"":              "Di \"hola\"",
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Nested keys keep their order",
+			input:    "{\n  \"title\": \"Hello\",\n  \"actions\": {\"save\": \"Save\", \"count\": 3}\n}\n",
+			expected: "{\n  \"title\": \"Hola\",\n  \"actions\": {\"save\": \"Guardar\", \"count\": 3}\n}\n",
+		},
+		{
+			name:     "Placeholders",
+			input:    `{"welcome": "Welcome back, {{name}}!", "unread": "You have {count} <b>new</b>"}`,
+			expected: `{"welcome": "¡Bienvenido de nuevo, {{name}}!", "unread": "Tienes {count} <b>nuevos</b>"}`,
+		},
+		{
+			name:     "Escapes and arrays",
+			input:    `{"quote": "Say \"hi\"", "list": ["Hello", "Save"]}`,
+			expected: `{"quote": "Di \"hola\"", "list": ["Hola", "Guardar"]}`,
+		},
+		{
+			name:     "ICU plural left untranslated",
+			input:    `{"items": "{count, plural, one {# item} other {# items}}"}`,
+			expected: `{"items": "{count, plural, one {# item} other {# items}}"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(translations)
+
+			got, err := h.translateJSONMessages(context.Background(), "en", "es", []byte(tt.input))
+			if err != nil {
+				t.Fatalf("translateJSONMessages() error = %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("translateJSONMessages() = %s, expected %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTranslateJSONMessagesInvalid(t *testing.T) {
+	h := newMockHandler(nil)
+
+	if _, err := h.translateJSONMessages(context.Background(), "en", "es", []byte(`{"title": `)); err == nil {
+		t.Error("translateJSONMessages() error = nil, expected an error")
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// languagePolicy restricts the languages translated whatever the provider supports, such as to
+// the languages with a legal review. A language listed without region also covers its
+// regional variants, pt covering pt-PT.
+type languagePolicy struct {
+	// allowedTargets are the only target languages translated to, every language when empty
+	allowedTargets map[string]bool
+	// blocked are the languages neither translated from nor to
+	blocked map[string]bool
+}
+
+// configuredLanguagePolicy is the language policy of the deployment, layered on top of the
+// languages the provider supports and of the entitlement of the caller
+var configuredLanguagePolicy languagePolicy
+
+// parseLanguageList parses a list of language codes separated by commas, such as en,fr,pt-PT
+func parseLanguageList(value string) map[string]bool {
+	languages := make(map[string]bool)
+	for _, language := range strings.Split(value, ",") {
+		if language = strings.TrimSpace(language); language != "" {
+			languages[normalizeLanguageCode(language)] = true
+		}
+	}
+	return languages
+}
+
+// listed reports whether the list holds the language or its base language
+func listed(languages map[string]bool, language string) bool {
+	base, _, _ := strings.Cut(language, "-")
+	return languages[language] || languages[base]
+}
+
+// allowsTarget reports whether the policy allows translating to the language
+func (p languagePolicy) allowsTarget(targetLanguage string) bool {
+	targetLanguage = normalizeLanguageCode(targetLanguage)
+	return !listed(p.blocked, targetLanguage) &&
+		(len(p.allowedTargets) == 0 || listed(p.allowedTargets, targetLanguage))
+}
+
+// check returns the error of a language pair the policy doesn't allow. A source language
+// still to be detected is checked once detected.
+func (p languagePolicy) check(sourceLanguage, targetLanguage string) error {
+	sourceLanguage, targetLanguage = normalizeLanguageCode(sourceLanguage), normalizeLanguageCode(targetLanguage)
+	switch {
+	case listed(p.blocked, sourceLanguage):
+		return fmt.Errorf("language %s is blocked", sourceLanguage)
+	case listed(p.blocked, targetLanguage):
+		return fmt.Errorf("language %s is blocked", targetLanguage)
+	case !p.allowsTarget(targetLanguage):
+		return fmt.Errorf("target language %s is not allowed", targetLanguage)
+	}
+	return nil
+}
+
+// languagePairNotAllowed is returned when the policy doesn't allow translating a language pair,
+// such as that of a source language detected by the provider
+type languagePairNotAllowed struct {
+	SourceLanguage string
+	TargetLanguage string
+	err            error
+}
+
+func (e *languagePairNotAllowed) Error() string {
+	return fmt.Sprintf("language pair %s to %s not allowed: %v", e.SourceLanguage, e.TargetLanguage, e.err)
+}
+
+func (e *languagePairNotAllowed) Unwrap() error { return e.err }
+
+// checkPair returns a languagePairNotAllowed error when the policy doesn't allow the pair
+func (p languagePolicy) checkPair(sourceLanguage, targetLanguage string) error {
+	if err := p.check(sourceLanguage, targetLanguage); err != nil {
+		return &languagePairNotAllowed{SourceLanguage: sourceLanguage, TargetLanguage: targetLanguage, err: err}
+	}
+	return nil
+}
+
+// blocksLanguages reports whether the policy blocks any language, so that the languages
+// detected in the texts without source language must be checked
+func (p languagePolicy) blocksLanguages() bool {
+	return len(p.blocked) > 0
+}
+
+// languagePolicyResponse returns a 403 response when the error was caused by the language
+// policy, such as a source language detected during the translation being blocked
+func languagePolicyResponse(err error) (events.APIGatewayProxyResponse, bool) {
+	var notAllowed *languagePairNotAllowed
+	if !errors.As(err, &notAllowed) {
+		return events.APIGatewayProxyResponse{}, false
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusForbidden,
+		Body:       fmt.Sprintf("Language pair %s to %s not allowed: %v", notAllowed.SourceLanguage, notAllowed.TargetLanguage, notAllowed.err),
+	}, true
+}
+
+// checkConfiguredLanguages checks the languages the modes translate to without a request
+// against the policy: the GitHub target languages it doesn't allow are dropped, the records
+// and messages of a stream or email target language it doesn't allow are left untranslated.
+func checkConfiguredLanguages() {
+	githubTargetLanguages = slices.DeleteFunc(githubTargetLanguages, func(targetLanguage string) bool {
+		err := configuredLanguagePolicy.check(githubSourceLanguage, targetLanguage)
+		if err != nil {
+			log.Printf("Ignoring GitHub target language %s: %v", targetLanguage, err)
+		}
+		return err != nil
+	})
+	if err := configuredLanguagePolicy.check(streamSourceLanguage, streamTargetLanguage); err != nil {
+		log.Printf("Stream records are left untranslated: %v", err)
+	}
+	if !configuredLanguagePolicy.allowsTarget(emailTargetLanguage) {
+		log.Printf("Inbound emails are left untranslated: target language %s is not allowed", emailTargetLanguage)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestLanguagePolicyCheck(t *testing.T) {
+	policy := languagePolicy{
+		allowedTargets: parseLanguageList("en, es, pt-PT, zh"),
+		blocked:        parseLanguageList("ru,zh-tw"),
+	}
+
+	tests := []struct {
+		source        string
+		target        string
+		expectedError string
+	}{
+		{source: "en", target: "es"},
+		{source: "es", target: "es-MX"},
+		{source: "en", target: "pt-PT"},
+		{source: "en", target: "zh"},
+		{source: autoDetectLanguage, target: "en"},
+		{source: "en", target: "pt", expectedError: "target language pt is not allowed"},
+		{source: "en", target: "pt-BR", expectedError: "target language pt-BR is not allowed"},
+		{source: "en", target: "fr", expectedError: "target language fr is not allowed"},
+		{source: "en", target: "zh-TW", expectedError: "language zh-TW is blocked"},
+		{source: "ru", target: "en", expectedError: "language ru is blocked"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source+":"+tt.target, func(t *testing.T) {
+			got := ""
+			if err := policy.check(tt.source, tt.target); err != nil {
+				got = err.Error()
+			}
+			if got != tt.expectedError {
+				t.Errorf("check() error = %q, expected %q", got, tt.expectedError)
+			}
+		})
+	}
+
+	if err := (languagePolicy{}).check("ru", "fr"); err != nil {
+		t.Errorf("check() error = %v without a policy, expected every pair allowed", err)
+	}
+}
+
+func TestHandleLanguagePolicy(t *testing.T) {
+	tests := []struct {
+		name             string
+		allowed          string
+		blocked          string
+		detected         string
+		request          events.APIGatewayProxyRequest
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name:    "Allowed target language",
+			allowed: "es",
+			request: events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Body: `{"source_language":"en","target_language":"es","text":"Hello"}`},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola "}`,
+			},
+		},
+		{
+			name:    "Target language not allowed",
+			allowed: "fr",
+			request: events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Body: `{"source_language":"en","target_language":"es","text":"Hello"}`},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusForbidden,
+				Body:       "Language pair en to es not allowed: target language es is not allowed",
+			},
+		},
+		{
+			name:    "Blocked source language",
+			blocked: "en",
+			request: events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Body: `{"source_language":"en","target_language":"es","text":"Hello"}`},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusForbidden,
+				Body:       "Language pair en to es not allowed: language en is blocked",
+			},
+		},
+		{
+			name:     "Blocked language detected in a text",
+			blocked:  "fr",
+			detected: "fr",
+			request:  events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Body: `{"source_language":"auto","target_language":"es","text":"Bonjour"}`},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusForbidden,
+				Body:       "Language pair fr to es not allowed: language fr is blocked",
+			},
+		},
+		{
+			name:     "Blocked language detected by the provider",
+			blocked:  "fr",
+			detected: "fr",
+			request:  events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Body: `{"source_language":"auto","target_language":"es","format":"plural","plurals":{"other":"Bonjour"}}`},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusForbidden,
+				Body:       "Language pair fr to es not allowed: language fr is blocked",
+			},
+		},
+		{
+			name:    "Languages listed without the blocked ones",
+			blocked: "es",
+			request: events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Resource: languagesResource},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"languages":["en"]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := configuredLanguagePolicy
+			configuredLanguagePolicy = languagePolicy{allowedTargets: parseLanguageList(tt.allowed), blocked: parseLanguageList(tt.blocked)}
+			defer func() { configuredLanguagePolicy = previous }()
+
+			h := newMockHandler(map[string]string{"Hello": "Hola"})
+			if tt.detected != "" {
+				h.comprehendClient = newMockLanguageDetector(tt.detected)
+				mock := h.translateClient.(*MockTranslateClient)
+				translateText := mock.TranslateTextFunc
+				mock.TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+					output, err := translateText(ctx, params, optFns...)
+					if aws.ToString(params.SourceLanguageCode) == autoDetectLanguage {
+						output.SourceLanguageCode = aws.String(tt.detected)
+					}
+					return output, err
+				}
+			}
+			got, err := h.handle(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+		})
+	}
+}
+
+func TestCheckConfiguredLanguages(t *testing.T) {
+	previousPolicy, previousTargets, previousSource := configuredLanguagePolicy, githubTargetLanguages, githubSourceLanguage
+	defer func() {
+		configuredLanguagePolicy, githubTargetLanguages, githubSourceLanguage = previousPolicy, previousTargets, previousSource
+	}()
+
+	configuredLanguagePolicy = languagePolicy{allowedTargets: parseLanguageList("es,fr,de"), blocked: parseLanguageList("fr")}
+	githubSourceLanguage = "en"
+	githubTargetLanguages = []string{"es", "fr", "ja", "de"}
+
+	checkConfiguredLanguages()
+	if expected := []string{"es", "de"}; !slices.Equal(githubTargetLanguages, expected) {
+		t.Errorf("checkConfiguredLanguages() GitHub target languages = %v, expected %v", githubTargetLanguages, expected)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// supportedLanguagesHash is the cache table key of the item holding the languages
+	// supported by the provider
+	supportedLanguagesHash = "supported-languages"
+
+	// defaultSupportedLanguagesTTL is the number of seconds before the languages are refreshed
+	defaultSupportedLanguagesTTL = 24 * 60 * 60
+
+	// languagesResource is the API Gateway resource listing the supported target languages
+	languagesResource = "/languages"
+)
+
+// LanguagesResponse lists the target languages supported by the API
+type LanguagesResponse struct {
+	// Languages are the sorted codes of the supported target languages
+	Languages []string `json:"languages"`
+}
+
+// languageCache holds the supported languages of the container, shared by every container
+// through the cache table. Expired languages are still served while they are refreshed in
+// the background.
+type languageCache struct {
+	mu         sync.Mutex
+	languages  []string
+	refreshAt  time.Time
+	refreshing bool
+}
+
+// doesTargetLanguageExist reports whether the provider supports the target language
+func (h *handler) doesTargetLanguageExist(ctx context.Context, targetLanguage string) (bool, error) {
+	if h.sageMakerTargetLanguage(targetLanguage) {
+		return true, nil
+	}
+
+	languages, err := h.supportedLanguages(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Contains(languages, targetLanguage), nil
+}
+
+// supportedLanguages returns the languages supported by the provider from the container,
+// then the cache table, only calling the provider when neither has them
+func (h *handler) supportedLanguages(ctx context.Context) ([]string, error) {
+	h.languages.mu.Lock()
+	languages, refreshAt := h.languages.languages, h.languages.refreshAt
+	h.languages.mu.Unlock()
+
+	if languages == nil {
+		var err error
+		languages, refreshAt, err = h.loadSupportedLanguages(ctx)
+		if err != nil {
+			log.Printf("Error loading supported languages from the cache: %v", err)
+		}
+	}
+
+	if languages == nil {
+		return h.refreshSupportedLanguages(ctx)
+	}
+
+	h.languages.mu.Lock()
+	defer h.languages.mu.Unlock()
+	h.languages.languages, h.languages.refreshAt = languages, refreshAt
+
+	if time.Now().After(refreshAt) && !h.languages.refreshing {
+		h.languages.refreshing = true
+		go func() {
+			if _, err := h.refreshSupportedLanguages(context.WithoutCancel(ctx)); err != nil {
+				log.Printf("Error refreshing supported languages: %v", err)
+			}
+			h.languages.mu.Lock()
+			h.languages.refreshing = false
+			h.languages.mu.Unlock()
+		}()
+	}
+
+	return languages, nil
+}
+
+// refreshSupportedLanguages fetches the languages from the provider and caches them
+func (h *handler) refreshSupportedLanguages(ctx context.Context) ([]string, error) {
+	languages, err := getSupportedLanguages(ctx, h.translateClient)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshAt := time.Now().Add(time.Duration(supportedLanguagesTTL) * time.Second)
+	if err := h.storeSupportedLanguages(ctx, languages, refreshAt); err != nil {
+		log.Printf("Error caching supported languages: %v", err)
+	}
+
+	h.languages.mu.Lock()
+	h.languages.languages, h.languages.refreshAt = languages, refreshAt
+	h.languages.mu.Unlock()
+
+	return languages, nil
+}
+
+// loadSupportedLanguages reads the languages cached in the table, nil when there are none
+func (h *handler) loadSupportedLanguages(ctx context.Context) ([]string, time.Time, error) {
+	out, err := h.dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(translateTableName),
+		Key: map[string]types.AttributeValue{
+			"hash": &types.AttributeValueMemberS{Value: supportedLanguagesHash},
+		},
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	languages, ok := out.Item["languages"].(*types.AttributeValueMemberSS)
+	if !ok {
+		return nil, time.Time{}, nil
+	}
+
+	var refreshAt time.Time
+	if value, ok := out.Item["refresh_at"].(*types.AttributeValueMemberN); ok {
+		if seconds, err := strconv.ParseInt(value.Value, 10, 64); err == nil {
+			refreshAt = time.Unix(seconds, 0)
+		}
+	}
+
+	return languages.Value, refreshAt, nil
+}
+
+// storeSupportedLanguages caches the languages in the table. The item outlives its refresh
+// time by a second TTL so stale languages are still served while the provider is down.
+func (h *handler) storeSupportedLanguages(ctx context.Context, languages []string, refreshAt time.Time) error {
+	// String sets can't be empty
+	if len(languages) == 0 {
+		return fmt.Errorf("no languages to cache")
+	}
+
+	expiresAt := refreshAt.Add(time.Duration(supportedLanguagesTTL) * time.Second)
+	_, err := h.dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(translateTableName),
+		Item: map[string]types.AttributeValue{
+			"hash":       &types.AttributeValueMemberS{Value: supportedLanguagesHash},
+			"languages":  &types.AttributeValueMemberSS{Value: languages},
+			"refresh_at": &types.AttributeValueMemberN{Value: strconv.FormatInt(refreshAt.Unix(), 10)},
+			"expires_at": &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt.Unix(), 10)},
+		},
+	})
+	return err
+}
+
+// handleLanguagesRequest lists the languages supported by the provider and the language pairs
+// of our own model
+func (h *handler) handleLanguagesRequest(ctx context.Context) events.APIGatewayProxyResponse {
+	languages, err := h.supportedLanguages(ctx)
+	if response, ok := unavailableResponse(err); ok {
+		return response
+	}
+	if err != nil {
+		log.Printf("Error listing supported languages: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error listing supported languages",
+		}
+	}
+
+	languages = slices.Clone(languages)
+	if h.sageMakerClient != nil {
+		for pair := range sageMakerLanguagePairs {
+			_, targetLanguage, _ := strings.Cut(pair, ":")
+			languages = append(languages, targetLanguage)
+		}
+	}
+	languages = slices.DeleteFunc(languages, func(language string) bool {
+		return !configuredLanguagePolicy.allowsTarget(language)
+	})
+	slices.Sort(languages)
+
+	body, err := json.Marshal(LanguagesResponse{Languages: slices.Compact(languages)})
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error marshalling response",
+		}
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(body),
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+)
+
+func TestSupportedLanguages(t *testing.T) {
+	cachedItem := func(refreshAt time.Time) map[string]dynamoTypes.AttributeValue {
+		return map[string]dynamoTypes.AttributeValue{
+			"hash":       &dynamoTypes.AttributeValueMemberS{Value: supportedLanguagesHash},
+			"languages":  &dynamoTypes.AttributeValueMemberSS{Value: []string{"en", "fr"}},
+			"refresh_at": &dynamoTypes.AttributeValueMemberN{Value: strconv.FormatInt(refreshAt.Unix(), 10)},
+		}
+	}
+
+	tests := []struct {
+		name             string
+		memory           []string
+		item             map[string]dynamoTypes.AttributeValue
+		getError         error
+		expected         []string
+		expectedListCall bool
+		expectedStored   bool
+	}{
+		{
+			name:     "Container cache",
+			memory:   []string{"en", "de"},
+			expected: []string{"en", "de"},
+		},
+		{
+			name:     "Table cache",
+			item:     cachedItem(time.Now().Add(time.Hour)),
+			expected: []string{"en", "fr"},
+		},
+		{
+			name:             "Expired table cache is served while refreshing",
+			item:             cachedItem(time.Now().Add(-time.Hour)),
+			expected:         []string{"en", "fr"},
+			expectedListCall: true,
+			expectedStored:   true,
+		},
+		{
+			name:             "Table miss",
+			expected:         []string{"en", "es"},
+			expectedListCall: true,
+			expectedStored:   true,
+		},
+		{
+			name:             "Table error",
+			getError:         fmt.Errorf("mock error"),
+			expected:         []string{"en", "es"},
+			expectedListCall: true,
+			expectedStored:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var listCalls, stores atomic.Int32
+
+			h := newMockHandler(nil)
+			h.languages.languages = tt.memory
+			h.languages.refreshAt = time.Now().Add(time.Hour)
+			h.dynamoClient = &MockDynamoDBClient{
+				GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					return &dynamodb.GetItemOutput{Item: tt.item}, tt.getError
+				},
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					languages, ok := params.Item["languages"].(*dynamoTypes.AttributeValueMemberSS)
+					if !ok || !slices.Equal(languages.Value, []string{"en", "es"}) {
+						t.Errorf("PutItem() languages = %v, expected [en es]", params.Item["languages"])
+					}
+					stores.Add(1)
+					return &dynamodb.PutItemOutput{}, nil
+				},
+			}
+			h.translateClient = &MockTranslateClient{
+				ListLanguagesFunc: func(ctx context.Context, params *translate.ListLanguagesInput, optFns ...func(*translate.Options)) (*translate.ListLanguagesOutput, error) {
+					listCalls.Add(1)
+					return &translate.ListLanguagesOutput{
+						Languages: []types.Language{
+							{LanguageCode: aws.String("en")},
+							{LanguageCode: aws.String("es")},
+						},
+					}, nil
+				},
+			}
+
+			got, err := h.supportedLanguages(context.Background())
+			if err != nil {
+				t.Errorf("supportedLanguages() error = %v", err)
+				return
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("supportedLanguages() = %v, expected %v", got, tt.expected)
+			}
+
+			// Wait for a background refresh to finish
+			for range 100 {
+				h.languages.mu.Lock()
+				refreshing := h.languages.refreshing
+				h.languages.mu.Unlock()
+				if !refreshing {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			if (listCalls.Load() > 0) != tt.expectedListCall || (stores.Load() > 0) != tt.expectedStored {
+				t.Errorf("supportedLanguages() listed = %d, stored = %d, expected listed %v, stored %v", listCalls.Load(), stores.Load(), tt.expectedListCall, tt.expectedStored)
+			}
+		})
+	}
+}
+
+func TestHandleLanguages(t *testing.T) {
+	original := sageMakerLanguagePairs
+	sageMakerLanguagePairs = parseLanguagePairs("en:sw, en:es")
+	defer func() { sageMakerLanguagePairs = original }()
+
+	tests := []struct {
+		name         string
+		sageMaker    bool
+		listError    error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "Provider languages",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"languages":["en","es"]}`,
+		},
+		{
+			name:         "Model languages",
+			sageMaker:    true,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"languages":["en","es","sw"]}`,
+		},
+		{
+			name:         "Provider error",
+			listError:    fmt.Errorf("mock error"),
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "Error listing supported languages",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(nil)
+			if tt.listError != nil {
+				h.translateClient.(*MockTranslateClient).ListLanguagesFunc = func(ctx context.Context, params *translate.ListLanguagesInput, optFns ...func(*translate.Options)) (*translate.ListLanguagesOutput, error) {
+					return nil, tt.listError
+				}
+			}
+			if tt.sageMaker {
+				h.sageMakerClient = &MockTranslateClient{}
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Resource: languagesResource})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedCode || got.Body != tt.expectedBody {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedCode, tt.expectedBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// localizedAmount matches an English formatted amount, with or without thousands separators
+const localizedAmount = `\d{1,3}(?:,\d{3})+(?:\.\d+)?|\d+(?:\.\d+)?`
+
+// localizedCurrencyPattern matches an amount with a currency symbol or ISO code before it, or
+// an ISO code after it
+var localizedCurrencyPattern = regexp.MustCompile(`(?:([$€£¥])\s?|\b([A-Z]{3})\s)(` + localizedAmount + `)\b|\b(` + localizedAmount + `)\s([A-Z]{3})\b`)
+
+// localizedDatePattern matches ISO 8601 dates and US month/day/year dates
+var localizedDatePattern = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b|\b\d{1,2}/\d{1,2}/\d{4}\b`)
+
+// localizedNumberPattern matches the numbers localized for the target language, those with a
+// thousands or decimal separator. Bare integers are often years or identifiers and are left
+// alone.
+var localizedNumberPattern = regexp.MustCompile(`\b\d{1,3}(?:,\d{3})+(?:\.\d+)?\b|\b\d+\.\d+\b`)
+
+// currencySymbols are the currencies of the symbols of English text
+var currencySymbols = map[string]currency.Unit{
+	"$": currency.USD,
+	"€": currency.EUR,
+	"£": currency.GBP,
+	"¥": currency.JPY,
+}
+
+// shortDateLayouts are the numeric CLDR short date patterns of the target languages, with four
+// digit years, by language tag or base language. golang.org/x/text has no date formatting, the
+// dates of other languages are kept as they are.
+var shortDateLayouts = map[string]string{
+	"en":    "1/2/2006",
+	"en-GB": "02/01/2006",
+	"en-AU": "02/01/2006",
+	"en-CA": "2006-01-02",
+	"de":    "02.01.2006",
+	"fr":    "02/01/2006",
+	"fr-CA": "2006-01-02",
+	"es":    "2/1/2006",
+	"it":    "02/01/2006",
+	"pt":    "02/01/2006",
+	"nl":    "02-01-2006",
+	"pl":    "02.01.2006",
+	"ru":    "02.01.2006",
+	"uk":    "02.01.2006",
+	"tr":    "02.01.2006",
+	"sv":    "2006-01-02",
+	"da":    "02.01.2006",
+	"nb":    "02.01.2006",
+	"fi":    "2.1.2006",
+	"ja":    "2006/01/02",
+	"zh":    "2006/1/2",
+	"ko":    "2006. 1. 2.",
+}
+
+type localeFormattingKey struct{}
+
+// withLocaleFormatting returns a context whose translated numbers, dates and currency amounts
+// are formatted for the target language, unchanged unless enabled
+func withLocaleFormatting(ctx context.Context, enabled bool) context.Context {
+	if !enabled {
+		return ctx
+	}
+	return context.WithValue(ctx, localeFormattingKey{}, true)
+}
+
+// localeFormattingFromContext reports whether the formats of the request are localized
+func localeFormattingFromContext(ctx context.Context) bool {
+	enabled, _ := ctx.Value(localeFormattingKey{}).(bool)
+	return enabled
+}
+
+// localizedValue is a value of a source sentence and its format in the target language
+type localizedValue struct {
+	start, end int
+	localized  string
+}
+
+// localizeFormats reformats the currency amounts, dates and numbers of each source sentence
+// kept verbatim in its translation with the conventions of the target language. Only English
+// sources are localized, the separators of other languages being ambiguous.
+func localizeFormats(sourceLanguage, targetLanguage string, sources, translations []string) {
+	if base, _ := language.Make(sourceLanguage).Base(); base.String() != "en" || sourceLanguage == targetLanguage {
+		return
+	}
+	target := language.Make(targetLanguage)
+	printer := message.NewPrinter(target)
+
+	for i, source := range sources {
+		for _, value := range localizedValues(printer, target, source) {
+			translations[i] = strings.Replace(translations[i], source[value.start:value.end], value.localized, 1)
+		}
+	}
+}
+
+// localizedValues finds the values of the source sentence, a currency amount taking
+// precedence over the date or number it overlaps
+func localizedValues(printer *message.Printer, target language.Tag, source string) []localizedValue {
+	var values []localizedValue
+	overlaps := func(start, end int) bool {
+		for _, value := range values {
+			if start < value.end && value.start < end {
+				return true
+			}
+		}
+		return false
+	}
+	add := func(start, end int, localized string, ok bool) {
+		if ok && !overlaps(start, end) {
+			values = append(values, localizedValue{start: start, end: end, localized: localized})
+		}
+	}
+
+	for _, match := range localizedCurrencyPattern.FindAllStringSubmatchIndex(source, -1) {
+		localized, ok := localizeCurrency(printer, source, match)
+		add(match[0], match[1], localized, ok)
+	}
+	for _, match := range localizedDatePattern.FindAllStringIndex(source, -1) {
+		localized, ok := localizeDate(target, source[match[0]:match[1]])
+		add(match[0], match[1], localized, ok)
+	}
+	for _, match := range localizedNumberPattern.FindAllStringIndex(source, -1) {
+		// Version numbers and dates such as 1.2.3 and 15.01.2024 aren't numbers
+		if isDottedSequence(source, match[0], match[1]) {
+			continue
+		}
+		localized, ok := localizeNumber(printer, source[match[0]:match[1]])
+		add(match[0], match[1], localized, ok)
+	}
+	return values
+}
+
+// localizeCurrency formats the currency amount of the match in the target language
+func localizeCurrency(printer *message.Printer, source string, match []int) (string, bool) {
+	group := func(n int) string {
+		if match[2*n] < 0 {
+			return ""
+		}
+		return source[match[2*n]:match[2*n+1]]
+	}
+
+	unit, ok := currencySymbols[group(1)]
+	amount := group(3)
+	if !ok {
+		code := group(2)
+		if code == "" {
+			code, amount = group(5), group(4)
+		}
+		var err error
+		if unit, err = currency.ParseISO(code); err != nil {
+			return "", false
+		}
+	}
+
+	value, err := parseEnglishNumber(amount)
+	if err != nil {
+		return "", false
+	}
+	return printer.Sprint(currency.Symbol(unit.Amount(value))), true
+}
+
+// localizeDate formats the date with the short date pattern of the target language
+func localizeDate(target language.Tag, text string) (string, bool) {
+	layout, ok := shortDateLayouts[target.String()]
+	if !ok {
+		base, _ := target.Base()
+		if layout, ok = shortDateLayouts[base.String()]; !ok {
+			return "", false
+		}
+	}
+
+	date, err := time.Parse("2006-01-02", text)
+	if err != nil {
+		if date, err = time.Parse("1/2/2006", text); err != nil {
+			return "", false
+		}
+	}
+	return date.Format(layout), true
+}
+
+// localizeNumber formats the number with the separators of the target language, keeping its
+// decimals
+func localizeNumber(printer *message.Printer, text string) (string, bool) {
+	value, err := parseEnglishNumber(text)
+	if err != nil {
+		return "", false
+	}
+	decimals := 0
+	if _, fraction, ok := strings.Cut(text, "."); ok {
+		decimals = len(fraction)
+	}
+	return printer.Sprint(number.Decimal(value, number.Scale(decimals))), true
+}
+
+// parseEnglishNumber parses a number with English thousands and decimal separators
+func parseEnglishNumber(text string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(text, ",", ""), 64)
+}
+
+// isDottedSequence reports whether the number at the span is part of a longer sequence of
+// dot separated digits
+func isDottedSequence(text string, start, end int) bool {
+	isDigit := func(i int) bool { return i >= 0 && i < len(text) && text[i] >= '0' && text[i] <= '9' }
+	return (start >= 2 && text[start-1] == '.' && isDigit(start-2)) ||
+		(end+1 < len(text) && text[end] == '.' && isDigit(end+1))
+}
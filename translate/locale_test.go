@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestLocalizeFormats(t *testing.T) {
+	tests := []struct {
+		name           string
+		sourceLanguage string
+		targetLanguage string
+		source         string
+		translation    string
+		expected       string
+	}{
+		{
+			name:           "Numbers",
+			sourceLanguage: "en",
+			targetLanguage: "de",
+			source:         "It weighs 1,250.5 kg and ships in 3 boxes.",
+			translation:    "Es wiegt 1,250.5 kg und wird in 3 Kartons versandt.",
+			expected:       "Es wiegt 1.250,5 kg und wird in 3 Kartons versandt.",
+		},
+		{
+			name:           "Currency amounts",
+			sourceLanguage: "en",
+			targetLanguage: "de",
+			source:         "The total is $1,234.5 plus 20 EUR shipping.",
+			translation:    "Die Summe beträgt $1,234.5 plus 20 EUR Versand.",
+			expected:       "Die Summe beträgt $ 1.234,50 plus € 20,00 Versand.",
+		},
+		{
+			name:           "Dates",
+			sourceLanguage: "en",
+			targetLanguage: "de",
+			source:         "Invoice of 2024-01-15, due 2/14/2024.",
+			translation:    "Rechnung vom 2024-01-15, fällig am 2/14/2024.",
+			expected:       "Rechnung vom 15.01.2024, fällig am 14.02.2024.",
+		},
+		{
+			name:           "Regional date pattern",
+			sourceLanguage: "en",
+			targetLanguage: "en-GB",
+			source:         "Due 2/14/2024.",
+			translation:    "Due 2/14/2024.",
+			expected:       "Due 14/02/2024.",
+		},
+		{
+			name:           "Versions, dotted dates and years",
+			sourceLanguage: "en",
+			targetLanguage: "de",
+			source:         "Version 1.2.3 from 15.01.2024 in 2024.",
+			translation:    "Version 1.2.3 vom 15.01.2024 im Jahr 2024.",
+			expected:       "Version 1.2.3 vom 15.01.2024 im Jahr 2024.",
+		},
+		{
+			name:           "Date pattern unknown",
+			sourceLanguage: "en",
+			targetLanguage: "sw",
+			source:         "Due 2024-01-15.",
+			translation:    "Tarehe 2024-01-15.",
+			expected:       "Tarehe 2024-01-15.",
+		},
+		{
+			name:           "Ambiguous source format",
+			sourceLanguage: "de",
+			targetLanguage: "en",
+			source:         "Es wiegt 1.250 kg.",
+			translation:    "It weighs 1.250 kg.",
+			expected:       "It weighs 1.250 kg.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			translations := []string{tt.translation}
+			localizeFormats(tt.sourceLanguage, tt.targetLanguage, []string{tt.source}, translations)
+			if translations[0] != tt.expected {
+				t.Errorf("localizeFormats() = %q, expected %q", translations[0], tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandleLocalizeFormats(t *testing.T) {
+	h := newMockHandler(map[string]string{"Total: {@0} on {@1}.": "Total: {@0} el {@1}."})
+
+	got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+		Body: `{"source_language":"en","target_language":"es","text":"Total: 1,234.56 on 2024-01-15.","localize_formats":true}`,
+	})
+	if err != nil {
+		t.Fatalf("handle() error = %v", err)
+	}
+
+	expected := `{"translated_text":"Total: 1.234,56 el 15/1/2024. "}`
+	if got.StatusCode != http.StatusOK || got.Body != expected {
+		t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, http.StatusOK, expected)
+	}
+}
@@ -4,29 +4,103 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/comprehend"
+	comprehendTypes "github.com/aws/aws-sdk-go-v2/service/comprehend/types"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/textract"
 	"github.com/aws/aws-sdk-go-v2/service/translate"
-	"github.com/aws/aws-xray-sdk-go/instrumentation/awsv2"
 	jsoniter "github.com/json-iterator/go"
-	"github.com/sentencizer/sentencizer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"golang.org/x/sync/errgroup"
 )
 
+// The configuration is read from the environment by loadEnvConfig rather than initialized
+// with the package, so a restored snapshot re-reads it
 var (
-	translateTableName = os.Getenv("TRANSLATE_TABLE_NAME")
-	region             = os.Getenv("AWS_REGION")
+	translateTableName string
+	region             string
+	handlerMode        string
+
+	// regionTableNames maps regions to their cache table name, formatted as region=table,region=table
+	regionTableNames map[string]string
+
+	inboundEmailBucket    string
+	inboundEmailPrefix    string
+	translatedEmailPrefix string
+	emailTargetLanguage   string
+
+	// bulkBucket holds the CSV files and documents translated by key and their translations
+	bulkBucket string
+	// documentChunkSize is the size of the chunks documents are split into by the state machine
+	documentChunkSize int
+
+	cacheCompression       string
+	cacheOverflowBucket    string
+	cacheOverflowThreshold int
+	negativeCacheTTL       int
+	maxRequestSentences    int
+	supportedLanguagesTTL  int
+
+	// cacheWriteQueueURL is the queue new cache items are published to for the cache writer
+	// function, empty to write them directly
+	cacheWriteQueueURL string
+	// cacheReverseEntries also caches each translation in the opposite direction, so the
+	// reply to a translated message is a cache hit
+	cacheReverseEntries bool
+	// cacheHitTracking counts the lookups served by each cache item, at the cost of a write
+	// per hit
+	cacheHitTracking bool
+
+	// streamTextField is the field of the Kinesis records translated to streamTargetLanguage
+	// into streamTranslatedField, nested fields are separated by dots. The records are
+	// written to the streamOutputName stream and the streamDeliveryStreamName Firehose.
+	streamTextField          string
+	streamTranslatedField    string
+	streamSourceLanguage     string
+	streamTargetLanguage     string
+	streamOutputName         string
+	streamDeliveryStreamName string
+
+	breakerFailurePercent int
+	breakerMinRequests    int
+	breakerOpenSeconds    int
+
+	// degradedMode serves cached translations only, either "auto" while the provider is
+	// unavailable or "force", empty to fail requests instead
+	degradedMode string
+
+	metricsExporter  string
+	metricsNamespace string
+	tracesExporter   string
+
+	// shadowTranslateRegion is the region of the provider evaluated on a sample of live
+	// traffic, shadowPercent is the percentage of segments sent to it
+	shadowTranslateRegion string
+	shadowPercent         int
+	// shadowLogText logs both translations of a shadow comparison, only their hashes otherwise
+	shadowLogText bool
 
 	json = jsoniter.ConfigCompatibleWithStandardLibrary
 )
@@ -34,15 +108,220 @@ var (
 const (
 	defaultTranslateTableName = "TranslateCache"
 	defaultAWSRegion          = "us-east-1"
+
+	defaultTranslatedEmailPrefix = "translated/"
+	defaultEmailTargetLanguage   = "en"
+
+	defaultStreamTextField      = "text"
+	defaultStreamTargetLanguage = "en"
+
+	// defaultCacheOverflowThreshold leaves headroom under the 400 KB DynamoDB item limit
+	defaultCacheOverflowThreshold = 350 * 1024
+	// defaultNegativeCacheTTL is the number of seconds a failed translation is remembered
+	defaultNegativeCacheTTL = 15 * 60
+
+	defaultBreakerFailurePercent = 50
+	defaultBreakerMinRequests    = 10
+	defaultBreakerOpenSeconds    = 30
+
+	// autoDetectLanguage lets AWS Translate detect the source language
+	autoDetectLanguage = "auto"
+	// maxDetectionSampleLength is the number of characters used to detect a language
+	maxDetectionSampleLength = 500
 )
 
 func init() {
+	loadEnvConfig()
+}
+
+// loadEnvConfig reads the configuration from the environment and applies its defaults
+func loadEnvConfig() {
+	translateTableName = os.Getenv("TRANSLATE_TABLE_NAME")
+	region = os.Getenv("AWS_REGION")
+	handlerMode = os.Getenv("HANDLER_MODE")
+	regionTableNames = parseRegionTableNames(os.Getenv("TRANSLATE_TABLE_REGIONS"))
+
+	inboundEmailBucket = os.Getenv("INBOUND_EMAIL_BUCKET")
+	inboundEmailPrefix = os.Getenv("INBOUND_EMAIL_PREFIX")
+	translatedEmailPrefix = os.Getenv("TRANSLATED_EMAIL_PREFIX")
+	emailTargetLanguage = os.Getenv("EMAIL_TARGET_LANGUAGE")
+
+	bulkBucket = os.Getenv("BULK_BUCKET")
+	documentChunkSize = getEnvInt("DOCUMENT_CHUNK_SIZE", defaultDocumentChunkSize)
+	crawlConcurrency = getEnvInt("CRAWL_CONCURRENCY", defaultCrawlConcurrency)
+	crawlMaxPages = getEnvInt("CRAWL_MAX_PAGES", defaultCrawlMaxPages)
+
+	cacheCompression = os.Getenv("CACHE_COMPRESSION")
+	cacheSourceRetention = os.Getenv("CACHE_SOURCE_RETENTION")
+	cacheSourceTruncateLength = getEnvInt("CACHE_SOURCE_TRUNCATE_LENGTH", defaultCacheSourceTruncateLength)
+	cacheOverflowBucket = os.Getenv("CACHE_OVERFLOW_BUCKET")
+	cacheOverflowThreshold = getEnvInt("CACHE_OVERFLOW_THRESHOLD", defaultCacheOverflowThreshold)
+	negativeCacheTTL = getEnvInt("NEGATIVE_CACHE_TTL", defaultNegativeCacheTTL)
+	responseCacheTTL = getEnvInt("RESPONSE_CACHE_TTL", 0)
+	documentTableName = os.Getenv("DOCUMENT_TABLE_NAME")
+	postEditTableName = os.Getenv("POST_EDIT_TABLE_NAME")
+	feedbackTableName = os.Getenv("FEEDBACK_TABLE_NAME")
+
+	corsAllowedOrigins = parseCORSOrigins(os.Getenv("CORS_ALLOWED_ORIGINS"))
+	corsAllowedHeaders = os.Getenv("CORS_ALLOWED_HEADERS")
+	corsMaxAge = getEnvInt("CORS_MAX_AGE", defaultCORSMaxAge)
+
+	claimsAuthorization = os.Getenv("CLAIMS_AUTHORIZATION") == "true"
+	tenantClaim = os.Getenv("TENANT_CLAIM")
+	languagePairsClaim = os.Getenv("LANGUAGE_PAIRS_CLAIM")
+	defaultTargetLanguageClaim = os.Getenv("DEFAULT_TARGET_LANGUAGE_CLAIM")
+
+	githubWebhookSecret = os.Getenv("GITHUB_WEBHOOK_SECRET")
+	githubToken = os.Getenv("GITHUB_TOKEN")
+	githubAPIURL = os.Getenv("GITHUB_API_URL")
+	githubSourceLanguage = os.Getenv("GITHUB_SOURCE_LANGUAGE")
+	githubTargetLanguages = parseLanguageCodes(os.Getenv("GITHUB_TARGET_LANGUAGES"))
+	githubGlossary = os.Getenv("GITHUB_GLOSSARY")
+	maxRequestSentences = getEnvInt("MAX_REQUEST_SENTENCES", defaultMaxRequestSentences)
+	supportedLanguagesTTL = getEnvInt("SUPPORTED_LANGUAGES_TTL", defaultSupportedLanguagesTTL)
+	cacheWriteQueueURL = os.Getenv("CACHE_WRITE_QUEUE_URL")
+	cacheReverseEntries = os.Getenv("CACHE_REVERSE_ENTRIES") == "true"
+	cacheHitTracking = os.Getenv("CACHE_HIT_TRACKING") != "false"
+
+	streamTextField = os.Getenv("STREAM_TEXT_FIELD")
+	streamTranslatedField = os.Getenv("STREAM_TRANSLATED_FIELD")
+	streamSourceLanguage = os.Getenv("STREAM_SOURCE_LANGUAGE")
+	streamTargetLanguage = os.Getenv("STREAM_TARGET_LANGUAGE")
+	streamOutputName = os.Getenv("STREAM_OUTPUT_NAME")
+	streamDeliveryStreamName = os.Getenv("STREAM_DELIVERY_STREAM_NAME")
+	auditDeliveryStreamName = os.Getenv("AUDIT_DELIVERY_STREAM_NAME")
+	auditIncludeText = os.Getenv("AUDIT_INCLUDE_TEXT") == "true"
+
+	breakerFailurePercent = getEnvInt("BREAKER_FAILURE_PERCENT", defaultBreakerFailurePercent)
+	breakerMinRequests = getEnvInt("BREAKER_MIN_REQUESTS", defaultBreakerMinRequests)
+	breakerOpenSeconds = getEnvInt("BREAKER_OPEN_SECONDS", defaultBreakerOpenSeconds)
+	batchConcurrency = getEnvInt("BATCH_CONCURRENCY", defaultBatchConcurrency)
+	providerConcurrency = getEnvInt("PROVIDER_CONCURRENCY", defaultProviderConcurrency)
+	interactiveTPS = getEnvInt("INTERACTIVE_TPS", 0)
+	bulkTPS = getEnvInt("BULK_TPS", 0)
+	degradedMode = os.Getenv("DEGRADED_MODE")
+	offlineFallback = os.Getenv("OFFLINE_FALLBACK") != "false"
+	tokenShielding = os.Getenv("TOKEN_SHIELDING") != "false"
+	entityProtection = os.Getenv("ENTITY_PROTECTION") == "true"
+
+	metricsExporter = os.Getenv("METRICS_EXPORTER")
+	metricsNamespace = os.Getenv("METRICS_NAMESPACE")
+	tracesExporter = os.Getenv("TRACES_EXPORTER")
+
+	shadowTranslateRegion = os.Getenv("SHADOW_TRANSLATE_REGION")
+	shadowPercent = getEnvInt("SHADOW_PERCENT", 0)
+	shadowLogText = os.Getenv("SHADOW_LOG_TEXT") == "true"
+	translatePrice = getEnvFloat("TRANSLATE_PRICE_PER_MILLION_CHARACTERS", defaultTranslatePrice)
+	maxRequestCharacters = getEnvInt("MAX_REQUEST_CHARACTERS", 0)
+	failoverTranslateRegion = os.Getenv("FAILOVER_TRANSLATE_REGION")
+
+	moderationKeywords = parseModerationKeywords(os.Getenv("MODERATION_KEYWORDS"))
+	noStorePattern = parseNoStorePattern(os.Getenv("NO_STORE_PATTERN"))
+	noStoreKeywords = parseNoStoreKeywords(os.Getenv("NO_STORE_KEYWORDS"))
+	configuredTypography = parseTypographyConfig(os.Getenv("TYPOGRAPHY"))
+	inclusiveTerminologies = parseRegionTableNames(os.Getenv("INCLUSIVE_TERMINOLOGIES"))
+	segmenters = parseSegmenters(os.Getenv("SEGMENTERS"))
+	documentWindowBytes = windowBytes(getEnvInt("SEGMENT_MEMORY_MB", defaultSegmentMemoryMB))
+	spillDirectory = os.Getenv("SPILL_DIRECTORY")
+	serviceEndpoints = parseServiceEndpoints(os.Getenv("SERVICE_ENDPOINTS"))
+	caBundleSource = os.Getenv("TLS_CA_BUNDLE")
+	tlsMinVersion = parseTLSVersion(os.Getenv("TLS_MIN_VERSION"))
+	configuredLanguagePolicy = languagePolicy{
+		allowedTargets: parseLanguageList(os.Getenv("ALLOWED_TARGET_LANGUAGES")),
+		blocked:        parseLanguageList(os.Getenv("BLOCKED_LANGUAGES")),
+	}
+
+	sageMakerEndpointName = os.Getenv("SAGEMAKER_ENDPOINT_NAME")
+	sageMakerModelFormat = os.Getenv("SAGEMAKER_MODEL_FORMAT")
+	sageMakerLanguagePairs = parseLanguagePairs(os.Getenv("SAGEMAKER_LANGUAGE_PAIRS"))
+	sageMakerLanguageCodes = parseRegionTableNames(os.Getenv("SAGEMAKER_LANGUAGE_CODES"))
+	pivotLanguage = os.Getenv("PIVOT_LANGUAGE")
+	deepLAuthKey = os.Getenv("DEEPL_AUTH_KEY")
+	deepLAPIURL = os.Getenv("DEEPL_API_URL")
+	grammarCheckURL = os.Getenv("GRAMMAR_CHECK_URL")
+	grammarCheckUsername = os.Getenv("GRAMMAR_CHECK_USERNAME")
+	grammarCheckAPIKey = os.Getenv("GRAMMAR_CHECK_API_KEY")
+	routingRules = parseRoutingRules(os.Getenv("ROUTING_RULES"))
+	routingTableName = os.Getenv("ROUTING_TABLE_NAME")
+	routingTableTTL = getEnvInt("ROUTING_TABLE_TTL", defaultRoutingTableTTL)
+	disclaimerTableName = os.Getenv("DISCLAIMER_TABLE_NAME")
+	disclaimerTableTTL = getEnvInt("DISCLAIMER_TABLE_TTL", defaultDisclaimerTableTTL)
+
 	if translateTableName == "" {
 		translateTableName = defaultTranslateTableName
 	}
 	if region == "" {
 		region = defaultAWSRegion
 	}
+	if tenantClaim == "" {
+		tenantClaim = defaultTenantClaim
+	}
+	if languagePairsClaim == "" {
+		languagePairsClaim = defaultLanguagePairsClaim
+	}
+	if defaultTargetLanguageClaim == "" {
+		defaultTargetLanguageClaim = defaultDefaultTargetLanguageClaim
+	}
+	if corsAllowedHeaders == "" {
+		corsAllowedHeaders = defaultCORSAllowedHeaders
+	}
+	if githubAPIURL == "" {
+		githubAPIURL = defaultGitHubAPIURL
+	}
+	if githubSourceLanguage == "" {
+		githubSourceLanguage = defaultGitHubSourceLanguage
+	}
+	if metricsNamespace == "" {
+		metricsNamespace = defaultMetricsNamespace
+	}
+	if pivotLanguage == "" {
+		pivotLanguage = defaultPivotLanguage
+	}
+	if tableName, ok := regionTableNames[region]; ok {
+		translateTableName = tableName
+	}
+	if translatedEmailPrefix == "" {
+		translatedEmailPrefix = defaultTranslatedEmailPrefix
+	}
+	if emailTargetLanguage == "" {
+		emailTargetLanguage = defaultEmailTargetLanguage
+	}
+	if streamTextField == "" {
+		streamTextField = defaultStreamTextField
+	}
+	if streamTranslatedField == "" {
+		streamTranslatedField = streamTextField + translatedFieldSuffix
+	}
+	if streamSourceLanguage == "" {
+		streamSourceLanguage = autoDetectLanguage
+	}
+	if streamTargetLanguage == "" {
+		streamTargetLanguage = defaultStreamTargetLanguage
+	}
+	if sageMakerModelFormat == "" {
+		sageMakerModelFormat = sageMakerFormatNLLB
+	}
+	checkConfiguredLanguages()
+}
+
+// getEnvInt reads an integer environment variable, falling back to the default when it is
+// missing or invalid
+func getEnvInt(name string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvFloat reads a decimal environment variable, falling back to the default when it is
+// missing or invalid
+func getEnvFloat(name string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(name), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
 }
 
 // TranslateRequest represents the request structure for the translation API
@@ -53,6 +332,110 @@ type TranslateRequest struct {
 	TargetLanguage string `json:"target_language"`
 	// Text is the text to be translated
 	Text string `json:"text"`
+	// Format is the format of the input, one of "text" (default), "html", "pdf", "email",
+	// "plural", "android_strings", "ios_strings", "ios_stringsdict" or "csv"
+	Format string `json:"format"`
+	// Document is the base64 encoded document for non-text formats
+	Document string `json:"document"`
+	// Output is the shape of the translated document, either "text" (default) or "blocks"
+	Output string `json:"output"`
+	// Moderation checks the translated text for profanity and hate speech, either "flag"
+	// to report it or "mask" to also mask it, empty to disable
+	Moderation string `json:"moderation"`
+	// Transliterate romanizes the text instead of translating it, for names and addresses
+	Transliterate bool `json:"transliterate"`
+	// Style is the tone of the translation, one of "technical", "marketing", "casual" or
+	// "legal", empty for the provider's default
+	Style string `json:"style"`
+	// NoStore reads the translations from the cache without caching the new ones, for
+	// sensitive content
+	NoStore bool `json:"no_store"`
+	// Plurals are the English "one" and "other" forms of a resource string for the "plural" format
+	Plurals map[string]string `json:"plurals"`
+	// PluralPlaceholder is the count placeholder of the plural forms, "{count}" by default
+	PluralPlaceholder string `json:"plural_placeholder"`
+	// Columns are the header names of the columns translated by the "csv" format
+	Columns []string `json:"columns"`
+	// Key is the key of a CSV file in the bulk bucket, used instead of the document for files
+	// too large for a request
+	Key string `json:"key"`
+	// Version is the API version of the response shape, "1" by default. The version of an
+	// application/vnd.gotranslate media type in the Accept header takes precedence.
+	Version string `json:"version"`
+	// DryRun looks the text up in the cache without translating the misses, estimating the
+	// cost of translating them instead, for the "text", "html" and "csv" formats
+	DryRun bool `json:"dry_run"`
+	// MaxCharacters caps the characters translated by the provider after the cache lookups,
+	// rejecting the request with its cost estimate when exceeded. The lower of it and the
+	// configured cap applies.
+	MaxCharacters int `json:"max_characters"`
+	// Alignment returns the source sentences aligned with their translations and their
+	// offsets, for the "text" format
+	Alignment bool `json:"alignment"`
+	// LocalizeFormats formats the numbers, dates and currency amounts of English text with the
+	// conventions of the target language rather than keeping them verbatim
+	LocalizeFormats bool `json:"localize_formats"`
+	// Typography normalizes the quotation marks, dashes and ellipses of the translation, such
+	// as quotes=typographic,dashes=ascii. Each option is either "ascii" for straight quotes, --
+	// and ... or "typographic" for the conventions of the target language, the configured
+	// typography applying to the options not given.
+	Typography string `json:"typography"`
+	// PreserveCasing applies the casing of source sentences in all caps, title case or
+	// sentence case to their translations, such as button labels
+	PreserveCasing bool `json:"preserve_casing"`
+	// MaxLength is the maximum number of characters of the translation of the "text" format
+	// and of each string of the resource formats, such as UI labels. A translation too long is
+	// translated again with the brevity setting of AWS Translate, then truncated.
+	MaxLength int `json:"max_length"`
+	// Truncation is how a translation still too long is cut, "ellipsis" (default) at a word
+	// boundary followed by an ellipsis, "hard" at the maximum length, or "none" to keep it
+	Truncation string `json:"truncation"`
+	// Inclusive phrases the translation gender-neutrally with the inclusive terminology of the
+	// target language, for the languages that have one
+	Inclusive bool `json:"inclusive"`
+	// Alternatives is the number of alternative translations of each sentence returned along
+	// the translation for reviewers to pick from, up to 5, for the "text" format
+	Alternatives int `json:"alternatives"`
+	// Analyze detects the sentiment and the named entities of the source text of text and
+	// HTML requests with Comprehend, returned along the translation
+	Analyze bool `json:"analyze"`
+	// TranslateEntities translates the names of people, organizations and products detected
+	// in the source text rather than keeping them verbatim
+	TranslateEntities bool `json:"translate_entities"`
+	// GrammarCheck reports the likely problems the grammar check finds in each translated
+	// segment, without applying its fixes
+	GrammarCheck bool `json:"grammar_check"`
+	// EchoParameters returns the parameters the text or html request was translated with,
+	// after its languages were normalized or detected and its options defaulted
+	EchoParameters bool `json:"echo_parameters"`
+	// LanguageCodeCase is the casing of the language codes of the response: "canonical" for
+	// the BCP 47 casing such as pt-BR, the default, "lower" or "upper"
+	LanguageCodeCase string `json:"language_code_case"`
+	// TranslateMetadata also translates the <title> and the description, Open Graph and
+	// Twitter card <meta> tags of HTML documents
+	TranslateMetadata bool `json:"translate_metadata"`
+	// Priority is "interactive" (default) for user-facing requests, whose provider calls go
+	// before those of "bulk" requests such as batch items
+	Priority string `json:"priority"`
+	// DeadlineMS is the time in milliseconds the request should be answered in. No segment is
+	// sent to the provider once it nears, the segments not translated by then are served
+	// untranslated and reported as timed out.
+	DeadlineMS int `json:"deadline_ms"`
+	// Granularity is the chunk of text translated at once: "sentence" (default) for the most
+	// reuse of the cached sentences, "paragraph" or "block" for the whole text or text node,
+	// which give the provider more context in fewer calls
+	Granularity string `json:"granularity"`
+	// Disclaimer adds a localized machine translation disclaimer to the "text", "html", "pdf"
+	// or "email" translation: "prepend" or "append". The disclaimer templates of the tenant of
+	// the caller may add it to every translation, refusing the formats it can't be added to.
+	Disclaimer string `json:"disclaimer"`
+	// URL is the web page translated by POST /translate/url, fetched by the function and
+	// translated as HTML
+	URL string `json:"url"`
+	// PreviousText and PreviousTranslation are an earlier version of the text and its
+	// translation. Only the sentences changed since are translated, for the "text" format.
+	PreviousText        string `json:"previous_text"`
+	PreviousTranslation string `json:"previous_translation"`
 }
 
 // TranslateResponse represents the response structure for the translation API
@@ -63,6 +446,47 @@ type TranslateResponse struct {
 	DetectedLanguage string `json:"detected_language,omitempty"`
 	// TranslationConfidence is the confidence score of the translation
 	TranslationConfidence float64 `json:"translation_confidence,omitempty"`
+	// Blocks are the source and translated text blocks of a document
+	Blocks []TranslatedBlock `json:"blocks,omitempty"`
+	// Skipped is set when the source language is the target language and the text is returned as is
+	Skipped bool `json:"skipped,omitempty"`
+	// Degraded is set when some text was left untranslated because the provider was unavailable
+	Degraded bool `json:"degraded,omitempty"`
+	// TimedOut is set when some segments were left untranslated to answer by the deadline of
+	// the request, each reported in the segments
+	TimedOut bool `json:"timed_out,omitempty"`
+	// LowQuality is set when some short strings were translated by the offline fallback
+	// because the provider was unavailable
+	LowQuality bool `json:"low_quality,omitempty"`
+	// Pivoted is set when some text was translated through the pivot language because the
+	// provider can't translate the language pair directly
+	Pivoted bool `json:"pivoted,omitempty"`
+	// Plurals are the translated CLDR plural forms of the target language for the "plural" format
+	Plurals map[string]string `json:"plurals,omitempty"`
+	// Segments reports the translated segments that needed attention, such as moderated ones
+	Segments []SegmentReport `json:"segments,omitempty"`
+	// Rows is the number of CSV rows translated
+	Rows int `json:"rows,omitempty"`
+	// OutputKey is the key of the translated CSV file in the bulk bucket
+	OutputKey string `json:"output_key,omitempty"`
+	// Estimate is the cost estimate of a dry run, which returns no translated text
+	Estimate *CostEstimate `json:"estimate,omitempty"`
+	// Alignment pairs the source sentences with their translations when requested
+	Alignment []AlignedSentence `json:"alignment,omitempty"`
+	// Direction is "rtl" when the target language is written right to left
+	Direction string `json:"direction,omitempty"`
+	// Changes reports the sentences translated and reused for a previous version of the text
+	Changes *ChangeReport `json:"changes,omitempty"`
+	// Alternatives are the alternative translations of each sentence when requested
+	Alternatives []SegmentAlternatives `json:"alternatives,omitempty"`
+	// Analysis is the sentiment and the named entities of the source text when requested,
+	// missing when Comprehend doesn't support the source language
+	Analysis *SourceAnalysis `json:"analysis,omitempty"`
+	// Disclaimer is the disclaimer added to the translated text, as a paragraph of a text or a
+	// banner of an HTML document
+	Disclaimer string `json:"disclaimer,omitempty"`
+	// Parameters are the parameters the request was translated with when requested
+	Parameters *ResolvedParameters `json:"parameters,omitempty"`
 }
 
 // CacheItem represents a cached translation item
@@ -77,11 +501,63 @@ type CacheItem struct {
 	SourceLanguage string
 	// TargetLanguage is the language code of the target text
 	TargetLanguage string
+	// Region is the AWS region the item was originally written in
+	Region string
+	// OverflowKey is the S3 key holding the text of items too large for DynamoDB
+	OverflowKey string
+	// Failure is the reason the text could not be translated, for negatively cached items
+	Failure string
+	// ExpiresAt is the unix time after which the item is expired by the table TTL
+	ExpiresAt int64
+	// Derived is set on items written from a translation in the opposite direction rather
+	// than translated by the provider
+	Derived bool
+	// SourceHash and TranslatedHash are the content hashes of the texts, indexed so the
+	// items holding a text can be found for erasure
+	SourceHash     string
+	TranslatedHash string
+	// CreatedAt is the unix time the item was cached
+	CreatedAt int64
+	// Provider is where the translation came from, the translation provider or an import
+	Provider string
+	// TranslateRegion is the region of the AWS Translate endpoint that translated the item,
+	// which differs from Region when the translation failed over
+	TranslateRegion string
+	// SourceCharacters and TranslatedCharacters are the lengths of the texts in characters
+	SourceCharacters     int
+	TranslatedCharacters int
+	// Hits is the number of lookups served by the item and LastHitAt the unix time of the
+	// last one, both are only maintained in the table and never written with the item
+	Hits      int64
+	LastHitAt int64
+	// Ratings is the number of feedback ratings of the translation and RatingSum their total,
+	// maintained in the table like the hits
+	Ratings   int64
+	RatingSum int64
+	// SourceRetention is the mode the source text was stored with, empty when SourceText
+	// is the full text
+	SourceRetention string
+	// Route is the language pair of the routing rule that chose the provider, empty when no
+	// rule routed the translation
+	Route string
+	// Pivot is the intermediate language the text was translated through, empty for a
+	// direct translation
+	Pivot string
+	// Authoritative is set on translations approved by a reviewer, which are kept by the
+	// compaction of the cache
+	Authoritative bool
+	// Retranslation is the reason the item replaced an earlier translation of the text, empty
+	// for a first translation
+	Retranslation string
 }
 
 type DynamoDBClient interface {
 	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
 	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
 }
 
 type TranslateClient interface {
@@ -89,52 +565,346 @@ type TranslateClient interface {
 	ListLanguages(ctx context.Context, params *translate.ListLanguagesInput, optFns ...func(*translate.Options)) (*translate.ListLanguagesOutput, error)
 }
 
+type S3Client interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+}
+
+type SQSClient interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+type KinesisClient interface {
+	PutRecords(ctx context.Context, params *kinesis.PutRecordsInput, optFns ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error)
+}
+
+type FirehoseClient interface {
+	PutRecordBatch(ctx context.Context, params *firehose.PutRecordBatchInput, optFns ...func(*firehose.Options)) (*firehose.PutRecordBatchOutput, error)
+}
+
+type TextractClient interface {
+	DetectDocumentText(ctx context.Context, params *textract.DetectDocumentTextInput, optFns ...func(*textract.Options)) (*textract.DetectDocumentTextOutput, error)
+}
+
+type ComprehendClient interface {
+	DetectToxicContent(ctx context.Context, params *comprehend.DetectToxicContentInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectToxicContentOutput, error)
+	DetectSentiment(ctx context.Context, params *comprehend.DetectSentimentInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectSentimentOutput, error)
+	DetectEntities(ctx context.Context, params *comprehend.DetectEntitiesInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectEntitiesOutput, error)
+	DetectDominantLanguage(ctx context.Context, params *comprehend.DetectDominantLanguageInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectDominantLanguageOutput, error)
+}
+
+// SageMakerRuntimeClient invokes SageMaker real-time endpoints
+type SageMakerRuntimeClient interface {
+	InvokeEndpoint(ctx context.Context, endpointName string, body []byte) ([]byte, error)
+}
+
 func main() {
-	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region))
+	initStart := time.Now()
+	ctx := context.Background()
+
+	cfg, tlsConfig, err := loadConfig(ctx)
 	if err != nil {
-		panic(fmt.Sprintf("failed to load configuration, %v", err))
+		panic(err.Error())
 	}
+	configDuration := time.Since(initStart)
 
-	// Setup xray tracing for sdks
-	awsv2.AWSV2Instrumentor(&cfg.APIOptions)
-
-	// Create DynamoDB and Translate clients
-	dynamoClient := dynamodb.NewFromConfig(cfg)
-	translateClient := translate.NewFromConfig(cfg)
+	// Create DynamoDB, Translate, Textract, S3 and Comprehend clients, through the endpoints
+	// overriding the public ones of the services if any
+	dynamoClient := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+		overrideEndpoint(&o.BaseEndpoint, serviceDynamoDB, o.Region)
+	})
+	translateClient := translate.NewFromConfig(cfg, func(o *translate.Options) {
+		overrideEndpoint(&o.BaseEndpoint, serviceTranslate, o.Region)
+	})
+	textractClient := textract.NewFromConfig(cfg, func(o *textract.Options) {
+		overrideEndpoint(&o.BaseEndpoint, serviceTextract, o.Region)
+	})
+	s3Client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		overrideEndpoint(&o.BaseEndpoint, serviceS3, o.Region)
+	})
+	comprehendClient := comprehend.NewFromConfig(cfg, func(o *comprehend.Options) {
+		overrideEndpoint(&o.BaseEndpoint, serviceComprehend, o.Region)
+	})
 
+	// Fail fast while Translate or DynamoDB are degraded instead of waiting out the timeout,
+	// failing over to the secondary Translate region when there is one
+	var primaryTranslateClient TranslateClient = newBreakerTranslateClient(translateClient)
+	if failoverTranslateRegion != "" && failoverTranslateRegion != region {
+		primaryTranslateClient = &failoverTranslateClient{
+			primary: primaryTranslateClient,
+			secondary: &breakerTranslateClient{
+				client: translate.NewFromConfig(cfg, func(o *translate.Options) {
+					o.Region = failoverTranslateRegion
+					overrideEndpoint(&o.BaseEndpoint, serviceTranslate, o.Region)
+				}),
+				breaker: newCircuitBreaker("translate-" + failoverTranslateRegion),
+			},
+			secondaryRegion: failoverTranslateRegion,
+		}
+	}
+	// The clients calling other endpoints than the sdk share a transport, trusting the CA
+	// bundle like the sdk clients
+	providerTransport := newProviderTransport(tlsConfig)
 	h := &handler{
-		dynamoClient:    dynamoClient,
-		translateClient: translateClient,
+		dynamoClient:     newBreakerDynamoDBClient(dynamoClient),
+		translateClient:  primaryTranslateClient,
+		textractClient:   textractClient,
+		s3Client:         s3Client,
+		comprehendClient: comprehendClient,
+		httpClient:       newPageClient(providerTransport),
+		transport:        providerTransport,
+		credentials:      cfg.Credentials,
+		scheduler:        newTranslationScheduler(providerConcurrency, interactiveTPS, bulkTPS),
+	}
+
+	// Warm the connections to the services and the supported languages of the modes
+	// validating the target language while the remaining clients are built
+	var warming sync.WaitGroup
+	warming.Add(1)
+	go func() {
+		defer warming.Done()
+		warmConnections(ctx, cfg.HTTPClient, sdkEndpoints(cfg))
+	}()
+	if handlerMode == "" || handlerMode == handlerModeAppSync {
+		warming.Add(1)
+		go func() {
+			defer warming.Done()
+			h.warmSupportedLanguages(ctx)
+		}()
+	}
+
+	if cacheWriteQueueURL != "" {
+		h.sqsClient = sqs.NewFromConfig(cfg, func(o *sqs.Options) {
+			overrideEndpoint(&o.BaseEndpoint, serviceSQS, o.Region)
+		})
+	}
+	if streamOutputName != "" {
+		h.kinesisClient = kinesis.NewFromConfig(cfg, func(o *kinesis.Options) {
+			overrideEndpoint(&o.BaseEndpoint, serviceKinesis, o.Region)
+		})
+	}
+	if streamDeliveryStreamName != "" || auditDeliveryStreamName != "" {
+		h.firehoseClient = firehose.NewFromConfig(cfg, func(o *firehose.Options) {
+			overrideEndpoint(&o.BaseEndpoint, serviceFirehose, o.Region)
+		})
+	}
+
+	// Our own model has its own breaker so its failures don't stop AWS Translate
+	if sageMakerEndpointName != "" {
+		sageMakerClient, err := newSageMakerTranslateClient(newSageMakerRuntime(cfg), sageMakerEndpointName, sageMakerModelFormat, sageMakerLanguageCodes)
+		if err != nil {
+			panic(fmt.Sprintf("failed to create sagemaker client, %v", err))
+		}
+		h.sageMakerClient = &breakerTranslateClient{client: sageMakerClient, breaker: newCircuitBreaker("sagemaker")}
+	}
+	if deepLAuthKey != "" {
+		h.deepLClient = &breakerTranslateClient{
+			client:  newDeepLTranslateClient(newProviderHTTPClient(providerTransport, deepLRequestTimeout), deepLAuthKey, deepLAPIURL),
+			breaker: newCircuitBreaker("deepl"),
+		}
+	}
+	if grammarCheckURL != "" {
+		h.grammarClient = newGrammarCheckClient(newProviderHTTPClient(providerTransport, grammarCheckTimeout), grammarCheckURL, grammarCheckUsername, grammarCheckAPIKey)
+	}
+
+	// The shadow provider is never guarded by the breaker, its failures are only logged
+	if shadowTranslateRegion != "" && shadowPercent > 0 {
+		h.shadowClient = translate.NewFromConfig(cfg, func(o *translate.Options) {
+			o.Region = shadowTranslateRegion
+			overrideEndpoint(&o.BaseEndpoint, serviceTranslate, o.Region)
+		})
+	}
+
+	// Every other region configured for the global table is a peer for read repair
+	for peerRegion, tableName := range regionTableNames {
+		if peerRegion == region {
+			continue
+		}
+		h.peerCaches = append(h.peerCaches, peerCache{
+			region:    peerRegion,
+			tableName: tableName,
+			client: dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+				o.Region = peerRegion
+				overrideEndpoint(&o.BaseEndpoint, serviceDynamoDB, o.Region)
+			}),
+		})
 	}
 
-	lambda.Start(h.handle)
+	warming.Wait()
+	log.Printf("Init took %s, %s to load the configuration", time.Since(initStart), configDuration)
+
+	// The same binary backs every function, the mode selects the event source
+	var handlerFunc any
+	switch handlerMode {
+	case handlerModeSES:
+		handlerFunc = h.handleSESEvent
+	case handlerModeCacheJob:
+		handlerFunc = h.handleCacheJob
+	case handlerModeCacheWriter:
+		handlerFunc = h.handleCacheWriteEvent
+	case handlerModeDocumentTask:
+		handlerFunc = h.handleDocumentTask
+	case handlerModeCrawlJob:
+		handlerFunc = h.handleCrawlJob
+	case handlerModeGitHub:
+		handlerFunc = h.handleGitHubWebhook
+	case handlerModeStream:
+		handlerFunc = h.handleStreamEvent
+	case handlerModeAppSync:
+		handlerFunc = h.handleAppSyncEvent
+	default:
+		handlerFunc = h.handle
+	}
+	lambda.Start(h.reloadOnRestore(handlerFunc))
 }
 
 type handler struct {
 	dynamoClient    DynamoDBClient
 	translateClient TranslateClient
+	textractClient  TextractClient
+	s3Client        S3Client
+	// peerCaches are the cache replicas in other regions, consulted on a local miss
+	peerCaches []peerCache
+	// shadowClient is the provider compared against translateClient, nil when disabled
+	shadowClient TranslateClient
+	// comprehendClient detects the language of the texts without source language, toxic
+	// content for moderation, and the sentiment and entities of the analyzed source texts
+	comprehendClient ComprehendClient
+	// sageMakerClient translates the language pairs routed to our own model, nil when disabled
+	sageMakerClient TranslateClient
+	// deepLClient translates the language pairs routed to DeepL, nil when disabled
+	deepLClient TranslateClient
+	// grammarClient checks the grammar of the translations of the requests asking for it, nil
+	// when disabled
+	grammarClient *grammarCheckClient
+	// scheduler allocates the provider calls between interactive and bulk requests, nil for
+	// unscheduled calls
+	scheduler *translationScheduler
+	// routes caches the rules of the routing table
+	routes routingCache
+	// disclaimers caches the templates of the disclaimer table
+	disclaimers disclaimerCache
+	// sqsClient publishes new cache items to the cache write queue, nil when disabled
+	sqsClient SQSClient
+	// kinesisClient and firehoseClient write translated stream records and audit records, nil
+	// when disabled
+	kinesisClient  KinesisClient
+	firehoseClient FirehoseClient
+	// languages caches the languages supported by translateClient
+	languages languageCache
+	// httpClient fetches web pages, nil for a client only connecting to public addresses
+	httpClient *http.Client
+	// transport is the transport of the clients calling GitHub and fetching web pages, nil for
+	// the default one
+	transport *http.Transport
+	// credentials are the credentials of the clients, invalidated by Reload
+	credentials aws.CredentialsProvider
 }
 
+// handle answers an API Gateway request through the middlewares of the API requests
 func (h *handler) handle(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	request, err := unmarshalRequest([]byte(event.Body))
-	if err != nil {
+	return chainMiddlewares(requestHandlerFunc(h.handleRequest), h.apiMiddlewares()...).serveRequest(ctx, event)
+}
+
+// handleRequest routes an API Gateway request to the handler of its resource, translating
+// the text or document of the translation resources
+func (h *handler) handleRequest(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	switch event.Resource {
+	case erasureResource:
+		return h.handleErasureRequest(ctx, event), nil
+	case openAPIResource:
+		return handleOpenAPIRequest(), nil
+	case languagesResource:
+		return h.handleLanguagesRequest(ctx), nil
+	case documentVersionsResource, documentTranslationResource, documentDiffResource:
+		return h.handleDocumentRegistryRequest(ctx, event), nil
+	case postEditSessionsResource, postEditSessionResource, postEditEditsResource, postEditFinalizeResource:
+		return h.handlePostEditRequest(ctx, event), nil
+	case feedbackResource, feedbackStatsResource:
+		return h.handleFeedbackRequest(ctx, event), nil
+	case batchResource:
+		return h.handleBatchRequest(ctx, event), nil
+	}
+
+	codec, ok := apiCodecs[negotiateVersion(event)]
+	if !ok {
 		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusBadRequest,
-			Body:       "Invalid request format",
+			StatusCode: http.StatusNotAcceptable,
+			Body:       "API version not supported",
 		}, nil
 	}
+	ctx = withAPICodec(ctx, codec)
+
+	var request TranslateRequest
+	if event.HTTPMethod == http.MethodGet {
+		request = requestFromParameters(event)
+	} else {
+		var err error
+		request, err = codec.unmarshalRequest([]byte(event.Body))
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "Invalid request format",
+			}, nil
+		}
+	}
+
+	if caller, _ := entitlementFromContext(ctx); request.TargetLanguage == "" {
+		request.TargetLanguage = caller.DefaultTargetLanguage
+	}
+
+	var invalid *validationError
+	if errors.As(validatePageURL(event.Resource, request), &invalid) {
+		return validationErrorResponse(invalid), nil
+	}
+
+	return h.handleTranslateRequest(ctx, request)
+}
+
+// handleTranslateRequest validates and translates a request, whatever its event source
+func (h *handler) handleTranslateRequest(ctx context.Context, request TranslateRequest) (events.APIGatewayProxyResponse, error) {
+	audit := auditFromContext(ctx)
+	audit.setRequest(request)
 
 	// Validate the request
-	err = validateRequest(request)
-	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusBadRequest,
-			Body:       err.Error(),
-		}, nil
+	var invalid *validationError
+	if errors.As(validateRequest(request), &invalid) {
+		return validationErrorResponse(invalid), nil
+	}
+	request.SourceLanguage = normalizeLanguageCode(request.SourceLanguage)
+	request.TargetLanguage = normalizeLanguageCode(request.TargetLanguage)
+
+	if response, ok := checkEntitlement(ctx, request.SourceLanguage, request.TargetLanguage); !ok {
+		return response, nil
+	}
+
+	// The page of a URL is fetched once the rest of the request is known to be valid
+	if request.URL != "" {
+		if response, ok := h.fetchRequestPage(ctx, &request); !ok {
+			return response, nil
+		}
+		audit.setRequest(request)
+	}
+
+	// Transliteration doesn't depend on the provider or the target language
+	if request.Transliterate {
+		return handleTransliteration(ctx, request), nil
 	}
 
 	// Check if the target language is supported
-	supported, err := doesTargetLanguageExist(ctx, h.translateClient, request.TargetLanguage)
+	supported, err := h.doesTargetLanguageExist(ctx, request.TargetLanguage)
+	if degradedMode != "" && errors.As(err, new(*dependencyUnavailable)) {
+		// The language can't be checked during an outage, misses are served untranslated
+		supported, err = true, nil
+	}
+	if response, ok := unavailableResponse(err); ok {
+		return response, nil
+	}
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: http.StatusInternalServerError,
@@ -148,55 +918,152 @@ func (h *handler) handle(ctx context.Context, event events.APIGatewayProxyReques
 		}, nil
 	}
 
-	// Split the text into sentences
-	tokens := splitSentences(request.Text)
+	ctx, degradation := withDegradation(ctx)
+	ctx, pivot := withPivotReport(ctx)
+	ctx, moderation := withModeration(ctx, request.Moderation)
+	ctx = withStyle(ctx, request.Style)
+	ctx = withNoStore(ctx, requestNoStoreReason(request))
+	ctx = withLocaleFormatting(ctx, request.LocalizeFormats)
+	ctx = withTypography(ctx, request.Typography)
+	ctx = withCasingPreservation(ctx, request.PreserveCasing)
+	ctx = withInclusive(ctx, request.Inclusive)
+	ctx, constraint := withLengthConstraint(ctx, request.MaxLength, request.Truncation)
+	ctx, grammar := withGrammarCheck(ctx, request.GrammarCheck)
+	ctx = withHTMLMetadata(ctx, request.TranslateMetadata)
+	ctx = withPriority(ctx, request.Priority)
+	ctx = withGranularity(ctx, request.Granularity)
+	ctx, deadline := withDeadline(ctx, request.DeadlineMS)
+	ctx, dryRun := withDryRun(ctx, request.DryRun)
+	if dryRun == nil {
+		ctx = withCharacterLimit(ctx, request.MaxCharacters)
+	}
 
-	// Iterate over each sentence and translate it
-	errGroup, groupCtx := errgroup.WithContext(ctx)
-	errGroup.SetLimit(10) // Limit the number of concurrent translations
+	// The disclaimer is added to the translation once it is cached, as the templates change
+	// independently of the translation
+	notice, err := h.resolveDisclaimer(ctx, request)
+	if response, ok := unavailableResponse(err); ok {
+		return response, nil
+	}
+	if err != nil {
+		log.Printf("Error resolving disclaimer: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error resolving disclaimer",
+		}, nil
+	}
+	if notice != nil && !slices.Contains(disclaimerFormats, request.Format) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			Body:       fmt.Sprintf("A disclaimer is required, it is not supported for %s", request.Format),
+		}, nil
+	}
+	ctx = withDisclaimer(ctx, notice)
+	request.PreviousTranslation = notice.remove(request.PreviousTranslation)
 
-	translatedSentences := make([]string, len(tokens))
+	// The response cache is keyed by the request as sent, before its source language is
+	// detected
+	responseHash, cacheable := responseCacheKey(request)
 
-	for idx, tok := range tokens {
-		index := idx // Capture the index for the goroutine
-		token := tok // Capture the token for the goroutine
-		errGroup.Go(func() error {
-			cacheItem, useCache, err := shouldCacheBeUsed(groupCtx, h.dynamoClient, request.SourceLanguage, request.TargetLanguage, token)
+	// Identity translations of text are skipped, detecting the source language when needed
+	if request.Format == "" || request.Format == formatText || request.Format == formatHTML {
+		sourceLanguage, detectedLanguage := request.SourceLanguage, ""
+		if sourceLanguage == autoDetectLanguage {
+			detectedLanguage, err = h.detectRequestLanguage(ctx, request)
+			if response, ok := unavailableResponse(err); ok {
+				return response, nil
+			}
 			if err != nil {
-				return fmt.Errorf("error checking cache for token %d: %w", index, err)
+				log.Printf("Error detecting language: %v", err)
+				return events.APIGatewayProxyResponse{
+					StatusCode: http.StatusInternalServerError,
+					Body:       "Error detecting language",
+				}, nil
 			}
-
-			if useCache {
-				// Use the cached translation
-				translatedSentences[index] = cacheItem.TranslatedText
-				return nil
+			detectedLanguage = normalizeLanguageCode(detectedLanguage)
+			sourceLanguage = detectedLanguage
+			audit.setSourceLanguage(sourceLanguage)
+			if response, ok := checkEntitlement(ctx, sourceLanguage, request.TargetLanguage); !ok {
+				return response, nil
 			}
+		}
 
-			translateResponse, err := translateLanguage(groupCtx, h.translateClient, token, request.SourceLanguage, request.TargetLanguage)
-			if err != nil {
-				return fmt.Errorf("error translating token %d: %w", index, err)
-			}
+		request.SourceLanguage = sourceLanguage
 
-			cacheItem = CacheItem{
-				Hash:           getHashFromText(fmt.Sprintf("%s-%s-%s", request.SourceLanguage, request.TargetLanguage, token)),
-				TranslatedText: translateResponse.TranslatedText,
-				SourceText:     token,
-				SourceLanguage: request.SourceLanguage,
-				TargetLanguage: request.TargetLanguage,
+		// An unchanged document is answered from its cached response without a lookup per
+		// sentence, once its detected language is checked
+		if cacheable {
+			if response, ok := h.lookupResponse(ctx, responseHash); ok {
+				notice.add(&response, request.Format)
+				return newJSONResponse(ctx, response), nil
 			}
+		}
 
-			err = cacheTranslatedText(groupCtx, h.dynamoClient, cacheItem)
-			if err != nil {
-				return fmt.Errorf("error caching translation for token %d: %w", index, err)
+		if sourceLanguage == request.TargetLanguage {
+			response := TranslateResponse{
+				TranslatedText:   request.Text,
+				DetectedLanguage: formatLanguageCode(detectedLanguage, request.LanguageCodeCase),
+				Skipped:          true,
+				Direction:        textDirection(request.TargetLanguage),
+				Parameters:       resolvedParameters(ctx, request),
 			}
-
-			translatedSentences[index] = translateResponse.TranslatedText
-			return nil
-		})
+			if request.Alignment {
+				sentences := splitSentences(request.Text)
+				response.Alignment = alignSentences(request.Text, sentences, request.Text, sentences)
+			}
+			return newJSONResponse(ctx, response), nil
+		}
 	}
 
-	// Wait for all translations to complete
-	if err := errGroup.Wait(); err != nil {
+	var (
+		translatedText string
+		alignment      []AlignedSentence
+		changes        *ChangeReport
+		alternatives   []SegmentAlternatives
+	)
+	analysis := h.startSourceAnalysis(ctx, request)
+	ctx = h.protectEntities(ctx, request)
+	switch request.Format {
+	case formatPDF:
+		// PDF documents are extracted and translated block by block
+		return h.handlePDF(ctx, request)
+	case formatEmail:
+		return h.handleEmail(ctx, request)
+	case formatPlural:
+		return h.handlePlurals(ctx, request)
+	case formatAndroidStrings, formatIOSStrings, formatIOSStringsDict:
+		return h.handleResources(ctx, request)
+	case formatCSV:
+		return h.handleCSV(ctx, request)
+	case formatHTML:
+		translatedText, err = h.translateHTML(ctx, request.SourceLanguage, request.TargetLanguage, request.Text)
+	default:
+		if request.PreviousText != "" {
+			translatedText, alignment, changes, err = h.translateDelta(ctx, request)
+		} else if request.Alternatives > 0 {
+			translatedText, alternatives, err = h.translateWithAlternatives(ctx, request.SourceLanguage, request.TargetLanguage, request.Text, request.Alternatives)
+		} else if request.Alignment {
+			translatedText, alignment, err = h.translateAlignedText(ctx, request.SourceLanguage, request.TargetLanguage, request.Text)
+		} else {
+			translatedText, err = h.translateText(ctx, request.SourceLanguage, request.TargetLanguage, request.Text)
+		}
+	}
+	if response, ok := unavailableResponse(err); ok {
+		return response, nil
+	}
+	if response, ok := characterLimitResponse(err); ok {
+		return response, nil
+	}
+	if response, ok := languagePolicyResponse(err); ok {
+		return response, nil
+	}
+	var failure *translationFailure
+	if errors.As(err, &failure) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			Body:       "Text could not be translated: " + failure.Reason,
+		}, nil
+	}
+	if err != nil {
 		log.Printf("Error during translation: %v", err)
 		return events.APIGatewayProxyResponse{
 			StatusCode: http.StatusInternalServerError,
@@ -204,44 +1071,387 @@ func (h *handler) handle(ctx context.Context, event events.APIGatewayProxyReques
 		}, nil
 	}
 
-	// Join the translated sentences into a single string
-	translatedText := strings.Builder{}
-	for _, sentence := range translatedSentences {
-		translatedText.WriteString(sentence) // The error is always nil
-		translatedText.WriteString(" ")
+	if dryRun != nil {
+		return newJSONResponse(ctx, TranslateResponse{Estimate: dryRun.Estimate()}), nil
 	}
 
 	// Create the response
 	response := TranslateResponse{
-		TranslatedText: translatedText.String(),
+		TranslatedText: translatedText,
+		Degraded:       degradation.Degraded(),
+		TimedOut:       deadline.TimedOut(),
+		LowQuality:     degradation.LowQuality(),
+		Pivoted:        pivot.Pivoted(),
+		Segments:       slices.Concat(moderation.Segments(), constraint.Segments(), grammar.Segments(), deadline.Segments()),
+		Alignment:      alignment,
+		Direction:      textDirection(request.TargetLanguage),
+		Changes:        changes,
+		Alternatives:   alternatives,
+		Analysis:       analysis(),
+		Parameters:     resolvedParameters(ctx, request),
+	}
+	if cacheable {
+		h.cacheResponse(ctx, responseHash, request, response)
 	}
+	notice.add(&response, request.Format)
+
+	return newJSONResponse(ctx, response), nil
+}
+
+// newJSONResponse marshals the response with the codec of the request into a 200 API Gateway
+// response
+func newJSONResponse(ctx context.Context, response TranslateResponse) events.APIGatewayProxyResponse {
+	auditFromContext(ctx).setTranslation(response.TranslatedText)
 
-	// Marshal the response to JSON
-	responseBody, err := marshalResponse(response)
+	codec := apiCodecFromContext(ctx)
+	responseBody, err := codec.marshalResponse(response)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: http.StatusInternalServerError,
 			Body:       "Error marshalling response",
-		}, nil
+		}
 	}
 
-	// Return the response
-	return events.APIGatewayProxyResponse{
+	apiResponse := events.APIGatewayProxyResponse{
 		StatusCode: http.StatusOK,
 		Body:       string(responseBody),
-	}, nil
+	}
+	if codec.contentType != "" {
+		apiResponse.Headers = map[string]string{"Content-Type": codec.contentType}
+	}
+	return apiResponse
 }
 
-func shouldCacheBeUsed(ctx context.Context, dynamoClient DynamoDBClient, sourceLanguage, targetLanguage, text string) (CacheItem, bool, error) {
-	hashKey := fmt.Sprintf("%s-%s-%s", sourceLanguage, targetLanguage, text)
-	hash := getHashFromText(hashKey)
+// translateText splits the text into sentences, translates each one through the cache
+// and joins the translated sentences back together. A text too large for the memory ceiling
+// of its segments is translated in windows, unless its length is constrained as a whole.
+func (h *handler) translateText(ctx context.Context, sourceLanguage, targetLanguage, text string) (string, error) {
+	if len(text) > documentWindowBytes && lengthConstraintFromContext(ctx) == nil {
+		return h.translateTextWindows(ctx, sourceLanguage, targetLanguage, text)
+	}
+	return h.translateWholeText(ctx, sourceLanguage, targetLanguage, text)
+}
+
+// translateWholeText translates the sentences of the text at once
+func (h *handler) translateWholeText(ctx context.Context, sourceLanguage, targetLanguage, text string) (string, error) {
+	// Split the text into sentences
+	tokens := splitChunks(ctx, sourceLanguage, text)
+
+	translatedSentences, err := h.translateSentences(ctx, sourceLanguage, targetLanguage, tokens)
+	if err != nil {
+		return "", err
+	}
+	if translatedSentences, err = h.constrainLength(ctx, sourceLanguage, targetLanguage, tokens, translatedSentences); err != nil {
+		return "", err
+	}
+
+	return joinSentences(translatedSentences), nil
+}
+
+// translateFields translates the fields at the indexes in place, such as the cells of a CSV
+// row, sending the sentences of every field to the provider together
+func (h *handler) translateFields(ctx context.Context, sourceLanguage, targetLanguage string, fields []string, indexes []int) error {
+	var (
+		tokens []string
+		counts = make([]int, len(indexes))
+	)
+	for i, index := range indexes {
+		sentences := splitChunks(ctx, sourceLanguage, fields[index])
+		counts[i] = len(sentences)
+		tokens = append(tokens, sentences...)
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	translated, err := h.translateSentences(ctx, sourceLanguage, targetLanguage, tokens)
+	if err != nil {
+		return err
+	}
+
+	offset := 0
+	for i, index := range indexes {
+		if counts[i] == 0 {
+			continue
+		}
+		fields[index] = strings.Join(translated[offset:offset+counts[i]], " ")
+		offset += counts[i]
+	}
+
+	return nil
+}
+
+// translateSentences translates the sentences in a pipeline preserving the input order. Cache
+// lookups run ahead of the translation stage, which only receives the misses, and new
+// translations are handed to the write-behind stage of the request.
+func (h *handler) translateSentences(ctx context.Context, sourceLanguage, targetLanguage string, tokens []string) ([]string, error) {
+	// Nothing to pay the provider for when the text is already in the target language
+	if sourceLanguage == targetLanguage {
+		return slices.Clone(tokens), nil
+	}
+
+	// Each distinct sentence is looked up and translated once, the stages sending their
+	// translations to a collector that puts them in the order of the tokens
+	distinct := dedupSentences(tokens)
+	results := make(chan segmentResult)
+	collected := make(chan []string, 1)
+	go func() {
+		collected <- distinct.collect(results)
+	}()
+
+	errGroup, groupCtx := errgroup.WithContext(ctx)
+	misses := make(chan int, len(distinct.texts))
 
+	// Lookup stage, cache misses are sent to the translation stage
+	limit := characterLimitFromContext(ctx)
+	held := make([]bool, len(distinct.texts))
+	errGroup.Go(func() (err error) {
+		defer catchPanic(&err)
+		defer close(misses)
+
+		// A lookup overrunning its budget is a miss, leaving the rest of the time to translate
+		budgetCtx, cancel := stageBudgetsFromContext(ctx).lookupContext(groupCtx)
+		defer cancel()
+		lookups, lookupCtx := errgroup.WithContext(budgetCtx)
+		lookups.SetLimit(maxConcurrentLookups)
+		for index, token := range distinct.texts {
+			lookups.Go(func() (err error) {
+				defer catchPanic(&err)
+				cacheItem, useCache, err := h.lookupCache(lookupCtx, sourceLanguage, targetLanguage, token)
+				if err != nil && errors.Is(budgetCtx.Err(), context.DeadlineExceeded) && groupCtx.Err() == nil {
+					cacheItem, useCache, err = CacheItem{}, false, nil
+				}
+				if err != nil {
+					return fmt.Errorf("error checking cache for token %d: %w", distinct.position(index), err)
+				}
+				if d := dryRunFromContext(ctx); d != nil {
+					d.recordLookup(useCache && cacheItem.Failure == "")
+				}
+				if limit != nil {
+					limit.usage.recordLookup(useCache && cacheItem.Failure == "")
+				}
+				if a := auditFromContext(ctx); a != nil {
+					a.usage.recordLookup(useCache && cacheItem.Failure == "")
+				}
+
+				if !useCache {
+					if limit != nil {
+						limit.usage.recordMiss(token)
+						held[index] = true
+						return nil
+					}
+					misses <- index
+					return nil
+				}
+
+				// A cached failure means the provider already rejected this text
+				if cacheItem.Failure != "" {
+					return fmt.Errorf("error translating token %d: %w", distinct.position(index), &translationFailure{Reason: cacheItem.Failure})
+				}
+
+				// Use the cached translation, which also serves the repetitions of the sentence
+				results <- segmentResult{index: index, text: cacheItem.TranslatedText}
+				if dryRunFromContext(ctx) == nil {
+					for range len(distinct.positions[index]) - 1 {
+						h.recordCacheHit(ctx, cacheItem.Hash)
+					}
+				}
+				if cacheItem.Pivot != "" {
+					pivotReportFromContext(ctx).record()
+				}
+				return nil
+			})
+		}
+		if err := lookups.Wait(); err != nil {
+			return err
+		}
+
+		// Misses held back by a character limit are only translated once all are counted
+		if limit != nil {
+			return limit.release(held, misses)
+		}
+		return nil
+	})
+
+	// Translation stage
+	for range min(maxConcurrentTranslations, len(distinct.texts)) {
+		errGroup.Go(func() (err error) {
+			defer catchPanic(&err)
+			for index := range misses {
+				translated, err := h.translateToken(groupCtx, sourceLanguage, targetLanguage, distinct.texts[index])
+				if err != nil {
+					return fmt.Errorf("error translating token %d: %w", distinct.position(index), err)
+				}
+				results <- segmentResult{index: index, text: translated}
+			}
+			return nil
+		})
+	}
+
+	// Wait for all translations to complete, then for the collector to order them
+	err := errGroup.Wait()
+	close(results)
+	translatedSentences := <-collected
+	if err != nil {
+		raisePanic(err)
+		return nil, err
+	}
+
+	// A dry run has nothing to moderate
+	if dryRunFromContext(ctx) != nil {
+		return translatedSentences, nil
+	}
+	if err := h.moderateSentences(ctx, targetLanguage, tokens, translatedSentences); err != nil {
+		return nil, err
+	}
+	if localeFormattingFromContext(ctx) {
+		localizeFormats(sourceLanguage, targetLanguage, tokens, translatedSentences)
+	}
+	if casingPreservationFromContext(ctx) {
+		preserveCasing(targetLanguage, tokens, translatedSentences)
+	}
+	if t := typographyFromContext(ctx); t != (typography{}) {
+		normalizeTypography(targetLanguage, t, translatedSentences)
+	}
+	h.checkGrammar(ctx, targetLanguage, tokens, translatedSentences)
+
+	return translatedSentences, nil
+}
+
+// translateToken translates a sentence missing from the cache with the provider and caches
+// the translation
+func (h *handler) translateToken(ctx context.Context, sourceLanguage, targetLanguage, token string) (string, error) {
+	if d := dryRunFromContext(ctx); d != nil {
+		d.recordMiss(token)
+		return token, nil
+	}
+	if a := auditFromContext(ctx); a != nil {
+		a.usage.recordMiss(token)
+	}
+
+	if degradedMode == degradedModeForce {
+		if translated, ok := serveFallback(ctx, sourceLanguage, targetLanguage, token, nil); ok {
+			return translated, nil
+		}
+		if serveUntranslated(ctx, nil) {
+			return token, nil
+		}
+	}
+
+	compareShadow := h.startShadowTranslation(ctx, sourceLanguage, targetLanguage, token)
+
+	translateClient, route := h.translationRoute(ctx, sourceLanguage, targetLanguage)
+	provider := route.Provider
+	ctx = withRoute(ctx, route)
+	var translateRegion *string
+	if provider == providerAWSTranslate {
+		ctx, translateRegion = withTranslateRegion(ctx, region)
+	}
+	if serveTimedOut(ctx, token, nil) {
+		return token, nil
+	}
+	providerCtx, cancel := deadlineFromContext(ctx).providerContext(ctx)
+	defer cancel()
+	release, err := h.scheduler.acquire(providerCtx, priorityFromContext(ctx))
+	if err != nil {
+		if serveTimedOut(ctx, token, err) {
+			return token, nil
+		}
+		return "", err
+	}
+	providerStart := time.Now()
+	translateResponse, err := translateShielded(providerCtx, translateClient, token, sourceLanguage, targetLanguage)
+	release()
+	recordStage(ctx, stageProvider, providerStart)
+	if err != nil && serveTimedOut(ctx, token, err) {
+		// The miss isn't cached, the provider may still translate it next time
+		return token, nil
+	}
+	providerCharacters.Add(ctx, int64(utf8.RuneCountInString(token)), metric.WithAttributes(
+		attribute.String("source_language", sourceLanguage),
+		attribute.String("target_language", targetLanguage),
+	))
+	if errors.As(err, new(*languagePairNotAllowed)) {
+		// Neither a provider failure nor a translation to fall back from
+		return "", err
+	}
+	if err != nil {
+		recordProviderError(ctx, err)
+		if translated, ok := serveFallback(ctx, sourceLanguage, targetLanguage, token, err); ok {
+			// A rough translation of a short string beats none, it isn't cached either
+			return translated, nil
+		}
+	}
+	if err != nil && serveUntranslated(ctx, err) {
+		// Some translated content is better than none, the miss isn't cached
+		return token, nil
+	}
+	if err != nil {
+		if reason, ok := permanentTranslateFailure(err); ok {
+			// A pair the provider can't translate directly may still be translated through
+			// the pivot language
+			if canPivot(sourceLanguage, targetLanguage, reason) {
+				return h.translatePivot(ctx, sourceLanguage, targetLanguage, token, provider)
+			}
+			h.cacheFailure(ctx, sourceLanguage, targetLanguage, token, provider, reason)
+			err = &translationFailure{Reason: reason}
+		}
+		return "", err
+	}
+
+	compareShadow(translateResponse.TranslatedText)
+
+	cacheItem := CacheItem{
+		Hash:           getCacheKey(sourceLanguage, cacheTargetLanguage(ctx, targetLanguage), token),
+		TranslatedText: translateResponse.TranslatedText,
+		SourceText:     token,
+		SourceLanguage: sourceLanguage,
+		TargetLanguage: targetLanguage,
+		Region:         region,
+		Provider:       provider,
+		Route:          route.Pair,
+	}
+	if translateRegion != nil {
+		cacheItem.TranslateRegion = *translateRegion
+	}
+	if skipCacheWrite(ctx, cacheItem) {
+		return translateResponse.TranslatedText, nil
+	}
+
+	if err := h.cacheTranslation(ctx, cacheItem); err != nil {
+		return "", fmt.Errorf("error caching translation: %w", err)
+	}
+	if cacheReverseEntries && cacheSourceRetention == "" && cacheTargetLanguage(ctx, targetLanguage) == targetLanguage {
+		// A styled, shortened, inclusive or glossary translation isn't the plain translation
+		// of its output, and the reverse item would store the source text as its translation
+		h.cacheReverseTranslation(ctx, cacheItem)
+	}
+
+	return translateResponse.TranslatedText, nil
+}
+
+// joinSentences joins the translated sentences into a single string
+func joinSentences(sentences []string) string {
+	translatedText := strings.Builder{}
+	for _, sentence := range sentences {
+		translatedText.WriteString(sentence) // The error is always nil
+		translatedText.WriteString(" ")
+	}
+	return translatedText.String()
+}
+
+func shouldCacheBeUsed(ctx context.Context, dynamoClient DynamoDBClient, sourceLanguage, targetLanguage, text string) (CacheItem, bool, error) {
 	// Check if the hash exists in the DynamoDB table
+	return getCacheItem(ctx, dynamoClient, translateTableName, getCacheKey(sourceLanguage, targetLanguage, text))
+}
+
+// getCacheItem reads the cache item with the given hash from the table
+func getCacheItem(ctx context.Context, dynamoClient DynamoDBClient, tableName, hash string) (CacheItem, bool, error) {
 	useCache := false
 	var cacheItem CacheItem
 
 	response, err := dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(translateTableName),
+		TableName: aws.String(tableName),
 		Key: map[string]types.AttributeValue{
 			"hash": &types.AttributeValueMemberS{
 				Value: hash,
@@ -258,30 +1468,143 @@ func shouldCacheBeUsed(ctx context.Context, dynamoClient DynamoDBClient, sourceL
 		return cacheItem, useCache, nil
 	}
 
-	// Build the cache item from the response
-	cacheItem = CacheItem{
-		Hash:           response.Item["hash"].(*types.AttributeValueMemberS).Value,
-		TranslatedText: response.Item["translated_text"].(*types.AttributeValueMemberS).Value,
-		SourceText:     response.Item["source_text"].(*types.AttributeValueMemberS).Value,
-		SourceLanguage: response.Item["source_language"].(*types.AttributeValueMemberS).Value,
-		TargetLanguage: response.Item["target_language"].(*types.AttributeValueMemberS).Value,
+	// Build the cache item from the response, malformed items are treated as a miss
+	cacheItem, ok := cacheItemFromAttributes(response.Item)
+	if !ok {
+		return CacheItem{}, useCache, nil
 	}
 
 	return cacheItem, true, nil
 }
 
+// cacheItemFromAttributes builds a cache item from its DynamoDB attributes, reporting
+// whether all the attributes were present
+func cacheItemFromAttributes(item map[string]types.AttributeValue) (CacheItem, bool) {
+	values := make(map[string]string, len(item))
+	for _, name := range []string{"hash", "source_language", "target_language"} {
+		value, ok := item[name].(*types.AttributeValueMemberS)
+		if !ok {
+			return CacheItem{}, false
+		}
+		values[name] = value.Value
+	}
+
+	// The text attributes may be compressed, as recorded by the encoding attribute
+	encoding := ""
+	if value, ok := item["encoding"].(*types.AttributeValueMemberS); ok {
+		encoding = value.Value
+	}
+	// The source text is missing from the items stored without it
+	sourceRetention := ""
+	if value, ok := item["source_retention"].(*types.AttributeValueMemberS); ok {
+		sourceRetention = value.Value
+	}
+	for _, name := range []string{"translated_text", "source_text"} {
+		if name == "source_text" && sourceRetention == sourceRetentionOmit {
+			continue
+		}
+		text, ok := decodeTextAttribute(item[name], encoding)
+		if !ok {
+			return CacheItem{}, false
+		}
+		values[name] = text
+	}
+
+	cacheItem := CacheItem{
+		Hash:            values["hash"],
+		TranslatedText:  values["translated_text"],
+		SourceText:      values["source_text"],
+		SourceLanguage:  values["source_language"],
+		TargetLanguage:  values["target_language"],
+		SourceRetention: sourceRetention,
+	}
+
+	// The region is optional as items written before it was introduced don't have it
+	if value, ok := item["region"].(*types.AttributeValueMemberS); ok {
+		cacheItem.Region = value.Value
+	}
+	if value, ok := item["overflow_key"].(*types.AttributeValueMemberS); ok {
+		cacheItem.OverflowKey = value.Value
+	}
+	if value, ok := item["failure"].(*types.AttributeValueMemberS); ok {
+		cacheItem.Failure = value.Value
+	}
+	if value, ok := item["source_hash"].(*types.AttributeValueMemberS); ok {
+		cacheItem.SourceHash = value.Value
+	}
+	if value, ok := item["translated_hash"].(*types.AttributeValueMemberS); ok {
+		cacheItem.TranslatedHash = value.Value
+	}
+	if value, ok := item["created_at"].(*types.AttributeValueMemberN); ok {
+		cacheItem.CreatedAt, _ = strconv.ParseInt(value.Value, 10, 64)
+	}
+	if value, ok := item["provider"].(*types.AttributeValueMemberS); ok {
+		cacheItem.Provider = value.Value
+	}
+	if value, ok := item["translate_region"].(*types.AttributeValueMemberS); ok {
+		cacheItem.TranslateRegion = value.Value
+	}
+	if value, ok := item["route"].(*types.AttributeValueMemberS); ok {
+		cacheItem.Route = value.Value
+	}
+	if value, ok := item["pivot"].(*types.AttributeValueMemberS); ok {
+		cacheItem.Pivot = value.Value
+	}
+	if value, ok := item["source_chars"].(*types.AttributeValueMemberN); ok {
+		cacheItem.SourceCharacters, _ = strconv.Atoi(value.Value)
+	}
+	if value, ok := item["translated_chars"].(*types.AttributeValueMemberN); ok {
+		cacheItem.TranslatedCharacters, _ = strconv.Atoi(value.Value)
+	}
+	if value, ok := item["hits"].(*types.AttributeValueMemberN); ok {
+		cacheItem.Hits, _ = strconv.ParseInt(value.Value, 10, 64)
+	}
+	if value, ok := item["last_hit_at"].(*types.AttributeValueMemberN); ok {
+		cacheItem.LastHitAt, _ = strconv.ParseInt(value.Value, 10, 64)
+	}
+	if value, ok := item["ratings"].(*types.AttributeValueMemberN); ok {
+		cacheItem.Ratings, _ = strconv.ParseInt(value.Value, 10, 64)
+	}
+	if value, ok := item["rating_sum"].(*types.AttributeValueMemberN); ok {
+		cacheItem.RatingSum, _ = strconv.ParseInt(value.Value, 10, 64)
+	}
+	if value, ok := item["expires_at"].(*types.AttributeValueMemberN); ok {
+		cacheItem.ExpiresAt, _ = strconv.ParseInt(value.Value, 10, 64)
+	}
+	if value, ok := item["derived"].(*types.AttributeValueMemberBOOL); ok {
+		cacheItem.Derived = value.Value
+	}
+	if value, ok := item["authoritative"].(*types.AttributeValueMemberBOOL); ok {
+		cacheItem.Authoritative = value.Value
+	}
+	if value, ok := item["retranslation"].(*types.AttributeValueMemberS); ok {
+		cacheItem.Retranslation = value.Value
+	}
+
+	return cacheItem, true
+}
+
 func translateLanguage(ctx context.Context, translateClient TranslateClient, text, sourceLanguage, targetLanguage string) (TranslateResponse, error) {
 	// Translate the text using the AWS Translate service
 	input := &translate.TranslateTextInput{
 		SourceLanguageCode: aws.String(sourceLanguage),
 		TargetLanguageCode: aws.String(targetLanguage),
 		Text:               aws.String(text),
+		Settings:           translationSettings(ctx),
+		TerminologyNames:   terminologyNames(ctx, targetLanguage),
 	}
 
 	output, err := translateClient.TranslateText(ctx, input)
 	if err != nil {
 		return TranslateResponse{}, err
 	}
+	// The language the provider detected may be one the policy blocks or the caller isn't
+	// entitled to
+	if sourceLanguage == autoDetectLanguage && output.SourceLanguageCode != nil {
+		if err := checkDetectedLanguage(ctx, normalizeLanguageCode(*output.SourceLanguageCode), targetLanguage); err != nil {
+			return TranslateResponse{}, err
+		}
+	}
 
 	// TODO - See if we can get detected lang and confidence
 	return TranslateResponse{
@@ -289,39 +1612,186 @@ func translateLanguage(ctx context.Context, translateClient TranslateClient, tex
 	}, nil
 }
 
-func cacheTranslatedText(ctx context.Context, dynamoClient DynamoDBClient, item CacheItem) error {
-	// Store the translated text in the DynamoDB table
-	_, err := dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(translateTableName),
-		Item: map[string]types.AttributeValue{
-			"hash": &types.AttributeValueMemberS{
-				Value: item.Hash,
-			},
-			"translated_text": &types.AttributeValueMemberS{
-				Value: item.TranslatedText,
-			},
-			"source_text": &types.AttributeValueMemberS{
-				Value: item.SourceText,
-			},
-			"source_language": &types.AttributeValueMemberS{
-				Value: item.SourceLanguage,
-			},
-			"target_language": &types.AttributeValueMemberS{
-				Value: item.TargetLanguage,
-			},
-		},
-	})
+// detectRequestLanguage detects the language of the text of a text or html request
+func (h *handler) detectRequestLanguage(ctx context.Context, request TranslateRequest) (string, error) {
+	text, err := requestPlainText(request)
+	if err != nil {
+		return "", err
+	}
+	return detectLanguage(ctx, h.comprehendClient, text)
+}
 
-	return err
+// requestPlainText returns the text of a text or html request, the text content of an HTML
+// document
+func requestPlainText(request TranslateRequest) (string, error) {
+	if request.Format != formatHTML {
+		return request.Text, nil
+	}
+	_, sentences, _, err := getTextFromHTML(request.Text, defaultSegmenter)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(sentences, " "), nil
 }
 
-func doesTargetLanguageExist(ctx context.Context, translateClient TranslateClient, targetLanguage string) (bool, error) {
-	languages, err := getSupportedLanguages(ctx, translateClient)
+// detectLanguage returns the dominant language of the text as detected by Amazon Comprehend.
+// Only a prefix of the text is sent to keep the call cheap.
+func detectLanguage(ctx context.Context, comprehendClient ComprehendClient, text string) (string, error) {
+	sample := []rune(strings.TrimSpace(text))
+	if len(sample) == 0 {
+		return "", fmt.Errorf("no text to detect the language of")
+	}
+	if len(sample) > maxDetectionSampleLength {
+		sample = sample[:maxDetectionSampleLength]
+	}
+	if comprehendClient == nil {
+		return "", fmt.Errorf("no Comprehend client to detect the language with")
+	}
+
+	output, err := comprehendClient.DetectDominantLanguage(ctx, &comprehend.DetectDominantLanguageInput{
+		Text: aws.String(string(sample)),
+	})
 	if err != nil {
-		return false, err
+		return "", err
+	}
+
+	var dominant *comprehendTypes.DominantLanguage
+	for i, language := range output.Languages {
+		if language.LanguageCode != nil && (dominant == nil || aws.ToFloat32(language.Score) > aws.ToFloat32(dominant.Score)) {
+			dominant = &output.Languages[i]
+		}
+	}
+	if dominant == nil {
+		return "", fmt.Errorf("no language detected by Amazon Comprehend")
+	}
+
+	return *dominant.LanguageCode, nil
+}
+
+func cacheTranslatedText(ctx context.Context, dynamoClient DynamoDBClient, item CacheItem) error {
+	// A derived item never replaces a translation of the provider
+	return putCacheItem(ctx, dynamoClient, item, item.Derived)
+}
+
+// putCacheItem writes the item to the translate table. When ifAbsent is set, an item already
+// cached under the hash is kept as it is, along with its hits and ratings.
+func putCacheItem(ctx context.Context, dynamoClient DynamoDBClient, item CacheItem, ifAbsent bool) error {
+	attributes := map[string]types.AttributeValue{
+		"hash": &types.AttributeValueMemberS{
+			Value: item.Hash,
+		},
+		"source_language": &types.AttributeValueMemberS{
+			Value: item.SourceLanguage,
+		},
+		"target_language": &types.AttributeValueMemberS{
+			Value: item.TargetLanguage,
+		},
+	}
+	encodeTextAttributes(attributes, item.SourceText, item.TranslatedText)
+	if item.SourceRetention != "" {
+		attributes["source_retention"] = &types.AttributeValueMemberS{
+			Value: item.SourceRetention,
+		}
+		if item.SourceRetention == sourceRetentionOmit {
+			delete(attributes, "source_text")
+		}
+	}
+	if item.Region != "" {
+		attributes["region"] = &types.AttributeValueMemberS{
+			Value: item.Region,
+		}
+	}
+	if item.OverflowKey != "" {
+		attributes["overflow_key"] = &types.AttributeValueMemberS{
+			Value: item.OverflowKey,
+		}
+	}
+	if item.Failure != "" {
+		attributes["failure"] = &types.AttributeValueMemberS{
+			Value: item.Failure,
+		}
+	}
+	if item.CreatedAt != 0 {
+		attributes["created_at"] = &types.AttributeValueMemberN{
+			Value: strconv.FormatInt(item.CreatedAt, 10),
+		}
+	}
+	if item.Provider != "" {
+		attributes["provider"] = &types.AttributeValueMemberS{
+			Value: item.Provider,
+		}
+	}
+	if item.TranslateRegion != "" {
+		attributes["translate_region"] = &types.AttributeValueMemberS{
+			Value: item.TranslateRegion,
+		}
+	}
+	if item.Route != "" {
+		attributes["route"] = &types.AttributeValueMemberS{
+			Value: item.Route,
+		}
+	}
+	if item.Pivot != "" {
+		attributes["pivot"] = &types.AttributeValueMemberS{
+			Value: item.Pivot,
+		}
+	}
+	if item.SourceCharacters != 0 {
+		attributes["source_chars"] = &types.AttributeValueMemberN{
+			Value: strconv.Itoa(item.SourceCharacters),
+		}
+	}
+	if item.TranslatedCharacters != 0 {
+		attributes["translated_chars"] = &types.AttributeValueMemberN{
+			Value: strconv.Itoa(item.TranslatedCharacters),
+		}
+	}
+	if item.SourceHash != "" {
+		attributes["source_hash"] = &types.AttributeValueMemberS{
+			Value: item.SourceHash,
+		}
+	}
+	if item.TranslatedHash != "" {
+		attributes["translated_hash"] = &types.AttributeValueMemberS{
+			Value: item.TranslatedHash,
+		}
+	}
+	if item.ExpiresAt != 0 {
+		attributes["expires_at"] = &types.AttributeValueMemberN{
+			Value: strconv.FormatInt(item.ExpiresAt, 10),
+		}
+	}
+	if item.Authoritative {
+		attributes["authoritative"] = &types.AttributeValueMemberBOOL{
+			Value: true,
+		}
+	}
+	if item.Retranslation != "" {
+		attributes["retranslation"] = &types.AttributeValueMemberS{
+			Value: item.Retranslation,
+		}
+	}
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(translateTableName),
+		Item:      attributes,
+	}
+	if item.Derived {
+		attributes["derived"] = &types.AttributeValueMemberBOOL{
+			Value: true,
+		}
+	}
+	if ifAbsent {
+		input.ConditionExpression = aws.String("attribute_not_exists(#hash)")
+		input.ExpressionAttributeNames = map[string]string{"#hash": "hash"}
 	}
 
-	return slices.Contains(languages, targetLanguage), nil
+	// Store the translated text in the DynamoDB table
+	_, err := dynamoClient.PutItem(ctx, input)
+	if ifAbsent && errors.As(err, new(*types.ConditionalCheckFailedException)) {
+		return nil
+	}
+
+	return err
 }
 
 func getSupportedLanguages(ctx context.Context, translateClient TranslateClient) ([]string, error) {
@@ -342,14 +1812,97 @@ func getSupportedLanguages(ctx context.Context, translateClient TranslateClient)
 	return languages, nil
 }
 
+// getCacheKey returns the hash identifying the translation of the text in the cache
+func getCacheKey(sourceLanguage, targetLanguage, text string) string {
+	return getHashFromText(sourceLanguage + "-" + targetLanguage + "-" + text)
+}
+
 func getHashFromText(text string) string {
 	hash := sha256.Sum256([]byte(text))
 	return hex.EncodeToString(hash[:])
 }
 
-func splitSentences(input string) []string {
-	segmenter := sentencizer.NewSegmenter("en")
-	return segmenter.Segment(input)
+// removeSpaces returns the text without its whitespace
+func removeSpaces(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// requestFromParameters maps the query string and path parameters of a GET request, which
+// API Gateway has already URL decoded. The source language is detected unless given.
+func requestFromParameters(event events.APIGatewayProxyRequest) TranslateRequest {
+	parameter := func(names ...string) string {
+		for _, name := range names {
+			if value := event.PathParameters[name]; value != "" {
+				return value
+			}
+			if value := event.QueryStringParameters[name]; value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	request := TranslateRequest{
+		SourceLanguage:    parameter("source_language", "source"),
+		TargetLanguage:    parameter("target_language", "target"),
+		Text:              parameter("text"),
+		Format:            parameter("format"),
+		Moderation:        parameter("moderation"),
+		Transliterate:     parameter("transliterate") == "true",
+		Style:             parameter("style"),
+		NoStore:           parameter("no_store") == "true",
+		DryRun:            parameter("dry_run") == "true",
+		Alignment:         parameter("alignment") == "true",
+		LocalizeFormats:   parameter("localize_formats") == "true",
+		Typography:        parameter("typography"),
+		PreserveCasing:    parameter("preserve_casing") == "true",
+		Truncation:        parameter("truncation"),
+		Inclusive:         parameter("inclusive") == "true",
+		Analyze:           parameter("analyze") == "true",
+		TranslateEntities: parameter("translate_entities") == "true",
+		GrammarCheck:      parameter("grammar_check") == "true",
+		EchoParameters:    parameter("echo_parameters") == "true",
+		LanguageCodeCase:  parameter("language_code_case"),
+		TranslateMetadata: parameter("translate_metadata") == "true",
+		Priority:          parameter("priority"),
+		Granularity:       parameter("granularity"),
+		Disclaimer:        parameter("disclaimer"),
+	}
+	if request.SourceLanguage == "" {
+		request.SourceLanguage = autoDetectLanguage
+	}
+	if maxCharacters := parameter("max_characters"); maxCharacters != "" {
+		// An unparseable cap is rejected by the validation
+		var err error
+		if request.MaxCharacters, err = strconv.Atoi(maxCharacters); err != nil {
+			request.MaxCharacters = -1
+		}
+	}
+	if maxLength := parameter("max_length"); maxLength != "" {
+		var err error
+		if request.MaxLength, err = strconv.Atoi(maxLength); err != nil {
+			request.MaxLength = -1
+		}
+	}
+	if alternatives := parameter("alternatives"); alternatives != "" {
+		var err error
+		if request.Alternatives, err = strconv.Atoi(alternatives); err != nil {
+			request.Alternatives = -1
+		}
+	}
+	if deadlineMS := parameter("deadline_ms"); deadlineMS != "" {
+		var err error
+		if request.DeadlineMS, err = strconv.Atoi(deadlineMS); err != nil {
+			request.DeadlineMS = -1
+		}
+	}
+
+	return request
 }
 
 func unmarshalRequest(body []byte) (TranslateRequest, error) {
@@ -371,14 +1924,126 @@ func marshalResponse(response TranslateResponse) ([]byte, error) {
 }
 
 func validateRequest(request TranslateRequest) error {
-	if request.SourceLanguage == "" {
-		return fmt.Errorf("source_language is required")
+	errs := &validationError{}
+
+	validateLanguageCode(errs, "source_language", request.SourceLanguage, true)
+	validateLanguageCode(errs, "target_language", request.TargetLanguage, false)
+	// Transliteration romanizes the text whatever its language
+	if request.SourceLanguage != "" && strings.EqualFold(request.SourceLanguage, request.TargetLanguage) && !request.Transliterate {
+		errs.add("target_language", "target_language must differ from source_language")
+	}
+
+	switch request.Format {
+	case "", formatText, formatHTML:
+		switch {
+		case request.Text == "" && request.URL == "":
+			errs.add("text", "text is required")
+		case !utf8.ValidString(request.Text):
+			errs.add("text", "text must be valid UTF-8")
+		case len(splitSentences(request.Text)) > maxRequestSentences:
+			errs.add("text", "text must not exceed %d sentences", maxRequestSentences)
+		}
+	case formatPDF, formatEmail, formatAndroidStrings, formatIOSStrings, formatIOSStringsDict:
+		if request.Document == "" {
+			errs.add("document", "document is required")
+		}
+	case formatPlural:
+		if request.Plurals["other"] == "" {
+			errs.add("plurals", "plurals must contain the other form")
+		}
+		for name, form := range request.Plurals {
+			if !utf8.ValidString(form) {
+				errs.add("plurals", "plurals %s form must be valid UTF-8", name)
+			}
+		}
+	case formatCSV:
+		if request.Document == "" && request.Key == "" {
+			errs.add("document", "document or key is required")
+		}
+		if len(request.Columns) == 0 {
+			errs.add("columns", "columns is required")
+		}
+	default:
+		errs.add("format", "format %q is not supported", request.Format)
+	}
+	switch request.Output {
+	case "", outputText, outputBlocks:
+	default:
+		errs.add("output", "output %q is not supported", request.Output)
+	}
+	if request.Transliterate && request.Format != "" && request.Format != formatText && request.Format != formatHTML {
+		errs.add("transliterate", "transliterate is only supported for text and html")
+	}
+	if request.DryRun && request.Format != "" && request.Format != formatText && request.Format != formatHTML && request.Format != formatCSV {
+		errs.add("dry_run", "dry_run is only supported for text, html and csv")
+	}
+	if request.Alignment && request.Format != "" && request.Format != formatText {
+		errs.add("alignment", "alignment is only supported for text")
+	}
+	if request.TranslateMetadata && request.Format != formatHTML {
+		errs.add("translate_metadata", "translate_metadata is only supported for html")
+	}
+	validatePageURLField(errs, request)
+	validateDelta(errs, request)
+	if request.MaxCharacters < 0 {
+		errs.add("max_characters", "max_characters must be a positive number")
+	}
+	validateMaxLength(errs, request)
+	if request.Alternatives < 0 || request.Alternatives > maxAlternatives {
+		errs.add("alternatives", "alternatives must be between 0 and %d", maxAlternatives)
 	}
-	if request.TargetLanguage == "" {
-		return fmt.Errorf("target_language is required")
+	if request.Alternatives > 0 && ((request.Format != "" && request.Format != formatText) || request.DryRun || request.Alignment || request.PreviousText != "" || request.MaxLength > 0) {
+		errs.add("alternatives", "alternatives are only supported for text, without dry_run, alignment, previous_text or max_length")
 	}
-	if request.Text == "" {
-		return fmt.Errorf("text is required")
+	if request.EchoParameters && request.Format != "" && request.Format != formatText && request.Format != formatHTML {
+		errs.add("echo_parameters", "echo_parameters is only supported for text and html")
+	}
+	switch request.LanguageCodeCase {
+	case "", languageCodeCaseCanonical, languageCodeCaseLower, languageCodeCaseUpper:
+	default:
+		errs.add("language_code_case", "language_code_case %q is not supported", request.LanguageCodeCase)
+	}
+	if request.GrammarCheck && grammarCheckURL == "" {
+		errs.add("grammar_check", "grammar_check is not enabled")
+	}
+	if request.Analyze && ((request.Format != "" && request.Format != formatText && request.Format != formatHTML) || request.DryRun) {
+		errs.add("analyze", "analyze is only supported for text and html, without dry_run")
+	}
+	switch request.Moderation {
+	case "", moderationFlag, moderationMask:
+	default:
+		errs.add("moderation", "moderation %q is not supported", request.Moderation)
+	}
+	if _, ok := styleSettings[request.Style]; request.Style != "" && !ok {
+		errs.add("style", "style %q is not supported", request.Style)
+	}
+	if _, err := parseTypography(request.Typography); err != nil {
+		errs.add("typography", "%v", err)
+	}
+	switch request.Priority {
+	case "", priorityInteractive, priorityBulk:
+	default:
+		errs.add("priority", "priority %q is not supported", request.Priority)
+	}
+	switch request.Granularity {
+	case "", granularitySentence, granularityParagraph, granularityBlock:
+	default:
+		errs.add("granularity", "granularity %q is not supported", request.Granularity)
+	}
+	switch request.Disclaimer {
+	case "", disclaimerPrepend, disclaimerAppend:
+		if request.Disclaimer != "" && !slices.Contains(disclaimerFormats, request.Format) {
+			errs.add("disclaimer", "disclaimer is only supported for text, html, pdf and email")
+		}
+	default:
+		errs.add("disclaimer", "disclaimer %q is not supported", request.Disclaimer)
+	}
+	if request.DeadlineMS < 0 {
+		errs.add("deadline_ms", "deadline_ms must be a positive number")
+	}
+
+	if len(errs.Fields) > 0 {
+		return errs
 	}
 	return nil
 }
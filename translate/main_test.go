@@ -4,13 +4,22 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"reflect"
 	"slices"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/comprehend"
+	comprehendTypes "github.com/aws/aws-sdk-go-v2/service/comprehend/types"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/textract"
 	"github.com/aws/aws-sdk-go-v2/service/translate"
 	"github.com/aws/aws-sdk-go-v2/service/translate/types"
 )
@@ -116,13 +125,194 @@ func TestUnmarshalRequest(t *testing.T) {
 				return
 			}
 
-			if got != tt.expected && !tt.wantErr {
+			if !reflect.DeepEqual(got, tt.expected) && !tt.wantErr {
 				t.Errorf("unmarshalRequest() = %v, expected %v", got, tt.expected)
 			}
 		})
 	}
 }
 
+func TestGetEnvInt(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected int
+	}{
+		{name: "Valid integer", value: "42", expected: 42},
+		{name: "Invalid integer", value: "forty-two", expected: 7},
+		{name: "Missing value", value: "", expected: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOTRANSLATE_TEST_INT", tt.value)
+
+			got := getEnvInt("GOTRANSLATE_TEST_INT", 7)
+			if got != tt.expected {
+				t.Errorf("getEnvInt() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRequestFromParameters(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    events.APIGatewayProxyRequest
+		expected TranslateRequest
+	}{
+		{
+			name: "Short query parameters",
+			event: events.APIGatewayProxyRequest{
+				HTTPMethod:            http.MethodGet,
+				QueryStringParameters: map[string]string{"text": "Hello world", "target": "es"},
+			},
+			expected: TranslateRequest{SourceLanguage: "auto", TargetLanguage: "es", Text: "Hello world"},
+		},
+		{
+			name: "Full query parameters",
+			event: events.APIGatewayProxyRequest{
+				HTTPMethod: http.MethodGet,
+				QueryStringParameters: map[string]string{
+					"text":            "<p>Hello</p>",
+					"source_language": "en",
+					"target_language": "fr",
+					"format":          "html",
+					"moderation":      "mask",
+				},
+			},
+			expected: TranslateRequest{SourceLanguage: "en", TargetLanguage: "fr", Text: "<p>Hello</p>", Format: "html", Moderation: "mask"},
+		},
+		{
+			name: "Path parameter",
+			event: events.APIGatewayProxyRequest{
+				HTTPMethod:            http.MethodGet,
+				PathParameters:        map[string]string{"target": "de"},
+				QueryStringParameters: map[string]string{"text": "Привет", "transliterate": "true"},
+			},
+			expected: TranslateRequest{SourceLanguage: "auto", TargetLanguage: "de", Text: "Привет", Transliterate: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := requestFromParameters(tt.event); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("requestFromParameters() = %+v, expected %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandleGet(t *testing.T) {
+	h := newMockHandler(map[string]string{"Hello": "Hola"})
+
+	got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod:            http.MethodGet,
+		QueryStringParameters: map[string]string{"text": "Hello", "source": "en", "target": "es"},
+	})
+	if err != nil {
+		t.Errorf("handle() error = %v", err)
+		return
+	}
+
+	if got.StatusCode != http.StatusOK || got.Body != `{"translated_text":"Hola "}` {
+		t.Errorf("handle() = %v, expected translated text", got)
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   TranslateRequest
+		wantErr bool
+	}{
+		{
+			name:    "Valid text request",
+			input:   TranslateRequest{SourceLanguage: "en", TargetLanguage: "es", Text: "Hello"},
+			wantErr: false,
+		},
+		{
+			name:    "Missing source language",
+			input:   TranslateRequest{TargetLanguage: "es", Text: "Hello"},
+			wantErr: true,
+		},
+		{
+			name:    "Missing target language",
+			input:   TranslateRequest{SourceLanguage: "en", Text: "Hello"},
+			wantErr: true,
+		},
+		{
+			name:    "Missing text",
+			input:   TranslateRequest{SourceLanguage: "en", TargetLanguage: "es"},
+			wantErr: true,
+		},
+		{
+			name:    "Valid PDF request",
+			input:   TranslateRequest{SourceLanguage: "en", TargetLanguage: "es", Format: formatPDF, Document: "JVBERi0=", Output: outputBlocks},
+			wantErr: false,
+		},
+		{
+			name:    "PDF request without document",
+			input:   TranslateRequest{SourceLanguage: "en", TargetLanguage: "es", Format: formatPDF},
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported format",
+			input:   TranslateRequest{SourceLanguage: "en", TargetLanguage: "es", Text: "Hello", Format: "docx"},
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported output",
+			input:   TranslateRequest{SourceLanguage: "en", TargetLanguage: "es", Text: "Hello", Output: "xml"},
+			wantErr: true,
+		},
+		{
+			name:    "Regional and auto detected languages",
+			input:   TranslateRequest{SourceLanguage: "auto", TargetLanguage: "zh-TW", Text: "Hello"},
+			wantErr: false,
+		},
+		{
+			name:    "Malformed language code",
+			input:   TranslateRequest{SourceLanguage: "en", TargetLanguage: "spanish", Text: "Hello"},
+			wantErr: true,
+		},
+		{
+			name:    "Target language can't be auto",
+			input:   TranslateRequest{SourceLanguage: "en", TargetLanguage: "auto", Text: "Hello"},
+			wantErr: true,
+		},
+		{
+			name:    "Identical source and target",
+			input:   TranslateRequest{SourceLanguage: "es", TargetLanguage: "ES", Text: "Hola"},
+			wantErr: true,
+		},
+		{
+			name:    "Identical source and target are transliterated",
+			input:   TranslateRequest{SourceLanguage: "ru", TargetLanguage: "ru", Text: "Привет", Transliterate: true},
+			wantErr: false,
+		},
+		{
+			name:    "Invalid UTF-8",
+			input:   TranslateRequest{SourceLanguage: "en", TargetLanguage: "es", Text: "Hello \xff"},
+			wantErr: true,
+		},
+		{
+			name:    "Too many sentences",
+			input:   TranslateRequest{SourceLanguage: "en", TargetLanguage: "es", Text: strings.Repeat("Hello. ", defaultMaxRequestSentences+1)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRequest(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestSplitSentences(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -219,6 +409,27 @@ func TestGetHashFromText(t *testing.T) {
 	}
 }
 
+// benchmarkText is a paragraph of typical request text, the benchmarks report the cost of
+// each of its sentences
+var benchmarkText = strings.Repeat("The order shipped on Monday. Dr. Smith will call you at 5 p.m. to confirm the delivery! Did you receive the invoice? ", 10)
+
+func BenchmarkSplitSentences(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		splitSentences(benchmarkText)
+	}
+}
+
+func BenchmarkGetCacheKey(b *testing.B) {
+	sentences := splitSentences(benchmarkText)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		getCacheKey("en", "es", sentences[i%len(sentences)])
+	}
+}
+
 func TestGetSupportedLanguages(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -336,7 +547,10 @@ func TestDoesTargetLanguageExist(t *testing.T) {
 				},
 			}
 
-			got, err := doesTargetLanguageExist(context.Background(), mockClient, tt.targetLanguage)
+			h := newMockHandler(nil)
+			h.translateClient = mockClient
+
+			got, err := h.doesTargetLanguageExist(context.Background(), tt.targetLanguage)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("doesTargetLanguageExist() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -380,12 +594,44 @@ func TestCacheTranslatedText(t *testing.T) {
 			mockError: fmt.Errorf("mock error"),
 			wantErr:   true,
 		},
+		{
+			name: "Successful cache with region",
+			cacheItem: CacheItem{
+				Hash:           "test-hash",
+				TranslatedText: "Hola",
+				SourceText:     "Hello",
+				SourceLanguage: "en",
+				TargetLanguage: "es",
+				Region:         "eu-west-1",
+			},
+			mockError: nil,
+			wantErr:   false,
+		},
+		{
+			name: "Derived item already cached",
+			cacheItem: CacheItem{
+				Hash:           "test-hash",
+				TranslatedText: "Hello",
+				SourceText:     "Hola",
+				SourceLanguage: "es",
+				TargetLanguage: "en",
+				Derived:        true,
+			},
+			mockError: &dynamoTypes.ConditionalCheckFailedException{},
+			wantErr:   false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockClient := &MockDynamoDBClient{
 				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					if _, ok := params.Item["region"]; ok != (tt.cacheItem.Region != "") {
+						t.Errorf("cacheTranslatedText() region attribute written = %v, expected %v", ok, tt.cacheItem.Region != "")
+					}
+					if _, ok := params.Item["derived"]; ok != tt.cacheItem.Derived || (params.ConditionExpression != nil) != tt.cacheItem.Derived {
+						t.Errorf("cacheTranslatedText() derived attribute or condition written, expected %v", tt.cacheItem.Derived)
+					}
 					return nil, tt.mockError
 				},
 			}
@@ -466,13 +712,77 @@ func TestTranslateLanguage(t *testing.T) {
 				return
 			}
 
-			if got != tt.expected {
+			if !reflect.DeepEqual(got, tt.expected) {
 				t.Errorf("translateLanguage() = %v, expected %v", got, tt.expected)
 			}
 		})
 	}
 }
 
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		mockOutput *comprehend.DetectDominantLanguageOutput
+		mockError  error
+		expected   string
+		wantErr    bool
+	}{
+		{
+			name: "Language detected",
+			text: "Hola mundo.",
+			mockOutput: &comprehend.DetectDominantLanguageOutput{
+				Languages: []comprehendTypes.DominantLanguage{
+					{LanguageCode: aws.String("pt"), Score: aws.Float32(0.2)},
+					{LanguageCode: aws.String("es"), Score: aws.Float32(0.8)},
+				},
+			},
+			expected: "es",
+			wantErr:  false,
+		},
+		{
+			name:     "Empty text",
+			text:     "   ",
+			expected: "",
+			wantErr:  true,
+		},
+		{
+			name:      "Error from Comprehend",
+			text:      "Hola mundo.",
+			mockError: fmt.Errorf("mock error"),
+			expected:  "",
+			wantErr:   true,
+		},
+		{
+			name:       "No language returned",
+			text:       "Hola mundo.",
+			mockOutput: &comprehend.DetectDominantLanguageOutput{},
+			expected:   "",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockComprehendClient{
+				DetectDominantLanguageFunc: func(ctx context.Context, params *comprehend.DetectDominantLanguageInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectDominantLanguageOutput, error) {
+					return tt.mockOutput, tt.mockError
+				},
+			}
+
+			got, err := detectLanguage(context.Background(), mockClient, tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("detectLanguage() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != tt.expected {
+				t.Errorf("detectLanguage() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestShouldCacheBeUsed(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -521,6 +831,22 @@ func TestShouldCacheBeUsed(t *testing.T) {
 			expectedUse:    false,
 			wantErr:        false,
 		},
+		{
+			name:           "Malformed cache item",
+			sourceLanguage: "en",
+			targetLanguage: "es",
+			text:           "Hello",
+			mockResponse: &dynamodb.GetItemOutput{
+				Item: map[string]dynamoTypes.AttributeValue{
+					"hash":            &dynamoTypes.AttributeValueMemberS{Value: "test-hash"},
+					"translated_text": &dynamoTypes.AttributeValueMemberN{Value: "1"},
+				},
+			},
+			mockError:     nil,
+			expectedCache: CacheItem{},
+			expectedUse:   false,
+			wantErr:       false,
+		},
 		{
 			name:           "DynamoDB error",
 			sourceLanguage: "en",
@@ -594,6 +920,9 @@ func TestHandle(t *testing.T) {
 						},
 					}, nil
 				},
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					return &dynamodb.PutItemOutput{}, nil
+				},
 			},
 			expectedResponse: events.APIGatewayProxyResponse{
 				StatusCode: http.StatusOK,
@@ -661,7 +990,14 @@ func TestHandle(t *testing.T) {
 					}, nil
 				},
 			},
-			mockDynamoDBClient: &MockDynamoDBClient{},
+			mockDynamoDBClient: &MockDynamoDBClient{
+				GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					return &dynamodb.GetItemOutput{Item: nil}, nil
+				},
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					return &dynamodb.PutItemOutput{}, nil
+				},
+			},
 			expectedResponse: events.APIGatewayProxyResponse{
 				StatusCode: http.StatusUnprocessableEntity,
 				Body:       "Target language not supported",
@@ -686,6 +1022,9 @@ func TestHandle(t *testing.T) {
 				GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
 					return nil, fmt.Errorf("mock error")
 				},
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					return &dynamodb.PutItemOutput{}, nil
+				},
 			},
 			expectedResponse: events.APIGatewayProxyResponse{
 				StatusCode: http.StatusInternalServerError,
@@ -715,10 +1054,111 @@ func TestHandle(t *testing.T) {
 	}
 }
 
+func TestHandleSkipIdentity(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		detected         string
+		expectedResponse events.APIGatewayProxyResponse
+		expectedCalls    int
+	}{
+		{
+			name: "Declared source equals target is rejected",
+			body: `{"source_language":"es","target_language":"es","text":"Hola"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"target_language","message":"target_language must differ from source_language"}]}`,
+			},
+			expectedCalls: 0,
+		},
+		{
+			name:     "Detected source equals target",
+			body:     `{"source_language":"auto","target_language":"es","text":"Hola"}`,
+			detected: "es",
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola","detected_language":"es","skipped":true}`,
+			},
+			expectedCalls: 0,
+		},
+		{
+			name:     "Detected source is translated",
+			body:     `{"source_language":"auto","target_language":"es","format":"html","text":"<p>Hello</p>"}`,
+			detected: "en",
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"\u003cp lang=\"es\"\u003eHola\u003c/p\u003e"}`,
+			},
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			h := newMockHandler(map[string]string{"Hello": "Hola"})
+			mockClient := h.translateClient.(*MockTranslateClient)
+			translateText := mockClient.TranslateTextFunc
+			mockClient.TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				calls++
+				return translateText(ctx, params, optFns...)
+			}
+			h.comprehendClient = newMockLanguageDetector(tt.detected)
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %v, expected %v", got, tt.expectedResponse)
+			}
+			if calls != tt.expectedCalls {
+				t.Errorf("TranslateText() called %d times, expected %d", calls, tt.expectedCalls)
+			}
+		})
+	}
+}
+
 // --
 // Mocks
 // --
 
+// newMockHandler returns a handler whose cache always misses and whose translate client looks
+// translations up in the given map, falling back to the source text
+func newMockHandler(translations map[string]string) *handler {
+	return &handler{
+		dynamoClient: &MockDynamoDBClient{
+			GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+				return &dynamodb.GetItemOutput{Item: nil}, nil
+			},
+			PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				return &dynamodb.PutItemOutput{}, nil
+			},
+		},
+		translateClient: &MockTranslateClient{
+			ListLanguagesFunc: func(ctx context.Context, params *translate.ListLanguagesInput, optFns ...func(*translate.Options)) (*translate.ListLanguagesOutput, error) {
+				return &translate.ListLanguagesOutput{
+					Languages: []types.Language{
+						{LanguageCode: aws.String("en")},
+						{LanguageCode: aws.String("es")},
+					},
+				}, nil
+			},
+			TranslateTextFunc: func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				translated, ok := translations[*params.Text]
+				if !ok {
+					translated = *params.Text
+				}
+				return &translate.TranslateTextOutput{
+					TranslatedText: aws.String(translated),
+				}, nil
+			},
+		},
+	}
+}
+
 // MockTranslateClient is a mock implementation of the TranslateClient interface
 type MockTranslateClient struct {
 	ListLanguagesFunc func(ctx context.Context, params *translate.ListLanguagesInput, optFns ...func(*translate.Options)) (*translate.ListLanguagesOutput, error)
@@ -733,10 +1173,134 @@ func (m *MockTranslateClient) TranslateText(ctx context.Context, params *transla
 	return m.TranslateTextFunc(ctx, params, optFns...)
 }
 
+// MockTextractClient is a mock implementation of the TextractClient interface
+type MockTextractClient struct {
+	DetectDocumentTextFunc func(ctx context.Context, params *textract.DetectDocumentTextInput, optFns ...func(*textract.Options)) (*textract.DetectDocumentTextOutput, error)
+}
+
+func (m *MockTextractClient) DetectDocumentText(ctx context.Context, params *textract.DetectDocumentTextInput, optFns ...func(*textract.Options)) (*textract.DetectDocumentTextOutput, error) {
+	return m.DetectDocumentTextFunc(ctx, params, optFns...)
+}
+
+// MockComprehendClient is a mock implementation of the ComprehendClient interface
+type MockComprehendClient struct {
+	DetectToxicContentFunc     func(ctx context.Context, params *comprehend.DetectToxicContentInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectToxicContentOutput, error)
+	DetectSentimentFunc        func(ctx context.Context, params *comprehend.DetectSentimentInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectSentimentOutput, error)
+	DetectEntitiesFunc         func(ctx context.Context, params *comprehend.DetectEntitiesInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectEntitiesOutput, error)
+	DetectDominantLanguageFunc func(ctx context.Context, params *comprehend.DetectDominantLanguageInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectDominantLanguageOutput, error)
+}
+
+func (m *MockComprehendClient) DetectToxicContent(ctx context.Context, params *comprehend.DetectToxicContentInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectToxicContentOutput, error) {
+	return m.DetectToxicContentFunc(ctx, params, optFns...)
+}
+
+func (m *MockComprehendClient) DetectSentiment(ctx context.Context, params *comprehend.DetectSentimentInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectSentimentOutput, error) {
+	return m.DetectSentimentFunc(ctx, params, optFns...)
+}
+
+func (m *MockComprehendClient) DetectEntities(ctx context.Context, params *comprehend.DetectEntitiesInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectEntitiesOutput, error) {
+	return m.DetectEntitiesFunc(ctx, params, optFns...)
+}
+
+func (m *MockComprehendClient) DetectDominantLanguage(ctx context.Context, params *comprehend.DetectDominantLanguageInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectDominantLanguageOutput, error) {
+	return m.DetectDominantLanguageFunc(ctx, params, optFns...)
+}
+
+// newMockLanguageDetector returns a Comprehend client detecting every text in the language
+func newMockLanguageDetector(language string) *MockComprehendClient {
+	return &MockComprehendClient{
+		DetectDominantLanguageFunc: func(ctx context.Context, params *comprehend.DetectDominantLanguageInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectDominantLanguageOutput, error) {
+			return &comprehend.DetectDominantLanguageOutput{
+				Languages: []comprehendTypes.DominantLanguage{{LanguageCode: aws.String(language), Score: aws.Float32(0.99)}},
+			}, nil
+		},
+	}
+}
+
+// MockSageMakerRuntimeClient is a mock implementation of the SageMakerRuntimeClient interface
+type MockSageMakerRuntimeClient struct {
+	InvokeEndpointFunc func(ctx context.Context, endpointName string, body []byte) ([]byte, error)
+}
+
+func (m *MockSageMakerRuntimeClient) InvokeEndpoint(ctx context.Context, endpointName string, body []byte) ([]byte, error) {
+	return m.InvokeEndpointFunc(ctx, endpointName, body)
+}
+
+// MockS3Client is a mock implementation of the S3Client interface
+type MockS3Client struct {
+	GetObjectFunc    func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObjectFunc    func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObjectFunc func(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+
+	CreateMultipartUploadFunc   func(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPartFunc              func(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUploadFunc func(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUploadFunc    func(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+}
+
+func (m *MockS3Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return m.GetObjectFunc(ctx, params, optFns...)
+}
+
+func (m *MockS3Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return m.PutObjectFunc(ctx, params, optFns...)
+}
+
+func (m *MockS3Client) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	return m.DeleteObjectFunc(ctx, params, optFns...)
+}
+
+func (m *MockS3Client) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return m.CreateMultipartUploadFunc(ctx, params, optFns...)
+}
+
+func (m *MockS3Client) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	return m.UploadPartFunc(ctx, params, optFns...)
+}
+
+func (m *MockS3Client) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return m.CompleteMultipartUploadFunc(ctx, params, optFns...)
+}
+
+func (m *MockS3Client) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return m.AbortMultipartUploadFunc(ctx, params, optFns...)
+}
+
+// MockSQSClient is a mock implementation of the SQSClient interface
+type MockSQSClient struct {
+	SendMessageFunc func(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+func (m *MockSQSClient) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	return m.SendMessageFunc(ctx, params, optFns...)
+}
+
+// MockKinesisClient is a mock implementation of the KinesisClient interface
+type MockKinesisClient struct {
+	PutRecordsFunc func(ctx context.Context, params *kinesis.PutRecordsInput, optFns ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error)
+}
+
+func (m *MockKinesisClient) PutRecords(ctx context.Context, params *kinesis.PutRecordsInput, optFns ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error) {
+	return m.PutRecordsFunc(ctx, params, optFns...)
+}
+
+// MockFirehoseClient is a mock implementation of the FirehoseClient interface
+type MockFirehoseClient struct {
+	PutRecordBatchFunc func(ctx context.Context, params *firehose.PutRecordBatchInput, optFns ...func(*firehose.Options)) (*firehose.PutRecordBatchOutput, error)
+}
+
+func (m *MockFirehoseClient) PutRecordBatch(ctx context.Context, params *firehose.PutRecordBatchInput, optFns ...func(*firehose.Options)) (*firehose.PutRecordBatchOutput, error) {
+	return m.PutRecordBatchFunc(ctx, params, optFns...)
+}
+
 // MockDynamoDBClient is a mock implementation of the DynamoDBClient interface
 type MockDynamoDBClient struct {
-	PutItemFunc func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
-	GetItemFunc func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItemFunc    func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItemFunc    func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	ScanFunc       func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	UpdateItemFunc func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	QueryFunc      func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	DeleteItemFunc func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
 }
 
 func (m *MockDynamoDBClient) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
@@ -746,3 +1310,23 @@ func (m *MockDynamoDBClient) PutItem(ctx context.Context, params *dynamodb.PutIt
 func (m *MockDynamoDBClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
 	return m.GetItemFunc(ctx, params, optFns...)
 }
+
+func (m *MockDynamoDBClient) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	return m.ScanFunc(ctx, params, optFns...)
+}
+
+func (m *MockDynamoDBClient) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	// Every cache hit counts, tests that don't check the counts don't need to mock it
+	if m.UpdateItemFunc == nil {
+		return &dynamodb.UpdateItemOutput{}, nil
+	}
+	return m.UpdateItemFunc(ctx, params, optFns...)
+}
+
+func (m *MockDynamoDBClient) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	return m.QueryFunc(ctx, params, optFns...)
+}
+
+func (m *MockDynamoDBClient) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	return m.DeleteItemFunc(ctx, params, optFns...)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+var (
+	// markdownFencePattern matches the opening and closing lines of fenced code blocks
+	markdownFencePattern = regexp.MustCompile("^\\s*(`{3,}|~{3,})")
+	// markdownMarkerPattern matches the block quote, heading and list item markers opening a
+	// line of Markdown, including task list checkboxes
+	markdownMarkerPattern = regexp.MustCompile(`^\s*(?:>\s?)*(?:#{1,6}\s+|[-*+]\s+(?:\[[ xX]\]\s+)?|\d+[.)]\s+)?`)
+	// markdownSkipPattern matches the lines left as they are: thematic breaks, setext heading
+	// underlines, link reference definitions, HTML blocks and table delimiter rows
+	markdownSkipPattern = regexp.MustCompile(`^\s*(?:(?:[-*_]\s*){3,}|=+\s*|\[[^\]]+\]:\s.*|<.*|\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*)$`)
+	// markdownInlinePattern matches the inline code spans, link destinations and references,
+	// autolinks and HTML tags of a line, kept out of reach of the provider
+	markdownInlinePattern = regexp.MustCompile("`+[^`]+`+|\\]\\([^)]*\\)|\\]\\[[^\\]]*\\]|</?[A-Za-z][^>]*>|<https?://[^>]+>")
+)
+
+// markdownPart is a part of a Markdown document, either kept as it is or replaced by the
+// translation of a text
+type markdownPart struct {
+	literal string
+	// text is the index of the translated text, -1 for literal parts
+	text int
+}
+
+// translateMarkdown translates the prose of a Markdown document, keeping its structure: front
+// matter, code blocks, HTML blocks and the markers of headings, lists and quotes are left as
+// they are. The lines of a paragraph are translated together and joined into one line, as
+// their sentences often run across lines.
+func (h *handler) translateMarkdown(ctx context.Context, sourceLanguage, targetLanguage, document string) (string, error) {
+	var (
+		parts []markdownPart
+		texts []string
+		fence string
+		// paragraph is the index of the text the next plain line continues, -1 for none
+		paragraph = -1
+		// indented is set while the lines indented by four spaces are code rather than the
+		// continuation of a list item
+		indented  = true
+		lines     = strings.SplitAfter(document, "\n")
+		literal   = func(text string) { parts = append(parts, markdownPart{literal: text, text: -1}) }
+		textPart  = func(text string) { parts = append(parts, markdownPart{text: len(texts)}); texts = append(texts, text) }
+		endOfLine = func(line string) string { return line[len(strings.TrimRight(line, " \t\r\n")):] }
+	)
+
+	// Front matter is metadata of the site generator rather than prose
+	if len(lines) > 0 && strings.TrimRight(lines[0], "\r\n") == "---" {
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimRight(lines[i], "\r\n") == "---" {
+				literal(strings.Join(lines[:i+1], ""))
+				lines = lines[i+1:]
+				break
+			}
+		}
+	}
+
+	for _, line := range lines {
+		content := strings.TrimRight(line, " \t\r\n")
+
+		if fence != "" {
+			literal(line)
+			if strings.HasPrefix(strings.TrimSpace(content), fence) {
+				fence = ""
+			}
+			continue
+		}
+		if match := markdownFencePattern.FindStringSubmatch(content); match != nil {
+			fence, paragraph = match[1], -1
+			literal(line)
+			continue
+		}
+
+		if strings.TrimSpace(content) == "" {
+			paragraph = -1
+			literal(line)
+			continue
+		}
+		if indented && paragraph < 0 && (strings.HasPrefix(content, "    ") || strings.HasPrefix(content, "\t")) {
+			literal(line)
+			continue
+		}
+		if markdownSkipPattern.MatchString(content) {
+			paragraph = -1
+			literal(line)
+			continue
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(content), "|") {
+			paragraph = -1
+			for i, cell := range splitMarkdownRow(content) {
+				if i%2 == 0 {
+					literal(cell)
+					continue
+				}
+				trimmed := strings.TrimSpace(cell)
+				if trimmed == "" {
+					literal(cell)
+					continue
+				}
+				start := strings.Index(cell, trimmed)
+				literal(cell[:start])
+				textPart(trimmed)
+				literal(cell[start+len(trimmed):])
+			}
+			literal(endOfLine(line))
+			continue
+		}
+
+		marker := markdownMarkerPattern.FindString(content)
+		text := content[len(marker):]
+		switch {
+		case strings.TrimSpace(marker) == "" && paragraph >= 0:
+			// A plain line continues the paragraph or list item above it
+			texts[paragraph] += " " + strings.TrimSpace(text)
+			continue
+		case strings.TrimSpace(marker) == "":
+			indented = true
+		case strings.ContainsAny(strings.TrimSpace(marker), "-*+0123456789"):
+			indented = false
+		}
+
+		literal(marker)
+		textPart(text)
+		literal(endOfLine(line))
+		paragraph = len(texts) - 1
+		// Headings are a single line
+		if strings.Contains(marker, "#") {
+			paragraph = -1
+		}
+	}
+
+	if len(texts) == 0 {
+		return document, nil
+	}
+
+	translated, err := h.translateMasked(ctx, sourceLanguage, targetLanguage, texts, markdownInlinePattern)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	for _, part := range parts {
+		if part.text < 0 {
+			result.WriteString(part.literal)
+			continue
+		}
+		result.WriteString(translated[part.text])
+	}
+	return result.String(), nil
+}
+
+// splitMarkdownRow splits a table row into its literal parts at even indexes, the pipes
+// with the text before the first one, and its cells at odd indexes. Escaped pipes and pipes
+// inside code spans belong to their cell.
+func splitMarkdownRow(row string) []string {
+	var (
+		fields []string
+		start  int
+		inCode bool
+	)
+	for i := 0; i < len(row); i++ {
+		switch row[i] {
+		case '\\':
+			i++
+		case '`':
+			inCode = !inCode
+		case '|':
+			if !inCode {
+				fields = append(fields, row[start:i])
+				start = i + 1
+			}
+		}
+	}
+	fields = append(fields, row[start:])
+
+	parts := []string{fields[0] + "|"}
+	for i, field := range fields[1:] {
+		if i > 0 {
+			parts = append(parts, "|")
+		}
+		parts = append(parts, field)
+	}
+	return parts
+}
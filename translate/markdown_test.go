@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTranslateMarkdown(t *testing.T) {
+	translations := map[string]string{
+		"Getting started":                       "Primeros pasos",
+		"Install the {0} and run it.":           "Instala el {0} y ejecútalo.",
+		"Read the [guide{0} first.":             "Lee la [guía{0} primero.",
+		"Fast":                                  "Rápido",
+		"Cached":                                "En caché",
+		"This paragraph runs across two lines.": "Este párrafo ocupa dos líneas.",
+		"Quoted text":                           "Texto citado",
+		"Done task":                             "Tarea terminada",
+		"Feature":                               "Función",
+		"Status":                                "Estado",
+		"A sentence that loses its placeholder {0}": "Una frase que pierde su marcador",
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Heading and list",
+			input:    "# Getting started\n\n- Fast\n- Cached\n",
+			expected: "# Primeros pasos\n\n- Rápido\n- En caché\n",
+		},
+		{
+			name:     "Inline code and links",
+			input:    "1. Install the `cli` and run it.\n2. Read the [guide](https://example.com/guide) first.\n",
+			expected: "1. Instala el `cli` y ejecútalo.\n2. Lee la [guía](https://example.com/guide) primero.\n",
+		},
+		{
+			name:     "Paragraph across lines",
+			input:    "This paragraph runs\nacross two lines.\n",
+			expected: "Este párrafo ocupa dos líneas.\n",
+		},
+		{
+			name:     "Front matter and code",
+			input:    "---\ntitle: Getting started\n---\n```sh\nFast\n```\n\n    Cached\n> Quoted text\n",
+			expected: "---\ntitle: Getting started\n---\n```sh\nFast\n```\n\n    Cached\n> Texto citado\n",
+		},
+		{
+			name:     "Task list and table",
+			input:    "- [x] Done task\n\n| Feature | Status |\n|---|:---:|\n| Fast | `ok` |\n",
+			expected: "- [x] Tarea terminada\n\n| Función | Estado |\n|---|:---:|\n| Rápido | `ok` |\n",
+		},
+		{
+			name:     "Lost placeholder",
+			input:    "A sentence that loses its placeholder `x`\n",
+			expected: "A sentence that loses its placeholder `x`\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(translations)
+
+			got, err := h.translateMarkdown(context.Background(), "en", "es", tt.input)
+			if err != nil {
+				t.Fatalf("translateMarkdown() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("translateMarkdown() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSplitMarkdownRow(t *testing.T) {
+	tests := []struct {
+		row      string
+		expected []string
+	}{
+		{row: "| a | b |", expected: []string{"|", " a ", "|", " b ", "|", ""}},
+		{row: "| `a|b` | c \\| d |", expected: []string{"|", " `a|b` ", "|", " c \\| d ", "|", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.row, func(t *testing.T) {
+			if got := splitMarkdownRow(tt.row); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("splitMarkdownRow() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
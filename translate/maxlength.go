@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+)
+
+const (
+	// truncationEllipsis cuts a translation too long at a word boundary and appends an
+	// ellipsis, truncationHard cuts it at the maximum length and truncationNone only reports it
+	truncationEllipsis = "ellipsis"
+	truncationHard     = "hard"
+	truncationNone     = "none"
+
+	// constraintBrevity, constraintTruncated and constraintExceeded report how a translation
+	// was fitted to the maximum length, or that it still exceeds it
+	constraintBrevity   = "brevity"
+	constraintTruncated = "truncated"
+	constraintExceeded  = "exceeded"
+)
+
+// lengthConstraint holds the maximum length of the translated segments of a request and the
+// segments it constrained
+type lengthConstraint struct {
+	maxLength  int
+	truncation string
+
+	mu       sync.Mutex
+	segments []SegmentReport
+}
+
+type lengthConstraintKey struct{}
+
+type brevityKey struct{}
+
+// withLengthConstraint returns a context fitting every translated segment of the request in
+// the maximum length, unconstrained when it is 0
+func withLengthConstraint(ctx context.Context, maxLength int, truncation string) (context.Context, *lengthConstraint) {
+	if maxLength <= 0 {
+		return ctx, nil
+	}
+	if truncation == "" {
+		truncation = truncationEllipsis
+	}
+	c := &lengthConstraint{maxLength: maxLength, truncation: truncation}
+	return context.WithValue(ctx, lengthConstraintKey{}, c), c
+}
+
+// lengthConstraintFromContext returns the length constraint of the request, nil when it has
+// none
+func lengthConstraintFromContext(ctx context.Context) *lengthConstraint {
+	c, _ := ctx.Value(lengthConstraintKey{}).(*lengthConstraint)
+	return c
+}
+
+// Segments returns the segments constrained so far
+func (c *lengthConstraint) Segments() []SegmentReport {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.segments
+}
+
+// withBrevity returns a context translating with the brevity setting of the provider, whose
+// translations are cached apart from the plain ones
+func withBrevity(ctx context.Context) context.Context {
+	return context.WithValue(ctx, brevityKey{}, true)
+}
+
+// brevityFromContext reports whether the translations of the context are shortened
+func brevityFromContext(ctx context.Context) bool {
+	brief, _ := ctx.Value(brevityKey{}).(bool)
+	return brief
+}
+
+// constrainLength fits the translated sentences of a segment in the maximum length of the
+// request. A translation too long is translated again with the brevity setting when AWS
+// Translate translates the pair, then truncated with the truncation of the request if still
+// too long. The sentences returned replace those of the segment.
+func (h *handler) constrainLength(ctx context.Context, sourceLanguage, targetLanguage string, sources, translations []string) ([]string, error) {
+	c := lengthConstraintFromContext(ctx)
+	if c == nil || dryRunFromContext(ctx) != nil || segmentLength(translations) <= c.maxLength {
+		return translations, nil
+	}
+
+	report := SegmentReport{SourceText: strings.Join(sources, " "), Length: segmentLength(translations)}
+	constrained := translations
+	if settings := translationSettings(ctx); settings == nil || settings.Brevity != types.BrevityOn {
+		if _, rule := h.translationRoute(ctx, sourceLanguage, targetLanguage); rule.Provider == providerAWSTranslate {
+			brief, err := h.translateSentences(withBrevity(ctx), sourceLanguage, targetLanguage, sources)
+			if err != nil {
+				return nil, err
+			}
+			constrained, report.Constraint = brief, constraintBrevity
+		}
+	}
+
+	if segmentLength(constrained) > c.maxLength {
+		if c.truncation == truncationNone {
+			report.Constraint = constraintExceeded
+		} else {
+			ellipsis := "…"
+			if c.truncation == truncationHard {
+				ellipsis = ""
+			} else if typographyFromContext(ctx).Ellipses == typographyASCII {
+				ellipsis = "..."
+			}
+			constrained = []string{truncateSegment(strings.Join(constrained, " "), c.maxLength, ellipsis)}
+			report.Constraint = constraintTruncated
+		}
+	}
+
+	report.TranslatedText = strings.Join(constrained, " ")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.segments = append(c.segments, report)
+	return constrained, nil
+}
+
+// segmentLength returns the number of characters of the translated sentences of a segment
+// joined by spaces
+func segmentLength(sentences []string) int {
+	length := max(len(sentences)-1, 0)
+	for _, sentence := range sentences {
+		length += utf8.RuneCountInString(sentence)
+	}
+	return length
+}
+
+// truncateSegment cuts the text to the maximum number of characters including the ellipsis.
+// With an ellipsis the text is cut between words, dropping the punctuation before the
+// ellipsis.
+func truncateSegment(text string, maxLength int, ellipsis string) string {
+	runes := []rune(text)
+	keep := max(maxLength-utf8.RuneCountInString(ellipsis), 0)
+	if len(runes) <= keep {
+		return text
+	}
+
+	cut := string(runes[:keep])
+	if ellipsis != "" {
+		// A word cut in the middle is dropped
+		if space := strings.LastIndexFunc(cut, unicode.IsSpace); space > 0 && !unicode.IsSpace(runes[keep]) {
+			cut = cut[:space]
+		}
+		cut = strings.TrimRightFunc(cut, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsPunct(r) })
+	}
+	return cut + ellipsis
+}
+
+// validateMaxLength checks the maximum length of the translated segments and its truncation
+func validateMaxLength(errs *validationError, request TranslateRequest) {
+	switch request.Truncation {
+	case "", truncationEllipsis, truncationHard, truncationNone:
+	default:
+		errs.add("truncation", "truncation %q is not supported", request.Truncation)
+	}
+	if request.MaxLength == 0 {
+		return
+	}
+
+	if request.MaxLength < 0 {
+		errs.add("max_length", "max_length must be a positive number")
+	}
+	switch request.Format {
+	case "", formatText, formatAndroidStrings, formatIOSStrings, formatIOSStringsDict:
+	default:
+		errs.add("max_length", "max_length is only supported for text and resource formats")
+	}
+	if request.PreviousText != "" || request.Alignment {
+		errs.add("max_length", "max_length is not supported with previous_text or alignment")
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+)
+
+func TestHandleMaxLength(t *testing.T) {
+	translations := map[string]string{
+		"Save":     "Guardar cambios ahora",
+		"Save all": "Guardar todos los cambios ahora",
+		"Cancel":   "Cancelar",
+	}
+	briefTranslations := map[string]string{
+		"Save":     "Guardar",
+		"Save all": "Guardar todos los cambios",
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedBody string
+	}{
+		{
+			name:         "Translation within the maximum length",
+			body:         `{"source_language":"en","target_language":"es","text":"Cancel","max_length":10}`,
+			expectedBody: `{"translated_text":"Cancelar "}`,
+		},
+		{
+			name:         "Brief translation",
+			body:         `{"source_language":"en","target_language":"es","text":"Save","max_length":10}`,
+			expectedBody: `{"translated_text":"Guardar ","segments":[{"source_text":"Save","translated_text":"Guardar","constraint":"brevity","length":21}]}`,
+		},
+		{
+			name:         "Truncated with an ellipsis",
+			body:         `{"source_language":"en","target_language":"es","text":"Save all","max_length":15}`,
+			expectedBody: `{"translated_text":"Guardar todos… ","segments":[{"source_text":"Save all","translated_text":"Guardar todos…","constraint":"truncated","length":31}]}`,
+		},
+		{
+			name:         "Hard truncation",
+			body:         `{"source_language":"en","target_language":"es","text":"Save all","max_length":15,"truncation":"hard"}`,
+			expectedBody: `{"translated_text":"Guardar todos l ","segments":[{"source_text":"Save all","translated_text":"Guardar todos l","constraint":"truncated","length":31}]}`,
+		},
+		{
+			name:         "Exceeded without truncation",
+			body:         `{"source_language":"en","target_language":"es","text":"Save all","max_length":15,"truncation":"none"}`,
+			expectedBody: `{"translated_text":"Guardar todos los cambios ","segments":[{"source_text":"Save all","translated_text":"Guardar todos los cambios","constraint":"exceeded","length":31}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(nil)
+			h.translateClient.(*MockTranslateClient).TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				translated := translations[aws.ToString(params.Text)]
+				if params.Settings != nil && params.Settings.Brevity == types.BrevityOn {
+					translated = briefTranslations[aws.ToString(params.Text)]
+				}
+				return &translate.TranslateTextOutput{TranslatedText: aws.String(translated)}, nil
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != http.StatusOK || got.Body != tt.expectedBody {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, http.StatusOK, tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestHandleResourcesMaxLength(t *testing.T) {
+	h := newMockHandler(nil)
+	h.translateClient.(*MockTranslateClient).TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+		translated := map[string]string{"Delete {0}": "Eliminar el archivo {0}", "Cancel": "Cancelar"}[aws.ToString(params.Text)]
+		if params.Settings != nil && params.Settings.Brevity == types.BrevityOn {
+			translated = "Borrar {0}"
+		}
+		return &translate.TranslateTextOutput{TranslatedText: aws.String(translated)}, nil
+	}
+
+	document := `<resources>
+    <string name="delete">Delete %1$s</string>
+    <string name="cancel">Cancel</string>
+</resources>`
+	got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+		Body: `{"source_language":"en","target_language":"es","format":"android_strings","max_length":12,"document":"` + base64.StdEncoding.EncodeToString([]byte(document)) + `"}`,
+	})
+	if err != nil {
+		t.Fatalf("handle() error = %v", err)
+	}
+
+	var response TranslateResponse
+	if err := json.Unmarshal([]byte(got.Body), &response); err != nil {
+		t.Fatalf("handle() = %d %s, expected a translation", got.StatusCode, got.Body)
+	}
+	expected := `<resources>
+    <string name="delete">Borrar %1$s</string>
+    <string name="cancel">Cancelar</string>
+</resources>`
+	if response.TranslatedText != expected {
+		t.Errorf("handle() = %s, expected %s", response.TranslatedText, expected)
+	}
+	if len(response.Segments) != 1 || response.Segments[0].Constraint != constraintBrevity {
+		t.Errorf("handle() segments = %+v, expected the brief delete string", response.Segments)
+	}
+}
+
+func TestTruncateSegment(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxLength int
+		ellipsis  string
+		expected  string
+	}{
+		{name: "Between words", text: "Guardar todos los cambios", maxLength: 15, ellipsis: "…", expected: "Guardar todos…"},
+		{name: "At the end of a word", text: "Guardar todos los cambios", maxLength: 14, ellipsis: "…", expected: "Guardar todos…"},
+		{name: "Punctuation", text: "Guardar, luego salir", maxLength: 10, ellipsis: "...", expected: "Guardar..."},
+		{name: "Without spaces", text: "変更をすべて保存します", maxLength: 6, ellipsis: "…", expected: "変更をすべ…"},
+		{name: "Hard", text: "Guardar todos", maxLength: 9, expected: "Guardar t"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateSegment(tt.text, tt.maxLength, tt.ellipsis); got != tt.expected {
+				t.Errorf("truncateSegment() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateMaxLength(t *testing.T) {
+	tests := []struct {
+		name          string
+		request       TranslateRequest
+		expectedError bool
+	}{
+		{name: "Text", request: TranslateRequest{MaxLength: 20}},
+		{name: "Resources", request: TranslateRequest{Format: formatIOSStrings, MaxLength: 20, Truncation: truncationHard}},
+		{name: "Negative", request: TranslateRequest{MaxLength: -1}, expectedError: true},
+		{name: "HTML", request: TranslateRequest{Format: formatHTML, MaxLength: 20}, expectedError: true},
+		{name: "Alignment", request: TranslateRequest{MaxLength: 20, Alignment: true}, expectedError: true},
+		{name: "Unknown truncation", request: TranslateRequest{MaxLength: 20, Truncation: "middle"}, expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := &validationError{}
+			validateMaxLength(errs, tt.request)
+			if got := len(errs.Fields) > 0; got != tt.expectedError {
+				t.Errorf("validateMaxLength() errors = %v, expected errors %v", errs.Fields, tt.expectedError)
+			}
+		})
+	}
+}
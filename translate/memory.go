@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+const (
+	// defaultSegmentMemoryMB is the memory the segments of a document may take at once
+	defaultSegmentMemoryMB = 16
+	// segmentMemoryFactor is about the memory the segments of a document take per byte of its
+	// source: the tokens, their sentences, the translated sentences and the output
+	segmentMemoryFactor = 8
+)
+
+var (
+	// documentWindowBytes is the size of the source of a document translated at once, within
+	// the memory ceiling of its segments. Larger documents are translated in sequential
+	// windows of this size.
+	documentWindowBytes int
+	// spillDirectory is the directory the translation of a document is moved to once it
+	// outgrows a window, such as /tmp, empty to keep it in memory
+	spillDirectory string
+)
+
+// windowBytes returns the size of the source of a document translated at once with a memory
+// ceiling of megabytes
+func windowBytes(megabytes int) int {
+	return max(megabytes, 1) * 1024 * 1024 / segmentMemoryFactor
+}
+
+// spillBuffer collects the translation of a document window by window. Once it outgrows the
+// limit it is moved to a temporary file of the spill directory, so memory only holds the
+// segments of the window being translated. Without a spill directory it stays in memory.
+type spillBuffer struct {
+	dir    string
+	limit  int
+	memory bytes.Buffer
+	file   *os.File
+}
+
+// newSpillBuffer returns a buffer spilling to the spill directory past a window
+func newSpillBuffer() *spillBuffer {
+	return &spillBuffer{dir: spillDirectory, limit: documentWindowBytes}
+}
+
+func (b *spillBuffer) Write(p []byte) (int, error) {
+	if b.file == nil && b.dir != "" && b.memory.Len()+len(p) > b.limit {
+		b.spill()
+	}
+	if b.file != nil {
+		return b.file.Write(p)
+	}
+	return b.memory.Write(p)
+}
+
+// spill moves the buffered translation to a temporary file. A failure is only logged, the
+// translation then stays in memory.
+func (b *spillBuffer) spill() {
+	file, err := os.CreateTemp(b.dir, "translation-*")
+	if err == nil {
+		if _, err = b.memory.WriteTo(file); err != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}
+	if err != nil {
+		log.Printf("Error spilling the translation to %s, keeping it in memory: %v", b.dir, err)
+		b.dir = ""
+		return
+	}
+	b.file = file
+	b.memory = bytes.Buffer{}
+}
+
+// String reads the whole translation back
+func (b *spillBuffer) String() (string, error) {
+	if b.file == nil {
+		return b.memory.String(), nil
+	}
+	if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to read spilled translation: %w", err)
+	}
+	var translation strings.Builder
+	if _, err := io.Copy(&translation, b.file); err != nil {
+		return "", fmt.Errorf("failed to read spilled translation: %w", err)
+	}
+	return translation.String(), nil
+}
+
+// Close removes the temporary file of a spilled translation
+func (b *spillBuffer) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	b.file.Close()
+	return os.Remove(b.file.Name())
+}
+
+// translateTextWindows translates a text too large for the memory ceiling in sequential
+// windows, only breaking it after a line break
+func (h *handler) translateTextWindows(ctx context.Context, sourceLanguage, targetLanguage, text string) (string, error) {
+	output := newSpillBuffer()
+	defer output.Close()
+
+	for _, window := range splitDocument(text, documentWindowBytes) {
+		translated, err := h.translateWholeText(ctx, sourceLanguage, targetLanguage, window)
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.WriteString(output, translated); err != nil {
+			return "", err
+		}
+	}
+	return output.String()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSpillBuffer(t *testing.T) {
+	tests := []struct {
+		name            string
+		dir             func(t *testing.T) string
+		writes          []string
+		expectedSpilled bool
+	}{
+		{
+			name:   "Under the limit",
+			dir:    func(t *testing.T) string { return t.TempDir() },
+			writes: []string{"Hola. ", "Adiós."},
+		},
+		{
+			name:            "Spilled past the limit",
+			dir:             func(t *testing.T) string { return t.TempDir() },
+			writes:          []string{"Hola. ", "¿Cómo estás? ", "Adiós."},
+			expectedSpilled: true,
+		},
+		{
+			name:   "Without spill directory",
+			dir:    func(t *testing.T) string { return "" },
+			writes: []string{"Hola. ", "¿Cómo estás? ", "Adiós."},
+		},
+		{
+			name:   "Spill directory missing",
+			dir:    func(t *testing.T) string { return filepath.Join(t.TempDir(), "missing") },
+			writes: []string{"Hola. ", "¿Cómo estás? ", "Adiós."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tt.dir(t)
+			buffer := &spillBuffer{dir: dir, limit: 16}
+			for _, write := range tt.writes {
+				if _, err := io.WriteString(buffer, write); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			if spilled := buffer.file != nil; spilled != tt.expectedSpilled {
+				t.Errorf("spillBuffer spilled = %t, expected %t", spilled, tt.expectedSpilled)
+			}
+
+			got, err := buffer.String()
+			if err != nil {
+				t.Fatalf("String() error = %v", err)
+			}
+			if expected := strings.Join(tt.writes, ""); got != expected {
+				t.Errorf("String() = %q, expected %q", got, expected)
+			}
+
+			if err := buffer.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if dir != "" {
+				if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+					t.Errorf("Close() left %d files in the spill directory", len(entries))
+				}
+			}
+		})
+	}
+}
+
+func TestTranslateTextWindows(t *testing.T) {
+	previousWindow, previousDir := documentWindowBytes, spillDirectory
+	documentWindowBytes, spillDirectory = 16, t.TempDir()
+	defer func() { documentWindowBytes, spillDirectory = previousWindow, previousDir }()
+
+	h := newMockHandler(map[string]string{"Hello.": "Hola.", "How are you?": "¿Cómo estás?", "Goodbye.": "Adiós."})
+	text := "Hello. How are you?\nGoodbye.\nHello."
+
+	got, err := h.translateText(context.Background(), "en", "es", text)
+	if err != nil {
+		t.Fatalf("translateText() error = %v", err)
+	}
+	if expected := "Hola. ¿Cómo estás? Adiós. Hola. "; got != expected {
+		t.Errorf("translateText() = %q, expected %q", got, expected)
+	}
+}
+
+func TestTranslateHTMLSpilled(t *testing.T) {
+	previousWindow, previousDir := documentWindowBytes, spillDirectory
+	documentWindowBytes, spillDirectory = 16, t.TempDir()
+	defer func() { documentWindowBytes, spillDirectory = previousWindow, previousDir }()
+
+	h := newMockHandler(map[string]string{"Hello.": "Hola.", "Goodbye.": "Adiós."})
+	got, err := h.translateHTML(context.Background(), "en", "es", "<p>Hello.</p><p>Goodbye.</p><p>Hello.</p>")
+	if err != nil {
+		t.Fatalf("translateHTML() error = %v", err)
+	}
+	expected := `<p lang="es">Hola.</p><p lang="es">Adiós.</p><p lang="es">Hola.</p>`
+	if got != expected {
+		t.Errorf("translateHTML() = %q, expected %q", got, expected)
+	}
+	if entries, _ := os.ReadDir(spillDirectory); len(entries) > 0 {
+		t.Errorf("translateHTML() left %d files in the spill directory", len(entries))
+	}
+}
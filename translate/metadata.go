@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// translatedMetaTags are the name or property of the <meta> tags whose content is translated
+// with the metadata of a document
+var translatedMetaTags = map[string]bool{
+	"description":         true,
+	"og:title":            true,
+	"og:description":      true,
+	"twitter:title":       true,
+	"twitter:description": true,
+}
+
+type htmlMetadataKey struct{}
+
+// withHTMLMetadata returns a context translating the <title> and SEO <meta> tags of HTML
+// documents, unchanged unless enabled
+func withHTMLMetadata(ctx context.Context, enabled bool) context.Context {
+	if !enabled {
+		return ctx
+	}
+	return context.WithValue(ctx, htmlMetadataKey{}, true)
+}
+
+// htmlMetadataFromContext reports whether the metadata of HTML documents is translated
+func htmlMetadataFromContext(ctx context.Context) bool {
+	enabled, _ := ctx.Value(htmlMetadataKey{}).(bool)
+	return enabled
+}
+
+// htmlMetadataField is a translatable metadata text of an HTML document
+type htmlMetadataField struct {
+	// index is the index of the token holding the text
+	index int
+	// tag is the <meta> tag whose content is the text, nil for the text of the <title>
+	tag *html.Token
+}
+
+// translateHTMLMetadata translates the <title> and the description, Open Graph and Twitter
+// card <meta> tags of the document in place
+func (h *handler) translateHTMLMetadata(ctx context.Context, sourceLanguage, targetLanguage string, tokens []htmlToken) error {
+	var (
+		fields []htmlMetadataField
+		texts  []string
+		title  bool
+	)
+	for i, token := range tokens {
+		switch {
+		case token.Tag != nil && token.Tag.Data == "title":
+			title = !isNoTranslate(*token.Tag)
+			continue
+		case title && token.Tag == nil && !strings.HasPrefix(token.Raw, "<"):
+			if text := html.UnescapeString(token.Raw); strings.TrimSpace(text) != "" {
+				fields = append(fields, htmlMetadataField{index: i})
+				texts = append(texts, text)
+			}
+		case token.Tag != nil && token.Tag.Data == "meta" && !token.Untranslated:
+			name, _ := htmlAttribute(*token.Tag, "name")
+			property, _ := htmlAttribute(*token.Tag, "property")
+			content, ok := htmlAttribute(*token.Tag, "content")
+			if ok && strings.TrimSpace(content) != "" && (translatedMetaTags[strings.ToLower(name)] || translatedMetaTags[strings.ToLower(property)]) {
+				fields = append(fields, htmlMetadataField{index: i, tag: token.Tag})
+				texts = append(texts, content)
+			}
+		}
+		title = false
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	indexes := make([]int, len(texts))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	if err := h.translateFields(ctx, sourceLanguage, targetLanguage, texts, indexes); err != nil {
+		return err
+	}
+
+	for i, field := range fields {
+		if field.tag == nil {
+			tokens[field.index].Raw = html.EscapeString(texts[i])
+			continue
+		}
+		// The tag is kept for the attributes rewritten after the translation
+		tag := setHTMLAttributes(*field.tag, []html.Attribute{{Key: "content", Val: texts[i]}})
+		tokens[field.index].Tag, tokens[field.index].Raw = &tag, tag.String()
+	}
+	return nil
+}
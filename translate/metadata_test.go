@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestTranslateHTMLMetadata(t *testing.T) {
+	input := `<html lang="en"><head>
+<title>Spring sale &amp; more</title>
+<meta charset="utf-8">
+<meta name="description" content="Save on shoes. Free returns.">
+<meta property="og:title" content="Spring sale">
+<meta name="twitter:description" content="">
+<meta name="author" content="Ana Silva">
+</head><body><p>Shop now.</p></body></html>`
+
+	tests := []struct {
+		name     string
+		metadata bool
+		expected string
+	}{
+		{
+			name:     "Metadata translated",
+			metadata: true,
+			expected: `<html lang="es"><head>
+<title>SPRING SALE &amp; MORE</title>
+<meta charset="utf-8">
+<meta name="description" content="SAVE ON SHOES. FREE RETURNS.">
+<meta property="og:title" content="SPRING SALE">
+<meta name="twitter:description" content="">
+<meta name="author" content="Ana Silva">
+</head><body><p>SHOP NOW.</p></body></html>`,
+		},
+		{
+			name: "Metadata kept",
+			expected: `<html lang="es"><head>
+<title>Spring sale &amp; more</title>
+<meta charset="utf-8">
+<meta name="description" content="Save on shoes. Free returns.">
+<meta property="og:title" content="Spring sale">
+<meta name="twitter:description" content="">
+<meta name="author" content="Ana Silva">
+</head><body><p>SHOP NOW.</p></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(nil)
+			h.translateClient.(*MockTranslateClient).TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				return &translate.TranslateTextOutput{TranslatedText: aws.String(strings.ToUpper(*params.Text))}, nil
+			}
+
+			got, err := h.translateHTML(withHTMLMetadata(context.Background(), tt.metadata), "en", "es", input)
+			if err != nil {
+				t.Fatalf("translateHTML() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("translateHTML() = %s, expected %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandleTranslateMetadataValidation(t *testing.T) {
+	got, err := newMockHandler(nil).handle(context.Background(), events.APIGatewayProxyRequest{
+		Body: `{"source_language":"en","target_language":"es","text":"Hello.","translate_metadata":true}`,
+	})
+	if err != nil {
+		t.Fatalf("handle() error = %v", err)
+	}
+
+	expected := `{"error":"Invalid request","fields":[{"field":"translate_metadata","message":"translate_metadata is only supported for html"}]}`
+	if got.StatusCode != http.StatusBadRequest || got.Body != expected {
+		t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, http.StatusBadRequest, expected)
+	}
+}
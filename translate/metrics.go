@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+const (
+	metricsExporterOTLP = "otlp"
+	metricsExporterEMF  = "emf"
+
+	defaultMetricsNamespace = "GoTranslate"
+
+	stageRequest     = "request"
+	stageCacheLookup = "cache_lookup"
+	stageCacheStore  = "cache_store"
+	stageProvider    = "provider"
+)
+
+// Instruments are created against the global meter provider, which forwards them to the
+// configured provider once setupMetrics has run. Creating them can only fail for invalid names.
+var (
+	meter = otel.Meter("translate")
+
+	stageDuration, _ = meter.Float64Histogram("translate.stage.duration",
+		metric.WithDescription("Latency of each stage of the translation pipeline"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10),
+	)
+	cacheLookups, _ = meter.Int64Counter("translate.cache.lookups",
+		metric.WithDescription("Cache lookups by result"),
+	)
+	providerCharacters, _ = meter.Int64Counter("translate.provider.characters",
+		metric.WithDescription("Characters sent to the translation provider"),
+	)
+	providerErrors, _ = meter.Int64Counter("translate.provider.errors",
+		metric.WithDescription("Failed translation provider calls"),
+	)
+	providerFailovers, _ = meter.Int64Counter("translate.provider.failovers",
+		metric.WithDescription("Translation provider calls failed over to the secondary region"),
+	)
+
+	// meterProvider is nil when metrics are disabled
+	meterProvider *sdkmetric.MeterProvider
+)
+
+// setupMetrics configures the exporter selected by METRICS_EXPORTER, metrics are only
+// recorded by the no-op provider when it is empty
+func setupMetrics(ctx context.Context) error {
+	var exporter sdkmetric.Exporter
+	switch metricsExporter {
+	case "":
+		return nil
+	case metricsExporterOTLP:
+		// The endpoint and headers are read from the standard OTEL_EXPORTER_OTLP_* variables
+		otlpExporter, err := otlpmetrichttp.New(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to create otlp exporter: %w", err)
+		}
+		exporter = otlpExporter
+	case metricsExporterEMF:
+		exporter = newEMFExporter(os.Stdout, metricsNamespace)
+	default:
+		return fmt.Errorf("metrics exporter %q is not supported", metricsExporter)
+	}
+
+	// Metrics are flushed at the end of each invocation, the interval only matters for
+	// long running cache jobs
+	meterProvider = sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(time.Minute))),
+	)
+	otel.SetMeterProvider(meterProvider)
+
+	return nil
+}
+
+// recordStage records the duration of a pipeline stage started at start
+func recordStage(ctx context.Context, stage string, start time.Time, attrs ...attribute.KeyValue) {
+	attrs = append(attrs, attribute.String("stage", stage))
+	stageDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+}
+
+// recordCacheLookup counts a cache lookup as a hit or a miss
+func recordCacheLookup(ctx context.Context, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.Add(ctx, 1, metric.WithAttributes(attribute.String("result", result)))
+}
+
+// recordProviderError counts a failed provider call by its error code
+func recordProviderError(ctx context.Context, err error) {
+	code := "unknown"
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code = apiErr.ErrorCode()
+	}
+	providerErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("error", code)))
+}
+
+// emfExporter writes metrics to the Lambda log in the CloudWatch embedded metric format
+type emfExporter struct {
+	writer    io.Writer
+	namespace string
+}
+
+func newEMFExporter(writer io.Writer, namespace string) *emfExporter {
+	return &emfExporter{writer: writer, namespace: namespace}
+}
+
+// emfDirective tells CloudWatch which fields of a log record are metrics
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+// emfValues is a distribution of values in the embedded metric format
+type emfValues struct {
+	Values []float64 `json:"Values"`
+	Counts []uint64  `json:"Counts"`
+}
+
+// Temporality is delta for every instrument as CloudWatch aggregates the values itself
+func (e *emfExporter) Temporality(sdkmetric.InstrumentKind) metricdata.Temporality {
+	return metricdata.DeltaTemporality
+}
+
+func (e *emfExporter) Aggregation(kind sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(kind)
+}
+
+// Export writes a log record for each data point
+func (e *emfExporter) Export(ctx context.Context, resourceMetrics *metricdata.ResourceMetrics) error {
+	for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+		for _, m := range scopeMetrics.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, point := range data.DataPoints {
+					if err := e.write(m, point.Attributes, point.Time, point.Value); err != nil {
+						return err
+					}
+				}
+			case metricdata.Sum[float64]:
+				for _, point := range data.DataPoints {
+					if err := e.write(m, point.Attributes, point.Time, point.Value); err != nil {
+						return err
+					}
+				}
+			case metricdata.Histogram[float64]:
+				for _, point := range data.DataPoints {
+					if point.Count == 0 {
+						continue
+					}
+					if err := e.write(m, point.Attributes, point.Time, histogramValues(point)); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (e *emfExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+func (e *emfExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+// write writes a single metric value with its attributes as dimensions
+func (e *emfExporter) write(m metricdata.Metrics, attributes attribute.Set, timestamp time.Time, value any) error {
+	dimensions := make([]string, 0, attributes.Len())
+	record := map[string]any{m.Name: value}
+	for _, kv := range attributes.ToSlice() {
+		dimensions = append(dimensions, string(kv.Key))
+		record[string(kv.Key)] = kv.Value.Emit()
+	}
+	record["_aws"] = map[string]any{
+		"Timestamp": timestamp.UnixMilli(),
+		"CloudWatchMetrics": []emfDirective{{
+			Namespace:  e.namespace,
+			Dimensions: [][]string{dimensions},
+			Metrics:    []emfMetric{{Name: m.Name, Unit: emfUnit(m.Unit)}},
+		}},
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal emf record: %w", err)
+	}
+	_, err = e.writer.Write(append(line, '\n'))
+	return err
+}
+
+// histogramValues approximates a histogram as the upper bound of every non-empty bucket,
+// capped by the largest recorded value
+func histogramValues(point metricdata.HistogramDataPoint[float64]) emfValues {
+	maximum, hasMaximum := point.Max.Value()
+
+	var values emfValues
+	for i, count := range point.BucketCounts {
+		if count == 0 {
+			continue
+		}
+		value := maximum
+		if i < len(point.Bounds) && (!hasMaximum || point.Bounds[i] < maximum) {
+			value = point.Bounds[i]
+		}
+		values.Values = append(values.Values, value)
+		values.Counts = append(values.Counts, count)
+	}
+	return values
+}
+
+// emfUnit converts an OpenTelemetry unit into a CloudWatch unit
+func emfUnit(unit string) string {
+	switch unit {
+	case "s":
+		return "Seconds"
+	case "ms":
+		return "Milliseconds"
+	case "By":
+		return "Bytes"
+	default:
+		return "Count"
+	}
+}
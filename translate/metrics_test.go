@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestHistogramValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		point    metricdata.HistogramDataPoint[float64]
+		expected emfValues
+	}{
+		{
+			name: "Empty buckets are skipped",
+			point: metricdata.HistogramDataPoint[float64]{
+				Bounds:       []float64{0.1, 1},
+				BucketCounts: []uint64{2, 0, 0},
+				Max:          metricdata.NewExtrema(0.5),
+			},
+			expected: emfValues{Values: []float64{0.1}, Counts: []uint64{2}},
+		},
+		{
+			name: "Bounds are capped by the maximum",
+			point: metricdata.HistogramDataPoint[float64]{
+				Bounds:       []float64{0.1, 1},
+				BucketCounts: []uint64{1, 3, 0},
+				Max:          metricdata.NewExtrema(0.5),
+			},
+			expected: emfValues{Values: []float64{0.1, 0.5}, Counts: []uint64{1, 3}},
+		},
+		{
+			name: "Overflow bucket uses the maximum",
+			point: metricdata.HistogramDataPoint[float64]{
+				Bounds:       []float64{0.1, 1},
+				BucketCounts: []uint64{0, 0, 1},
+				Max:          metricdata.NewExtrema(4.0),
+			},
+			expected: emfValues{Values: []float64{4}, Counts: []uint64{1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := histogramValues(tt.point); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("histogramValues() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEMFExporterExport(t *testing.T) {
+	timestamp := time.UnixMilli(1700000000000)
+
+	tests := []struct {
+		name     string
+		metrics  metricdata.Metrics
+		expected string
+	}{
+		{
+			name: "Counter with a dimension",
+			metrics: metricdata.Metrics{
+				Name: "translate.cache.lookups",
+				Data: metricdata.Sum[int64]{
+					DataPoints: []metricdata.DataPoint[int64]{
+						{Attributes: attribute.NewSet(attribute.String("result", "hit")), Time: timestamp, Value: 3},
+					},
+				},
+			},
+			expected: `{"_aws":{"CloudWatchMetrics":[{"Namespace":"Test","Dimensions":[["result"]],"Metrics":[{"Name":"translate.cache.lookups","Unit":"Count"}]}],"Timestamp":1700000000000},"result":"hit","translate.cache.lookups":3}` + "\n",
+		},
+		{
+			name: "Histogram",
+			metrics: metricdata.Metrics{
+				Name: "translate.stage.duration",
+				Unit: "s",
+				Data: metricdata.Histogram[float64]{
+					DataPoints: []metricdata.HistogramDataPoint[float64]{
+						{
+							Attributes:   attribute.NewSet(attribute.String("stage", stageProvider)),
+							Time:         timestamp,
+							Count:        1,
+							Bounds:       []float64{0.1, 1},
+							BucketCounts: []uint64{0, 1, 0},
+							Max:          metricdata.NewExtrema(0.2),
+						},
+					},
+				},
+			},
+			expected: `{"_aws":{"CloudWatchMetrics":[{"Namespace":"Test","Dimensions":[["stage"]],"Metrics":[{"Name":"translate.stage.duration","Unit":"Seconds"}]}],"Timestamp":1700000000000},"stage":"provider","translate.stage.duration":{"Values":[0.2],"Counts":[1]}}` + "\n",
+		},
+		{
+			name: "Empty histogram is skipped",
+			metrics: metricdata.Metrics{
+				Name: "translate.stage.duration",
+				Unit: "s",
+				Data: metricdata.Histogram[float64]{
+					DataPoints: []metricdata.HistogramDataPoint[float64]{{Time: timestamp}},
+				},
+			},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			exporter := newEMFExporter(&buf, "Test")
+
+			err := exporter.Export(context.Background(), &metricdata.ResourceMetrics{
+				ScopeMetrics: []metricdata.ScopeMetrics{{Metrics: []metricdata.Metrics{tt.metrics}}},
+			})
+			if err != nil {
+				t.Errorf("Export() error = %v", err)
+				return
+			}
+
+			if got := buf.String(); got != tt.expected {
+				t.Errorf("Export() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
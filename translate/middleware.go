@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// requestHandler handles an API Gateway request. The API requests go through a chain of
+// middlewares, each wrapping the next handler with a cross-cutting concern, down to the
+// router of the resources.
+type requestHandler interface {
+	serveRequest(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+}
+
+// requestHandlerFunc is a function handling an API Gateway request
+type requestHandlerFunc func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+func (f requestHandlerFunc) serveRequest(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return f(ctx, event)
+}
+
+// requestMiddleware wraps a request handler with a cross-cutting concern
+type requestMiddleware func(next requestHandler) requestHandler
+
+// chainMiddlewares returns the handler wrapped by the middlewares, the first one outermost
+func chainMiddlewares(handler requestHandler, middlewares ...requestMiddleware) requestHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// apiMiddlewares are the middlewares of the API requests, outermost first. A response is
+// finished by the inner ones first: recovered, given its CORS headers, audited, its cache
+// writes drained, then traced.
+func (h *handler) apiMiddlewares() []requestMiddleware {
+	return []requestMiddleware{
+		preflightMiddleware,
+		telemetryMiddleware,
+		tracingMiddleware,
+		h.cacheWriterMiddleware,
+		h.auditMiddleware,
+		corsMiddleware,
+		recoveryMiddleware,
+		authorizationMiddleware,
+	}
+}
+
+// preflightMiddleware answers the preflights before any work, browsers send them ahead of
+// every request
+func preflightMiddleware(next requestHandler) requestHandler {
+	return requestHandlerFunc(func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		if event.HTTPMethod == http.MethodOptions {
+			return handlePreflight(event), nil
+		}
+		return next.serveRequest(ctx, event)
+	})
+}
+
+// telemetryMiddleware records the duration of the request and flushes the metrics and spans
+// before the environment is frozen
+func telemetryMiddleware(next requestHandler) requestHandler {
+	return requestHandlerFunc(func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		defer flushTelemetry(ctx)
+		defer recordStage(ctx, stageRequest, time.Now())
+		return next.serveRequest(ctx, event)
+	})
+}
+
+// tracingMiddleware traces the request in a span, whose trace id is returned in a header
+func tracingMiddleware(next requestHandler) requestHandler {
+	return requestHandlerFunc(func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		ctx, span := startRequestSpan(ctx, event)
+		response, err := next.serveRequest(ctx, event)
+		endRequestSpan(span, &response)
+		return response, err
+	})
+}
+
+// cacheWriterMiddleware runs the write-behind stage of the request, waiting for it to store
+// the new translations once the response is ready
+func (h *handler) cacheWriterMiddleware(next requestHandler) requestHandler {
+	return requestHandlerFunc(func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		ctx, writer := h.withCacheWriter(ctx)
+		defer writer.Close()
+		return next.serveRequest(ctx, event)
+	})
+}
+
+// auditMiddleware writes the audit record of the request once it is answered
+func (h *handler) auditMiddleware(next requestHandler) requestHandler {
+	return requestHandlerFunc(func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		ctx, audit := withAudit(ctx, event)
+		response, err := next.serveRequest(ctx, event)
+		h.writeAuditRecord(ctx, audit, response)
+		return response, err
+	})
+}
+
+// corsMiddleware adds the CORS headers of the origin of the request to its response
+func corsMiddleware(next requestHandler) requestHandler {
+	return requestHandlerFunc(func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		response, err := next.serveRequest(ctx, event)
+		setCORSHeaders(event, &response)
+		return response, err
+	})
+}
+
+// claimsExemptResources are the resources served to callers without authorization claims,
+// as they translate nothing
+var claimsExemptResources = map[string]bool{
+	erasureResource:   true,
+	openAPIResource:   true,
+	languagesResource: true,
+}
+
+// authorizationMiddleware adds the entitlement of the caller to the context. Translations are
+// only served to callers whose claims say what they may translate.
+func authorizationMiddleware(next requestHandler) requestHandler {
+	return requestHandlerFunc(func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		caller, authorized := entitlementFromEvent(event)
+		if authorized {
+			ctx = withEntitlement(ctx, caller)
+		}
+		if claimsAuthorization && !authorized && !claimsExemptResources[event.Resource] {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusForbidden,
+				Body:       "Missing authorization claims",
+			}, nil
+		}
+		return next.serveRequest(ctx, event)
+	})
+}
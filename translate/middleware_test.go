@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestChainMiddlewares(t *testing.T) {
+	var calls []string
+	record := func(name string) requestMiddleware {
+		return func(next requestHandler) requestHandler {
+			return requestHandlerFunc(func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				calls = append(calls, name+" in")
+				response, err := next.serveRequest(ctx, event)
+				calls = append(calls, name+" out")
+				return response, err
+			})
+		}
+	}
+	handler := requestHandlerFunc(func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		calls = append(calls, "handler")
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+	})
+
+	chainMiddlewares(handler, record("outer"), record("inner")).serveRequest(context.Background(), events.APIGatewayProxyRequest{})
+	expected := []string{"outer in", "inner in", "handler", "inner out", "outer out"}
+	if !slices.Equal(calls, expected) {
+		t.Errorf("chainMiddlewares() calls = %v, expected %v", calls, expected)
+	}
+}
+
+func TestAuthorizationMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		resource       string
+		authorizer     map[string]any
+		expectedStatus int
+		expectedTenant string
+	}{
+		{
+			name:           "Entitled caller",
+			authorizer:     map[string]any{"custom:tenant": "acme"},
+			expectedStatus: http.StatusOK,
+			expectedTenant: "acme",
+		},
+		{
+			name:           "Missing claims",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Resource exempt from claims",
+			resource:       languagesResource,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := claimsAuthorization
+			claimsAuthorization = true
+			defer func() { claimsAuthorization = previous }()
+
+			var tenant string
+			handler := authorizationMiddleware(requestHandlerFunc(func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				caller, _ := entitlementFromContext(ctx)
+				tenant = caller.Tenant
+				return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+			}))
+
+			got, err := handler.serveRequest(context.Background(), events.APIGatewayProxyRequest{
+				Resource:       tt.resource,
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: tt.authorizer},
+			})
+			if err != nil {
+				t.Fatalf("serveRequest() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedStatus || tenant != tt.expectedTenant {
+				t.Errorf("serveRequest() = %d for tenant %q, expected %d for %q", got.StatusCode, tenant, tt.expectedStatus, tt.expectedTenant)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/service/comprehend"
+	comprehendTypes "github.com/aws/aws-sdk-go-v2/service/comprehend/types"
+)
+
+const (
+	moderationFlag = "flag"
+	moderationMask = "mask"
+
+	// moderationKeywordLabel labels segments matching the configured keywords
+	moderationKeywordLabel = "PROFANITY"
+	// toxicityThreshold is the Comprehend score above which a label is reported
+	toxicityThreshold = 0.5
+	// maxToxicitySegments is the number of segments Comprehend accepts per call
+	maxToxicitySegments = 10
+	// maxToxicitySegmentBytes is the size Comprehend accepts for each segment
+	maxToxicitySegmentBytes = 1024
+)
+
+// moderationKeywords are the lower case words of the comma separated MODERATION_KEYWORDS
+var moderationKeywords map[string]bool
+
+// SegmentReport describes a translated segment that needed attention
+type SegmentReport struct {
+	// SourceText is the source text of the segment
+	SourceText string `json:"source_text"`
+	// TranslatedText is the translated text of the segment as returned
+	TranslatedText string `json:"translated_text"`
+	// Labels are the moderation labels found in the translated text
+	Labels []string `json:"labels,omitempty"`
+	// Masked is set when the flagged text was masked in the response
+	Masked bool `json:"masked,omitempty"`
+	// Constraint is how the translation was fitted to the maximum length, "brevity" for a
+	// shorter translation and "truncated" for a cut one, or "exceeded" when it still exceeds it
+	Constraint string `json:"constraint,omitempty"`
+	// Length is the number of characters of the translation before it was constrained
+	Length int `json:"length,omitempty"`
+	// Issues are the likely problems the grammar check found in the translated text
+	Issues []GrammarIssue `json:"issues,omitempty"`
+	// TimedOut is set when the segment was left untranslated to answer by the deadline of the
+	// request
+	TimedOut bool `json:"timed_out,omitempty"`
+}
+
+// moderation holds the moderation setting of a request and the segments it flagged
+type moderation struct {
+	mode string
+
+	mu       sync.Mutex
+	segments []SegmentReport
+}
+
+type moderationKey struct{}
+
+// withModeration returns a context moderating every translated segment of the request
+func withModeration(ctx context.Context, mode string) (context.Context, *moderation) {
+	m := &moderation{mode: mode}
+	return context.WithValue(ctx, moderationKey{}, m), m
+}
+
+// moderationFromContext returns the moderation of the request, nil when it isn't moderated
+func moderationFromContext(ctx context.Context) *moderation {
+	m, _ := ctx.Value(moderationKey{}).(*moderation)
+	return m
+}
+
+// Segments returns the segments flagged so far
+func (m *moderation) Segments() []SegmentReport {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.segments
+}
+
+// moderateSentences checks the translated sentences against the keyword list and, for
+// English output, Comprehend toxicity detection. Flagged sentences are reported and masked
+// in place when the request asked for it.
+func (h *handler) moderateSentences(ctx context.Context, targetLanguage string, sourceSentences, translatedSentences []string) error {
+	m := moderationFromContext(ctx)
+	if m == nil || m.mode == "" {
+		return nil
+	}
+
+	labels := make([][]string, len(translatedSentences))
+	for i, sentence := range translatedSentences {
+		if containsKeyword(sentence) {
+			labels[i] = append(labels[i], moderationKeywordLabel)
+		}
+	}
+
+	// Comprehend only detects toxic content in English
+	if h.comprehendClient != nil && (targetLanguage == "en" || strings.HasPrefix(targetLanguage, "en-")) {
+		if err := detectToxicSentences(ctx, h.comprehendClient, translatedSentences, labels); err != nil {
+			return fmt.Errorf("error detecting toxic content: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, sentenceLabels := range labels {
+		if len(sentenceLabels) == 0 {
+			continue
+		}
+
+		report := SegmentReport{
+			SourceText: sourceSentences[i],
+			Labels:     sentenceLabels,
+		}
+		if m.mode == moderationMask {
+			translatedSentences[i] = maskSentence(translatedSentences[i], sentenceLabels)
+			report.Masked = true
+		}
+		report.TranslatedText = translatedSentences[i]
+		m.segments = append(m.segments, report)
+	}
+
+	return nil
+}
+
+// detectToxicSentences appends the toxic content labels of each sentence to its labels
+func detectToxicSentences(ctx context.Context, comprehendClient ComprehendClient, sentences []string, labels [][]string) error {
+	for start := 0; start < len(sentences); start += maxToxicitySegments {
+		end := min(start+maxToxicitySegments, len(sentences))
+
+		segments := make([]comprehendTypes.TextSegment, end-start)
+		for i, sentence := range sentences[start:end] {
+			text := truncateBytes(sentence, maxToxicitySegmentBytes)
+			segments[i] = comprehendTypes.TextSegment{Text: &text}
+		}
+
+		out, err := comprehendClient.DetectToxicContent(ctx, &comprehend.DetectToxicContentInput{
+			LanguageCode: comprehendTypes.LanguageCodeEn,
+			TextSegments: segments,
+		})
+		if err != nil {
+			return err
+		}
+
+		for i, result := range out.ResultList {
+			for _, label := range result.Labels {
+				if label.Score == nil || *label.Score < toxicityThreshold {
+					continue
+				}
+				if name := string(label.Name); !slices.Contains(labels[start+i], name) {
+					labels[start+i] = append(labels[start+i], name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// maskSentence masks the flagged text of a sentence. Keyword matches are masked word by word,
+// Comprehend doesn't locate the toxic content so the whole sentence is masked.
+func maskSentence(sentence string, labels []string) string {
+	if slices.ContainsFunc(labels, func(label string) bool { return label != moderationKeywordLabel }) {
+		return maskText(sentence)
+	}
+	return mapWords(sentence, func(word string) string {
+		if moderationKeywords[strings.ToLower(word)] {
+			return maskText(word)
+		}
+		return word
+	})
+}
+
+// containsKeyword reports whether any word of the sentence is a moderation keyword
+func containsKeyword(sentence string) bool {
+	found := false
+	mapWords(sentence, func(word string) string {
+		found = found || moderationKeywords[strings.ToLower(word)]
+		return word
+	})
+	return found
+}
+
+// mapWords rebuilds the text replacing each run of letters and digits with fn's result
+func mapWords(text string, fn func(word string) string) string {
+	var builder strings.Builder
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			builder.WriteString(fn(text[start:i]))
+			start = -1
+		}
+		if !isWord {
+			builder.WriteRune(r)
+		}
+	}
+	if start >= 0 {
+		builder.WriteString(fn(text[start:]))
+	}
+	return builder.String()
+}
+
+// maskText replaces every letter and digit with an asterisk, keeping spaces and punctuation
+func maskText(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return '*'
+		}
+		return r
+	}, text)
+}
+
+// truncateBytes shortens the text to at most limit bytes without splitting a character
+func truncateBytes(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+	return text[:limit]
+}
+
+// parseModerationKeywords parses a comma separated list of words
+func parseModerationKeywords(keywords string) map[string]bool {
+	parsed := make(map[string]bool)
+	for _, keyword := range strings.Split(keywords, ",") {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			parsed[strings.ToLower(keyword)] = true
+		}
+	}
+	return parsed
+}
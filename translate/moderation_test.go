@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/comprehend"
+	comprehendTypes "github.com/aws/aws-sdk-go-v2/service/comprehend/types"
+)
+
+func TestMaskSentence(t *testing.T) {
+	original := moderationKeywords
+	moderationKeywords = parseModerationKeywords("darn, coño")
+	defer func() { moderationKeywords = original }()
+
+	tests := []struct {
+		name     string
+		sentence string
+		labels   []string
+		expected string
+	}{
+		{
+			name:     "Keywords are masked word by word",
+			sentence: "Darn it, darned darn!",
+			labels:   []string{moderationKeywordLabel},
+			expected: "**** it, darned ****!",
+		},
+		{
+			name:     "Non ASCII keywords",
+			sentence: "¡Coño, qué frío!",
+			labels:   []string{moderationKeywordLabel},
+			expected: "¡****, qué frío!",
+		},
+		{
+			name:     "Comprehend labels mask the whole sentence",
+			sentence: "You are an idiot.",
+			labels:   []string{"INSULT"},
+			expected: "*** *** ** *****.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maskSentence(tt.sentence, tt.labels); got != tt.expected {
+				t.Errorf("maskSentence() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandleModeration(t *testing.T) {
+	original := moderationKeywords
+	moderationKeywords = parseModerationKeywords("darn")
+	defer func() { moderationKeywords = original }()
+
+	translations := map[string]string{
+		"Hola.":           "Hello.",
+		"Maldita sea.":    "Darn it.",
+		"Eres un idiota.": "You are an idiot.",
+	}
+
+	tests := []struct {
+		name             string
+		body             string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name: "Moderation disabled",
+			body: `{"source_language":"es","target_language":"en","text":"Hola. Maldita sea. Eres un idiota."}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hello. Darn it. You are an idiot. "}`,
+			},
+		},
+		{
+			name: "Flagged segments are reported",
+			body: `{"source_language":"es","target_language":"en","text":"Hola. Maldita sea. Eres un idiota.","moderation":"flag"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hello. Darn it. You are an idiot. ","segments":[{"source_text":"Maldita sea.","translated_text":"Darn it.","labels":["PROFANITY"]},{"source_text":"Eres un idiota.","translated_text":"You are an idiot.","labels":["INSULT"]}]}`,
+			},
+		},
+		{
+			name: "Flagged segments are masked",
+			body: `{"source_language":"es","target_language":"en","text":"Hola. Maldita sea. Eres un idiota.","moderation":"mask"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hello. **** it. *** *** ** *****. ","segments":[{"source_text":"Maldita sea.","translated_text":"**** it.","labels":["PROFANITY"],"masked":true},{"source_text":"Eres un idiota.","translated_text":"*** *** ** *****.","labels":["INSULT"],"masked":true}]}`,
+			},
+		},
+		{
+			name: "Unsupported moderation",
+			body: `{"source_language":"es","target_language":"en","text":"Hola.","moderation":"remove"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"moderation","message":"moderation \"remove\" is not supported"}]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(translations)
+			h.comprehendClient = &MockComprehendClient{
+				DetectToxicContentFunc: func(ctx context.Context, params *comprehend.DetectToxicContentInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectToxicContentOutput, error) {
+					out := &comprehend.DetectToxicContentOutput{}
+					for _, segment := range params.TextSegments {
+						result := comprehendTypes.ToxicLabels{}
+						if *segment.Text == "You are an idiot." {
+							result.Labels = []comprehendTypes.ToxicContent{
+								{Name: comprehendTypes.ToxicContentTypeInsult, Score: aws.Float32(0.9)},
+								{Name: comprehendTypes.ToxicContentTypeProfanity, Score: aws.Float32(0.1)},
+							}
+						}
+						out.ResultList = append(out.ResultList, result)
+					}
+					return out, nil
+				},
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %v, expected %v", got, tt.expectedResponse)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// multipartPartSize is the size of the parts of a multipart upload. S3 requires every part
+// but the last to be at least 5 MiB.
+var multipartPartSize = 8 << 20
+
+// multipartUpload writes an object to S3 part by part, so an object larger than the memory of
+// the function can be streamed to the bucket. Objects smaller than a part are written with a
+// single PutObject.
+type multipartUpload struct {
+	ctx         context.Context
+	client      S3Client
+	bucket      string
+	key         string
+	contentType string
+
+	uploadID *string
+	parts    []s3Types.CompletedPart
+	buf      bytes.Buffer
+}
+
+func newMultipartUpload(ctx context.Context, client S3Client, bucket, key, contentType string) *multipartUpload {
+	return &multipartUpload{
+		ctx:         ctx,
+		client:      client,
+		bucket:      bucket,
+		key:         key,
+		contentType: contentType,
+	}
+}
+
+// Write buffers p, uploading a part each time the buffer holds a full one
+func (u *multipartUpload) Write(p []byte) (int, error) {
+	u.buf.Write(p)
+	for u.buf.Len() >= multipartPartSize {
+		if err := u.uploadPart(u.buf.Next(multipartPartSize)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close uploads the rest of the buffer and completes the upload
+func (u *multipartUpload) Close() error {
+	if u.uploadID == nil {
+		_, err := u.client.PutObject(u.ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(u.bucket),
+			Key:         aws.String(u.key),
+			Body:        bytes.NewReader(u.buf.Bytes()),
+			ContentType: aws.String(u.contentType),
+		})
+		return err
+	}
+
+	if u.buf.Len() > 0 {
+		if err := u.uploadPart(u.buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	_, err := u.client.CompleteMultipartUpload(u.ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(u.bucket),
+		Key:             aws.String(u.key),
+		UploadId:        u.uploadID,
+		MultipartUpload: &s3Types.CompletedMultipartUpload{Parts: u.parts},
+	})
+	if err != nil {
+		u.Abort()
+		return fmt.Errorf("failed to complete upload: %w", err)
+	}
+	return nil
+}
+
+// Abort discards the parts uploaded so far, which S3 would otherwise keep and bill for
+func (u *multipartUpload) Abort() {
+	if u.uploadID == nil {
+		return
+	}
+	// Aborting is best effort, the upload has already failed
+	_, _ = u.client.AbortMultipartUpload(u.ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(u.bucket),
+		Key:      aws.String(u.key),
+		UploadId: u.uploadID,
+	})
+	u.uploadID = nil
+}
+
+// uploadPart uploads the next part, starting the upload with the first one
+func (u *multipartUpload) uploadPart(part []byte) error {
+	if u.uploadID == nil {
+		out, err := u.client.CreateMultipartUpload(u.ctx, &s3.CreateMultipartUploadInput{
+			Bucket:      aws.String(u.bucket),
+			Key:         aws.String(u.key),
+			ContentType: aws.String(u.contentType),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to start upload: %w", err)
+		}
+		u.uploadID = out.UploadId
+	}
+
+	partNumber := aws.Int32(int32(len(u.parts) + 1))
+	out, err := u.client.UploadPart(u.ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(u.bucket),
+		Key:        aws.String(u.key),
+		UploadId:   u.uploadID,
+		PartNumber: partNumber,
+		Body:       bytes.NewReader(part),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload part %d: %w", *partNumber, err)
+	}
+	u.parts = append(u.parts, s3Types.CompletedPart{ETag: out.ETag, PartNumber: partNumber})
+	return nil
+}
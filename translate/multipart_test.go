@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestMultipartUpload(t *testing.T) {
+	previous := multipartPartSize
+	multipartPartSize = 4
+	defer func() { multipartPartSize = previous }()
+
+	tests := []struct {
+		name            string
+		writes          []string
+		uploadError     error
+		expectedObject  string
+		expectedParts   []string
+		expectedAborted bool
+		wantErr         bool
+	}{
+		{
+			name:           "Object smaller than a part is put whole",
+			writes:         []string{"abc"},
+			expectedObject: "abc",
+		},
+		{
+			name:          "Object is uploaded in parts",
+			writes:        []string{"abcdef", "ghij", "k"},
+			expectedParts: []string{"abcd", "efgh", "ijk"},
+		},
+		{
+			name:            "Failed part aborts the upload",
+			writes:          []string{"abcdefgh"},
+			uploadError:     fmt.Errorf("mock error"),
+			expectedAborted: true,
+			wantErr:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				object    string
+				parts     []string
+				completed []int32
+				aborted   bool
+			)
+
+			client := &MockS3Client{
+				PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+					body, _ := io.ReadAll(params.Body)
+					object = string(body)
+					return &s3.PutObjectOutput{}, nil
+				},
+				CreateMultipartUploadFunc: func(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+					return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-id")}, nil
+				},
+				UploadPartFunc: func(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+					if tt.uploadError != nil {
+						return nil, tt.uploadError
+					}
+					body, _ := io.ReadAll(params.Body)
+					parts = append(parts, string(body))
+					return &s3.UploadPartOutput{ETag: aws.String(fmt.Sprintf("etag-%d", *params.PartNumber))}, nil
+				},
+				CompleteMultipartUploadFunc: func(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+					for _, part := range params.MultipartUpload.Parts {
+						if aws.ToString(part.ETag) != fmt.Sprintf("etag-%d", *part.PartNumber) {
+							t.Errorf("CompleteMultipartUpload() part %d etag = %s", *part.PartNumber, aws.ToString(part.ETag))
+						}
+						completed = append(completed, *part.PartNumber)
+					}
+					return &s3.CompleteMultipartUploadOutput{}, nil
+				},
+				AbortMultipartUploadFunc: func(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+					aborted = true
+					return &s3.AbortMultipartUploadOutput{}, nil
+				},
+			}
+
+			upload := newMultipartUpload(context.Background(), client, "bucket", "key", "text/plain")
+			var err error
+			for _, write := range tt.writes {
+				if _, err = io.WriteString(upload, write); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = upload.Close()
+			} else {
+				upload.Abort()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("multipartUpload error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if object != tt.expectedObject {
+				t.Errorf("PutObject() = %q, expected %q", object, tt.expectedObject)
+			}
+			if strings.Join(parts, ",") != strings.Join(tt.expectedParts, ",") {
+				t.Errorf("UploadPart() = %q, expected %q", parts, tt.expectedParts)
+			}
+			if len(completed) != len(tt.expectedParts) {
+				t.Errorf("CompleteMultipartUpload() parts = %v, expected %d", completed, len(tt.expectedParts))
+			}
+			if aborted != tt.expectedAborted {
+				t.Errorf("AbortMultipartUpload() = %v, expected %v", aborted, tt.expectedAborted)
+			}
+		})
+	}
+}
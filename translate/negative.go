@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/smithy-go"
+)
+
+// permanentTranslateErrorCodes are the AWS Translate errors caused by the text or language
+// pair itself, retrying them can never succeed
+var permanentTranslateErrorCodes = map[string]bool{
+	"UnsupportedLanguagePairException":       true,
+	"DetectedLanguageLowConfidenceException": true,
+	"TextSizeLimitExceededException":         true,
+}
+
+// translationFailure is returned when a text can't be translated by the provider
+type translationFailure struct {
+	// Reason is the provider error code explaining the failure
+	Reason string
+}
+
+func (f *translationFailure) Error() string {
+	return fmt.Sprintf("text could not be translated: %s", f.Reason)
+}
+
+// permanentTranslateFailure returns the reason of a translate error that will fail again
+// for the same text
+func permanentTranslateFailure(err error) (string, bool) {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && permanentTranslateErrorCodes[apiErr.ErrorCode()] {
+		return apiErr.ErrorCode(), true
+	}
+	return "", false
+}
+
+// cacheFailure remembers that the text could not be translated for the negative cache TTL,
+// so repeated requests for it don't call the provider again. Failing to store it is only
+// logged as the request fails either way.
+func (h *handler) cacheFailure(ctx context.Context, sourceLanguage, targetLanguage, text, provider, reason string) {
+	if negativeCacheTTL <= 0 {
+		return
+	}
+
+	item := CacheItem{
+		Hash:           getCacheKey(sourceLanguage, cacheTargetLanguage(ctx, targetLanguage), text),
+		SourceText:     text,
+		SourceLanguage: sourceLanguage,
+		TargetLanguage: targetLanguage,
+		Region:         region,
+		Provider:       provider,
+		Failure:        reason,
+		ExpiresAt:      time.Now().Add(time.Duration(negativeCacheTTL) * time.Second).Unix(),
+	}
+	if skipCacheWrite(ctx, item) {
+		return
+	}
+
+	if err := h.storeCacheItem(ctx, item); err != nil {
+		log.Printf("Error caching translation failure: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+)
+
+func TestPermanentTranslateFailure(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedReason string
+		expectedOk     bool
+	}{
+		{
+			name:           "Unsupported language pair",
+			err:            &types.UnsupportedLanguagePairException{},
+			expectedReason: "UnsupportedLanguagePairException",
+			expectedOk:     true,
+		},
+		{
+			name:           "Wrapped text size limit",
+			err:            fmt.Errorf("wrapped: %w", &types.TextSizeLimitExceededException{}),
+			expectedReason: "TextSizeLimitExceededException",
+			expectedOk:     true,
+		},
+		{
+			name:           "Throttling is transient",
+			err:            &types.TooManyRequestsException{},
+			expectedReason: "",
+			expectedOk:     false,
+		},
+		{
+			name:           "Generic error",
+			err:            fmt.Errorf("mock error"),
+			expectedReason: "",
+			expectedOk:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotReason, gotOk := permanentTranslateFailure(tt.err)
+			if gotReason != tt.expectedReason || gotOk != tt.expectedOk {
+				t.Errorf("permanentTranslateFailure() = %v, %v, expected %v, %v", gotReason, gotOk, tt.expectedReason, tt.expectedOk)
+			}
+		})
+	}
+}
+
+func TestHandleNegativeCache(t *testing.T) {
+	failureItem := func(expiresAt int64) map[string]dynamoTypes.AttributeValue {
+		return map[string]dynamoTypes.AttributeValue{
+			"hash":            &dynamoTypes.AttributeValueMemberS{Value: "test-hash"},
+			"translated_text": &dynamoTypes.AttributeValueMemberS{Value: ""},
+			"source_text":     &dynamoTypes.AttributeValueMemberS{Value: "Hello"},
+			"source_language": &dynamoTypes.AttributeValueMemberS{Value: "en"},
+			"target_language": &dynamoTypes.AttributeValueMemberS{Value: "es"},
+			"failure":         &dynamoTypes.AttributeValueMemberS{Value: "UnsupportedLanguagePairException"},
+			"expires_at":      &dynamoTypes.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt, 10)},
+		}
+	}
+
+	tests := []struct {
+		name              string
+		cachedItem        map[string]dynamoTypes.AttributeValue
+		translateError    error
+		expectedResponse  events.APIGatewayProxyResponse
+		expectedCalls     int
+		expectedPutFailed bool
+	}{
+		{
+			name:       "Cached failure skips the provider",
+			cachedItem: failureItem(time.Now().Add(time.Minute).Unix()),
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusUnprocessableEntity,
+				Body:       "Text could not be translated: UnsupportedLanguagePairException",
+			},
+			expectedCalls: 0,
+		},
+		{
+			name:       "Expired failure is retried",
+			cachedItem: failureItem(time.Now().Add(-time.Minute).Unix()),
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola "}`,
+			},
+			expectedCalls: 1,
+		},
+		{
+			name:           "Permanent failure is cached",
+			translateError: &types.UnsupportedLanguagePairException{},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusUnprocessableEntity,
+				Body:       "Text could not be translated: UnsupportedLanguagePairException",
+			},
+			expectedCalls:     1,
+			expectedPutFailed: true,
+		},
+		{
+			name:           "Transient failure is not cached",
+			translateError: fmt.Errorf("mock error"),
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Error during translation",
+			},
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			putFailed := false
+
+			h := newMockHandler(map[string]string{"Hello": "Hola"})
+			h.dynamoClient = &MockDynamoDBClient{
+				GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					return &dynamodb.GetItemOutput{Item: tt.cachedItem}, nil
+				},
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					if _, ok := params.Item["failure"]; ok {
+						putFailed = true
+					}
+					return &dynamodb.PutItemOutput{}, nil
+				},
+			}
+			translateText := h.translateClient.(*MockTranslateClient).TranslateTextFunc
+			h.translateClient.(*MockTranslateClient).TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				calls++
+				if tt.translateError != nil {
+					return nil, tt.translateError
+				}
+				return translateText(ctx, params, optFns...)
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+				Body: `{"source_language":"en","target_language":"es","text":"Hello"}`,
+			})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %v, expected %v", got, tt.expectedResponse)
+			}
+			if calls != tt.expectedCalls {
+				t.Errorf("TranslateText() called %d times, expected %d", calls, tt.expectedCalls)
+			}
+			if putFailed != tt.expectedPutFailed {
+				t.Errorf("failure cached = %v, expected %v", putFailed, tt.expectedPutFailed)
+			}
+		})
+	}
+}
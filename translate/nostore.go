@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+)
+
+var (
+	// noStorePattern and noStoreKeywords are the policy of the text never written to the
+	// cache, a regular expression and comma separated words matched case insensitively
+	noStorePattern  *regexp.Regexp
+	noStoreKeywords []string
+)
+
+const (
+	noStoreReasonRequested = "no_store was requested"
+	noStoreReasonPolicy    = "the text matched the no-store policy"
+)
+
+type noStoreKey struct{}
+
+// withNoStore returns a context whose translations are read from the cache but never written
+// to it, reason being why for the audit log. The context is unchanged when reason is empty.
+func withNoStore(ctx context.Context, reason string) context.Context {
+	if reason == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, noStoreKey{}, reason)
+}
+
+// requestNoStoreReason returns why the translations of the request must not be cached, empty
+// when they can be
+func requestNoStoreReason(request TranslateRequest) string {
+	switch {
+	case request.NoStore:
+		return noStoreReasonRequested
+	case matchesNoStorePolicy(request.Text):
+		return noStoreReasonPolicy
+	}
+	return ""
+}
+
+// skipCacheWrite reports whether the item must not be cached, either because of its request
+// or because its text matches the policy. Every skipped write is audit logged by hash so the
+// text itself is never logged.
+func skipCacheWrite(ctx context.Context, item CacheItem) bool {
+	reason, _ := ctx.Value(noStoreKey{}).(string)
+	if reason == "" && matchesNoStorePolicy(item.SourceText) {
+		reason = noStoreReasonPolicy
+	}
+	if reason == "" {
+		return false
+	}
+
+	log.Printf("Audit: translation %s from %q to %q was not cached, %s", item.Hash, item.SourceLanguage, item.TargetLanguage, reason)
+	return true
+}
+
+// matchesNoStorePolicy reports whether the text matches the no-store pattern or keywords
+func matchesNoStorePolicy(text string) bool {
+	if noStorePattern != nil && noStorePattern.MatchString(text) {
+		return true
+	}
+	if len(noStoreKeywords) == 0 {
+		return false
+	}
+
+	lower := strings.ToLower(text)
+	for _, keyword := range noStoreKeywords {
+		if strings.Contains(lower, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNoStorePattern compiles the no-store pattern. An invalid pattern stops the function
+// from starting rather than caching the text it was meant to protect.
+func parseNoStorePattern(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		log.Fatalf("Invalid NO_STORE_PATTERN: %v", err)
+	}
+	return compiled
+}
+
+// parseNoStoreKeywords returns the lower case keywords of the comma separated list
+func parseNoStoreKeywords(keywords string) []string {
+	var parsed []string
+	for _, keyword := range strings.Split(keywords, ",") {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			parsed = append(parsed, strings.ToLower(keyword))
+		}
+	}
+	return parsed
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestMatchesNoStorePolicy(t *testing.T) {
+	originalPattern, originalKeywords := noStorePattern, noStoreKeywords
+	noStorePattern = regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)
+	noStoreKeywords = parseNoStoreKeywords("confidential, Privileged ")
+	defer func() { noStorePattern, noStoreKeywords = originalPattern, originalKeywords }()
+
+	tests := []struct {
+		name     string
+		text     string
+		expected bool
+	}{
+		{name: "No match", text: "The meeting is on Monday.", expected: false},
+		{name: "Pattern", text: "My SSN is 123-45-6789.", expected: true},
+		{name: "Keyword in any case", text: "CONFIDENTIAL: Supply agreement", expected: true},
+		{name: "Keyword in a word", text: "Attorney-client privileged.", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesNoStorePolicy(tt.text); got != tt.expected {
+				t.Errorf("matchesNoStorePolicy() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandleNoStore(t *testing.T) {
+	original := noStoreKeywords
+	noStoreKeywords = parseNoStoreKeywords("confidential")
+	defer func() { noStoreKeywords = original }()
+
+	translations := map[string]string{
+		"Hello.":              "Hola.",
+		"Confidential terms.": "Términos confidenciales.",
+	}
+
+	tests := []struct {
+		name             string
+		body             string
+		expectedLookups  []string
+		expectedWrites   []string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name:             "Translations are cached",
+			body:             `{"source_language":"en","target_language":"es","text":"Hello."}`,
+			expectedLookups:  []string{getCacheKey("en", "es", "Hello.")},
+			expectedWrites:   []string{getCacheKey("en", "es", "Hello.")},
+			expectedResponse: events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: `{"translated_text":"Hola. "}`},
+		},
+		{
+			name:             "No store reads the cache only",
+			body:             `{"source_language":"en","target_language":"es","text":"Hello.","no_store":true}`,
+			expectedLookups:  []string{getCacheKey("en", "es", "Hello.")},
+			expectedResponse: events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: `{"translated_text":"Hola. "}`},
+		},
+		{
+			name:             "Text matching the policy isn't cached",
+			body:             `{"source_language":"en","target_language":"es","text":"Hello. Confidential terms."}`,
+			expectedLookups:  []string{getCacheKey("en", "es", "Hello."), getCacheKey("en", "es", "Confidential terms.")},
+			expectedResponse: events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: `{"translated_text":"Hola. Términos confidenciales. "}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu      sync.Mutex
+				lookups []string
+				writes  []string
+			)
+
+			h := newMockHandler(translations)
+			h.dynamoClient = &MockDynamoDBClient{
+				GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					if hash := params.Key["hash"].(*dynamoTypes.AttributeValueMemberS).Value; hash != supportedLanguagesHash {
+						lookups = append(lookups, hash)
+					}
+					return &dynamodb.GetItemOutput{}, nil
+				},
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					if hash := params.Item["hash"].(*dynamoTypes.AttributeValueMemberS).Value; hash != supportedLanguagesHash {
+						writes = append(writes, hash)
+					}
+					return &dynamodb.PutItemOutput{}, nil
+				},
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %v, expected %v", got, tt.expectedResponse)
+			}
+			slices.Sort(lookups)
+			slices.Sort(tt.expectedLookups)
+			if !slices.Equal(lookups, tt.expectedLookups) {
+				t.Errorf("GetItem() hashes = %v, expected %v", lookups, tt.expectedLookups)
+			}
+			if !slices.Equal(writes, tt.expectedWrites) {
+				t.Errorf("PutItem() hashes = %v, expected %v", writes, tt.expectedWrites)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+//go:generate go run ./tools/openapigen -out openapi.json
+
+// openAPIResource is the API Gateway resource serving the OpenAPI document of the API
+const openAPIResource = "/openapi.json"
+
+// openAPIDocument is the OpenAPI document generated from the request and response types
+//
+//go:embed openapi.json
+var openAPIDocument string
+
+// handleOpenAPIRequest returns the OpenAPI document of the API
+func handleOpenAPIRequest() events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       openAPIDocument,
+	}
+}
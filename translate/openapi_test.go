@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestHandleOpenAPI(t *testing.T) {
+	h := newMockHandler(nil)
+
+	got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Resource: openAPIResource})
+	if err != nil {
+		t.Fatalf("handle() error = %v", err)
+	}
+	if got.StatusCode != http.StatusOK || got.Headers["Content-Type"] != "application/json" {
+		t.Fatalf("handle() = %d %v, expected a JSON document", got.StatusCode, got.Headers)
+	}
+
+	var document struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal([]byte(got.Body), &document); err != nil {
+		t.Fatalf("handle() returned an invalid document: %v", err)
+	}
+	if document.OpenAPI != "3.0.3" || document.Paths["/translate"]["post"] == nil || document.Paths[openAPIResource]["get"] == nil {
+		t.Errorf("handle() document = %s, expected the translation and OpenAPI paths", got.Body)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// pageURLResource is the API Gateway resource translating the web page of a URL, fetched by
+// the function rather than sent as the text of the request
+const pageURLResource = "/translate/url"
+
+// validatePageURL checks that a URL is requested by the page URL resource and only by it
+func validatePageURL(resource string, request TranslateRequest) error {
+	errs := &validationError{}
+
+	switch {
+	case resource != pageURLResource && request.URL != "":
+		errs.add("url", "url is only supported by POST %s", pageURLResource)
+	case resource == pageURLResource && request.URL == "":
+		errs.add("url", "url is required")
+	}
+
+	if len(errs.Fields) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validatePageURLField checks the shape of the url of a request, the address it resolves to
+// is checked when it is fetched
+func validatePageURLField(errs *validationError, request TranslateRequest) {
+	if request.URL == "" {
+		return
+	}
+
+	page, err := url.Parse(request.URL)
+	switch {
+	case err != nil || (page.Scheme != "http" && page.Scheme != "https") || page.Host == "":
+		errs.add("url", "url must be an absolute http or https url")
+	case page.User != nil:
+		errs.add("url", "url must not contain credentials")
+	}
+	if request.Text != "" {
+		errs.add("text", "text must be empty when url is set")
+	}
+	if request.Format != "" && request.Format != formatHTML {
+		errs.add("format", "format must be html when url is set")
+	}
+}
+
+// pageClient returns the client fetching web pages
+func (h *handler) pageClient() *http.Client {
+	if h.httpClient != nil {
+		return h.httpClient
+	}
+	return newPageClient(h.transport)
+}
+
+// fetchRequestPage fetches the page of a URL request as its HTML text. The response is
+// returned instead when the page can't be translated.
+func (h *handler) fetchRequestPage(ctx context.Context, request *TranslateRequest) (events.APIGatewayProxyResponse, bool) {
+	body, contentType, err := fetchPage(ctx, h.pageClient(), request.URL)
+	var statusErr *fetchStatusError
+	switch {
+	case errors.Is(err, errBlockedAddress):
+		errs := &validationError{}
+		errs.add("url", "url must resolve to a public address")
+		return validationErrorResponse(errs), false
+	case errors.As(err, &statusErr):
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadGateway,
+			Body:       "Error fetching url: " + statusErr.Error(),
+		}, false
+	case err != nil:
+		log.Printf("Error fetching %s: %v", request.URL, err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadGateway,
+			Body:       "Error fetching url",
+		}, false
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "text/html" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			Body:       "URL is not an HTML page",
+		}, false
+	}
+	text, err := decodeHTMLPage(body, contentType)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			Body:       "URL could not be decoded: " + err.Error(),
+		}, false
+	}
+	if len(splitSentences(text)) > maxRequestSentences {
+		errs := &validationError{}
+		errs.add("url", "url page must not exceed %d sentences", maxRequestSentences)
+		return validationErrorResponse(errs), false
+	}
+
+	request.Text = text
+	request.Format = formatHTML
+	return events.APIGatewayProxyResponse{}, true
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestHandlePageURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+			w.Write([]byte("<p>Hello.</p>"))
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("PNG"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name             string
+		resource         string
+		body             string
+		guarded          bool
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:             "Page",
+			resource:         pageURLResource,
+			body:             `{"source_language":"en","target_language":"es","url":"` + server.URL + `/page"}`,
+			expectedStatus:   http.StatusOK,
+			expectedContains: `Hola.`,
+		},
+		{
+			name:             "URL on the translate resource",
+			resource:         "/translate",
+			body:             `{"source_language":"en","target_language":"es","text":"Hello.","url":"` + server.URL + `/page"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `"message":"url is only supported by POST /translate/url"`,
+		},
+		{
+			name:             "Missing URL",
+			resource:         pageURLResource,
+			body:             `{"source_language":"en","target_language":"es","text":"Hello."}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `"message":"url is required"`,
+		},
+		{
+			name:             "Invalid URL",
+			resource:         pageURLResource,
+			body:             `{"source_language":"en","target_language":"es","url":"file:///etc/passwd","format":"pdf"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `"message":"url must be an absolute http or https url"},{"field":"format","message":"format must be html when url is set"}`,
+		},
+		{
+			name:             "Private address",
+			resource:         pageURLResource,
+			body:             `{"source_language":"en","target_language":"es","url":"` + server.URL + `/page"}`,
+			guarded:          true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `"message":"url must resolve to a public address"`,
+		},
+		{
+			name:             "Not found",
+			resource:         pageURLResource,
+			body:             `{"source_language":"en","target_language":"es","url":"` + server.URL + `/missing"}`,
+			expectedStatus:   http.StatusBadGateway,
+			expectedContains: "Error fetching url: unexpected status 404",
+		},
+		{
+			name:             "Not HTML",
+			resource:         pageURLResource,
+			body:             `{"source_language":"en","target_language":"es","url":"` + server.URL + `/logo.png"}`,
+			expectedStatus:   http.StatusUnprocessableEntity,
+			expectedContains: "URL is not an HTML page",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(map[string]string{"Hello.": "Hola."})
+			if !tt.guarded {
+				h.httpClient = server.Client()
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+				HTTPMethod: http.MethodPost,
+				Resource:   tt.resource,
+				Body:       tt.body,
+			})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedStatus || !strings.Contains(got.Body, tt.expectedContains) {
+				t.Errorf("handle() = %d %s, expected %d containing %s", got.StatusCode, got.Body, tt.expectedStatus, tt.expectedContains)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+const (
+	// languageCodeCaseCanonical writes language codes with the BCP 47 casing, such as zh-Hant-TW
+	languageCodeCaseCanonical = "canonical"
+	languageCodeCaseLower     = "lower"
+	languageCodeCaseUpper     = "upper"
+)
+
+// ResolvedParameters echoes the parameters a request was translated with, once its languages
+// were normalized or detected and its options defaulted, so clients can verify what was done
+type ResolvedParameters struct {
+	SourceLanguage string `json:"source_language"`
+	TargetLanguage string `json:"target_language"`
+	Format         string `json:"format"`
+	Style          string `json:"style,omitempty"`
+	Moderation     string `json:"moderation,omitempty"`
+	Typography     string `json:"typography,omitempty"`
+	MaxLength      int    `json:"max_length,omitempty"`
+	// Truncation is the truncation of a translation exceeding the maximum length
+	Truncation string `json:"truncation,omitempty"`
+	// Granularity is the chunk of text translated at once, left out for sentences
+	Granularity string `json:"granularity,omitempty"`
+	// Disclaimer is the position of the disclaimer added to the translation
+	Disclaimer string `json:"disclaimer,omitempty"`
+	// Options are the other options applied to the translation in alphabetical order. An
+	// option without effect, such as inclusive for a language without inclusive terminology,
+	// isn't listed.
+	Options []string `json:"options,omitempty"`
+}
+
+// normalizeLanguageCode returns the language code with the BCP 47 casing: a lower case
+// language, a title case script and an upper case region. The auto detection code is kept.
+func normalizeLanguageCode(code string) string {
+	if code == autoDetectLanguage {
+		return code
+	}
+	subtags := strings.Split(code, "-")
+	for i, subtag := range subtags {
+		switch {
+		case i == 0:
+			subtags[i] = strings.ToLower(subtag)
+		case len(subtag) == 4:
+			subtags[i] = strings.ToUpper(subtag[:1]) + strings.ToLower(subtag[1:])
+		default:
+			subtags[i] = strings.ToUpper(subtag)
+		}
+	}
+	return strings.Join(subtags, "-")
+}
+
+// formatLanguageCode writes a normalized language code with the casing of the request
+func formatLanguageCode(code, casing string) string {
+	switch casing {
+	case languageCodeCaseLower:
+		return strings.ToLower(code)
+	case languageCodeCaseUpper:
+		return strings.ToUpper(code)
+	}
+	return code
+}
+
+// resolvedParameters returns the parameters the request was translated with in the context
+func resolvedParameters(ctx context.Context, request TranslateRequest) *ResolvedParameters {
+	if !request.EchoParameters {
+		return nil
+	}
+
+	parameters := &ResolvedParameters{
+		SourceLanguage: formatLanguageCode(request.SourceLanguage, request.LanguageCodeCase),
+		TargetLanguage: formatLanguageCode(request.TargetLanguage, request.LanguageCodeCase),
+		Format:         request.Format,
+		Style:          request.Style,
+		Moderation:     request.Moderation,
+		Typography:     request.Typography,
+	}
+	if parameters.Format == "" {
+		parameters.Format = formatText
+	}
+	if request.Granularity != granularitySentence {
+		parameters.Granularity = request.Granularity
+	}
+	if c := lengthConstraintFromContext(ctx); c != nil {
+		parameters.MaxLength, parameters.Truncation = c.maxLength, c.truncation
+	}
+
+	for option, applied := range map[string]bool{
+		"alignment":          request.Alignment,
+		"analyze":            request.Analyze,
+		"entity_protection":  len(protectedEntitiesFromContext(ctx)) > 0,
+		"grammar_check":      grammarCheckFromContext(ctx) != nil,
+		"inclusive":          inclusiveTerminology(ctx, request.TargetLanguage) != "",
+		"localize_formats":   localeFormattingFromContext(ctx),
+		"no_store":           requestNoStoreReason(request) != "",
+		"preserve_casing":    casingPreservationFromContext(ctx),
+		"translate_metadata": request.TranslateMetadata,
+	} {
+		if applied {
+			parameters.Options = append(parameters.Options, option)
+		}
+	}
+	slices.Sort(parameters.Options)
+	return parameters
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestNormalizeLanguageCode(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{code: "ES", expected: "es"},
+		{code: "pt-br", expected: "pt-BR"},
+		{code: "ZH-HANT-tw", expected: "zh-Hant-TW"},
+		{code: "es-419", expected: "es-419"},
+		{code: autoDetectLanguage, expected: autoDetectLanguage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := normalizeLanguageCode(tt.code); got != tt.expected {
+				t.Errorf("normalizeLanguageCode() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEchoParameters(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name: "Normalized languages and defaulted options",
+			body: `{"source_language":"EN","target_language":"ES","text":"Hello.","echo_parameters":true,"max_length":20,"preserve_casing":true}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body: `{"translated_text":"Hola. ","parameters":{"source_language":"en","target_language":"es","format":"text",` +
+					`"max_length":20,"truncation":"ellipsis","options":["preserve_casing"]}}`,
+			},
+		},
+		{
+			name: "Detected source language in upper case",
+			body: `{"source_language":"auto","target_language":"ES","text":"<p>Hello.</p>","format":"html","no_store":true,"echo_parameters":true,"language_code_case":"upper"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"\u003cp lang=\"es\"\u003eHola.\u003c/p\u003e","parameters":{"source_language":"EN","target_language":"ES","format":"html","options":["no_store"]}}`,
+			},
+		},
+		{
+			name: "Skipped translation",
+			body: `{"source_language":"auto","target_language":"en","text":"Hello.","echo_parameters":true,"language_code_case":"lower"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hello.","detected_language":"en","skipped":true,"parameters":{"source_language":"en","target_language":"en","format":"text"}}`,
+			},
+		},
+		{
+			name: "Inclusive without a terminology has no effect",
+			body: `{"source_language":"en","target_language":"es","text":"Hello.","inclusive":true,"echo_parameters":true}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola. ","parameters":{"source_language":"en","target_language":"es","format":"text"}}`,
+			},
+		},
+		{
+			name: "Unsupported casing",
+			body: `{"source_language":"en","target_language":"es","text":"Hello.","language_code_case":"title"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"language_code_case","message":"language_code_case \"title\" is not supported"}]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(map[string]string{"Hello.": "Hola."})
+			h.comprehendClient = newMockLanguageDetector("en")
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/textract"
+	textractTypes "github.com/aws/aws-sdk-go-v2/service/textract/types"
+)
+
+const (
+	formatText = "text"
+	formatPDF  = "pdf"
+
+	outputText   = "text"
+	outputBlocks = "blocks"
+)
+
+var (
+	// pdfPagePattern matches the page objects of a PDF document, not the /Pages tree nodes.
+	// The pages of a document compressing its objects are not counted.
+	pdfPagePattern = regexp.MustCompile(`/Type\s*/Page\b`)
+
+	errNoDocumentText = errors.New("no text detected in document")
+)
+
+// multiPageDocument is returned for a document with more pages than Textract supports
+type multiPageDocument struct {
+	Pages int
+}
+
+func (m *multiPageDocument) Error() string {
+	return fmt.Sprintf("document has %d pages", m.Pages)
+}
+
+// TranslatedBlock represents a block of text extracted from a document
+type TranslatedBlock struct {
+	// SourceText is the original text of the block
+	SourceText string `json:"source_text"`
+	// TranslatedText is the translated text of the block
+	TranslatedText string `json:"translated_text"`
+}
+
+func (h *handler) handlePDF(ctx context.Context, request TranslateRequest) (events.APIGatewayProxyResponse, error) {
+	document, err := base64.StdEncoding.DecodeString(request.Document)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "document must be base64 encoded",
+		}, nil
+	}
+
+	degradation := degradationFromContext(ctx)
+	moderation := moderationFromContext(ctx)
+
+	blocks, err := extractTextBlocks(ctx, h.textractClient, document)
+	if response, ok := documentErrorResponse(err); ok {
+		return response, nil
+	}
+	if err != nil {
+		log.Printf("Error extracting document text: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error extracting document text",
+		}, nil
+	}
+
+	translatedBlocks, err := h.translateBlocks(ctx, request.SourceLanguage, request.TargetLanguage, blocks)
+	if response, ok := unavailableResponse(err); ok {
+		return response, nil
+	}
+	if response, ok := characterLimitResponse(err); ok {
+		return response, nil
+	}
+	if response, ok := languagePolicyResponse(err); ok {
+		return response, nil
+	}
+	if err != nil {
+		log.Printf("Error during translation: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error during translation",
+		}, nil
+	}
+
+	lines := make([]string, len(translatedBlocks))
+	for i, block := range translatedBlocks {
+		lines[i] = block.TranslatedText
+	}
+
+	response := TranslateResponse{
+		TranslatedText: strings.Join(lines, "\n"),
+		Skipped:        request.SourceLanguage == request.TargetLanguage,
+		Degraded:       degradation.Degraded(),
+		LowQuality:     degradation.LowQuality(),
+		Pivoted:        pivotReportFromContext(ctx).Pivoted(),
+		Segments:       moderation.Segments(),
+		Direction:      textDirection(request.TargetLanguage),
+	}
+	if request.Output == outputBlocks {
+		response.Blocks = translatedBlocks
+	}
+	disclaimerFromContext(ctx).add(&response, formatPDF)
+
+	return newJSONResponse(ctx, response), nil
+}
+
+// translateBlocks translates all sentences of all blocks in a single pass and
+// regroups the translated sentences by block
+func (h *handler) translateBlocks(ctx context.Context, sourceLanguage, targetLanguage string, blocks []string) ([]TranslatedBlock, error) {
+	var tokens []string
+	sentenceCounts := make([]int, len(blocks))
+	for i, block := range blocks {
+		sentences := splitSentences(block)
+		sentenceCounts[i] = len(sentences)
+		tokens = append(tokens, sentences...)
+	}
+
+	translatedSentences, err := h.translateSentences(ctx, sourceLanguage, targetLanguage, tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	translatedBlocks := make([]TranslatedBlock, len(blocks))
+	offset := 0
+	for i, block := range blocks {
+		sentences := translatedSentences[offset : offset+sentenceCounts[i]]
+		offset += sentenceCounts[i]
+
+		translatedBlocks[i] = TranslatedBlock{
+			SourceText:     block,
+			TranslatedText: strings.TrimSpace(joinSentences(sentences)),
+		}
+	}
+
+	return translatedBlocks, nil
+}
+
+// extractTextBlocks returns the lines of text detected in the document. The synchronous
+// Textract API only supports single page PDF documents, a document with several pages is
+// rejected before paying for the call.
+func extractTextBlocks(ctx context.Context, textractClient TextractClient, document []byte) ([]string, error) {
+	if pages := len(pdfPagePattern.FindAllIndex(document, -1)); pages > 1 {
+		return nil, &multiPageDocument{Pages: pages}
+	}
+
+	out, err := textractClient.DetectDocumentText(ctx, &textract.DetectDocumentTextInput{
+		Document: &textractTypes.Document{
+			Bytes: document,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []string
+	for _, block := range out.Blocks {
+		if block.BlockType != textractTypes.BlockTypeLine || block.Text == nil {
+			continue
+		}
+		blocks = append(blocks, *block.Text)
+	}
+
+	if len(blocks) == 0 {
+		return nil, errNoDocumentText
+	}
+
+	return blocks, nil
+}
+
+// documentErrorResponse returns a 4xx response when the error was caused by the document
+// rather than by Textract
+func documentErrorResponse(err error) (events.APIGatewayProxyResponse, bool) {
+	var multiPage *multiPageDocument
+	switch {
+	case errors.As(err, &multiPage):
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			Body:       fmt.Sprintf("Document has %d pages, only single page documents are supported", multiPage.Pages),
+		}, true
+	case errors.As(err, new(*textractTypes.UnsupportedDocumentException)):
+		// Textract also rejects the documents whose pages we couldn't count this way
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			Body:       "Document format not supported, only single page documents are supported",
+		}, true
+	case errors.As(err, new(*textractTypes.DocumentTooLargeException)):
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			Body:       "Document is too large",
+		}, true
+	case errors.As(err, new(*textractTypes.BadDocumentException)):
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Document could not be read",
+		}, true
+	case errors.Is(err, errNoDocumentText):
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			Body:       "No text detected in document",
+		}, true
+	}
+	return events.APIGatewayProxyResponse{}, false
+}
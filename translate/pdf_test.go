@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/textract"
+	textractTypes "github.com/aws/aws-sdk-go-v2/service/textract/types"
+)
+
+func TestExtractTextBlocks(t *testing.T) {
+	tests := []struct {
+		name       string
+		document   string
+		mockOutput *textract.DetectDocumentTextOutput
+		mockError  error
+		expected   []string
+		wantErr    bool
+	}{
+		{
+			name: "Lines are extracted",
+			mockOutput: &textract.DetectDocumentTextOutput{
+				Blocks: []textractTypes.Block{
+					{BlockType: textractTypes.BlockTypePage},
+					{BlockType: textractTypes.BlockTypeLine, Text: aws.String("Hello world.")},
+					{BlockType: textractTypes.BlockTypeWord, Text: aws.String("Hello")},
+					{BlockType: textractTypes.BlockTypeLine, Text: aws.String("How are you?")},
+				},
+			},
+			expected: []string{"Hello world.", "How are you?"},
+			wantErr:  false,
+		},
+		{
+			name: "No text detected",
+			mockOutput: &textract.DetectDocumentTextOutput{
+				Blocks: []textractTypes.Block{
+					{BlockType: textractTypes.BlockTypePage},
+				},
+			},
+			expected: nil,
+			wantErr:  true,
+		},
+		{
+			name:      "Error from Textract",
+			mockError: fmt.Errorf("mock error"),
+			expected:  nil,
+			wantErr:   true,
+		},
+		{
+			name:     "Several pages",
+			document: "%PDF-1.4 1 0 obj << /Type /Pages /Count 2 >> 2 0 obj << /Type /Page >> 3 0 obj << /Type/Page >>",
+			expected: nil,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockTextractClient{
+				DetectDocumentTextFunc: func(ctx context.Context, params *textract.DetectDocumentTextInput, optFns ...func(*textract.Options)) (*textract.DetectDocumentTextOutput, error) {
+					return tt.mockOutput, tt.mockError
+				},
+			}
+
+			document := tt.document
+			if document == "" {
+				document = "%PDF-"
+			}
+			got, err := extractTextBlocks(context.Background(), mockClient, []byte(document))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("extractTextBlocks() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("extractTextBlocks() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandlePDF(t *testing.T) {
+	translations := map[string]string{
+		"Hello world.": "Hola mundo.",
+		"How are you?": "¿Cómo estás?",
+	}
+
+	tests := []struct {
+		name             string
+		body             string
+		mockError        error
+		noBlocks         bool
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name: "Plain text output",
+			body: `{"source_language":"en","target_language":"es","format":"pdf","document":"JVBERi0="}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola mundo.\n¿Cómo estás?"}`,
+			},
+		},
+		{
+			name: "Blocks output",
+			body: `{"source_language":"en","target_language":"es","format":"pdf","document":"JVBERi0=","output":"blocks"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola mundo.\n¿Cómo estás?","blocks":[{"source_text":"Hello world.","translated_text":"Hola mundo."},{"source_text":"How are you?","translated_text":"¿Cómo estás?"}]}`,
+			},
+		},
+		{
+			name: "Document is not base64",
+			body: `{"source_language":"en","target_language":"es","format":"pdf","document":"not base64!"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       "document must be base64 encoded",
+			},
+		},
+		{
+			name:      "Error from Textract",
+			body:      `{"source_language":"en","target_language":"es","format":"pdf","document":"JVBERi0="}`,
+			mockError: fmt.Errorf("mock error"),
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       "Error extracting document text",
+			},
+		},
+		{
+			name: "Several pages",
+			body: `{"source_language":"en","target_language":"es","format":"pdf","document":"JVBERi0xLjQgMSAwIG9iaiA8PCAvVHlwZSAvUGFnZXMgL0NvdW50IDIgPj4gMiAwIG9iaiA8PCAvVHlwZSAvUGFnZSA+PiAzIDAgb2JqIDw8IC9UeXBlIC9QYWdlID4+"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusUnprocessableEntity,
+				Body:       "Document has 2 pages, only single page documents are supported",
+			},
+		},
+		{
+			name:      "Document Textract doesn't support",
+			body:      `{"source_language":"en","target_language":"es","format":"pdf","document":"JVBERi0="}`,
+			mockError: &textractTypes.UnsupportedDocumentException{},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusUnprocessableEntity,
+				Body:       "Document format not supported, only single page documents are supported",
+			},
+		},
+		{
+			name:     "No text detected",
+			body:     `{"source_language":"en","target_language":"es","format":"pdf","document":"JVBERi0="}`,
+			noBlocks: true,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusUnprocessableEntity,
+				Body:       "No text detected in document",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(translations)
+			h.textractClient = &MockTextractClient{
+				DetectDocumentTextFunc: func(ctx context.Context, params *textract.DetectDocumentTextInput, optFns ...func(*textract.Options)) (*textract.DetectDocumentTextOutput, error) {
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					if tt.noBlocks {
+						return &textract.DetectDocumentTextOutput{}, nil
+					}
+					return &textract.DetectDocumentTextOutput{
+						Blocks: []textractTypes.Block{
+							{BlockType: textractTypes.BlockTypeLine, Text: aws.String("Hello world.")},
+							{BlockType: textractTypes.BlockTypeLine, Text: aws.String("How are you?")},
+						},
+					}, nil
+				},
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %v, expected %v", got, tt.expectedResponse)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// maxConcurrentLookups and maxConcurrentTranslations limit the lookup and translation
+	// stages of a batch of sentences
+	maxConcurrentLookups      = 10
+	maxConcurrentTranslations = 10
+	// maxConcurrentCacheWrites limits the write-behind stage of a request
+	maxConcurrentCacheWrites = 5
+)
+
+// cacheWriter is the write-behind stage of a request, storing new translations in the
+// background so a cache write never holds up the next translation. Lambda freezes the
+// environment once the handler returns, so the writes are drained after the response is
+// assembled rather than after it is sent. Cache hits are counted per item and written once
+// the request is done.
+type cacheWriter struct {
+	items chan CacheItem
+	wg    sync.WaitGroup
+
+	h        *handler
+	writeCtx context.Context
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+	hits     map[string]int
+}
+
+type cacheWriterKey struct{}
+
+// withCacheWriter starts the write-behind stage of a request, which must be closed before
+// the handler returns. The stages of the request are budgeted from here, the start of its
+// pipeline.
+func (h *handler) withCacheWriter(ctx context.Context) (context.Context, *cacheWriter) {
+	ctx = withStageBudgets(ctx)
+
+	// The writes outlive the request stages, cancelling the request must not drop them. They
+	// are only cut by their budget, before Lambda would stop them mid-write.
+	writeCtx, cancel := stageBudgetsFromContext(ctx).cacheWriteContext(context.WithoutCancel(ctx))
+
+	w := &cacheWriter{
+		items:    make(chan CacheItem, maxConcurrentTranslations),
+		h:        h,
+		writeCtx: writeCtx,
+		cancel:   cancel,
+		hits:     map[string]int{},
+	}
+	for range maxConcurrentCacheWrites {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			for item := range w.items {
+				if err := h.writeCacheItem(writeCtx, item); err != nil {
+					log.Printf("Error caching translation %s: %v", item.Hash, err)
+				}
+			}
+		}()
+	}
+
+	return context.WithValue(ctx, cacheWriterKey{}, w), w
+}
+
+// cacheWriterFromContext returns the write-behind stage of the request, nil when cache
+// items are stored synchronously
+func cacheWriterFromContext(ctx context.Context) *cacheWriter {
+	w, _ := ctx.Value(cacheWriterKey{}).(*cacheWriter)
+	return w
+}
+
+// Close waits for the queued cache items to be stored and writes the cache hits
+func (w *cacheWriter) Close() {
+	if w == nil {
+		return
+	}
+	defer w.cancel()
+	close(w.items)
+	w.wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var hits errgroup.Group
+	hits.SetLimit(maxConcurrentCacheWrites)
+	for hash, count := range w.hits {
+		hits.Go(func() error {
+			if err := w.h.incrementCacheHits(w.writeCtx, hash, count); err != nil {
+				log.Printf("Error counting hits of cache item %s: %v", hash, err)
+			}
+			return nil
+		})
+	}
+	hits.Wait()
+}
+
+// cacheTranslation hands the item to the write-behind stage of the request, or writes it
+// synchronously when the request has none, such as inbound emails which are retried
+func (h *handler) cacheTranslation(ctx context.Context, item CacheItem) error {
+	if w := cacheWriterFromContext(ctx); w != nil {
+		w.items <- item
+		return nil
+	}
+	return h.writeCacheItem(ctx, item)
+}
+
+// recordCacheHit counts a lookup served by the cache item, in the write-behind stage of the
+// request or synchronously when the request has none. The count is best effort, a failure
+// is only logged.
+func (h *handler) recordCacheHit(ctx context.Context, hash string) {
+	if !cacheHitTracking {
+		return
+	}
+	if w := cacheWriterFromContext(ctx); w != nil {
+		w.mu.Lock()
+		w.hits[hash]++
+		w.mu.Unlock()
+		return
+	}
+	if err := h.incrementCacheHits(ctx, hash, 1); err != nil {
+		log.Printf("Error counting hits of cache item %s: %v", hash, err)
+	}
+}
+
+// incrementCacheHits adds the hits to the counter of the cache item and records when it was
+// last hit. Items deleted since the lookup are not recreated.
+func (h *handler) incrementCacheHits(ctx context.Context, hash string, count int) error {
+	_, err := h.dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(translateTableName),
+		Key: map[string]types.AttributeValue{
+			"hash": &types.AttributeValueMemberS{Value: hash},
+		},
+		UpdateExpression:         aws.String("ADD hits :count SET last_hit_at = :now"),
+		ConditionExpression:      aws.String("attribute_exists(#hash)"),
+		ExpressionAttributeNames: map[string]string{"#hash": "hash"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":count": &types.AttributeValueMemberN{Value: strconv.Itoa(count)},
+			":now":   &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Unix(), 10)},
+		},
+	})
+	if errors.As(err, new(*types.ConditionalCheckFailedException)) {
+		return nil
+	}
+	return err
+}
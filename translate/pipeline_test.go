@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestCacheWriter(t *testing.T) {
+	tests := []struct {
+		name             string
+		putError         error
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name: "Translations are written behind",
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola. Adiós. "}`,
+			},
+		},
+		{
+			name:     "Failed writes don't fail the request",
+			putError: fmt.Errorf("mock error"),
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola. Adiós. "}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var writes atomic.Int32
+
+			h := newMockHandler(map[string]string{"Hello.": "Hola.", "Goodbye.": "Adiós."})
+			h.dynamoClient.(*MockDynamoDBClient).PutItemFunc = func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				if item, ok := cacheItemFromAttributes(params.Item); ok && item.SourceText != "" {
+					writes.Add(1)
+				}
+				return &dynamodb.PutItemOutput{}, tt.putError
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+				Body: `{"source_language":"en","target_language":"es","text":"Hello. Goodbye."}`,
+			})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %v, expected %v", got, tt.expectedResponse)
+			}
+			// Every write is done by the time the handler returns
+			if writes.Load() != 2 {
+				t.Errorf("handle() wrote %d cache items, expected 2", writes.Load())
+			}
+		})
+	}
+}
+
+func TestCacheTranslationWithoutWriter(t *testing.T) {
+	h := newMockHandler(nil)
+	h.dynamoClient.(*MockDynamoDBClient).PutItemFunc = func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+		return nil, fmt.Errorf("mock error")
+	}
+
+	if err := h.cacheTranslation(context.Background(), CacheItem{Hash: "test-hash"}); err == nil {
+		t.Errorf("cacheTranslation() expected the synchronous write error")
+	}
+
+	// Requests without a write-behind stage have nothing to close
+	cacheWriterFromContext(context.Background()).Close()
+}
+
+func TestRecordCacheHit(t *testing.T) {
+	cached := map[string]dynamoTypes.AttributeValue{
+		"hash":            &dynamoTypes.AttributeValueMemberS{Value: getCacheKey("en", "es", "Hello.")},
+		"source_language": &dynamoTypes.AttributeValueMemberS{Value: "en"},
+		"target_language": &dynamoTypes.AttributeValueMemberS{Value: "es"},
+		"source_text":     &dynamoTypes.AttributeValueMemberS{Value: "Hello."},
+		"translated_text": &dynamoTypes.AttributeValueMemberS{Value: "Hola."},
+	}
+
+	tests := []struct {
+		name           string
+		tracking       bool
+		updateError    error
+		expectedCounts map[string]string
+	}{
+		{
+			name:           "Hits of an item are counted once per request",
+			tracking:       true,
+			expectedCounts: map[string]string{getCacheKey("en", "es", "Hello."): "2"},
+		},
+		{
+			name:           "Deleted items are not recreated",
+			tracking:       true,
+			updateError:    &dynamoTypes.ConditionalCheckFailedException{},
+			expectedCounts: map[string]string{getCacheKey("en", "es", "Hello."): "2"},
+		},
+		{
+			name:           "Tracking disabled",
+			expectedCounts: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := cacheHitTracking
+			cacheHitTracking = tt.tracking
+			defer func() { cacheHitTracking = previous }()
+
+			var (
+				mu     sync.Mutex
+				counts = map[string]string{}
+			)
+
+			h := newMockHandler(nil)
+			h.dynamoClient.(*MockDynamoDBClient).GetItemFunc = func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+				if params.Key["hash"].(*dynamoTypes.AttributeValueMemberS).Value == getCacheKey("en", "es", "Hello.") {
+					return &dynamodb.GetItemOutput{Item: cached}, nil
+				}
+				return &dynamodb.GetItemOutput{}, nil
+			}
+			h.dynamoClient.(*MockDynamoDBClient).UpdateItemFunc = func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				hash := params.Key["hash"].(*dynamoTypes.AttributeValueMemberS).Value
+				counts[hash] = params.ExpressionAttributeValues[":count"].(*dynamoTypes.AttributeValueMemberN).Value
+				return &dynamodb.UpdateItemOutput{}, tt.updateError
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+				Body: `{"source_language":"en","target_language":"es","text":"Hello. Goodbye. Hello."}`,
+			})
+			if err != nil || got.StatusCode != http.StatusOK {
+				t.Errorf("handle() = %v, error = %v", got, err)
+				return
+			}
+
+			// The counts are written by the time the handler returns
+			if !maps.Equal(counts, tt.expectedCounts) {
+				t.Errorf("handle() counted hits %v, expected %v", counts, tt.expectedCounts)
+			}
+		})
+	}
+}
+
+// BenchmarkTranslateSentences measures the overhead of the pipeline per sentence, against
+// providers and a cache that answer immediately
+func BenchmarkTranslateSentences(b *testing.B) {
+	h := newMockHandler(nil)
+	sentences := splitSentences(benchmarkText)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := h.translateSentences(context.Background(), "en", "es", sentences); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(sentences)), "ns/sentence")
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+const (
+	defaultPivotLanguage = "en"
+
+	// pivotDisabled is the pivot language turning pivot translation off
+	pivotDisabled = "none"
+	// unsupportedLanguagePair is the failure reason of a pair the provider can't translate
+	unsupportedLanguagePair = "UnsupportedLanguagePairException"
+)
+
+// pivotLanguage is the intermediate language of the pairs the provider can't translate
+// directly, which are translated from the source to it and from it to the target
+var pivotLanguage string
+
+// pivotReport records whether a request was translated through the pivot language
+type pivotReport struct {
+	pivoted atomic.Bool
+}
+
+type pivotKey struct{}
+
+// withPivotReport returns a context recording whether the request was pivoted
+func withPivotReport(ctx context.Context) (context.Context, *pivotReport) {
+	p := &pivotReport{}
+	return context.WithValue(ctx, pivotKey{}, p), p
+}
+
+// pivotReportFromContext returns the pivot report of the request, nil when it has none
+func pivotReportFromContext(ctx context.Context) *pivotReport {
+	p, _ := ctx.Value(pivotKey{}).(*pivotReport)
+	return p
+}
+
+// record marks the request as pivoted
+func (p *pivotReport) record() {
+	if p != nil {
+		p.pivoted.Store(true)
+	}
+}
+
+// Pivoted reports whether any text of the request was translated through the pivot language
+func (p *pivotReport) Pivoted() bool {
+	return p != nil && p.pivoted.Load()
+}
+
+// canPivot reports whether a pair the provider failed to translate for the reason can be
+// translated through the pivot language instead
+func canPivot(sourceLanguage, targetLanguage, reason string) bool {
+	return reason == unsupportedLanguagePair && pivotLanguage != pivotDisabled &&
+		sourceLanguage != autoDetectLanguage && sourceLanguage != pivotLanguage && targetLanguage != pivotLanguage
+}
+
+// translatePivot translates a sentence the provider can't translate directly in two cached
+// steps through the pivot language, and caches the result for the pair marked as pivoted so
+// the next request skips both steps
+func (h *handler) translatePivot(ctx context.Context, sourceLanguage, targetLanguage, token, provider string) (string, error) {
+	intermediate, err := h.translateCached(ctx, sourceLanguage, pivotLanguage, token)
+	if err != nil {
+		return "", fmt.Errorf("error translating to pivot language %s: %w", pivotLanguage, err)
+	}
+	translated, err := h.translateCached(ctx, pivotLanguage, targetLanguage, intermediate)
+	if err != nil {
+		return "", fmt.Errorf("error translating from pivot language %s: %w", pivotLanguage, err)
+	}
+	pivotReportFromContext(ctx).record()
+
+	cacheItem := CacheItem{
+		Hash:           getCacheKey(sourceLanguage, cacheTargetLanguage(ctx, targetLanguage), token),
+		TranslatedText: translated,
+		SourceText:     token,
+		SourceLanguage: sourceLanguage,
+		TargetLanguage: targetLanguage,
+		Region:         region,
+		Provider:       provider,
+		Pivot:          pivotLanguage,
+	}
+	if !skipCacheWrite(ctx, cacheItem) {
+		// Both steps are cached, a missing pivoted item only costs two lookups later
+		if err := h.cacheTranslation(ctx, cacheItem); err != nil {
+			log.Printf("Error caching pivoted translation %s: %v", cacheItem.Hash, err)
+		}
+	}
+	return translated, nil
+}
+
+// translateCached translates a sentence through the cache, calling the provider on a miss
+func (h *handler) translateCached(ctx context.Context, sourceLanguage, targetLanguage, token string) (string, error) {
+	cacheItem, useCache, err := h.lookupCache(ctx, sourceLanguage, targetLanguage, token)
+	if err != nil {
+		return "", err
+	}
+	if !useCache {
+		return h.translateToken(ctx, sourceLanguage, targetLanguage, token)
+	}
+	if cacheItem.Failure != "" {
+		return "", &translationFailure{Reason: cacheItem.Failure}
+	}
+	return cacheItem.TranslatedText, nil
+}
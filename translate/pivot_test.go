@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+)
+
+func TestHandlePivotTranslation(t *testing.T) {
+	// The provider translates to and from English only
+	translations := map[string]string{
+		"de:en:Hallo": "Hello",
+		"en:es:Hello": "Hola",
+	}
+
+	tests := []struct {
+		name             string
+		pivot            string
+		cached           map[string]string
+		expectedResponse events.APIGatewayProxyResponse
+		expectedCalls    int
+		expectedPivots   []string
+	}{
+		{
+			name:  "Unsupported pair is pivoted through English",
+			pivot: defaultPivotLanguage,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola ","pivoted":true}`,
+			},
+			expectedCalls:  3,
+			expectedPivots: []string{"de:es"},
+		},
+		{
+			name:   "Cached step",
+			pivot:  defaultPivotLanguage,
+			cached: map[string]string{getCacheKey("de", "en", "Hallo"): "Hello"},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Hola ","pivoted":true}`,
+			},
+			expectedCalls:  2,
+			expectedPivots: []string{"de:es"},
+		},
+		{
+			name:  "Pivot disabled",
+			pivot: pivotDisabled,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusUnprocessableEntity,
+				Body:       "Text could not be translated: UnsupportedLanguagePairException",
+			},
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := pivotLanguage
+			pivotLanguage = tt.pivot
+			defer func() { pivotLanguage = previous }()
+
+			var (
+				mu     sync.Mutex
+				calls  int
+				pivots []string
+			)
+			stored := map[string]map[string]dynamoTypes.AttributeValue{}
+			for hash, translated := range tt.cached {
+				stored[hash] = map[string]dynamoTypes.AttributeValue{
+					"hash":            &dynamoTypes.AttributeValueMemberS{Value: hash},
+					"translated_text": &dynamoTypes.AttributeValueMemberS{Value: translated},
+					"source_text":     &dynamoTypes.AttributeValueMemberS{Value: "Hallo"},
+					"source_language": &dynamoTypes.AttributeValueMemberS{Value: "de"},
+					"target_language": &dynamoTypes.AttributeValueMemberS{Value: "en"},
+				}
+			}
+
+			h := newMockHandler(nil)
+			h.dynamoClient = &MockDynamoDBClient{
+				GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					return &dynamodb.GetItemOutput{Item: stored[params.Key["hash"].(*dynamoTypes.AttributeValueMemberS).Value]}, nil
+				},
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					stored[params.Item["hash"].(*dynamoTypes.AttributeValueMemberS).Value] = params.Item
+					if _, ok := params.Item["pivot"]; ok {
+						source := params.Item["source_language"].(*dynamoTypes.AttributeValueMemberS).Value
+						target := params.Item["target_language"].(*dynamoTypes.AttributeValueMemberS).Value
+						pivots = append(pivots, languagePair(source, target))
+					}
+					return &dynamodb.PutItemOutput{}, nil
+				},
+			}
+			h.translateClient.(*MockTranslateClient).TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				mu.Lock()
+				calls++
+				mu.Unlock()
+				key := aws.ToString(params.SourceLanguageCode) + ":" + aws.ToString(params.TargetLanguageCode) + ":" + aws.ToString(params.Text)
+				translated, ok := translations[key]
+				if !ok {
+					return nil, &types.UnsupportedLanguagePairException{}
+				}
+				return &translate.TranslateTextOutput{TranslatedText: aws.String(translated)}, nil
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+				HTTPMethod: http.MethodPost,
+				Body:       `{"source_language":"de","target_language":"es","text":"Hallo"}`,
+			})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+			if calls != tt.expectedCalls {
+				t.Errorf("TranslateText() called %d times, expected %d", calls, tt.expectedCalls)
+			}
+			if len(pivots) != len(tt.expectedPivots) || (len(pivots) > 0 && pivots[0] != tt.expectedPivots[0]) {
+				t.Errorf("pivoted items cached = %v, expected %v", pivots, tt.expectedPivots)
+			}
+
+			// The pivoted translation is served from the cache, still flagged
+			if tt.expectedResponse.StatusCode == http.StatusOK {
+				calls = 0
+				got, err = h.handle(context.Background(), events.APIGatewayProxyRequest{
+					HTTPMethod: http.MethodPost,
+					Body:       `{"source_language":"de","target_language":"es","text":"Hallo"}`,
+				})
+				if err != nil {
+					t.Fatalf("handle() error = %v", err)
+				}
+				if got.Body != tt.expectedResponse.Body || calls != 0 {
+					t.Errorf("cached handle() = %s with %d calls, expected %s without any", got.Body, calls, tt.expectedResponse.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestCanPivot(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		target   string
+		reason   string
+		expected bool
+	}{
+		{name: "Unsupported pair", source: "de", target: "sw", reason: unsupportedLanguagePair, expected: true},
+		{name: "Other failure", source: "de", target: "sw", reason: "TextSizeLimitExceededException", expected: false},
+		{name: "From the pivot language", source: "en", target: "sw", reason: unsupportedLanguagePair, expected: false},
+		{name: "To the pivot language", source: "de", target: "en", reason: unsupportedLanguagePair, expected: false},
+		{name: "Detected source language", source: autoDetectLanguage, target: "sw", reason: unsupportedLanguagePair, expected: false},
+	}
+
+	previous := pivotLanguage
+	pivotLanguage = defaultPivotLanguage
+	defer func() { pivotLanguage = previous }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canPivot(tt.source, tt.target, tt.reason); got != tt.expected {
+				t.Errorf("canPivot() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+const (
+	formatPlural = "plural"
+
+	// defaultPluralPlaceholder is the count placeholder of ICU and i18next style messages
+	defaultPluralPlaceholder = "{count}"
+)
+
+// pluralFormNames are the CLDR names of the plural forms
+var pluralFormNames = map[plural.Form]string{
+	plural.Zero:  "zero",
+	plural.One:   "one",
+	plural.Two:   "two",
+	plural.Few:   "few",
+	plural.Many:  "many",
+	plural.Other: "other",
+}
+
+// pluralSample is a number used to make the provider pick a plural form
+type pluralSample struct {
+	// Text is the number as written in the English source
+	Text string
+	// Integer and Fraction are the integer and fraction digits of the number
+	Integer, Fraction int
+	// FractionDigits is the number of fraction digits
+	FractionDigits int
+}
+
+// pluralSamples are tried in order to find a number for each plural form of a language,
+// the fractions cover forms such as the Russian other which only applies to decimals
+var pluralSamples = func() []pluralSample {
+	var samples []pluralSample
+	for i := 0; i <= 200; i++ {
+		samples = append(samples, pluralSample{Text: strconv.Itoa(i), Integer: i})
+	}
+	samples = append(samples,
+		pluralSample{Text: "1000000", Integer: 1000000},
+		pluralSample{Text: "1.5", Integer: 1, Fraction: 5, FractionDigits: 1},
+	)
+	return samples
+}()
+
+func (h *handler) handlePlurals(ctx context.Context, request TranslateRequest) (events.APIGatewayProxyResponse, error) {
+	plurals, err := h.translatePlurals(ctx, request.SourceLanguage, request.TargetLanguage, request.Plurals, request.PluralPlaceholder)
+	if response, ok := unavailableResponse(err); ok {
+		return response, nil
+	}
+	if response, ok := characterLimitResponse(err); ok {
+		return response, nil
+	}
+	if response, ok := languagePolicyResponse(err); ok {
+		return response, nil
+	}
+	if err != nil {
+		log.Printf("Error translating plurals: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error during translation",
+		}, nil
+	}
+
+	response := TranslateResponse{
+		Plurals:    plurals,
+		Degraded:   degradationFromContext(ctx).Degraded(),
+		LowQuality: degradationFromContext(ctx).LowQuality(),
+		Pivoted:    pivotReportFromContext(ctx).Pivoted(),
+		Direction:  textDirection(request.TargetLanguage),
+	}
+
+	return newJSONResponse(ctx, response), nil
+}
+
+// translatePlurals generates every CLDR plural form of the target language from the English
+// one and other forms. Each form is translated with a sample number of that form in place of
+// the placeholder, so the provider inflects the sentence for it.
+func (h *handler) translatePlurals(ctx context.Context, sourceLanguage, targetLanguage string, forms map[string]string, placeholder string) (map[string]string, error) {
+	if placeholder == "" {
+		placeholder = defaultPluralPlaceholder
+	}
+
+	tag, err := language.Parse(targetLanguage)
+	if err != nil {
+		return nil, fmt.Errorf("unknown target language %q: %w", targetLanguage, err)
+	}
+
+	samples := pluralFormSamples(tag)
+	targetForms := make([]plural.Form, 0, len(samples))
+	tokens := make([]string, 0, len(samples))
+	for form, sample := range samples {
+		// English only distinguishes one from other
+		source := forms["other"]
+		if sample.Text == "1" && forms["one"] != "" {
+			source = forms["one"]
+		}
+
+		targetForms = append(targetForms, form)
+		tokens = append(tokens, strings.ReplaceAll(source, placeholder, sample.Text))
+	}
+
+	translated, err := h.translateSentences(ctx, sourceLanguage, targetLanguage, tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	plurals := make(map[string]string, len(targetForms))
+	for i, form := range targetForms {
+		plurals[pluralFormNames[form]] = restorePluralPlaceholder(translated[i], samples[form], placeholder)
+	}
+
+	return plurals, nil
+}
+
+// pluralFormSamples returns the first sample number of each plural form of the language
+func pluralFormSamples(tag language.Tag) map[plural.Form]pluralSample {
+	samples := make(map[plural.Form]pluralSample)
+	for _, sample := range pluralSamples {
+		form := plural.Cardinal.MatchPlural(tag, sample.Integer, sample.FractionDigits, sample.FractionDigits, sample.Fraction, sample.Fraction)
+		if _, ok := samples[form]; !ok {
+			samples[form] = sample
+		}
+	}
+	return samples
+}
+
+// restorePluralPlaceholder puts the placeholder back in place of the sample number, which
+// the provider may have written with a decimal comma
+func restorePluralPlaceholder(text string, sample pluralSample, placeholder string) string {
+	for _, number := range []string{sample.Text, strings.ReplaceAll(sample.Text, ".", ",")} {
+		for offset := 0; ; {
+			index := strings.Index(text[offset:], number)
+			if index < 0 {
+				break
+			}
+			start, end := offset+index, offset+index+len(number)
+
+			// Only replace the whole number, not digits of a longer one
+			if (start == 0 || !isASCIIDigit(text[start-1])) && (end == len(text) || !isASCIIDigit(text[end])) {
+				return text[:start] + placeholder + text[end:]
+			}
+			offset = end
+		}
+	}
+	return text
+}
+
+func isASCIIDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
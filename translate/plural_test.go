@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"golang.org/x/text/language"
+)
+
+func TestPluralFormSamples(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		expected map[string]string
+	}{
+		{
+			name:     "English",
+			language: "en",
+			expected: map[string]string{"one": "1", "other": "0"},
+		},
+		{
+			name:     "Russian",
+			language: "ru",
+			expected: map[string]string{"one": "1", "few": "2", "many": "0", "other": "1.5"},
+		},
+		{
+			name:     "Japanese",
+			language: "ja",
+			expected: map[string]string{"other": "0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]string)
+			for form, sample := range pluralFormSamples(language.MustParse(tt.language)) {
+				got[pluralFormNames[form]] = sample.Text
+			}
+
+			if len(got) != len(tt.expected) {
+				t.Errorf("pluralFormSamples() = %v, expected %v", got, tt.expected)
+				return
+			}
+			for name, text := range tt.expected {
+				if got[name] != text {
+					t.Errorf("pluralFormSamples() = %v, expected %v", got, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestRestorePluralPlaceholder(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		sample   pluralSample
+		expected string
+	}{
+		{name: "Integer", text: "2 файла", sample: pluralSample{Text: "2"}, expected: "{count} файла"},
+		{name: "Decimal comma", text: "1,5 файла", sample: pluralSample{Text: "1.5"}, expected: "{count} файла"},
+		{name: "Digits of a longer number are kept", text: "Quedan 10 de 1", sample: pluralSample{Text: "1"}, expected: "Quedan 10 de {count}"},
+		{name: "Number written out", text: "un archivo", sample: pluralSample{Text: "1"}, expected: "un archivo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := restorePluralPlaceholder(tt.text, tt.sample, "{count}"); got != tt.expected {
+				t.Errorf("restorePluralPlaceholder() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandlePlurals(t *testing.T) {
+	translations := map[string]string{
+		"1 file":  "1 archivo",
+		"0 files": "0 archivos",
+	}
+
+	tests := []struct {
+		name             string
+		body             string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name: "Every target form is generated",
+			body: `{"source_language":"en","target_language":"es","format":"plural","plurals":{"one":"{count} file","other":"{count} files"}}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"","plurals":{"one":"{count} archivo","other":"{count} archivos"}}`,
+			},
+		},
+		{
+			name: "Other form is required",
+			body: `{"source_language":"en","target_language":"es","format":"plural","plurals":{"one":"{count} file"}}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"plurals","message":"plurals must contain the other form"}]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(translations)
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %v, expected %v", got, tt.expectedResponse)
+			}
+		})
+	}
+}
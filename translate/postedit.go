@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// postEditSessionsResource opens a post-editing session on the translation of a document
+	// of the registry, the other resources return a session, submit its edits and finalize it
+	postEditSessionsResource = "/documents/{id}/translations/{language}/sessions"
+	postEditSessionResource  = "/sessions/{session}"
+	postEditEditsResource    = "/sessions/{session}/edits"
+	postEditFinalizeResource = "/sessions/{session}/finalize"
+
+	// sessionStatusOpen and sessionStatusFinalized are the statuses of a post-editing session
+	sessionStatusOpen      = "open"
+	sessionStatusFinalized = "finalized"
+
+	// postEditSessionTTL is how long a session is kept before the table TTL removes it,
+	// finalized or not
+	postEditSessionTTL = 30 * 24 * time.Hour
+)
+
+// postEditTableName is the table of the post-editing sessions, keyed by session id. Sessions
+// are opened on the documents of the registry, and are disabled without either table.
+var postEditTableName string
+
+// PostEditSession is a post-editing session on the translation of a version of a document,
+// segmented in the sentences of the source text
+type PostEditSession struct {
+	SessionID      string `json:"session_id"`
+	DocumentID     string `json:"document_id"`
+	Version        int    `json:"version"`
+	SourceLanguage string `json:"source_language"`
+	Language       string `json:"language"`
+	// Status is open until the session is finalized, after which it takes no more edits
+	Status   string            `json:"status"`
+	Segments []PostEditSegment `json:"segments"`
+	// Cached is the number of approved segments written to the translation cache, set by the
+	// finalization
+	Cached int `json:"cached,omitempty"`
+}
+
+// PostEditSegment is a sentence of the document and its translation
+type PostEditSegment struct {
+	// Segment is the index of the sentence in the document
+	Segment    int    `json:"segment"`
+	SourceText string `json:"source_text"`
+	// MachineText is the translation of the sentence when the session was opened
+	MachineText string `json:"machine_text"`
+	// TranslatedText is the latest edit of the translation, the machine translation until
+	// the segment is edited
+	TranslatedText string `json:"translated_text"`
+	// Approved is set once a reviewer submitted the segment, edited or not
+	Approved bool `json:"approved"`
+}
+
+// PostEditRequest submits edits of the segments of a session
+type PostEditRequest struct {
+	Edits []SegmentEdit `json:"edits"`
+}
+
+// SegmentEdit is the translation of a segment approved by a reviewer
+type SegmentEdit struct {
+	Segment int `json:"segment"`
+	// TranslatedText is the edited translation, the machine translation approves it unchanged
+	TranslatedText string `json:"translated_text"`
+}
+
+// postEditItem is the item of a session in the post-editing table. The revision is
+// incremented by each write, so concurrent edits don't overwrite each other.
+type postEditItem struct {
+	PostEditSession
+	Revision  int
+	CreatedAt int64
+}
+
+// handlePostEditRequest serves the resources of the post-editing sessions
+func (h *handler) handlePostEditRequest(ctx context.Context, event events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	if documentTableName == "" || postEditTableName == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotImplemented,
+			Body:       "Post-editing not configured",
+		}
+	}
+
+	if event.Resource == postEditSessionsResource {
+		documentID := event.PathParameters["id"]
+		if documentID == "" || len(documentID) > maxDocumentIDLength {
+			errs := &validationError{}
+			errs.add("id", "id must be between 1 and %d characters", maxDocumentIDLength)
+			return validationErrorResponse(errs)
+		}
+		return h.openPostEditSession(ctx, documentID, event.PathParameters["language"], event.QueryStringParameters["version"])
+	}
+
+	session, found, err := h.getPostEditSession(ctx, event.PathParameters["session"])
+	if err != nil {
+		return registryErrorResponse("Error reading session", err)
+	}
+	if !found {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotFound,
+			Body:       "Session not found",
+		}
+	}
+	if response, ok := checkEntitlement(ctx, session.SourceLanguage, session.Language); !ok {
+		return response
+	}
+
+	switch event.Resource {
+	case postEditEditsResource:
+		return h.editPostEditSession(ctx, session, event.Body)
+	case postEditFinalizeResource:
+		return h.finalizePostEditSession(ctx, session)
+	default:
+		return newRegistryResponse(http.StatusOK, session.PostEditSession)
+	}
+}
+
+// openPostEditSession opens a session on the translation of a version of the document, the
+// latest by default. The sentences are translated like the translation of the registry, so
+// they are served from the cache when the document was translated already.
+func (h *handler) openPostEditSession(ctx context.Context, documentID, language, versionParameter string) events.APIGatewayProxyResponse {
+	errs := &validationError{}
+	validateLanguageCode(errs, "language", language, false)
+	versionNumber, ok := parseDocumentVersion(versionParameter)
+	if !ok {
+		errs.add("version", "version must be a positive number")
+	}
+	if len(errs.Fields) > 0 {
+		return validationErrorResponse(errs)
+	}
+
+	version, found, err := h.findDocumentVersion(ctx, documentID, versionNumber)
+	if err != nil {
+		return registryErrorResponse("Error reading document", err)
+	}
+	if !found {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotFound,
+			Body:       "Document version not found",
+		}
+	}
+
+	if response, ok := checkEntitlement(ctx, version.SourceLanguage, language); !ok {
+		return response
+	}
+	if language == version.SourceLanguage {
+		errs.add("language", "language must differ from the source language of the document")
+		return validationErrorResponse(errs)
+	}
+
+	supported, err := h.doesTargetLanguageExist(ctx, language)
+	if err != nil {
+		return registryErrorResponse("Error checking supported languages", err)
+	}
+	if !supported {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			Body:       "Target language not supported",
+		}
+	}
+
+	sentences := splitSentences(version.Text)
+	translations, err := h.translateSentences(ctx, version.SourceLanguage, language, sentences)
+	if err != nil {
+		return registryErrorResponse("Error during translation", err)
+	}
+
+	sessionID, err := newRandomID()
+	if err != nil {
+		return registryErrorResponse("Error opening session", err)
+	}
+	session := postEditItem{
+		PostEditSession: PostEditSession{
+			SessionID:      sessionID,
+			DocumentID:     documentID,
+			Version:        version.Version,
+			SourceLanguage: version.SourceLanguage,
+			Language:       language,
+			Status:         sessionStatusOpen,
+			Segments:       make([]PostEditSegment, len(sentences)),
+		},
+		CreatedAt: time.Now().Unix(),
+	}
+	for i, sentence := range sentences {
+		session.Segments[i] = PostEditSegment{
+			Segment:        i,
+			SourceText:     sentence,
+			MachineText:    translations[i],
+			TranslatedText: translations[i],
+		}
+	}
+	if err := h.putPostEditSession(ctx, session); err != nil {
+		return registryErrorResponse("Error opening session", err)
+	}
+
+	return newRegistryResponse(http.StatusCreated, session.PostEditSession)
+}
+
+// editPostEditSession applies the edits to the segments of an open session, approving them
+func (h *handler) editPostEditSession(ctx context.Context, session postEditItem, body string) events.APIGatewayProxyResponse {
+	var request PostEditRequest
+	if err := json.Unmarshal([]byte(body), &request); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Invalid request format",
+		}
+	}
+
+	errs := &validationError{}
+	if len(request.Edits) == 0 {
+		errs.add("edits", "edits is required")
+	}
+	for _, edit := range request.Edits {
+		if edit.Segment < 0 || edit.Segment >= len(session.Segments) {
+			errs.add("edits", "segment must be between 0 and %d", len(session.Segments)-1)
+		} else if edit.TranslatedText == "" {
+			errs.add("edits", "translated_text of segment %d is required", edit.Segment)
+		}
+	}
+	if len(errs.Fields) > 0 {
+		return validationErrorResponse(errs)
+	}
+
+	if session.Status != sessionStatusOpen {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusConflict,
+			Body:       "Session already finalized",
+		}
+	}
+
+	for _, edit := range request.Edits {
+		session.Segments[edit.Segment].TranslatedText = edit.TranslatedText
+		session.Segments[edit.Segment].Approved = true
+	}
+	if response, ok := h.updatePostEditSession(ctx, session); !ok {
+		return response
+	}
+
+	return newRegistryResponse(http.StatusOK, session.PostEditSession)
+}
+
+// finalizePostEditSession writes the approved segments into the translation cache as
+// authoritative translations, replacing the machine translations, and stores the edited
+// translation of the document in the registry. A session is finalized once, the cache
+// writes are repeated harmlessly when the finalization is retried after a failure.
+func (h *handler) finalizePostEditSession(ctx context.Context, session postEditItem) events.APIGatewayProxyResponse {
+	if session.Status != sessionStatusOpen {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusConflict,
+			Body:       "Session already finalized",
+		}
+	}
+
+	translations := make([]string, len(session.Segments))
+	for i, segment := range session.Segments {
+		translations[i] = segment.TranslatedText
+		if !segment.Approved {
+			continue
+		}
+
+		item := CacheItem{
+			Hash:           getCacheKey(session.SourceLanguage, session.Language, segment.SourceText),
+			TranslatedText: segment.TranslatedText,
+			SourceText:     segment.SourceText,
+			SourceLanguage: session.SourceLanguage,
+			TargetLanguage: session.Language,
+			Region:         region,
+			Provider:       providerPostEdit,
+			Authoritative:  true,
+		}
+		if skipCacheWrite(ctx, item) {
+			continue
+		}
+		if err := h.cacheTranslation(ctx, item); err != nil {
+			return registryErrorResponse("Error caching approved segment", err)
+		}
+		session.Cached++
+	}
+
+	version := registryVersion{DocumentID: session.DocumentID, Version: session.Version}
+	if err := h.storeDocumentTranslation(ctx, version, session.Language, joinSentences(translations)); err != nil {
+		return registryErrorResponse("Error storing translation", err)
+	}
+
+	session.Status = sessionStatusFinalized
+	if response, ok := h.updatePostEditSession(ctx, session); !ok {
+		return response
+	}
+
+	return newRegistryResponse(http.StatusOK, session.PostEditSession)
+}
+
+// newRandomID returns a random id, such as the id of a session
+func newRandomID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// getPostEditSession returns the session with the id
+func (h *handler) getPostEditSession(ctx context.Context, sessionID string) (postEditItem, bool, error) {
+	if sessionID == "" {
+		return postEditItem{}, false, nil
+	}
+	out, err := h.dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(postEditTableName),
+		Key:       map[string]types.AttributeValue{"session_id": &types.AttributeValueMemberS{Value: sessionID}},
+	})
+	if err != nil || out.Item == nil {
+		return postEditItem{}, false, err
+	}
+	session, ok := postEditItemFromAttributes(out.Item)
+	return session, ok, nil
+}
+
+// putPostEditSession writes a new session
+func (h *handler) putPostEditSession(ctx context.Context, session postEditItem) error {
+	_, err := h.dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(postEditTableName),
+		Item:                session.attributes(),
+		ConditionExpression: aws.String("attribute_not_exists(session_id)"),
+	})
+	return err
+}
+
+// updatePostEditSession writes the next revision of the session, unless it was written by
+// another request since it was read, returning the error response of a failed write
+func (h *handler) updatePostEditSession(ctx context.Context, session postEditItem) (events.APIGatewayProxyResponse, bool) {
+	previous := session.Revision
+	session.Revision++
+	_, err := h.dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 aws.String(postEditTableName),
+		Item:                      session.attributes(),
+		ConditionExpression:       aws.String("revision = :revision"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":revision": &types.AttributeValueMemberN{Value: strconv.Itoa(previous)}},
+	})
+	if errors.As(err, new(*types.ConditionalCheckFailedException)) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusConflict,
+			Body:       "Session was changed by another request",
+		}, false
+	}
+	if err != nil {
+		return registryErrorResponse("Error updating session", err), false
+	}
+	return events.APIGatewayProxyResponse{}, true
+}
+
+// attributes returns the item of the session
+func (s postEditItem) attributes() map[string]types.AttributeValue {
+	segments := make([]types.AttributeValue, len(s.Segments))
+	for i, segment := range s.Segments {
+		segments[i] = &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"source_text":     &types.AttributeValueMemberS{Value: segment.SourceText},
+			"machine_text":    &types.AttributeValueMemberS{Value: segment.MachineText},
+			"translated_text": &types.AttributeValueMemberS{Value: segment.TranslatedText},
+			"approved":        &types.AttributeValueMemberBOOL{Value: segment.Approved},
+		}}
+	}
+	return map[string]types.AttributeValue{
+		"session_id":      &types.AttributeValueMemberS{Value: s.SessionID},
+		"document_id":     &types.AttributeValueMemberS{Value: s.DocumentID},
+		"version":         &types.AttributeValueMemberN{Value: strconv.Itoa(s.Version)},
+		"source_language": &types.AttributeValueMemberS{Value: s.SourceLanguage},
+		"language":        &types.AttributeValueMemberS{Value: s.Language},
+		"status":          &types.AttributeValueMemberS{Value: s.Status},
+		"segments":        &types.AttributeValueMemberL{Value: segments},
+		"cached":          &types.AttributeValueMemberN{Value: strconv.Itoa(s.Cached)},
+		"revision":        &types.AttributeValueMemberN{Value: strconv.Itoa(s.Revision)},
+		"created_at":      &types.AttributeValueMemberN{Value: strconv.FormatInt(s.CreatedAt, 10)},
+		"expires_at":      &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Unix(s.CreatedAt, 0).Add(postEditSessionTTL).Unix(), 10)},
+	}
+}
+
+// postEditItemFromAttributes reads a session item, malformed items are treated as missing
+func postEditItemFromAttributes(item map[string]types.AttributeValue) (postEditItem, bool) {
+	values := make(map[string]string, 5)
+	for _, name := range []string{"session_id", "document_id", "source_language", "language", "status"} {
+		value, ok := item[name].(*types.AttributeValueMemberS)
+		if !ok {
+			return postEditItem{}, false
+		}
+		values[name] = value.Value
+	}
+	segments, ok := item["segments"].(*types.AttributeValueMemberL)
+	if !ok {
+		return postEditItem{}, false
+	}
+
+	session := postEditItem{PostEditSession: PostEditSession{
+		SessionID:      values["session_id"],
+		DocumentID:     values["document_id"],
+		SourceLanguage: values["source_language"],
+		Language:       values["language"],
+		Status:         values["status"],
+		Segments:       make([]PostEditSegment, len(segments.Value)),
+	}}
+	if value, ok := item["version"].(*types.AttributeValueMemberN); ok {
+		session.Version, _ = strconv.Atoi(value.Value)
+	}
+	if value, ok := item["cached"].(*types.AttributeValueMemberN); ok {
+		session.Cached, _ = strconv.Atoi(value.Value)
+	}
+	if value, ok := item["revision"].(*types.AttributeValueMemberN); ok {
+		session.Revision, _ = strconv.Atoi(value.Value)
+	}
+	if value, ok := item["created_at"].(*types.AttributeValueMemberN); ok {
+		session.CreatedAt, _ = strconv.ParseInt(value.Value, 10, 64)
+	}
+	for i, value := range segments.Value {
+		attributes, ok := value.(*types.AttributeValueMemberM)
+		if !ok {
+			return postEditItem{}, false
+		}
+		segment := PostEditSegment{Segment: i}
+		if value, ok := attributes.Value["source_text"].(*types.AttributeValueMemberS); ok {
+			segment.SourceText = value.Value
+		}
+		if value, ok := attributes.Value["machine_text"].(*types.AttributeValueMemberS); ok {
+			segment.MachineText = value.Value
+		}
+		if value, ok := attributes.Value["translated_text"].(*types.AttributeValueMemberS); ok {
+			segment.TranslatedText = value.Value
+		}
+		if value, ok := attributes.Value["approved"].(*types.AttributeValueMemberBOOL); ok {
+			segment.Approved = value.Value
+		}
+		session.Segments[i] = segment
+	}
+	return session, true
+}
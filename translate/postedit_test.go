@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestHandlePostEditSession(t *testing.T) {
+	previous := postEditTableName
+	postEditTableName = "sessions"
+	defer func() { postEditTableName = previous }()
+
+	h := newMockRegistry(t, map[int]registryVersion{
+		1: {DocumentID: "guide", Version: 1, SourceLanguage: "en", Text: "Hello. See you soon.", Translations: map[string]string{}},
+	})
+
+	// The sessions and the cache items are kept apart from the registry table of the mock, the
+	// cache items written concurrently by the write-behind stage
+	var (
+		sessions = map[string]map[string]types.AttributeValue{}
+		cached   []CacheItem
+		mu       sync.Mutex
+	)
+	registry := h.dynamoClient.(*MockDynamoDBClient)
+	getItem, putItem := registry.GetItemFunc, registry.PutItemFunc
+	registry.GetItemFunc = func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+		if *params.TableName != postEditTableName {
+			return getItem(ctx, params, optFns...)
+		}
+		return &dynamodb.GetItemOutput{Item: sessions[params.Key["session_id"].(*types.AttributeValueMemberS).Value]}, nil
+	}
+	registry.PutItemFunc = func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+		switch *params.TableName {
+		case documentTableName:
+			return putItem(ctx, params, optFns...)
+		case postEditTableName:
+			id := params.Item["session_id"].(*types.AttributeValueMemberS).Value
+			if revision, ok := params.ExpressionAttributeValues[":revision"]; ok {
+				stored := sessions[id]["revision"].(*types.AttributeValueMemberN).Value
+				if stored != revision.(*types.AttributeValueMemberN).Value {
+					return nil, &types.ConditionalCheckFailedException{}
+				}
+			}
+			sessions[id] = params.Item
+		default:
+			if item, ok := cacheItemFromAttributes(params.Item); ok && item.SourceText != "" {
+				mu.Lock()
+				cached = append(cached, item)
+				mu.Unlock()
+			}
+		}
+		return &dynamodb.PutItemOutput{}, nil
+	}
+
+	request := func(resource string, parameters map[string]string, body string) events.APIGatewayProxyResponse {
+		t.Helper()
+		got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+			Resource:       resource,
+			HTTPMethod:     http.MethodPost,
+			PathParameters: parameters,
+			Body:           body,
+		})
+		if err != nil {
+			t.Fatalf("handle() error = %v", err)
+		}
+		return got
+	}
+
+	got := request(postEditSessionsResource, map[string]string{"id": "guide", "language": "es"}, "")
+	if got.StatusCode != http.StatusCreated {
+		t.Fatalf("open session = %d %s, expected %d", got.StatusCode, got.Body, http.StatusCreated)
+	}
+	var session PostEditSession
+	if err := json.Unmarshal([]byte(got.Body), &session); err != nil {
+		t.Fatalf("open session = %s, expected a session", got.Body)
+	}
+	if len(session.Segments) != 2 || session.Segments[1].MachineText != "Hasta pronto." || session.Status != sessionStatusOpen {
+		t.Fatalf("open session = %s, expected the open segments of the document", got.Body)
+	}
+	parameters := map[string]string{"session": session.SessionID}
+
+	got = request(postEditEditsResource, parameters, `{"edits":[{"segment":2,"translated_text":"Adiós."}]}`)
+	if got.StatusCode != http.StatusBadRequest {
+		t.Errorf("edit unknown segment = %d %s, expected %d", got.StatusCode, got.Body, http.StatusBadRequest)
+	}
+
+	got = request(postEditEditsResource, parameters, `{"edits":[{"segment":1,"translated_text":"Nos vemos pronto."}]}`)
+	if got.StatusCode != http.StatusOK {
+		t.Fatalf("edit session = %d %s, expected %d", got.StatusCode, got.Body, http.StatusOK)
+	}
+
+	got = request(postEditFinalizeResource, parameters, "")
+	expected := `{"session_id":"` + session.SessionID + `","document_id":"guide","version":1,"source_language":"en","language":"es","status":"finalized","segments":[` +
+		`{"segment":0,"source_text":"Hello.","machine_text":"Hola.","translated_text":"Hola.","approved":false},` +
+		`{"segment":1,"source_text":"See you soon.","machine_text":"Hasta pronto.","translated_text":"Nos vemos pronto.","approved":true}],"cached":1}`
+	if got.StatusCode != http.StatusOK || got.Body != expected {
+		t.Errorf("finalize session = %d %s, expected %d %s", got.StatusCode, got.Body, http.StatusOK, expected)
+	}
+
+	// Only the approved segment replaces its machine translation in the cache
+	var approved []CacheItem
+	for _, item := range cached {
+		if item.Provider == providerPostEdit {
+			approved = append(approved, item)
+		}
+	}
+	if len(approved) != 1 || approved[0].Hash != getCacheKey("en", "es", "See you soon.") || approved[0].TranslatedText != "Nos vemos pronto." || !approved[0].Authoritative {
+		t.Errorf("PutItem() approved items = %+v, expected the authoritative edit of the second segment", approved)
+	}
+
+	got, _ = h.handle(context.Background(), events.APIGatewayProxyRequest{
+		Resource:       documentTranslationResource,
+		HTTPMethod:     http.MethodGet,
+		PathParameters: map[string]string{"id": "guide", "language": "es"},
+	})
+	if expected := `{"document_id":"guide","version":1,"language":"es","translated_text":"Hola. Nos vemos pronto. "}`; got.Body != expected {
+		t.Errorf("document translation = %s, expected %s", got.Body, expected)
+	}
+
+	got = request(postEditFinalizeResource, parameters, "")
+	if got.StatusCode != http.StatusConflict {
+		t.Errorf("finalize finalized session = %d %s, expected %d", got.StatusCode, got.Body, http.StatusConflict)
+	}
+
+	got = request(postEditSessionResource, map[string]string{"session": "unknown"}, "")
+	if got.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown session = %d %s, expected %d", got.StatusCode, got.Body, http.StatusNotFound)
+	}
+}
+
+func TestHandlePostEditNotConfigured(t *testing.T) {
+	previous := postEditTableName
+	postEditTableName = ""
+	defer func() { postEditTableName = previous }()
+
+	h := newMockRegistry(t, nil)
+	got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+		Resource:       postEditSessionResource,
+		HTTPMethod:     http.MethodGet,
+		PathParameters: map[string]string{"session": "abc"},
+	})
+	if err != nil {
+		t.Fatalf("handle() error = %v", err)
+	}
+	if got.StatusCode != http.StatusNotImplemented {
+		t.Errorf("handle() = %d %s, expected %d", got.StatusCode, got.Body, http.StatusNotImplemented)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// priorityInteractive is the priority of user-facing requests, such as chat messages, whose
+	// provider calls go before the queued bulk ones
+	priorityInteractive = "interactive"
+	// priorityBulk is the priority of batch and background translations, the default of the
+	// items of a batch request
+	priorityBulk = "bulk"
+
+	// defaultProviderConcurrency is the number of provider calls of an instance at once
+	defaultProviderConcurrency = 20
+)
+
+var (
+	// providerConcurrency is the number of provider calls of an instance at once, shared by the
+	// requests and batch items the instance translates concurrently
+	providerConcurrency int
+	// interactiveTPS and bulkTPS are the provider calls per second of each priority, 0 for no
+	// budget
+	interactiveTPS int
+	bulkTPS        int
+)
+
+// translationScheduler allocates the provider calls of an instance between the priorities.
+// A freed call slot goes to a waiting interactive call before any bulk one, so bulk work
+// can't starve interactive latency, and each priority may spend its own budget of calls per
+// second.
+type translationScheduler struct {
+	mu      sync.Mutex
+	free    int
+	waiting map[string][]chan struct{}
+	budgets map[string]*rateBudget
+}
+
+// newTranslationScheduler returns a scheduler of the number of provider calls at once, with
+// the calls per second of each priority, 0 for no budget
+func newTranslationScheduler(concurrency, interactiveTPS, bulkTPS int) *translationScheduler {
+	s := &translationScheduler{
+		free:    max(concurrency, 1),
+		waiting: map[string][]chan struct{}{},
+		budgets: map[string]*rateBudget{},
+	}
+	for priority, tps := range map[string]int{priorityInteractive: interactiveTPS, priorityBulk: bulkTPS} {
+		if tps > 0 {
+			s.budgets[priority] = newRateBudget(tps)
+		}
+	}
+	return s
+}
+
+// acquire waits for a call slot and the budget of the priority, returning the function
+// releasing the slot once the call is done. A nil scheduler doesn't wait.
+func (s *translationScheduler) acquire(ctx context.Context, priority string) (func(), error) {
+	if s == nil {
+		return func() {}, nil
+	}
+
+	s.mu.Lock()
+	if s.free > 0 && (priority == priorityInteractive || len(s.waiting[priorityInteractive]) == 0) {
+		s.free--
+		s.mu.Unlock()
+	} else {
+		granted := make(chan struct{})
+		s.waiting[priority] = append(s.waiting[priority], granted)
+		s.mu.Unlock()
+
+		select {
+		case <-granted:
+		case <-ctx.Done():
+			s.mu.Lock()
+			queue := s.waiting[priority]
+			if i := slices.Index(queue, granted); i >= 0 {
+				s.waiting[priority] = slices.Delete(queue, i, i+1)
+				s.mu.Unlock()
+				return nil, ctx.Err()
+			}
+			s.mu.Unlock()
+			// The slot was granted meanwhile, hand it on
+			s.release()
+			return nil, ctx.Err()
+		}
+	}
+
+	if budget := s.budgets[priority]; budget != nil {
+		if err := budget.wait(ctx); err != nil {
+			s.release()
+			return nil, err
+		}
+	}
+	return s.release, nil
+}
+
+// release hands the slot to the first waiting call, interactive ones first
+func (s *translationScheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, priority := range []string{priorityInteractive, priorityBulk} {
+		if queue := s.waiting[priority]; len(queue) > 0 {
+			close(queue[0])
+			s.waiting[priority] = queue[1:]
+			return
+		}
+	}
+	s.free++
+}
+
+// rateBudget is a token bucket of calls per second, bursting up to a second of calls
+type rateBudget struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateBudget(tps int) *rateBudget {
+	return &rateBudget{rate: float64(tps), tokens: float64(tps), last: time.Now()}
+}
+
+// wait takes a call from the budget, waiting for it to refill when spent
+func (b *rateBudget) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens = min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+type priorityKey struct{}
+
+// withPriority returns a context whose provider calls are scheduled with the priority
+func withPriority(ctx context.Context, priority string) context.Context {
+	if priority == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// priorityFromContext returns the priority of the request, interactive by default
+func priorityFromContext(ctx context.Context) string {
+	if priority, ok := ctx.Value(priorityKey{}).(string); ok {
+		return priority
+	}
+	return priorityInteractive
+}
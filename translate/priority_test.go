@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestTranslationSchedulerOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		queued   []string
+		expected []string
+	}{
+		{
+			name:     "Interactive before bulk",
+			queued:   []string{priorityBulk, priorityBulk, priorityInteractive},
+			expected: []string{priorityInteractive, priorityBulk, priorityBulk},
+		},
+		{
+			name:     "Same priority in arrival order",
+			queued:   []string{priorityInteractive, priorityBulk, priorityInteractive},
+			expected: []string{priorityInteractive, priorityInteractive, priorityBulk},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTranslationScheduler(1, 0, 0)
+			release, err := s.acquire(context.Background(), priorityInteractive)
+			if err != nil {
+				t.Fatalf("acquire() error = %v", err)
+			}
+
+			granted := make(chan string, len(tt.queued))
+			for i, priority := range tt.queued {
+				go func() {
+					release, err := s.acquire(context.Background(), priority)
+					if err != nil {
+						t.Errorf("acquire() error = %v", err)
+						return
+					}
+					granted <- priority
+					release()
+				}()
+				// Queue the calls in order
+				for queued := 0; queued <= i; {
+					time.Sleep(time.Millisecond)
+					s.mu.Lock()
+					queued = len(s.waiting[priorityInteractive]) + len(s.waiting[priorityBulk])
+					s.mu.Unlock()
+				}
+			}
+			release()
+
+			for i, expected := range tt.expected {
+				if got := <-granted; got != expected {
+					t.Errorf("call %d got %q, expected %q", i, got, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestTranslationSchedulerCancel(t *testing.T) {
+	s := newTranslationScheduler(1, 0, 0)
+	release, err := s.acquire(context.Background(), priorityInteractive)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.acquire(ctx, priorityBulk); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquire() error = %v, expected %v", err, context.DeadlineExceeded)
+	}
+
+	// The cancelled call gave up its place, the slot is free again once released
+	release()
+	if s.free != 1 || len(s.waiting[priorityBulk]) != 0 {
+		t.Errorf("scheduler has %d free slots and %d waiting calls, expected 1 and 0", s.free, len(s.waiting[priorityBulk]))
+	}
+}
+
+func TestRateBudget(t *testing.T) {
+	b := newRateBudget(50)
+	start := time.Now()
+	for range 51 {
+		if err := b.wait(context.Background()); err != nil {
+			t.Fatalf("wait() error = %v", err)
+		}
+	}
+	// A second of calls bursts, the next one waits for the budget to refill
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("wait() took %v, expected at least 15ms", elapsed)
+	}
+}
+
+func TestPriorityValidation(t *testing.T) {
+	h := newMockHandler(nil)
+	got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+		Body: `{"source_language":"en","target_language":"es","text":"Hello.","priority":"urgent"}`,
+	})
+	if err != nil {
+		t.Fatalf("handle() error = %v", err)
+	}
+	expected := `{"error":"Invalid request","fields":[{"field":"priority","message":"priority \"urgent\" is not supported"}]}`
+	if got.StatusCode != http.StatusBadRequest || got.Body != expected {
+		t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, http.StatusBadRequest, expected)
+	}
+}
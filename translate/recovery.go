@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// InternalErrorResponse is the body of a request that failed unexpectedly, identifying the
+// failure in the logs without exposing its details
+type InternalErrorResponse struct {
+	Error string `json:"error"`
+	// ErrorID identifies the logged failure, to be quoted when reporting it
+	ErrorID string `json:"error_id"`
+}
+
+// goroutinePanic is a panic of a goroutine of a request, raised again by the goroutine waiting
+// for it so a single recovery handles the request
+type goroutinePanic struct {
+	value any
+	stack []byte
+}
+
+func (p *goroutinePanic) Error() string {
+	return fmt.Sprintf("panic: %v", p.value)
+}
+
+// catchPanic converts a panic of the goroutine into its error, deferred by the goroutines of
+// an errgroup so their panic doesn't crash the runtime
+func catchPanic(err *error) {
+	if recovered := recover(); recovered != nil {
+		*err = &goroutinePanic{value: recovered, stack: debug.Stack()}
+	}
+}
+
+// raisePanic panics again with the panic of a goroutine carried by the error, if any
+func raisePanic(err error) {
+	var p *goroutinePanic
+	if errors.As(err, &p) {
+		panic(p)
+	}
+}
+
+// recoveryMiddleware converts a panic of the request into a 500 response rather than crashing
+// the runtime and losing the request
+func recoveryMiddleware(next requestHandler) requestHandler {
+	return requestHandlerFunc(func(ctx context.Context, event events.APIGatewayProxyRequest) (response events.APIGatewayProxyResponse, err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				response, err = panicResponse(logPanic(event.RequestContext.RequestID, recovered)), nil
+			}
+		}()
+		return next.serveRequest(ctx, event)
+	})
+}
+
+// logPanic logs the panic with its stack and returns the identifier of the log record
+func logPanic(requestID string, recovered any) string {
+	errorID := newErrorID()
+	stack := debug.Stack()
+	if p, ok := recovered.(*goroutinePanic); ok {
+		recovered, stack = p.value, p.stack
+	}
+	log.Printf("Panic serving request %s, error %s: %v\n%s", requestID, errorID, recovered, stack)
+	return errorID
+}
+
+// panicResponse returns the 500 response of a request that panicked
+func panicResponse(errorID string) events.APIGatewayProxyResponse {
+	body, err := json.Marshal(InternalErrorResponse{Error: "Internal error", ErrorID: errorID})
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError, Body: "Internal error"}
+	}
+	return events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError, Body: string(body)}
+}
+
+// newErrorID returns a random identifier of a failure
+func newErrorID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestHandlePanic(t *testing.T) {
+	tests := []struct {
+		name           string
+		resource       string
+		body           string
+		mock           func(*MockTranslateClient)
+		expectedStatus int
+		expectedBody   *regexp.Regexp
+	}{
+		{
+			name: "Panic of the request",
+			body: `{"source_language":"en","target_language":"es","text":"Hello."}`,
+			mock: func(mock *MockTranslateClient) {
+				mock.ListLanguagesFunc = func(ctx context.Context, params *translate.ListLanguagesInput, optFns ...func(*translate.Options)) (*translate.ListLanguagesOutput, error) {
+					panic("list languages")
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   regexp.MustCompile(`^\{"error":"Internal error","error_id":"[0-9a-f]{16}"\}$`),
+		},
+		{
+			name: "Panic of a segment translation",
+			body: `{"source_language":"en","target_language":"es","text":"Hello. Goodbye."}`,
+			mock: func(mock *MockTranslateClient) {
+				mock.TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+					var segments []string
+					return &translate.TranslateTextOutput{TranslatedText: aws.String(segments[1])}, nil
+				}
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   regexp.MustCompile(`^\{"error":"Internal error","error_id":"[0-9a-f]{16}"\}$`),
+		},
+		{
+			name:     "Panic of a batch item",
+			resource: batchResource,
+			body:     `{"items":[{"id":"a","request":{"source_language":"en","target_language":"es","text":"Hello."}}]}`,
+			mock: func(mock *MockTranslateClient) {
+				mock.TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+					panic("translate text")
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   regexp.MustCompile(`^\{"results":\[\{"id":"a","status_code":500,"error":"\{\\"error\\":\\"Internal error\\",\\"error_id\\":\\"[0-9a-f]{16}\\"\}"\}\],"succeeded":0,"failed":1\}$`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(nil)
+			tt.mock(h.translateClient.(*MockTranslateClient))
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Resource: tt.resource, Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedStatus || !tt.expectedBody.MatchString(got.Body) {
+				t.Errorf("handle() = %d %s, expected %d matching %s", got.StatusCode, got.Body, tt.expectedStatus, tt.expectedBody)
+			}
+		})
+	}
+}
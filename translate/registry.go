@@ -0,0 +1,494 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// documentVersionsResource, documentTranslationResource and documentDiffResource are the
+	// API Gateway resources of the document registry
+	documentVersionsResource    = "/documents/{id}/versions"
+	documentTranslationResource = "/documents/{id}/translations/{language}"
+	documentDiffResource        = "/documents/{id}/diff"
+
+	// maxDocumentIDLength caps the document ids chosen by the clients
+	maxDocumentIDLength = 256
+)
+
+// documentTableName is the table of the document registry, keyed by document id and version.
+// The registry is disabled without it.
+var documentTableName string
+
+// DocumentVersionRequest registers a new version of the source text of a document
+type DocumentVersionRequest struct {
+	// SourceLanguage is the language code of the text
+	SourceLanguage string `json:"source_language"`
+	// Text is the source text of the version
+	Text string `json:"text"`
+}
+
+// DocumentVersion is a version of a document of the registry
+type DocumentVersion struct {
+	DocumentID string `json:"document_id"`
+	// Version is the number of the version, starting at 1
+	Version int `json:"version"`
+	// Unchanged is set when the text is the text of the latest version, which is returned
+	// instead of registering a new one
+	Unchanged bool `json:"unchanged,omitempty"`
+}
+
+// DocumentTranslation is the translation of a version of a document
+type DocumentTranslation struct {
+	DocumentID     string `json:"document_id"`
+	Version        int    `json:"version"`
+	Language       string `json:"language"`
+	TranslatedText string `json:"translated_text"`
+	// Changes reports the sentences translated and reused from the translation of an earlier
+	// version, when the version was translated by this request
+	Changes *ChangeReport `json:"changes,omitempty"`
+}
+
+// DocumentDiff lists the sentences changed between two versions of a document
+type DocumentDiff struct {
+	DocumentID string `json:"document_id"`
+	From       int    `json:"from"`
+	To         int    `json:"to"`
+	// Added are the sentences of the later version not in the earlier one
+	Added []string `json:"added"`
+	// Removed are the sentences of the earlier version not in the later one
+	Removed []string `json:"removed"`
+	// Unchanged is the number of sentences of the later version in both versions
+	Unchanged int `json:"unchanged"`
+}
+
+// registryVersion is the item of a document version in the registry table, holding its
+// translations by language
+type registryVersion struct {
+	DocumentID     string
+	Version        int
+	SourceLanguage string
+	Text           string
+	Translations   map[string]string
+	CreatedAt      int64
+}
+
+// handleDocumentRegistryRequest serves the resources of the document registry
+func (h *handler) handleDocumentRegistryRequest(ctx context.Context, event events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	if documentTableName == "" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotImplemented,
+			Body:       "Document registry not configured",
+		}
+	}
+
+	documentID := event.PathParameters["id"]
+	if documentID == "" || len(documentID) > maxDocumentIDLength {
+		errs := &validationError{}
+		errs.add("id", "id must be between 1 and %d characters", maxDocumentIDLength)
+		return validationErrorResponse(errs)
+	}
+
+	switch event.Resource {
+	case documentVersionsResource:
+		return h.registerDocumentVersion(ctx, documentID, event.Body)
+	case documentTranslationResource:
+		return h.getDocumentTranslation(ctx, documentID, event.PathParameters["language"], event.QueryStringParameters["version"])
+	default:
+		return h.diffDocumentVersions(ctx, documentID, event.QueryStringParameters["from"], event.QueryStringParameters["to"])
+	}
+}
+
+// registerDocumentVersion stores the text as the next version of the document, unless it is
+// the text of the latest version. Two versions registered at once are told apart by the
+// condition of the write, the loser is asked to retry.
+func (h *handler) registerDocumentVersion(ctx context.Context, documentID, body string) events.APIGatewayProxyResponse {
+	var request DocumentVersionRequest
+	if err := json.Unmarshal([]byte(body), &request); err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "Invalid request format",
+		}
+	}
+
+	errs := &validationError{}
+	validateLanguageCode(errs, "source_language", request.SourceLanguage, false)
+	if request.Text == "" {
+		errs.add("text", "text is required")
+	} else if len(splitSentences(request.Text)) > maxRequestSentences {
+		errs.add("text", "text must not exceed %d sentences", maxRequestSentences)
+	}
+	if len(errs.Fields) > 0 {
+		return validationErrorResponse(errs)
+	}
+
+	latest, found, err := h.latestDocumentVersion(ctx, documentID)
+	if err != nil {
+		return registryErrorResponse("Error reading document", err)
+	}
+	if found && latest.Text == request.Text && latest.SourceLanguage == request.SourceLanguage {
+		return newRegistryResponse(http.StatusOK, DocumentVersion{DocumentID: documentID, Version: latest.Version, Unchanged: true})
+	}
+
+	version := registryVersion{
+		DocumentID:     documentID,
+		Version:        latest.Version + 1,
+		SourceLanguage: request.SourceLanguage,
+		Text:           request.Text,
+		Translations:   map[string]string{},
+		CreatedAt:      time.Now().Unix(),
+	}
+	_, err = h.dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(documentTableName),
+		Item:                version.attributes(),
+		ConditionExpression: aws.String("attribute_not_exists(version)"),
+	})
+	if errors.As(err, new(*types.ConditionalCheckFailedException)) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusConflict,
+			Body:       "Another version was registered at the same time",
+		}
+	}
+	if err != nil {
+		return registryErrorResponse("Error registering version", err)
+	}
+
+	return newRegistryResponse(http.StatusCreated, DocumentVersion{DocumentID: documentID, Version: version.Version})
+}
+
+// getDocumentTranslation returns the translation of a version of the document, the latest
+// by default. A version not translated to the language yet is translated and stored,
+// reusing the translation of the latest earlier version translated to it.
+func (h *handler) getDocumentTranslation(ctx context.Context, documentID, language, versionParameter string) events.APIGatewayProxyResponse {
+	errs := &validationError{}
+	validateLanguageCode(errs, "language", language, false)
+	versionNumber, ok := parseDocumentVersion(versionParameter)
+	if !ok {
+		errs.add("version", "version must be a positive number")
+	}
+	if len(errs.Fields) > 0 {
+		return validationErrorResponse(errs)
+	}
+
+	version, found, err := h.findDocumentVersion(ctx, documentID, versionNumber)
+	if err != nil {
+		return registryErrorResponse("Error reading document", err)
+	}
+	if !found {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotFound,
+			Body:       "Document version not found",
+		}
+	}
+
+	if response, ok := checkEntitlement(ctx, version.SourceLanguage, language); !ok {
+		return response
+	}
+
+	if translated, ok := version.Translations[language]; ok {
+		return newRegistryResponse(http.StatusOK, DocumentTranslation{
+			DocumentID:     documentID,
+			Version:        version.Version,
+			Language:       language,
+			TranslatedText: translated,
+		})
+	}
+	if language == version.SourceLanguage {
+		return newRegistryResponse(http.StatusOK, DocumentTranslation{
+			DocumentID:     documentID,
+			Version:        version.Version,
+			Language:       language,
+			TranslatedText: version.Text,
+		})
+	}
+
+	supported, err := h.doesTargetLanguageExist(ctx, language)
+	if err != nil {
+		return registryErrorResponse("Error checking supported languages", err)
+	}
+	if !supported {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			Body:       "Target language not supported",
+		}
+	}
+
+	previous, _, err := h.previousDocumentTranslation(ctx, documentID, version.Version, language)
+	if err != nil {
+		return registryErrorResponse("Error reading document", err)
+	}
+
+	ctx, degradation := withDegradation(ctx)
+	request := TranslateRequest{
+		SourceLanguage: version.SourceLanguage,
+		TargetLanguage: language,
+		Text:           version.Text,
+	}
+	var (
+		translatedText string
+		changes        *ChangeReport
+	)
+	if previous.Version != 0 {
+		request.PreviousText, request.PreviousTranslation = previous.Text, previous.Translations[language]
+		translatedText, _, changes, err = h.translateDelta(ctx, request)
+	} else {
+		translatedText, err = h.translateText(ctx, request.SourceLanguage, language, request.Text)
+	}
+	if err != nil {
+		return registryErrorResponse("Error during translation", err)
+	}
+
+	// A translation missing sentences the provider couldn't translate isn't kept
+	if !degradation.Degraded() && !degradation.LowQuality() {
+		if err := h.storeDocumentTranslation(ctx, version, language, translatedText); err != nil {
+			log.Printf("Error storing translation of document %s version %d: %v", documentID, version.Version, err)
+		}
+	}
+
+	return newRegistryResponse(http.StatusOK, DocumentTranslation{
+		DocumentID:     documentID,
+		Version:        version.Version,
+		Language:       language,
+		TranslatedText: translatedText,
+		Changes:        changes,
+	})
+}
+
+// diffDocumentVersions compares the sentences of two versions of the document, by default
+// the latest version and the one before it
+func (h *handler) diffDocumentVersions(ctx context.Context, documentID, fromParameter, toParameter string) events.APIGatewayProxyResponse {
+	errs := &validationError{}
+	from, ok := parseDocumentVersion(fromParameter)
+	if !ok {
+		errs.add("from", "from must be a positive number")
+	}
+	to, ok := parseDocumentVersion(toParameter)
+	if !ok {
+		errs.add("to", "to must be a positive number")
+	}
+	if len(errs.Fields) > 0 {
+		return validationErrorResponse(errs)
+	}
+
+	toVersion, found, err := h.findDocumentVersion(ctx, documentID, to)
+	if err != nil {
+		return registryErrorResponse("Error reading document", err)
+	}
+	if from == 0 {
+		from = max(toVersion.Version-1, 1)
+	}
+	fromVersion, fromFound, err := h.getDocumentVersion(ctx, documentID, from)
+	if err != nil {
+		return registryErrorResponse("Error reading document", err)
+	}
+	if !found || !fromFound {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotFound,
+			Body:       "Document version not found",
+		}
+	}
+
+	diff := DocumentDiff{DocumentID: documentID, From: fromVersion.Version, To: toVersion.Version, Added: []string{}, Removed: []string{}}
+	fromSentences, toSentences := splitSentences(fromVersion.Text), splitSentences(toVersion.Text)
+	inFrom, inTo := map[string]bool{}, map[string]bool{}
+	for _, sentence := range fromSentences {
+		inFrom[sentence] = true
+	}
+	for _, sentence := range toSentences {
+		inTo[sentence] = true
+		if inFrom[sentence] {
+			diff.Unchanged++
+		} else {
+			diff.Added = append(diff.Added, sentence)
+		}
+	}
+	for _, sentence := range fromSentences {
+		if !inTo[sentence] {
+			diff.Removed = append(diff.Removed, sentence)
+		}
+	}
+
+	return newRegistryResponse(http.StatusOK, diff)
+}
+
+// parseDocumentVersion parses an optional version parameter, 0 when it is missing
+func parseDocumentVersion(value string) (int, bool) {
+	if value == "" {
+		return 0, true
+	}
+	version, err := strconv.Atoi(value)
+	return version, err == nil && version > 0
+}
+
+// latestDocumentVersion returns the latest version of the document
+func (h *handler) latestDocumentVersion(ctx context.Context, documentID string) (registryVersion, bool, error) {
+	out, err := h.dynamoClient.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String(documentTableName),
+		KeyConditionExpression:    aws.String("document_id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":id": &types.AttributeValueMemberS{Value: documentID}},
+		ScanIndexForward:          aws.Bool(false),
+		Limit:                     aws.Int32(1),
+	})
+	if err != nil {
+		return registryVersion{}, false, err
+	}
+	if len(out.Items) == 0 {
+		return registryVersion{}, false, nil
+	}
+	version, ok := registryVersionFromAttributes(out.Items[0])
+	return version, ok, nil
+}
+
+// findDocumentVersion returns a version of the document, the latest when the version is 0
+func (h *handler) findDocumentVersion(ctx context.Context, documentID string, version int) (registryVersion, bool, error) {
+	if version == 0 {
+		return h.latestDocumentVersion(ctx, documentID)
+	}
+	return h.getDocumentVersion(ctx, documentID, version)
+}
+
+// getDocumentVersion returns a version of the document
+func (h *handler) getDocumentVersion(ctx context.Context, documentID string, version int) (registryVersion, bool, error) {
+	out, err := h.dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(documentTableName),
+		Key: map[string]types.AttributeValue{
+			"document_id": &types.AttributeValueMemberS{Value: documentID},
+			"version":     &types.AttributeValueMemberN{Value: strconv.Itoa(version)},
+		},
+	})
+	if err != nil || out.Item == nil {
+		return registryVersion{}, false, err
+	}
+	item, ok := registryVersionFromAttributes(out.Item)
+	return item, ok, nil
+}
+
+// previousDocumentTranslation returns the latest version before the given one translated to
+// the language
+func (h *handler) previousDocumentTranslation(ctx context.Context, documentID string, before int, language string) (registryVersion, bool, error) {
+	paginator := dynamodb.NewQueryPaginator(h.dynamoClient, &dynamodb.QueryInput{
+		TableName:              aws.String(documentTableName),
+		KeyConditionExpression: aws.String("document_id = :id AND version < :version"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id":      &types.AttributeValueMemberS{Value: documentID},
+			":version": &types.AttributeValueMemberN{Value: strconv.Itoa(before)},
+		},
+		ScanIndexForward: aws.Bool(false),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return registryVersion{}, false, err
+		}
+		for _, item := range page.Items {
+			version, ok := registryVersionFromAttributes(item)
+			if _, translated := version.Translations[language]; ok && translated {
+				return version, true, nil
+			}
+		}
+	}
+	return registryVersion{}, false, nil
+}
+
+// storeDocumentTranslation adds the translation to the version
+func (h *handler) storeDocumentTranslation(ctx context.Context, version registryVersion, language, translatedText string) error {
+	_, err := h.dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(documentTableName),
+		Key: map[string]types.AttributeValue{
+			"document_id": &types.AttributeValueMemberS{Value: version.DocumentID},
+			"version":     &types.AttributeValueMemberN{Value: strconv.Itoa(version.Version)},
+		},
+		UpdateExpression:          aws.String("SET translations.#language = :text"),
+		ExpressionAttributeNames:  map[string]string{"#language": language},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":text": &types.AttributeValueMemberS{Value: translatedText}},
+	})
+	return err
+}
+
+// attributes returns the item of the version
+func (v registryVersion) attributes() map[string]types.AttributeValue {
+	translations := make(map[string]types.AttributeValue, len(v.Translations))
+	for language, text := range v.Translations {
+		translations[language] = &types.AttributeValueMemberS{Value: text}
+	}
+	return map[string]types.AttributeValue{
+		"document_id":     &types.AttributeValueMemberS{Value: v.DocumentID},
+		"version":         &types.AttributeValueMemberN{Value: strconv.Itoa(v.Version)},
+		"source_language": &types.AttributeValueMemberS{Value: v.SourceLanguage},
+		"text":            &types.AttributeValueMemberS{Value: v.Text},
+		"translations":    &types.AttributeValueMemberM{Value: translations},
+		"created_at":      &types.AttributeValueMemberN{Value: strconv.FormatInt(v.CreatedAt, 10)},
+	}
+}
+
+// registryVersionFromAttributes reads a version item, malformed items are skipped
+func registryVersionFromAttributes(item map[string]types.AttributeValue) (registryVersion, bool) {
+	documentID, ok := item["document_id"].(*types.AttributeValueMemberS)
+	if !ok {
+		return registryVersion{}, false
+	}
+	number, ok := item["version"].(*types.AttributeValueMemberN)
+	if !ok {
+		return registryVersion{}, false
+	}
+	versionNumber, err := strconv.Atoi(number.Value)
+	if err != nil {
+		return registryVersion{}, false
+	}
+
+	version := registryVersion{DocumentID: documentID.Value, Version: versionNumber, Translations: map[string]string{}}
+	if value, ok := item["source_language"].(*types.AttributeValueMemberS); ok {
+		version.SourceLanguage = value.Value
+	}
+	if value, ok := item["text"].(*types.AttributeValueMemberS); ok {
+		version.Text = value.Value
+	}
+	if value, ok := item["created_at"].(*types.AttributeValueMemberN); ok {
+		version.CreatedAt, _ = strconv.ParseInt(value.Value, 10, 64)
+	}
+	if value, ok := item["translations"].(*types.AttributeValueMemberM); ok {
+		for language, text := range value.Value {
+			if text, ok := text.(*types.AttributeValueMemberS); ok {
+				version.Translations[language] = text.Value
+			}
+		}
+	}
+	return version, true
+}
+
+// newRegistryResponse marshals the body of a registry response
+func newRegistryResponse(statusCode int, body any) events.APIGatewayProxyResponse {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error marshalling response",
+		}
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Body:       string(data),
+	}
+}
+
+// registryErrorResponse logs the error of a registry request and returns a 500 response
+// with the message, or the 503 response of an unavailable dependency
+func registryErrorResponse(message string, err error) events.APIGatewayProxyResponse {
+	if response, ok := unavailableResponse(err); ok {
+		return response
+	}
+	log.Printf("%s: %v", message, err)
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusInternalServerError,
+		Body:       message,
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// newMockRegistry returns a handler whose document table holds the versions of a single
+// document, by version number. The translation cache is always empty.
+func newMockRegistry(t *testing.T, versions map[int]registryVersion) *handler {
+	t.Helper()
+
+	previous := documentTableName
+	documentTableName = "documents"
+	t.Cleanup(func() { documentTableName = previous })
+
+	items := map[int]map[string]types.AttributeValue{}
+	for number, version := range versions {
+		items[number] = version.attributes()
+	}
+
+	h := newMockHandler(map[string]string{
+		"Hello.":          "Hola.",
+		"See you soon.":   "Hasta pronto.",
+		"See you later.":  "Hasta luego.",
+		"Welcome aboard.": "Bienvenido a bordo.",
+	})
+	h.dynamoClient = &MockDynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			if *params.TableName != documentTableName {
+				return &dynamodb.GetItemOutput{}, nil
+			}
+			number, _ := strconv.Atoi(params.Key["version"].(*types.AttributeValueMemberN).Value)
+			return &dynamodb.GetItemOutput{Item: items[number]}, nil
+		},
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			if *params.TableName != documentTableName {
+				return &dynamodb.PutItemOutput{}, nil
+			}
+			number, _ := strconv.Atoi(params.Item["version"].(*types.AttributeValueMemberN).Value)
+			if _, ok := items[number]; ok {
+				return nil, &types.ConditionalCheckFailedException{}
+			}
+			items[number] = params.Item
+			return &dynamodb.PutItemOutput{}, nil
+		},
+		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			if *params.TableName != documentTableName {
+				return &dynamodb.UpdateItemOutput{}, nil
+			}
+			number, _ := strconv.Atoi(params.Key["version"].(*types.AttributeValueMemberN).Value)
+			translations := items[number]["translations"].(*types.AttributeValueMemberM)
+			translations.Value[params.ExpressionAttributeNames["#language"]] = params.ExpressionAttributeValues[":text"]
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+		QueryFunc: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			before := len(items) + 1
+			if value, ok := params.ExpressionAttributeValues[":version"]; ok {
+				before, _ = strconv.Atoi(value.(*types.AttributeValueMemberN).Value)
+			}
+			var numbers []int
+			for number := range items {
+				if number < before {
+					numbers = append(numbers, number)
+				}
+			}
+			slices.Sort(numbers)
+			slices.Reverse(numbers)
+			if params.Limit != nil && len(numbers) > int(*params.Limit) {
+				numbers = numbers[:*params.Limit]
+			}
+
+			out := &dynamodb.QueryOutput{}
+			for _, number := range numbers {
+				out.Items = append(out.Items, items[number])
+			}
+			return out, nil
+		},
+	}
+	return h
+}
+
+func TestHandleDocumentRegistry(t *testing.T) {
+	versions := map[int]registryVersion{
+		1: {DocumentID: "guide", Version: 1, SourceLanguage: "en", Text: "Hello. See you soon.", Translations: map[string]string{"es": "Hola, amigo. Hasta pronto."}},
+		2: {DocumentID: "guide", Version: 2, SourceLanguage: "en", Text: "Hello. Welcome aboard. See you later.", Translations: map[string]string{}},
+	}
+
+	tests := []struct {
+		name             string
+		event            events.APIGatewayProxyRequest
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name: "Register version",
+			event: events.APIGatewayProxyRequest{
+				Resource:       documentVersionsResource,
+				HTTPMethod:     http.MethodPost,
+				PathParameters: map[string]string{"id": "guide"},
+				Body:           `{"source_language":"en","text":"Hello. Welcome aboard."}`,
+			},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusCreated,
+				Body:       `{"document_id":"guide","version":3}`,
+			},
+		},
+		{
+			name: "Register unchanged version",
+			event: events.APIGatewayProxyRequest{
+				Resource:       documentVersionsResource,
+				HTTPMethod:     http.MethodPost,
+				PathParameters: map[string]string{"id": "guide"},
+				Body:           `{"source_language":"en","text":"Hello. Welcome aboard. See you later."}`,
+			},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"document_id":"guide","version":2,"unchanged":true}`,
+			},
+		},
+		{
+			name: "Register version without text",
+			event: events.APIGatewayProxyRequest{
+				Resource:       documentVersionsResource,
+				HTTPMethod:     http.MethodPost,
+				PathParameters: map[string]string{"id": "guide"},
+				Body:           `{"source_language":"en"}`,
+			},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"text","message":"text is required"}]}`,
+			},
+		},
+		{
+			name: "Stored translation",
+			event: events.APIGatewayProxyRequest{
+				Resource:              documentTranslationResource,
+				HTTPMethod:            http.MethodGet,
+				PathParameters:        map[string]string{"id": "guide", "language": "es"},
+				QueryStringParameters: map[string]string{"version": "1"},
+			},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"document_id":"guide","version":1,"language":"es","translated_text":"Hola, amigo. Hasta pronto."}`,
+			},
+		},
+		{
+			name: "Latest translation reusing the previous version",
+			event: events.APIGatewayProxyRequest{
+				Resource:       documentTranslationResource,
+				HTTPMethod:     http.MethodGet,
+				PathParameters: map[string]string{"id": "guide", "language": "es"},
+			},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"document_id":"guide","version":2,"language":"es","translated_text":"Hola, amigo. Bienvenido a bordo. Hasta luego. ","changes":{"reused":1,"translated":["Welcome aboard.","See you later."],"removed":["See you soon."]}}`,
+			},
+		},
+		{
+			name: "Unknown version",
+			event: events.APIGatewayProxyRequest{
+				Resource:              documentTranslationResource,
+				HTTPMethod:            http.MethodGet,
+				PathParameters:        map[string]string{"id": "guide", "language": "es"},
+				QueryStringParameters: map[string]string{"version": "7"},
+			},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Body:       "Document version not found",
+			},
+		},
+		{
+			name: "Unsupported language",
+			event: events.APIGatewayProxyRequest{
+				Resource:       documentTranslationResource,
+				HTTPMethod:     http.MethodGet,
+				PathParameters: map[string]string{"id": "guide", "language": "fr"},
+			},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusUnprocessableEntity,
+				Body:       "Target language not supported",
+			},
+		},
+		{
+			name: "Diff of the latest version",
+			event: events.APIGatewayProxyRequest{
+				Resource:       documentDiffResource,
+				HTTPMethod:     http.MethodGet,
+				PathParameters: map[string]string{"id": "guide"},
+			},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"document_id":"guide","from":1,"to":2,"added":["Welcome aboard.","See you later."],"removed":["See you soon."],"unchanged":1}`,
+			},
+		},
+		{
+			name: "Diff with an invalid version",
+			event: events.APIGatewayProxyRequest{
+				Resource:              documentDiffResource,
+				HTTPMethod:            http.MethodGet,
+				PathParameters:        map[string]string{"id": "guide"},
+				QueryStringParameters: map[string]string{"from": "first"},
+			},
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"from","message":"from must be a positive number"}]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockRegistry(t, versions)
+
+			got, err := h.handleRequest(context.Background(), tt.event)
+			if err != nil {
+				t.Fatalf("handleRequest() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handleRequest() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedResponse.StatusCode, tt.expectedResponse.Body)
+			}
+		})
+	}
+}
+
+func TestDocumentTranslationStored(t *testing.T) {
+	h := newMockRegistry(t, map[int]registryVersion{
+		1: {DocumentID: "guide", Version: 1, SourceLanguage: "en", Text: "Hello.", Translations: map[string]string{}},
+	})
+	event := events.APIGatewayProxyRequest{
+		Resource:       documentTranslationResource,
+		HTTPMethod:     http.MethodGet,
+		PathParameters: map[string]string{"id": "guide", "language": "es"},
+	}
+
+	for _, expected := range []string{
+		`{"document_id":"guide","version":1,"language":"es","translated_text":"Hola. "}`,
+		// The second request is answered from the table
+		`{"document_id":"guide","version":1,"language":"es","translated_text":"Hola. "}`,
+	} {
+		got, err := h.handleRequest(context.Background(), event)
+		if err != nil {
+			t.Fatalf("handleRequest() error = %v", err)
+		}
+		if got.StatusCode != http.StatusOK || got.Body != expected {
+			t.Errorf("handleRequest() = %d %s, expected %s", got.StatusCode, got.Body, expected)
+		}
+	}
+
+	version, _, err := h.getDocumentVersion(context.Background(), "guide", 1)
+	if err != nil {
+		t.Fatalf("getDocumentVersion() error = %v", err)
+	}
+	if version.Translations["es"] != "Hola. " {
+		t.Errorf("stored translations = %v, expected es translation", version.Translations)
+	}
+}
+
+func TestDocumentRegistryNotConfigured(t *testing.T) {
+	h := newMockHandler(nil)
+
+	got, err := h.handleRequest(context.Background(), events.APIGatewayProxyRequest{
+		Resource:       documentDiffResource,
+		HTTPMethod:     http.MethodGet,
+		PathParameters: map[string]string{"id": "guide"},
+	})
+	if err != nil {
+		t.Fatalf("handleRequest() error = %v", err)
+	}
+	if got.StatusCode != http.StatusNotImplemented {
+		t.Errorf("handleRequest() status = %d, expected %d", got.StatusCode, http.StatusNotImplemented)
+	}
+}
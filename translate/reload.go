@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// initializationTypeVariable is set by Lambda to how the container was initialized
+const initializationTypeVariable = "AWS_LAMBDA_INITIALIZATION_TYPE"
+
+// aheadOfTrafficInitializations are the initialization types whose init phase may run long
+// before the first invocation, in a snapshot restored later or a provisioned container
+var aheadOfTrafficInitializations = []string{"snap-start", "provisioned-concurrency"}
+
+// Reload re-reads the configuration from the environment and drops the state captured by the
+// init phase: the cached credentials, which may have expired or belong to the snapshotted
+// container, and the supported languages, which are warmed again. The clients keep the
+// region they were built with.
+func (h *handler) Reload(ctx context.Context) {
+	start := time.Now()
+	loadEnvConfig()
+
+	if credentials, ok := h.credentials.(*aws.CredentialsCache); ok {
+		credentials.Invalidate()
+	}
+
+	h.languages.mu.Lock()
+	h.languages.languages, h.languages.refreshAt = nil, time.Time{}
+	h.languages.mu.Unlock()
+	h.routes.mu.Lock()
+	h.routes.rules = nil
+	h.routes.mu.Unlock()
+	if handlerMode == "" || handlerMode == handlerModeAppSync {
+		h.warmSupportedLanguages(ctx)
+	}
+
+	log.Printf("Reload took %s", time.Since(start))
+}
+
+// reloadingHandler reloads the handler before the first invocation of the container
+type reloadingHandler struct {
+	lambda.Handler
+	reload func(ctx context.Context)
+	once   sync.Once
+}
+
+func (r *reloadingHandler) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	r.once.Do(func() { r.reload(ctx) })
+	return r.Handler.Invoke(ctx, payload)
+}
+
+// reloadOnRestore returns the lambda handler of the handler function, which reloads the
+// handler on the first invocation when the container was initialized ahead of its traffic
+func (h *handler) reloadOnRestore(handlerFunc any) lambda.Handler {
+	handler := lambda.NewHandler(handlerFunc)
+	if !slices.Contains(aheadOfTrafficInitializations, os.Getenv(initializationTypeVariable)) {
+		return handler
+	}
+	return &reloadingHandler{Handler: handler, reload: h.Reload}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestLoadEnvConfig(t *testing.T) {
+	t.Cleanup(loadEnvConfig)
+
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("TRANSLATE_TABLE_REGIONS", "us-east-1=TranslateCacheUS,eu-west-1=TranslateCacheEU")
+	t.Setenv("NEGATIVE_CACHE_TTL", "60")
+	t.Setenv("CACHE_HIT_TRACKING", "false")
+	t.Setenv("SAGEMAKER_MODEL_FORMAT", "")
+	loadEnvConfig()
+
+	if region != "eu-west-1" || translateTableName != "TranslateCacheEU" {
+		t.Errorf("loadEnvConfig() region = %q, table = %q, expected eu-west-1 and TranslateCacheEU", region, translateTableName)
+	}
+	if negativeCacheTTL != 60 || cacheHitTracking {
+		t.Errorf("loadEnvConfig() negativeCacheTTL = %d, cacheHitTracking = %t, expected 60 and false", negativeCacheTTL, cacheHitTracking)
+	}
+	if sageMakerModelFormat != sageMakerFormatNLLB || streamTranslatedField != defaultStreamTextField+translatedFieldSuffix {
+		t.Errorf("loadEnvConfig() didn't apply the defaults, sageMakerModelFormat = %q, streamTranslatedField = %q", sageMakerModelFormat, streamTranslatedField)
+	}
+
+	t.Setenv("AWS_REGION", "us-east-1")
+	loadEnvConfig()
+	if translateTableName != "TranslateCacheUS" {
+		t.Errorf("loadEnvConfig() table = %q after the region changed, expected TranslateCacheUS", translateTableName)
+	}
+}
+
+func TestReload(t *testing.T) {
+	t.Cleanup(loadEnvConfig)
+
+	var retrievals atomic.Int32
+	credentials := aws.NewCredentialsCache(aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+		retrievals.Add(1)
+		return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET", CanExpire: true, Expires: time.Now().Add(time.Hour)}, nil
+	}))
+	if _, err := credentials.Retrieve(context.Background()); err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+
+	h := newMockHandler(nil)
+	h.credentials = credentials
+	h.languages.languages = []string{"en", "de"}
+	h.languages.refreshAt = time.Now().Add(time.Hour)
+
+	t.Setenv("HANDLER_MODE", "")
+	h.Reload(context.Background())
+
+	if _, err := credentials.Retrieve(context.Background()); err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if retrievals.Load() != 2 {
+		t.Errorf("Reload() kept the cached credentials, %d retrievals", retrievals.Load())
+	}
+	if !slices.Equal(h.languages.languages, []string{"en", "es"}) {
+		t.Errorf("Reload() languages = %v, expected the provider languages", h.languages.languages)
+	}
+}
+
+func TestReloadOnRestore(t *testing.T) {
+	tests := []struct {
+		name               string
+		initializationType string
+		expectedReloads    int32
+	}{
+		{name: "On demand", initializationType: "on-demand"},
+		{name: "Unknown", initializationType: ""},
+		{name: "SnapStart", initializationType: "snap-start", expectedReloads: 1},
+		{name: "Provisioned concurrency", initializationType: "provisioned-concurrency", expectedReloads: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(initializationTypeVariable, tt.initializationType)
+
+			var invocations atomic.Int32
+			h := newMockHandler(nil)
+			handler := h.reloadOnRestore(func(ctx context.Context) error {
+				invocations.Add(1)
+				return nil
+			})
+
+			var reloads atomic.Int32
+			if reloading, ok := handler.(*reloadingHandler); ok {
+				reloading.reload = func(ctx context.Context) { reloads.Add(1) }
+			}
+
+			for range 2 {
+				if _, err := handler.Invoke(context.Background(), []byte(`{}`)); err != nil {
+					t.Fatalf("Invoke() error = %v", err)
+				}
+			}
+
+			if invocations.Load() != 2 {
+				t.Errorf("Invoke() called the handler %d times, expected 2", invocations.Load())
+			}
+			if reloads.Load() != tt.expectedReloads {
+				t.Errorf("Invoke() reloaded %d times, expected %d", reloads.Load(), tt.expectedReloads)
+			}
+		})
+	}
+}
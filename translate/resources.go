@@ -0,0 +1,688 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	formatAndroidStrings = "android_strings"
+	formatIOSStrings     = "ios_strings"
+	formatIOSStringsDict = "ios_stringsdict"
+
+	// stringsDictPluralRule marks a stringsdict variable holding plural forms
+	stringsDictPluralRule = "NSStringPluralRuleType"
+)
+
+// pluralFormOrder is the CLDR order plural forms are written in
+var pluralFormOrder = []string{"zero", "one", "two", "few", "many", "other"}
+
+// formatSpecifierPattern matches printf style format specifiers used by Android and iOS,
+// including positional arguments and stringsdict variables such as %#@files@
+var formatSpecifierPattern = regexp.MustCompile(`%(?:#@[A-Za-z0-9_]+@|(?:\d+\$)?[-#+ 0,(]*\d*(?:\.\d+)?(?:hh|h|ll|l|L|q|z|t|j)?[@dDiuUxXoOfFeEgGcCsSpaAn%])`)
+
+// iosStringsPattern matches a "key" = "value"; entry of an iOS .strings file
+var iosStringsPattern = regexp.MustCompile(`(?m)^(\s*"(?:[^"\\]|\\.)*"\s*=\s*")((?:[^"\\]|\\.)*)("\s*;)`)
+
+// resourceEdit replaces a byte range of a resource file
+type resourceEdit struct {
+	start, end int
+	text       string
+}
+
+// resourceValue is a translatable value of a resource file
+type resourceValue struct {
+	start, end int
+	// text is the unescaped value
+	text string
+}
+
+// resourcePlurals is a group of plural forms rewritten for the target language
+type resourcePlurals struct {
+	// start and end delimit the forms, from the first to the end of the last
+	start, end int
+	// separator is the whitespace written between forms
+	separator string
+	// forms are the unescaped source plural forms by CLDR name
+	forms map[string]string
+	// write formats a single plural form
+	write func(name, value string) string
+}
+
+func (h *handler) handleResources(ctx context.Context, request TranslateRequest) (events.APIGatewayProxyResponse, error) {
+	document, err := base64.StdEncoding.DecodeString(request.Document)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       "document must be base64 encoded",
+		}, nil
+	}
+
+	var (
+		values  []resourceValue
+		plurals []resourcePlurals
+		escape  func(string) string
+	)
+	switch request.Format {
+	case formatAndroidStrings:
+		values, plurals, err = parseAndroidStrings(document)
+		escape = escapeAndroidValue
+	case formatIOSStrings:
+		values = parseIOSStrings(document)
+		escape = escapeIOSValue
+	case formatIOSStringsDict:
+		values, plurals, err = parseStringsDict(document)
+		escape = escapeXMLText
+	}
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       fmt.Sprintf("document is not a valid %s file: %v", request.Format, err),
+		}, nil
+	}
+
+	translated, err := h.translateResources(ctx, request.SourceLanguage, request.TargetLanguage, values, plurals, escape)
+	if response, ok := unavailableResponse(err); ok {
+		return response, nil
+	}
+	if response, ok := characterLimitResponse(err); ok {
+		return response, nil
+	}
+	if response, ok := languagePolicyResponse(err); ok {
+		return response, nil
+	}
+	if err != nil {
+		log.Printf("Error translating resources: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error during translation",
+		}, nil
+	}
+
+	response := TranslateResponse{
+		TranslatedText: string(applyResourceEdits(document, translated)),
+		Degraded:       degradationFromContext(ctx).Degraded(),
+		LowQuality:     degradationFromContext(ctx).LowQuality(),
+		Pivoted:        pivotReportFromContext(ctx).Pivoted(),
+		Segments:       lengthConstraintFromContext(ctx).Segments(),
+	}
+
+	return newJSONResponse(ctx, response), nil
+}
+
+// translateResources translates the values and plural groups of a resource file, returning
+// the edits to apply to it
+func (h *handler) translateResources(ctx context.Context, sourceLanguage, targetLanguage string, values []resourceValue, plurals []resourcePlurals, escape func(string) string) ([]resourceEdit, error) {
+	texts := make([]string, len(values))
+	for i, value := range values {
+		texts[i] = value.text
+	}
+
+	translatedTexts, err := h.translateProtected(ctx, sourceLanguage, targetLanguage, texts)
+	if err != nil {
+		return nil, err
+	}
+
+	edits := make([]resourceEdit, 0, len(values)+len(plurals))
+	for i, value := range values {
+		edits = append(edits, resourceEdit{start: value.start, end: value.end, text: escape(translatedTexts[i])})
+	}
+
+	for _, group := range plurals {
+		forms, err := h.translateResourcePlurals(ctx, sourceLanguage, targetLanguage, group.forms)
+		if err != nil {
+			return nil, err
+		}
+
+		written := make([]string, 0, len(forms))
+		for _, name := range pluralFormOrder {
+			if value, ok := forms[name]; ok {
+				written = append(written, group.write(name, escape(value)))
+			}
+		}
+		edits = append(edits, resourceEdit{start: group.start, end: group.end, text: strings.Join(written, group.separator)})
+	}
+
+	return edits, nil
+}
+
+// translateProtected translates each text whole, keeping its format specifiers out of reach
+// of the provider. Texts whose specifiers don't survive translation are left untranslated,
+// as are texts made only of specifiers.
+func (h *handler) translateProtected(ctx context.Context, sourceLanguage, targetLanguage string, texts []string) ([]string, error) {
+	return h.translateMasked(ctx, sourceLanguage, targetLanguage, texts, formatSpecifierPattern)
+}
+
+// translateMasked translates each text whole, replacing the matches of the pattern with
+// numbered placeholders the provider leaves alone. Texts whose placeholders don't survive
+// translation are left untranslated, as are texts made only of matches.
+func (h *handler) translateMasked(ctx context.Context, sourceLanguage, targetLanguage string, texts []string, pattern *regexp.Regexp) ([]string, error) {
+	var (
+		indexes    []int
+		masked     []string
+		specifiers [][]string
+	)
+	for i, text := range texts {
+		protected, textSpecifiers := maskPattern(text, pattern)
+		if !strings.ContainsFunc(pattern.ReplaceAllString(text, ""), unicode.IsLetter) {
+			continue
+		}
+		indexes = append(indexes, i)
+		masked = append(masked, protected)
+		specifiers = append(specifiers, textSpecifiers)
+	}
+
+	translated, err := h.translateSentences(ctx, sourceLanguage, targetLanguage, masked)
+	if err != nil {
+		return nil, err
+	}
+	for i := range translated {
+		constrained, err := h.constrainLength(ctx, sourceLanguage, targetLanguage, masked[i:i+1], translated[i:i+1])
+		if err != nil {
+			return nil, err
+		}
+		translated[i] = strings.Join(constrained, " ")
+	}
+
+	results := slices.Clone(texts)
+	for i, index := range indexes {
+		restored, ok := restoreSpecifiers(translated[i], specifiers[i])
+		if !ok {
+			log.Printf("Placeholders of %q were lost in translation, keeping the source text", texts[index])
+			continue
+		}
+		results[index] = restored
+	}
+
+	return results, nil
+}
+
+// translateResourcePlurals generates the target plural forms of a resource string. The count
+// is the first integer specifier of the other form, every other specifier is protected.
+func (h *handler) translateResourcePlurals(ctx context.Context, sourceLanguage, targetLanguage string, forms map[string]string) (map[string]string, error) {
+	countSpecifier := ""
+	for _, specifier := range formatSpecifierPattern.FindAllString(forms["other"], -1) {
+		if strings.ContainsAny(specifier[len(specifier)-1:], "dDiuU") {
+			countSpecifier = specifier
+			break
+		}
+	}
+
+	// Without a count there is nothing to inflect, the forms are translated one by one
+	if countSpecifier == "" {
+		names := make([]string, 0, len(forms))
+		texts := make([]string, 0, len(forms))
+		for _, name := range pluralFormOrder {
+			if text, ok := forms[name]; ok {
+				names = append(names, name)
+				texts = append(texts, text)
+			}
+		}
+
+		translated, err := h.translateProtected(ctx, sourceLanguage, targetLanguage, texts)
+		if err != nil {
+			return nil, err
+		}
+
+		translatedForms := make(map[string]string, len(names))
+		for i, name := range names {
+			translatedForms[name] = translated[i]
+		}
+		return translatedForms, nil
+	}
+
+	// Protect every other specifier, the count is replaced by a sample number of each form
+	protectedForms := make(map[string]string, len(forms))
+	var specifiers []string
+	for name, text := range forms {
+		var protected []string
+		protectedForms[name], protected = protectSpecifiers(strings.ReplaceAll(text, countSpecifier, defaultPluralPlaceholder))
+		if len(protected) > len(specifiers) {
+			specifiers = protected
+		}
+	}
+
+	translated, err := h.translatePlurals(ctx, sourceLanguage, targetLanguage, protectedForms, defaultPluralPlaceholder)
+	if err != nil {
+		return nil, err
+	}
+
+	// Forms whose specifiers were lost fall back to the other form, translated if possible
+	restoredForms := make(map[string]string, len(translated))
+	var lost []string
+	for name, text := range translated {
+		restored, ok := restoreSpecifiers(text, specifiers)
+		if !ok {
+			lost = append(lost, name)
+			continue
+		}
+		restoredForms[name] = strings.ReplaceAll(restored, defaultPluralPlaceholder, countSpecifier)
+	}
+	for _, name := range lost {
+		log.Printf("Format specifiers of the %s plural form were lost in translation, keeping the other form", name)
+		other, ok := restoredForms["other"]
+		if !ok {
+			other = forms["other"]
+		}
+		restoredForms[name] = other
+	}
+
+	return restoredForms, nil
+}
+
+// protectSpecifiers replaces the format specifiers of the text with numbered placeholders
+func protectSpecifiers(text string) (string, []string) {
+	return maskPattern(text, formatSpecifierPattern)
+}
+
+// maskPattern replaces the matches of the pattern with numbered placeholders, returning the
+// matches in placeholder order
+func maskPattern(text string, pattern *regexp.Regexp) (string, []string) {
+	var specifiers []string
+	masked := pattern.ReplaceAllStringFunc(text, func(specifier string) string {
+		specifiers = append(specifiers, specifier)
+		return "{" + strconv.Itoa(len(specifiers)-1) + "}"
+	})
+	return masked, specifiers
+}
+
+// restoreSpecifiers puts the format specifiers back, reporting whether every placeholder
+// was found exactly once
+func restoreSpecifiers(text string, specifiers []string) (string, bool) {
+	for i, specifier := range specifiers {
+		placeholder := "{" + strconv.Itoa(i) + "}"
+		if strings.Count(text, placeholder) != 1 {
+			return text, false
+		}
+		text = strings.Replace(text, placeholder, specifier, 1)
+	}
+	return text, true
+}
+
+// applyResourceEdits applies the non overlapping edits to the document
+func applyResourceEdits(document []byte, edits []resourceEdit) []byte {
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+
+	var buf bytes.Buffer
+	offset := 0
+	for _, edit := range edits {
+		buf.Write(document[offset:edit.start])
+		buf.WriteString(edit.text)
+		offset = edit.end
+	}
+	buf.Write(document[offset:])
+
+	return buf.Bytes()
+}
+
+// parseAndroidStrings finds the translatable values of an Android strings.xml file. Text
+// inside xliff:g elements and strings marked translatable="false" are left untouched.
+func parseAndroidStrings(document []byte) ([]resourceValue, []resourcePlurals, error) {
+	if err := checkXML(document); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		values   []resourceValue
+		plurals  []resourcePlurals
+		group    *resourcePlurals
+		groupRaw []resourceValue
+		mixed    bool
+		item     string
+		depth    int // depth inside the current translatable element
+		skipped  int // depth inside untranslatable elements
+	)
+
+	decoder := xml.NewDecoder(bytes.NewReader(document))
+	for {
+		start := int(decoder.InputOffset())
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		end := int(decoder.InputOffset())
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			name := xmlName(token.Name)
+			switch {
+			case skipped > 0 || (depth > 0 && name == "xliff:g"):
+				skipped++
+			case depth > 0:
+				depth++
+				if group != nil {
+					mixed = true
+				}
+			case name == "string" || (name == "item" && group == nil):
+				if xmlAttr(token, "translatable") == "false" {
+					skipped++
+				} else {
+					depth++
+				}
+			case name == "plurals":
+				group = &resourcePlurals{start: -1, forms: make(map[string]string)}
+				groupRaw, mixed = nil, false
+			case name == "item" && group != nil:
+				if group.start < 0 {
+					group.start = start
+				} else if group.separator == "" {
+					group.separator = string(document[group.end:start])
+				}
+				item = xmlAttr(token, "quantity")
+				depth++
+			}
+		case xml.EndElement:
+			name := xmlName(token.Name)
+			switch {
+			case skipped > 0:
+				skipped--
+			case depth > 0:
+				depth--
+				if depth == 0 && group != nil {
+					group.end = end
+					item = ""
+				}
+			case name == "plurals" && group != nil:
+				if mixed || len(group.forms) == 0 || group.forms["other"] == "" {
+					values = append(values, groupRaw...)
+				} else {
+					if group.separator == "" {
+						group.separator = "\n"
+					}
+					group.write = func(name, value string) string {
+						return fmt.Sprintf(`<item quantity="%s">%s</item>`, name, value)
+					}
+					plurals = append(plurals, *group)
+				}
+				group = nil
+			}
+		case xml.CharData:
+			if depth == 0 || skipped > 0 {
+				continue
+			}
+			value, ok := androidValue(start, end, string(token))
+			if !ok {
+				continue
+			}
+			if group != nil {
+				group.forms[item] += value.text
+				groupRaw = append(groupRaw, value)
+				continue
+			}
+			values = append(values, value)
+		}
+	}
+
+	return values, plurals, nil
+}
+
+// androidValue unescapes the text of an Android resource, excluding surrounding whitespace
+// and references to other resources
+func androidValue(start, end int, text string) (resourceValue, bool) {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" || strings.HasPrefix(trimmed, "@") || strings.HasPrefix(trimmed, "?") {
+		return resourceValue{}, false
+	}
+
+	// The decoder unescapes entities, so the raw length of the whitespace is unknown.
+	// Whitespace is never escaped, which keeps the trimmed offsets exact.
+	leading := len(text) - len(strings.TrimLeftFunc(text, unicode.IsSpace))
+	trailing := len(text) - len(strings.TrimRightFunc(text, unicode.IsSpace))
+
+	return resourceValue{
+		start: start + leading,
+		end:   end - trailing,
+		text:  unescapeAndroidValue(trimmed),
+	}, true
+}
+
+// unescapeAndroidValue removes the backslash escapes and quoting of an Android string
+func unescapeAndroidValue(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) && !strings.HasSuffix(value, `\"`) {
+		value = value[1 : len(value)-1]
+	}
+
+	var builder strings.Builder
+	escaped := false
+	for _, r := range value {
+		if !escaped && r == '\\' {
+			escaped = true
+			continue
+		}
+		if escaped {
+			switch r {
+			case 'n':
+				r = '\n'
+			case 't':
+				r = '\t'
+			}
+			escaped = false
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// escapeAndroidValue escapes a translated value for an Android strings.xml file
+func escapeAndroidValue(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(value)
+	if strings.HasPrefix(value, "@") || strings.HasPrefix(value, "?") {
+		value = `\` + value
+	}
+	return escapeXMLText(value)
+}
+
+// parseIOSStrings finds the values of the "key" = "value"; entries of an iOS .strings file
+func parseIOSStrings(document []byte) []resourceValue {
+	var values []resourceValue
+	for _, match := range iosStringsPattern.FindAllSubmatchIndex(document, -1) {
+		start, end := match[4], match[5]
+		text := unescapeIOSValue(string(document[start:end]))
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		values = append(values, resourceValue{start: start, end: end, text: text})
+	}
+	return values
+}
+
+// unescapeIOSValue removes the backslash escapes of an iOS .strings value
+func unescapeIOSValue(value string) string {
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`, `\n`, "\n", `\t`, "\t").Replace(value)
+}
+
+// escapeIOSValue escapes a translated value for an iOS .strings file
+func escapeIOSValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(value)
+}
+
+// stringsDictEntry is a key and its string value in a stringsdict dictionary
+type stringsDictEntry struct {
+	key string
+	// keyStart is the offset of the key element, end the offset after the string element
+	keyStart, end int
+	// valueStart and valueEnd delimit the text of the string element
+	valueStart, valueEnd int
+	valueText            string
+}
+
+// parseStringsDict finds the format keys and plural variables of an iOS .stringsdict file
+func parseStringsDict(document []byte) ([]resourceValue, []resourcePlurals, error) {
+	if err := checkXML(document); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		values  []resourceValue
+		plurals []resourcePlurals
+		dicts   [][]stringsDictEntry
+		key     string
+		keyAt   int
+		inKey   bool
+		inValue bool
+		current stringsDictEntry
+	)
+
+	decoder := xml.NewDecoder(bytes.NewReader(document))
+	for {
+		start := int(decoder.InputOffset())
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		end := int(decoder.InputOffset())
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			switch token.Name.Local {
+			case "dict":
+				dicts = append(dicts, nil)
+				key = ""
+			case "key":
+				inKey, key, keyAt = true, "", start
+			case "string":
+				if len(dicts) > 0 && key != "" {
+					inValue = true
+					current = stringsDictEntry{key: key, keyStart: keyAt, valueStart: end, valueEnd: end}
+				}
+			}
+		case xml.EndElement:
+			switch token.Name.Local {
+			case "key":
+				inKey = false
+			case "string":
+				if inValue {
+					inValue = false
+					current.end = end
+					dicts[len(dicts)-1] = append(dicts[len(dicts)-1], current)
+				}
+				key = ""
+			case "dict":
+				if len(dicts) == 0 {
+					return nil, nil, fmt.Errorf("unbalanced dict")
+				}
+				entries := dicts[len(dicts)-1]
+				dicts = dicts[:len(dicts)-1]
+				key = ""
+
+				dictValues, group := stringsDictPlurals(document, entries)
+				values = append(values, dictValues...)
+				if group != nil {
+					plurals = append(plurals, *group)
+				}
+			}
+		case xml.CharData:
+			switch {
+			case inKey:
+				key += string(token)
+			case inValue:
+				current.valueText += string(token)
+				current.valueEnd = end
+			}
+		}
+	}
+
+	return values, plurals, nil
+}
+
+// stringsDictPlurals returns the translatable values of a stringsdict dictionary, and its
+// plural forms when it is a plural rule variable
+func stringsDictPlurals(document []byte, entries []stringsDictEntry) ([]resourceValue, *resourcePlurals) {
+	var values []resourceValue
+	isPlural := slices.ContainsFunc(entries, func(entry stringsDictEntry) bool {
+		return entry.key == "NSStringFormatSpecTypeKey" && entry.valueText == stringsDictPluralRule
+	})
+
+	group := &resourcePlurals{start: -1, forms: make(map[string]string)}
+	for _, entry := range entries {
+		switch {
+		case entry.key == "NSStringLocalizedFormatKey":
+			values = append(values, resourceValue{start: entry.valueStart, end: entry.valueEnd, text: entry.valueText})
+		case isPlural && slices.Contains(pluralFormOrder, entry.key):
+			if group.start < 0 {
+				group.start = entry.keyStart
+			} else if group.separator == "" {
+				group.separator = string(document[group.end:entry.keyStart])
+			}
+			group.forms[entry.key] = entry.valueText
+			group.end = entry.end
+		}
+	}
+
+	if !isPlural || group.forms["other"] == "" {
+		return values, nil
+	}
+
+	// A key and its string are written on separate lines with the same indentation
+	pair := string(document[entries[0].keyStart:entries[0].valueStart])
+	between := pair[strings.Index(pair, "</key>")+len("</key>") : strings.LastIndex(pair, "<string>")]
+	if group.separator == "" {
+		group.separator = between
+	}
+	group.write = func(name, value string) string {
+		return fmt.Sprintf("<key>%s</key>%s<string>%s</string>", name, between, value)
+	}
+
+	return values, group
+}
+
+// checkXML reports whether the document is well formed, the raw tokens used to keep the
+// offsets of values don't check that elements are balanced
+func checkXML(document []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(document))
+	for {
+		_, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// escapeXMLText escapes the characters with a meaning in XML text
+func escapeXMLText(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// xmlName returns the prefixed name of a raw XML token
+func xmlName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// xmlAttr returns the value of the unprefixed attribute or an empty string
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestProtectSpecifiers(t *testing.T) {
+	tests := []struct {
+		name               string
+		text               string
+		expectedMasked     string
+		expectedSpecifiers []string
+	}{
+		{
+			name:               "Android positional arguments",
+			text:               "Hello %1$s, you have %2$d messages",
+			expectedMasked:     "Hello {0}, you have {1} messages",
+			expectedSpecifiers: []string{"%1$s", "%2$d"},
+		},
+		{
+			name:               "iOS object and stringsdict variable",
+			text:               "%@ shared %#@photos@",
+			expectedMasked:     "{0} shared {1}",
+			expectedSpecifiers: []string{"%@", "%#@photos@"},
+		},
+		{
+			name:               "Escaped percent and precision",
+			text:               "%.1f%% done",
+			expectedMasked:     "{0}{1} done",
+			expectedSpecifiers: []string{"%.1f", "%%"},
+		},
+		{
+			name:           "No specifiers",
+			text:           "Settings",
+			expectedMasked: "Settings",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			masked, specifiers := protectSpecifiers(tt.text)
+			if masked != tt.expectedMasked || !slices.Equal(specifiers, tt.expectedSpecifiers) {
+				t.Errorf("protectSpecifiers() = %q, %v, expected %q, %v", masked, specifiers, tt.expectedMasked, tt.expectedSpecifiers)
+			}
+
+			restored, ok := restoreSpecifiers(masked, specifiers)
+			if !ok || restored != tt.text {
+				t.Errorf("restoreSpecifiers() = %q, %v, expected %q, true", restored, ok, tt.text)
+			}
+		})
+	}
+}
+
+func TestRestoreSpecifiersLost(t *testing.T) {
+	if _, ok := restoreSpecifiers("Hola {0}", []string{"%1$s", "%2$d"}); ok {
+		t.Errorf("restoreSpecifiers() reported a missing placeholder as restored")
+	}
+	if _, ok := restoreSpecifiers("Hola {0} {0}", []string{"%1$s"}); ok {
+		t.Errorf("restoreSpecifiers() reported a duplicated placeholder as restored")
+	}
+}
+
+func TestParseAndroidStrings(t *testing.T) {
+	tests := []struct {
+		name            string
+		document        string
+		expectedTexts   []string
+		expectedPlurals int
+		wantErr         bool
+	}{
+		{
+			name: "Strings are unescaped",
+			document: `<resources>
+    <string name="greeting">Don\'t panic</string>
+    <string name="quoted">"Hello  there"</string>
+</resources>`,
+			expectedTexts: []string{"Don't panic", "Hello  there"},
+		},
+		{
+			name: "Untranslatable text is skipped",
+			document: `<resources xmlns:xliff="urn:oasis:names:tc:xliff:document:1.2">
+    <string name="app_name" translatable="false">GoTranslate</string>
+    <string name="alias">@string/app_name</string>
+    <string name="welcome">Welcome to <xliff:g id="app">GoTranslate</xliff:g></string>
+</resources>`,
+			expectedTexts: []string{"Welcome to"},
+		},
+		{
+			name: "Plurals are grouped",
+			document: `<resources>
+    <plurals name="files">
+        <item quantity="one">%d file</item>
+        <item quantity="other">%d files</item>
+    </plurals>
+</resources>`,
+			expectedPlurals: 1,
+		},
+		{
+			name:     "Malformed document",
+			document: `<resources><string name="a">Hello</resources>`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, plurals, err := parseAndroidStrings([]byte(tt.document))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseAndroidStrings() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var texts []string
+			for _, value := range values {
+				texts = append(texts, value.text)
+			}
+			if !slices.Equal(texts, tt.expectedTexts) || len(plurals) != tt.expectedPlurals {
+				t.Errorf("parseAndroidStrings() = %q, %d plurals, expected %q, %d plurals", texts, len(plurals), tt.expectedTexts, tt.expectedPlurals)
+			}
+		})
+	}
+}
+
+func TestHandleResources(t *testing.T) {
+	translations := map[string]string{
+		"Don't panic":           "No entres en pánico",
+		"Hello {0}":             "Hola {0}",
+		"Welcome to":            "Bienvenido a",
+		"1 file":                "1 archivo",
+		"0 files":               "0 archivos",
+		"You have {0} messages": "Tiene mensajes",
+		"Cancel":                "Cancelar",
+	}
+
+	tests := []struct {
+		name             string
+		format           string
+		document         string
+		expectedStatus   int
+		expectedDocument string
+	}{
+		{
+			name:   "Android strings",
+			format: formatAndroidStrings,
+			document: `<resources xmlns:xliff="urn:oasis:names:tc:xliff:document:1.2">
+    <string name="panic">Don\'t panic</string>
+    <string name="hello">Hello %1$s</string>
+    <string name="welcome">Welcome to <xliff:g id="app">GoTranslate</xliff:g></string>
+    <string name="app_name" translatable="false">GoTranslate</string>
+    <plurals name="files">
+        <item quantity="one">%d file</item>
+        <item quantity="other">%d files</item>
+    </plurals>
+</resources>`,
+			expectedStatus: http.StatusOK,
+			expectedDocument: `<resources xmlns:xliff="urn:oasis:names:tc:xliff:document:1.2">
+    <string name="panic">No entres en pánico</string>
+    <string name="hello">Hola %1$s</string>
+    <string name="welcome">Bienvenido a <xliff:g id="app">GoTranslate</xliff:g></string>
+    <string name="app_name" translatable="false">GoTranslate</string>
+    <plurals name="files">
+        <item quantity="one">%d archivo</item>
+        <item quantity="other">%d archivos</item>
+    </plurals>
+</resources>`,
+		},
+		{
+			name:   "iOS strings",
+			format: formatIOSStrings,
+			document: `/* Greeting */
+"hello" = "Hello %@";
+"cancel" = "Cancel";
+"messages" = "You have %d messages";
+`,
+			expectedStatus: http.StatusOK,
+			expectedDocument: `/* Greeting */
+"hello" = "Hola %@";
+"cancel" = "Cancelar";
+"messages" = "You have %d messages";
+`,
+		},
+		{
+			name:   "iOS stringsdict",
+			format: formatIOSStringsDict,
+			document: `<plist version="1.0">
+<dict>
+    <key>files</key>
+    <dict>
+        <key>NSStringLocalizedFormatKey</key>
+        <string>%#@files@</string>
+        <key>files</key>
+        <dict>
+            <key>NSStringFormatSpecTypeKey</key>
+            <string>NSStringPluralRuleType</string>
+            <key>NSStringFormatValueTypeKey</key>
+            <string>d</string>
+            <key>one</key>
+            <string>%d file</string>
+            <key>other</key>
+            <string>%d files</string>
+        </dict>
+    </dict>
+</dict>
+</plist>`,
+			expectedStatus: http.StatusOK,
+			expectedDocument: `<plist version="1.0">
+<dict>
+    <key>files</key>
+    <dict>
+        <key>NSStringLocalizedFormatKey</key>
+        <string>%#@files@</string>
+        <key>files</key>
+        <dict>
+            <key>NSStringFormatSpecTypeKey</key>
+            <string>NSStringPluralRuleType</string>
+            <key>NSStringFormatValueTypeKey</key>
+            <string>d</string>
+            <key>one</key>
+            <string>%d archivo</string>
+            <key>other</key>
+            <string>%d archivos</string>
+        </dict>
+    </dict>
+</dict>
+</plist>`,
+		},
+		{
+			name:           "Malformed document",
+			format:         formatAndroidStrings,
+			document:       `<resources><string name="a">Hello</resources>`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(translations)
+
+			body, err := json.Marshal(TranslateRequest{
+				SourceLanguage: "en",
+				TargetLanguage: "es",
+				Format:         tt.format,
+				Document:       base64.StdEncoding.EncodeToString([]byte(tt.document)),
+			})
+			if err != nil {
+				t.Fatalf("failed to marshal request: %v", err)
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: string(body)})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+			if got.StatusCode != tt.expectedStatus {
+				t.Errorf("handle() status = %d, expected %d: %s", got.StatusCode, tt.expectedStatus, got.Body)
+				return
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response TranslateResponse
+			if err := json.Unmarshal([]byte(got.Body), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if response.TranslatedText != tt.expectedDocument {
+				t.Errorf("handle() document = %s, expected %s", response.TranslatedText, tt.expectedDocument)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// responseCachePrefix prefixes the cache table hash of whole responses, keeping them apart
+	// from the sentence hashes
+	responseCachePrefix = "response-"
+
+	// providerResponseCache is the provenance of the cached responses, which aren't
+	// translations of a single sentence
+	providerResponseCache = "response-cache"
+
+	// responseReferencePrefix prefixes the cache table hash of the references from the
+	// sentences of a cached response to the response, whose hash follows the last slash
+	responseReferencePrefix = "response-ref-"
+
+	// providerResponseReference is the provenance of the response references
+	providerResponseReference = "response-reference"
+)
+
+// responseCacheTTL is the number of seconds a whole text or HTML response is cached, so an
+// unchanged document submitted again is answered without a lookup per sentence. 0 disables
+// the response cache, as does a source text retention mode.
+var responseCacheTTL int
+
+// responseCacheKey returns the cache table hash of the response of the request, over its
+// text and every option, and whether the response may be cached. The version is left out,
+// the response is stored before the codec of the request shapes it. Dry runs only estimate
+// the translation and no-store requests must not leave their text in the cache. Alternatives
+// are requested anew every time, only the translation is cached. Responses carry the source
+// text in their alignment, segments and analysis as well, so none are cached when the source
+// text retention is restricted.
+func responseCacheKey(request TranslateRequest) (string, bool) {
+	if responseCacheTTL <= 0 || cacheSourceRetention != "" || request.DryRun || request.NoStore || request.Transliterate || request.Alternatives > 0 {
+		return "", false
+	}
+	if request.Format != "" && request.Format != formatText && request.Format != formatHTML {
+		return "", false
+	}
+
+	request.Version = ""
+	key, err := json.Marshal(request)
+	if err != nil {
+		return "", false
+	}
+	return responseCachePrefix + getHashFromText(string(key)), true
+}
+
+// lookupResponse returns the cached response of the hash. A failed lookup is only logged,
+// the request is then translated sentence by sentence.
+func (h *handler) lookupResponse(ctx context.Context, hash string) (TranslateResponse, bool) {
+	lookupCtx, cancel := stageBudgetsFromContext(ctx).lookupContext(ctx)
+	defer cancel()
+	item, found, err := getCacheItem(lookupCtx, h.dynamoClient, translateTableName, hash)
+	if err != nil {
+		log.Printf("Error looking up cached response %s: %v", hash, err)
+		return TranslateResponse{}, false
+	}
+	// Expired responses are only removed lazily by the table TTL
+	if !found || item.ExpiresAt <= time.Now().Unix() {
+		return TranslateResponse{}, false
+	}
+
+	if item.OverflowKey != "" {
+		if item, err = h.resolveCacheItem(ctx, item); err != nil {
+			log.Printf("Error loading overflowed cached response %s: %v", hash, err)
+			return TranslateResponse{}, false
+		}
+	}
+
+	var response TranslateResponse
+	if err := json.Unmarshal([]byte(item.TranslatedText), &response); err != nil {
+		log.Printf("Error unmarshalling cached response %s: %v", hash, err)
+		return TranslateResponse{}, false
+	}
+	return response, true
+}
+
+// cacheResponse stores the response of the request in the write-behind stage, along with a
+// reference to it from each of its source and translated sentences so erasing a sentence
+// also erases the response. Degraded and timed out responses are left out, the next request
+// may translate the text the provider couldn't.
+func (h *handler) cacheResponse(ctx context.Context, hash string, request TranslateRequest, response TranslateResponse) {
+	if response.Degraded || response.LowQuality || response.TimedOut {
+		return
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Error marshalling response %s for the cache: %v", hash, err)
+		return
+	}
+
+	item := CacheItem{
+		Hash:           hash,
+		SourceText:     request.Text,
+		TranslatedText: string(body),
+		SourceLanguage: request.SourceLanguage,
+		TargetLanguage: request.TargetLanguage,
+		Region:         region,
+		Provider:       providerResponseCache,
+		ExpiresAt:      time.Now().Add(time.Duration(responseCacheTTL) * time.Second).Unix(),
+	}
+	if skipCacheWrite(ctx, item) {
+		return
+	}
+
+	if err := h.cacheTranslation(ctx, item); err != nil {
+		log.Printf("Error caching response %s: %v", hash, err)
+		return
+	}
+
+	for _, reference := range responseReferences(item, request, response.TranslatedText) {
+		if err := h.cacheTranslation(ctx, reference); err != nil {
+			log.Printf("Error caching reference %s: %v", reference.Hash, err)
+		}
+	}
+}
+
+// responseReferences returns the references from the distinct sentences of the source and
+// translated text of a request to its cached response item. The erasure requests find
+// them through the content hash indexes, which only hold one hash per item.
+func responseReferences(item CacheItem, request TranslateRequest, translatedText string) []CacheItem {
+	var references []CacheItem
+	seen := make(map[string]bool)
+	for _, side := range []struct {
+		name     string
+		language string
+		text     string
+	}{
+		{"source", request.SourceLanguage, request.Text},
+		{"translated", request.TargetLanguage, translatedText},
+	} {
+		text, err := requestPlainText(TranslateRequest{Text: side.text, Format: request.Format})
+		if err != nil {
+			log.Printf("Error extracting the %s sentences of response %s: %v", side.name, item.Hash, err)
+			continue
+		}
+		for _, sentence := range splitLanguageSentences(side.language, text) {
+			sentence = strings.TrimSpace(sentence)
+			if sentence == "" {
+				continue
+			}
+			contentHash := getHashFromText(sentence)
+			hash := responseReferencePrefix + side.name + "-" + contentHash + "/" + item.Hash
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+
+			reference := CacheItem{
+				Hash:           hash,
+				SourceLanguage: item.SourceLanguage,
+				TargetLanguage: item.TargetLanguage,
+				Region:         item.Region,
+				Provider:       providerResponseReference,
+				ExpiresAt:      item.ExpiresAt,
+			}
+			if side.name == "source" {
+				reference.SourceHash = contentHash
+			} else {
+				reference.TranslatedHash = contentHash
+			}
+			references = append(references, reference)
+		}
+	}
+	return references
+}
+
+// referencedResponse returns the hash of the response a reference points to
+func referencedResponse(hash string) (string, bool) {
+	if !strings.HasPrefix(hash, responseReferencePrefix) {
+		return "", false
+	}
+	i := strings.LastIndex(hash, "/")
+	if i < 0 {
+		return "", false
+	}
+	return hash[i+1:], true
+}
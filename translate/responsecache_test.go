@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestResponseCacheKey(t *testing.T) {
+	original, originalRetention := responseCacheTTL, cacheSourceRetention
+	defer func() { responseCacheTTL, cacheSourceRetention = original, originalRetention }()
+
+	base := TranslateRequest{SourceLanguage: "en", TargetLanguage: "es", Text: "Hello."}
+	responseCacheTTL = 60
+	baseKey, _ := responseCacheKey(base)
+
+	tests := []struct {
+		name              string
+		ttl               int
+		retention         string
+		modify            func(request *TranslateRequest)
+		expectedCacheable bool
+		expectedSameKey   bool
+	}{
+		{name: "Same request", ttl: 60, modify: func(r *TranslateRequest) {}, expectedCacheable: true, expectedSameKey: true},
+		{name: "Version is ignored", ttl: 60, modify: func(r *TranslateRequest) { r.Version = "2" }, expectedCacheable: true, expectedSameKey: true},
+		{name: "Other text", ttl: 60, modify: func(r *TranslateRequest) { r.Text = "Goodbye." }, expectedCacheable: true},
+		{name: "Other option", ttl: 60, modify: func(r *TranslateRequest) { r.Style = "casual" }, expectedCacheable: true},
+		{name: "HTML", ttl: 60, modify: func(r *TranslateRequest) { r.Format = formatHTML }, expectedCacheable: true},
+		{name: "Disabled", ttl: 0, modify: func(r *TranslateRequest) {}},
+		{name: "Dry run", ttl: 60, modify: func(r *TranslateRequest) { r.DryRun = true }},
+		{name: "No store", ttl: 60, modify: func(r *TranslateRequest) { r.NoStore = true }},
+		{name: "Document format", ttl: 60, modify: func(r *TranslateRequest) { r.Format = formatCSV }},
+		{name: "Source text omitted", ttl: 60, retention: sourceRetentionOmit, modify: func(r *TranslateRequest) { r.Alignment = true }},
+		{name: "Source text blinded", ttl: 60, retention: sourceRetentionBlind, modify: func(r *TranslateRequest) {}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responseCacheTTL, cacheSourceRetention = tt.ttl, tt.retention
+			request := base
+			tt.modify(&request)
+
+			key, cacheable := responseCacheKey(request)
+			if cacheable != tt.expectedCacheable {
+				t.Fatalf("responseCacheKey() cacheable = %v, expected %v", cacheable, tt.expectedCacheable)
+			}
+			if cacheable && (key == baseKey) != tt.expectedSameKey {
+				t.Errorf("responseCacheKey() = %s, base key %s, expected same key %v", key, baseKey, tt.expectedSameKey)
+			}
+		})
+	}
+}
+
+func TestHandleCachedResponse(t *testing.T) {
+	original := responseCacheTTL
+	responseCacheTTL = 60
+	defer func() { responseCacheTTL = original }()
+
+	var (
+		mu                    sync.Mutex
+		items                 = map[string]map[string]dynamoTypes.AttributeValue{}
+		lookups, translations atomic.Int32
+	)
+	h := newMockHandler(map[string]string{"Hello.": "Hola.", "How are you?": "¿Cómo estás?"})
+	h.dynamoClient = &MockDynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			lookups.Add(1)
+			mu.Lock()
+			defer mu.Unlock()
+			return &dynamodb.GetItemOutput{Item: items[params.Key["hash"].(*dynamoTypes.AttributeValueMemberS).Value]}, nil
+		},
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			items[params.Item["hash"].(*dynamoTypes.AttributeValueMemberS).Value] = params.Item
+			return &dynamodb.PutItemOutput{}, nil
+		},
+		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+	mock := h.translateClient.(*MockTranslateClient)
+	translateText := mock.TranslateTextFunc
+	mock.TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+		translations.Add(1)
+		return translateText(ctx, params, optFns...)
+	}
+
+	body := `{"source_language":"en","target_language":"es","text":"Hello. How are you?"}`
+	first, err := h.handle(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Body: body})
+	if err != nil || first.StatusCode != http.StatusOK {
+		t.Fatalf("handle() = %d %s, %v", first.StatusCode, first.Body, err)
+	}
+	if translations.Load() != 2 {
+		t.Fatalf("handle() made %d translations, expected 2", translations.Load())
+	}
+	responseHash, _ := responseCacheKey(TranslateRequest{SourceLanguage: "en", TargetLanguage: "es", Text: "Hello. How are you?"})
+	mu.Lock()
+	reference := items[responseReferencePrefix+"translated-"+getHashFromText("¿Cómo estás?")+"/"+responseHash]
+	mu.Unlock()
+	if reference == nil || reference["translated_hash"].(*dynamoTypes.AttributeValueMemberS).Value != getHashFromText("¿Cómo estás?") {
+		t.Errorf("handle() cached reference = %v, expected the translated sentence hash", reference)
+	}
+
+	lookups.Store(0)
+	second, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodPost,
+		Body:       body,
+		Headers:    map[string]string{"Accept": "application/vnd.gotranslate.v2+json"},
+	})
+	if err != nil || second.StatusCode != http.StatusOK {
+		t.Fatalf("handle() = %d %s, %v", second.StatusCode, second.Body, err)
+	}
+	if translations.Load() != 2 || lookups.Load() != 1 {
+		t.Errorf("cached handle() made %d translations and %d lookups, expected none and the response lookup", translations.Load()-2, lookups.Load())
+	}
+
+	expected := `{"version":"2","translated_text":"Hola. ¿Cómo estás? ","segments":[],"stats":{"translated_characters":19,"rows":0,"skipped":false,"degraded":false,"low_quality":false,"pivoted":false}}`
+	if second.Body != expected {
+		t.Errorf("cached handle() = %s, expected %s", second.Body, expected)
+	}
+}
+
+func TestResponseReferences(t *testing.T) {
+	item := CacheItem{Hash: "response-1", SourceLanguage: "en", TargetLanguage: "es", Region: "us-east-1", Provider: providerResponseCache, ExpiresAt: 100}
+
+	tests := []struct {
+		name           string
+		request        TranslateRequest
+		translatedText string
+		expected       []string
+	}{
+		{
+			name:           "Sentences of both texts",
+			request:        TranslateRequest{SourceLanguage: "en", TargetLanguage: "es", Text: "Hello. Hello. How are you?"},
+			translatedText: "Hola. Hola. ¿Cómo estás?",
+			expected:       []string{"source/Hello.", "source/How are you?", "translated/Hola.", "translated/¿Cómo estás?"},
+		},
+		{
+			name:           "Text content of HTML",
+			request:        TranslateRequest{SourceLanguage: "en", TargetLanguage: "es", Text: "<p>Hello.</p>", Format: formatHTML},
+			translatedText: "<p>Hola.</p>",
+			expected:       []string{"source/Hello.", "translated/Hola."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			references := responseReferences(item, tt.request, tt.translatedText)
+			if len(references) != len(tt.expected) {
+				t.Fatalf("responseReferences() = %+v, expected %v", references, tt.expected)
+			}
+			for i, expected := range tt.expected {
+				side, sentence, _ := strings.Cut(expected, "/")
+				contentHash := getHashFromText(sentence)
+				reference := references[i]
+				if reference.Hash != responseReferencePrefix+side+"-"+contentHash+"/"+item.Hash || reference.ExpiresAt != item.ExpiresAt {
+					t.Errorf("responseReferences()[%d] = %+v, expected a reference from %s", i, reference, expected)
+				}
+				if (side == "source") != (reference.SourceHash == contentHash) || (side == "translated") != (reference.TranslatedHash == contentHash) {
+					t.Errorf("responseReferences()[%d] content hashes = %s %s, expected %s", i, reference.SourceHash, reference.TranslatedHash, expected)
+				}
+				if responseHash, ok := referencedResponse(reference.Hash); !ok || responseHash != item.Hash {
+					t.Errorf("referencedResponse(%s) = %s, %v", reference.Hash, responseHash, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestCachedResponseChecksDetectedLanguage(t *testing.T) {
+	original, previousAuthorization := responseCacheTTL, claimsAuthorization
+	responseCacheTTL, claimsAuthorization = 60, true
+	defer func() { responseCacheTTL, claimsAuthorization = original, previousAuthorization }()
+
+	var (
+		mu    sync.Mutex
+		items = map[string]map[string]dynamoTypes.AttributeValue{}
+	)
+	h := newMockHandler(map[string]string{"Hello.": "Hola."})
+	h.comprehendClient = newMockLanguageDetector("en")
+	h.dynamoClient = &MockDynamoDBClient{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return &dynamodb.GetItemOutput{Item: items[params.Key["hash"].(*dynamoTypes.AttributeValueMemberS).Value]}, nil
+		},
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			items[params.Item["hash"].(*dynamoTypes.AttributeValueMemberS).Value] = params.Item
+			return &dynamodb.PutItemOutput{}, nil
+		},
+		UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	}
+
+	request := func(languagePairs string) events.APIGatewayProxyResponse {
+		response, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+			HTTPMethod: http.MethodPost,
+			Body:       `{"source_language":"auto","target_language":"es","text":"Hello."}`,
+			RequestContext: events.APIGatewayProxyRequestContext{Authorizer: map[string]any{
+				"custom:language_pairs": languagePairs,
+			}},
+		})
+		if err != nil {
+			t.Fatalf("handle() error = %v", err)
+		}
+		return response
+	}
+
+	if first := request("*:es"); first.StatusCode != http.StatusOK {
+		t.Fatalf("handle() = %d %s, expected the response to be cached", first.StatusCode, first.Body)
+	}
+
+	// The cached response isn't served to a caller not entitled to the detected language
+	restricted := request("fr:es")
+	if restricted.StatusCode != http.StatusForbidden || restricted.Body != "Language pair en to es not allowed" {
+		t.Errorf("handle() = %d %s, expected the detected language to be rejected", restricted.StatusCode, restricted.Body)
+	}
+}
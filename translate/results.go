@@ -0,0 +1,57 @@
+package main
+
+// segmentResult is the translation of a distinct sentence of a request, sent by the stage
+// that found it to the collector of the translations
+type segmentResult struct {
+	// index is the index of the sentence among the distinct sentences
+	index int
+	text  string
+}
+
+// distinctSentences are the sentences of a request without repetitions, each looked up and
+// translated once whatever the number of times it appears
+type distinctSentences struct {
+	texts []string
+	// positions are the indexes in the request of each distinct sentence, in order
+	positions [][]int
+	// count is the number of sentences of the request
+	count int
+}
+
+// dedupSentences returns the distinct sentences of the tokens in the order they first appear
+func dedupSentences(tokens []string) distinctSentences {
+	var (
+		distinct distinctSentences
+		indexes  = make(map[string]int, len(tokens))
+	)
+	for position, token := range tokens {
+		index, ok := indexes[token]
+		if !ok {
+			index = len(distinct.texts)
+			indexes[token] = index
+			distinct.texts = append(distinct.texts, token)
+			distinct.positions = append(distinct.positions, nil)
+		}
+		distinct.positions[index] = append(distinct.positions[index], position)
+	}
+	distinct.count = len(tokens)
+	return distinct
+}
+
+// position returns the index in the request of the first occurrence of the distinct sentence
+func (d distinctSentences) position(index int) int {
+	return d.positions[index][0]
+}
+
+// collect reads the results until the channel is closed and returns the translations of the
+// tokens in their order, each result filling every position of its sentence. Only the
+// collector writes the translations, so the stages never share them.
+func (d distinctSentences) collect(results <-chan segmentResult) []string {
+	translated := make([]string, d.count)
+	for result := range results {
+		for _, position := range d.positions[result.index] {
+			translated[position] = result.text
+		}
+	}
+	return translated
+}
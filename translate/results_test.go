@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestDedupSentences(t *testing.T) {
+	tests := []struct {
+		name              string
+		tokens            []string
+		expectedTexts     []string
+		expectedPositions [][]int
+	}{
+		{
+			name: "No sentences",
+		},
+		{
+			name:              "Distinct sentences",
+			tokens:            []string{"Hello.", "Goodbye."},
+			expectedTexts:     []string{"Hello.", "Goodbye."},
+			expectedPositions: [][]int{{0}, {1}},
+		},
+		{
+			name:              "Repeated sentences",
+			tokens:            []string{"Hello.", "Goodbye.", "Hello.", "Hello.", "Goodbye."},
+			expectedTexts:     []string{"Hello.", "Goodbye."},
+			expectedPositions: [][]int{{0, 2, 3}, {1, 4}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dedupSentences(tt.tokens)
+			if !slices.Equal(got.texts, tt.expectedTexts) || !slices.EqualFunc(got.positions, tt.expectedPositions, slices.Equal) || got.count != len(tt.tokens) {
+				t.Errorf("dedupSentences() = %v %v %d, expected %v %v %d", got.texts, got.positions, got.count, tt.expectedTexts, tt.expectedPositions, len(tt.tokens))
+			}
+		})
+	}
+}
+
+func TestCollectResults(t *testing.T) {
+	distinct := dedupSentences([]string{"a", "b", "a", "c", "b", "a"})
+
+	// The results arrive from concurrent stages in any order
+	results := make(chan segmentResult)
+	var senders sync.WaitGroup
+	for index, text := range distinct.texts {
+		senders.Add(1)
+		go func() {
+			defer senders.Done()
+			results <- segmentResult{index: index, text: strings.ToUpper(text)}
+		}()
+	}
+	go func() {
+		senders.Wait()
+		close(results)
+	}()
+
+	expected := []string{"A", "B", "A", "C", "B", "A"}
+	if got := distinct.collect(results); !slices.Equal(got, expected) {
+		t.Errorf("collect() = %q, expected %q", got, expected)
+	}
+}
+
+func TestTranslateSentencesOrder(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls = map[string]int{}
+	)
+	h := newMockHandler(nil)
+	h.translateClient.(*MockTranslateClient).TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+		mu.Lock()
+		calls[*params.Text]++
+		mu.Unlock()
+		// The first sentences are translated last
+		time.Sleep(time.Duration(10-len(*params.Text)) * time.Millisecond)
+		return &translate.TranslateTextOutput{TranslatedText: aws.String(strings.ToUpper(*params.Text))}, nil
+	}
+
+	tokens := []string{"a.", "bb.", "a.", "ccc.", "dddd.", "bb.", "a."}
+	got, err := h.translateSentences(context.Background(), "en", "es", tokens)
+	if err != nil {
+		t.Fatalf("translateSentences() error = %v", err)
+	}
+	if expected := []string{"A.", "BB.", "A.", "CCC.", "DDDD.", "BB.", "A."}; !slices.Equal(got, expected) {
+		t.Errorf("translateSentences() = %q, expected %q", got, expected)
+	}
+	if expected := map[string]int{"a.": 1, "bb.": 1, "ccc.": 1, "dddd.": 1}; !maps.Equal(calls, expected) {
+		t.Errorf("translateSentences() called the provider %v, expected %v", calls, expected)
+	}
+}
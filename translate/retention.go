@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// sourceRetentionOmit, sourceRetentionTruncate and sourceRetentionBlind are the modes
+	// the source text of the cache items is stored with: not at all, its first characters
+	// only, or with its letters and digits masked. The full text is stored otherwise.
+	sourceRetentionOmit     = "omit"
+	sourceRetentionTruncate = "truncate"
+	sourceRetentionBlind    = "blind"
+
+	defaultCacheSourceTruncateLength = 32
+
+	// blindedLetter and blindedDigit replace the letters and digits of a blinded source text
+	blindedLetter = 'x'
+	blindedDigit  = '0'
+)
+
+var (
+	// cacheSourceRetention is the mode the source text of the cache items is stored with,
+	// the full text when empty. Lookups only need the hash, so hits keep working whatever
+	// the mode.
+	cacheSourceRetention string
+	// cacheSourceTruncateLength is the number of characters of the source text kept by the
+	// truncate mode
+	cacheSourceTruncateLength int
+)
+
+// withSourceRetention applies the retention mode of the deployment to the source text of an
+// item about to be stored. The content hashes and lengths are set beforehand, so erasure
+// and usage reports still see the full text.
+func withSourceRetention(item CacheItem) CacheItem {
+	if item.SourceRetention != "" {
+		// The item was read back from the table, its text is already retained
+		return item
+	}
+
+	switch cacheSourceRetention {
+	case sourceRetentionOmit:
+		item.SourceText = ""
+	case sourceRetentionTruncate:
+		item.SourceText = truncateSourceText(item.SourceText, cacheSourceTruncateLength)
+	case sourceRetentionBlind:
+		item.SourceText = blindSourceText(item.SourceText)
+	default:
+		return item
+	}
+	item.SourceRetention = cacheSourceRetention
+	return item
+}
+
+// truncateSourceText keeps the first characters of a text, marking the cut with an ellipsis
+func truncateSourceText(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length]) + "…"
+}
+
+// blindSourceText masks the letters and digits of a text, keeping its shape: the length of
+// its words and its punctuation
+func blindSourceText(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r):
+			return blindedLetter
+		case unicode.IsDigit(r):
+			return blindedDigit
+		default:
+			return r
+		}
+	}, text)
+}
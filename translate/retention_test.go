@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestStoreCacheItemSourceRetention(t *testing.T) {
+	sourceText := "Your order 1234 ships to Springfield on Monday."
+
+	tests := []struct {
+		name               string
+		retention          string
+		expectedSourceText string
+		expectedStored     bool
+	}{
+		{
+			name:               "Full text",
+			retention:          "",
+			expectedSourceText: sourceText,
+			expectedStored:     true,
+		},
+		{
+			name:           "Omitted",
+			retention:      sourceRetentionOmit,
+			expectedStored: false,
+		},
+		{
+			name:               "Truncated",
+			retention:          sourceRetentionTruncate,
+			expectedSourceText: "Your order 1234 ships…",
+			expectedStored:     true,
+		},
+		{
+			name:               "Blinded",
+			retention:          sourceRetentionBlind,
+			expectedSourceText: "xxxx xxxxx 0000 xxxxx xx xxxxxxxxxxx xx xxxxxx.",
+			expectedStored:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousRetention, previousLength := cacheSourceRetention, cacheSourceTruncateLength
+			cacheSourceRetention, cacheSourceTruncateLength = tt.retention, 21
+			defer func() { cacheSourceRetention, cacheSourceTruncateLength = previousRetention, previousLength }()
+
+			var stored map[string]dynamoTypes.AttributeValue
+			h := &handler{dynamoClient: &MockDynamoDBClient{
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					stored = params.Item
+					return &dynamodb.PutItemOutput{}, nil
+				},
+			}}
+
+			item := CacheItem{
+				Hash:           getCacheKey("en", "es", sourceText),
+				SourceText:     sourceText,
+				TranslatedText: "Su pedido 1234 se envía a Springfield el lunes.",
+				SourceLanguage: "en",
+				TargetLanguage: "es",
+			}
+			if err := h.storeCacheItem(context.Background(), item); err != nil {
+				t.Fatalf("storeCacheItem() error = %v", err)
+			}
+
+			if _, ok := stored["source_text"]; ok != tt.expectedStored {
+				t.Errorf("source_text stored = %v, expected %v", ok, tt.expectedStored)
+			}
+			got, ok := cacheItemFromAttributes(stored)
+			if !ok {
+				t.Fatal("cacheItemFromAttributes() = false, expected the stored item to be read back")
+			}
+			if got.SourceText != tt.expectedSourceText {
+				t.Errorf("source text = %q, expected %q", got.SourceText, tt.expectedSourceText)
+			}
+			if got.SourceRetention != tt.retention {
+				t.Errorf("source retention = %q, expected %q", got.SourceRetention, tt.retention)
+			}
+			if got.TranslatedText != item.TranslatedText {
+				t.Errorf("translated text = %q, expected %q", got.TranslatedText, item.TranslatedText)
+			}
+			// The hash and length still describe the full text, for erasure and usage reports
+			if got.SourceHash != getHashFromText(sourceText) || got.SourceCharacters != len(sourceText) {
+				t.Errorf("source hash and length = %s %d, expected those of the full text", got.SourceHash, got.SourceCharacters)
+			}
+		})
+	}
+}
+
+func TestSourceRetentionCacheHit(t *testing.T) {
+	previousRetention := cacheSourceRetention
+	cacheSourceRetention = sourceRetentionOmit
+	defer func() { cacheSourceRetention = previousRetention }()
+
+	stored := map[string]map[string]dynamoTypes.AttributeValue{}
+	translations := 0
+	h := newMockHandler(map[string]string{"Hello": "Hola"})
+	h.dynamoClient = &MockDynamoDBClient{
+		PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			hash := params.Item["hash"].(*dynamoTypes.AttributeValueMemberS).Value
+			stored[hash] = params.Item
+			return &dynamodb.PutItemOutput{}, nil
+		},
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			hash := params.Key["hash"].(*dynamoTypes.AttributeValueMemberS).Value
+			return &dynamodb.GetItemOutput{Item: stored[hash]}, nil
+		},
+	}
+	translateText := h.translateClient.(*MockTranslateClient).TranslateTextFunc
+	h.translateClient.(*MockTranslateClient).TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+		translations++
+		return translateText(ctx, params, optFns...)
+	}
+
+	for range 2 {
+		got, err := h.translateText(context.Background(), "en", "es", "Hello")
+		if err != nil {
+			t.Fatalf("translateText() error = %v", err)
+		}
+		if got != "Hola " {
+			t.Errorf("translateText() = %q, expected %q", got, "Hola ")
+		}
+	}
+	if translations != 1 {
+		t.Errorf("translated %d times, expected the second request to be a cache hit", translations)
+	}
+}
+
+func TestBlindSourceText(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{text: "Call 555-0100, ask for Ana!", expected: "xxxx 000-0000, xxx xxx xxx!"},
+		{text: "Grüße {name}", expected: "xxxxx {xxxx}"},
+		{text: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := blindSourceText(tt.text); got != tt.expected {
+				t.Errorf("blindSourceText() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+const (
+	cacheJobRetranslate = "retranslate"
+
+	// retranslationLowRating and retranslationOutdated are the reasons a cache item was
+	// re-translated, recorded on the new item
+	retranslationLowRating = "low-rating"
+	retranslationOutdated  = "outdated"
+
+	// defaultMinRatings is the number of ratings an item needs before its average is trusted
+	defaultMinRatings = 3
+	// defaultRetranslationRate is the number of items re-translated per second
+	defaultRetranslationRate = 5
+)
+
+// retranslateCache re-translates the cache items rated poorly, or translated by the provider
+// before the given date, with the provider and settings routing the pair today. The new
+// translation replaces the item, along with its provenance and ratings. Items stop matching
+// once re-translated, so a scan that runs out of time is simply restarted by the next run.
+//
+// Only the plain translations of sentences can be translated again: the items of styled,
+// glossary or other keyed translations, items stored without their full source text, failures,
+// and the translations imported or approved by reviewers are left alone.
+func (h *handler) retranslateCache(ctx context.Context, request CacheJobRequest) (CacheJobResponse, error) {
+	if request.MaxRating <= 0 && request.TranslatedBefore == "" {
+		return CacheJobResponse{}, fmt.Errorf("max_rating or translated_before is required")
+	}
+	var before time.Time
+	if request.TranslatedBefore != "" {
+		var err error
+		if before, err = time.Parse(time.DateOnly, request.TranslatedBefore); err != nil {
+			return CacheJobResponse{}, fmt.Errorf("translated_before must be a date: %w", err)
+		}
+	}
+	minRatings := int64(request.MinRatings)
+	if minRatings <= 0 {
+		minRatings = defaultMinRatings
+	}
+	rate := request.Rate
+	if rate <= 0 {
+		rate = defaultRetranslationRate
+	}
+
+	reason := func(item CacheItem) string {
+		if item.Ratings >= minRatings && float64(item.RatingSum)/float64(item.Ratings) <= request.MaxRating {
+			return retranslationLowRating
+		}
+		if !before.IsZero() && item.CreatedAt != 0 && item.CreatedAt < before.Unix() && (request.Provider == "" || item.Provider == request.Provider) {
+			return retranslationOutdated
+		}
+		return ""
+	}
+
+	response := CacheJobResponse{Action: request.Action}
+	pace := time.NewTicker(time.Second / time.Duration(rate))
+	defer pace.Stop()
+
+	paginator := dynamodb.NewScanPaginator(h.dynamoClient, &dynamodb.ScanInput{TableName: aws.String(translateTableName)})
+	for paginator.HasMorePages() {
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < compactionDeadlineMargin {
+			response.Partial = true
+			break
+		}
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return response, fmt.Errorf("error scanning cache: %w", err)
+		}
+
+		for _, attributes := range page.Items {
+			item, ok := cacheItemFromAttributes(attributes)
+			if !ok {
+				continue
+			}
+			response.Scanned++
+
+			why := reason(item)
+			if why == "" || item.Failure != "" || item.Authoritative || item.Provider == providerTMX {
+				continue
+			}
+			if item.OverflowKey != "" {
+				if item, err = h.resolveCacheItem(ctx, item); err != nil {
+					log.Printf("Error loading overflowed cache item %s: %v", item.Hash, err)
+					response.Skipped++
+					continue
+				}
+			}
+			if item.SourceRetention != "" || item.Hash != getCacheKey(item.SourceLanguage, item.TargetLanguage, item.SourceText) {
+				response.Skipped++
+				continue
+			}
+
+			select {
+			case <-pace.C:
+			case <-ctx.Done():
+				return response, ctx.Err()
+			}
+			if err := h.retranslateItem(ctx, item, why); err != nil {
+				log.Printf("Error re-translating cache item %s: %v", item.Hash, err)
+				response.Skipped++
+				continue
+			}
+			response.Items++
+		}
+	}
+
+	log.Printf("Re-translated %d of %d cache items, skipped %d, partial %v", response.Items, response.Scanned, response.Skipped, response.Partial)
+	return response, nil
+}
+
+// retranslateItem translates the source text of the item again and replaces the item
+func (h *handler) retranslateItem(ctx context.Context, item CacheItem, reason string) error {
+	translateClient, route := h.translationRoute(ctx, item.SourceLanguage, item.TargetLanguage)
+	ctx = withRoute(ctx, route)
+	var translateRegion *string
+	if route.Provider == providerAWSTranslate {
+		ctx, translateRegion = withTranslateRegion(ctx, region)
+	}
+
+	translated, err := translateShielded(ctx, translateClient, item.SourceText, item.SourceLanguage, item.TargetLanguage)
+	if err != nil {
+		return err
+	}
+
+	replacement := CacheItem{
+		Hash:           item.Hash,
+		TranslatedText: translated.TranslatedText,
+		SourceText:     item.SourceText,
+		SourceLanguage: item.SourceLanguage,
+		TargetLanguage: item.TargetLanguage,
+		Region:         region,
+		Provider:       route.Provider,
+		Route:          route.Pair,
+		Retranslation:  reason,
+	}
+	if translateRegion != nil {
+		replacement.TranslateRegion = *translateRegion
+	}
+	return h.storeCacheItem(ctx, replacement)
+}
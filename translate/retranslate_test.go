@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestRetranslateCache(t *testing.T) {
+	item := func(source, targetLanguage, provider string, createdAt time.Time, ratings, sum int) map[string]dynamoTypes.AttributeValue {
+		return map[string]dynamoTypes.AttributeValue{
+			"hash":            &dynamoTypes.AttributeValueMemberS{Value: getCacheKey("en", targetLanguage, source)},
+			"source_language": &dynamoTypes.AttributeValueMemberS{Value: "en"},
+			"target_language": &dynamoTypes.AttributeValueMemberS{Value: "es"},
+			"source_text":     &dynamoTypes.AttributeValueMemberS{Value: source},
+			"translated_text": &dynamoTypes.AttributeValueMemberS{Value: "old " + source},
+			"provider":        &dynamoTypes.AttributeValueMemberS{Value: provider},
+			"created_at":      &dynamoTypes.AttributeValueMemberN{Value: strconv.FormatInt(createdAt.Unix(), 10)},
+			"ratings":         &dynamoTypes.AttributeValueMemberN{Value: strconv.Itoa(ratings)},
+			"rating_sum":      &dynamoTypes.AttributeValueMemberN{Value: strconv.Itoa(sum)},
+		}
+	}
+	old := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	approved := item("Approved", "es", providerPostEdit, recent, 3, 3)
+	approved["authoritative"] = &dynamoTypes.AttributeValueMemberBOOL{Value: true}
+	items := []map[string]dynamoTypes.AttributeValue{
+		item("Poorly rated", "es", providerAWSTranslate, recent, 3, 4),
+		item("Well rated", "es", providerAWSTranslate, recent, 3, 14),
+		item("Too few ratings", "es", providerAWSTranslate, recent, 1, 1),
+		item("Old model", "es", providerSageMaker, old, 0, 0),
+		item("Old AWS Translate", "es", providerAWSTranslate, old, 0, 0),
+		// A formal translation is keyed apart from the plain one
+		item("Formal", "es+formal", providerAWSTranslate, recent, 3, 3),
+		item("Imported", "es", providerTMX, recent, 3, 3),
+		approved,
+	}
+
+	tests := []struct {
+		name             string
+		request          CacheJobRequest
+		expectedReplaced []string
+		expectedResponse CacheJobResponse
+		wantErr          bool
+	}{
+		{
+			name:             "Poorly rated items",
+			request:          CacheJobRequest{Action: cacheJobRetranslate, MaxRating: 2, Rate: 1000},
+			expectedReplaced: []string{"Poorly rated"},
+			expectedResponse: CacheJobResponse{Action: cacheJobRetranslate, Items: 1, Scanned: 8, Skipped: 1},
+		},
+		{
+			name:             "Items of an old model",
+			request:          CacheJobRequest{Action: cacheJobRetranslate, TranslatedBefore: "2026-01-01", Provider: providerSageMaker, Rate: 1000},
+			expectedReplaced: []string{"Old model"},
+			expectedResponse: CacheJobResponse{Action: cacheJobRetranslate, Items: 1, Scanned: 8},
+		},
+		{
+			name:             "Single rating",
+			request:          CacheJobRequest{Action: cacheJobRetranslate, MaxRating: 2, MinRatings: 1, TranslatedBefore: "2026-01-01", Rate: 1000},
+			expectedReplaced: []string{"Poorly rated", "Too few ratings", "Old model", "Old AWS Translate"},
+			expectedResponse: CacheJobResponse{Action: cacheJobRetranslate, Items: 4, Scanned: 8, Skipped: 1},
+		},
+		{
+			name:    "Criteria are required",
+			request: CacheJobRequest{Action: cacheJobRetranslate},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid date",
+			request: CacheJobRequest{Action: cacheJobRetranslate, TranslatedBefore: "January"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var replaced []CacheItem
+			h := newMockHandler(nil)
+			h.translateClient.(*MockTranslateClient).TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				return &translate.TranslateTextOutput{TranslatedText: aws.String("new " + aws.ToString(params.Text))}, nil
+			}
+			h.dynamoClient = &MockDynamoDBClient{
+				ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+					return &dynamodb.ScanOutput{Items: items}, nil
+				},
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					item, _ := cacheItemFromAttributes(params.Item)
+					replaced = append(replaced, item)
+					return &dynamodb.PutItemOutput{}, nil
+				},
+			}
+
+			got, err := h.handleCacheJob(context.Background(), tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("handleCacheJob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got != tt.expectedResponse {
+				t.Errorf("handleCacheJob() = %+v, expected %+v", got, tt.expectedResponse)
+			}
+			var sources []string
+			for _, item := range replaced {
+				sources = append(sources, item.SourceText)
+				if item.TranslatedText != "new "+item.SourceText || item.Provider != providerAWSTranslate || item.Retranslation == "" || item.Ratings != 0 {
+					t.Errorf("PutItem() = %+v, expected a new translation of AWS Translate", item)
+				}
+			}
+			if !slices.Equal(sources, tt.expectedReplaced) {
+				t.Errorf("PutItem() sources = %v, expected %v", sources, tt.expectedReplaced)
+			}
+		})
+	}
+}
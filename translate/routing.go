@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"log"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+)
+
+// defaultRoutingTableTTL is the number of seconds the rules of the routing table are cached
+const defaultRoutingTableTTL = 300
+
+var (
+	// routingRules are the configured routes of the language pairs by source:target pair,
+	// either side may be *
+	routingRules map[string]routingRule
+	// routingTableName is the DynamoDB table holding routing rules on top of the configured
+	// ones, empty to route with the configured rules only
+	routingTableName string
+	// routingTableTTL is the number of seconds the rules of the routing table are cached
+	routingTableTTL int
+)
+
+// routingRule routes the translations of a language pair to a provider with its settings
+type routingRule struct {
+	// Pair is the source:target language pair of the rule, either side may be *
+	Pair     string
+	Provider string
+	// Formality and Profanity are the provider settings of the translations, overridden by
+	// the settings of the style of the request
+	Formality types.Formality
+	Profanity types.Profanity
+}
+
+// routingCache caches the rules of the routing table
+type routingCache struct {
+	mu        sync.Mutex
+	rules     map[string]routingRule
+	refreshAt time.Time
+}
+
+type routeKey struct{}
+
+// routingProviders are the providers a rule may route to
+var routingProviders = []string{providerAWSTranslate, providerSageMaker, providerDeepL}
+
+// parseRoutingRules parses a list of rules separated by commas, each routing a source:target
+// pair to a provider followed by its settings, such as ja:en=deepl;formality=FORMAL
+func parseRoutingRules(value string) map[string]routingRule {
+	rules := make(map[string]routingRule)
+	for _, rule := range strings.Split(value, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		pair, route, _ := strings.Cut(rule, "=")
+		provider, settings, _ := strings.Cut(route, ";")
+		parsed, ok := newRoutingRule(pair, provider)
+		if !ok {
+			log.Printf("Ignoring invalid routing rule %q", rule)
+			continue
+		}
+		for _, setting := range strings.Split(settings, ";") {
+			name, value, _ := strings.Cut(setting, "=")
+			switch strings.TrimSpace(name) {
+			case "formality":
+				parsed.Formality = types.Formality(strings.TrimSpace(value))
+			case "profanity":
+				parsed.Profanity = types.Profanity(strings.TrimSpace(value))
+			}
+		}
+		rules[parsed.Pair] = parsed
+	}
+	return rules
+}
+
+// newRoutingRule returns the rule routing the pair to the provider, reporting whether both
+// are valid
+func newRoutingRule(pair, provider string) (routingRule, bool) {
+	source, target, found := strings.Cut(strings.TrimSpace(pair), ":")
+	source, target, provider = strings.TrimSpace(source), strings.TrimSpace(target), strings.TrimSpace(provider)
+	if !found || source == "" || target == "" {
+		return routingRule{}, false
+	}
+	for _, known := range routingProviders {
+		if provider == known {
+			return routingRule{Pair: languagePair(source, target), Provider: provider}, true
+		}
+	}
+	return routingRule{}, false
+}
+
+// matchRoutingRule returns the most specific rule of the language pair: the rule of the pair
+// itself, then of the target language, then of the source language, then the catch-all
+func matchRoutingRule(rules map[string]routingRule, sourceLanguage, targetLanguage string) (routingRule, bool) {
+	for _, pair := range []string{
+		languagePair(sourceLanguage, targetLanguage),
+		languagePair(anyLanguage, targetLanguage),
+		languagePair(sourceLanguage, anyLanguage),
+		languagePair(anyLanguage, anyLanguage),
+	} {
+		if rule, ok := rules[pair]; ok {
+			return rule, true
+		}
+	}
+	return routingRule{}, false
+}
+
+// translationRoute returns the client translating the language pair and the route recorded
+// in the provenance of its translations. A pair without a rule, or whose rule names a
+// provider that isn't configured or can't take the pair, is routed by translationProvider.
+// Gender-neutral translations need the inclusive terminology of AWS Translate.
+func (h *handler) translationRoute(ctx context.Context, sourceLanguage, targetLanguage string) (TranslateClient, routingRule) {
+	if inclusiveTerminology(ctx, targetLanguage) != "" {
+		return h.translateClient, routingRule{Provider: providerAWSTranslate}
+	}
+	if rule, ok := matchRoutingRule(h.routingRules(ctx), sourceLanguage, targetLanguage); ok {
+		var client TranslateClient
+		switch rule.Provider {
+		case providerAWSTranslate:
+			client = h.translateClient
+		case providerSageMaker:
+			// Our own model never detects the source language
+			if sourceLanguage != autoDetectLanguage {
+				client = h.sageMakerClient
+			}
+		case providerDeepL:
+			client = h.deepLClient
+		}
+		if client != nil {
+			return client, rule
+		}
+	}
+
+	client, provider := h.translationProvider(sourceLanguage, targetLanguage)
+	return client, routingRule{Provider: provider}
+}
+
+// routingRules returns the configured rules overridden by those of the routing table, which
+// are scanned again once their TTL has passed. The last rules read are kept when the table
+// can't be read.
+func (h *handler) routingRules(ctx context.Context) map[string]routingRule {
+	if routingTableName == "" {
+		return routingRules
+	}
+
+	h.routes.mu.Lock()
+	defer h.routes.mu.Unlock()
+	if h.routes.rules != nil && time.Now().Before(h.routes.refreshAt) {
+		return h.routes.rules
+	}
+
+	// A failed scan isn't retried before the TTL either, so an outage doesn't add a scan to
+	// every segment
+	h.routes.refreshAt = time.Now().Add(time.Duration(routingTableTTL) * time.Second)
+	tableRules, err := h.scanRoutingTable(ctx)
+	if err != nil {
+		log.Printf("Error reading routing table %s: %v", routingTableName, err)
+		if h.routes.rules == nil {
+			return routingRules
+		}
+		return h.routes.rules
+	}
+
+	rules := maps.Clone(routingRules)
+	if rules == nil {
+		rules = make(map[string]routingRule, len(tableRules))
+	}
+	maps.Copy(rules, tableRules)
+	h.routes.rules = rules
+	return rules
+}
+
+// scanRoutingTable reads the rules of the routing table, items holding a pair, a provider
+// and optionally a formality and a profanity setting
+func (h *handler) scanRoutingTable(ctx context.Context) (map[string]routingRule, error) {
+	rules := make(map[string]routingRule)
+	paginator := dynamodb.NewScanPaginator(h.dynamoClient, &dynamodb.ScanInput{
+		TableName: aws.String(routingTableName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			pair, _ := item["pair"].(*dynamoTypes.AttributeValueMemberS)
+			provider, _ := item["provider"].(*dynamoTypes.AttributeValueMemberS)
+			if pair == nil || provider == nil {
+				continue
+			}
+			rule, ok := newRoutingRule(pair.Value, provider.Value)
+			if !ok {
+				log.Printf("Ignoring invalid routing rule %s=%s", pair.Value, provider.Value)
+				continue
+			}
+			if value, ok := item["formality"].(*dynamoTypes.AttributeValueMemberS); ok {
+				rule.Formality = types.Formality(value.Value)
+			}
+			if value, ok := item["profanity"].(*dynamoTypes.AttributeValueMemberS); ok {
+				rule.Profanity = types.Profanity(value.Value)
+			}
+			rules[rule.Pair] = rule
+		}
+	}
+	return rules, nil
+}
+
+// withRoute returns a context translating with the settings of the route
+func withRoute(ctx context.Context, rule routingRule) context.Context {
+	if rule.Formality == "" && rule.Profanity == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, routeKey{}, rule)
+}
+
+// routeSettings returns the provider settings of the route of the translation, nil when it
+// has none
+func routeSettings(ctx context.Context) *types.TranslationSettings {
+	rule, ok := ctx.Value(routeKey{}).(routingRule)
+	if !ok {
+		return nil
+	}
+	return &types.TranslationSettings{Formality: rule.Formality, Profanity: rule.Profanity}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+)
+
+func TestParseRoutingRules(t *testing.T) {
+	got := parseRoutingRules(" ja:en=deepl;formality=FORMAL, en:ja=deepl,*:*=aws-translate;profanity=MASK, de:fr=unknown,invalid")
+	expected := map[string]routingRule{
+		"ja:en": {Pair: "ja:en", Provider: providerDeepL, Formality: types.FormalityFormal},
+		"en:ja": {Pair: "en:ja", Provider: providerDeepL},
+		"*:*":   {Pair: "*:*", Provider: providerAWSTranslate, Profanity: types.ProfanityMask},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("parseRoutingRules() = %+v, expected %+v", got, expected)
+	}
+}
+
+func TestMatchRoutingRule(t *testing.T) {
+	rules := parseRoutingRules("ja:en=deepl,*:ko=sagemaker,fr:*=deepl,*:*=aws-translate")
+
+	tests := []struct {
+		source   string
+		target   string
+		expected string
+	}{
+		{source: "ja", target: "en", expected: "ja:en"},
+		{source: "fr", target: "ko", expected: "*:ko"},
+		{source: "fr", target: "de", expected: "fr:*"},
+		{source: "en", target: "es", expected: "*:*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source+":"+tt.target, func(t *testing.T) {
+			got, ok := matchRoutingRule(rules, tt.source, tt.target)
+			if !ok || got.Pair != tt.expected {
+				t.Errorf("matchRoutingRule() = %q, expected %q", got.Pair, tt.expected)
+			}
+		})
+	}
+
+	if _, ok := matchRoutingRule(parseRoutingRules("ja:en=deepl"), "en", "es"); ok {
+		t.Error("matchRoutingRule() matched a pair without a rule")
+	}
+}
+
+func TestHandleRoutedRequest(t *testing.T) {
+	tests := []struct {
+		name             string
+		rules            string
+		tableRules       []map[string]dynamoTypes.AttributeValue
+		body             string
+		expectedBody     string
+		expectedProvider string
+		expectedRoute    string
+	}{
+		{
+			name:             "Pair routed to DeepL",
+			rules:            "ja:en=deepl,*:*=aws-translate",
+			body:             `{"source_language":"ja","target_language":"en","text":"こんにちは"}`,
+			expectedBody:     `{"translated_text":"Hello from DeepL "}`,
+			expectedProvider: providerDeepL,
+			expectedRoute:    "ja:en",
+		},
+		{
+			name:             "Other pairs routed to AWS Translate",
+			rules:            "ja:en=deepl,*:*=aws-translate",
+			body:             `{"source_language":"en","target_language":"es","text":"Hello"}`,
+			expectedBody:     `{"translated_text":"Hola "}`,
+			expectedProvider: providerAWSTranslate,
+			expectedRoute:    "*:*",
+		},
+		{
+			name:  "Rule of the routing table",
+			rules: "*:*=aws-translate",
+			tableRules: []map[string]dynamoTypes.AttributeValue{{
+				"pair":     &dynamoTypes.AttributeValueMemberS{Value: "en:es"},
+				"provider": &dynamoTypes.AttributeValueMemberS{Value: providerDeepL},
+			}},
+			body:             `{"source_language":"en","target_language":"es","text":"Hello"}`,
+			expectedBody:     `{"translated_text":"Hello from DeepL "}`,
+			expectedProvider: providerDeepL,
+			expectedRoute:    "en:es",
+		},
+		{
+			name:             "Pair without a rule",
+			body:             `{"source_language":"en","target_language":"es","text":"Hello"}`,
+			expectedBody:     `{"translated_text":"Hola "}`,
+			expectedProvider: providerAWSTranslate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousRules, previousTable, previousTTL := routingRules, routingTableName, routingTableTTL
+			routingRules, routingTableName, routingTableTTL = parseRoutingRules(tt.rules), "", defaultRoutingTableTTL
+			if tt.tableRules != nil {
+				routingTableName = "Routes"
+			}
+			defer func() { routingRules, routingTableName, routingTableTTL = previousRules, previousTable, previousTTL }()
+
+			deepL := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"translations":[{"text":"Hello from DeepL"}]}`))
+			}))
+			defer deepL.Close()
+
+			var (
+				mu       sync.Mutex
+				provider string
+				route    string
+				scans    int
+			)
+			h := newMockHandler(map[string]string{"Hello": "Hola"})
+			h.deepLClient = newDeepLTranslateClient(deepL.Client(), "key", deepL.URL)
+			mock := h.dynamoClient.(*MockDynamoDBClient)
+			mock.PutItemFunc = func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if value, ok := params.Item["provider"].(*dynamoTypes.AttributeValueMemberS); ok {
+					provider = value.Value
+				}
+				if value, ok := params.Item["route"].(*dynamoTypes.AttributeValueMemberS); ok {
+					route = value.Value
+				}
+				return &dynamodb.PutItemOutput{}, nil
+			}
+			mock.ScanFunc = func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+				scans++
+				return &dynamodb.ScanOutput{Items: tt.tableRules}, nil
+			}
+
+			for range 2 {
+				got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Body: tt.body})
+				if err != nil {
+					t.Fatalf("handle() error = %v", err)
+				}
+				if got.StatusCode != http.StatusOK || got.Body != tt.expectedBody {
+					t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, http.StatusOK, tt.expectedBody)
+				}
+			}
+			if provider != tt.expectedProvider || route != tt.expectedRoute {
+				t.Errorf("PutItem() provider = %q, route = %q, expected %q and %q", provider, route, tt.expectedProvider, tt.expectedRoute)
+			}
+			// The rules of the table are cached for their TTL
+			if tt.tableRules != nil && scans != 1 {
+				t.Errorf("Scan() called %d times, expected once", scans)
+			}
+		})
+	}
+}
+
+func TestTranslationSettingsOfRoute(t *testing.T) {
+	ctx := withRoute(context.Background(), routingRule{Formality: types.FormalityInformal, Profanity: types.ProfanityMask})
+	ctx = withStyle(ctx, styleLegal)
+
+	got := translationSettings(ctx)
+	expected := &types.TranslationSettings{Formality: types.FormalityFormal, Profanity: types.ProfanityMask}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("translationSettings() = %+v, expected the formality of the style and the profanity of the route", got)
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+)
+
+const (
+	// sageMakerFormatNLLB is a multilingual model taking FLORES-200 language codes, such as
+	// the NLLB models of the Hugging Face inference containers
+	sageMakerFormatNLLB = "nllb"
+	// sageMakerFormatMarian is a model of a single language pair, such as the Marian opus-mt
+	// models, taking the text alone
+	sageMakerFormatMarian = "marian"
+
+	// sageMakerSigningName is the service name of the SageMaker runtime requests signatures
+	sageMakerSigningName = "sagemaker"
+	// maxSageMakerResponseSize is the size limit of an endpoint response
+	maxSageMakerResponseSize = 6 * 1024 * 1024
+)
+
+var (
+	// sageMakerEndpointName is the real-time endpoint hosting our translation model, empty to
+	// translate every language pair with AWS Translate
+	sageMakerEndpointName string
+	// sageMakerModelFormat selects the request and response adapter of the model
+	sageMakerModelFormat string
+	// sageMakerLanguagePairs are the language pairs translated by the endpoint, formatted as
+	// source:target,source:target, empty for every pair
+	sageMakerLanguagePairs map[string]bool
+	// sageMakerLanguageCodes maps our language codes to the codes of the model on top of the
+	// defaults of the format, formatted as language=code,language=code like the region tables
+	sageMakerLanguageCodes map[string]string
+)
+
+// nllbLanguageCodes are the FLORES-200 codes of the languages we translate most
+var nllbLanguageCodes = map[string]string{
+	"am":    "amh_Ethi",
+	"ar":    "arb_Arab",
+	"de":    "deu_Latn",
+	"en":    "eng_Latn",
+	"es":    "spa_Latn",
+	"fr":    "fra_Latn",
+	"ha":    "hau_Latn",
+	"hi":    "hin_Deva",
+	"ig":    "ibo_Latn",
+	"it":    "ita_Latn",
+	"ja":    "jpn_Jpan",
+	"ko":    "kor_Hang",
+	"nl":    "nld_Latn",
+	"pt":    "por_Latn",
+	"ru":    "rus_Cyrl",
+	"so":    "som_Latn",
+	"sw":    "swh_Latn",
+	"yo":    "yor_Latn",
+	"zh":    "zho_Hans",
+	"zh-TW": "zho_Hant",
+	"zu":    "zul_Latn",
+}
+
+// sageMakerAdapter converts the translations of a model format to and from the payloads of
+// its endpoint
+type sageMakerAdapter struct {
+	// languageCodes maps our language codes to the codes of the model, nil when the model
+	// doesn't take any
+	languageCodes map[string]string
+	// request returns the payload translating the text between the model language codes
+	request func(text, sourceCode, targetCode string) any
+}
+
+// sageMakerAdapters are the adapters of the supported model formats. Both formats answer
+// with the translation_text list of the Hugging Face translation pipeline.
+var sageMakerAdapters = map[string]sageMakerAdapter{
+	sageMakerFormatNLLB: {
+		languageCodes: nllbLanguageCodes,
+		request: func(text, sourceCode, targetCode string) any {
+			return map[string]any{
+				"inputs": text,
+				"parameters": map[string]string{
+					"src_lang": sourceCode,
+					"tgt_lang": targetCode,
+				},
+			}
+		},
+	},
+	sageMakerFormatMarian: {
+		request: func(text, _, _ string) any {
+			return map[string]string{"inputs": text}
+		},
+	},
+}
+
+// sageMakerTranslation is a translation of the response of an endpoint
+type sageMakerTranslation struct {
+	TranslationText string `json:"translation_text"`
+}
+
+// sageMakerTranslateClient is a TranslateClient backed by a translation model hosted on a
+// SageMaker endpoint. Settings such as the formality of a style aren't supported by the
+// models and are ignored.
+type sageMakerTranslateClient struct {
+	runtime      SageMakerRuntimeClient
+	endpointName string
+	adapter      sageMakerAdapter
+}
+
+// newSageMakerTranslateClient returns the client of the endpoint for the model format, with
+// the language codes of the format overridden by the configured ones
+func newSageMakerTranslateClient(runtime SageMakerRuntimeClient, endpointName, format string, languageCodes map[string]string) (*sageMakerTranslateClient, error) {
+	adapter, ok := sageMakerAdapters[format]
+	if !ok {
+		return nil, fmt.Errorf("sagemaker model format %q is not supported", format)
+	}
+
+	// Models of a single language pair take no language codes to override
+	if adapter.languageCodes != nil {
+		adapter.languageCodes = maps.Clone(adapter.languageCodes)
+		maps.Copy(adapter.languageCodes, languageCodes)
+	}
+
+	return &sageMakerTranslateClient{runtime: runtime, endpointName: endpointName, adapter: adapter}, nil
+}
+
+func (c *sageMakerTranslateClient) TranslateText(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+	sourceLanguage, targetLanguage := aws.ToString(params.SourceLanguageCode), aws.ToString(params.TargetLanguageCode)
+	sourceCode, err := c.languageCode(sourceLanguage)
+	if err != nil {
+		return nil, err
+	}
+	targetCode, err := c.languageCode(targetLanguage)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(c.adapter.request(aws.ToString(params.Text), sourceCode, targetCode))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal sagemaker request: %w", err)
+	}
+
+	response, err := c.runtime.InvokeEndpoint(ctx, c.endpointName, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var translations []sageMakerTranslation
+	if err := json.Unmarshal(response, &translations); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sagemaker response: %w", err)
+	}
+	if len(translations) == 0 {
+		return nil, fmt.Errorf("sagemaker endpoint %s returned no translation", c.endpointName)
+	}
+
+	return &translate.TranslateTextOutput{
+		TranslatedText:     aws.String(translations[0].TranslationText),
+		SourceLanguageCode: aws.String(sourceLanguage),
+		TargetLanguageCode: aws.String(targetLanguage),
+	}, nil
+}
+
+// ListLanguages lists the languages with a code for the model, none for models of a single
+// language pair
+func (c *sageMakerTranslateClient) ListLanguages(ctx context.Context, params *translate.ListLanguagesInput, optFns ...func(*translate.Options)) (*translate.ListLanguagesOutput, error) {
+	output := &translate.ListLanguagesOutput{}
+	for language := range c.adapter.languageCodes {
+		output.Languages = append(output.Languages, types.Language{LanguageCode: aws.String(language)})
+	}
+	slices.SortFunc(output.Languages, func(a, b types.Language) int {
+		return strings.Compare(aws.ToString(a.LanguageCode), aws.ToString(b.LanguageCode))
+	})
+	return output, nil
+}
+
+// languageCode returns the model code of the language, the models never detect the source
+// language
+func (c *sageMakerTranslateClient) languageCode(language string) (string, error) {
+	if language == autoDetectLanguage {
+		return "", fmt.Errorf("sagemaker endpoint %s does not detect the source language", c.endpointName)
+	}
+	if c.adapter.languageCodes == nil {
+		return language, nil
+	}
+	code, ok := c.adapter.languageCodes[language]
+	if !ok {
+		return "", fmt.Errorf("language %q has no code for sagemaker endpoint %s", language, c.endpointName)
+	}
+	return code, nil
+}
+
+// sageMakerRuntime calls the InvokeEndpoint API of the SageMaker runtime, signing the
+// requests with the credentials of the function
+type sageMakerRuntime struct {
+	httpClient  aws.HTTPClient
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	region      string
+	// baseEndpoint is the URL of the runtime API, without the path of the endpoint
+	baseEndpoint string
+}
+
+func newSageMakerRuntime(cfg aws.Config) *sageMakerRuntime {
+	runtime := &sageMakerRuntime{
+		httpClient:   cfg.HTTPClient,
+		credentials:  cfg.Credentials,
+		signer:       v4.NewSigner(),
+		region:       cfg.Region,
+		baseEndpoint: fmt.Sprintf("https://runtime.sagemaker.%s.amazonaws.com", cfg.Region),
+	}
+	if runtime.httpClient == nil {
+		runtime.httpClient = http.DefaultClient
+	}
+	if cfg.BaseEndpoint != nil {
+		runtime.baseEndpoint = *cfg.BaseEndpoint
+	}
+	if endpoint := serviceEndpoint(serviceSageMakerRuntime, cfg.Region); endpoint != nil {
+		runtime.baseEndpoint = *endpoint
+	}
+	return runtime
+}
+
+func (r *sageMakerRuntime) InvokeEndpoint(ctx context.Context, endpointName string, body []byte) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		r.baseEndpoint+"/endpoints/"+url.PathEscape(endpointName)+"/invocations", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sagemaker request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+
+	credentials, err := r.credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+	payloadHash := sha256.Sum256(body)
+	if err := r.signer.SignHTTP(ctx, credentials, request, hex.EncodeToString(payloadHash[:]), sageMakerSigningName, r.region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign sagemaker request: %w", err)
+	}
+
+	response, err := r.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to invoke sagemaker endpoint %s: %w", endpointName, err)
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(io.LimitReader(response.Body, maxSageMakerResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sagemaker response: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sagemaker endpoint %s returned %d: %s", endpointName, response.StatusCode, responseBody)
+	}
+
+	return responseBody, nil
+}
+
+// translationProvider returns the client translating the language pair and the provenance
+// recorded on its translations. Detecting the source language is left to AWS Translate.
+func (h *handler) translationProvider(sourceLanguage, targetLanguage string) (TranslateClient, string) {
+	if h.sageMakerClient != nil && sourceLanguage != autoDetectLanguage &&
+		(len(sageMakerLanguagePairs) == 0 || sageMakerLanguagePairs[languagePair(sourceLanguage, targetLanguage)]) {
+		return h.sageMakerClient, providerSageMaker
+	}
+	return h.translateClient, providerAWSTranslate
+}
+
+// sageMakerTargetLanguage reports whether a configured language pair of the endpoint
+// translates to the language, which AWS Translate may not support
+func (h *handler) sageMakerTargetLanguage(targetLanguage string) bool {
+	if h.sageMakerClient == nil {
+		return false
+	}
+	for pair := range sageMakerLanguagePairs {
+		if strings.HasSuffix(pair, ":"+targetLanguage) {
+			return true
+		}
+	}
+	return false
+}
+
+// languagePair returns the key of the language pair in sageMakerLanguagePairs
+func languagePair(sourceLanguage, targetLanguage string) string {
+	return sourceLanguage + ":" + targetLanguage
+}
+
+// parseLanguagePairs parses a list of source:target language pairs separated by commas.
+// Language codes may contain dashes, such as zh-TW, so pairs are separated by a colon.
+func parseLanguagePairs(value string) map[string]bool {
+	pairs := make(map[string]bool)
+	for _, pair := range strings.Split(value, ",") {
+		source, target, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found || source == "" || target == "" {
+			continue
+		}
+		pairs[languagePair(strings.TrimSpace(source), strings.TrimSpace(target))] = true
+	}
+	return pairs
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestSageMakerTranslateText(t *testing.T) {
+	tests := []struct {
+		name            string
+		format          string
+		languageCodes   map[string]string
+		sourceLanguage  string
+		targetLanguage  string
+		response        string
+		invokeError     error
+		expectedRequest string
+		expectedText    string
+		wantErr         bool
+	}{
+		{
+			name:            "NLLB request",
+			format:          sageMakerFormatNLLB,
+			sourceLanguage:  "en",
+			targetLanguage:  "sw",
+			response:        `[{"translation_text":"Habari."}]`,
+			expectedRequest: `{"inputs":"Hello.","parameters":{"src_lang":"eng_Latn","tgt_lang":"swh_Latn"}}`,
+			expectedText:    "Habari.",
+		},
+		{
+			name:            "Configured language code",
+			format:          sageMakerFormatNLLB,
+			languageCodes:   map[string]string{"sw": "swa_Latn", "lg": "lug_Latn"},
+			sourceLanguage:  "en",
+			targetLanguage:  "lg",
+			response:        `[{"translation_text":"Gyebale ko."}]`,
+			expectedRequest: `{"inputs":"Hello.","parameters":{"src_lang":"eng_Latn","tgt_lang":"lug_Latn"}}`,
+			expectedText:    "Gyebale ko.",
+		},
+		{
+			name:            "Marian request",
+			format:          sageMakerFormatMarian,
+			sourceLanguage:  "en",
+			targetLanguage:  "de",
+			response:        `[{"translation_text":"Hallo."}]`,
+			expectedRequest: `{"inputs":"Hello."}`,
+			expectedText:    "Hallo.",
+		},
+		{
+			name:           "Language without a code",
+			format:         sageMakerFormatNLLB,
+			sourceLanguage: "en",
+			targetLanguage: "xx",
+			wantErr:        true,
+		},
+		{
+			name:           "Source language detection",
+			format:         sageMakerFormatMarian,
+			sourceLanguage: autoDetectLanguage,
+			targetLanguage: "de",
+			wantErr:        true,
+		},
+		{
+			name:           "Invoke error",
+			format:         sageMakerFormatNLLB,
+			sourceLanguage: "en",
+			targetLanguage: "sw",
+			invokeError:    fmt.Errorf("mock error"),
+			wantErr:        true,
+		},
+		{
+			name:           "No translation",
+			format:         sageMakerFormatNLLB,
+			sourceLanguage: "en",
+			targetLanguage: "sw",
+			response:       `[]`,
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var request string
+			runtime := &MockSageMakerRuntimeClient{
+				InvokeEndpointFunc: func(ctx context.Context, endpointName string, body []byte) ([]byte, error) {
+					request = string(body)
+					return []byte(tt.response), tt.invokeError
+				},
+			}
+
+			client, err := newSageMakerTranslateClient(runtime, "nmt-endpoint", tt.format, tt.languageCodes)
+			if err != nil {
+				t.Fatalf("newSageMakerTranslateClient() error = %v", err)
+			}
+
+			got, err := client.TranslateText(context.Background(), &translate.TranslateTextInput{
+				SourceLanguageCode: aws.String(tt.sourceLanguage),
+				TargetLanguageCode: aws.String(tt.targetLanguage),
+				Text:               aws.String("Hello."),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TranslateText() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if request != tt.expectedRequest {
+				t.Errorf("InvokeEndpoint() body = %s, expected %s", request, tt.expectedRequest)
+			}
+			if aws.ToString(got.TranslatedText) != tt.expectedText {
+				t.Errorf("TranslateText() = %q, expected %q", aws.ToString(got.TranslatedText), tt.expectedText)
+			}
+		})
+	}
+}
+
+func TestSageMakerRuntime(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		response   string
+		wantErr    bool
+	}{
+		{name: "Translation", statusCode: http.StatusOK, response: `[{"translation_text":"Habari."}]`},
+		{name: "Model error", statusCode: http.StatusBadRequest, response: `{"ErrorCode":"CLIENT_ERROR_FROM_MODEL"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, authorization, body string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path, authorization = r.URL.Path, r.Header.Get("Authorization")
+				data, _ := io.ReadAll(r.Body)
+				body = string(data)
+				w.WriteHeader(tt.statusCode)
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			runtime := newSageMakerRuntime(aws.Config{
+				Region:       "us-east-1",
+				BaseEndpoint: aws.String(server.URL),
+				Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+					return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+				}),
+			})
+
+			got, err := runtime.InvokeEndpoint(context.Background(), "nmt-endpoint", []byte(`{"inputs":"Hello."}`))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("InvokeEndpoint() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if path != "/endpoints/nmt-endpoint/invocations" || body != `{"inputs":"Hello."}` {
+				t.Errorf("InvokeEndpoint() requested %s with %s", path, body)
+			}
+			if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(authorization, "/us-east-1/sagemaker/") {
+				t.Errorf("InvokeEndpoint() authorization = %q, expected a sagemaker signature", authorization)
+			}
+			if !tt.wantErr && string(got) != tt.response {
+				t.Errorf("InvokeEndpoint() = %s, expected %s", got, tt.response)
+			}
+		})
+	}
+}
+
+func TestHandleSageMaker(t *testing.T) {
+	original := sageMakerLanguagePairs
+	sageMakerLanguagePairs = parseLanguagePairs("en:sw, en:zh-TW")
+	defer func() { sageMakerLanguagePairs = original }()
+
+	tests := []struct {
+		name             string
+		body             string
+		expectedProvider string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name:             "Routed pair is translated by the model",
+			body:             `{"source_language":"en","target_language":"sw","text":"Hello."}`,
+			expectedProvider: providerSageMaker,
+			expectedResponse: events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: `{"translated_text":"Habari. "}`},
+		},
+		{
+			name:             "Other pairs are translated by AWS Translate",
+			body:             `{"source_language":"en","target_language":"es","text":"Hello."}`,
+			expectedProvider: providerAWSTranslate,
+			expectedResponse: events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: `{"translated_text":"Hola. "}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				provider string
+			)
+
+			h := newMockHandler(map[string]string{"Hello.": "Hola."})
+			h.dynamoClient.(*MockDynamoDBClient).PutItemFunc = func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if value, ok := params.Item["provider"].(*dynamoTypes.AttributeValueMemberS); ok {
+					provider = value.Value
+				}
+				return &dynamodb.PutItemOutput{}, nil
+			}
+			client, err := newSageMakerTranslateClient(&MockSageMakerRuntimeClient{
+				InvokeEndpointFunc: func(ctx context.Context, endpointName string, body []byte) ([]byte, error) {
+					return []byte(`[{"translation_text":"Habari."}]`), nil
+				},
+			}, "nmt-endpoint", sageMakerFormatNLLB, nil)
+			if err != nil {
+				t.Fatalf("newSageMakerTranslateClient() error = %v", err)
+			}
+			h.sageMakerClient = client
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %v, expected %v", got, tt.expectedResponse)
+			}
+			if provider != tt.expectedProvider {
+				t.Errorf("PutItem() provider = %q, expected %q", provider, tt.expectedProvider)
+			}
+		})
+	}
+}
+
+func TestParseLanguagePairs(t *testing.T) {
+	got := parseLanguagePairs("en:sw, en:zh-TW,invalid,:fr")
+	if len(got) != 2 || !got[languagePair("en", "sw")] || !got[languagePair("en", "zh-TW")] {
+		t.Errorf("parseLanguagePairs() = %v, expected en:sw and en:zh-TW", got)
+	}
+}
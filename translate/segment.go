@@ -0,0 +1,148 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sentencizer/sentencizer"
+)
+
+const (
+	// segmenterSentencizer splits with the rules of sentencizer, those of the language for
+	// English, Chinese, Japanese and Russian and the English ones otherwise
+	segmenterSentencizer = "sentencizer"
+	// segmenterPunctuation splits after sentence-final punctuation, for the languages whose
+	// sentences sentencizer's English rules split poorly
+	segmenterPunctuation = "punctuation"
+)
+
+// Segmenter splits a text into sentences. The sentences joined back together must hold every
+// non-space character of the text, a text segmented otherwise is kept whole.
+type Segmenter interface {
+	Segment(text string) []string
+}
+
+var (
+	// defaultSegmenter splits the texts of the languages without a configured segmenter, and
+	// those whose language isn't known
+	defaultSegmenter Segmenter = sentencizerSegmenter{language: "en"}
+	// segmenters are the segmenters configured for some source languages, by language code
+	segmenters map[string]Segmenter
+)
+
+// sentencizerLanguages are the languages sentencizer has rules for
+var sentencizerLanguages = map[string]bool{"en": true, "zh": true, "ja": true, "ru": true}
+
+// sentencizerSegmenter splits with the rules sentencizer has for the language
+type sentencizerSegmenter struct {
+	language string
+}
+
+func (s sentencizerSegmenter) Segment(text string) []string {
+	return sentencizer.NewSegmenter(s.language).Segment(text)
+}
+
+// sentenceEndPattern matches the end of a sentence: its final punctuation, closing quotes and
+// brackets, and the whitespace after it, which full-width punctuation doesn't need
+var sentenceEndPattern = regexp.MustCompile(`[.!?…]+["'”’)\]]*\s+|[。！？｡]+["'”’」』）\]]*\s*|\n+`)
+
+// punctuationSegmenter splits after the final punctuation of each sentence
+type punctuationSegmenter struct{}
+
+func (punctuationSegmenter) Segment(text string) []string {
+	var sentences []string
+	start := 0
+	for _, match := range sentenceEndPattern.FindAllStringIndex(text, -1) {
+		if sentence := strings.TrimSpace(text[start:match[1]]); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		start = match[1]
+	}
+	if sentence := strings.TrimSpace(text[start:]); sentence != "" {
+		sentences = append(sentences, sentence)
+	}
+	return sentences
+}
+
+// newSegmenter returns the segmenter of the backend for the language, false for an unknown
+// backend
+func newSegmenter(backend, language string) (Segmenter, bool) {
+	switch backend {
+	case segmenterSentencizer:
+		if !sentencizerLanguages[language] {
+			language = "en"
+		}
+		return sentencizerSegmenter{language: language}, true
+	case segmenterPunctuation:
+		return punctuationSegmenter{}, true
+	}
+	return nil, false
+}
+
+// parseSegmenters parses a list of language=backend pairs separated by commas, such as
+// ja=sentencizer,th=punctuation. Invalid pairs are logged and ignored.
+func parseSegmenters(value string) map[string]Segmenter {
+	configured := make(map[string]Segmenter)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		language, backend, _ := strings.Cut(pair, "=")
+		language = normalizeLanguageCode(strings.TrimSpace(language))
+		base, _, _ := strings.Cut(language, "-")
+		segmenter, ok := newSegmenter(strings.TrimSpace(backend), base)
+		if !ok || language == "" {
+			log.Printf("Ignoring invalid segmenter %q", pair)
+			continue
+		}
+		configured[language] = segmenter
+	}
+	return configured
+}
+
+// segmenterFor returns the segmenter of the language, that of its base language or the
+// default one
+func segmenterFor(language string) Segmenter {
+	if segmenter, ok := segmenters[language]; ok {
+		return segmenter
+	}
+	base, _, _ := strings.Cut(language, "-")
+	if segmenter, ok := segmenters[base]; ok {
+		return segmenter
+	}
+	return defaultSegmenter
+}
+
+// splitSentences splits the text into sentences with the default segmenter
+func splitSentences(input string) []string {
+	return splitSentencesWith(defaultSegmenter, input)
+}
+
+// splitLanguageSentences splits the text into sentences with the segmenter of its language
+func splitLanguageSentences(language, input string) []string {
+	return splitSentencesWith(segmenterFor(language), input)
+}
+
+// splitSentencesWith splits the text into sentences with the segmenter
+func splitSentencesWith(segmenter Segmenter, input string) []string {
+	// The segmenter panics on invalid UTF-8, which scraped pages and email parts may contain
+	if !utf8.ValidString(input) {
+		input = strings.ToValidUTF8(input, string(utf8.RuneError))
+	}
+
+	sentences := segmenter.Segment(input)
+
+	// The segmenter can drop characters, such as a quote after the last sentence, the text is
+	// kept whole rather than losing them
+	if removeSpaces(strings.Join(sentences, "")) != removeSpaces(input) {
+		if text := strings.TrimSpace(input); text != "" {
+			return []string{text}
+		}
+		return nil
+	}
+
+	return sentences
+}
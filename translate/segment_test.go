@@ -0,0 +1,105 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPunctuationSegmenter(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "Latin punctuation",
+			input:    "Hello there! How are you? \"Fine.\" Thanks",
+			expected: []string{"Hello there!", "How are you?", "\"Fine.\"", "Thanks"},
+		},
+		{
+			name:     "Full-width punctuation without spaces",
+			input:    "こんにちは。お元気ですか？「はい。」",
+			expected: []string{"こんにちは。", "お元気ですか？", "「はい。」"},
+		},
+		{
+			name:     "Line breaks",
+			input:    "First line\nSecond line",
+			expected: []string{"First line", "Second line"},
+		},
+		{
+			name:     "Decimal numbers",
+			input:    "It costs 3.50 today.",
+			expected: []string{"It costs 3.50 today."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitSentencesWith(punctuationSegmenter{}, tt.input); !slices.Equal(got, tt.expected) {
+				t.Errorf("Segment() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSegmenterFor(t *testing.T) {
+	previous := segmenters
+	segmenters = parseSegmenters("ja=sentencizer, th=punctuation, pt-br=punctuation, xx=unknown, =punctuation")
+	defer func() { segmenters = previous }()
+
+	tests := []struct {
+		language string
+		expected Segmenter
+	}{
+		{language: "ja", expected: sentencizerSegmenter{language: "ja"}},
+		{language: "th", expected: punctuationSegmenter{}},
+		{language: "th-TH", expected: punctuationSegmenter{}},
+		{language: "pt-BR", expected: punctuationSegmenter{}},
+		{language: "pt", expected: defaultSegmenter},
+		{language: "xx", expected: defaultSegmenter},
+		{language: "en", expected: defaultSegmenter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			if got := segmenterFor(tt.language); got != tt.expected {
+				t.Errorf("segmenterFor() = %#v, expected %#v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// lineSegmenter splits texts on line breaks, a segmenter independent of sentencizer
+type lineSegmenter struct{}
+
+func (lineSegmenter) Segment(text string) []string {
+	return strings.Split(text, "\n")
+}
+
+func TestGetTextFromHTMLSegmenter(t *testing.T) {
+	_, sentences, sentenceCounts, err := getTextFromHTML("<p>One. Two\nThree.</p><p>Four.</p>", lineSegmenter{})
+	if err != nil {
+		t.Fatalf("getTextFromHTML() error = %v", err)
+	}
+	if expected := []string{"One. Two", "Three.", "Four."}; !slices.Equal(sentences, expected) {
+		t.Errorf("getTextFromHTML() sentences = %q, expected %q", sentences, expected)
+	}
+	// The counts are per text node, those of the elements are zero
+	counts := slices.DeleteFunc(sentenceCounts, func(count int) bool { return count == 0 })
+	if expected := []int{2, 1}; !slices.Equal(counts, expected) {
+		t.Errorf("getTextFromHTML() sentenceCounts = %v, expected %v", counts, expected)
+	}
+}
+
+func BenchmarkSegmenters(b *testing.B) {
+	for _, backend := range []string{segmenterSentencizer, segmenterPunctuation} {
+		segmenter, _ := newSegmenter(backend, "en")
+		b.Run(backend, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				splitSentencesWith(segmenter, benchmarkText)
+			}
+		})
+	}
+}
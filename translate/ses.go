@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	handlerModeSES = "ses"
+
+	// detectedLanguageHeader tags translated messages with the language they were written in
+	detectedLanguageHeader = "X-Detected-Language"
+)
+
+// handleSESEvent translates messages received by an SES receipt rule. The rule must store
+// the raw message in the inbound bucket before invoking the function. Translated messages
+// are stored under the translated prefix using the same message ID.
+func (h *handler) handleSESEvent(ctx context.Context, event events.SimpleEmailEvent) error {
+	defer flushTelemetry(ctx)
+
+	for _, record := range event.Records {
+		messageID := record.SES.Mail.MessageID
+
+		object, err := h.s3Client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(inboundEmailBucket),
+			Key:    aws.String(inboundEmailPrefix + messageID),
+		})
+		if err != nil {
+			return fmt.Errorf("error fetching message %s: %w", messageID, err)
+		}
+
+		message, err := io.ReadAll(object.Body)
+		object.Body.Close()
+		if err != nil {
+			return fmt.Errorf("error reading message %s: %w", messageID, err)
+		}
+
+		translatedMessage, detectedLanguage, err := h.translateInboundEmail(ctx, message)
+		if err != nil {
+			return fmt.Errorf("error translating message %s: %w", messageID, err)
+		}
+
+		input := &s3.PutObjectInput{
+			Bucket:      aws.String(inboundEmailBucket),
+			Key:         aws.String(translatedEmailPrefix + messageID),
+			Body:        bytes.NewReader(translatedMessage),
+			ContentType: aws.String("message/rfc822"),
+		}
+		if detectedLanguage != "" {
+			input.Metadata = map[string]string{"detected-language": detectedLanguage}
+		}
+
+		if _, err := h.s3Client.PutObject(ctx, input); err != nil {
+			return fmt.Errorf("error storing message %s: %w", messageID, err)
+		}
+
+		log.Printf("Translated message %s from %q to %q", messageID, detectedLanguage, emailTargetLanguage)
+	}
+
+	return nil
+}
+
+// translateInboundEmail detects the language of the message and translates it to the
+// configured email target language, tagging it with the detected language
+func (h *handler) translateInboundEmail(ctx context.Context, message []byte) ([]byte, string, error) {
+	text, err := extractEmailText(message)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Messages without any text are stored as they are
+	if strings.TrimSpace(text) == "" {
+		return message, "", nil
+	}
+
+	detectedLanguage, err := detectLanguage(ctx, h.comprehendClient, text)
+	if err != nil {
+		return nil, "", err
+	}
+
+	translatedMessage := message
+	if err := checkDetectedLanguage(ctx, normalizeLanguageCode(detectedLanguage), emailTargetLanguage); err != nil {
+		// The message is still stored and tagged, untranslated
+		log.Printf("Leaving message in %s untranslated: %v", detectedLanguage, err)
+	} else if detectedLanguage != emailTargetLanguage {
+		translatedMessage, err = h.translateEmail(ctx, detectedLanguage, emailTargetLanguage, message)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	tagged := fmt.Sprintf("%s: %s\r\n%s", detectedLanguageHeader, detectedLanguage, translatedMessage)
+	return []byte(tagged), detectedLanguage, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestHandleSESEvent(t *testing.T) {
+	tests := []struct {
+		name             string
+		message          string
+		detectedLanguage string
+		blocked          string
+		getError         error
+		expectedBody     string
+		expectedMetadata map[string]string
+		wantErr          bool
+	}{
+		{
+			name:             "Foreign message is translated",
+			message:          "Subject: Hola\r\n\r\nHola mundo.",
+			detectedLanguage: "es",
+			expectedBody:     "X-Detected-Language: es\r\nSubject: Hola\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\nHello world.",
+			expectedMetadata: map[string]string{"detected-language": "es"},
+			wantErr:          false,
+		},
+		{
+			name:             "Message already in target language is only tagged",
+			message:          "Subject: Hello\r\n\r\nHello world.",
+			detectedLanguage: "en",
+			expectedBody:     "X-Detected-Language: en\r\nSubject: Hello\r\n\r\nHello world.",
+			expectedMetadata: map[string]string{"detected-language": "en"},
+			wantErr:          false,
+		},
+		{
+			name:             "Message in a blocked language is only tagged",
+			message:          "Subject: Hola\r\n\r\nHola mundo.",
+			detectedLanguage: "es",
+			blocked:          "es",
+			expectedBody:     "X-Detected-Language: es\r\nSubject: Hola\r\n\r\nHola mundo.",
+			expectedMetadata: map[string]string{"detected-language": "es"},
+			wantErr:          false,
+		},
+		{
+			name:             "Message without text is stored as is",
+			message:          "Subject: Empty\r\n\r\n",
+			expectedBody:     "Subject: Empty\r\n\r\n",
+			expectedMetadata: nil,
+			wantErr:          false,
+		},
+		{
+			name:     "Error fetching message",
+			getError: fmt.Errorf("mock error"),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousPolicy := configuredLanguagePolicy
+			configuredLanguagePolicy = languagePolicy{blocked: parseLanguageList(tt.blocked)}
+			defer func() { configuredLanguagePolicy = previousPolicy }()
+
+			var stored *s3.PutObjectInput
+
+			h := newMockHandler(map[string]string{"Hola mundo.": "Hello world."})
+			h.comprehendClient = newMockLanguageDetector(tt.detectedLanguage)
+			h.s3Client = &MockS3Client{
+				GetObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+					if tt.getError != nil {
+						return nil, tt.getError
+					}
+					if *params.Key != inboundEmailPrefix+"message-id" {
+						t.Errorf("GetObject() key = %s", *params.Key)
+					}
+					return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(tt.message))}, nil
+				},
+				PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+					stored = params
+					return &s3.PutObjectOutput{}, nil
+				},
+			}
+
+			event := events.SimpleEmailEvent{
+				Records: []events.SimpleEmailRecord{
+					{SES: events.SimpleEmailService{Mail: events.SimpleEmailMessage{MessageID: "message-id"}}},
+				},
+			}
+
+			err := h.handleSESEvent(context.Background(), event)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handleSESEvent() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if stored == nil {
+				t.Errorf("handleSESEvent() did not store the translated message")
+				return
+			}
+
+			if *stored.Key != translatedEmailPrefix+"message-id" {
+				t.Errorf("handleSESEvent() stored key = %s, expected %s", *stored.Key, translatedEmailPrefix+"message-id")
+			}
+
+			body, _ := io.ReadAll(stored.Body)
+			if !bytes.Equal(body, []byte(tt.expectedBody)) {
+				t.Errorf("handleSESEvent() stored body = %q, expected %q", body, tt.expectedBody)
+			}
+
+			if fmt.Sprint(stored.Metadata) != fmt.Sprint(tt.expectedMetadata) {
+				t.Errorf("handleSESEvent() stored metadata = %v, expected %v", stored.Metadata, tt.expectedMetadata)
+			}
+		})
+	}
+}
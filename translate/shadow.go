@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// shadowTimeout bounds how long a shadow translation may take
+	shadowTimeout = 2 * time.Second
+	// shadowDrainTimeout bounds how long the end of an invocation waits for the shadow
+	// comparisons still running. Lambda freezes the environment once the handler returns, a
+	// comparison left running would be lost or recorded in a later invocation.
+	shadowDrainTimeout = 500 * time.Millisecond
+)
+
+var shadowDivergence, _ = meter.Float64Histogram("translate.shadow.divergence",
+	metric.WithDescription("Word level edit distance between the primary and shadow translations, from 0 to 1"),
+	metric.WithExplicitBucketBoundaries(0, 0.05, 0.1, 0.2, 0.3, 0.5, 0.75, 1),
+)
+
+// startShadowTranslation sends a sample of the segments to the shadow provider alongside the
+// primary provider. The returned function hands the primary translation to the comparison,
+// which runs in the background and is drained before the telemetry of the invocation is
+// flushed. The comparison is only recorded and logged, by the hashes of the texts unless
+// shadowLogText is set, and never returned to the caller.
+func (h *handler) startShadowTranslation(ctx context.Context, sourceLanguage, targetLanguage, text string) func(primary string) {
+	if h.shadowClient == nil || rand.IntN(100) >= shadowPercent {
+		return func(string) {}
+	}
+
+	// The shadow translation isn't cut by the request, only by its own timeout or the drain
+	shadowCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shadowTimeout)
+	id := shadowComparisons.add(cancel)
+	primaries := make(chan string, 1)
+	go func() {
+		defer shadowComparisons.done(id)
+
+		response, err := translateLanguage(shadowCtx, h.shadowClient, text, sourceLanguage, targetLanguage)
+		if err != nil {
+			log.Printf("Shadow translation %s from %q to %q failed: %v", textDigest(text), sourceLanguage, targetLanguage, err)
+			return
+		}
+
+		var primary string
+		select {
+		case primary = <-primaries:
+		case <-shadowCtx.Done():
+			// The primary translation failed, or took longer than the shadow one may
+			return
+		}
+		if errors.Is(shadowCtx.Err(), context.Canceled) {
+			// Dropped by the drain, the invocation is over
+			return
+		}
+
+		divergence := translationDivergence(primary, response.TranslatedText)
+		shadowDivergence.Record(ctx, divergence, metric.WithAttributes(
+			attribute.String("source_language", sourceLanguage),
+			attribute.String("target_language", targetLanguage),
+		))
+		if shadowLogText {
+			log.Printf("Shadow translation %s from %q to %q diverged by %.2f: primary %q, shadow %q",
+				textDigest(text), sourceLanguage, targetLanguage, divergence, primary, response.TranslatedText)
+			return
+		}
+		log.Printf("Shadow translation %s from %q to %q diverged by %.2f: primary %s, shadow %s",
+			textDigest(text), sourceLanguage, targetLanguage, divergence, textDigest(primary), textDigest(response.TranslatedText))
+	}()
+
+	return func(primary string) {
+		primaries <- primary
+	}
+}
+
+// shadowComparisons are the shadow comparisons running in the background of the invocation
+var shadowComparisons pendingComparisons
+
+// pendingComparisons tracks background comparisons by the functions cancelling them
+type pendingComparisons struct {
+	mu      sync.Mutex
+	next    int
+	cancels map[int]context.CancelFunc
+	// idle is closed once no comparison is running
+	idle chan struct{}
+}
+
+// add registers a comparison, returning its id
+func (p *pendingComparisons) add(cancel context.CancelFunc) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.cancels) == 0 {
+		p.cancels = map[int]context.CancelFunc{}
+		p.idle = make(chan struct{})
+	}
+	p.next++
+	p.cancels[p.next] = cancel
+	return p.next
+}
+
+// done releases the comparison once it has finished
+func (p *pendingComparisons) done(id int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cancel, ok := p.cancels[id]
+	if !ok {
+		return
+	}
+	cancel()
+	delete(p.cancels, id)
+	if len(p.cancels) == 0 {
+		close(p.idle)
+	}
+}
+
+// drain waits for the running comparisons up to the timeout, then cancels those left so they
+// aren't recorded in a later invocation
+func (p *pendingComparisons) drain(timeout time.Duration) {
+	p.mu.Lock()
+	if len(p.cancels) == 0 {
+		p.mu.Unlock()
+		return
+	}
+	idle := p.idle
+	p.mu.Unlock()
+
+	select {
+	case <-idle:
+		return
+	case <-time.After(timeout):
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.cancels) == 0 {
+		return
+	}
+	log.Printf("Dropping %d shadow comparisons still running", len(p.cancels))
+	for id, cancel := range p.cancels {
+		cancel()
+		delete(p.cancels, id)
+	}
+	close(p.idle)
+}
+
+// textDigest returns a short hash identifying a text in the logs without disclosing it
+func textDigest(text string) string {
+	hash := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(hash[:8])
+}
+
+// translationDivergence returns the word level edit distance between two translations,
+// normalised by the length of the longest one
+func translationDivergence(a, b string) float64 {
+	wordsA, wordsB := strings.Fields(a), strings.Fields(b)
+	longest := max(len(wordsA), len(wordsB))
+	if longest == 0 {
+		return 0
+	}
+
+	// Levenshtein distance keeping a single row of the matrix
+	row := make([]int, len(wordsB)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(wordsA); i++ {
+		previous := row[0]
+		row[0] = i
+		for j := 1; j <= len(wordsB); j++ {
+			substitution := previous
+			if wordsA[i-1] != wordsB[j-1] {
+				substitution++
+			}
+			previous = row[j]
+			row[j] = min(row[j]+1, row[j-1]+1, substitution)
+		}
+	}
+
+	return float64(row[len(wordsB)]) / float64(longest)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestTranslationDivergence(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected float64
+	}{
+		{name: "Identical", a: "Hola mundo", b: "Hola mundo", expected: 0},
+		{name: "One word differs", a: "Hola mundo", b: "Hola tierra", expected: 0.5},
+		{name: "Extra word", a: "Hola mundo", b: "Hola a todo el mundo", expected: 0.6},
+		{name: "Completely different", a: "Hola", b: "Adiós", expected: 1},
+		{name: "Both empty", a: "", b: "", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := translationDivergence(tt.a, tt.b); got != tt.expected {
+				t.Errorf("translationDivergence() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandleShadowTranslation(t *testing.T) {
+	tests := []struct {
+		name          string
+		percent       int
+		enabled       bool
+		stuck         bool
+		expectedCalls int
+	}{
+		{name: "Disabled", percent: 100, enabled: false, expectedCalls: 0},
+		{name: "Every segment sampled", percent: 100, enabled: true, expectedCalls: 2},
+		{name: "No segment sampled", percent: 0, enabled: true, expectedCalls: 0},
+		{name: "Shadow provider stuck", percent: 100, enabled: true, stuck: true, expectedCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := shadowPercent
+			shadowPercent = tt.percent
+			defer func() { shadowPercent = original }()
+
+			calls := make(chan string, 10)
+			h := newMockHandler(map[string]string{"Hello.": "Hola.", "How are you?": "¿Cómo estás?"})
+			if tt.enabled {
+				h.shadowClient = &MockTranslateClient{
+					TranslateTextFunc: func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+						calls <- *params.Text
+						if tt.stuck {
+							<-ctx.Done()
+							return nil, ctx.Err()
+						}
+						return &translate.TranslateTextOutput{TranslatedText: aws.String("shadow")}, nil
+					},
+				}
+			}
+
+			start := time.Now()
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+				Body: `{"source_language":"en","target_language":"es","text":"Hello. How are you?"}`,
+			})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+			// The request never waits for the shadow provider
+			if elapsed := time.Since(start); elapsed >= shadowTimeout {
+				t.Errorf("handle() took %s, expected not to wait for the shadow provider", elapsed)
+			}
+
+			// The shadow translation is never returned
+			if expected := `{"translated_text":"Hola. ¿Cómo estás? "}`; got.Body != expected {
+				t.Errorf("handle() = %v, expected %v", got.Body, expected)
+			}
+			for range tt.expectedCalls {
+				select {
+				case <-calls:
+				case <-time.After(time.Second):
+					t.Fatalf("shadow TranslateText() not called %d times", tt.expectedCalls)
+				}
+			}
+			if len(calls) != 0 {
+				t.Errorf("shadow TranslateText() called %d times, expected %d", tt.expectedCalls+len(calls), tt.expectedCalls)
+			}
+
+			// The comparisons are drained before the handler returns
+			shadowComparisons.mu.Lock()
+			defer shadowComparisons.mu.Unlock()
+			if pending := len(shadowComparisons.cancels); pending != 0 {
+				t.Errorf("handle() returned with %d shadow comparisons running", pending)
+			}
+		})
+	}
+}
+
+func TestPendingComparisonsDrain(t *testing.T) {
+	var comparisons pendingComparisons
+
+	finished, cancelFinished := context.WithCancel(context.Background())
+	id := comparisons.add(cancelFinished)
+	stuck, cancelStuck := context.WithCancel(context.Background())
+	comparisons.add(cancelStuck)
+	comparisons.done(id)
+
+	start := time.Now()
+	comparisons.drain(50 * time.Millisecond)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("drain() returned after %s, expected to wait for the stuck comparison", elapsed)
+	}
+	if finished.Err() == nil || stuck.Err() == nil {
+		t.Errorf("drain() left comparisons running")
+	}
+
+	// Nothing is left to wait for
+	start = time.Now()
+	comparisons.drain(time.Second)
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("drain() waited %s without comparisons running", elapsed)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tokenShielding replaces the URLs, email addresses, file paths, dates and numbers of the
+// text sent to the provider with placeholders, restoring them verbatim in the translation
+var tokenShielding bool
+
+// shieldedTokenPattern matches the tokens held back from the provider: URLs, email
+// addresses, numbers with separators such as dates and times, and file paths, whose leading
+// delimiter is outside the group. Bare integers are left to the provider, which needs them
+// for the grammatical number of the text around them.
+var shieldedTokenPattern = regexp.MustCompile(`\b(?:https?|ftp)://[^\s<>"]+|\b[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}\b|\b\d+(?:[.,:/\-]\d+)+\b|(?:^|[\s(\["'])((?:~|[A-Za-z]:)?(?:[/\\][\w.\-]+)+[/\\]?)`)
+
+// shieldedTokenTrailer is the punctuation ending the sentence rather than a URL or path
+const shieldedTokenTrailer = ".,;:!?)]'\""
+
+// shieldedTokenPlaceholder returns the placeholder of the shielded token at the index,
+// distinct from the {0} placeholders of format specifiers
+func shieldedTokenPlaceholder(index int) string {
+	return "{@" + strconv.Itoa(index) + "}"
+}
+
+// shieldTokens replaces the untranslatable tokens of the text with numbered placeholders
+func shieldTokens(text string) (string, []string) {
+	var (
+		shielded strings.Builder
+		tokens   []string
+		offset   int
+	)
+	for _, match := range shieldedTokenPattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[0], match[1]
+		if match[2] >= 0 {
+			start, end = match[2], match[3]
+		}
+		end = start + len(strings.TrimRight(text[start:end], shieldedTokenTrailer))
+		if start >= end {
+			continue
+		}
+
+		shielded.WriteString(text[offset:start])
+		shielded.WriteString(shieldedTokenPlaceholder(len(tokens)))
+		tokens = append(tokens, text[start:end])
+		offset = end
+	}
+	if len(tokens) == 0 {
+		return text, nil
+	}
+	shielded.WriteString(text[offset:])
+	return shielded.String(), tokens
+}
+
+// restoreShieldedTokens puts the shielded tokens back verbatim, reporting whether every
+// placeholder was found exactly once
+func restoreShieldedTokens(text string, tokens []string) (string, bool) {
+	for i, token := range tokens {
+		placeholder := shieldedTokenPlaceholder(i)
+		if strings.Count(text, placeholder) != 1 {
+			return text, false
+		}
+		text = strings.Replace(text, placeholder, token, 1)
+	}
+	return text, true
+}
+
+// translateShielded translates the text with its untranslatable tokens and the protected
+// entities of the request shielded from the provider. Text made only of such tokens isn't sent
+// at all, and text whose placeholders don't survive translation is translated again
+// unshielded.
+func translateShielded(ctx context.Context, translateClient TranslateClient, text, sourceLanguage, targetLanguage string) (TranslateResponse, error) {
+	shielded, tokens := text, []string(nil)
+	if tokenShielding {
+		shielded, tokens = shieldTokens(text)
+	}
+	shielded, tokens = shieldEntities(shielded, protectedEntitiesFromContext(ctx), tokens)
+	if len(tokens) == 0 {
+		return translateLanguage(ctx, translateClient, text, sourceLanguage, targetLanguage)
+	}
+	if !strings.ContainsFunc(shielded, unicode.IsLetter) {
+		return TranslateResponse{TranslatedText: text}, nil
+	}
+
+	output, err := translateLanguage(ctx, translateClient, shielded, sourceLanguage, targetLanguage)
+	if err != nil {
+		return output, err
+	}
+	restored, ok := restoreShieldedTokens(output.TranslatedText, tokens)
+	if !ok {
+		log.Printf("Shielded tokens of %q were lost in translation, translating it unshielded", text)
+		return translateLanguage(ctx, translateClient, text, sourceLanguage, targetLanguage)
+	}
+	output.TranslatedText = restored
+	return output, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+)
+
+func TestShieldTokens(t *testing.T) {
+	tests := []struct {
+		name             string
+		text             string
+		expectedShielded string
+		expectedTokens   []string
+	}{
+		{
+			name:             "URL ending a sentence",
+			text:             "Read the guide at https://example.com/docs?page=2.",
+			expectedShielded: "Read the guide at {@0}.",
+			expectedTokens:   []string{"https://example.com/docs?page=2"},
+		},
+		{
+			name:             "Email address",
+			text:             "Write to support@example.co.uk for help.",
+			expectedShielded: "Write to {@0} for help.",
+			expectedTokens:   []string{"support@example.co.uk"},
+		},
+		{
+			name:             "File paths",
+			text:             "Edit /etc/hosts or C:\\Windows\\hosts, not and/or.",
+			expectedShielded: "Edit {@0} or {@1}, not and/or.",
+			expectedTokens:   []string{"/etc/hosts", "C:\\Windows\\hosts"},
+		},
+		{
+			name:             "Dates and numbers",
+			text:             "Due 2024-01-15 at 10:30, 1,250.5 kg in 3 boxes.",
+			expectedShielded: "Due {@0} at {@1}, {@2} kg in 3 boxes.",
+			expectedTokens:   []string{"2024-01-15", "10:30", "1,250.5"},
+		},
+		{
+			name:             "Nothing to shield",
+			text:             "You have 3 files in {0}.",
+			expectedShielded: "You have 3 files in {0}.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shielded, tokens := shieldTokens(tt.text)
+			if shielded != tt.expectedShielded || !reflect.DeepEqual(tokens, tt.expectedTokens) {
+				t.Errorf("shieldTokens() = %q, %q, expected %q, %q", shielded, tokens, tt.expectedShielded, tt.expectedTokens)
+			}
+
+			restored, ok := restoreShieldedTokens(shielded, tokens)
+			if !ok || restored != tt.text {
+				t.Errorf("restoreShieldedTokens() = %q, %v, expected %q", restored, ok, tt.text)
+			}
+		})
+	}
+}
+
+func TestTranslateShielded(t *testing.T) {
+	tests := []struct {
+		name                 string
+		text                 string
+		translations         map[string]string
+		expected             string
+		expectedTranslations []string
+	}{
+		{
+			name:                 "Restored verbatim",
+			text:                 "See https://example.com/a.",
+			translations:         map[string]string{"See {@0}.": "Vea {@0}."},
+			expected:             "Vea https://example.com/a.",
+			expectedTranslations: []string{"See {@0}."},
+		},
+		{
+			name:     "Only tokens",
+			text:     "support@example.com",
+			expected: "support@example.com",
+		},
+		{
+			name: "Placeholder lost",
+			text: "See https://example.com/a.",
+			translations: map[string]string{
+				"See {@0}.":                  "Vea.",
+				"See https://example.com/a.": "Vea https://example.com/a.",
+			},
+			expected:             "Vea https://example.com/a.",
+			expectedTranslations: []string{"See {@0}.", "See https://example.com/a."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := tokenShielding
+			tokenShielding = true
+			defer func() { tokenShielding = original }()
+
+			var sent []string
+			client := &MockTranslateClient{
+				TranslateTextFunc: func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+					sent = append(sent, *params.Text)
+					return &translate.TranslateTextOutput{TranslatedText: aws.String(tt.translations[*params.Text])}, nil
+				},
+			}
+
+			got, err := translateShielded(context.Background(), client, tt.text, "en", "es")
+			if err != nil {
+				t.Fatalf("translateShielded() error = %v", err)
+			}
+			if got.TranslatedText != tt.expected {
+				t.Errorf("translateShielded() = %q, expected %q", got.TranslatedText, tt.expected)
+			}
+			if !reflect.DeepEqual(sent, tt.expectedTranslations) {
+				t.Errorf("translateShielded() sent %q, expected %q", sent, tt.expectedTranslations)
+			}
+		})
+	}
+}
+
+func TestTranslateTokenShielded(t *testing.T) {
+	original := tokenShielding
+	tokenShielding = true
+	defer func() { tokenShielding = original }()
+
+	var calls atomic.Int32
+	h := newMockHandler(map[string]string{"Open {@0} now.": "Abra {@0} ahora."})
+	mock := h.translateClient.(*MockTranslateClient)
+	translateText := mock.TranslateTextFunc
+	mock.TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+		calls.Add(1)
+		return translateText(ctx, params, optFns...)
+	}
+
+	got, err := h.translateText(context.Background(), "en", "es", "Open https://example.com/login now.")
+	if err != nil {
+		t.Fatalf("translateText() error = %v", err)
+	}
+	if got != "Abra https://example.com/login ahora. " || calls.Load() != 1 {
+		t.Errorf("translateText() = %q after %d calls, expected the URL restored after 1 call", got, calls.Load())
+	}
+}
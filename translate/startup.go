@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-xray-sdk-go/instrumentation/awsv2"
+	"github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
+	"golang.org/x/sync/errgroup"
+)
+
+// languagesWarmTimeout bounds the time the init phase waits for the supported languages
+const languagesWarmTimeout = 2 * time.Second
+
+// loadConfig loads the sdk configuration while the telemetry providers are set up, as none
+// of them depends on the others. The sdk calls are instrumented from the first one on, once
+// the tracer provider is known. The TLS configuration of the other clients is returned along,
+// nil when the defaults apply.
+func loadConfig(ctx context.Context) (aws.Config, *tls.Config, error) {
+	var (
+		cfg   aws.Config
+		group errgroup.Group
+	)
+	group.Go(func() error {
+		var err error
+		cfg, err = config.LoadDefaultConfig(ctx, config.WithRegion(region), config.WithHTTPClient(newSDKHTTPClient(nil)))
+		if err != nil {
+			return fmt.Errorf("failed to load configuration, %w", err)
+		}
+		return nil
+	})
+	group.Go(func() error {
+		if err := setupMetrics(ctx); err != nil {
+			return fmt.Errorf("failed to setup metrics, %w", err)
+		}
+		return nil
+	})
+	group.Go(func() error {
+		if err := setupTracing(ctx); err != nil {
+			return fmt.Errorf("failed to setup tracing, %w", err)
+		}
+		return nil
+	})
+	if err := group.Wait(); err != nil {
+		return aws.Config{}, nil, err
+	}
+
+	// The clients trust the CA bundle once it is read, such as that of an egress proxy
+	tlsConfig, err := loadTLSConfig(ctx, cfg)
+	if err != nil {
+		return aws.Config{}, nil, err
+	}
+	if tlsConfig != nil {
+		cfg.HTTPClient = newSDKHTTPClient(tlsConfig)
+	}
+
+	cfg.APIOptions = append(cfg.APIOptions, instrumentSDK)
+	return cfg, tlsConfig, nil
+}
+
+// sdkInstrumentation returns the middlewares tracing the sdk calls, with OpenTelemetry when
+// it exports spans, otherwise with the xray sdk. They are built by the first call rather than
+// during the init phase.
+var sdkInstrumentation = sync.OnceValue(func() []func(*middleware.Stack) error {
+	var options []func(*middleware.Stack) error
+	if tracerProvider != nil {
+		otelaws.AppendMiddlewares(&options)
+	} else {
+		awsv2.AWSV2Instrumentor(&options)
+	}
+	return options
+})
+
+// instrumentSDK adds the tracing middlewares to the stack of an sdk call
+func instrumentSDK(stack *middleware.Stack) error {
+	for _, option := range sdkInstrumentation() {
+		if err := option(stack); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// warmSupportedLanguages loads the supported languages during the init phase, so the first
+// request doesn't wait for them. Failing to is only logged, the request loads them again.
+func (h *handler) warmSupportedLanguages(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, languagesWarmTimeout)
+	defer cancel()
+
+	if _, err := h.supportedLanguages(ctx); err != nil {
+		log.Printf("Error warming supported languages: %v", err)
+	}
+}
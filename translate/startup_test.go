@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"github.com/aws/smithy-go/middleware"
+)
+
+func TestInstrumentSDK(t *testing.T) {
+	stack := middleware.NewStack("test", nil)
+	if err := instrumentSDK(stack); err != nil {
+		t.Fatalf("instrumentSDK() error = %v", err)
+	}
+
+	// The xray sdk traces the calls without a tracer provider
+	if len(stack.Initialize.List())+len(stack.Deserialize.List()) == 0 {
+		t.Errorf("instrumentSDK() added no middleware to %s", stack)
+	}
+}
+
+func TestWarmSupportedLanguages(t *testing.T) {
+	tests := []struct {
+		name          string
+		listError     error
+		expectedCache []string
+	}{
+		{name: "Languages are cached", expectedCache: []string{"en", "es"}},
+		{name: "Errors are left to the first request", listError: fmt.Errorf("mock error")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(nil)
+			if tt.listError != nil {
+				h.translateClient.(*MockTranslateClient).ListLanguagesFunc = func(ctx context.Context, params *translate.ListLanguagesInput, optFns ...func(*translate.Options)) (*translate.ListLanguagesOutput, error) {
+					return nil, tt.listError
+				}
+			}
+
+			h.warmSupportedLanguages(context.Background())
+
+			h.languages.mu.Lock()
+			defer h.languages.mu.Unlock()
+			if !slices.Equal(h.languages.languages, tt.expectedCache) {
+				t.Errorf("warmSupportedLanguages() cached %v, expected %v", h.languages.languages, tt.expectedCache)
+			}
+		})
+	}
+}
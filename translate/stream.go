@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	firehoseTypes "github.com/aws/aws-sdk-go-v2/service/firehose/types"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	kinesisTypes "github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+)
+
+const (
+	handlerModeStream = "stream"
+
+	// maxStreamOutputBatch is the record limit of PutRecords and PutRecordBatch
+	maxStreamOutputBatch = 500
+
+	// translatedFieldSuffix names the field holding the translation when none is configured
+	translatedFieldSuffix = "_translated"
+)
+
+// streamRecord is a Kinesis record with its decoded JSON payload
+type streamRecord struct {
+	sequenceNumber string
+	partitionKey   string
+	// payload is nil when the record isn't a JSON object, it is forwarded as is
+	payload map[string]any
+	data    []byte
+}
+
+// handleStreamEvent translates the text field of the JSON records of a Kinesis batch and
+// writes the enriched records to the output stream or delivery stream. The records of each
+// shard are translated together so repeated texts are looked up and cached once. A shard
+// that fails is reported from its first record, Lambda retries it from there.
+func (h *handler) handleStreamEvent(ctx context.Context, event events.KinesisEvent) (events.KinesisEventResponse, error) {
+	defer flushTelemetry(ctx)
+
+	ctx, writer := h.withCacheWriter(ctx)
+	defer writer.Close()
+
+	var (
+		shards  []string
+		records = map[string][]streamRecord{}
+	)
+	for _, record := range event.Records {
+		shard, _, _ := strings.Cut(record.EventID, ":")
+		if _, ok := records[shard]; !ok {
+			shards = append(shards, shard)
+		}
+		records[shard] = append(records[shard], decodeStreamRecord(record.Kinesis))
+	}
+
+	var response events.KinesisEventResponse
+	for _, shard := range shards {
+		if err := h.translateStreamRecords(ctx, records[shard]); err != nil {
+			log.Printf("Error translating records of %s: %v", shard, err)
+			response.BatchItemFailures = append(response.BatchItemFailures, events.KinesisBatchItemFailure{
+				ItemIdentifier: records[shard][0].sequenceNumber,
+			})
+		}
+	}
+
+	return response, nil
+}
+
+// decodeStreamRecord decodes the payload of a record, keeping numbers as they were written
+func decodeStreamRecord(record events.KinesisRecord) streamRecord {
+	decoded := streamRecord{
+		sequenceNumber: record.SequenceNumber,
+		partitionKey:   record.PartitionKey,
+		data:           record.Data,
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(record.Data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded.payload); err != nil {
+		log.Printf("Forwarding record %s untranslated, it is not a JSON object: %v", record.SequenceNumber, err)
+		decoded.payload = nil
+	}
+
+	return decoded
+}
+
+// translateStreamRecords translates the text field of the records in one batch and writes
+// them to the outputs
+func (h *handler) translateStreamRecords(ctx context.Context, records []streamRecord) error {
+	var (
+		texts   = make([]string, len(records))
+		indexes []int
+	)
+	for i, record := range records {
+		if record.payload == nil {
+			continue
+		}
+		text, ok := lookupField(record.payload, streamTextField).(string)
+		if !ok || strings.TrimSpace(text) == "" {
+			continue
+		}
+		texts[i] = text
+		indexes = append(indexes, i)
+	}
+
+	if err := h.translateStreamTexts(ctx, records, texts, indexes); err != nil {
+		return err
+	}
+
+	data := make([][]byte, len(records))
+	for i, record := range records {
+		data[i] = record.data
+		if record.payload == nil {
+			continue
+		}
+		if texts[i] != "" {
+			setField(record.payload, streamTranslatedField, texts[i])
+		}
+		encoded, err := json.Marshal(record.payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal record %s: %w", record.sequenceNumber, err)
+		}
+		data[i] = encoded
+	}
+
+	if streamOutputName != "" {
+		if err := h.putStreamRecords(ctx, records, data); err != nil {
+			return err
+		}
+	}
+	if streamDeliveryStreamName != "" {
+		if err := h.putDeliveryStreamRecords(ctx, data); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Translated %d of %d records", len(indexes), len(records))
+	return nil
+}
+
+// translateStreamTexts translates the texts of the records at the indexes in place. The texts
+// the language policy doesn't allow translating are cleared, their records are forwarded
+// untranslated. When the policy blocks languages, the language of the texts without source
+// language is detected first so that those in a blocked language aren't sent to the provider.
+func (h *handler) translateStreamTexts(ctx context.Context, records []streamRecord, texts []string, indexes []int) error {
+	if err := configuredLanguagePolicy.check(streamSourceLanguage, streamTargetLanguage); err != nil {
+		clear(texts)
+		return nil
+	}
+	if streamSourceLanguage != autoDetectLanguage || !configuredLanguagePolicy.blocksLanguages() {
+		return h.translateFields(ctx, streamSourceLanguage, streamTargetLanguage, texts, indexes)
+	}
+
+	var (
+		languages  []string
+		byLanguage = map[string][]int{}
+	)
+	for _, i := range indexes {
+		language, err := detectLanguage(ctx, h.comprehendClient, texts[i])
+		if err != nil {
+			return fmt.Errorf("failed to detect the language of record %s: %w", records[i].sequenceNumber, err)
+		}
+		language = normalizeLanguageCode(language)
+		if err := checkDetectedLanguage(ctx, language, streamTargetLanguage); err != nil {
+			log.Printf("Forwarding record %s untranslated: %v", records[i].sequenceNumber, err)
+			texts[i] = ""
+			continue
+		}
+		if _, ok := byLanguage[language]; !ok {
+			languages = append(languages, language)
+		}
+		byLanguage[language] = append(byLanguage[language], i)
+	}
+	for _, language := range languages {
+		if err := h.translateFields(ctx, language, streamTargetLanguage, texts, byLanguage[language]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putStreamRecords writes the records to the output stream, keeping their partition keys so
+// the output is sharded like the input
+func (h *handler) putStreamRecords(ctx context.Context, records []streamRecord, data [][]byte) error {
+	for start := 0; start < len(data); start += maxStreamOutputBatch {
+		end := min(start+maxStreamOutputBatch, len(data))
+
+		entries := make([]kinesisTypes.PutRecordsRequestEntry, 0, end-start)
+		for i := start; i < end; i++ {
+			entries = append(entries, kinesisTypes.PutRecordsRequestEntry{
+				Data:         data[i],
+				PartitionKey: aws.String(records[i].partitionKey),
+			})
+		}
+
+		output, err := h.kinesisClient.PutRecords(ctx, &kinesis.PutRecordsInput{
+			StreamName: aws.String(streamOutputName),
+			Records:    entries,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to put records to %s: %w", streamOutputName, err)
+		}
+		if failed := aws.ToInt32(output.FailedRecordCount); failed > 0 {
+			return fmt.Errorf("failed to put %d records to %s", failed, streamOutputName)
+		}
+	}
+
+	return nil
+}
+
+// putDeliveryStreamRecords writes the records to the delivery stream, one per line
+func (h *handler) putDeliveryStreamRecords(ctx context.Context, data [][]byte) error {
+	for start := 0; start < len(data); start += maxStreamOutputBatch {
+		end := min(start+maxStreamOutputBatch, len(data))
+
+		records := make([]firehoseTypes.Record, 0, end-start)
+		for _, record := range data[start:end] {
+			records = append(records, firehoseTypes.Record{Data: append(bytes.Clone(record), '\n')})
+		}
+
+		output, err := h.firehoseClient.PutRecordBatch(ctx, &firehose.PutRecordBatchInput{
+			DeliveryStreamName: aws.String(streamDeliveryStreamName),
+			Records:            records,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to put records to %s: %w", streamDeliveryStreamName, err)
+		}
+		if failed := aws.ToInt32(output.FailedPutCount); failed > 0 {
+			return fmt.Errorf("failed to put %d records to %s", failed, streamDeliveryStreamName)
+		}
+	}
+
+	return nil
+}
+
+// lookupField returns the value of a field of the payload, nested fields are separated by dots
+func lookupField(payload map[string]any, path string) any {
+	var value any = payload
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	return value
+}
+
+// setField sets a field of the payload, creating the objects of a nested field as needed
+func setField(payload map[string]any, path string, value any) {
+	names := strings.Split(path, ".")
+	object := payload
+	for _, name := range names[:len(names)-1] {
+		child, ok := object[name].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			object[name] = child
+		}
+		object = child
+	}
+	object[names[len(names)-1]] = value
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/comprehend"
+	comprehendTypes "github.com/aws/aws-sdk-go-v2/service/comprehend/types"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+)
+
+func TestHandleStreamEvent(t *testing.T) {
+	translations := map[string]string{
+		"Muy bueno.": "Very good.",
+		"Lento.":     "Slow.",
+	}
+	record := func(shard, sequenceNumber, data string) events.KinesisEventRecord {
+		return events.KinesisEventRecord{
+			EventID: shard + ":" + sequenceNumber,
+			Kinesis: events.KinesisRecord{
+				SequenceNumber: sequenceNumber,
+				PartitionKey:   "user-" + sequenceNumber,
+				Data:           []byte(data),
+			},
+		}
+	}
+
+	tests := []struct {
+		name             string
+		textField        string
+		sourceLanguage   string
+		blocked          string
+		detected         map[string]string
+		records          []events.KinesisEventRecord
+		putError         error
+		expectedStream   []string
+		expectedFirehose []string
+		expectedFailures []string
+	}{
+		{
+			name:      "Text field is translated",
+			textField: "feedback",
+			records: []events.KinesisEventRecord{
+				record("shardId-0", "1", `{"id":12345678901234567890,"feedback":"Muy bueno."}`),
+				record("shardId-0", "2", `{"id":2,"feedback":""}`),
+				record("shardId-0", "3", `not json`),
+			},
+			expectedStream: []string{
+				`{"feedback":"Muy bueno.","feedback_translated":"Very good.","id":12345678901234567890}`,
+				`{"feedback":"","id":2}`,
+				`not json`,
+			},
+			expectedFirehose: []string{
+				"{\"feedback\":\"Muy bueno.\",\"feedback_translated\":\"Very good.\",\"id\":12345678901234567890}\n",
+				"{\"feedback\":\"\",\"id\":2}\n",
+				"not json\n",
+			},
+		},
+		{
+			name:      "Nested text field",
+			textField: "review.body",
+			records: []events.KinesisEventRecord{
+				record("shardId-0", "1", `{"review":{"body":"Lento."}}`),
+			},
+			expectedStream:   []string{`{"review":{"body":"Lento.","body_translated":"Slow."}}`},
+			expectedFirehose: []string{"{\"review\":{\"body\":\"Lento.\",\"body_translated\":\"Slow.\"}}\n"},
+		},
+		{
+			name:      "Failed shard is retried from its first record",
+			textField: "feedback",
+			records: []events.KinesisEventRecord{
+				record("shardId-0", "1", `{"feedback":"Lento."}`),
+				record("shardId-1", "7", `{"feedback":"Lento."}`),
+				record("shardId-1", "8", `{"feedback":"Muy bueno."}`),
+			},
+			putError:         fmt.Errorf("mock error"),
+			expectedFailures: []string{"1", "7"},
+		},
+		{
+			name:           "Record in a blocked language is forwarded untranslated",
+			textField:      "feedback",
+			sourceLanguage: autoDetectLanguage,
+			blocked:        "fr",
+			detected:       map[string]string{"Muy bueno.": "es", "Très bien.": "fr"},
+			records: []events.KinesisEventRecord{
+				record("shardId-0", "1", `{"feedback":"Muy bueno."}`),
+				record("shardId-0", "2", `{"feedback":"Très bien."}`),
+			},
+			expectedStream: []string{
+				`{"feedback":"Muy bueno.","feedback_translated":"Very good."}`,
+				`{"feedback":"Très bien."}`,
+			},
+			expectedFirehose: []string{
+				"{\"feedback\":\"Muy bueno.\",\"feedback_translated\":\"Very good.\"}\n",
+				"{\"feedback\":\"Très bien.\"}\n",
+			},
+		},
+		{
+			name:           "Blocked source language is forwarded untranslated",
+			textField:      "feedback",
+			sourceLanguage: "es",
+			blocked:        "es",
+			records: []events.KinesisEventRecord{
+				record("shardId-0", "1", `{"feedback":"Lento."}`),
+			},
+			expectedStream:   []string{`{"feedback":"Lento."}`},
+			expectedFirehose: []string{"{\"feedback\":\"Lento.\"}\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := []string{streamTextField, streamTranslatedField, streamOutputName, streamDeliveryStreamName}
+			streamTextField = tt.textField
+			streamTranslatedField = tt.textField + translatedFieldSuffix
+			streamOutputName = "translated-feedback"
+			streamDeliveryStreamName = "feedback-archive"
+			previousSource, previousPolicy := streamSourceLanguage, configuredLanguagePolicy
+			streamSourceLanguage = tt.sourceLanguage
+			configuredLanguagePolicy = languagePolicy{blocked: parseLanguageList(tt.blocked)}
+			defer func() {
+				streamTextField, streamTranslatedField, streamOutputName, streamDeliveryStreamName = previous[0], previous[1], previous[2], previous[3]
+				streamSourceLanguage, configuredLanguagePolicy = previousSource, previousPolicy
+			}()
+
+			var streamRecords, firehoseRecords []string
+
+			h := newMockHandler(translations)
+			h.comprehendClient = &MockComprehendClient{
+				DetectDominantLanguageFunc: func(ctx context.Context, params *comprehend.DetectDominantLanguageInput, optFns ...func(*comprehend.Options)) (*comprehend.DetectDominantLanguageOutput, error) {
+					return &comprehend.DetectDominantLanguageOutput{
+						Languages: []comprehendTypes.DominantLanguage{{LanguageCode: aws.String(tt.detected[aws.ToString(params.Text)]), Score: aws.Float32(0.99)}},
+					}, nil
+				},
+			}
+			h.kinesisClient = &MockKinesisClient{
+				PutRecordsFunc: func(ctx context.Context, params *kinesis.PutRecordsInput, optFns ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error) {
+					if tt.putError != nil {
+						return nil, tt.putError
+					}
+					for _, entry := range params.Records {
+						if aws.ToString(entry.PartitionKey) == "" {
+							t.Errorf("PutRecords() partition key is missing")
+						}
+						streamRecords = append(streamRecords, string(entry.Data))
+					}
+					return &kinesis.PutRecordsOutput{FailedRecordCount: aws.Int32(0)}, nil
+				},
+			}
+			h.firehoseClient = &MockFirehoseClient{
+				PutRecordBatchFunc: func(ctx context.Context, params *firehose.PutRecordBatchInput, optFns ...func(*firehose.Options)) (*firehose.PutRecordBatchOutput, error) {
+					for _, record := range params.Records {
+						firehoseRecords = append(firehoseRecords, string(record.Data))
+					}
+					return &firehose.PutRecordBatchOutput{FailedPutCount: aws.Int32(0)}, nil
+				},
+			}
+
+			got, err := h.handleStreamEvent(context.Background(), events.KinesisEvent{Records: tt.records})
+			if err != nil {
+				t.Errorf("handleStreamEvent() error = %v", err)
+				return
+			}
+
+			var failures []string
+			for _, failure := range got.BatchItemFailures {
+				failures = append(failures, failure.ItemIdentifier)
+			}
+			if !slices.Equal(failures, tt.expectedFailures) {
+				t.Errorf("handleStreamEvent() failures = %v, expected %v", failures, tt.expectedFailures)
+			}
+			if !slices.Equal(streamRecords, tt.expectedStream) {
+				t.Errorf("PutRecords() = %q, expected %q", streamRecords, tt.expectedStream)
+			}
+			if !slices.Equal(firehoseRecords, tt.expectedFirehose) {
+				t.Errorf("PutRecordBatch() = %q, expected %q", firehoseRecords, tt.expectedFirehose)
+			}
+		})
+	}
+}
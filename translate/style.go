@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+)
+
+const (
+	styleTechnical = "technical"
+	styleMarketing = "marketing"
+	styleCasual    = "casual"
+	styleLegal     = "legal"
+)
+
+// styleSettings are the AWS Translate settings of each style. Formality and brevity are only
+// applied by AWS Translate for the target languages that support them, other languages are
+// translated as without a style.
+var styleSettings = map[string]types.TranslationSettings{
+	styleTechnical: {Formality: types.FormalityFormal, Brevity: types.BrevityOn},
+	styleMarketing: {Formality: types.FormalityInformal, Brevity: types.BrevityOn},
+	styleCasual:    {Formality: types.FormalityInformal},
+	styleLegal:     {Formality: types.FormalityFormal},
+}
+
+type styleKey struct{}
+
+// withStyle returns a context translating every segment of the request in the style
+func withStyle(ctx context.Context, style string) context.Context {
+	if style == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, styleKey{}, style)
+}
+
+// styleFromContext returns the style of the request, empty when it has none
+func styleFromContext(ctx context.Context) string {
+	style, _ := ctx.Value(styleKey{}).(string)
+	return style
+}
+
+// translationSettings returns the provider settings of the route of the translation
+// overridden by those of the style of the request and by the brevity of a length constrained
+// translation, nil when there are none
+func translationSettings(ctx context.Context) *types.TranslationSettings {
+	settings := routeSettings(ctx)
+	if style, ok := styleSettings[styleFromContext(ctx)]; ok {
+		if settings == nil {
+			settings = &types.TranslationSettings{}
+		}
+		if style.Formality != "" {
+			settings.Formality = style.Formality
+		}
+		if style.Profanity != "" {
+			settings.Profanity = style.Profanity
+		}
+		settings.Brevity = style.Brevity
+	}
+	if brevityFromContext(ctx) {
+		if settings == nil {
+			settings = &types.TranslationSettings{}
+		}
+		settings.Brevity = types.BrevityOn
+	}
+	return settings
+}
+
+// cacheTargetLanguage returns the target language identifying the translations of the
+// request in the cache keys. A styled translation is keyed by the language and the style, a
+// shortened one by "+brief", a gender-neutral one by "+inclusive" and one keeping the names
+// of the text verbatim by "+entities" after them, and a translation with a glossary by the
+// glossary after an "@". Neither "+" nor "@" can appear in a language code so they never
+// collide with a plain key.
+func cacheTargetLanguage(ctx context.Context, targetLanguage string) string {
+	inclusive := inclusiveTerminology(ctx, targetLanguage) != ""
+	if style := styleFromContext(ctx); style != "" {
+		targetLanguage += "+" + style
+	}
+	if brevityFromContext(ctx) {
+		targetLanguage += "+brief"
+	}
+	if inclusive {
+		targetLanguage += "+inclusive"
+	}
+	if len(protectedEntitiesFromContext(ctx)) > 0 {
+		targetLanguage += "+entities"
+	}
+	if glossary := glossaryFromContext(ctx); glossary != "" {
+		targetLanguage += "@" + glossary
+	}
+	return targetLanguage
+}
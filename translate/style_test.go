@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+)
+
+func TestHandleStyle(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedSettings *types.TranslationSettings
+		expectedHash     string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name:             "No style",
+			body:             `{"source_language":"en","target_language":"es","text":"Hello."}`,
+			expectedHash:     getCacheKey("en", "es", "Hello."),
+			expectedResponse: events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: `{"translated_text":"Hola. "}`},
+		},
+		{
+			name:             "Legal style is formal",
+			body:             `{"source_language":"en","target_language":"es","text":"Hello.","style":"legal"}`,
+			expectedSettings: &types.TranslationSettings{Formality: types.FormalityFormal},
+			expectedHash:     getCacheKey("en", "es+legal", "Hello."),
+			expectedResponse: events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: `{"translated_text":"Hola. "}`},
+		},
+		{
+			name:             "Marketing style is informal and brief",
+			body:             `{"source_language":"en","target_language":"es","text":"Hello.","style":"marketing"}`,
+			expectedSettings: &types.TranslationSettings{Formality: types.FormalityInformal, Brevity: types.BrevityOn},
+			expectedHash:     getCacheKey("en", "es+marketing", "Hello."),
+			expectedResponse: events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: `{"translated_text":"Hola. "}`},
+		},
+		{
+			name: "Unsupported style",
+			body: `{"source_language":"en","target_language":"es","text":"Hello.","style":"poetic"}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"style","message":"style \"poetic\" is not supported"}]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				settings *types.TranslationSettings
+				lookups  []string
+				hashes   []string
+			)
+
+			h := newMockHandler(map[string]string{"Hello.": "Hola."})
+			h.translateClient.(*MockTranslateClient).TranslateTextFunc = func(ctx context.Context, params *translate.TranslateTextInput, optFns ...func(*translate.Options)) (*translate.TranslateTextOutput, error) {
+				settings = params.Settings
+				return &translate.TranslateTextOutput{TranslatedText: aws.String("Hola.")}, nil
+			}
+			h.dynamoClient = &MockDynamoDBClient{
+				GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					lookups = append(lookups, params.Key["hash"].(*dynamoTypes.AttributeValueMemberS).Value)
+					return &dynamodb.GetItemOutput{}, nil
+				},
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					hashes = append(hashes, params.Item["hash"].(*dynamoTypes.AttributeValueMemberS).Value)
+					return &dynamodb.PutItemOutput{}, nil
+				},
+			}
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %v, expected %v", got, tt.expectedResponse)
+			}
+			if tt.expectedHash == "" {
+				return
+			}
+			if (settings == nil) != (tt.expectedSettings == nil) || settings != nil && *settings != *tt.expectedSettings {
+				t.Errorf("TranslateText() settings = %+v, expected %+v", settings, tt.expectedSettings)
+			}
+			if !slices.Contains(lookups, tt.expectedHash) {
+				t.Errorf("GetItem() hashes = %v, expected %v", lookups, tt.expectedHash)
+			}
+			if !slices.Contains(hashes, tt.expectedHash) {
+				t.Errorf("PutItem() hashes = %v, expected %v", hashes, tt.expectedHash)
+			}
+		})
+	}
+}
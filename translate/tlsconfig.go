@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	// caBundleSSMPrefix and caBundleSecretPrefix select a CA bundle stored in an SSM parameter
+	// or a Secrets Manager secret rather than in a file
+	caBundleSSMPrefix    = "ssm:"
+	caBundleSecretPrefix = "secretsmanager:"
+
+	// maxCABundleSize bounds the CA bundle read at init
+	maxCABundleSize = 1024 * 1024
+)
+
+var (
+	// caBundleSource is where the root certificates trusted besides those of the system are
+	// read from, such as that of a TLS-inspecting egress proxy: a file, ssm:<parameter name>
+	// or secretsmanager:<secret id>. Empty to trust the system ones only.
+	caBundleSource string
+	// tlsMinVersion is the lowest TLS version the clients negotiate, 0 for the Go default
+	tlsMinVersion uint16
+)
+
+// parseTLSVersion parses a TLS version such as 1.2 or 1.3. An invalid version is logged and
+// ignored.
+func parseTLSVersion(value string) uint16 {
+	switch strings.TrimSpace(value) {
+	case "":
+		return 0
+	case "1.2":
+		return tls.VersionTLS12
+	case "1.3":
+		return tls.VersionTLS13
+	}
+	log.Printf("Ignoring invalid TLS version %q", value)
+	return 0
+}
+
+// loadTLSConfig returns the TLS configuration of the clients calling the services and the
+// providers, nil when the defaults apply. The CA bundle is read with the sdk configuration,
+// so a bundle stored in SSM or Secrets Manager is read through the endpoints of the services
+// and those of SERVICE_ENDPOINTS, not yet trusting it.
+func loadTLSConfig(ctx context.Context, cfg aws.Config) (*tls.Config, error) {
+	if caBundleSource == "" && tlsMinVersion == 0 {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tlsMinVersion}
+	if caBundleSource == "" {
+		return tlsConfig, nil
+	}
+
+	bundle, err := readCABundle(ctx, cfg, caBundleSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle %s: %w", caBundleSource, err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("CA bundle %s has no PEM certificate", caBundleSource)
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
+
+// readCABundle reads the PEM certificates of the source
+func readCABundle(ctx context.Context, cfg aws.Config, source string) ([]byte, error) {
+	if name, ok := strings.CutPrefix(source, caBundleSSMPrefix); ok {
+		var output struct {
+			Parameter struct {
+				Value string
+			}
+		}
+		input := map[string]any{"Name": name, "WithDecryption": true}
+		if err := callAWSJSON(ctx, cfg, serviceSSM, "AmazonSSM.GetParameter", input, &output); err != nil {
+			return nil, err
+		}
+		return []byte(output.Parameter.Value), nil
+	}
+	if id, ok := strings.CutPrefix(source, caBundleSecretPrefix); ok {
+		var output struct {
+			SecretString string
+		}
+		input := map[string]any{"SecretId": id}
+		if err := callAWSJSON(ctx, cfg, serviceSecretsManager, "secretsmanager.GetSecretValue", input, &output); err != nil {
+			return nil, err
+		}
+		return []byte(output.SecretString), nil
+	}
+	return os.ReadFile(source)
+}
+
+// callAWSJSON calls an action of a service speaking the AWS JSON protocol, signing the request
+// with the credentials of the configuration. It serves the services only read once at init,
+// which don't deserve an sdk module.
+func callAWSJSON(ctx context.Context, cfg aws.Config, service, target string, input, output any) error {
+	endpoint := fmt.Sprintf("https://%s.%s.amazonaws.com", service, cfg.Region)
+	if cfg.BaseEndpoint != nil {
+		endpoint = *cfg.BaseEndpoint
+	}
+	if override := serviceEndpoint(service, cfg.Region); override != nil {
+		endpoint = *override
+	}
+	body, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", target, err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", target, err)
+	}
+	request.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	request.Header.Set("X-Amz-Target", target)
+
+	if cfg.Credentials == nil {
+		return errors.New("no credentials to sign the request")
+	}
+	credentials, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+	payloadHash := sha256.Sum256(body)
+	if err := v4.NewSigner().SignHTTP(ctx, credentials, request, hex.EncodeToString(payloadHash[:]), service, cfg.Region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign %s request: %w", target, err)
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", target, err)
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(io.LimitReader(response.Body, maxCABundleSize))
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", target, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d: %s", target, response.StatusCode, responseBody)
+	}
+	if err := json.Unmarshal(responseBody, output); err != nil {
+		return fmt.Errorf("failed to unmarshal %s response: %w", target, err)
+	}
+	return nil
+}
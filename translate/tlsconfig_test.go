@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestParseTLSVersion(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected uint16
+	}{
+		{value: "", expected: 0},
+		{value: "1.2", expected: tls.VersionTLS12},
+		{value: " 1.3", expected: tls.VersionTLS13},
+		{value: "1.1", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := parseTLSVersion(tt.value); got != tt.expected {
+				t.Errorf("parseTLSVersion() = %d, expected %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	// The server of a TLS-inspecting proxy, its certificate not trusted by the system
+	proxied := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer proxied.Close()
+	bundle := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: proxied.Certificate().Raw}))
+
+	dir := t.TempDir()
+	bundleFile, emptyFile := filepath.Join(dir, "bundle.pem"), filepath.Join(dir, "empty.pem")
+	os.WriteFile(bundleFile, []byte(bundle), 0o600)
+	os.WriteFile(emptyFile, nil, 0o600)
+
+	var target string
+	services := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target = r.Header.Get("X-Amz-Target")
+		body, _ := io.ReadAll(r.Body)
+		switch string(body) {
+		case `{"Name":"/egress/ca","WithDecryption":true}`:
+			json.NewEncoder(w).Encode(map[string]any{"Parameter": map[string]string{"Value": bundle}})
+		case `{"SecretId":"egress-ca"}`:
+			json.NewEncoder(w).Encode(map[string]string{"SecretString": bundle})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer services.Close()
+	cfg := aws.Config{
+		Region:       "eu-west-1",
+		BaseEndpoint: aws.String(services.URL),
+		HTTPClient:   services.Client(),
+		Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+		}),
+	}
+
+	tests := []struct {
+		name           string
+		source         string
+		minVersion     uint16
+		expectedTarget string
+		expectedNil    bool
+		wantErr        bool
+	}{
+		{name: "Defaults", expectedNil: true},
+		{name: "Minimum version", minVersion: tls.VersionTLS13},
+		{name: "Bundle file", source: bundleFile},
+		{name: "SSM parameter", source: "ssm:/egress/ca", expectedTarget: "AmazonSSM.GetParameter"},
+		{name: "Secret", source: "secretsmanager:egress-ca", expectedTarget: "secretsmanager.GetSecretValue"},
+		{name: "Missing secret", source: "secretsmanager:other-ca", wantErr: true},
+		{name: "Missing file", source: filepath.Join(dir, "missing.pem"), wantErr: true},
+		{name: "No certificate", source: emptyFile, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousSource, previousVersion := caBundleSource, tlsMinVersion
+			caBundleSource, tlsMinVersion, target = tt.source, tt.minVersion, ""
+			defer func() { caBundleSource, tlsMinVersion = previousSource, previousVersion }()
+
+			got, err := loadTLSConfig(context.Background(), cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadTLSConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (got == nil) != tt.expectedNil {
+				t.Fatalf("loadTLSConfig() = %v, expected nil %t", got, tt.expectedNil)
+			}
+			if target != tt.expectedTarget {
+				t.Errorf("loadTLSConfig() called %q, expected %q", target, tt.expectedTarget)
+			}
+			if got == nil {
+				return
+			}
+			if got.MinVersion != tt.minVersion {
+				t.Errorf("loadTLSConfig() MinVersion = %d, expected %d", got.MinVersion, tt.minVersion)
+			}
+
+			// The sdk client trusts the proxy once it has the bundle
+			request, _ := http.NewRequest(http.MethodGet, proxied.URL, nil)
+			_, err = newSDKHTTPClient(got).Do(request)
+			if trusted := err == nil; trusted != (tt.source != "") {
+				t.Errorf("sdk client trusted the proxy = %t, error = %v", trusted, err)
+			}
+
+			// So do the other clients, through their own transport rather than the default one
+			request, _ = http.NewRequest(http.MethodGet, proxied.URL, nil)
+			_, err = newProviderHTTPClient(newProviderTransport(got), time.Second).Do(request)
+			if trusted := err == nil; trusted != (tt.source != "") {
+				t.Errorf("provider client trusted the proxy = %t, error = %v", trusted, err)
+			}
+			if defaults := http.DefaultTransport.(*http.Transport).TLSClientConfig; defaults != nil && (defaults.RootCAs != nil || defaults.MinVersion != 0) {
+				t.Errorf("default transport trusts %v from TLS %d, expected it untouched", defaults.RootCAs, defaults.MinVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	handlerModeCacheJob = "cache-job"
+
+	cacheJobExportTMX = "export_tmx"
+	cacheJobImportTMX = "import_tmx"
+
+	tmxVersion = "1.4"
+)
+
+// regionalLanguageCodes are the regional variants AWS Translate distinguishes, any other
+// TMX language tag is reduced to its primary language subtag
+var regionalLanguageCodes = map[string]bool{
+	"fa-AF": true,
+	"fr-CA": true,
+	"es-MX": true,
+	"pt-PT": true,
+	"zh-TW": true,
+}
+
+// CacheJobRequest is the event used to invoke a cache maintenance job
+type CacheJobRequest struct {
+	// Action is the job to run
+	Action string `json:"action"`
+	// Bucket is the S3 bucket holding the job file
+	Bucket string `json:"bucket"`
+	// Key is the S3 key of the job file
+	Key string `json:"key"`
+	// MaxAgeDays evicts the items cached more than this many days ago, 0 to keep them
+	MaxAgeDays int `json:"max_age_days"`
+	// IdleDays evicts the items without a hit in this many days, 0 to keep them
+	IdleDays int `json:"idle_days"`
+	// Rate is the number of items compaction scans and deletes per second, or the number of
+	// items re-translated per second
+	Rate int `json:"rate"`
+	// MaxRating re-translates the items whose average feedback rating is at most this, once
+	// they have MinRatings ratings, 0 to ignore the ratings
+	MaxRating  float64 `json:"max_rating"`
+	MinRatings int     `json:"min_ratings"`
+	// TranslatedBefore re-translates the items translated before this date, formatted as
+	// 2006-01-02, by Provider when set
+	TranslatedBefore string `json:"translated_before"`
+	Provider         string `json:"provider"`
+}
+
+// CacheJobResponse reports the outcome of a cache maintenance job
+type CacheJobResponse struct {
+	// Action is the job that was run
+	Action string `json:"action"`
+	// Items is the number of cache items processed
+	Items int `json:"items"`
+	// Skipped is the number of entries that could not be processed
+	Skipped int `json:"skipped,omitempty"`
+	// Scanned is the number of cache items compaction or re-translation looked at
+	Scanned int `json:"scanned,omitempty"`
+	// Partial is set when the job ran out of time, the next run resumes where it stopped
+	Partial bool `json:"partial,omitempty"`
+}
+
+// tmxDocument is a TMX translation memory document
+type tmxDocument struct {
+	XMLName xml.Name  `xml:"tmx"`
+	Version string    `xml:"version,attr"`
+	Header  tmxHeader `xml:"header"`
+	Units   []tmxUnit `xml:"body>tu"`
+}
+
+type tmxHeader struct {
+	CreationTool        string `xml:"creationtool,attr"`
+	CreationToolVersion string `xml:"creationtoolversion,attr"`
+	DataType            string `xml:"datatype,attr"`
+	SegType             string `xml:"segtype,attr"`
+	AdminLang           string `xml:"adminlang,attr"`
+	SrcLang             string `xml:"srclang,attr"`
+	OTMF                string `xml:"o-tmf,attr"`
+}
+
+type tmxUnit struct {
+	ID       string           `xml:"tuid,attr,omitempty"`
+	Variants []tmxUnitVariant `xml:"tuv"`
+}
+
+type tmxUnitVariant struct {
+	Lang    string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Segment string `xml:"seg"`
+}
+
+func (h *handler) handleCacheJob(ctx context.Context, request CacheJobRequest) (CacheJobResponse, error) {
+	defer flushTelemetry(ctx)
+
+	// Compaction and re-translation only work on the table
+	switch request.Action {
+	case cacheJobCompact:
+		return h.compactCache(ctx, request)
+	case cacheJobRetranslate:
+		return h.retranslateCache(ctx, request)
+	}
+
+	if request.Bucket == "" || request.Key == "" {
+		return CacheJobResponse{}, fmt.Errorf("bucket and key are required")
+	}
+
+	switch request.Action {
+	case cacheJobExportTMX:
+		return h.exportTMX(ctx, request)
+	case cacheJobImportTMX:
+		return h.importTMX(ctx, request)
+	default:
+		return CacheJobResponse{}, fmt.Errorf("action %q is not supported", request.Action)
+	}
+}
+
+// exportTMX writes every cached translation to a TMX file in S3. The table is streamed to
+// the file as it is scanned, it may not fit in the memory of the function.
+func (h *handler) exportTMX(ctx context.Context, request CacheJobRequest) (CacheJobResponse, error) {
+	upload := newMultipartUpload(ctx, h.s3Client, request.Bucket, request.Key, "application/x-tmx+xml")
+
+	items, err := h.writeTMX(ctx, upload)
+	if err != nil {
+		upload.Abort()
+		return CacheJobResponse{}, err
+	}
+	if err := upload.Close(); err != nil {
+		return CacheJobResponse{}, fmt.Errorf("error storing tmx: %w", err)
+	}
+
+	log.Printf("Exported %d cache items to s3://%s/%s", items, request.Bucket, request.Key)
+	return CacheJobResponse{Action: request.Action, Items: items}, nil
+}
+
+// writeTMX encodes the cached translations as a TMX document, returning the number of
+// translation units written
+func (h *handler) writeTMX(ctx context.Context, w io.Writer) (int, error) {
+	encoder, err := newTMXEncoder(w)
+	if err != nil {
+		return 0, err
+	}
+
+	items := 0
+	err = scanCacheItems(ctx, h.dynamoClient, func(item CacheItem) error {
+		// Cached responses and their references are whole documents rather than translation
+		// units, and negatively cached items have no translation
+		if item.Provider == providerResponseCache || item.Provider == providerResponseReference || item.Failure != "" {
+			return nil
+		}
+		if overflowKey := item.OverflowKey; overflowKey != "" {
+			resolved, err := h.resolveCacheItem(ctx, item)
+			if err != nil {
+				return fmt.Errorf("error loading overflowed text %s of cache item %s: %w", overflowKey, item.Hash, err)
+			}
+			item = resolved
+		}
+		if item.TranslatedText == "" {
+			return nil
+		}
+
+		items++
+		return encoder.encode(item)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error exporting cache: %w", err)
+	}
+
+	return items, encoder.close()
+}
+
+// importTMX reads a TMX file from S3 and caches its translation units
+func (h *handler) importTMX(ctx context.Context, request CacheJobRequest) (CacheJobResponse, error) {
+	object, err := h.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(request.Bucket),
+		Key:    aws.String(request.Key),
+	})
+	if err != nil {
+		return CacheJobResponse{}, fmt.Errorf("error fetching tmx: %w", err)
+	}
+	defer object.Body.Close()
+
+	items, skipped, err := unmarshalTMX(object.Body)
+	if err != nil {
+		return CacheJobResponse{}, err
+	}
+
+	errGroup, groupCtx := errgroup.WithContext(ctx)
+	errGroup.SetLimit(10) // Limit the number of concurrent writes
+
+	for _, item := range items {
+		errGroup.Go(func() error {
+			return h.storeCacheItem(groupCtx, item)
+		})
+	}
+
+	if err := errGroup.Wait(); err != nil {
+		return CacheJobResponse{}, fmt.Errorf("error caching tmx: %w", err)
+	}
+
+	log.Printf("Imported %d cache items from s3://%s/%s, skipped %d", len(items), request.Bucket, request.Key, skipped)
+	return CacheJobResponse{Action: request.Action, Items: len(items), Skipped: skipped}, nil
+}
+
+// scanCacheItems calls fn with every cache item in the table, page by page
+func scanCacheItems(ctx context.Context, dynamoClient DynamoDBClient, fn func(CacheItem) error) error {
+	var lastEvaluatedKey map[string]types.AttributeValue
+
+	for {
+		out, err := dynamoClient.Scan(ctx, &dynamodb.ScanInput{
+			TableName:         aws.String(translateTableName),
+			ExclusiveStartKey: lastEvaluatedKey,
+		})
+		if err != nil {
+			return err
+		}
+
+		// The items stored without their full source text can't be exported
+		for _, attributes := range out.Items {
+			if item, ok := cacheItemFromAttributes(attributes); ok && item.SourceRetention == "" {
+				if err := fn(item); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(out.LastEvaluatedKey) == 0 {
+			return nil
+		}
+		lastEvaluatedKey = out.LastEvaluatedKey
+	}
+}
+
+// tmxEncoder writes a TMX document unit by unit, so the document is never held in memory
+type tmxEncoder struct {
+	encoder *xml.Encoder
+}
+
+var (
+	tmxRoot = xml.StartElement{Name: xml.Name{Local: "tmx"}, Attr: []xml.Attr{{Name: xml.Name{Local: "version"}, Value: tmxVersion}}}
+	tmxBody = xml.StartElement{Name: xml.Name{Local: "body"}}
+)
+
+// newTMXEncoder writes the opening of the document to w
+func newTMXEncoder(w io.Writer) (*tmxEncoder, error) {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return nil, fmt.Errorf("failed to marshal tmx: %w", err)
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	header := tmxHeader{
+		CreationTool:        "gotranslate",
+		CreationToolVersion: "1.0",
+		DataType:            "plaintext",
+		SegType:             "sentence",
+		AdminLang:           "en",
+		SrcLang:             "*all*",
+		OTMF:                "gotranslate",
+	}
+	if err := encoder.EncodeToken(tmxRoot); err != nil {
+		return nil, fmt.Errorf("failed to marshal tmx: %w", err)
+	}
+	if err := encoder.EncodeElement(header, xml.StartElement{Name: xml.Name{Local: "header"}}); err != nil {
+		return nil, fmt.Errorf("failed to marshal tmx: %w", err)
+	}
+	if err := encoder.EncodeToken(tmxBody); err != nil {
+		return nil, fmt.Errorf("failed to marshal tmx: %w", err)
+	}
+
+	return &tmxEncoder{encoder: encoder}, nil
+}
+
+// encode writes the cache item as a translation unit
+func (e *tmxEncoder) encode(item CacheItem) error {
+	unit := tmxUnit{
+		ID: item.Hash,
+		Variants: []tmxUnitVariant{
+			{Lang: item.SourceLanguage, Segment: item.SourceText},
+			{Lang: item.TargetLanguage, Segment: item.TranslatedText},
+		},
+	}
+	if err := e.encoder.EncodeElement(unit, xml.StartElement{Name: xml.Name{Local: "tu"}}); err != nil {
+		return fmt.Errorf("failed to marshal tmx: %w", err)
+	}
+	return nil
+}
+
+// close writes the end of the document
+func (e *tmxEncoder) close() error {
+	if err := e.encoder.EncodeToken(tmxBody.End()); err != nil {
+		return fmt.Errorf("failed to marshal tmx: %w", err)
+	}
+	if err := e.encoder.EncodeToken(tmxRoot.End()); err != nil {
+		return fmt.Errorf("failed to marshal tmx: %w", err)
+	}
+	if err := e.encoder.Close(); err != nil {
+		return fmt.Errorf("failed to marshal tmx: %w", err)
+	}
+	return nil
+}
+
+// marshalTMX encodes the cache items as a TMX document
+func marshalTMX(items []CacheItem) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder, err := newTMXEncoder(&buf)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if err := encoder.encode(item); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalTMX decodes a TMX document into cache items for every language pair of every
+// translation unit. Multi-sentence segments are aligned sentence by sentence, units whose
+// sentence counts differ can't be aligned and are skipped.
+func unmarshalTMX(r io.Reader) ([]CacheItem, int, error) {
+	var document tmxDocument
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, 0, fmt.Errorf("failed to unmarshal tmx: %w", err)
+	}
+
+	srcLang := ""
+	if document.Header.SrcLang != "" && document.Header.SrcLang != "*all*" {
+		srcLang = normalizeTMXLanguage(document.Header.SrcLang)
+	}
+
+	var (
+		items   []CacheItem
+		skipped int
+	)
+
+	for _, unit := range document.Units {
+		for _, source := range unit.Variants {
+			sourceLanguage := normalizeTMXLanguage(source.Lang)
+			if srcLang != "" && sourceLanguage != srcLang {
+				continue
+			}
+
+			for _, target := range unit.Variants {
+				targetLanguage := normalizeTMXLanguage(target.Lang)
+				if sourceLanguage == targetLanguage {
+					continue
+				}
+
+				sourceSentences := splitSentences(source.Segment)
+				targetSentences := splitSentences(target.Segment)
+				if len(sourceSentences) == 0 || len(sourceSentences) != len(targetSentences) {
+					skipped++
+					continue
+				}
+
+				for i, sentence := range sourceSentences {
+					items = append(items, CacheItem{
+						Hash:           getCacheKey(sourceLanguage, targetLanguage, sentence),
+						TranslatedText: targetSentences[i],
+						SourceText:     sentence,
+						SourceLanguage: sourceLanguage,
+						TargetLanguage: targetLanguage,
+						Region:         region,
+						Provider:       providerTMX,
+					})
+				}
+			}
+		}
+	}
+
+	return items, skipped, nil
+}
+
+// normalizeTMXLanguage converts a TMX language tag such as en-US into the language code
+// used by the cache
+func normalizeTMXLanguage(lang string) string {
+	primary, region, found := strings.Cut(strings.ReplaceAll(lang, "_", "-"), "-")
+	primary = strings.ToLower(primary)
+	if !found {
+		return primary
+	}
+
+	code := primary + "-" + strings.ToUpper(region)
+	if regionalLanguageCodes[code] {
+		return code
+	}
+	return primary
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestNormalizeTMXLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Primary subtag", input: "EN", expected: "en"},
+		{name: "Region is dropped", input: "en-US", expected: "en"},
+		{name: "Underscore separator", input: "de_DE", expected: "de"},
+		{name: "Supported regional variant", input: "fr-ca", expected: "fr-CA"},
+		{name: "Chinese traditional", input: "zh-TW", expected: "zh-TW"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeTMXLanguage(tt.input)
+			if got != tt.expected {
+				t.Errorf("normalizeTMXLanguage() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUnmarshalTMX(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           string
+		expected        []CacheItem
+		expectedSkipped int
+		wantErr         bool
+	}{
+		{
+			name: "Bilingual unit with source language",
+			input: `<tmx version="1.4"><header srclang="en-US"/><body>
+				<tu><tuv xml:lang="en-US"><seg>Hello</seg></tuv><tuv xml:lang="es-ES"><seg>Hola</seg></tuv></tu>
+			</body></tmx>`,
+			expected: []CacheItem{
+				{Hash: getHashFromText("en-es-Hello"), TranslatedText: "Hola", SourceText: "Hello", SourceLanguage: "en", TargetLanguage: "es", Region: region, Provider: providerTMX},
+			},
+			expectedSkipped: 0,
+			wantErr:         false,
+		},
+		{
+			name: "All languages produce both directions",
+			input: `<tmx version="1.4"><header srclang="*all*"/><body>
+				<tu><tuv xml:lang="en"><seg>Hello</seg></tuv><tuv xml:lang="es"><seg>Hola</seg></tuv></tu>
+			</body></tmx>`,
+			expected: []CacheItem{
+				{Hash: getHashFromText("en-es-Hello"), TranslatedText: "Hola", SourceText: "Hello", SourceLanguage: "en", TargetLanguage: "es", Region: region, Provider: providerTMX},
+				{Hash: getHashFromText("es-en-Hola"), TranslatedText: "Hello", SourceText: "Hola", SourceLanguage: "es", TargetLanguage: "en", Region: region, Provider: providerTMX},
+			},
+			expectedSkipped: 0,
+			wantErr:         false,
+		},
+		{
+			name: "Multi-sentence segments are aligned",
+			input: `<tmx version="1.4"><header srclang="en"/><body>
+				<tu><tuv xml:lang="en"><seg>Hello world. How are you?</seg></tuv><tuv xml:lang="es"><seg>Hola mundo. ¿Cómo estás?</seg></tuv></tu>
+			</body></tmx>`,
+			expected: []CacheItem{
+				{Hash: getHashFromText("en-es-Hello world."), TranslatedText: "Hola mundo.", SourceText: "Hello world.", SourceLanguage: "en", TargetLanguage: "es", Region: region, Provider: providerTMX},
+				{Hash: getHashFromText("en-es-How are you?"), TranslatedText: "¿Cómo estás?", SourceText: "How are you?", SourceLanguage: "en", TargetLanguage: "es", Region: region, Provider: providerTMX},
+			},
+			expectedSkipped: 0,
+			wantErr:         false,
+		},
+		{
+			name: "Unaligned segments are skipped",
+			input: `<tmx version="1.4"><header srclang="en"/><body>
+				<tu><tuv xml:lang="en"><seg>Hello world. How are you?</seg></tuv><tuv xml:lang="es"><seg>Hola mundo, ¿cómo estás?</seg></tuv></tu>
+			</body></tmx>`,
+			expected:        nil,
+			expectedSkipped: 1,
+			wantErr:         false,
+		},
+		{
+			name:    "Invalid XML",
+			input:   `<tmx version="1.4"><body>`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, skipped, err := unmarshalTMX(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unmarshalTMX() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if len(got) != len(tt.expected) {
+				t.Errorf("unmarshalTMX() = %v, expected %v", got, tt.expected)
+				return
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("unmarshalTMX()[%d] = %v, expected %v", i, got[i], tt.expected[i])
+				}
+			}
+
+			if skipped != tt.expectedSkipped {
+				t.Errorf("unmarshalTMX() skipped = %d, expected %d", skipped, tt.expectedSkipped)
+			}
+		})
+	}
+}
+
+func TestMarshalTMXRoundTrip(t *testing.T) {
+	items := []CacheItem{
+		{Hash: getHashFromText("en-es-Fish & chips"), TranslatedText: "Pescado y papas", SourceText: "Fish & chips", SourceLanguage: "en", TargetLanguage: "es", Region: region, Provider: providerTMX},
+	}
+
+	body, err := marshalTMX(items)
+	if err != nil {
+		t.Errorf("marshalTMX() error = %v", err)
+		return
+	}
+
+	if !bytes.Contains(body, []byte(`<tuv xml:lang="en">`)) {
+		t.Errorf("marshalTMX() = %s, expected xml:lang attributes", body)
+	}
+
+	got, _, err := unmarshalTMX(bytes.NewReader(body))
+	if err != nil {
+		t.Errorf("unmarshalTMX() error = %v", err)
+		return
+	}
+
+	if len(got) != 2 || got[0] != items[0] {
+		t.Errorf("unmarshalTMX() = %v, expected %v first", got, items[0])
+	}
+}
+
+func TestHandleCacheJob(t *testing.T) {
+	cacheAttributes := map[string]dynamoTypes.AttributeValue{
+		"hash":            &dynamoTypes.AttributeValueMemberS{Value: "test-hash"},
+		"translated_text": &dynamoTypes.AttributeValueMemberS{Value: "Hola"},
+		"source_text":     &dynamoTypes.AttributeValueMemberS{Value: "Hello"},
+		"source_language": &dynamoTypes.AttributeValueMemberS{Value: "en"},
+		"target_language": &dynamoTypes.AttributeValueMemberS{Value: "es"},
+	}
+	// Negatively cached items have no translation to export
+	failureAttributes := map[string]dynamoTypes.AttributeValue{
+		"hash":            &dynamoTypes.AttributeValueMemberS{Value: "failure-hash"},
+		"translated_text": &dynamoTypes.AttributeValueMemberS{Value: ""},
+		"source_text":     &dynamoTypes.AttributeValueMemberS{Value: "Hello"},
+		"source_language": &dynamoTypes.AttributeValueMemberS{Value: "en"},
+		"target_language": &dynamoTypes.AttributeValueMemberS{Value: "es"},
+		"failure":         &dynamoTypes.AttributeValueMemberS{Value: "DetectedLanguageLowConfidenceException"},
+	}
+
+	tests := []struct {
+		name             string
+		request          CacheJobRequest
+		tmx              string
+		expectedResponse CacheJobResponse
+		expectedPuts     int
+		wantErr          bool
+	}{
+		{
+			name:             "Export",
+			request:          CacheJobRequest{Action: cacheJobExportTMX, Bucket: "bucket", Key: "cache.tmx"},
+			expectedResponse: CacheJobResponse{Action: cacheJobExportTMX, Items: 2},
+			expectedPuts:     0,
+			wantErr:          false,
+		},
+		{
+			name:    "Import",
+			request: CacheJobRequest{Action: cacheJobImportTMX, Bucket: "bucket", Key: "memory.tmx"},
+			tmx: `<tmx version="1.4"><header srclang="en"/><body>
+				<tu><tuv xml:lang="en"><seg>Hello</seg></tuv><tuv xml:lang="es"><seg>Hola</seg></tuv><tuv xml:lang="fr"><seg>Bonjour</seg></tuv></tu>
+			</body></tmx>`,
+			expectedResponse: CacheJobResponse{Action: cacheJobImportTMX, Items: 2},
+			expectedPuts:     2,
+			wantErr:          false,
+		},
+		{
+			name:    "Unsupported action",
+			request: CacheJobRequest{Action: "compact", Bucket: "bucket", Key: "key"},
+			wantErr: true,
+		},
+		{
+			name:    "Missing key",
+			request: CacheJobRequest{Action: cacheJobExportTMX, Bucket: "bucket"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu   sync.Mutex
+				puts int
+			)
+
+			h := &handler{
+				dynamoClient: &MockDynamoDBClient{
+					ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+						// Return two pages to exercise pagination
+						if params.ExclusiveStartKey == nil {
+							return &dynamodb.ScanOutput{
+								Items:            []map[string]dynamoTypes.AttributeValue{cacheAttributes},
+								LastEvaluatedKey: map[string]dynamoTypes.AttributeValue{"hash": cacheAttributes["hash"]},
+							}, nil
+						}
+						return &dynamodb.ScanOutput{Items: []map[string]dynamoTypes.AttributeValue{cacheAttributes, failureAttributes}}, nil
+					},
+					PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+						mu.Lock()
+						defer mu.Unlock()
+						puts++
+						return &dynamodb.PutItemOutput{}, nil
+					},
+				},
+				s3Client: &MockS3Client{
+					GetObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+						return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(tt.tmx))}, nil
+					},
+					PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+						body, _ := io.ReadAll(params.Body)
+						if count := bytes.Count(body, []byte("<tu ")); count != 2 {
+							return nil, fmt.Errorf("expected 2 translation units, got %d", count)
+						}
+						return &s3.PutObjectOutput{}, nil
+					},
+				},
+			}
+
+			got, err := h.handleCacheJob(context.Background(), tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handleCacheJob() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != tt.expectedResponse {
+				t.Errorf("handleCacheJob() = %v, expected %v", got, tt.expectedResponse)
+			}
+
+			if puts != tt.expectedPuts {
+				t.Errorf("handleCacheJob() cached %d items, expected %d", puts, tt.expectedPuts)
+			}
+		})
+	}
+}
+
+func TestWriteTMXReportsOverflowKey(t *testing.T) {
+	overflowed := map[string]dynamoTypes.AttributeValue{
+		"hash":            &dynamoTypes.AttributeValueMemberS{Value: "test-hash"},
+		"translated_text": &dynamoTypes.AttributeValueMemberS{Value: ""},
+		"source_text":     &dynamoTypes.AttributeValueMemberS{Value: ""},
+		"source_language": &dynamoTypes.AttributeValueMemberS{Value: "en"},
+		"target_language": &dynamoTypes.AttributeValueMemberS{Value: "es"},
+		"overflow_key":    &dynamoTypes.AttributeValueMemberS{Value: cacheOverflowPrefix + "test-hash"},
+	}
+
+	h := &handler{
+		dynamoClient: &MockDynamoDBClient{
+			ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+				return &dynamodb.ScanOutput{Items: []map[string]dynamoTypes.AttributeValue{overflowed}}, nil
+			},
+		},
+		s3Client: &MockS3Client{
+			GetObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+				return nil, fmt.Errorf("mock error")
+			},
+		},
+	}
+
+	_, err := h.writeTMX(context.Background(), io.Discard)
+	if err == nil || !strings.Contains(err.Error(), cacheOverflowPrefix+"test-hash") {
+		t.Errorf("writeTMX() error = %v, expected the overflow key", err)
+	}
+}
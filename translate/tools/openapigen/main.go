@@ -0,0 +1,505 @@
+// Command openapigen generates the OpenAPI document of the translation API from the request
+// and response types of the function, so the document can't drift from the code. It is run
+// by go generate in the function directory:
+//
+//	go generate ./...
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// versionedMediaType is the media type of the version 2 responses
+const versionedMediaType = "application/vnd.gotranslate.v2+json"
+
+// operation describes an operation of the API, whose schemas are named after the Go types
+type operation struct {
+	summary    string
+	parameters []parameter
+	// request is the type of the request body, empty for operations without one
+	request string
+	// responses are the types of the response bodies by status code, empty for plain text
+	responses map[string]string
+	// versioned adds the version 2 shape of the response to the successful response
+	versioned bool
+}
+
+type parameter struct {
+	name        string
+	in          string
+	description string
+	required    bool
+}
+
+// translateParameters are the parameters of the GET translation requests, mapped by
+// requestFromParameters
+var translateParameters = []parameter{
+	{name: "source_language", in: "query", description: "Language code of the source text, detected when omitted"},
+	{name: "target_language", in: "query", description: "Language code of the target text"},
+	{name: "text", in: "query", description: "Text to be translated"},
+	{name: "format", in: "query", description: "Format of the input"},
+	{name: "moderation", in: "query", description: "Moderation of the translated text, flag or mask"},
+	{name: "transliterate", in: "query", description: "Romanizes the text instead of translating it when true"},
+	{name: "style", in: "query", description: "Tone of the translation"},
+	{name: "no_store", in: "query", description: "Doesn't cache the translations when true"},
+	{name: "version", in: "query", description: "API version of the response shape"},
+	{name: "dry_run", in: "query", description: "Estimates the cost of the cache misses instead of translating them when true"},
+	{name: "max_characters", in: "query", description: "Caps the characters translated after the cache lookups"},
+	{name: "alignment", in: "query", description: "Returns the source sentences aligned with their translations when true"},
+	{name: "localize_formats", in: "query", description: "Formats the numbers, dates and currency amounts of English text for the target language when true"},
+	{name: "translate_metadata", in: "query", description: "Translates the title and SEO meta tags of HTML documents when true"},
+}
+
+// translateResponses are the responses of the translation requests
+var translateResponses = map[string]string{
+	"200": "TranslateResponse",
+	"400": "ValidationErrorResponse",
+	"403": "",
+	"406": "",
+	"413": "CharacterLimitResponse",
+	"422": "",
+	"503": "",
+}
+
+// pageURLResponses are the responses of the URL translation requests, which also fail when
+// the page can't be fetched
+var pageURLResponses = map[string]string{
+	"200": "TranslateResponse",
+	"400": "ValidationErrorResponse",
+	"403": "",
+	"406": "",
+	"413": "CharacterLimitResponse",
+	"422": "",
+	"502": "",
+	"503": "",
+}
+
+// documentIDParameter is the document id of the document registry paths
+var documentIDParameter = parameter{name: "id", in: "path", description: "Id of the document chosen by the client", required: true}
+
+// paths are the operations of the API by path and method, following the events of the
+// function in template.yaml
+var paths = map[string]map[string]operation{
+	"/translate": {
+		"post": {summary: "Translate a text or document", request: "TranslateRequest", responses: translateResponses, versioned: true},
+		"get":  {summary: "Translate a text", parameters: translateParameters, responses: translateResponses, versioned: true},
+	},
+	"/translate/{target}": {
+		"get": {
+			summary:    "Translate a text to the target language",
+			parameters: append([]parameter{{name: "target", in: "path", description: "Language code of the target text", required: true}}, translateParameters...),
+			responses:  translateResponses,
+			versioned:  true,
+		},
+	},
+	"/translate/url": {
+		"post": {summary: "Translate the web page of a URL", request: "TranslateRequest", responses: pageURLResponses, versioned: true},
+	},
+	"/translate/batch": {
+		"post": {summary: "Translate independent documents in one request", request: "BatchRequest", responses: map[string]string{"200": "BatchResponse", "400": "ValidationErrorResponse", "403": ""}},
+	},
+	"/cache/erasure": {
+		"post": {summary: "Erase texts from the translation cache", request: "ErasureRequest", responses: map[string]string{"200": "ErasureReport", "400": "", "500": "ErasureReport"}},
+	},
+	"/languages": {
+		"get": {summary: "List the supported target languages", responses: map[string]string{"200": "LanguagesResponse", "503": ""}},
+	},
+	"/documents/{id}/versions": {
+		"post": {
+			summary:    "Register a new version of a document",
+			parameters: []parameter{documentIDParameter},
+			request:    "DocumentVersionRequest",
+			responses:  map[string]string{"200": "DocumentVersion", "201": "DocumentVersion", "400": "ValidationErrorResponse", "409": "", "501": ""},
+		},
+	},
+	"/documents/{id}/translations/{language}": {
+		"get": {
+			summary: "Get the translation of a version of a document, translating it on first request",
+			parameters: []parameter{
+				documentIDParameter,
+				{name: "language", in: "path", description: "Language code of the translation", required: true},
+				{name: "version", in: "query", description: "Version of the document, the latest when omitted"},
+			},
+			responses: map[string]string{"200": "DocumentTranslation", "400": "ValidationErrorResponse", "403": "", "404": "", "422": "", "501": "", "503": ""},
+		},
+	},
+	"/documents/{id}/diff": {
+		"get": {
+			summary: "Compare the sentences of two versions of a document",
+			parameters: []parameter{
+				documentIDParameter,
+				{name: "from", in: "query", description: "Earlier version, the version before the later one when omitted"},
+				{name: "to", in: "query", description: "Later version, the latest when omitted"},
+			},
+			responses: map[string]string{"200": "DocumentDiff", "400": "ValidationErrorResponse", "404": "", "501": ""},
+		},
+	},
+	"/openapi.json": {
+		"get": {summary: "Get the OpenAPI document of the API", responses: map[string]string{"200": ""}},
+	},
+}
+
+func main() {
+	dir := flag.String("dir", ".", "directory of the function sources")
+	out := flag.String("out", "openapi.json", "path of the generated document")
+	flag.Parse()
+
+	document, err := generate(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, document, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate returns the OpenAPI document of the function whose sources are in the directory
+func generate(dir string) ([]byte, error) {
+	types, err := parseTypes(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{types: types, schemas: map[string]any{}, requestTypes: map[string]bool{}}
+	// The fields of the request types are optional, their validation is done by the function
+	for _, methods := range paths {
+		for _, op := range methods {
+			if op.request != "" {
+				g.markRequestType(op.request)
+			}
+		}
+	}
+
+	document := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "gotranslate",
+			"description": "Translates text and documents through a shared translation cache. Responses have the version 1 shape unless the version 2 media type is accepted.",
+			"version":     "2",
+		},
+		"paths": map[string]any{},
+		"components": map[string]any{
+			"schemas": g.schemas,
+			"securitySchemes": map[string]any{
+				"ApiKey": map[string]any{"type": "apiKey", "in": "header", "name": "x-api-key"},
+			},
+		},
+		"security": []any{map[string]any{"ApiKey": []any{}}},
+	}
+
+	for path, methods := range paths {
+		item := map[string]any{}
+		for method, op := range methods {
+			operation, err := g.operation(op)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+			item[method] = operation
+		}
+		document["paths"].(map[string]any)[path] = item
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// parseTypes returns the type declarations of the non-test sources of the directory by name,
+// with their doc comments
+func parseTypes(dir string) (map[string]*ast.TypeSpec, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	types := map[string]*ast.TypeSpec{}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		parsed, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range parsed.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				// The doc comment of a lone declaration is attached to the declaration
+				if typeSpec.Doc == nil && len(genDecl.Specs) == 1 {
+					typeSpec.Doc = genDecl.Doc
+				}
+				types[typeSpec.Name.Name] = typeSpec
+			}
+		}
+	}
+	return types, nil
+}
+
+type generator struct {
+	types map[string]*ast.TypeSpec
+	// schemas are the component schemas generated so far by name
+	schemas map[string]any
+	// requestTypes are the types reachable from a request body
+	requestTypes map[string]bool
+}
+
+func (g *generator) operation(op operation) (map[string]any, error) {
+	operation := map[string]any{"summary": op.summary}
+
+	if len(op.parameters) > 0 {
+		var parameters []any
+		for _, p := range op.parameters {
+			parameters = append(parameters, map[string]any{
+				"name":        p.name,
+				"in":          p.in,
+				"description": p.description,
+				"required":    p.required,
+				"schema":      map[string]any{"type": "string"},
+			})
+		}
+		operation["parameters"] = parameters
+	}
+
+	if op.request != "" {
+		schema, err := g.named(op.request)
+		if err != nil {
+			return nil, err
+		}
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": schema}},
+		}
+	}
+
+	responses := map[string]any{}
+	for status, name := range op.responses {
+		response := map[string]any{"description": statusDescription(status)}
+		if name == "" {
+			response["content"] = map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}}
+			if status == "200" {
+				response["content"] = map[string]any{"application/json": map[string]any{"schema": map[string]any{"type": "object"}}}
+			}
+			responses[status] = response
+			continue
+		}
+
+		schema, err := g.named(name)
+		if err != nil {
+			return nil, err
+		}
+		content := map[string]any{"application/json": map[string]any{"schema": schema}}
+		if op.versioned && status == "200" {
+			versioned, err := g.named(name + "V2")
+			if err != nil {
+				return nil, err
+			}
+			content[versionedMediaType] = map[string]any{"schema": versioned}
+		}
+		response["content"] = content
+		responses[status] = response
+	}
+	operation["responses"] = responses
+	return operation, nil
+}
+
+// statusDescription describes the responses of a status code
+func statusDescription(status string) string {
+	switch status {
+	case "200":
+		return "Success"
+	case "201":
+		return "Created"
+	case "400":
+		return "Invalid request"
+	case "406":
+		return "API version not supported"
+	case "403":
+		return "Language pair outside the language policy or the entitlement of the caller, or missing authorization claims"
+	case "404":
+		return "Not found"
+	case "409":
+		return "Conflicting concurrent request"
+	case "413":
+		return "Characters to translate exceed the character limit of the request"
+	case "422":
+		return "Target language not supported, text that could not be translated or a document without text or with several pages"
+	case "500":
+		return "Failed part way, with the work done before the failure"
+	case "501":
+		return "Feature not configured"
+	case "503":
+		return "Translation provider unavailable"
+	default:
+		return status
+	}
+}
+
+// markRequestType records the type and the types of its fields as request types
+func (g *generator) markRequestType(name string) {
+	if g.requestTypes[name] {
+		return
+	}
+	g.requestTypes[name] = true
+
+	spec, ok := g.types[name]
+	if !ok {
+		return
+	}
+	ast.Inspect(spec.Type, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Ident); ok {
+			if _, declared := g.types[ident.Name]; declared {
+				g.markRequestType(ident.Name)
+			}
+		}
+		return true
+	})
+}
+
+// named returns the reference to the component schema of a declared type, generating it on
+// first use. Types that aren't structs are inlined.
+func (g *generator) named(name string) (map[string]any, error) {
+	spec, ok := g.types[name]
+	if !ok {
+		return nil, fmt.Errorf("type %s is not declared", name)
+	}
+	structType, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return g.schema(spec.Type)
+	}
+
+	schemaName := exportedName(name)
+	ref := map[string]any{"$ref": "#/components/schemas/" + schemaName}
+	if _, done := g.schemas[schemaName]; done {
+		return ref, nil
+	}
+	// Recursive types refer to the schema while it is generated
+	g.schemas[schemaName] = nil
+
+	properties := map[string]any{}
+	var required []string
+	for _, field := range structType.Fields.List {
+		jsonName, omitEmpty, skip := jsonField(field)
+		for _, fieldName := range field.Names {
+			if skip || !fieldName.IsExported() {
+				continue
+			}
+			propertyName := jsonName
+			if propertyName == "" {
+				propertyName = fieldName.Name
+			}
+
+			property, err := g.schema(field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", name, fieldName.Name, err)
+			}
+			if description := describe(field.Doc); description != "" {
+				// Sibling keywords of a reference are ignored, the reference is wrapped
+				if _, isRef := property["$ref"]; isRef {
+					property = map[string]any{"allOf": []any{property}}
+				}
+				property["description"] = description
+			}
+			properties[propertyName] = property
+			if !omitEmpty && !g.requestTypes[name] {
+				required = append(required, propertyName)
+			}
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if description := describe(spec.Doc); description != "" {
+		schema["description"] = description
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	g.schemas[schemaName] = schema
+	return ref, nil
+}
+
+// schema returns the schema of a type expression
+func (g *generator) schema(expr ast.Expr) (map[string]any, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return map[string]any{"type": "string"}, nil
+		case "bool":
+			return map[string]any{"type": "boolean"}, nil
+		case "int", "int32", "int64", "uint", "uint32", "uint64":
+			return map[string]any{"type": "integer"}, nil
+		case "float32", "float64":
+			return map[string]any{"type": "number"}, nil
+		}
+		return g.named(t.Name)
+	case *ast.StarExpr:
+		return g.schema(t.X)
+	case *ast.ArrayType:
+		items, err := g.schema(t.Elt)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case *ast.MapType:
+		if key, ok := t.Key.(*ast.Ident); !ok || key.Name != "string" {
+			return nil, fmt.Errorf("map keys must be strings")
+		}
+		values, err := g.schema(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", expr)
+	}
+}
+
+// jsonField returns the name and omitempty option of the json tag of the field, and whether
+// the field is never marshalled
+func jsonField(field *ast.Field) (name string, omitEmpty, skip bool) {
+	if field.Tag == nil {
+		return "", false, false
+	}
+	tag := reflect.StructTag(strings.Trim(field.Tag.Value, "`")).Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	return name, strings.Contains(options, "omitempty"), false
+}
+
+// describe joins the lines of a doc comment into a description
+func describe(doc *ast.CommentGroup) string {
+	return strings.Join(strings.Fields(doc.Text()), " ")
+}
+
+// exportedName capitalizes the name of unexported types for their schema
+func exportedName(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
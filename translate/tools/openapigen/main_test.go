@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateUpToDate(t *testing.T) {
+	got, err := generate("../..")
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+	expected, err := os.ReadFile("../../openapi.json")
+	if err != nil {
+		t.Fatalf("failed to read the document: %v", err)
+	}
+	if string(got) != string(expected) {
+		t.Errorf("openapi.json is out of date, run go generate ./...")
+	}
+}
+
+func TestNamedSchema(t *testing.T) {
+	source := `package main
+
+// Example is an example response
+type Example struct {
+	// Text is the text
+	Text string ` + "`json:\"text\"`" + `
+	// Count is
+	// the count
+	Count    int               ` + "`json:\"count,omitempty\"`" + `
+	Labels   map[string]string ` + "`json:\"labels\"`" + `
+	Children []*child          ` + "`json:\"children,omitempty\"`" + `
+	Ignored  string            ` + "`json:\"-\"`" + `
+	Untagged bool
+	hidden   string
+}
+
+type child struct {
+	Value float64 ` + "`json:\"value\"`" + `
+}
+
+type Request struct {
+	Child child ` + "`json:\"child\"`" + `
+}
+`
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "example.go"), []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	types, err := parseTypes(dir)
+	if err != nil {
+		t.Fatalf("parseTypes() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		typeName     string
+		requestTypes []string
+		expected     map[string]any
+	}{
+		{
+			name:     "Response type",
+			typeName: "Example",
+			expected: map[string]any{
+				"Example": map[string]any{
+					"type":        "object",
+					"description": "Example is an example response",
+					"properties": map[string]any{
+						"text":     map[string]any{"type": "string", "description": "Text is the text"},
+						"count":    map[string]any{"type": "integer", "description": "Count is the count"},
+						"labels":   map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+						"children": map[string]any{"type": "array", "items": map[string]any{"$ref": "#/components/schemas/Child"}},
+						"Untagged": map[string]any{"type": "boolean"},
+					},
+					"required": []string{"Untagged", "labels", "text"},
+				},
+				"Child": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"value": map[string]any{"type": "number"}},
+					"required":   []string{"value"},
+				},
+			},
+		},
+		{
+			name:         "Request type",
+			typeName:     "Request",
+			requestTypes: []string{"Request"},
+			expected: map[string]any{
+				"Request": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"child": map[string]any{"$ref": "#/components/schemas/Child"}},
+				},
+				"Child": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"value": map[string]any{"type": "number"}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &generator{types: types, schemas: map[string]any{}, requestTypes: map[string]bool{}}
+			for _, name := range tt.requestTypes {
+				g.markRequestType(name)
+			}
+
+			if _, err := g.named(tt.typeName); err != nil {
+				t.Fatalf("named() error = %v", err)
+			}
+
+			// Compare the JSON documents so the types of the maps don't matter
+			got, _ := json.Marshal(g.schemas)
+			expected, _ := json.Marshal(tt.expected)
+			var gotValue, expectedValue any
+			json.Unmarshal(got, &gotValue)
+			json.Unmarshal(expected, &expectedValue)
+			if !reflect.DeepEqual(gotValue, expectedValue) {
+				t.Errorf("named() schemas = %s, expected %s", got, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracesExporterOTLP = "otlp"
+
+	// traceIDHeader returns the trace ID of the request to the caller
+	traceIDHeader = "X-Trace-Id"
+)
+
+var (
+	tracer = otel.Tracer("translate")
+
+	// tracerProvider is nil when traces are only recorded by the X-Ray SDK
+	tracerProvider *sdktrace.TracerProvider
+)
+
+// setupTracing installs the trace propagators and, when TRACES_EXPORTER is set, an
+// OpenTelemetry tracer provider using X-Ray compatible IDs so the collector can forward the
+// spans to X-Ray
+func setupTracing(ctx context.Context) error {
+	// Propagators extract in order, so a W3C traceparent wins over an X-Ray trace header
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		xray.Propagator{},
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	switch tracesExporter {
+	case "":
+		return nil
+	case tracesExporterOTLP:
+	default:
+		return fmt.Errorf("traces exporter %q is not supported", tracesExporter)
+	}
+
+	// The endpoint and headers are read from the standard OTEL_EXPORTER_OTLP_* variables
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create otlp trace exporter: %w", err)
+	}
+
+	tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithIDGenerator(xray.NewIDGenerator()),
+	)
+	otel.SetTracerProvider(tracerProvider)
+
+	return nil
+}
+
+// startRequestSpan continues the trace of the incoming request headers
+func startRequestSpan(ctx context.Context, event events.APIGatewayProxyRequest) (context.Context, trace.Span) {
+	// API Gateway keeps the header case used by the caller, http.Header matches any case
+	carrier := make(propagation.HeaderCarrier, len(event.Headers))
+	for name, value := range event.Headers {
+		http.Header(carrier).Set(name, value)
+	}
+
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	return tracer.Start(ctx, "translate", trace.WithSpanKind(trace.SpanKindServer))
+}
+
+// endRequestSpan records the response status on the span and returns the trace ID to the caller
+func endRequestSpan(span trace.Span, response *events.APIGatewayProxyResponse) {
+	defer span.End()
+
+	if response.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, response.Body)
+	}
+
+	spanContext := span.SpanContext()
+	if !spanContext.HasTraceID() {
+		return
+	}
+	if response.Headers == nil {
+		response.Headers = make(map[string]string)
+	}
+	response.Headers[traceIDHeader] = spanContext.TraceID().String()
+}
+
+// flushTelemetry exports the metrics and spans recorded during the invocation before the
+// Lambda environment is frozen
+func flushTelemetry(ctx context.Context) {
+	// The shadow comparisons of the invocation record their divergence in the background
+	shadowComparisons.drain(shadowDrainTimeout)
+	if meterProvider != nil {
+		if err := meterProvider.ForceFlush(ctx); err != nil {
+			log.Printf("Error flushing metrics: %v", err)
+		}
+	}
+	if tracerProvider != nil {
+		if err := tracerProvider.ForceFlush(ctx); err != nil {
+			log.Printf("Error flushing traces: %v", err)
+		}
+	}
+}
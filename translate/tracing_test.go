@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestHandleTracePropagation(t *testing.T) {
+	if err := setupTracing(context.Background()); err != nil {
+		t.Fatalf("setupTracing() error = %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		headers         map[string]string
+		expectedTraceID string
+	}{
+		{
+			name:            "W3C trace context",
+			headers:         map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:            "Header names are case insensitive",
+			headers:         map[string]string{"Traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:            "X-Ray trace header",
+			headers:         map[string]string{"X-Amzn-Trace-Id": "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"},
+			expectedTraceID: "5759e988bd862e3fe1be46a994272793",
+		},
+		{
+			name:            "W3C trace context wins over X-Ray",
+			headers:         map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "X-Amzn-Trace-Id": "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"},
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:            "No trace headers",
+			headers:         nil,
+			expectedTraceID: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(map[string]string{"Hello": "Hola"})
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{
+				Headers: tt.headers,
+				Body:    `{"source_language":"en","target_language":"es","text":"Hello"}`,
+			})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+
+			if got.Headers[traceIDHeader] != tt.expectedTraceID {
+				t.Errorf("handle() trace ID = %v, expected %v", got.Headers[traceIDHeader], tt.expectedTraceID)
+			}
+		})
+	}
+}
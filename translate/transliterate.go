@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// romanization maps lower case Cyrillic (BGN/PCGN) and Greek (ELOT 743) letters to Latin
+var romanization = map[rune]string{
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'е': "e", 'ё': "yo", 'є': "ye",
+	'ж': "zh", 'з': "z", 'и': "i", 'і': "i", 'ї': "yi", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh",
+	'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+	// Greek
+	'α': "a", 'ά': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'έ': "e", 'ζ': "z", 'η': "i",
+	'ή': "i", 'θ': "th", 'ι': "i", 'ί': "i", 'ϊ': "i", 'ΐ': "i", 'κ': "k", 'λ': "l", 'μ': "m",
+	'ν': "n", 'ξ': "x", 'ο': "o", 'ό': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t",
+	'υ': "y", 'ύ': "y", 'ϋ': "y", 'ΰ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o", 'ώ': "o",
+}
+
+// transliterateText romanizes the Cyrillic and Greek letters of the text, leaving every
+// other character untouched. Names and addresses keep their sound instead of their meaning.
+func transliterateText(text string) string {
+	var builder strings.Builder
+	for _, r := range text {
+		lower := unicode.ToLower(r)
+		latin, ok := romanization[lower]
+		if !ok {
+			builder.WriteRune(r)
+			continue
+		}
+
+		// Upper case letters only capitalise the first letter of their romanization
+		if lower != r && latin != "" {
+			first, size := utf8.DecodeRuneInString(latin)
+			latin = string(unicode.ToUpper(first)) + latin[size:]
+		}
+		builder.WriteString(latin)
+	}
+	return builder.String()
+}
+
+// handleTransliteration romanizes the text of a request
+func handleTransliteration(ctx context.Context, request TranslateRequest) events.APIGatewayProxyResponse {
+	if request.Format != formatHTML {
+		return newJSONResponse(ctx, TranslateResponse{TranslatedText: transliterateText(request.Text)})
+	}
+
+	transliterated, err := transliterateHTML(request.Text)
+	if err != nil {
+		log.Printf("Error transliterating html: %v", err)
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       "Error during transliteration",
+		}
+	}
+
+	return newJSONResponse(ctx, TranslateResponse{TranslatedText: transliterated})
+}
+
+// transliterateHTML romanizes the text nodes of an HTML document, leaving the markup untouched
+func transliterateHTML(input string) (string, error) {
+	tokens, sentences, sentenceCounts, err := getTextFromHTML(input, defaultSegmenter)
+	if err != nil {
+		return "", err
+	}
+
+	for i, sentence := range sentences {
+		sentences[i] = transliterateText(sentence)
+	}
+
+	return reconstructHTML(tokens, sentenceCounts, sentences)
+}
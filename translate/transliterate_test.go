@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestTransliterateText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "Russian address", text: "ул. Щорса, д. 5, Москва", expected: "ul. Shchorsa, d. 5, Moskva"},
+		{name: "Ukrainian name", text: "Юлія Їжакевич", expected: "Yuliya Yizhakevich"},
+		{name: "Greek", text: "Οδός Ψαρών, Αθήνα", expected: "Odos Psaron, Athina"},
+		{name: "Hard and soft signs are dropped", text: "Подъезд Ильича", expected: "Podezd Ilicha"},
+		{name: "Latin text is unchanged", text: "Calle Mayor 1, Madrid", expected: "Calle Mayor 1, Madrid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := transliterateText(tt.text); got != tt.expected {
+				t.Errorf("transliterateText() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandleTransliteration(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedResponse events.APIGatewayProxyResponse
+	}{
+		{
+			name: "Text",
+			body: `{"source_language":"ru","target_language":"en","text":"Москва","transliterate":true}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"Moskva"}`,
+			},
+		},
+		{
+			name: "HTML",
+			body: `{"source_language":"ru","target_language":"en","format":"html","text":"<p>Москва <b>Кремль</b></p>","transliterate":true}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Body:       `{"translated_text":"\u003cp\u003eMoskva \u003cb\u003eKreml\u003c/b\u003e\u003c/p\u003e"}`,
+			},
+		},
+		{
+			name: "Documents can't be transliterated",
+			body: `{"source_language":"ru","target_language":"en","format":"pdf","document":"JVBERi0=","transliterate":true}`,
+			expectedResponse: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       `{"error":"Invalid request","fields":[{"field":"transliterate","message":"transliterate is only supported for text and html"}]}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(nil)
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Errorf("handle() error = %v", err)
+				return
+			}
+
+			if got.StatusCode != tt.expectedResponse.StatusCode || got.Body != tt.expectedResponse.Body {
+				t.Errorf("handle() = %v, expected %v", got, tt.expectedResponse)
+			}
+		})
+	}
+}
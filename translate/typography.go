@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	typographyQuotes   = "quotes"
+	typographyDashes   = "dashes"
+	typographyEllipses = "ellipses"
+
+	// typographyASCII normalizes to straight quotes, -- and ...
+	typographyASCII = "ascii"
+	// typographyTypographic normalizes to the quotation marks and dash of the target language
+	// and to …
+	typographyTypographic = "typographic"
+)
+
+// configuredTypography is the normalization of the translations of the requests without
+// their own, such as quotes=typographic,dashes=typographic,ellipses=ascii
+var configuredTypography typography
+
+// typography is the normalization of the punctuation of the translations, each option either
+// "ascii" or "typographic", empty to keep the punctuation of the provider
+type typography struct {
+	Quotes   string
+	Dashes   string
+	Ellipses string
+}
+
+// quoteMarks are the opening and closing double and single quotation marks of a language
+type quoteMarks struct {
+	open, close             string
+	openSingle, closeSingle string
+}
+
+// quoteConventions are the quotation marks of the target languages by language tag or base
+// language, the other languages are quoted as English. French guillemets are spaced with a
+// no-break space.
+var quoteConventions = map[string]quoteMarks{
+	"en":    {open: "“", close: "”", openSingle: "‘", closeSingle: "’"},
+	"cs":    {open: "„", close: "“", openSingle: "‚", closeSingle: "‘"},
+	"da":    {open: "»", close: "«", openSingle: "›", closeSingle: "‹"},
+	"de":    {open: "„", close: "“", openSingle: "‚", closeSingle: "‘"},
+	"es":    {open: "«", close: "»", openSingle: "“", closeSingle: "”"},
+	"fi":    {open: "”", close: "”", openSingle: "’", closeSingle: "’"},
+	"fr":    {open: "«\u00a0", close: "\u00a0»", openSingle: "‹\u00a0", closeSingle: "\u00a0›"},
+	"it":    {open: "«", close: "»", openSingle: "“", closeSingle: "”"},
+	"ja":    {open: "「", close: "」", openSingle: "『", closeSingle: "』"},
+	"nb":    {open: "«", close: "»", openSingle: "‘", closeSingle: "’"},
+	"pl":    {open: "„", close: "”", openSingle: "«", closeSingle: "»"},
+	"pt":    {open: "«", close: "»", openSingle: "“", closeSingle: "”"},
+	"pt-BR": {open: "“", close: "”", openSingle: "‘", closeSingle: "’"},
+	"ru":    {open: "«", close: "»", openSingle: "„", closeSingle: "“"},
+	"sv":    {open: "”", close: "”", openSingle: "’", closeSingle: "’"},
+	"uk":    {open: "«", close: "»", openSingle: "„", closeSingle: "“"},
+	"zh":    {open: "“", close: "”", openSingle: "‘", closeSingle: "’"},
+	"zh-TW": {open: "「", close: "」", openSingle: "『", closeSingle: "』"},
+}
+
+// dashConventions are the sentence dashes of the target languages spacing an en dash rather
+// than using an em dash
+var dashConventions = map[string]string{
+	"cs": "–",
+	"da": "–",
+	"de": "–",
+	"fi": "–",
+	"nb": "–",
+	"pl": "–",
+	"sv": "–",
+}
+
+// ellipsisConventions are the ellipses of the target languages not using a single …
+var ellipsisConventions = map[string]string{
+	"zh": "……",
+}
+
+const (
+	// doubleQuotes and singleQuotes are the quotation marks normalized, guillemets being
+	// spaced in French
+	doubleQuotes = "\"“”„‟«»"
+	singleQuotes = "'‘’‚‛‹›"
+	guillemets   = "«»‹›"
+)
+
+var (
+	dashesPattern   = regexp.MustCompile(`-{2,}|—`)
+	ellipsesPattern = regexp.MustCompile(`\.{3,}|…+`)
+)
+
+type typographyKey struct{}
+
+// parseTypography parses a list of options separated by commas, such as
+// quotes=typographic,ellipses=ascii
+func parseTypography(value string) (typography, error) {
+	var t typography
+	for _, option := range strings.Split(value, ",") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+
+		name, normalization, _ := strings.Cut(option, "=")
+		normalization = strings.TrimSpace(normalization)
+		if normalization != typographyASCII && normalization != typographyTypographic {
+			return typography{}, fmt.Errorf("typography %q must be %s or %s", option, typographyASCII, typographyTypographic)
+		}
+		switch strings.TrimSpace(name) {
+		case typographyQuotes:
+			t.Quotes = normalization
+		case typographyDashes:
+			t.Dashes = normalization
+		case typographyEllipses:
+			t.Ellipses = normalization
+		default:
+			return typography{}, fmt.Errorf("typography option %q is not supported", strings.TrimSpace(name))
+		}
+	}
+	return t, nil
+}
+
+// parseTypographyConfig parses the configured typography, normalizing nothing when invalid
+func parseTypographyConfig(value string) typography {
+	t, err := parseTypography(value)
+	if err != nil {
+		log.Printf("Ignoring invalid typography configuration: %v", err)
+	}
+	return t
+}
+
+// withTypography returns a context normalizing the translations with the options of the
+// request, the configured typography applying to the others. The options are validated.
+func withTypography(ctx context.Context, options string) context.Context {
+	t, _ := parseTypography(options)
+	if t.Quotes == "" {
+		t.Quotes = configuredTypography.Quotes
+	}
+	if t.Dashes == "" {
+		t.Dashes = configuredTypography.Dashes
+	}
+	if t.Ellipses == "" {
+		t.Ellipses = configuredTypography.Ellipses
+	}
+	if t == (typography{}) {
+		return ctx
+	}
+	return context.WithValue(ctx, typographyKey{}, t)
+}
+
+// typographyFromContext returns the normalization of the translations of the request
+func typographyFromContext(ctx context.Context) typography {
+	t, _ := ctx.Value(typographyKey{}).(typography)
+	return t
+}
+
+// languageConvention returns the convention of the target language by language tag or base
+// language
+func languageConvention[T any](conventions map[string]T, targetLanguage string) (T, bool) {
+	tag := language.Make(targetLanguage)
+	if convention, ok := conventions[tag.String()]; ok {
+		return convention, true
+	}
+	base, _ := tag.Base()
+	convention, ok := conventions[base.String()]
+	return convention, ok
+}
+
+// normalizeTypography normalizes the quotation marks, dashes and ellipses of the translations
+// with the conventions of the target language. The cached translations are kept as the
+// provider returned them, so changing the typography needs no new translation.
+func normalizeTypography(targetLanguage string, t typography, translations []string) {
+	for i, translated := range translations {
+		if t.Quotes != "" {
+			translated = normalizeQuotes(targetLanguage, t.Quotes, translated)
+		}
+		if t.Dashes != "" {
+			translated = normalizeDashes(targetLanguage, t.Dashes, translated)
+		}
+		if t.Ellipses != "" {
+			translated = normalizeEllipses(targetLanguage, t.Ellipses, translated)
+		}
+		translations[i] = translated
+	}
+}
+
+// normalizeQuotes replaces the quotation marks of the text with straight quotes or those of
+// the target language. A mark is opening at the start of the text or after a space or an
+// opening bracket when text follows it and closing otherwise, and a single quote between
+// letters is an apostrophe. Guillemets keep their direction.
+func normalizeQuotes(targetLanguage, normalization, text string) string {
+	marks, ok := languageConvention(quoteConventions, targetLanguage)
+	if !ok {
+		marks = quoteConventions["en"]
+	}
+	if normalization == typographyASCII {
+		marks = quoteMarks{open: `"`, close: `"`, openSingle: "'", closeSingle: "'"}
+	}
+
+	runes := []rune(text)
+	first := strings.IndexAny(text, guillemets)
+	reversed := first >= 0 && strings.ContainsRune("»›", []rune(text[first:])[0])
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		double := strings.ContainsRune(doubleQuotes, r)
+		if !double && !strings.ContainsRune(singleQuotes, r) {
+			b.WriteRune(r)
+			continue
+		}
+
+		var previous, next rune
+		if i > 0 {
+			previous = runes[i-1]
+		}
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		if !double && unicode.IsLetter(previous) && unicode.IsLetter(next) {
+			if normalization == typographyASCII {
+				b.WriteRune('\'')
+			} else {
+				b.WriteRune('’')
+			}
+			continue
+		}
+
+		opening := (previous == 0 || unicode.IsSpace(previous) || strings.ContainsRune("([{-–—", previous)) &&
+			next != 0 && !unicode.IsSpace(next)
+		// Spaced guillemets have spaces on both sides, their direction is that of the glyph
+		// unless the text opens with » as in Danish. Their spacing is replaced by the spacing
+		// of the target language.
+		if strings.ContainsRune(guillemets, r) {
+			opening = strings.ContainsRune("«‹", r) != reversed
+			if opening {
+				for i+1 < len(runes) && isQuoteSpace(runes[i+1]) {
+					i++
+				}
+			} else {
+				trimmed := strings.TrimRightFunc(b.String(), isQuoteSpace)
+				b.Reset()
+				b.WriteString(trimmed)
+			}
+		}
+
+		switch {
+		case double && opening:
+			b.WriteString(marks.open)
+		case double:
+			b.WriteString(marks.close)
+		case opening:
+			b.WriteString(marks.openSingle)
+		default:
+			b.WriteString(marks.closeSingle)
+		}
+	}
+	return b.String()
+}
+
+// isQuoteSpace reports whether the rune is a space separating a guillemet from its quote
+func isQuoteSpace(r rune) bool {
+	return r == ' ' || r == '\u00a0' || r == '\u202f'
+}
+
+// normalizeDashes replaces the double hyphens of the text with the dash of the target
+// language, or its dashes with double hyphens. Single hyphens and the en dashes of ranges are
+// kept.
+func normalizeDashes(targetLanguage, normalization, text string) string {
+	dash, ok := languageConvention(dashConventions, targetLanguage)
+	if !ok {
+		dash = "—"
+	}
+	return dashesPattern.ReplaceAllStringFunc(text, func(match string) string {
+		switch {
+		case normalization == typographyASCII && match == "—":
+			return "--"
+		case normalization == typographyTypographic && (match == "--" || match == "—"):
+			return dash
+		}
+		// Longer runs of hyphens are rules or separators
+		return match
+	})
+}
+
+// normalizeEllipses replaces the three dots of the text with the ellipsis of the target
+// language, or its ellipses with three dots. Longer runs of dots are kept.
+func normalizeEllipses(targetLanguage, normalization, text string) string {
+	ellipsis, ok := languageConvention(ellipsisConventions, targetLanguage)
+	if !ok {
+		ellipsis = "…"
+	}
+	return ellipsesPattern.ReplaceAllStringFunc(text, func(match string) string {
+		switch {
+		case normalization == typographyASCII && strings.HasPrefix(match, "…"):
+			return "..."
+		case normalization == typographyTypographic && (match == "..." || strings.HasPrefix(match, "…")):
+			return ellipsis
+		}
+		return match
+	})
+}
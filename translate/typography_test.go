@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestNormalizeTypography(t *testing.T) {
+	tests := []struct {
+		name           string
+		targetLanguage string
+		typography     typography
+		translation    string
+		expected       string
+	}{
+		{
+			name:           "English quotes",
+			targetLanguage: "en",
+			typography:     typography{Quotes: typographyTypographic},
+			translation:    `He said "it's 'fine'" (honestly).`,
+			expected:       "He said “it’s ‘fine’” (honestly).",
+		},
+		{
+			name:           "German quotes",
+			targetLanguage: "de",
+			typography:     typography{Quotes: typographyTypographic},
+			translation:    `Er sagte "Hallo" und ging.`,
+			expected:       "Er sagte „Hallo“ und ging.",
+		},
+		{
+			name:           "French guillemets",
+			targetLanguage: "fr-CA",
+			typography:     typography{Quotes: typographyTypographic},
+			translation:    `Il a dit "bonjour" puis «au revoir».`,
+			expected:       "Il a dit «\u00a0bonjour\u00a0» puis «\u00a0au revoir\u00a0».",
+		},
+		{
+			name:           "Straight quotes",
+			targetLanguage: "fr",
+			typography:     typography{Quotes: typographyASCII},
+			translation:    "Il a dit «\u00a0bonjour\u00a0» et l’a ‘répété’.",
+			expected:       `Il a dit "bonjour" et l'a 'répété'.`,
+		},
+		{
+			name:           "Danish guillemets",
+			targetLanguage: "da",
+			typography:     typography{Quotes: typographyTypographic},
+			translation:    "Han sagde »hej« og \"farvel\".",
+			expected:       "Han sagde »hej« og »farvel«.",
+		},
+		{
+			name:           "Japanese quotes",
+			targetLanguage: "ja",
+			typography:     typography{Quotes: typographyTypographic},
+			translation:    `彼は "こんにちは" と言った。`,
+			expected:       "彼は 「こんにちは」 と言った。",
+		},
+		{
+			name:           "Em dashes",
+			targetLanguage: "en",
+			typography:     typography{Dashes: typographyTypographic},
+			translation:    "Wait -- pages 1-5, 10–12 ---",
+			expected:       "Wait — pages 1-5, 10–12 ---",
+		},
+		{
+			name:           "German dashes",
+			targetLanguage: "de",
+			typography:     typography{Dashes: typographyTypographic},
+			translation:    "Warte -- oder — doch nicht.",
+			expected:       "Warte – oder – doch nicht.",
+		},
+		{
+			name:           "Double hyphens",
+			targetLanguage: "en",
+			typography:     typography{Dashes: typographyASCII},
+			translation:    "Wait — pages 10–12.",
+			expected:       "Wait -- pages 10–12.",
+		},
+		{
+			name:           "Ellipses",
+			targetLanguage: "en",
+			typography:     typography{Ellipses: typographyTypographic},
+			translation:    "Well... and so on.....",
+			expected:       "Well… and so on.....",
+		},
+		{
+			name:           "Chinese ellipses",
+			targetLanguage: "zh",
+			typography:     typography{Ellipses: typographyTypographic},
+			translation:    "好吧...",
+			expected:       "好吧……",
+		},
+		{
+			name:           "Three dots",
+			targetLanguage: "zh",
+			typography:     typography{Ellipses: typographyASCII},
+			translation:    "好吧……",
+			expected:       "好吧...",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			translations := []string{tt.translation}
+			normalizeTypography(tt.targetLanguage, tt.typography, translations)
+			if translations[0] != tt.expected {
+				t.Errorf("normalizeTypography() = %q, expected %q", translations[0], tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseTypography(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    typography
+		expectedErr bool
+	}{
+		{value: "", expected: typography{}},
+		{value: " quotes=typographic, ellipses=ascii", expected: typography{Quotes: typographyTypographic, Ellipses: typographyASCII}},
+		{value: "dashes=em", expectedErr: true},
+		{value: "spaces=ascii", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseTypography(tt.value)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("parseTypography() error = %v, expected error %v", err, tt.expectedErr)
+			}
+			if got != tt.expected {
+				t.Errorf("parseTypography() = %+v, expected %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandleTypography(t *testing.T) {
+	tests := []struct {
+		name               string
+		configured         typography
+		body               string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "Typography of the request",
+			body:               `{"source_language":"en","target_language":"es","text":"Wait...","typography":"quotes=typographic,ellipses=typographic"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"translated_text":"«Espera»… "}`,
+		},
+		{
+			name:               "Configured typography",
+			configured:         typography{Quotes: typographyTypographic, Ellipses: typographyTypographic},
+			body:               `{"source_language":"en","target_language":"es","text":"Wait...","typography":"ellipses=ascii"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"translated_text":"«Espera»... "}`,
+		},
+		{
+			name:               "Without typography",
+			body:               `{"source_language":"en","target_language":"es","text":"Wait..."}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"translated_text":"\"Espera\"... "}`,
+		},
+		{
+			name:               "Invalid typography",
+			body:               `{"source_language":"en","target_language":"es","text":"Wait...","typography":"quotes=curly"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := configuredTypography
+			configuredTypography = tt.configured
+			defer func() { configuredTypography = previous }()
+
+			h := newMockHandler(map[string]string{"Wait...": `"Espera"...`})
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+			if got.StatusCode != tt.expectedStatusCode {
+				t.Fatalf("handle() status = %d, expected %d: %s", got.StatusCode, tt.expectedStatusCode, got.Body)
+			}
+			if tt.expectedBody != "" && got.Body != tt.expectedBody {
+				t.Errorf("handle() = %s, expected %s", got.Body, tt.expectedBody)
+			}
+		})
+	}
+}
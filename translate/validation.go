@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// defaultMaxRequestSentences caps the sentences of a text request, each is a provider call
+const defaultMaxRequestSentences = 1000
+
+// languageCodePattern matches the shape of an ISO 639 language code with optional BCP 47
+// script and region subtags, such as es, zh-TW or sr-Latn-RS
+var languageCodePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(?:-[A-Za-z]{4})?(?:-(?:[A-Za-z]{2}|[0-9]{3}))?$`)
+
+// fieldError is the validation error of a single request field
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validationError collects every field error of an invalid request
+type validationError struct {
+	Fields []fieldError
+}
+
+func (e *validationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// add records an error of the field, the message should name the field
+func (e *validationError) add(field, format string, args ...any) {
+	e.Fields = append(e.Fields, fieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidationErrorResponse is the body of a request rejected by validation
+type ValidationErrorResponse struct {
+	// Error summarizes why the request was rejected
+	Error string `json:"error"`
+	// Fields are the errors of each invalid field
+	Fields []fieldError `json:"fields"`
+}
+
+// validationErrorResponse returns a 400 response detailing the invalid fields
+func validationErrorResponse(err *validationError) events.APIGatewayProxyResponse {
+	body, marshalErr := json.Marshal(ValidationErrorResponse{
+		Error:  "Invalid request",
+		Fields: err.Fields,
+	})
+	if marshalErr != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       err.Error(),
+		}
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusBadRequest,
+		Body:       string(body),
+	}
+}
+
+// validateLanguageCode checks the shape of a language code, the provider decides whether it
+// is supported
+func validateLanguageCode(errs *validationError, field, code string, allowAuto bool) {
+	switch {
+	case code == "":
+		errs.add(field, "%s is required", field)
+	case code == autoDetectLanguage && allowAuto:
+	case !languageCodePattern.MatchString(code):
+		errs.add(field, "%s %q is not a valid language code", field, code)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestValidateLanguageCode(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		allowAuto bool
+		wantErr   bool
+	}{
+		{name: "Language", code: "es"},
+		{name: "Three letter language", code: "fil"},
+		{name: "Language and region", code: "fr-CA"},
+		{name: "Language, script and region", code: "sr-Latn-RS"},
+		{name: "Numeric region", code: "es-419"},
+		{name: "Auto when allowed", code: "auto", allowAuto: true},
+		{name: "Auto when not allowed", code: "auto", wantErr: true},
+		{name: "Missing", code: "", wantErr: true},
+		{name: "Language name", code: "Spanish", wantErr: true},
+		{name: "Underscore separator", code: "pt_PT", wantErr: true},
+		{name: "Injected text", code: "en; drop", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := &validationError{}
+			validateLanguageCode(errs, "target_language", tt.code, tt.allowAuto)
+			if (len(errs.Fields) > 0) != tt.wantErr {
+				t.Errorf("validateLanguageCode() = %v, wantErr %v", errs.Fields, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidationErrorResponse(t *testing.T) {
+	var invalid *validationError
+	if !errors.As(validateRequest(TranslateRequest{SourceLanguage: "english", TargetLanguage: "es", Moderation: "remove"}), &invalid) {
+		t.Fatalf("validateRequest() expected a validation error")
+	}
+
+	got := validationErrorResponse(invalid)
+	expected := `{"error":"Invalid request","fields":[` +
+		`{"field":"source_language","message":"source_language \"english\" is not a valid language code"},` +
+		`{"field":"text","message":"text is required"},` +
+		`{"field":"moderation","message":"moderation \"remove\" is not supported"}]}`
+	if got.StatusCode != http.StatusBadRequest || got.Body != expected {
+		t.Errorf("validationErrorResponse() = %v, expected %v", got.Body, expected)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	apiVersion1 = "1"
+	apiVersion2 = "2"
+
+	// versionedMediaTypePrefix and versionedMediaTypeSuffix surround the version of the
+	// media types negotiated with the Accept header, such as application/vnd.gotranslate.v2+json
+	versionedMediaTypePrefix = "application/vnd.gotranslate.v"
+	versionedMediaTypeSuffix = "+json"
+)
+
+// apiCodec reads the requests and writes the responses of an API version
+type apiCodec struct {
+	unmarshalRequest func(body []byte) (TranslateRequest, error)
+	marshalResponse  func(response TranslateResponse) ([]byte, error)
+	// contentType is the content type of the responses, empty for the unversioned ones
+	contentType string
+}
+
+// apiCodecs are the codecs of the supported API versions. The first version is the shape of
+// the API before versioning, which clients get unless they ask for another one.
+var apiCodecs = map[string]apiCodec{
+	apiVersion1: {
+		unmarshalRequest: unmarshalRequest,
+		marshalResponse:  marshalResponse,
+	},
+	apiVersion2: {
+		unmarshalRequest: unmarshalRequest,
+		marshalResponse:  marshalResponseV2,
+		contentType:      versionedMediaTypePrefix + apiVersion2 + versionedMediaTypeSuffix,
+	},
+}
+
+// TranslateResponseV2 is the version 2 shape of the translation response, which always
+// reports the segments and groups the flags and counts in a stats block
+type TranslateResponseV2 struct {
+	// Version is the version of the response shape
+	Version string `json:"version"`
+	// TranslatedText is the translated text
+	TranslatedText string `json:"translated_text"`
+	// DetectedLanguage is the detected language of the source text
+	DetectedLanguage string `json:"detected_language,omitempty"`
+	// Blocks are the source and translated text blocks of a document
+	Blocks []TranslatedBlock `json:"blocks,omitempty"`
+	// Plurals are the translated CLDR plural forms of the target language for the "plural" format
+	Plurals map[string]string `json:"plurals,omitempty"`
+	// OutputKey is the key of the translated CSV file in the bulk bucket
+	OutputKey string `json:"output_key,omitempty"`
+	// Segments reports the translated segments that needed attention, empty when none did
+	Segments []SegmentReport `json:"segments"`
+	// Stats describes how the text was translated
+	Stats ResponseStats `json:"stats"`
+	// Estimate is the cost estimate of a dry run, which returns no translated text
+	Estimate *CostEstimate `json:"estimate,omitempty"`
+	// Alignment pairs the source sentences with their translations when requested
+	Alignment []AlignedSentence `json:"alignment,omitempty"`
+	// Direction is "rtl" when the target language is written right to left
+	Direction string `json:"direction,omitempty"`
+	// Changes reports the sentences translated and reused for a previous version of the text
+	Changes *ChangeReport `json:"changes,omitempty"`
+	// Alternatives are the alternative translations of each sentence when requested
+	Alternatives []SegmentAlternatives `json:"alternatives,omitempty"`
+	// Analysis is the sentiment and the named entities of the source text when requested
+	Analysis *SourceAnalysis `json:"analysis,omitempty"`
+	// Parameters are the parameters the request was translated with when requested
+	Parameters *ResolvedParameters `json:"parameters,omitempty"`
+}
+
+// ResponseStats describes how the text of a version 2 response was translated
+type ResponseStats struct {
+	// TranslatedCharacters is the length of the translated text in characters
+	TranslatedCharacters int `json:"translated_characters"`
+	// Rows is the number of CSV rows translated
+	Rows int `json:"rows"`
+	// TranslationConfidence is the confidence score of the translation
+	TranslationConfidence float64 `json:"translation_confidence,omitempty"`
+	// Skipped is set when the source language is the target language and the text is returned as is
+	Skipped bool `json:"skipped"`
+	// Degraded is set when some text was left untranslated because the provider was unavailable
+	Degraded bool `json:"degraded"`
+	// TimedOut is set when some segments were left untranslated to answer by the deadline
+	TimedOut bool `json:"timed_out,omitempty"`
+	// LowQuality is set when some short strings were translated by the offline fallback
+	LowQuality bool `json:"low_quality"`
+	// Pivoted is set when some text was translated through the pivot language
+	Pivoted bool `json:"pivoted"`
+}
+
+// marshalResponseV2 marshals the response in the version 2 shape
+func marshalResponseV2(response TranslateResponse) ([]byte, error) {
+	segments := response.Segments
+	if segments == nil {
+		segments = []SegmentReport{}
+	}
+
+	body, err := json.Marshal(TranslateResponseV2{
+		Version:          apiVersion2,
+		TranslatedText:   response.TranslatedText,
+		DetectedLanguage: response.DetectedLanguage,
+		Blocks:           response.Blocks,
+		Plurals:          response.Plurals,
+		OutputKey:        response.OutputKey,
+		Segments:         segments,
+		Estimate:         response.Estimate,
+		Alignment:        response.Alignment,
+		Direction:        response.Direction,
+		Changes:          response.Changes,
+		Alternatives:     response.Alternatives,
+		Analysis:         response.Analysis,
+		Parameters:       response.Parameters,
+		Stats: ResponseStats{
+			TranslatedCharacters:  utf8.RuneCountInString(response.TranslatedText),
+			Rows:                  response.Rows,
+			TranslationConfidence: response.TranslationConfidence,
+			Skipped:               response.Skipped,
+			Degraded:              response.Degraded,
+			TimedOut:              response.TimedOut,
+			LowQuality:            response.LowQuality,
+			Pivoted:               response.Pivoted,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+	return body, nil
+}
+
+// negotiateVersion returns the API version of the request: the version of the
+// application/vnd.gotranslate media type in the Accept header, otherwise the version query
+// parameter or field of the body, and the first version when none is given
+func negotiateVersion(event events.APIGatewayProxyRequest) string {
+	for name, value := range event.Headers {
+		if !strings.EqualFold(name, "Accept") {
+			continue
+		}
+		for _, mediaRange := range strings.Split(value, ",") {
+			mediaType, _, err := mime.ParseMediaType(mediaRange)
+			if err != nil {
+				continue
+			}
+			version, ok := strings.CutPrefix(mediaType, versionedMediaTypePrefix)
+			if !ok {
+				continue
+			}
+			if version, ok = strings.CutSuffix(version, versionedMediaTypeSuffix); ok && version != "" {
+				return version
+			}
+		}
+	}
+
+	if version := event.QueryStringParameters["version"]; version != "" {
+		return version
+	}
+	if event.HTTPMethod != http.MethodGet {
+		var body struct {
+			Version string `json:"version"`
+		}
+		if err := json.Unmarshal([]byte(event.Body), &body); err == nil && body.Version != "" {
+			return body.Version
+		}
+	}
+	return apiVersion1
+}
+
+type apiCodecKey struct{}
+
+// withAPICodec returns a context whose responses are written by the codec
+func withAPICodec(ctx context.Context, codec apiCodec) context.Context {
+	return context.WithValue(ctx, apiCodecKey{}, codec)
+}
+
+// apiCodecFromContext returns the codec of the request, the first version when there is none
+func apiCodecFromContext(ctx context.Context) apiCodec {
+	if codec, ok := ctx.Value(apiCodecKey{}).(apiCodec); ok {
+		return codec
+	}
+	return apiCodecs[apiVersion1]
+}
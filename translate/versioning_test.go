@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    events.APIGatewayProxyRequest
+		expected string
+	}{
+		{
+			name:     "Unversioned request",
+			event:    events.APIGatewayProxyRequest{Body: `{"text":"Hello."}`},
+			expected: apiVersion1,
+		},
+		{
+			name: "Accept header",
+			event: events.APIGatewayProxyRequest{
+				Headers: map[string]string{"accept": "text/html, application/vnd.gotranslate.v2+json;q=0.9"},
+				Body:    `{"text":"Hello.","version":"1"}`,
+			},
+			expected: apiVersion2,
+		},
+		{
+			name: "Other media types",
+			event: events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Accept": "application/ld+json, */*"},
+			},
+			expected: apiVersion1,
+		},
+		{
+			name:     "Body version",
+			event:    events.APIGatewayProxyRequest{Body: `{"text":"Hello.","version":"2"}`},
+			expected: apiVersion2,
+		},
+		{
+			name: "Query string version",
+			event: events.APIGatewayProxyRequest{
+				HTTPMethod:            http.MethodGet,
+				QueryStringParameters: map[string]string{"version": "2"},
+			},
+			expected: apiVersion2,
+		},
+		{
+			name: "Unknown version",
+			event: events.APIGatewayProxyRequest{
+				Headers: map[string]string{"Accept": "application/vnd.gotranslate.v9+json"},
+			},
+			expected: "9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := negotiateVersion(tt.event); got != tt.expected {
+				t.Errorf("negotiateVersion() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMarshalResponseV2(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    TranslateResponse
+		expected string
+	}{
+		{
+			name:     "Translation",
+			input:    TranslateResponse{TranslatedText: "¡Hola!", Degraded: true},
+			expected: `{"version":"2","translated_text":"¡Hola!","segments":[],"stats":{"translated_characters":6,"rows":0,"skipped":false,"degraded":true,"low_quality":false,"pivoted":false}}`,
+		},
+		{
+			name: "Segments and rows",
+			input: TranslateResponse{
+				OutputKey: "translated/file.csv",
+				Rows:      2,
+				Segments:  []SegmentReport{{SourceText: "a", TranslatedText: "b", Labels: []string{"PROFANITY"}}},
+			},
+			expected: `{"version":"2","translated_text":"","output_key":"translated/file.csv","segments":[{"source_text":"a","translated_text":"b","labels":["PROFANITY"]}],"stats":{"translated_characters":0,"rows":2,"skipped":false,"degraded":false,"low_quality":false,"pivoted":false}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := marshalResponseV2(tt.input)
+			if err != nil {
+				t.Fatalf("marshalResponseV2() error = %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("marshalResponseV2() = %s, expected %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandleVersioned(t *testing.T) {
+	tests := []struct {
+		name                string
+		headers             map[string]string
+		body                string
+		expectedStatus      int
+		expectedBody        string
+		expectedContentType string
+	}{
+		{
+			name:           "Version 1",
+			body:           `{"source_language":"en","target_language":"es","text":"Hello."}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"translated_text":"Hola. "}`,
+		},
+		{
+			name:                "Version 2",
+			headers:             map[string]string{"Accept": "application/vnd.gotranslate.v2+json"},
+			body:                `{"source_language":"en","target_language":"es","text":"Hello."}`,
+			expectedStatus:      http.StatusOK,
+			expectedBody:        `{"version":"2","translated_text":"Hola. ","segments":[],"stats":{"translated_characters":6,"rows":0,"skipped":false,"degraded":false,"low_quality":false,"pivoted":false}}`,
+			expectedContentType: "application/vnd.gotranslate.v2+json",
+		},
+		{
+			name:           "Unsupported version",
+			body:           `{"source_language":"en","target_language":"es","text":"Hello.","version":"9"}`,
+			expectedStatus: http.StatusNotAcceptable,
+			expectedBody:   "API version not supported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMockHandler(map[string]string{"Hello.": "Hola."})
+
+			got, err := h.handle(context.Background(), events.APIGatewayProxyRequest{Headers: tt.headers, Body: tt.body})
+			if err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+
+			if got.StatusCode != tt.expectedStatus || got.Body != tt.expectedBody {
+				t.Errorf("handle() = %d %s, expected %d %s", got.StatusCode, got.Body, tt.expectedStatus, tt.expectedBody)
+			}
+			if got.Headers["Content-Type"] != tt.expectedContentType {
+				t.Errorf("handle() Content-Type = %q, expected %q", got.Headers["Content-Type"], tt.expectedContentType)
+			}
+		})
+	}
+}